@@ -0,0 +1,111 @@
+//! Bitbucket client implementation
+
+/// Default Bitbucket Cloud REST API base URL
+pub const DEFAULT_API_BASE: &str = "https://api.bitbucket.org/2.0";
+
+/// Credentials used to authenticate against the Bitbucket API
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub enum BitbucketAuth {
+    /// Username and app password, sent as HTTP basic auth
+    AppPassword { username: String, password: String },
+    /// Repository, project or workspace access token, sent as a bearer token
+    Token(String),
+}
+
+impl BitbucketAuth {
+    /// Interpret a credential string
+    ///
+    /// Values in the form `username:app_password` are treated as an app
+    /// password, anything else as an access token.
+    pub fn from_credential(credential: &str) -> Self {
+        match credential.split_once(':') {
+            Some((username, password)) if !username.is_empty() && !password.is_empty() => {
+                Self::AppPassword {
+                    username: username.to_string(),
+                    password: password.to_string(),
+                }
+            }
+            _ => Self::Token(credential.to_string()),
+        }
+    }
+}
+
+/// Bitbucket API client for making authenticated requests
+pub struct BitbucketClient {
+    pub(crate) client: reqwest::Client,
+    pub(crate) auth: Option<BitbucketAuth>,
+    pub(crate) base_url: String,
+}
+
+impl BitbucketClient {
+    /// Create a new Bitbucket client with an optional credential
+    /// If no credential is provided, will try to read from BITBUCKET_TOKEN environment variable
+    pub fn new(credential: Option<String>) -> Self {
+        Self {
+            client: reqwest::Client::new(),
+            auth: credential
+                .or_else(|| std::env::var("BITBUCKET_TOKEN").ok())
+                .map(|c| BitbucketAuth::from_credential(&c)),
+            base_url: DEFAULT_API_BASE.to_string(),
+        }
+    }
+
+    /// Use a different API base URL (e.g. for a proxy or a test server)
+    pub fn with_base_url(mut self, base_url: impl Into<String>) -> Self {
+        self.base_url = base_url.into().trim_end_matches('/').to_string();
+        self
+    }
+
+    /// Apply the configured credentials to a request
+    pub(crate) fn authorize(&self, request: reqwest::RequestBuilder) -> reqwest::RequestBuilder {
+        match &self.auth {
+            Some(BitbucketAuth::AppPassword { username, password }) => {
+                request.basic_auth(username, Some(password))
+            }
+            Some(BitbucketAuth::Token(token)) => request.bearer_auth(token),
+            None => request,
+        }
+    }
+}
+
+impl Default for BitbucketClient {
+    fn default() -> Self {
+        Self::new(None)
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_auth_from_app_password() {
+        assert_eq!(
+            BitbucketAuth::from_credential("alice:s3cret"),
+            BitbucketAuth::AppPassword {
+                username: "alice".to_string(),
+                password: "s3cret".to_string(),
+            }
+        );
+    }
+
+    #[test]
+    fn test_auth_from_token() {
+        assert_eq!(
+            BitbucketAuth::from_credential("ATCTT3xFfGN0"),
+            BitbucketAuth::Token("ATCTT3xFfGN0".to_string())
+        );
+        // A dangling separator is not a valid app password
+        assert_eq!(
+            BitbucketAuth::from_credential("alice:"),
+            BitbucketAuth::Token("alice:".to_string())
+        );
+    }
+
+    #[test]
+    fn test_with_base_url_trims_trailing_slash() {
+        let client = BitbucketClient::new(Some("token".to_string()))
+            .with_base_url("http://localhost:8080/2.0/");
+        assert_eq!(client.base_url, "http://localhost:8080/2.0");
+    }
+}
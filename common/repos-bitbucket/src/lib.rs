@@ -0,0 +1,17 @@
+//! Bitbucket Cloud API client library
+//!
+//! This library provides the Bitbucket Cloud counterpart of `repos-github`,
+//! used by the `repos` CLI to open pull requests on repositories hosted on
+//! bitbucket.org.
+//!
+//! ## Modules
+//!
+//! - [`client`]: Core Bitbucket client implementation and authentication
+//! - [`pull_requests`]: Pull request creation
+
+mod client;
+mod pull_requests;
+
+// Re-export public API
+pub use client::{BitbucketAuth, BitbucketClient};
+pub use pull_requests::{PullRequest, PullRequestParams};
@@ -0,0 +1,198 @@
+//! Branch protection rules
+
+use crate::client::GitHubClient;
+use anyhow::{Context, Result};
+use serde::Deserialize;
+
+/// The protection rules of a branch that affect pushing and merging
+#[derive(Debug, Clone, Default, PartialEq, Eq)]
+pub struct BranchProtection {
+    /// Changes must go through a pull request (`required_pull_request_reviews`)
+    pub requires_pull_request: bool,
+    /// Approving reviews needed before merging
+    pub required_approving_reviews: u32,
+    /// Status checks that must pass before merging
+    pub required_checks: Vec<String>,
+    /// Only selected users, teams or apps may push
+    pub push_restricted: bool,
+    /// The branch is read-only
+    pub locked: bool,
+}
+
+impl BranchProtection {
+    /// Whether pushing directly to the branch is rejected for most users
+    pub fn blocks_direct_push(&self) -> bool {
+        self.requires_pull_request || self.push_restricted || self.locked
+    }
+}
+
+#[derive(Deserialize)]
+struct ProtectionResponse {
+    required_status_checks: Option<StatusChecks>,
+    required_pull_request_reviews: Option<PullRequestReviews>,
+    restrictions: Option<serde::de::IgnoredAny>,
+    lock_branch: Option<Enabled>,
+}
+
+#[derive(Deserialize)]
+struct StatusChecks {
+    #[serde(default)]
+    contexts: Vec<String>,
+}
+
+#[derive(Deserialize)]
+struct PullRequestReviews {
+    #[serde(default)]
+    required_approving_review_count: u32,
+}
+
+#[derive(Deserialize)]
+struct Enabled {
+    enabled: bool,
+}
+
+impl From<ProtectionResponse> for BranchProtection {
+    fn from(response: ProtectionResponse) -> Self {
+        Self {
+            requires_pull_request: response.required_pull_request_reviews.is_some(),
+            required_approving_reviews: response
+                .required_pull_request_reviews
+                .map_or(0, |reviews| reviews.required_approving_review_count),
+            required_checks: response
+                .required_status_checks
+                .map(|checks| checks.contexts)
+                .unwrap_or_default(),
+            push_restricted: response.restrictions.is_some(),
+            locked: response.lock_branch.is_some_and(|lock| lock.enabled),
+        }
+    }
+}
+
+impl GitHubClient {
+    /// Fetch the protection rules of a branch
+    ///
+    /// Returns `None` when the branch is not protected. Reading protection
+    /// needs a token with admin or maintain access to the repository.
+    ///
+    /// # Errors
+    /// Returns an error if the API request fails or the response cannot be parsed
+    pub async fn get_branch_protection(
+        &self,
+        owner: &str,
+        repo: &str,
+        branch: &str,
+    ) -> Result<Option<BranchProtection>> {
+        let url = format!(
+            "{}/repos/{}/{}/branches/{}/protection",
+            self.base_url, owner, repo, branch
+        );
+        let Some(response) = self.get_optional(&url).await? else {
+            return Ok(None);
+        };
+
+        let protection: ProtectionResponse = response
+            .json()
+            .await
+            .context("Failed to parse GitHub branch protection response")?;
+        Ok(Some(protection.into()))
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use repos_mock_server::{MockServer, Response};
+
+    #[tokio::test]
+    async fn test_get_branch_protection_parses_rules() {
+        let server = MockServer::start(vec![Response::new(
+            "200 OK",
+            r#"{
+                "url": "https://api.github.com/repos/acme/api/branches/main/protection",
+                "required_status_checks": {"strict": true, "contexts": ["ci/test", "lint"], "checks": []},
+                "enforce_admins": {"enabled": false},
+                "required_pull_request_reviews": {"dismiss_stale_reviews": true, "required_approving_review_count": 2},
+                "lock_branch": {"enabled": false}
+            }"#,
+        )]);
+        let client = GitHubClient::new(Some("secret".to_string())).with_base_url(&server.url);
+
+        let protection = client
+            .get_branch_protection("acme", "api", "main")
+            .await
+            .unwrap()
+            .expect("a protected branch");
+        assert_eq!(
+            protection,
+            BranchProtection {
+                requires_pull_request: true,
+                required_approving_reviews: 2,
+                required_checks: vec!["ci/test".to_string(), "lint".to_string()],
+                push_restricted: false,
+                locked: false,
+            }
+        );
+        assert!(protection.blocks_direct_push());
+        assert!(
+            server.requests()[0]
+                .line
+                .starts_with("GET /repos/acme/api/branches/main/protection ")
+        );
+    }
+
+    #[tokio::test]
+    async fn test_get_branch_protection_of_locked_branch() {
+        let server = MockServer::start(vec![Response::new(
+            "200 OK",
+            r#"{"restrictions": {"users": [], "teams": [], "apps": []}, "lock_branch": {"enabled": true}}"#,
+        )]);
+        let client = GitHubClient::new(Some("secret".to_string())).with_base_url(&server.url);
+
+        let protection = client
+            .get_branch_protection("acme", "api", "release")
+            .await
+            .unwrap()
+            .unwrap();
+        assert!(protection.locked);
+        assert!(protection.push_restricted);
+        assert!(!protection.requires_pull_request);
+        assert!(protection.required_checks.is_empty());
+        server.requests();
+    }
+
+    #[tokio::test]
+    async fn test_unprotected_branch_is_none() {
+        let server = MockServer::start(vec![Response::new(
+            "404 Not Found",
+            r#"{"message": "Branch not protected"}"#,
+        )]);
+        let client = GitHubClient::new(Some("secret".to_string())).with_base_url(&server.url);
+
+        assert_eq!(
+            client
+                .get_branch_protection("acme", "api", "main")
+                .await
+                .unwrap(),
+            None
+        );
+        server.requests();
+
+        assert!(!BranchProtection::default().blocks_direct_push());
+    }
+
+    #[tokio::test]
+    async fn test_get_branch_protection_reports_forbidden() {
+        let server = MockServer::start(vec![Response::new(
+            "403 Forbidden",
+            r#"{"message": "Must have admin rights"}"#,
+        )]);
+        let client = GitHubClient::new(Some("secret".to_string())).with_base_url(&server.url);
+
+        let err = client
+            .get_branch_protection("acme", "api", "main")
+            .await
+            .unwrap_err();
+        assert!(err.to_string().contains("403"));
+        server.requests();
+    }
+}
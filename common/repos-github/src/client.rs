@@ -1,20 +1,47 @@
 //! GitHub client implementation
 
+use crate::proxy::{ProxyConfig, proxy};
+use anyhow::Result;
+
+/// Default GitHub REST API base URL
+pub const DEFAULT_API_BASE: &str = "https://api.github.com";
+
 /// GitHub API client for making authenticated requests
 pub struct GitHubClient {
     pub(crate) client: reqwest::Client,
     pub(crate) token: Option<String>,
+    pub(crate) base_url: String,
 }
 
 impl GitHubClient {
     /// Create a new GitHub client with an optional token
     /// If no token is provided, will try to read from GITHUB_TOKEN environment variable
+    ///
+    /// Requests go through the proxy given to [`crate::set_proxy`], else the
+    /// one in the environment.
     pub fn new(token: Option<String>) -> Self {
         Self {
-            client: reqwest::Client::new(),
+            // set_proxy only accepts settings that build
+            client: proxy().http_client().unwrap_or_default(),
             token: token.or_else(|| std::env::var("GITHUB_TOKEN").ok()),
+            base_url: DEFAULT_API_BASE.to_string(),
         }
     }
+
+    /// Use a different API base URL (e.g. for a proxy or a test server)
+    pub fn with_base_url(mut self, base_url: impl Into<String>) -> Self {
+        self.base_url = base_url.into().trim_end_matches('/').to_string();
+        self
+    }
+
+    /// Send requests through `proxy` instead of the process-wide settings
+    ///
+    /// # Errors
+    /// Returns an error if the proxy URL is invalid
+    pub fn with_proxy(mut self, proxy: &ProxyConfig) -> Result<Self> {
+        self.client = proxy.http_client()?;
+        Ok(self)
+    }
 }
 
 impl Default for GitHubClient {
@@ -22,3 +49,57 @@ impl Default for GitHubClient {
         Self::new(None)
     }
 }
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use repos_mock_server::{MockServer, Response};
+
+    /// A server answering one request with a repository
+    fn mock_server() -> MockServer {
+        MockServer::start(vec![Response::new("200 OK", r#"{"topics": ["rust"]}"#)])
+    }
+
+    #[tokio::test]
+    async fn test_requests_go_through_configured_proxy() {
+        let proxy = mock_server();
+        let client = GitHubClient::new(Some("token".to_string()))
+            .with_base_url("http://github.example.invalid/api/v3")
+            .with_proxy(&ProxyConfig {
+                url: Some(proxy.url.clone()),
+                no_proxy: None,
+            })
+            .unwrap();
+
+        let repo = client.get_repository_details("acme", "api").await.unwrap();
+        assert_eq!(repo.topics, vec!["rust"]);
+        // A proxied plain HTTP request carries the absolute URL
+        assert_eq!(
+            proxy.request_lines()[0],
+            "GET http://github.example.invalid/api/v3/repos/acme/api HTTP/1.1"
+        );
+    }
+
+    #[tokio::test]
+    async fn test_no_proxy_hosts_bypass_the_proxy() {
+        let api = mock_server();
+        let client = GitHubClient::new(Some("token".to_string()))
+            .with_base_url(&api.url)
+            .with_proxy(&ProxyConfig {
+                // Nothing listens here; the request must not be sent to it
+                url: Some("http://127.0.0.1:9".to_string()),
+                no_proxy: Some("localhost,127.0.0.1".to_string()),
+            })
+            .unwrap();
+
+        client.get_repository_details("acme", "api").await.unwrap();
+        assert_eq!(api.request_lines()[0], "GET /repos/acme/api HTTP/1.1");
+    }
+
+    #[test]
+    fn test_with_base_url_trims_trailing_slash() {
+        let client = GitHubClient::new(Some("token".to_string()))
+            .with_base_url("http://localhost:8080/api/v3/");
+        assert_eq!(client.base_url, "http://localhost:8080/api/v3");
+    }
+}
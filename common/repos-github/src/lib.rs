@@ -5,18 +5,24 @@
 //!
 //! ## Modules
 //!
+//! - [`branches`]: Branch protection rules
 //! - [`client`]: Core GitHub client implementation
+//! - [`proxy`]: HTTP(S) proxy settings for API requests
 //! - [`pull_requests`]: Pull request creation and management
 //! - [`repositories`]: Repository information retrieval
 //! - [`util`]: Utility functions for GitHub operations
 
+mod branches;
 mod client;
+mod proxy;
 mod pull_requests;
 mod repositories;
 mod util;
 
 // Re-export public API
-pub use client::GitHubClient;
+pub use branches::BranchProtection;
+pub use client::{DEFAULT_API_BASE, GitHubClient};
+pub use proxy::{ProxyConfig, proxy, set_proxy};
 pub use pull_requests::{PullRequest, PullRequestParams};
-pub use repositories::GitHubRepo;
-pub use util::parse_github_url;
+pub use repositories::{GitHubRepo, OrgRepository};
+pub use util::{api_base_url, parse_github_url, url_host};
@@ -0,0 +1,122 @@
+//! HTTP(S) proxy settings for API requests
+//!
+//! Without overrides, requests follow the standard `HTTPS_PROXY`,
+//! `HTTP_PROXY` and `NO_PROXY` environment variables. A [`ProxyConfig`]
+//! (`--proxy` / `--no-proxy`) replaces them: [`set_proxy`] applies it to every
+//! [`GitHubClient`](crate::GitHubClient) created afterwards, and
+//! [`GitHubClient::with_proxy`](crate::GitHubClient::with_proxy) to one client.
+
+use anyhow::{Context, Result};
+use std::sync::RwLock;
+
+/// Proxy overrides; unset fields fall back to the environment
+#[derive(Debug, Clone, Default, PartialEq, Eq)]
+pub struct ProxyConfig {
+    /// Proxy for every HTTP and HTTPS request (`--proxy`)
+    pub url: Option<String>,
+    /// Comma-separated hosts reached without the proxy (`--no-proxy`); `*` for all
+    pub no_proxy: Option<String>,
+}
+
+/// Process-wide overrides used by [`crate::GitHubClient::new`]
+static PROXY: RwLock<Option<ProxyConfig>> = RwLock::new(None);
+
+/// Use `config` for every GitHub client created from now on
+///
+/// # Errors
+/// Returns an error if the proxy URL is invalid
+pub fn set_proxy(config: ProxyConfig) -> Result<()> {
+    config.http_client()?;
+    *PROXY
+        .write()
+        .unwrap_or_else(|poisoned| poisoned.into_inner()) = Some(config);
+    Ok(())
+}
+
+/// The overrides given to [`set_proxy`], if any
+pub fn proxy() -> ProxyConfig {
+    PROXY
+        .read()
+        .unwrap_or_else(|poisoned| poisoned.into_inner())
+        .clone()
+        .unwrap_or_default()
+}
+
+impl ProxyConfig {
+    /// Whether nothing overrides the environment
+    pub fn is_empty(&self) -> bool {
+        self.url.is_none() && self.no_proxy.is_none()
+    }
+
+    /// An HTTP client sending requests through the configured proxy
+    ///
+    /// # Errors
+    /// Returns an error if the proxy URL is invalid
+    pub fn http_client(&self) -> Result<reqwest::Client> {
+        let builder = reqwest::Client::builder();
+        if self.is_empty() {
+            // reqwest reads HTTP_PROXY, HTTPS_PROXY and NO_PROXY itself
+            return builder.build().context("Failed to build HTTP client");
+        }
+
+        let no_proxy = match &self.no_proxy {
+            Some(hosts) => reqwest::NoProxy::from_string(hosts),
+            None => reqwest::NoProxy::from_env(),
+        };
+        let proxies = match &self.url {
+            Some(url) => vec![
+                reqwest::Proxy::all(url.as_str())
+                    .with_context(|| format!("Invalid proxy URL '{}'", url))?,
+            ],
+            // Only --no-proxy: keep the environment's proxies with the new exceptions
+            None => {
+                let mut proxies = Vec::new();
+                if let Some(url) = env_proxy(&["HTTP_PROXY", "http_proxy"]) {
+                    proxies.push(
+                        reqwest::Proxy::http(url.as_str())
+                            .with_context(|| format!("Invalid HTTP_PROXY '{}'", url))?,
+                    );
+                }
+                if let Some(url) = env_proxy(&["HTTPS_PROXY", "https_proxy"]) {
+                    proxies.push(
+                        reqwest::Proxy::https(url.as_str())
+                            .with_context(|| format!("Invalid HTTPS_PROXY '{}'", url))?,
+                    );
+                }
+                proxies
+            }
+        };
+
+        // Without any proxy this disables the environment lookup as well
+        let mut builder = builder.no_proxy();
+        for proxy in proxies {
+            builder = builder.proxy(proxy.no_proxy(no_proxy.clone()));
+        }
+        builder.build().context("Failed to build HTTP client")
+    }
+}
+
+/// The first of `names` set to a non-empty value
+fn env_proxy(names: &[&str]) -> Option<String> {
+    names
+        .iter()
+        .filter_map(|name| std::env::var(name).ok())
+        .find(|value| !value.is_empty())
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_invalid_proxy_url_is_rejected() {
+        let config = ProxyConfig {
+            url: Some("http://[::1".to_string()),
+            no_proxy: None,
+        };
+        let err = config.http_client().unwrap_err();
+        assert!(err.to_string().contains("Invalid proxy URL"));
+        assert!(ProxyConfig::default().http_client().is_ok());
+        assert!(ProxyConfig::default().is_empty());
+    }
+}
@@ -79,8 +79,8 @@ impl GitHubClient {
         }
 
         let url = format!(
-            "https://api.github.com/repos/{}/{}/pulls",
-            params.owner, params.repo
+            "{}/repos/{}/{}/pulls",
+            self.base_url, params.owner, params.repo
         );
 
         let payload = CreatePullRequestPayload {
@@ -2,17 +2,115 @@
 
 use crate::client::GitHubClient;
 use anyhow::{Context, Result, anyhow};
-use serde::Deserialize;
+use serde::{Deserialize, Serialize};
+
+/// Number of repositories requested per page when listing an organization
+const ORG_REPOS_PER_PAGE: usize = 100;
 
 #[derive(Deserialize, Debug, Clone)]
 pub struct GitHubRepo {
     pub topics: Vec<String>,
+    /// Approximate size of the repository in kilobytes
+    #[serde(default)]
+    pub size: Option<u64>,
+}
+
+/// A repository as returned by the organization repository listing
+#[derive(Serialize, Deserialize, Debug, Clone, PartialEq)]
+pub struct OrgRepository {
+    pub name: String,
+    pub clone_url: String,
+    pub ssh_url: String,
+    #[serde(default)]
+    pub topics: Vec<String>,
+    #[serde(default)]
+    pub archived: bool,
+    #[serde(default)]
+    pub visibility: Option<String>,
+}
+
+#[derive(Deserialize)]
+struct TopicsResponse {
+    names: Vec<String>,
 }
 
 impl GitHubClient {
     pub async fn get_repository_details(&self, owner: &str, repo: &str) -> Result<GitHubRepo> {
-        let url = format!("https://api.github.com/repos/{}/{}", owner, repo);
-        let mut request = self.client.get(&url).header("User-Agent", "repos-cli");
+        let url = format!("{}/repos/{}/{}", self.base_url, owner, repo);
+        let response = self.get(&url).await?;
+
+        let repo_data: GitHubRepo = response
+            .json()
+            .await
+            .context("Failed to parse GitHub API response")?;
+        Ok(repo_data)
+    }
+
+    /// Fetch the topics assigned to a repository
+    ///
+    /// # Errors
+    /// Returns an error if the API request fails or the response cannot be parsed
+    pub async fn get_repository_topics(&self, owner: &str, repo: &str) -> Result<Vec<String>> {
+        let url = format!("{}/repos/{}/{}/topics", self.base_url, owner, repo);
+        let response = self.get(&url).await?;
+
+        let topics: TopicsResponse = response
+            .json()
+            .await
+            .context("Failed to parse GitHub topics response")?;
+        Ok(topics.names)
+    }
+
+    /// List every repository of an organization, following pagination
+    ///
+    /// `repo_type` is passed through as the API's `type` filter
+    /// (`all`, `public`, `private`, ...).
+    ///
+    /// # Errors
+    /// Returns an error if any page cannot be fetched or parsed
+    pub async fn list_org_repositories(
+        &self,
+        org: &str,
+        repo_type: &str,
+    ) -> Result<Vec<OrgRepository>> {
+        let mut next = Some(format!(
+            "{}/orgs/{}/repos?type={}&per_page={}",
+            self.base_url, org, repo_type, ORG_REPOS_PER_PAGE
+        ));
+        let mut repositories = Vec::new();
+
+        while let Some(url) = next {
+            let response = self.get(&url).await?;
+            next = response
+                .headers()
+                .get("link")
+                .and_then(|value| value.to_str().ok())
+                .and_then(next_page_url);
+
+            let mut page: Vec<OrgRepository> = response
+                .json()
+                .await
+                .context("Failed to parse GitHub organization repositories response")?;
+            repositories.append(&mut page);
+        }
+
+        Ok(repositories)
+    }
+
+    /// Send an authenticated GET request, mapping unsuccessful statuses to errors
+    pub(crate) async fn get(&self, url: &str) -> Result<reqwest::Response> {
+        self.get_optional(url)
+            .await?
+            .ok_or_else(|| anyhow!("Failed to connect (404 Not Found)"))
+    }
+
+    /// Like [`get`](Self::get), but a 404 response is `None` instead of an error
+    pub(crate) async fn get_optional(&self, url: &str) -> Result<Option<reqwest::Response>> {
+        let mut request = self
+            .client
+            .get(url)
+            .header("User-Agent", "repos-cli")
+            .header("Accept", "application/vnd.github+json");
 
         if let Some(token) = &self.token {
             request = request.header("Authorization", format!("token {}", token));
@@ -20,6 +118,9 @@ impl GitHubClient {
 
         let response = request.send().await?;
 
+        if response.status() == reqwest::StatusCode::NOT_FOUND {
+            return Ok(None);
+        }
         if !response.status().is_success() {
             let status = response.status();
             let error_msg = if status.as_u16() == 403 {
@@ -38,10 +139,136 @@ impl GitHubClient {
             ));
         }
 
-        let repo_data: GitHubRepo = response
-            .json()
+        Ok(Some(response))
+    }
+}
+
+/// Extract the `rel="next"` target from a `Link` response header
+fn next_page_url(link: &str) -> Option<String> {
+    link.split(',').find_map(|part| {
+        let (target, params) = part.split_once(';')?;
+        params
+            .split(';')
+            .any(|param| param.trim() == r#"rel="next""#)
+            .then(|| {
+                target
+                    .trim()
+                    .trim_start_matches('<')
+                    .trim_end_matches('>')
+                    .to_string()
+            })
+    })
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use repos_mock_server::{MockServer, Response};
+
+    #[tokio::test]
+    async fn test_list_org_repositories_follows_pagination() {
+        let first_page = r#"[{"name": "api", "clone_url": "https://github.com/acme/api.git", "ssh_url": "git@github.com:acme/api.git", "topics": ["rust"]},
+                {"name": "web", "clone_url": "https://github.com/acme/web.git", "ssh_url": "git@github.com:acme/web.git"}]"#;
+        let last_page = r#"[{"name": "docs", "clone_url": "https://github.com/acme/docs.git", "ssh_url": "git@github.com:acme/docs.git", "archived": true, "visibility": "public"}]"#;
+        // The first page links to the second
+        let server = MockServer::start_with(|base_url| {
+            let next = format!("{}/orgs/acme/repos?page=2", base_url);
+            vec![
+                Response::new("200 OK", first_page).with_header(format!(
+                    "Link: <{0}>; rel=\"next\", <{0}>; rel=\"last\"",
+                    next
+                )),
+                Response::new("200 OK", last_page),
+            ]
+        });
+        let client = GitHubClient::new(Some("secret".to_string())).with_base_url(&server.url);
+
+        let repos = client
+            .list_org_repositories("acme", "public")
             .await
-            .context("Failed to parse GitHub API response")?;
-        Ok(repo_data)
+            .unwrap();
+        let names: Vec<&str> = repos.iter().map(|r| r.name.as_str()).collect();
+        assert_eq!(names, vec!["api", "web", "docs"]);
+        assert_eq!(repos[0].topics, vec!["rust"]);
+        assert!(repos[1].topics.is_empty());
+        assert!(repos[2].archived);
+
+        let requests = server.requests();
+        assert_eq!(requests.len(), 2);
+        assert!(
+            requests[0]
+                .line
+                .starts_with("GET /orgs/acme/repos?type=public&per_page=100 ")
+        );
+        assert!(requests[1].line.starts_with("GET /orgs/acme/repos?page=2 "));
+    }
+
+    #[test]
+    fn test_next_page_url() {
+        let link = r#"<https://api.github.com/orgs/acme/repos?page=2>; rel="next", <https://api.github.com/orgs/acme/repos?page=5>; rel="last""#;
+        assert_eq!(
+            next_page_url(link).as_deref(),
+            Some("https://api.github.com/orgs/acme/repos?page=2")
+        );
+
+        let last_page = r#"<https://api.github.com/orgs/acme/repos?page=1>; rel="first", <https://api.github.com/orgs/acme/repos?page=4>; rel="prev""#;
+        assert_eq!(next_page_url(last_page), None);
+    }
+
+    #[tokio::test]
+    async fn test_get_repository_topics() {
+        let server = MockServer::start(vec![Response::new(
+            "200 OK",
+            r#"{"names": ["rust", "cli"]}"#,
+        )]);
+        let client = GitHubClient::new(Some("secret".to_string())).with_base_url(&server.url);
+
+        let topics = client
+            .get_repository_topics("acme", "widgets")
+            .await
+            .unwrap();
+        assert_eq!(topics, vec!["rust", "cli"]);
+
+        let request = server.requests().remove(0);
+        assert!(request.line.starts_with("GET /repos/acme/widgets/topics "));
+        assert!(
+            request
+                .head
+                .to_lowercase()
+                .contains("authorization: token secret")
+        );
+    }
+
+    #[tokio::test]
+    async fn test_get_repository_topics_reports_api_error() {
+        let server = MockServer::start(vec![Response::new(
+            "404 Not Found",
+            r#"{"message": "Not Found"}"#,
+        )]);
+        let client = GitHubClient::new(Some("secret".to_string())).with_base_url(&server.url);
+
+        let err = client
+            .get_repository_topics("acme", "missing")
+            .await
+            .unwrap_err();
+        assert!(err.to_string().contains("404"));
+        server.requests();
+    }
+
+    #[tokio::test]
+    async fn test_get_repository_details_uses_base_url() {
+        let server = MockServer::start(vec![Response::new("200 OK", r#"{"topics": ["api"]}"#)]);
+        let client = GitHubClient::new(Some("secret".to_string())).with_base_url(&server.url);
+
+        let repo = client
+            .get_repository_details("acme", "widgets")
+            .await
+            .unwrap();
+        assert_eq!(repo.topics, vec!["api"]);
+        assert!(
+            server.requests()[0]
+                .line
+                .starts_with("GET /repos/acme/widgets ")
+        );
     }
 }
@@ -0,0 +1,142 @@
+//! Canned-response HTTP server for tests
+//!
+//! [`MockServer`] listens on a free local port and answers one connection per
+//! [`Response`], in order, recording each [`Request`] it receives.
+
+use std::io::{BufRead, BufReader, Read, Write};
+use std::net::TcpListener;
+use std::thread::JoinHandle;
+
+/// A response the server sends back
+#[derive(Debug, Clone)]
+pub struct Response {
+    status: String,
+    headers: Vec<String>,
+    body: String,
+}
+
+impl Response {
+    /// A JSON response with `status`, such as `200 OK`
+    pub fn new(status: &str, body: &str) -> Self {
+        Self {
+            status: status.to_string(),
+            headers: vec!["Content-Type: application/json".to_string()],
+            body: body.to_string(),
+        }
+    }
+
+    /// Add a header line, such as `Link: <...>; rel="next"`
+    pub fn with_header(mut self, header: impl Into<String>) -> Self {
+        self.headers.push(header.into());
+        self
+    }
+
+    fn to_http(&self) -> String {
+        let mut response = format!("HTTP/1.1 {}\r\n", self.status);
+        for header in &self.headers {
+            response.push_str(header);
+            response.push_str("\r\n");
+        }
+        response.push_str(&format!(
+            "Content-Length: {}\r\nConnection: close\r\n\r\n{}",
+            self.body.len(),
+            self.body
+        ));
+        response
+    }
+}
+
+/// A request the server received
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct Request {
+    /// Request line without its line ending, e.g. `GET /repos/acme/api HTTP/1.1`
+    pub line: String,
+    /// Request line and headers as sent, up to and including the blank line
+    pub head: String,
+    pub body: String,
+}
+
+impl Request {
+    /// The whole request as sent: head followed by body
+    pub fn raw(&self) -> String {
+        format!("{}{}", self.head, self.body)
+    }
+}
+
+/// A server answering a fixed sequence of responses on a background thread
+pub struct MockServer {
+    /// Base URL of the server, e.g. `http://127.0.0.1:41235`
+    pub url: String,
+    handle: JoinHandle<Vec<Request>>,
+}
+
+impl MockServer {
+    /// Answer one request with each of `responses`, in order
+    pub fn start(responses: Vec<Response>) -> Self {
+        Self::start_with(|_| responses)
+    }
+
+    /// Like [`MockServer::start`], with the responses built from the server's base URL
+    pub fn start_with(responses: impl FnOnce(&str) -> Vec<Response>) -> Self {
+        let listener = TcpListener::bind("127.0.0.1:0").unwrap();
+        let url = format!("http://{}", listener.local_addr().unwrap());
+        let responses = responses(&url);
+
+        let handle = std::thread::spawn(move || {
+            responses
+                .iter()
+                .map(|response| {
+                    let (stream, _) = listener.accept().unwrap();
+                    let mut reader = BufReader::new(stream);
+                    let request = read_request(&mut reader);
+                    reader
+                        .get_mut()
+                        .write_all(response.to_http().as_bytes())
+                        .unwrap();
+                    request
+                })
+                .collect()
+        });
+
+        Self { url, handle }
+    }
+
+    /// Wait until every response has been served and return the requests received
+    pub fn requests(self) -> Vec<Request> {
+        self.handle.join().unwrap()
+    }
+
+    /// Like [`MockServer::requests`], keeping only each request line
+    pub fn request_lines(self) -> Vec<String> {
+        self.requests()
+            .into_iter()
+            .map(|request| request.line)
+            .collect()
+    }
+}
+
+fn read_request(reader: &mut impl BufRead) -> Request {
+    let mut head = String::new();
+    let mut content_length = 0;
+    loop {
+        let mut line = String::new();
+        reader.read_line(&mut line).unwrap();
+        if let Some((name, value)) = line.split_once(':')
+            && name.eq_ignore_ascii_case("content-length")
+        {
+            content_length = value.trim().parse().unwrap();
+        }
+        head.push_str(&line);
+        if line == "\r\n" || line.is_empty() {
+            break;
+        }
+    }
+    let mut body = vec![0; content_length];
+    reader.read_exact(&mut body).unwrap();
+
+    Request {
+        line: head.lines().next().unwrap_or_default().to_string(),
+        head,
+        body: String::from_utf8_lossy(&body).into_owned(),
+    }
+}
@@ -0,0 +1,30 @@
+//! Embedding repos: run a command in every repository with a given tag
+//!
+//! ```sh
+//! cargo run --example embed -- repos.yaml backend "git status --short"
+//! ```
+
+use repos::{RepoFilter, Repos};
+
+#[tokio::main]
+async fn main() -> repos::Result<()> {
+    let mut args = std::env::args().skip(1);
+    let (Some(config), Some(tag), Some(command)) = (args.next(), args.next(), args.next()) else {
+        anyhow::bail!("usage: embed <config> <tag> <command>");
+    };
+
+    let repos = Repos::load(&config)?;
+    let selected = repos.select(&RepoFilter::tagged([tag]));
+
+    for output in repos.run(&selected, &command).await {
+        match output.result {
+            Ok(run) if run.success() => print!("== {}\n{}", output.repository, run.stdout),
+            Ok(run) => eprintln!(
+                "== {} exited with {}\n{}",
+                output.repository, run.exit_code, run.stderr
+            ),
+            Err(e) => eprintln!("== {} failed: {}", output.repository, e),
+        }
+    }
+    Ok(())
+}
@@ -1,8 +1,19 @@
 use anyhow::{Context, Result};
-use repos::Repository;
+use repos::config::Defaults;
+use repos::github::{GitHubApi, default_branch_protection};
+use repos::health::{
+    self, BranchingOptions, CheckResult, Checker, CheckerFactory, DockerfileOptions,
+    DockerfileRule, FleetReport, HealthOptions, HealthReport, HealthTarget, HistoryEntry,
+    ReadmeOptions, Regression, ReportOrder, ScanExclude, ScoreTrend, SensitiveFilesOptions,
+    SigningOptions, check_parallel, find_regressions, load_history, overall_score, score_trends,
+    unknown_checks,
+};
+use repos::utils::table::{Align, Cell, Color, Table};
+use repos::{Config, Repository};
 use serde::{Deserialize, Serialize};
+use std::collections::BTreeMap;
 use std::env;
-use std::path::Path;
+use std::path::{Path, PathBuf};
 use std::process::{Command, Stdio};
 
 #[derive(Debug, Serialize, Deserialize)]
@@ -48,7 +59,7 @@ async fn main() -> Result<()> {
     // Parse mode from arguments
     let mut mode = "deps"; // default mode
     for arg in &args[1..] {
-        if arg == "deps" || arg == "prs" {
+        if arg == "deps" || arg == "prs" || arg == "check" || arg == "merge" || arg == "trend" {
             mode = arg;
             break;
         } else if arg == "--help" || arg == "-h" {
@@ -60,8 +71,64 @@ async fn main() -> Result<()> {
     match mode {
         "deps" => run_deps_check(repos).await,
         "prs" => run_pr_report(repos).await,
+        "check" => {
+            let config = invoking_config()?;
+            let mut scan_exclude = config.scan_exclude.clone();
+            scan_exclude.extend(parse_scan_exclude(&args[1..])?);
+            let factory = CheckerFactory::with_options(HealthOptions {
+                readme: parse_readme_options(&args[1..])?,
+                dockerfile: parse_dockerfile_options(&args[1..])?,
+                signing: parse_signing_options(&args[1..])?,
+                sensitive_files: SensitiveFilesOptions::new(&config.sensitive_files_allow)?,
+                branching: parse_branching_options(&args[1..])?,
+                scan_exclude: ScanExclude::new(scan_exclude)?,
+            })
+            .with_custom_categories(config.categories)?;
+            if args.iter().any(|arg| arg == "--list-categories") {
+                categories_table(&factory).print();
+                return Ok(());
+            }
+            let known_checks = factory.checker_names();
+            let categories = parse_categories(&args[1..])?;
+            let select = |factory: &CheckerFactory| {
+                if categories.is_empty() {
+                    Ok(factory.checkers())
+                } else {
+                    factory.for_categories(&categories)
+                }
+            };
+            // Read the baseline first so a bad file fails before any checks run
+            let baseline = parse_baseline(&args[1..])?;
+            let mut checkers = select(&factory)?;
+            // Branch protection needs the API, so it is only looked up when scored
+            if checkers.iter().any(|checker| checker.name() == "branching")
+                && let Ok(token) = env::var("GITHUB_TOKEN")
+            {
+                let protection = fetch_branch_protection(&repos, &config.defaults, token).await;
+                checkers = select(&factory.with_branch_protection(protection))?;
+            }
+            run_health_checks(
+                repos,
+                &checkers,
+                &known_checks,
+                parse_format(&args[1..])?,
+                parse_history_file(&args[1..])?.as_deref(),
+                parse_parallel(&args[1..]),
+                baseline.as_ref(),
+                parse_verbose(&args[1..]),
+            )
+        }
+        "merge" => run_merge(&parse_merge_files(&args[1..])?, parse_format(&args[1..])?),
+        "trend" => {
+            let history_file =
+                parse_history_file(&args[1..])?.context("trend requires --history-file <PATH>")?;
+            run_trend(&history_file)
+        }
         _ => {
-            eprintln!("Unknown mode: {}. Use 'deps' or 'prs'", mode);
+            eprintln!(
+                "Unknown mode: {}. Use 'deps', 'prs', 'check', 'merge' or 'trend'",
+                mode
+            );
             print_help();
             std::process::exit(1);
         }
@@ -72,11 +139,15 @@ fn print_help() {
     println!("repos-health - Repository health checks and reports");
     println!();
     println!("USAGE:");
-    println!("    repos health [MODE]");
+    println!("    repos health [MODE] [OPTIONS]");
     println!();
     println!("MODES:");
     println!("    deps    Check and update npm dependencies (default)");
     println!("    prs     Generate PR report showing PRs awaiting approval");
+    println!("    check   Score repository health (README quality, license, Dockerfile,");
+    println!("            known vulnerabilities)");
+    println!("    merge   Combine health reports written with `check --format json`");
+    println!("    trend   Show score changes since the previous run in a history file");
     println!();
     println!("DEPS MODE:");
     println!("    Scans repositories for outdated npm packages and automatically");
@@ -98,13 +169,99 @@ fn print_help() {
     println!("    - GITHUB_TOKEN environment variable for API access");
     println!("    - Repositories must be GitHub repositories");
     println!();
+    println!("CHECK MODE:");
+    println!("    Scores each cloned repository per category and lists findings.");
+    println!("    The README check gives partial credit for presence, a title");
+    println!("    heading, the required sections and a minimum word count.");
+    println!("    A root Dockerfile is checked for unpinned or `latest` base images");
+    println!("    (latest-tag), running as root (root-user) and a missing");
+    println!("    HEALTHCHECK (missing-healthcheck).");
+    println!("    Dependencies are scanned for known vulnerabilities with govulncheck,");
+    println!("    npm audit or pip-audit when installed; high or critical findings");
+    println!("    mark the repository critical.");
+    println!("    The most recent commits must be signed in at least the configured");
+    println!("    proportion (signing).");
+    println!("    The default branch must have an allowed name, and with GITHUB_TOKEN");
+    println!("    set it must be protected on GitHub (branching).");
+    println!("    Custom categories grouping checkers by name can be defined under");
+    println!("    `categories` in the config, e.g. `compliance: [license, readme]`.");
+    println!("    Checkers listed in a repository's `health_ignore`, or in");
+    println!("    `# repos:ignore <check>` lines of its `.repos-health` file, are");
+    println!("    skipped for that repository and left out of its score.");
+    println!();
+    println!("    With --baseline, the run is compared with an earlier JSON report and");
+    println!("    every drop in a repository's score or category score, and every check");
+    println!("    newly marking a repository critical, is listed as a regression.");
+    println!();
+    println!("MERGE MODE:");
+    println!("    Reads the JSON reports given as arguments and prints one report");
+    println!("    with the summary recomputed over all repositories. A repository");
+    println!("    in several reports keeps its entry from the last file.");
+    println!();
+    println!("TREND MODE:");
+    println!("    Reads the history file written by `check --history-file` and prints");
+    println!("    each repository's latest score with its change since the previous");
+    println!("    run that checked it.");
+    println!();
     println!("OPTIONS:");
-    println!("    -h, --help    Print this help message");
+    println!("    --readme-section <KEYWORDS>   Required README section as heading");
+    println!("                                  keywords separated by '|' (repeatable,");
+    println!("                                  default: installation|install|usage|");
+    println!("                                  getting started|quick start)");
+    println!(
+        "    --readme-min-words <N>        Minimum README word count (default: {})",
+        health::readme::DEFAULT_MIN_WORDS
+    );
+    println!("    --dockerfile-skip <RULE>      Do not enforce a Dockerfile rule (repeatable)");
+    println!(
+        "    --signing-commits <N>         Recent commits checked for signatures (default: {})",
+        health::signing::DEFAULT_COMMITS
+    );
+    println!("    --signing-min-ratio <PERCENT> Signed commits required among them");
+    println!("                                  (default: 100)");
+    println!("    --allowed-branch <NAMES>      Allowed default branch names, comma-separated");
+    println!("                                  (repeatable, default: main)");
+    println!("    --scan-exclude <GLOB>         Skip matching paths in file-walking checks,");
+    println!("                                  in addition to the config's scan_exclude");
+    println!("                                  (repeatable)");
+    println!("    --categories <NAMES>          Only run checkers in these comma-separated");
+    println!("                                  built-in or custom categories (repeatable)");
+    println!("    --list-categories             List categories and their checkers");
+    println!("    --format <FORMAT>             Output of check and merge: text (default), json,");
+    println!("                                  slack (a short mrkdwn summary) or");
+    println!("                                  github-actions (annotations for failing checks)");
+    println!("    --slack-below <PERCENT>       Repositories listed by the slack format: those");
+    println!("                                  scoring below this (default: 80)");
+    println!("    -p, --parallel                Check repositories concurrently; each one's");
+    println!("                                  results still print as one block, in");
+    println!("                                  config order");
+    println!("    --completion-order            With --parallel, print each repository as");
+    println!("                                  soon as it finishes instead");
+    println!("    --history-file <PATH>         JSON Lines file check appends scores to and");
+    println!("                                  trend reads");
+    println!("    --baseline <PATH>             Report from `check --format json` that check");
+    println!("                                  lists regressions against");
+    println!("    --fail-on-regression          With --baseline, exit non-zero on regressions");
+    println!("    -v, --verbose                 Also list the checks each repository ignores");
+    println!("    -h, --help                    Print this help message");
     println!();
     println!("EXAMPLES:");
     println!("    repos health          # Run dependency check (default)");
     println!("    repos health deps     # Explicitly run dependency check");
     println!("    repos health prs      # Generate PR report");
+    println!("    repos health check    # Score README, license, Dockerfile and vulnerabilities");
+    println!("    repos health check --categories compliance,security");
+    println!("    repos health check --format json -t team-a > team-a.json");
+    println!("    repos health merge team-a.json team-b.json");
+    println!("    repos health check --format slack --slack-below 60");
+    println!("    repos health check --format github-actions");
+    println!("    repos health check --parallel --completion-order");
+    println!("    repos health check --history-file health.jsonl");
+    println!("    repos health trend --history-file health.jsonl");
+    println!("    repos health check --baseline main.json --fail-on-regression");
+    println!(
+        "    repos health check --readme-section usage --readme-section 'contributing|development'"
+    );
 }
 
 async fn run_deps_check(repos: Vec<Repository>) -> Result<()> {
@@ -120,6 +277,529 @@ async fn run_deps_check(repos: Vec<Repository>) -> Result<()> {
     Ok(())
 }
 
+/// Parse README requirements from the plugin arguments
+fn parse_readme_options(args: &[String]) -> Result<ReadmeOptions> {
+    let mut options = ReadmeOptions::default();
+    let mut sections = Vec::new();
+    let mut iter = args.iter();
+
+    while let Some(arg) = iter.next() {
+        match arg.as_str() {
+            "--readme-section" => {
+                let value = iter.next().context("--readme-section requires a value")?;
+                let keywords = ReadmeOptions::parse_section(value);
+                if keywords.is_empty() {
+                    anyhow::bail!("--readme-section requires at least one keyword");
+                }
+                sections.push(keywords);
+            }
+            "--readme-min-words" => {
+                let value = iter.next().context("--readme-min-words requires a value")?;
+                options.min_words = value
+                    .parse()
+                    .with_context(|| format!("Invalid --readme-min-words value: {}", value))?;
+            }
+            _ => {}
+        }
+    }
+
+    if !sections.is_empty() {
+        options.sections = sections;
+    }
+    Ok(options)
+}
+
+/// Parse the Dockerfile rules to skip from the plugin arguments
+fn parse_dockerfile_options(args: &[String]) -> Result<DockerfileOptions> {
+    let mut skipped = Vec::new();
+    let mut iter = args.iter();
+
+    while let Some(arg) = iter.next() {
+        if arg == "--dockerfile-skip" {
+            let value = iter.next().context("--dockerfile-skip requires a value")?;
+            skipped.push(value.parse::<DockerfileRule>()?);
+        }
+    }
+    Ok(DockerfileOptions::without(&skipped))
+}
+
+/// Parse commit signing requirements from the plugin arguments
+fn parse_signing_options(args: &[String]) -> Result<SigningOptions> {
+    let mut options = SigningOptions::default();
+    let mut iter = args.iter();
+
+    while let Some(arg) = iter.next() {
+        match arg.as_str() {
+            "--signing-commits" => {
+                let value = iter.next().context("--signing-commits requires a value")?;
+                options.commits = match value.parse() {
+                    Ok(commits) if commits > 0 => commits,
+                    _ => anyhow::bail!("Invalid --signing-commits value: {}", value),
+                };
+            }
+            "--signing-min-ratio" => {
+                let value = iter
+                    .next()
+                    .context("--signing-min-ratio requires a value")?;
+                options.min_signed_ratio = match value.trim_end_matches('%').parse::<f64>() {
+                    Ok(percent) if (0.0..=100.0).contains(&percent) => percent / 100.0,
+                    _ => anyhow::bail!(
+                        "Invalid --signing-min-ratio value '{}': expected a percentage between 0 and 100",
+                        value
+                    ),
+                };
+            }
+            _ => {}
+        }
+    }
+    Ok(options)
+}
+
+/// Parse the allowed default branch names from the plugin arguments
+fn parse_branching_options(args: &[String]) -> Result<BranchingOptions> {
+    let mut options = BranchingOptions::default();
+    let mut allowed = Vec::new();
+    let mut iter = args.iter();
+
+    while let Some(arg) = iter.next() {
+        if arg == "--allowed-branch" {
+            let value = iter.next().context("--allowed-branch requires a value")?;
+            allowed.extend(
+                value
+                    .split(',')
+                    .map(str::trim)
+                    .filter(|name| !name.is_empty())
+                    .map(str::to_string),
+            );
+        }
+    }
+
+    if !allowed.is_empty() {
+        options.allowed = allowed;
+    }
+    Ok(options)
+}
+
+/// Whether each cloned GitHub repository's default branch is protected
+///
+/// Protection is read at the API base URL `repos pr` would use for each
+/// repository. Repositories whose protection cannot be read are reported and
+/// scored on their branch name alone.
+async fn fetch_branch_protection(
+    repos: &[Repository],
+    defaults: &Defaults,
+    token: String,
+) -> BTreeMap<PathBuf, bool> {
+    let api = GitHubApi::from_defaults(Some(token), defaults);
+    let (protection, failures) = default_branch_protection(repos, &api).await;
+    for (name, error) in failures {
+        eprintln!(
+            "health: {} branch protection not checked: {:#}",
+            name, error
+        );
+    }
+    protection
+}
+
+/// Categories named with `--categories`, comma-separated and repeatable
+fn parse_categories(args: &[String]) -> Result<Vec<String>> {
+    let mut categories = Vec::new();
+    let mut iter = args.iter();
+
+    while let Some(arg) = iter.next() {
+        if arg == "--categories" {
+            let value = iter.next().context("--categories requires a value")?;
+            categories.extend(
+                value
+                    .split(',')
+                    .map(str::trim)
+                    .filter(|name| !name.is_empty())
+                    .map(String::from),
+            );
+        }
+    }
+    Ok(categories)
+}
+
+/// Paths to skip given with `--scan-exclude`, repeatable
+fn parse_scan_exclude(args: &[String]) -> Result<Vec<String>> {
+    let mut patterns = Vec::new();
+    let mut iter = args.iter();
+
+    while let Some(arg) = iter.next() {
+        if arg == "--scan-exclude" {
+            let value = iter.next().context("--scan-exclude requires a value")?;
+            patterns.push(value.clone());
+        }
+    }
+    Ok(patterns)
+}
+
+/// The config the CLI was run with, for its `categories` and `scan_exclude`
+fn invoking_config() -> Result<Config> {
+    match env::var("REPOS_CONFIG_FILE") {
+        Ok(path) if Path::new(&path).exists() => Config::load_config(&path),
+        _ => Ok(Config::default()),
+    }
+}
+
+/// One row per category with the checkers it runs
+fn categories_table(factory: &CheckerFactory) -> Table {
+    let mut table = Table::new(["CATEGORY", "CHECKERS", "SOURCE"]);
+    for category in factory.categories() {
+        table.add_row([
+            Cell::new(category.name),
+            Cell::new(category.checkers.join(", ")),
+            Cell::new(if category.custom {
+                "config"
+            } else {
+                "built-in"
+            }),
+        ]);
+    }
+    table
+}
+
+/// How `check` and `merge` print reports
+#[derive(Debug, Clone, Copy, PartialEq)]
+enum Format {
+    Text,
+    Json,
+    /// Slack mrkdwn summary listing the repositories scoring below `below`
+    Slack {
+        below: f64,
+    },
+    /// `::error`/`::warning` workflow commands for failing checks
+    GithubActions,
+}
+
+/// Parse `--format` (and `--slack-below` for the slack format) from the plugin arguments
+fn parse_format(args: &[String]) -> Result<Format> {
+    let mut format = Format::Text;
+    let mut below = health::WARNING_BELOW;
+    let mut iter = args.iter();
+
+    while let Some(arg) = iter.next() {
+        if arg == "--format" {
+            format = match iter.next().map(String::as_str) {
+                Some("text") => Format::Text,
+                Some("json") => Format::Json,
+                Some("slack") => Format::Slack { below: 0.0 },
+                Some("github-actions") => Format::GithubActions,
+                Some(other) => anyhow::bail!(
+                    "Unknown format '{}': expected text, json, slack or github-actions",
+                    other
+                ),
+                None => anyhow::bail!("--format requires a value"),
+            };
+        } else if arg == "--slack-below" {
+            let value = iter.next().context("--slack-below requires a value")?;
+            below = match value.trim_end_matches('%').parse::<f64>() {
+                Ok(percent) if (0.0..=100.0).contains(&percent) => percent / 100.0,
+                _ => anyhow::bail!(
+                    "Invalid --slack-below value '{}': expected a percentage between 0 and 100",
+                    value
+                ),
+            };
+        }
+    }
+    Ok(match format {
+        Format::Slack { .. } => Format::Slack { below },
+        other => other,
+    })
+}
+
+/// Report order for `--parallel` (with `--completion-order`), or `None` to check sequentially
+fn parse_parallel(args: &[String]) -> Option<ReportOrder> {
+    let parallel = args.iter().any(|arg| arg == "--parallel" || arg == "-p");
+    let completion = args.iter().any(|arg| arg == "--completion-order");
+    parallel.then_some(if completion {
+        ReportOrder::Completion
+    } else {
+        ReportOrder::Config
+    })
+}
+
+/// Whether `--verbose` asked for the checks ignored per repository
+fn parse_verbose(args: &[String]) -> bool {
+    args.iter().any(|arg| arg == "--verbose" || arg == "-v")
+}
+
+/// History file given with `--history-file`
+fn parse_history_file(args: &[String]) -> Result<Option<PathBuf>> {
+    let mut history_file = None;
+    let mut iter = args.iter();
+
+    while let Some(arg) = iter.next() {
+        if arg == "--history-file" {
+            let value = iter.next().context("--history-file requires a value")?;
+            history_file = Some(PathBuf::from(value));
+        }
+    }
+    Ok(history_file)
+}
+
+/// Report to compare a check against (`--baseline`) and what regressions mean for the exit code
+struct Baseline {
+    report: FleetReport,
+    /// `--fail-on-regression`: exit non-zero when anything regressed
+    fail_on_regression: bool,
+}
+
+/// Load the report given with `--baseline`
+fn parse_baseline(args: &[String]) -> Result<Option<Baseline>> {
+    let mut path = None;
+    let mut iter = args.iter();
+
+    while let Some(arg) = iter.next() {
+        if arg == "--baseline" {
+            let value = iter.next().context("--baseline requires a value")?;
+            path = Some(PathBuf::from(value));
+        }
+    }
+    let fail_on_regression = args.iter().any(|arg| arg == "--fail-on-regression");
+    match path {
+        Some(path) => Ok(Some(Baseline {
+            report: FleetReport::load(&path)?,
+            fail_on_regression,
+        })),
+        None if fail_on_regression => anyhow::bail!("--fail-on-regression requires --baseline"),
+        None => Ok(None),
+    }
+}
+
+/// The regression lines printed after a check, ending with their count
+fn render_regressions(regressions: &[Regression]) -> String {
+    if regressions.is_empty() {
+        return "health: no regressions against baseline\n".to_string();
+    }
+    let mut block = String::new();
+    for regression in regressions {
+        block.push_str(&format!("  - {}\n", regression));
+    }
+    block.push_str(&format!(
+        "health: {} regressions against baseline\n",
+        regressions.len()
+    ));
+    block
+}
+
+/// Report files given after `merge`, skipping options and their values
+fn parse_merge_files(args: &[String]) -> Result<Vec<PathBuf>> {
+    let mut files = Vec::new();
+    let mut iter = args.iter().skip_while(|arg| *arg != "merge").skip(1);
+
+    while let Some(arg) = iter.next() {
+        if arg == "--format" || arg == "--slack-below" {
+            iter.next();
+        } else if !arg.starts_with('-') {
+            files.push(PathBuf::from(arg));
+        }
+    }
+    if files.is_empty() {
+        anyhow::bail!("merge requires at least one health report file");
+    }
+    Ok(files)
+}
+
+fn run_health_checks(
+    repos: Vec<Repository>,
+    checkers: &[Box<dyn Checker>],
+    known_checks: &[String],
+    format: Format,
+    history_file: Option<&Path>,
+    parallel: Option<ReportOrder>,
+    baseline: Option<&Baseline>,
+    verbose: bool,
+) -> Result<()> {
+    let mut targets = Vec::new();
+    for repo in &repos {
+        let target = HealthTarget::from_repository(repo);
+        if !target.path.exists() {
+            eprintln!("health: {} skipped: not cloned", repo.name);
+            continue;
+        }
+        for check in unknown_checks(&target.ignore, known_checks) {
+            eprintln!(
+                "health: {} ignores unknown check '{}' (available: {})",
+                repo.name,
+                check,
+                known_checks.join(", ")
+            );
+        }
+        targets.push(target);
+    }
+
+    let (jobs, order) = match parallel {
+        Some(order) => (
+            std::thread::available_parallelism().map_or(4, usize::from),
+            order,
+        ),
+        None => (1, ReportOrder::Config),
+    };
+    // Text reports are printed whole as they are released, so parallel
+    // repositories never interleave
+    let reports = check_parallel(&targets, checkers, jobs, order, |report| {
+        if format == Format::Text {
+            let ignored = if verbose {
+                render_ignored(report)
+            } else {
+                String::new()
+            };
+            print!("{}{}", render_report(report), ignored);
+        }
+    });
+
+    if let Some(history_file) = history_file {
+        HistoryEntry::from_reports(chrono::Utc::now(), &reports).append(history_file)?;
+    }
+
+    let report = FleetReport::new(reports);
+    match format {
+        Format::Json => println!("{}", serde_json::to_string_pretty(&report)?),
+        Format::Slack { below } => print!("{}", report.slack_summary(below)),
+        Format::GithubActions => print!("{}", report.github_annotations()),
+        Format::Text => {
+            println!("health: checked {} repositories", report.repositories.len());
+        }
+    }
+
+    if let Some(baseline) = baseline {
+        let regressions = find_regressions(&baseline.report, &report);
+        // Keep JSON, Slack and workflow command output on stdout parseable
+        let rendered = render_regressions(&regressions);
+        if format == Format::Text {
+            print!("{}", rendered);
+        } else {
+            eprint!("{}", rendered);
+        }
+        if baseline.fail_on_regression && !regressions.is_empty() {
+            anyhow::bail!("health regressed against baseline");
+        }
+    }
+    Ok(())
+}
+
+fn run_merge(files: &[PathBuf], format: Format) -> Result<()> {
+    let reports = files
+        .iter()
+        .map(|file| FleetReport::load(file))
+        .collect::<Result<Vec<_>>>()?;
+    let merged = FleetReport::merge(reports);
+
+    match format {
+        Format::Json => println!("{}", serde_json::to_string_pretty(&merged)?),
+        Format::Slack { below } => print!("{}", merged.slack_summary(below)),
+        Format::GithubActions => print!("{}", merged.github_annotations()),
+        Format::Text => {
+            print_reports(&merged.repositories);
+            println!("health: merged {} reports: {}", files.len(), merged.summary);
+        }
+    }
+    Ok(())
+}
+
+fn run_trend(history_file: &Path) -> Result<()> {
+    let history = load_history(history_file)?;
+    let Some(latest) = history.last() else {
+        println!("health: no runs recorded in {}", history_file.display());
+        return Ok(());
+    };
+    println!(
+        "health: {} runs recorded, latest at {}",
+        history.len(),
+        latest.timestamp.format("%Y-%m-%d %H:%M:%S UTC")
+    );
+    trend_table(&score_trends(&history)).print();
+    Ok(())
+}
+
+/// One row per repository with its latest score and the change since the previous one
+fn trend_table(trends: &[ScoreTrend]) -> Table {
+    let mut table = Table::new(["REPOSITORY", "SCORE", "CHANGE"])
+        .align(1, Align::Right)
+        .align(2, Align::Right);
+    for trend in trends {
+        let change = match trend.delta() {
+            None => Cell::new("new"),
+            Some(delta) if delta > 0.0 => {
+                Cell::colored(format!("{:+.0}%", delta * 100.0), Color::Green)
+            }
+            Some(delta) if delta < 0.0 => {
+                Cell::colored(format!("{:+.0}%", delta * 100.0), Color::Red)
+            }
+            Some(_) => Cell::new("0%"),
+        };
+        table.add_row([
+            Cell::new(trend.repository.as_str()),
+            Cell::colored(
+                format!("{:.0}%", trend.score * 100.0),
+                score_color(trend.score),
+            ),
+            change,
+        ]);
+    }
+    table
+}
+
+/// Print each repository's table, findings and overall score
+fn print_reports(reports: &[HealthReport]) {
+    for report in reports {
+        print!("{}", render_report(report));
+    }
+}
+
+/// One repository's table, findings and overall score as a single block
+fn render_report(report: &HealthReport) -> String {
+    let mut block = format!("health: {}\n", report.repository);
+    block.push_str(&health_table(&report.results).render_for_terminal());
+    for result in &report.results {
+        for finding in &result.findings {
+            block.push_str(&format!("  - {}: {}\n", result.checker, finding));
+        }
+    }
+    if let Some(score) = overall_score(&report.results) {
+        block.push_str(&format!("  score: {:.0}%\n", score * 100.0));
+    }
+    block
+}
+
+/// The checkers skipped for the repository, for `--verbose`
+fn render_ignored(report: &HealthReport) -> String {
+    if report.ignored.is_empty() {
+        return String::new();
+    }
+    format!("  ignored: {}\n", report.ignored.join(", "))
+}
+
+/// Summary table with one row per check, scores colored by how much credit was earned
+fn health_table(results: &[CheckResult]) -> Table {
+    let mut table = Table::new(["CATEGORY", "CHECK", "SCORE"])
+        .align(2, Align::Right)
+        .indent(2);
+    for result in results {
+        table.add_row([
+            Cell::new(result.category.to_string()),
+            Cell::new(result.checker.as_str()),
+            Cell::colored(
+                format!("{:.0}%", result.score * 100.0),
+                score_color(result.score),
+            ),
+        ]);
+    }
+    table
+}
+
+fn score_color(score: f64) -> Color {
+    if score >= 1.0 {
+        Color::Green
+    } else if score > 0.0 {
+        Color::Yellow
+    } else {
+        Color::Red
+    }
+}
+
 async fn run_pr_report(repos: Vec<Repository>) -> Result<()> {
     let github_token = std::env::var("GITHUB_TOKEN").context("GITHUB_TOKEN not set")?;
     let mut reports = Vec::new();
@@ -360,6 +1040,7 @@ fn has_lockfile_changes(repo_path: &Path) -> Result<bool> {
 #[cfg(test)]
 mod tests {
     use super::*;
+    use std::collections::BTreeMap;
     use tempfile::TempDir;
 
     #[test]
@@ -370,6 +1051,331 @@ mod tests {
         // Test passes if print_help() completes without panicking
     }
 
+    #[test]
+    fn test_health_table_aligns_scores() {
+        let results = vec![
+            CheckResult::from_criteria("readme", health::Category::Documentation, 2, 4, vec![]),
+            CheckResult::from_criteria("license", health::Category::Documentation, 1, 1, vec![]),
+        ];
+        assert_eq!(
+            health_table(&results).render(false),
+            "  CATEGORY       CHECK    SCORE\n  \
+             documentation  readme     50%\n  \
+             documentation  license   100%\n"
+        );
+    }
+
+    #[test]
+    fn test_parse_categories() {
+        let args: Vec<String> = [
+            "check",
+            "--categories",
+            "compliance, security",
+            "--categories",
+            "infrastructure",
+        ]
+        .iter()
+        .map(|v| v.to_string())
+        .collect();
+        assert_eq!(
+            parse_categories(&args).unwrap(),
+            vec!["compliance", "security", "infrastructure"]
+        );
+        assert!(parse_categories(&["--categories".to_string()]).is_err());
+    }
+
+    #[test]
+    fn test_parse_scan_exclude() {
+        let args: Vec<String> = [
+            "check",
+            "--scan-exclude",
+            "vendor",
+            "--scan-exclude",
+            "**/gen",
+        ]
+        .iter()
+        .map(|v| v.to_string())
+        .collect();
+        assert_eq!(parse_scan_exclude(&args).unwrap(), vec!["vendor", "**/gen"]);
+        assert!(parse_scan_exclude(&["--scan-exclude".to_string()]).is_err());
+    }
+
+    #[test]
+    fn test_categories_table_includes_custom_categories() {
+        let mut custom = BTreeMap::new();
+        custom.insert(
+            "compliance".to_string(),
+            vec!["license".to_string(), "readme".to_string()],
+        );
+        let factory = CheckerFactory::new(ReadmeOptions::default(), DockerfileOptions::default())
+            .with_custom_categories(custom)
+            .unwrap();
+        let rendered = categories_table(&factory).render(false);
+        assert!(
+            rendered.contains("compliance      license, readme"),
+            "{}",
+            rendered
+        );
+        assert!(rendered.lines().last().unwrap().ends_with("config"));
+    }
+
+    #[test]
+    fn test_parse_format() {
+        let args =
+            |values: &[&str]| -> Vec<String> { values.iter().map(|v| v.to_string()).collect() };
+        assert_eq!(parse_format(&args(&["check"])).unwrap(), Format::Text);
+        assert_eq!(
+            parse_format(&args(&["check", "--format", "json"])).unwrap(),
+            Format::Json
+        );
+        assert_eq!(
+            parse_format(&args(&["check", "--format", "slack"])).unwrap(),
+            Format::Slack { below: 0.8 }
+        );
+        assert_eq!(
+            parse_format(&args(&["--slack-below", "60%", "--format", "slack"])).unwrap(),
+            Format::Slack { below: 0.6 }
+        );
+        assert_eq!(
+            parse_format(&args(&["check", "--format", "github-actions"])).unwrap(),
+            Format::GithubActions
+        );
+        assert!(parse_format(&args(&["check", "--format", "yaml"])).is_err());
+        assert!(parse_format(&args(&["check", "--format"])).is_err());
+        assert!(parse_format(&args(&["--format", "slack", "--slack-below", "120"])).is_err());
+    }
+
+    #[test]
+    fn test_parse_parallel() {
+        let args =
+            |values: &[&str]| -> Vec<String> { values.iter().map(|v| v.to_string()).collect() };
+        assert_eq!(parse_parallel(&args(&["check"])), None);
+        assert_eq!(
+            parse_parallel(&args(&["check", "-p"])),
+            Some(ReportOrder::Config)
+        );
+        assert_eq!(
+            parse_parallel(&args(&["check", "--parallel", "--completion-order"])),
+            Some(ReportOrder::Completion)
+        );
+    }
+
+    #[test]
+    fn test_verbose_lists_ignored_checks() {
+        let args =
+            |values: &[&str]| -> Vec<String> { values.iter().map(|v| v.to_string()).collect() };
+        assert!(!parse_verbose(&args(&["check"])));
+        assert!(parse_verbose(&args(&["check", "-v"])));
+        assert!(parse_verbose(&args(&["check", "--verbose"])));
+
+        let mut report = HealthReport {
+            repository: "api".to_string(),
+            results: Vec::new(),
+            ignored: Vec::new(),
+        };
+        assert_eq!(render_ignored(&report), "");
+        report.ignored = vec!["gitignore".to_string(), "signing".to_string()];
+        assert_eq!(render_ignored(&report), "  ignored: gitignore, signing\n");
+    }
+
+    #[test]
+    fn test_parallel_reports_print_as_contiguous_blocks() {
+        let temp_dir = TempDir::new().unwrap();
+        let targets: Vec<HealthTarget> = ["api", "web", "docs", "cli"]
+            .iter()
+            .map(|name| {
+                let path = temp_dir.path().join(name);
+                std::fs::create_dir(&path).unwrap();
+                HealthTarget::new(name.to_string(), path)
+            })
+            .collect();
+        let checkers = CheckerFactory::new(ReadmeOptions::default(), DockerfileOptions::default())
+            .for_categories(&["documentation".to_string()])
+            .unwrap();
+
+        for order in [ReportOrder::Config, ReportOrder::Completion] {
+            let mut output = String::new();
+            check_parallel(&targets, &checkers, 4, order, |report| {
+                output.push_str(&render_report(report))
+            });
+
+            // Every line up to the next header belongs to the same repository
+            let lines: Vec<&str> = output.lines().collect();
+            let headers: Vec<usize> = (0..lines.len())
+                .filter(|&i| lines[i].starts_with("health: "))
+                .collect();
+            assert_eq!(headers.len(), targets.len(), "{}", output);
+            for (i, &start) in headers.iter().enumerate() {
+                let end = headers.get(i + 1).copied().unwrap_or(lines.len());
+                let block = &lines[start..end];
+                assert!(block.iter().any(|line| line.contains("readme")));
+                assert!(block.iter().any(|line| line.contains("no LICENSE file")));
+                assert!(block.last().unwrap().starts_with("  score: "));
+            }
+            if order == ReportOrder::Config {
+                let names: Vec<&str> = headers.iter().map(|&i| &lines[i][8..]).collect();
+                assert_eq!(names, vec!["api", "web", "docs", "cli"]);
+            }
+        }
+    }
+
+    #[test]
+    fn test_parse_history_file() {
+        let args: Vec<String> = ["check", "--history-file", "health.jsonl"]
+            .iter()
+            .map(|v| v.to_string())
+            .collect();
+        assert_eq!(
+            parse_history_file(&args).unwrap(),
+            Some(PathBuf::from("health.jsonl"))
+        );
+        assert_eq!(parse_history_file(&["check".to_string()]).unwrap(), None);
+        assert!(parse_history_file(&["--history-file".to_string()]).is_err());
+    }
+
+    #[test]
+    fn test_parse_baseline() {
+        let fixtures =
+            Path::new(env!("CARGO_MANIFEST_DIR")).join("../../tests/fixtures/health/reports");
+        let team_a = fixtures.join("team-a.json").to_string_lossy().to_string();
+        let args = |values: &[&str]| values.iter().map(|v| v.to_string()).collect::<Vec<_>>();
+
+        let baseline = parse_baseline(&args(&["check", "--baseline", &team_a]))
+            .unwrap()
+            .unwrap();
+        assert_eq!(baseline.report.repositories.len(), 2);
+        assert!(!baseline.fail_on_regression);
+        let baseline = parse_baseline(&args(&["--fail-on-regression", "--baseline", &team_a]))
+            .unwrap()
+            .unwrap();
+        assert!(baseline.fail_on_regression);
+
+        assert!(parse_baseline(&args(&["check"])).unwrap().is_none());
+        assert!(parse_baseline(&args(&["--baseline"])).is_err());
+        assert!(parse_baseline(&args(&["--fail-on-regression"])).is_err());
+        let missing = fixtures.join("missing.json").to_string_lossy().to_string();
+        assert!(parse_baseline(&args(&["--baseline", &missing])).is_err());
+    }
+
+    #[test]
+    fn test_check_against_worse_baseline_fails_on_regression() {
+        let temp_dir = TempDir::new().unwrap();
+        let clone = temp_dir.path().join("api");
+        std::fs::create_dir(&clone).unwrap();
+        let mut repo = Repository::new("api".to_string(), "git@github.com:o/api.git".to_string());
+        repo.path = Some(clone.to_string_lossy().to_string());
+        let checkers: Vec<Box<dyn Checker>> = vec![Box::new(health::LicenseChecker)];
+
+        // The baseline had a license; the clone now has none
+        let baseline = |fail_on_regression| Baseline {
+            report: FleetReport::new(vec![HealthReport {
+                repository: "api".to_string(),
+                results: vec![CheckResult::from_criteria(
+                    "license",
+                    health::Category::Documentation,
+                    1,
+                    1,
+                    vec![],
+                )],
+                ignored: Vec::new(),
+            }]),
+            fail_on_regression,
+        };
+        let run = |baseline: &Baseline| {
+            run_health_checks(
+                vec![repo.clone()],
+                &checkers,
+                &[],
+                Format::Json,
+                None,
+                None,
+                Some(baseline),
+                false,
+            )
+        };
+        assert!(run(&baseline(false)).is_ok());
+        let err = run(&baseline(true)).unwrap_err();
+        assert_eq!(err.to_string(), "health regressed against baseline");
+
+        let report = FleetReport::new(check_parallel(
+            &[HealthTarget::new("api".to_string(), clone.clone())],
+            &checkers,
+            1,
+            ReportOrder::Config,
+            |_| {},
+        ));
+        assert_eq!(
+            render_regressions(&find_regressions(&baseline(true).report, &report)),
+            "  - api: score 100% -> 0%\n  \
+             - api: documentation 100% -> 0%\n\
+             health: 2 regressions against baseline\n"
+        );
+        assert_eq!(
+            render_regressions(&[]),
+            "health: no regressions against baseline\n"
+        );
+    }
+
+    #[test]
+    fn test_trend_table_shows_changes() {
+        let trends = vec![
+            ScoreTrend {
+                repository: "api".to_string(),
+                score: 0.75,
+                previous: Some(0.5),
+            },
+            ScoreTrend {
+                repository: "docs".to_string(),
+                score: 1.0,
+                previous: None,
+            },
+            ScoreTrend {
+                repository: "web".to_string(),
+                score: 0.5,
+                previous: Some(0.75),
+            },
+        ];
+        assert_eq!(
+            trend_table(&trends).render(false),
+            "REPOSITORY  SCORE  CHANGE\n\
+             api           75%    +25%\n\
+             docs         100%     new\n\
+             web           50%    -25%\n"
+        );
+    }
+
+    #[test]
+    fn test_parse_merge_files() {
+        let args: Vec<String> = [
+            "merge",
+            "a.json",
+            "--format",
+            "slack",
+            "--slack-below",
+            "50",
+            "b.json",
+        ]
+        .iter()
+        .map(|v| v.to_string())
+        .collect();
+        assert_eq!(
+            parse_merge_files(&args).unwrap(),
+            vec![PathBuf::from("a.json"), PathBuf::from("b.json")]
+        );
+        assert!(parse_merge_files(&["merge".to_string()]).is_err());
+    }
+
+    #[test]
+    fn test_run_merge_fixture_reports() {
+        let fixtures =
+            Path::new(env!("CARGO_MANIFEST_DIR")).join("../../tests/fixtures/health/reports");
+        let files = vec![fixtures.join("team-a.json"), fixtures.join("team-b.json")];
+        assert!(run_merge(&files, Format::Text).is_ok());
+        assert!(run_merge(&files, Format::Json).is_ok());
+        assert!(run_merge(&files, Format::Slack { below: 0.8 }).is_ok());
+        assert!(run_merge(&[fixtures.join("missing.json")], Format::Text).is_err());
+    }
+
     #[test]
     fn test_parse_github_repo_valid() {
         let url = "https://github.com/owner/repo.git";
@@ -494,9 +1500,7 @@ mod tests {
             name: "test-repo".to_string(),
             url: "https://github.com/test/repo.git".to_string(),
             path: Some(temp_dir.path().to_string_lossy().to_string()),
-            branch: None,
-            tags: vec![],
-            config_dir: None,
+            ..Default::default()
         };
 
         // This should hit the "no package.json" error path
@@ -505,15 +1509,120 @@ mod tests {
         assert!(result.unwrap_err().to_string().contains("no package.json"));
     }
 
+    #[test]
+    fn test_parse_readme_options_defaults() {
+        let options = parse_readme_options(&["check".to_string()]).unwrap();
+        assert_eq!(options, ReadmeOptions::default());
+    }
+
+    #[test]
+    fn test_parse_readme_options_overrides_sections() {
+        let args: Vec<String> = [
+            "check",
+            "--readme-section",
+            "usage",
+            "--readme-section",
+            "Contributing|Development",
+            "--readme-min-words",
+            "200",
+        ]
+        .iter()
+        .map(|s| s.to_string())
+        .collect();
+
+        let options = parse_readme_options(&args).unwrap();
+        assert_eq!(
+            options.sections,
+            vec![
+                vec!["usage".to_string()],
+                vec!["contributing".to_string(), "development".to_string()],
+            ]
+        );
+        assert_eq!(options.min_words, 200);
+    }
+
+    #[test]
+    fn test_parse_readme_options_rejects_invalid_values() {
+        let args = vec!["--readme-min-words".to_string(), "many".to_string()];
+        assert!(parse_readme_options(&args).is_err());
+        let args = vec!["--readme-section".to_string(), " | ".to_string()];
+        assert!(parse_readme_options(&args).is_err());
+        let args = vec!["--readme-section".to_string()];
+        assert!(parse_readme_options(&args).is_err());
+    }
+
+    #[test]
+    fn test_parse_dockerfile_options() {
+        let options = parse_dockerfile_options(&["check".to_string()]).unwrap();
+        assert_eq!(options, DockerfileOptions::default());
+
+        let args: Vec<String> = ["check", "--dockerfile-skip", "missing-healthcheck"]
+            .iter()
+            .map(|s| s.to_string())
+            .collect();
+        let options = parse_dockerfile_options(&args).unwrap();
+        assert_eq!(
+            options.rules,
+            vec![DockerfileRule::LatestTag, DockerfileRule::RootUser]
+        );
+
+        let args = vec!["--dockerfile-skip".to_string(), "no-sudo".to_string()];
+        assert!(parse_dockerfile_options(&args).is_err());
+    }
+
+    #[test]
+    fn test_parse_signing_options() {
+        let options = parse_signing_options(&["check".to_string()]).unwrap();
+        assert_eq!(options, SigningOptions::default());
+
+        let args: Vec<String> = [
+            "check",
+            "--signing-commits",
+            "50",
+            "--signing-min-ratio",
+            "80%",
+        ]
+        .iter()
+        .map(|s| s.to_string())
+        .collect();
+        let options = parse_signing_options(&args).unwrap();
+        assert_eq!(options.commits, 50);
+        assert_eq!(options.min_signed_ratio, 0.8);
+
+        let args = vec!["--signing-commits".to_string(), "0".to_string()];
+        assert!(parse_signing_options(&args).is_err());
+        let args = vec!["--signing-min-ratio".to_string(), "120".to_string()];
+        assert!(parse_signing_options(&args).is_err());
+        assert!(parse_signing_options(&["--signing-min-ratio".to_string()]).is_err());
+    }
+
+    #[test]
+    fn test_parse_branching_options() {
+        let options = parse_branching_options(&["check".to_string()]).unwrap();
+        assert_eq!(options, BranchingOptions::default());
+
+        let args: Vec<String> = [
+            "check",
+            "--allowed-branch",
+            "main, master",
+            "--allowed-branch",
+            "trunk",
+        ]
+        .iter()
+        .map(|s| s.to_string())
+        .collect();
+        let options = parse_branching_options(&args).unwrap();
+        assert_eq!(options.allowed, vec!["main", "master", "trunk"]);
+
+        assert!(parse_branching_options(&["--allowed-branch".to_string()]).is_err());
+    }
+
     #[tokio::test]
     async fn test_fetch_pr_report_invalid_url() {
         let repo = Repository {
             name: "test".to_string(),
             url: "invalid".to_string(),
-            path: None,
-            branch: None,
-            tags: vec![],
-            config_dir: None,
+            ..Default::default()
         };
 
         let result = fetch_pr_report(&repo, "fake-token").await;
@@ -0,0 +1,282 @@
+//! Stable entry points for using repos as a library
+//!
+//! [`Repos`] wraps a loaded configuration and offers the operations of the
+//! CLI as plain functions: select repositories, clone or pull them, run a
+//! command in each and score their health. Results are returned per
+//! repository instead of being printed as a summary, so an embedding program
+//! decides how to report them.
+//!
+//! ```rust,no_run
+//! use repos::{RepoFilter, Repos};
+//!
+//! # async fn example() -> repos::Result<()> {
+//! let repos = Repos::load("repos.yaml")?;
+//! let backend = repos.select(&RepoFilter::tagged(["backend"]));
+//!
+//! for output in repos.run(&backend, "git status --short").await {
+//!     match output.result {
+//!         Ok(run) => println!("{}: exit {}", output.repository, run.exit_code),
+//!         Err(e) => eprintln!("{}: {}", output.repository, e),
+//!     }
+//! }
+//! # Ok(())
+//! # }
+//! ```
+//!
+//! The lower-level modules ([`crate::config`], [`crate::git`],
+//! [`crate::health`], [`crate::runner`]) stay public for callers that need
+//! more control.
+
+use crate::config::{Config, Repository};
+use crate::git::{self, CloneOptions, CloneOutcome, PullOptions};
+use crate::health::{self, DockerfileOptions, HealthReport, ReadmeOptions};
+use crate::runner::{CommandRunner, Shell};
+use anyhow::Result;
+use std::path::Path;
+
+/// Which repositories of the configuration an operation applies to
+///
+/// The empty filter selects every repository. Tags and names combine the same
+/// way as the CLI's `--tag`, `--exclude-tag` and repository arguments.
+#[derive(Debug, Clone, Default, PartialEq, Eq)]
+pub struct RepoFilter {
+    /// Keep repositories with any of these tags
+    pub tags: Vec<String>,
+    /// Drop repositories with any of these tags
+    pub exclude_tags: Vec<String>,
+    /// Keep only repositories with these names
+    pub names: Vec<String>,
+}
+
+impl RepoFilter {
+    /// Repositories with any of `tags`
+    pub fn tagged<I, S>(tags: I) -> Self
+    where
+        I: IntoIterator<Item = S>,
+        S: Into<String>,
+    {
+        Self {
+            tags: tags.into_iter().map(Into::into).collect(),
+            ..Self::default()
+        }
+    }
+
+    /// Also drop repositories with any of `tags`
+    pub fn excluding<I, S>(mut self, tags: I) -> Self
+    where
+        I: IntoIterator<Item = S>,
+        S: Into<String>,
+    {
+        self.exclude_tags = tags.into_iter().map(Into::into).collect();
+        self
+    }
+
+    /// Only keep repositories with these names
+    pub fn named<I, S>(mut self, names: I) -> Self
+    where
+        I: IntoIterator<Item = S>,
+        S: Into<String>,
+    {
+        self.names = names.into_iter().map(Into::into).collect();
+        self
+    }
+}
+
+/// Outcome of an operation for one repository
+#[derive(Debug)]
+pub struct RepoResult<T> {
+    pub repository: String,
+    pub result: Result<T>,
+}
+
+/// Captured output of a command run in one repository
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct RunOutput {
+    pub stdout: String,
+    pub stderr: String,
+    pub exit_code: i32,
+}
+
+impl RunOutput {
+    pub fn success(&self) -> bool {
+        self.exit_code == 0
+    }
+}
+
+/// A loaded configuration and the operations available on it
+#[derive(Debug, Clone)]
+pub struct Repos {
+    config: Config,
+}
+
+impl Repos {
+    /// Load and validate the configuration file at `path`
+    pub fn load(path: impl AsRef<Path>) -> Result<Self> {
+        let path = path.as_ref();
+        let path = path
+            .to_str()
+            .ok_or_else(|| anyhow::anyhow!("Config path is not valid UTF-8: {}", path.display()))?;
+        Ok(Self::from_config(Config::load_config(path)?))
+    }
+
+    /// Wrap a configuration built in code
+    pub fn from_config(config: Config) -> Self {
+        Self { config }
+    }
+
+    pub fn config(&self) -> &Config {
+        &self.config
+    }
+
+    /// Every configured repository, in config order
+    pub fn repositories(&self) -> &[Repository] {
+        &self.config.repositories
+    }
+
+    /// The repositories matching `filter`, in config order
+    pub fn select(&self, filter: &RepoFilter) -> Vec<Repository> {
+        let names = (!filter.names.is_empty()).then_some(filter.names.as_slice());
+        self.config
+            .filter_repositories(&filter.tags, &filter.exclude_tags, names)
+    }
+
+    /// Clone each repository that is not cloned yet
+    pub fn clone_repositories(
+        &self,
+        repositories: &[Repository],
+        options: CloneOptions,
+    ) -> Vec<RepoResult<CloneOutcome>> {
+        for_each(repositories, |repo| {
+            git::clone_repository_with(repo, &options)
+        })
+    }
+
+    /// Pull the current branch of each cloned repository
+    ///
+    /// A repository's `pull_strategy` takes precedence over `options.strategy`.
+    /// A pull that stops on conflicts fails with a [`git::MergeConflict`], and
+    /// one skipped under [`git::LockMode::Skip`] with a [`git::RepositoryLocked`].
+    pub fn pull_repositories(
+        &self,
+        repositories: &[Repository],
+        options: PullOptions,
+    ) -> Vec<RepoResult<()>> {
+        for_each(repositories, |repo| {
+            git::pull_repository_with(repo, &options.for_repository(repo))
+        })
+    }
+
+    /// Run a shell command in each repository, one after another, capturing its output
+    ///
+    /// The command runs with the config's `defaults.shell`, if set. A command
+    /// the config's `run_policy` refuses does not run at all, and every
+    /// repository gets the refusal as its error.
+    ///
+    /// A non-zero exit code is returned in the [`RunOutput`], not as an error;
+    /// errors mean the command could not be run (e.g. the repository is not
+    /// cloned).
+    pub async fn run(
+        &self,
+        repositories: &[Repository],
+        command: &str,
+    ) -> Vec<RepoResult<RunOutput>> {
+        let shell = self
+            .config
+            .defaults
+            .shell
+            .as_deref()
+            .map(Shell::new)
+            .unwrap_or_default();
+        let mut results = Vec::with_capacity(repositories.len());
+        for repo in repositories {
+            let checked = self.config.run_policy.check(command);
+            let result = match checked.and_then(|()| repo.timeout()) {
+                // Buffered so the runner's progress lines stay out of the caller's output
+                Ok(timeout) => CommandRunner::new()
+                    .with_timeout(timeout)
+                    .with_shell(shell.clone())
+                    .with_buffered_output()
+                    .run_command_with_capture_no_logs(repo, command, None)
+                    .await
+                    .map(|(stdout, stderr, exit_code)| RunOutput {
+                        stdout,
+                        stderr,
+                        exit_code,
+                    }),
+                Err(e) => Err(e),
+            };
+            results.push(RepoResult {
+                repository: repo.name.clone(),
+                result,
+            });
+        }
+        results
+    }
+
+    /// Score each cloned repository with the default health checks
+    ///
+    /// Repositories that are not cloned get no report.
+    pub fn health(&self, repositories: &[Repository]) -> Vec<HealthReport> {
+        let checkers =
+            health::default_checkers(ReadmeOptions::default(), DockerfileOptions::default());
+        health::check_all_repositories(repositories, &checkers)
+    }
+}
+
+fn for_each<T>(
+    repositories: &[Repository],
+    operation: impl Fn(&Repository) -> Result<T>,
+) -> Vec<RepoResult<T>> {
+    repositories
+        .iter()
+        .map(|repo| RepoResult {
+            repository: repo.name.clone(),
+            result: operation(repo),
+        })
+        .collect()
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn repos() -> Repos {
+        let mut config = Config::new();
+        for (name, tags) in [
+            ("api", vec!["backend"]),
+            ("web", vec!["frontend"]),
+            ("worker", vec!["backend", "legacy"]),
+        ] {
+            let mut repo =
+                Repository::new(name.to_string(), format!("git@github.com:o/{name}.git"));
+            repo.tags = tags.into_iter().map(String::from).collect();
+            config.repositories.push(repo);
+        }
+        Repos::from_config(config)
+    }
+
+    fn names(repositories: &[Repository]) -> Vec<&str> {
+        repositories.iter().map(|repo| repo.name.as_str()).collect()
+    }
+
+    #[test]
+    fn test_select_combines_tags_and_names() {
+        let repos = repos();
+        assert_eq!(
+            names(&repos.select(&RepoFilter::default())),
+            vec!["api", "web", "worker"]
+        );
+        assert_eq!(
+            names(&repos.select(&RepoFilter::tagged(["backend"]))),
+            vec!["api", "worker"]
+        );
+        assert_eq!(
+            names(&repos.select(&RepoFilter::tagged(["backend"]).excluding(["legacy"]))),
+            vec!["api"]
+        );
+        assert_eq!(
+            names(&repos.select(&RepoFilter::default().named(["web"]))),
+            vec!["web"]
+        );
+    }
+}
@@ -0,0 +1,152 @@
+//! Repositories currently running in a parallel batch (`--show-active`)
+//!
+//! Each worker marks its repository active for as long as it runs, and a
+//! heartbeat task prints the set to stderr at a fixed interval, so a slow
+//! repository stands out while the rest of the batch streams its output.
+
+use colored::Colorize;
+use std::collections::BTreeSet;
+use std::future::Future;
+use std::sync::{Arc, Mutex};
+use std::time::Duration;
+
+/// How often `--show-active` prints the repositories still running
+pub const ACTIVE_HEARTBEAT: Duration = Duration::from_secs(5);
+
+/// Names of the repositories whose work is in progress, shared between workers
+#[derive(Debug, Clone, Default)]
+pub struct ActiveSet {
+    names: Arc<Mutex<BTreeSet<String>>>,
+}
+
+/// Keeps a repository in its [`ActiveSet`] until dropped
+#[must_use = "the repository leaves the active set when the guard is dropped"]
+pub struct ActiveGuard {
+    set: ActiveSet,
+    name: String,
+}
+
+impl ActiveSet {
+    pub fn new() -> Self {
+        Self::default()
+    }
+
+    /// Mark `name` active until the returned guard is dropped
+    pub fn enter(&self, name: &str) -> ActiveGuard {
+        self.lock().insert(name.to_string());
+        ActiveGuard {
+            set: self.clone(),
+            name: name.to_string(),
+        }
+    }
+
+    /// The active repositories, sorted by name
+    pub fn snapshot(&self) -> Vec<String> {
+        self.lock().iter().cloned().collect()
+    }
+
+    /// Await `work`, printing the active repositories to stderr every `interval`
+    pub async fn report_while<F: Future>(&self, interval: Duration, work: F) -> F::Output {
+        let heartbeat = tokio::spawn({
+            let active = self.clone();
+            async move {
+                let mut ticker = tokio::time::interval(interval);
+                // The first tick completes immediately, before anything is slow
+                ticker.tick().await;
+                loop {
+                    ticker.tick().await;
+                    let names = active.snapshot();
+                    if !names.is_empty() {
+                        eprintln!(
+                            "{}",
+                            format!("Active ({}): {}", names.len(), names.join(", ")).dimmed()
+                        );
+                    }
+                }
+            }
+        });
+        let output = work.await;
+        heartbeat.abort();
+        output
+    }
+
+    fn lock(&self) -> std::sync::MutexGuard<'_, BTreeSet<String>> {
+        // A worker that panicked while holding the lock leaves a usable set
+        self.names
+            .lock()
+            .unwrap_or_else(|poisoned| poisoned.into_inner())
+    }
+}
+
+impl Drop for ActiveGuard {
+    fn drop(&mut self) {
+        self.set.lock().remove(&self.name);
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::commands::join_limited;
+
+    #[test]
+    fn test_guard_adds_and_removes() {
+        let active = ActiveSet::new();
+        let api = active.enter("api");
+        let web = active.enter("web");
+        assert_eq!(active.snapshot(), vec!["api", "web"]);
+
+        drop(api);
+        assert_eq!(active.snapshot(), vec!["web"]);
+        drop(web);
+        assert!(active.snapshot().is_empty());
+    }
+
+    #[tokio::test(flavor = "multi_thread", worker_threads = 4)]
+    async fn test_concurrent_workers_leave_set_empty() {
+        let active = ActiveSet::new();
+        let peak = Arc::new(Mutex::new(0));
+
+        let tasks: Vec<_> = (0..32)
+            .map(|i| {
+                let active = active.clone();
+                let peak = peak.clone();
+                tokio::spawn(async move {
+                    let _guard = active.enter(&format!("repo-{:02}", i));
+                    tokio::time::sleep(Duration::from_millis(5)).await;
+                    let running = active.snapshot().len();
+                    let mut peak = peak.lock().unwrap();
+                    *peak = (*peak).max(running);
+                })
+            })
+            .collect();
+        for task in tasks {
+            task.await.unwrap();
+        }
+
+        assert!(active.snapshot().is_empty());
+        assert!(*peak.lock().unwrap() > 1);
+    }
+
+    #[tokio::test]
+    async fn test_active_set_tracks_limited_batch() {
+        let active = ActiveSet::new();
+        let tasks: Vec<_> = (0..6)
+            .map(|i| {
+                let active = active.clone();
+                async move {
+                    let _guard = active.enter(&format!("repo-{}", i));
+                    tokio::time::sleep(Duration::from_millis(10)).await;
+                    // Never more in the set than --jobs allows to run
+                    active.snapshot().len()
+                }
+            })
+            .collect();
+
+        let seen = active
+            .report_while(Duration::from_millis(1), join_limited(tasks, Some(2)))
+            .await;
+        assert!(seen.iter().all(|&running| (1..=2).contains(&running)));
+        assert!(active.snapshot().is_empty());
+    }
+}
@@ -1,5 +1,6 @@
 //! Base types and traits for the command pattern
 
+use super::report::OutcomeRecorder;
 use crate::config::Config;
 use anyhow::Result;
 
@@ -16,6 +17,60 @@ pub struct CommandContext {
     pub parallel: bool,
     /// Optional list of specific repository names to operate on
     pub repos: Option<Vec<String>>,
+    /// Collector for per-repository outcomes used in run reports
+    pub outcomes: OutcomeRecorder,
+    /// Maximum number of repositories processed at once in parallel mode
+    /// (`None` processes all of them at once)
+    pub jobs: Option<usize>,
+}
+
+impl CommandContext {
+    /// `message` followed by a "did you mean" hint for `--tag` values no
+    /// repository has, if any known tag is close
+    pub fn with_tag_hint(&self, message: &str) -> String {
+        match self.config.tag_hint(&self.tag) {
+            Some(hint) => format!("{message}; {hint}"),
+            None => message.to_string(),
+        }
+    }
+}
+
+/// Concurrency limits from `--jobs`, `--clone-jobs` and `--run-jobs`
+///
+/// The command-specific limit wins; otherwise the global `--jobs` applies.
+#[derive(Debug, Clone, Copy, Default, PartialEq, Eq)]
+pub struct JobLimits {
+    pub jobs: Option<usize>,
+    pub clone_jobs: Option<usize>,
+    pub run_jobs: Option<usize>,
+}
+
+impl JobLimits {
+    /// Effective limit for `clone`
+    pub fn for_clone(&self) -> Option<usize> {
+        self.clone_jobs.or(self.jobs)
+    }
+
+    /// Effective limit for `run`
+    pub fn for_run(&self) -> Option<usize> {
+        self.run_jobs.or(self.jobs)
+    }
+}
+
+/// Drive `tasks` concurrently with at most `limit` in flight
+///
+/// Results are returned in completion order.
+pub async fn join_limited<F>(tasks: Vec<F>, limit: Option<usize>) -> Vec<F::Output>
+where
+    F: std::future::Future,
+{
+    use futures::StreamExt;
+
+    let limit = limit.unwrap_or(tasks.len()).max(1);
+    futures::stream::iter(tasks)
+        .buffer_unordered(limit)
+        .collect()
+        .await
 }
 
 /// Trait that all commands must implement
@@ -24,3 +79,86 @@ pub trait Command {
     /// Execute the command with the given context
     async fn execute(&self, context: &CommandContext) -> Result<()>;
 }
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use std::sync::Arc;
+    use std::sync::atomic::{AtomicUsize, Ordering};
+
+    #[test]
+    fn test_with_tag_hint_suggests_known_tags() {
+        let mut repo = crate::config::Repository::new("api".to_string(), "url".to_string());
+        repo.tags = vec!["backend".to_string()];
+        let mut context = CommandContext {
+            config: Config {
+                repositories: vec![repo],
+                ..Config::default()
+            },
+            tag: vec!["backnd".to_string()],
+            exclude_tag: vec![],
+            parallel: false,
+            repos: None,
+            outcomes: OutcomeRecorder::new(),
+            jobs: None,
+        };
+        assert_eq!(
+            context.with_tag_hint("No repositories found"),
+            "No repositories found; did you mean 'backend'?"
+        );
+
+        context.tag = vec!["mobile".to_string()];
+        assert_eq!(
+            context.with_tag_hint("No repositories found"),
+            "No repositories found"
+        );
+    }
+
+    #[test]
+    fn test_command_specific_jobs_override_global() {
+        let limits = JobLimits {
+            jobs: Some(4),
+            clone_jobs: Some(16),
+            run_jobs: Some(2),
+        };
+        assert_eq!(limits.for_clone(), Some(16));
+        assert_eq!(limits.for_run(), Some(2));
+    }
+
+    #[test]
+    fn test_jobs_fall_back_to_global() {
+        let limits = JobLimits {
+            jobs: Some(4),
+            clone_jobs: None,
+            run_jobs: Some(2),
+        };
+        assert_eq!(limits.for_clone(), Some(4));
+        assert_eq!(limits.for_run(), Some(2));
+        assert_eq!(JobLimits::default().for_run(), None);
+    }
+
+    #[tokio::test]
+    async fn test_join_limited_caps_concurrency() {
+        let running = Arc::new(AtomicUsize::new(0));
+        let peak = Arc::new(AtomicUsize::new(0));
+
+        let tasks: Vec<_> = (0..8)
+            .map(|i| {
+                let running = running.clone();
+                let peak = peak.clone();
+                async move {
+                    let now = running.fetch_add(1, Ordering::SeqCst) + 1;
+                    peak.fetch_max(now, Ordering::SeqCst);
+                    tokio::time::sleep(std::time::Duration::from_millis(10)).await;
+                    running.fetch_sub(1, Ordering::SeqCst);
+                    i
+                }
+            })
+            .collect();
+
+        let mut results = join_limited(tasks, Some(3)).await;
+        results.sort();
+        assert_eq!(results, (0..8).collect::<Vec<_>>());
+        assert_eq!(peak.load(Ordering::SeqCst), 3);
+    }
+}
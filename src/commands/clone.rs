@@ -1,13 +1,30 @@
 //! Clone command implementation
 
 use super::{Command, CommandContext};
+use crate::config::Repository;
 use crate::git;
+use crate::github::order_by_size;
+use crate::utils::table::Table;
 use anyhow::Result;
 use async_trait::async_trait;
 use colored::*;
+use std::collections::BTreeMap;
+use std::path::PathBuf;
+use std::sync::Arc;
+use std::time::Instant;
+use tokio::sync::Semaphore;
 
 /// Clone command for cloning repositories
-pub struct CloneCommand;
+#[derive(Debug, Default)]
+pub struct CloneCommand {
+    pub options: git::CloneOptions,
+    /// Print where each repository would be cloned instead of cloning it
+    pub print_paths: bool,
+    /// Sizes in kilobytes by repository name; when set, smaller repositories are cloned first
+    pub sizes: Option<BTreeMap<String, u64>>,
+    /// Archived repositories left out of the selection, reported in the summary
+    pub archived_skipped: usize,
+}
 
 #[async_trait]
 impl Command for CloneCommand {
@@ -37,13 +54,20 @@ impl Command for CloneCommand {
                 filter_parts.join(" and ")
             };
 
-            println!(
-                "{}",
-                format!("No repositories found with {filter_desc}").yellow()
-            );
+            let message = format!("No repositories found with {filter_desc}");
+            println!("{}", context.with_tag_hint(&message).yellow());
             return Ok(());
         }
 
+        if self.print_paths {
+            paths_table(&repositories).print();
+            return Ok(());
+        }
+
+        let repositories = match &self.sizes {
+            Some(sizes) => order_by_size(repositories, sizes),
+            None => repositories,
+        };
         println!(
             "{}",
             format!("Cloning {} repositories...", repositories.len()).green()
@@ -51,25 +75,43 @@ impl Command for CloneCommand {
 
         let mut errors = Vec::new();
         let mut successful = 0;
+        let mut existing = ExistingClones::default();
 
         if context.parallel {
+            let permits = Arc::new(Semaphore::new(
+                context.jobs.unwrap_or(repositories.len()).max(1),
+            ));
             let tasks: Vec<_> = repositories
                 .into_iter()
                 .map(|repo| {
                     let repo_name = repo.name.clone();
+                    let outcomes = context.outcomes.clone();
+                    let permits = permits.clone();
+                    let options = self.options;
                     tokio::spawn(async move {
-                        let result =
-                            tokio::task::spawn_blocking(move || git::clone_repository(&repo))
-                                .await?;
-                        Ok::<_, anyhow::Error>((repo_name, result))
+                        let _permit = permits.acquire_owned().await?;
+                        if outcomes.skip_if_aborted(&repo_name) {
+                            return Ok(None);
+                        }
+                        let started = Instant::now();
+                        let result = tokio::task::spawn_blocking(move || {
+                            git::clone_repository_with(&repo, &options)
+                        })
+                        .await?;
+                        outcomes.record_result(&repo_name, &result, started.elapsed());
+                        Ok::<_, anyhow::Error>(Some((repo_name, result)))
                     })
                 })
                 .collect();
 
             for task in tasks {
                 match task.await? {
-                    Ok((_, Ok(_))) => successful += 1,
-                    Ok((repo_name, Err(e))) => {
+                    Ok(None) => {}
+                    Ok(Some((repo_name, Ok(outcome)))) => {
+                        successful += 1;
+                        existing.record(repo_name, outcome);
+                    }
+                    Ok(Some((repo_name, Err(e)))) => {
                         eprintln!("{}", format!("Error: {e}").red());
                         errors.push((repo_name, e));
                     }
@@ -81,14 +123,26 @@ impl Command for CloneCommand {
             }
         } else {
             for repo in repositories {
+                if context.outcomes.skip_if_aborted(&repo.name) {
+                    continue;
+                }
                 let repo_name = repo.name.clone();
-                match tokio::task::spawn_blocking({
+                let started = Instant::now();
+                let result = tokio::task::spawn_blocking({
                     let repo = repo.clone();
-                    move || git::clone_repository(&repo)
+                    let options = self.options;
+                    move || git::clone_repository_with(&repo, &options)
                 })
-                .await?
-                {
-                    Ok(_) => successful += 1,
+                .await?;
+                context
+                    .outcomes
+                    .record_result(&repo_name, &result, started.elapsed());
+
+                match result {
+                    Ok(outcome) => {
+                        successful += 1;
+                        existing.record(repo_name, outcome);
+                    }
                     Err(e) => {
                         eprintln!("{}", format!("Error: {e}").red());
                         errors.push((repo_name, e));
@@ -98,15 +152,24 @@ impl Command for CloneCommand {
         }
 
         // Report summary
+        existing.print();
+        let archived = match self.archived_skipped {
+            0 => String::new(),
+            count => format!(", {} archived skipped", count),
+        };
         if errors.is_empty() {
-            println!("{}", "Done cloning repositories".green());
+            println!(
+                "{}",
+                format!("Done cloning repositories{}", archived).green()
+            );
         } else {
             println!(
                 "{}",
                 format!(
-                    "Completed with {} successful, {} failed",
+                    "Completed with {} successful, {} failed{}",
                     successful,
-                    errors.len()
+                    errors.len(),
+                    archived
                 )
                 .yellow()
             );
@@ -124,9 +187,60 @@ impl Command for CloneCommand {
     }
 }
 
+/// The absolute directory a repository is cloned into
+///
+/// Relative targets (a repository's `path`, or its name) are resolved against
+/// the config file's directory, and that against the current directory.
+fn resolved_path(repo: &Repository) -> PathBuf {
+    let target = PathBuf::from(repo.get_target_dir());
+    std::path::absolute(&target).unwrap_or(target)
+}
+
+fn paths_table(repositories: &[Repository]) -> Table {
+    let mut table = Table::new(["REPOSITORY", "PATH"]);
+    for repo in repositories {
+        table.add_row([repo.name.clone(), resolved_path(repo).display().to_string()]);
+    }
+    table
+}
+
+/// Repositories that were already cloned, by what happened to them
+#[derive(Debug, Default)]
+struct ExistingClones {
+    updated: Vec<String>,
+    skipped: Vec<String>,
+}
+
+impl ExistingClones {
+    fn record(&mut self, repo_name: String, outcome: git::CloneOutcome) {
+        match outcome {
+            git::CloneOutcome::Cloned => {}
+            git::CloneOutcome::Updated => self.updated.push(repo_name),
+            git::CloneOutcome::Skipped => self.skipped.push(repo_name),
+        }
+    }
+
+    fn print(&mut self) {
+        for (label, names) in [
+            ("Updated", &mut self.updated),
+            ("Skipped", &mut self.skipped),
+        ] {
+            if names.is_empty() {
+                continue;
+            }
+            names.sort();
+            println!(
+                "{}",
+                format!("{} {} existing: {}", label, names.len(), names.join(", ")).yellow()
+            );
+        }
+    }
+}
+
 #[cfg(test)]
 mod tests {
     use super::*;
+    use crate::commands::OutcomeRecorder;
     use crate::config::{Config, Repository};
 
     /// Helper function to create a test config with repositories
@@ -151,7 +265,8 @@ mod tests {
 
         Config {
             repositories: vec![repo1, repo2, repo3],
-            recipes: vec![],
+            version: None,
+            ..Default::default()
         }
     }
 
@@ -168,13 +283,121 @@ mod tests {
             exclude_tag: Vec::new(),
             repos,
             parallel,
+            outcomes: OutcomeRecorder::new(),
+            jobs: None,
+        }
+    }
+
+    fn repository_in(config_dir: &str, name: &str, path: Option<&str>) -> Repository {
+        let mut repo = Repository::new(
+            name.to_string(),
+            format!("git@github.com:acme/{}.git", name),
+        );
+        repo.path = path.map(str::to_string);
+        repo.set_config_dir(Some(PathBuf::from(config_dir)));
+        repo
+    }
+
+    #[test]
+    fn test_resolved_path_flat_layout() {
+        let repo = repository_in("/work/fleet", "api", None);
+        assert_eq!(resolved_path(&repo), PathBuf::from("/work/fleet/api"));
+    }
+
+    #[test]
+    fn test_resolved_path_overrides() {
+        let absolute = repository_in("/work/fleet", "api", Some("/srv/checkouts/api"));
+        assert_eq!(
+            resolved_path(&absolute),
+            PathBuf::from("/srv/checkouts/api")
+        );
+
+        let relative = repository_in("/work/fleet", "api", Some("./services/api-v2"));
+        assert_eq!(
+            resolved_path(&relative),
+            PathBuf::from("/work/fleet/services/api-v2")
+        );
+    }
+
+    #[test]
+    fn test_resolved_path_org_layout() {
+        let repos = [
+            repository_in("/work", "api", Some("acme/api")),
+            repository_in("/work", "api-fork", Some("contoso/api")),
+        ];
+        let rendered = paths_table(&repos).render(false);
+        assert_eq!(
+            rendered.lines().collect::<Vec<_>>(),
+            vec![
+                "REPOSITORY  PATH",
+                "api         /work/acme/api",
+                "api-fork    /work/contoso/api",
+            ]
+        );
+    }
+
+    #[test]
+    fn test_resolved_path_without_config_dir_is_absolute() {
+        let repo = Repository::new("api".to_string(), "git@github.com:acme/api.git".to_string());
+        let path = resolved_path(&repo);
+        assert!(path.is_absolute());
+        assert!(path.ends_with("api"));
+    }
+
+    #[tokio::test]
+    async fn test_print_paths_does_not_clone() {
+        let temp_dir = tempfile::TempDir::new().unwrap();
+        let mut config = create_test_config();
+        for repo in &mut config.repositories {
+            repo.set_config_dir(Some(temp_dir.path().to_path_buf()));
+        }
+        let command = CloneCommand {
+            print_paths: true,
+            ..Default::default()
+        };
+
+        let context = create_context(config, vec![], None, false);
+        command.execute(&context).await.unwrap();
+        assert_eq!(std::fs::read_dir(temp_dir.path()).unwrap().count(), 0);
+        assert!(context.outcomes.outcomes().is_empty());
+    }
+
+    #[tokio::test]
+    async fn test_sizes_schedule_smaller_repositories_first() {
+        let temp_dir = tempfile::TempDir::new().unwrap();
+        let mut config = create_test_config();
+        for repo in &mut config.repositories {
+            repo.set_config_dir(Some(temp_dir.path().to_path_buf()));
+            // Fails straight away without touching the network
+            repo.url = temp_dir.path().join("missing").display().to_string();
+        }
+        let command = CloneCommand {
+            sizes: Some(BTreeMap::from([
+                ("test-repo-1".to_string(), 90_000),
+                ("test-repo-3".to_string(), 40),
+            ])),
+            ..Default::default()
+        };
+
+        for parallel in [false, true] {
+            let mut context = create_context(config.clone(), vec![], None, parallel);
+            // One worker takes the queue in order
+            context.jobs = Some(1);
+            assert!(command.execute(&context).await.is_err());
+            let order: Vec<String> = context
+                .outcomes
+                .outcomes()
+                .into_iter()
+                .map(|outcome| outcome.name)
+                .collect();
+            assert_eq!(order, vec!["test-repo-3", "test-repo-1", "test-repo-2"]);
         }
     }
 
     #[tokio::test]
     async fn test_clone_command_no_repositories() {
         let config = create_test_config();
-        let command = CloneCommand;
+        let command = CloneCommand::default();
 
         // Test with tag that doesn't match any repository
         let context = create_context(config, vec!["nonexistent".to_string()], None, false);
@@ -187,7 +410,7 @@ mod tests {
     #[tokio::test]
     async fn test_clone_command_with_tag_filter() {
         let config = create_test_config();
-        let command = CloneCommand;
+        let command = CloneCommand::default();
 
         // Test with tag that matches some repositories
         let context = create_context(config, vec!["frontend".to_string()], None, false);
@@ -201,7 +424,7 @@ mod tests {
     #[tokio::test]
     async fn test_clone_command_with_repo_filter() {
         let config = create_test_config();
-        let command = CloneCommand;
+        let command = CloneCommand::default();
 
         // Test with specific repository names
         let context = create_context(
@@ -220,7 +443,7 @@ mod tests {
     #[tokio::test]
     async fn test_clone_command_with_combined_filters() {
         let config = create_test_config();
-        let command = CloneCommand;
+        let command = CloneCommand::default();
 
         // Test with both tag and repository filters
         let context = create_context(
@@ -237,7 +460,7 @@ mod tests {
     #[tokio::test]
     async fn test_clone_command_parallel_execution() {
         let config = create_test_config();
-        let command = CloneCommand;
+        let command = CloneCommand::default();
 
         // Test parallel execution mode
         let context = create_context(config, vec!["frontend".to_string()], None, true);
@@ -250,7 +473,7 @@ mod tests {
     #[tokio::test]
     async fn test_clone_command_sequential_execution() {
         let config = create_test_config();
-        let command = CloneCommand;
+        let command = CloneCommand::default();
 
         // Test sequential execution mode
         let context = create_context(config, vec!["backend".to_string()], None, false);
@@ -263,7 +486,7 @@ mod tests {
     #[tokio::test]
     async fn test_clone_command_nonexistent_repository() {
         let config = create_test_config();
-        let command = CloneCommand;
+        let command = CloneCommand::default();
 
         // Test with repository names that don't exist
         let context = create_context(
@@ -280,7 +503,7 @@ mod tests {
     #[tokio::test]
     async fn test_clone_command_empty_filters() {
         let config = create_test_config();
-        let command = CloneCommand;
+        let command = CloneCommand::default();
 
         // Test with no filters (should try to clone all repositories)
         let context = create_context(config, vec![], None, false);
@@ -302,10 +525,11 @@ mod tests {
 
         let config = Config {
             repositories: vec![invalid_repo],
-            recipes: vec![],
+            version: None,
+            ..Default::default()
         };
 
-        let command = CloneCommand;
+        let command = CloneCommand::default();
         let context = create_context(config, vec![], None, false);
 
         let result = command.execute(&context).await;
@@ -320,7 +544,7 @@ mod tests {
         // This test is more conceptual since we can't easily mock the git operations
         // In a real scenario, we'd have some repos that succeed and some that fail
         let config = create_test_config();
-        let command = CloneCommand;
+        let command = CloneCommand::default();
 
         let context = create_context(config, vec![], None, false);
 
@@ -346,10 +570,11 @@ mod tests {
 
         let config = Config {
             repositories: vec![invalid_repo1, invalid_repo2],
-            recipes: vec![],
+            version: None,
+            ..Default::default()
         };
 
-        let command = CloneCommand;
+        let command = CloneCommand::default();
         let context = create_context(config, vec![], None, true); // Parallel execution
 
         let result = command.execute(&context).await;
@@ -360,7 +585,7 @@ mod tests {
     #[tokio::test]
     async fn test_clone_command_filter_combinations() {
         let config = create_test_config();
-        let command = CloneCommand;
+        let command = CloneCommand::default();
 
         // Test different filter combination scenarios
 
@@ -394,11 +619,11 @@ mod tests {
     async fn test_clone_command_empty_config() {
         // Test with empty configuration
         let config = Config {
-            repositories: vec![],
-            recipes: vec![],
+            version: None,
+            ..Default::default()
         };
 
-        let command = CloneCommand;
+        let command = CloneCommand::default();
         let context = create_context(config, vec![], None, false);
 
         let result = command.execute(&context).await;
@@ -410,7 +635,7 @@ mod tests {
         // This test targets the error handling in parallel execution
         // where tokio tasks might fail
         let config = create_test_config();
-        let command = CloneCommand;
+        let command = CloneCommand::default();
 
         // Use parallel execution to test task error handling paths
         let context = create_context(config, vec!["backend".to_string()], None, true);
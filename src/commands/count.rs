@@ -0,0 +1,89 @@
+//! Count command implementation
+//!
+//! Prints how many repositories the filters select, and with `--list` their
+//! names, without touching any of them: a quick check of a filter before an
+//! operation uses it.
+
+use super::{Command, CommandContext};
+use crate::config::Repository;
+use anyhow::Result;
+use async_trait::async_trait;
+
+/// Count command printing the number of selected repositories
+pub struct CountCommand {
+    /// Also print the name of each selected repository (`--list`)
+    pub list: bool,
+}
+
+impl CountCommand {
+    /// The count, followed by one name per line with `--list`
+    pub fn render(&self, repositories: &[Repository]) -> String {
+        let mut output = repositories.len().to_string();
+        if self.list {
+            for repo in repositories {
+                output.push('\n');
+                output.push_str(&repo.name);
+            }
+        }
+        output
+    }
+}
+
+#[async_trait]
+impl Command for CountCommand {
+    async fn execute(&self, context: &CommandContext) -> Result<()> {
+        let repositories = context.config.filter_repositories(
+            &context.tag,
+            &context.exclude_tag,
+            context.repos.as_deref(),
+        );
+        println!("{}", self.render(&repositories));
+        Ok(())
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::config::Config;
+
+    fn config() -> Config {
+        let repositories = [
+            ("api", &["backend", "rust"][..]),
+            ("web", &["frontend"][..]),
+            ("worker", &["backend", "deprecated"][..]),
+        ]
+        .iter()
+        .map(|(name, tags)| {
+            let mut repo =
+                Repository::new(name.to_string(), format!("git@github.com:acme/{name}.git"));
+            repo.tags = tags.iter().map(|tag| tag.to_string()).collect();
+            repo
+        })
+        .collect();
+        Config {
+            repositories,
+            ..Config::new()
+        }
+    }
+
+    fn count(tag: &[&str], exclude_tag: &[&str], list: bool) -> String {
+        let strings = |values: &[&str]| values.iter().map(|v| v.to_string()).collect::<Vec<_>>();
+        let repositories = config().filter_repositories(&strings(tag), &strings(exclude_tag), None);
+        CountCommand { list }.render(&repositories)
+    }
+
+    #[test]
+    fn test_count_follows_filters() {
+        assert_eq!(count(&[], &[], false), "3");
+        assert_eq!(count(&["backend"], &[], false), "2");
+        assert_eq!(count(&["backend"], &["deprecated"], false), "1");
+        assert_eq!(count(&["mobile"], &[], false), "0");
+    }
+
+    #[test]
+    fn test_list_adds_names() {
+        assert_eq!(count(&["backend"], &[], true), "2\napi\nworker");
+        assert_eq!(count(&["mobile"], &[], true), "0");
+    }
+}
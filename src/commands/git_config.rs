@@ -0,0 +1,118 @@
+//! Git config command implementation
+
+use super::{Command, CommandContext};
+use crate::git;
+use anyhow::Result;
+use async_trait::async_trait;
+use colored::*;
+use std::time::Instant;
+
+/// Git config command for applying `git_config` entries to existing clones
+pub struct GitConfigCommand;
+
+#[async_trait]
+impl Command for GitConfigCommand {
+    async fn execute(&self, context: &CommandContext) -> Result<()> {
+        let repositories = context.config.filter_repositories(
+            &context.tag,
+            &context.exclude_tag,
+            context.repos.as_deref(),
+        );
+
+        if repositories.is_empty() {
+            println!(
+                "{}",
+                context.with_tag_hint("No repositories found").yellow()
+            );
+            return Ok(());
+        }
+
+        println!(
+            "{}",
+            format!(
+                "Applying git config to {} repositories...",
+                repositories.len()
+            )
+            .green()
+        );
+
+        let mut failed = 0;
+        for repo in &repositories {
+            if !repo.exists() {
+                println!(
+                    "{} | {}",
+                    repo.name.cyan().bold(),
+                    "Not cloned, skipping".yellow()
+                );
+                continue;
+            }
+            if repo.git_config.is_empty() {
+                println!("{} | No git config entries", repo.name.cyan().bold());
+                continue;
+            }
+
+            let started = Instant::now();
+            let result = git::apply_git_config(repo);
+            context
+                .outcomes
+                .record_result(&repo.name, &result, started.elapsed());
+            if let Err(e) = result {
+                eprintln!(
+                    "{} | {}",
+                    repo.name.cyan().bold(),
+                    format!("Error: {e}").red()
+                );
+                failed += 1;
+            }
+        }
+
+        if failed > 0 {
+            anyhow::bail!("Failed to apply git config to {} repositories", failed);
+        }
+        println!("{}", "Done applying git config".green());
+        Ok(())
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::commands::OutcomeRecorder;
+    use crate::config::{Config, Repository};
+    use tempfile::TempDir;
+
+    #[tokio::test]
+    async fn test_git_config_command_skips_uncloned_repositories() {
+        let temp_dir = TempDir::new().unwrap();
+        let mut repo = Repository::new(
+            "missing".to_string(),
+            "git@github.com:owner/missing.git".to_string(),
+        );
+        repo.path = Some(
+            temp_dir
+                .path()
+                .join("missing")
+                .to_string_lossy()
+                .to_string(),
+        );
+        repo.git_config
+            .insert("user.email".to_string(), "ci@example.com".to_string());
+
+        let outcomes = OutcomeRecorder::new();
+        let context = CommandContext {
+            config: Config {
+                repositories: vec![repo],
+                ..Config::default()
+            },
+            tag: vec![],
+            exclude_tag: vec![],
+            parallel: false,
+            repos: None,
+            outcomes: outcomes.clone(),
+            jobs: None,
+        };
+
+        GitConfigCommand.execute(&context).await.unwrap();
+        assert!(outcomes.outcomes().is_empty());
+    }
+}
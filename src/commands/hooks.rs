@@ -0,0 +1,272 @@
+//! Outcome hooks for `repos run`
+//!
+//! Once a repository's command has finished, its `on_success` or its
+//! `on_failure` command runs, depending on the result. A repository's own
+//! `on_success` / `on_failure` in the config wins over `--on-success` /
+//! `--on-failure`. Hooks are checked against the config's `run_policy` and
+//! run with `run`'s shell in the repository's directory (the current directory
+//! when it does not exist), reading the outcome from their environment:
+//!
+//! - `REPOS_REPO_NAME`: the repository's name
+//! - `REPOS_RESULT`: `success` or `failure`
+//! - `REPOS_DURATION_MS`: how long the command took
+//! - `REPOS_ERROR`: why it failed, set for failures only
+//!
+//! A failing hook is reported as a warning and never changes the repository's
+//! outcome. [`ShellHook`] runs these hooks and the `--on-result` command alike.
+
+use crate::config::{Repository, RunPolicy};
+use crate::git::TraceCommand;
+use crate::runner::Shell;
+use anyhow::{Context, Result};
+use colored::*;
+use std::path::Path;
+use std::process::{Command, Stdio};
+use std::time::Duration;
+use tokio::io::AsyncWriteExt;
+
+/// Commands run after each repository, chosen by its result
+#[derive(Debug, Clone, Default, PartialEq, Eq)]
+pub struct OutcomeHooks {
+    /// `--on-success`: run for repositories without their own `on_success`
+    pub on_success: Option<String>,
+    /// `--on-failure`: run for repositories without their own `on_failure`
+    pub on_failure: Option<String>,
+    /// Shell the hooks are passed to, the one `run` uses for its commands
+    pub shell: Shell,
+    /// Policy a hook must pass before it runs
+    pub policy: RunPolicy,
+}
+
+impl OutcomeHooks {
+    /// The hook to run for `repo` after it succeeded or failed, if any
+    pub fn command_for<'a>(&'a self, repo: &'a Repository, success: bool) -> Option<&'a str> {
+        if success {
+            repo.on_success.as_deref().or(self.on_success.as_deref())
+        } else {
+            repo.on_failure.as_deref().or(self.on_failure.as_deref())
+        }
+    }
+
+    /// Run `repo`'s hook for the outcome: `error` is `None` when it succeeded
+    pub async fn run(&self, repo: &Repository, error: Option<&str>, duration: Duration) {
+        let Some(command) = self.command_for(repo, error.is_none()) else {
+            return;
+        };
+        if let Err(e) = self.run_hook(command, repo, error, duration).await {
+            eprintln!(
+                "{} | {}",
+                repo.name.cyan().bold(),
+                format!("Warning: {:#}", e).yellow()
+            );
+        }
+    }
+
+    async fn run_hook(
+        &self,
+        command: &str,
+        repo: &Repository,
+        error: Option<&str>,
+        duration: Duration,
+    ) -> Result<()> {
+        let repo_dir = repo.get_target_dir();
+        let repo_dir = Path::new(&repo_dir);
+        ShellHook {
+            label: "Hook",
+            command,
+            shell: &self.shell,
+            policy: &self.policy,
+            trace: &repo.name,
+            dir: repo_dir.is_dir().then_some(repo_dir),
+            env: outcome_env(repo, error, duration),
+            stdin: None,
+        }
+        .run()
+        .await
+    }
+}
+
+/// A shell command run once a repository's outcome is known
+pub(crate) struct ShellHook<'a> {
+    /// What the command is called in errors, e.g. `Hook`
+    pub label: &'static str,
+    pub command: &'a str,
+    pub shell: &'a Shell,
+    /// Policy the command must pass before it starts
+    pub policy: &'a RunPolicy,
+    /// Label `--verbose` prints the command under
+    pub trace: &'a str,
+    /// Working directory; the current directory when `None`
+    pub dir: Option<&'a Path>,
+    pub env: Vec<(&'static str, String)>,
+    /// Written to the command's stdin, which is closed afterwards
+    pub stdin: Option<Vec<u8>>,
+}
+
+impl ShellHook<'_> {
+    /// Run the command to completion without blocking the runtime's workers
+    ///
+    /// # Errors
+    /// Returns an error when the policy refuses the command, or when it cannot
+    /// be started or exits unsuccessfully
+    pub(crate) async fn run(self) -> Result<()> {
+        self.policy.check(self.command)?;
+        let mut command = Command::new(&self.shell.program);
+        command
+            .args(self.shell.args(self.command))
+            .envs(self.env)
+            .traced(self.trace);
+        if let Some(dir) = self.dir {
+            command.current_dir(dir);
+        }
+        if self.stdin.is_some() {
+            command.stdin(Stdio::piped());
+        }
+
+        let mut child = tokio::process::Command::from(command)
+            .spawn()
+            .with_context(|| format!("Failed to start {} '{}'", self.label, self.command))?;
+        if let (Some(input), Some(mut pipe)) = (self.stdin, child.stdin.take()) {
+            // A command that does not read its input closes the pipe early
+            let _ = pipe.write_all(&input).await;
+        }
+        let status = child
+            .wait()
+            .await
+            .with_context(|| format!("Failed to wait for {} '{}'", self.label, self.command))?;
+        if !status.success() {
+            anyhow::bail!("{} '{}' failed: {}", self.label, self.command, status);
+        }
+        Ok(())
+    }
+}
+
+/// Environment describing an outcome to its hook
+fn outcome_env(
+    repo: &Repository,
+    error: Option<&str>,
+    duration: Duration,
+) -> Vec<(&'static str, String)> {
+    let result = if error.is_none() {
+        "success"
+    } else {
+        "failure"
+    };
+    let mut env = vec![
+        ("REPOS_REPO_NAME", repo.name.clone()),
+        ("REPOS_RESULT", result.to_string()),
+        ("REPOS_DURATION_MS", duration.as_millis().to_string()),
+    ];
+    if let Some(error) = error {
+        env.push(("REPOS_ERROR", error.to_string()));
+    }
+    env
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use tempfile::TempDir;
+
+    fn hooks(on_success: &str, on_failure: &str) -> OutcomeHooks {
+        OutcomeHooks {
+            on_success: Some(on_success.to_string()),
+            on_failure: Some(on_failure.to_string()),
+            ..OutcomeHooks::default()
+        }
+    }
+
+    fn repo_in(dir: &Path) -> Repository {
+        let mut repo =
+            Repository::new("api".to_string(), "git@github.com:acme/api.git".to_string());
+        repo.path = Some(dir.to_string_lossy().into_owned());
+        repo
+    }
+
+    #[test]
+    fn test_repository_hooks_win_over_flags() {
+        let temp_dir = TempDir::new().unwrap();
+        let mut repo = repo_in(temp_dir.path());
+        let flags = hooks("echo flag-ok", "echo flag-failed");
+        assert_eq!(flags.command_for(&repo, true), Some("echo flag-ok"));
+        assert_eq!(flags.command_for(&repo, false), Some("echo flag-failed"));
+
+        repo.on_failure = Some("echo own-failed".to_string());
+        assert_eq!(flags.command_for(&repo, true), Some("echo flag-ok"));
+        assert_eq!(flags.command_for(&repo, false), Some("echo own-failed"));
+
+        assert_eq!(OutcomeHooks::default().command_for(&repo, true), None);
+    }
+
+    #[tokio::test]
+    async fn test_hook_matching_the_outcome_runs_with_its_environment() {
+        let temp_dir = TempDir::new().unwrap();
+        let repo = repo_in(temp_dir.path());
+        let hooks = hooks(
+            "echo \"$REPOS_REPO_NAME $REPOS_RESULT\" > success.txt",
+            "echo \"$REPOS_REPO_NAME $REPOS_RESULT $REPOS_ERROR\" > failure.txt",
+        );
+
+        hooks.run(&repo, None, Duration::from_millis(5)).await;
+        assert_eq!(
+            std::fs::read_to_string(temp_dir.path().join("success.txt")).unwrap(),
+            "api success\n"
+        );
+        assert!(!temp_dir.path().join("failure.txt").exists());
+
+        hooks
+            .run(&repo, Some("exit code 2"), Duration::from_millis(5))
+            .await;
+        assert_eq!(
+            std::fs::read_to_string(temp_dir.path().join("failure.txt")).unwrap(),
+            "api failure exit code 2\n"
+        );
+    }
+
+    #[tokio::test]
+    async fn test_failing_hook_is_an_error_for_the_caller_to_report() {
+        let temp_dir = TempDir::new().unwrap();
+        let repo = repo_in(temp_dir.path());
+        let hooks = hooks("exit 4", "exit 4");
+        let err = hooks
+            .run_hook("exit 4", &repo, None, Duration::ZERO)
+            .await
+            .unwrap_err();
+        assert!(err.to_string().starts_with("Hook 'exit 4' failed"));
+
+        // Reported as a warning only
+        hooks.run(&repo, None, Duration::ZERO).await;
+    }
+
+    #[cfg(unix)]
+    #[tokio::test]
+    async fn test_hooks_use_the_shell_and_honour_the_run_policy() {
+        use std::os::unix::fs::PermissionsExt;
+
+        let temp_dir = TempDir::new().unwrap();
+        let repo = repo_in(temp_dir.path());
+        // A shell that records how it was called instead of running the command
+        let shell = temp_dir.path().join("record-sh");
+        std::fs::write(&shell, "#!/bin/sh\necho \"$@\" >> calls.txt\n").unwrap();
+        std::fs::set_permissions(&shell, std::fs::Permissions::from_mode(0o755)).unwrap();
+        let hooks = OutcomeHooks {
+            shell: Shell::new(&shell.to_string_lossy()),
+            policy: RunPolicy {
+                allow: Vec::new(),
+                deny: vec!["rm ".to_string()],
+            },
+            ..hooks("echo done", "rm -r build")
+        };
+
+        hooks.run(&repo, None, Duration::ZERO).await;
+        let err = hooks
+            .run_hook("rm -r build", &repo, Some("exit 1"), Duration::ZERO)
+            .await
+            .unwrap_err();
+        assert!(err.to_string().contains("denied by run_policy"));
+        assert_eq!(
+            std::fs::read_to_string(temp_dir.path().join("calls.txt")).unwrap(),
+            "-c echo done\n"
+        );
+    }
+}
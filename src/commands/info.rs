@@ -0,0 +1,260 @@
+//! Info command implementation
+//!
+//! Shows each repository's config entry next to the live state of its clone,
+//! so one command answers both "what is configured" and "what is checked out".
+
+use super::{Command, CommandContext};
+use crate::config::Repository;
+use crate::git::{self, RepoFacts};
+use anyhow::Result;
+use async_trait::async_trait;
+use colored::*;
+use serde::Serialize;
+use std::path::Path;
+use std::str::FromStr;
+
+/// How `repos info` prints repositories (`--output`)
+#[derive(Debug, Clone, Copy, Default, PartialEq, Eq)]
+pub enum InfoFormat {
+    /// One readable block per repository
+    #[default]
+    Text,
+    Json,
+}
+
+impl FromStr for InfoFormat {
+    type Err = anyhow::Error;
+
+    fn from_str(value: &str) -> Result<Self> {
+        match value {
+            "text" => Ok(Self::Text),
+            "json" => Ok(Self::Json),
+            _ => anyhow::bail!("Unknown output format '{}': expected text or json", value),
+        }
+    }
+}
+
+/// Config metadata and live facts for one repository
+#[derive(Debug, Clone, PartialEq, Eq, Serialize)]
+pub struct RepositoryInfo {
+    pub name: String,
+    pub url: String,
+    #[serde(skip_serializing_if = "Vec::is_empty")]
+    pub tags: Vec<String>,
+    /// Configured path, if any
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub path: Option<String>,
+    /// Configured branch, if any
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub branch: Option<String>,
+    /// Where the clone lives or would be cloned to
+    pub directory: String,
+    pub cloned: bool,
+    /// Live facts; absent when not cloned or unreadable
+    #[serde(flatten, skip_serializing_if = "Option::is_none")]
+    pub facts: Option<RepoFacts>,
+    /// Why the live facts could not be read from an existing directory
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub error: Option<String>,
+}
+
+impl RepositoryInfo {
+    /// Gather the config entry and, when cloned, the live facts of `repo`
+    pub fn gather(repo: &Repository) -> Self {
+        let directory = repo.get_target_dir();
+        let cloned = Path::new(&directory).exists();
+        let (facts, error) = if cloned {
+            match git::repository_facts(&directory) {
+                Ok(facts) => (Some(facts), None),
+                Err(e) => (None, Some(e.to_string())),
+            }
+        } else {
+            (None, None)
+        };
+
+        Self {
+            name: repo.name.clone(),
+            url: repo.url.clone(),
+            tags: repo.tags.clone(),
+            path: repo.path.clone(),
+            branch: repo.branch.clone(),
+            directory,
+            cloned,
+            facts,
+            error,
+        }
+    }
+
+    fn print(&self) {
+        println!("{} {}", "•".blue(), self.name.bold());
+        println!("  URL: {}", self.url);
+        if !self.tags.is_empty() {
+            println!("  Tags: {}", self.tags.join(", ").cyan());
+        }
+        if let Some(path) = &self.path {
+            println!("  Path: {}", path);
+        }
+        if let Some(branch) = &self.branch {
+            println!("  Branch: {}", branch);
+        }
+        println!("  Directory: {}", self.directory);
+
+        if !self.cloned {
+            println!("  Cloned: {}", "no".yellow());
+            return;
+        }
+        if let Some(error) = &self.error {
+            println!("  Cloned: {} ({})", "unreadable".red(), error);
+            return;
+        }
+        println!("  Cloned: {}", "yes".green());
+        let Some(facts) = &self.facts else {
+            return;
+        };
+        match &facts.current_branch {
+            Some(branch) => println!("  Current branch: {}", branch),
+            None => println!("  Current branch: {}", "detached HEAD".yellow()),
+        }
+        match &facts.last_commit {
+            Some(commit) => println!(
+                "  Last commit: {} {} ({}, {})",
+                commit.sha.chars().take(7).collect::<String>(),
+                commit.subject,
+                commit.author,
+                commit.date
+            ),
+            None => println!("  Last commit: none"),
+        }
+        if let Some(remote_url) = &facts.remote_url {
+            println!("  Remote: {}", remote_url);
+        }
+    }
+}
+
+/// Info command showing config metadata and live git facts per repository
+pub struct InfoCommand {
+    pub format: InfoFormat,
+}
+
+#[async_trait]
+impl Command for InfoCommand {
+    async fn execute(&self, context: &CommandContext) -> Result<()> {
+        let repositories = context.config.filter_repositories(
+            &context.tag,
+            &context.exclude_tag,
+            context.repos.as_deref(),
+        );
+        let infos: Vec<RepositoryInfo> = repositories.iter().map(RepositoryInfo::gather).collect();
+
+        if self.format == InfoFormat::Json {
+            println!("{}", serde_json::to_string_pretty(&infos)?);
+            return Ok(());
+        }
+
+        if infos.is_empty() {
+            println!("{}", "No repositories found".yellow());
+            return Ok(());
+        }
+        for info in &infos {
+            info.print();
+            println!();
+        }
+        Ok(())
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use std::process::Command;
+    use tempfile::TempDir;
+
+    fn git(dir: &Path, args: &[&str]) {
+        Command::new("git")
+            .args(args)
+            .current_dir(dir)
+            .output()
+            .unwrap();
+    }
+
+    #[test]
+    fn test_output_format_parsing() {
+        assert_eq!("text".parse::<InfoFormat>().unwrap(), InfoFormat::Text);
+        assert_eq!("json".parse::<InfoFormat>().unwrap(), InfoFormat::Json);
+        let err = "yaml".parse::<InfoFormat>().unwrap_err();
+        assert!(err.to_string().contains("expected text or json"));
+    }
+
+    #[test]
+    fn test_not_cloned_repository_has_config_only() {
+        let temp_dir = TempDir::new().unwrap();
+        let mut repo =
+            Repository::new("api".to_string(), "git@github.com:acme/api.git".to_string());
+        repo.tags = vec!["backend".to_string()];
+        repo.branch = Some("develop".to_string());
+        repo.path = Some(temp_dir.path().join("api").to_string_lossy().into_owned());
+
+        let info = RepositoryInfo::gather(&repo);
+        assert!(!info.cloned);
+        assert_eq!(info.facts, None);
+        assert_eq!(info.error, None);
+
+        let json = serde_json::to_value(&info).unwrap();
+        assert_eq!(json["name"], "api");
+        assert_eq!(json["tags"], serde_json::json!(["backend"]));
+        assert_eq!(json["branch"], "develop");
+        assert_eq!(json["cloned"], false);
+        assert!(json.get("current_branch").is_none());
+    }
+
+    #[test]
+    fn test_cloned_repository_has_live_facts() {
+        let temp_dir = TempDir::new().unwrap();
+        let clone = temp_dir.path().join("web");
+        std::fs::create_dir(&clone).unwrap();
+        git(&clone, &["init", "-b", "trunk"]);
+        git(&clone, &["config", "user.name", "Test User"]);
+        git(&clone, &["config", "user.email", "test@example.com"]);
+        std::fs::write(clone.join("README.md"), "# web\n").unwrap();
+        git(&clone, &["add", "."]);
+        git(&clone, &["commit", "-m", "Initial commit"]);
+        git(
+            &clone,
+            &["remote", "add", "origin", "git@github.com:acme/web.git"],
+        );
+
+        let mut repo =
+            Repository::new("web".to_string(), "git@github.com:acme/web.git".to_string());
+        repo.path = Some(clone.to_string_lossy().into_owned());
+
+        let info = RepositoryInfo::gather(&repo);
+        assert!(info.cloned);
+        let facts = info.facts.clone().unwrap();
+        assert_eq!(facts.current_branch.as_deref(), Some("trunk"));
+        assert_eq!(
+            facts.remote_url.as_deref(),
+            Some("git@github.com:acme/web.git")
+        );
+        assert_eq!(facts.last_commit.unwrap().subject, "Initial commit");
+
+        // Live facts sit next to the config fields in JSON
+        let json = serde_json::to_value(&info).unwrap();
+        assert_eq!(json["current_branch"], "trunk");
+        assert_eq!(json["last_commit"]["author"], "Test User");
+    }
+
+    #[test]
+    fn test_directory_that_is_not_a_repository_reports_error() {
+        let temp_dir = TempDir::new().unwrap();
+        let mut repo = Repository::new(
+            "docs".to_string(),
+            "git@github.com:acme/docs.git".to_string(),
+        );
+        repo.path = Some(temp_dir.path().to_string_lossy().into_owned());
+
+        let info = RepositoryInfo::gather(&repo);
+        assert!(info.cloned);
+        assert_eq!(info.facts, None);
+        assert!(info.error.unwrap().contains("Not a git repository"));
+    }
+}
@@ -1,7 +1,8 @@
 //! Init command implementation
 
 use super::{Command, CommandContext};
-use crate::config::{Config, RepositoryBuilder};
+use crate::config::{Config, ConfigFormat, RepositoryBuilder};
+use crate::utils::tags_from_ancestors;
 use anyhow::Result;
 use async_trait::async_trait;
 use colored::*;
@@ -16,15 +17,32 @@ pub struct InitCommand {
     pub output: String,
     pub overwrite: bool,
     pub supplement: bool,
+    /// Tag each repository with up to this many ancestor directory names
+    pub tag_depth: usize,
+    /// `--config-format` of the output file, overriding its extension
+    pub config_format: Option<ConfigFormat>,
 }
 
 #[async_trait]
 impl Command for InitCommand {
     async fn execute(&self, _context: &CommandContext) -> Result<()> {
+        let format = self
+            .config_format
+            .unwrap_or_else(|| ConfigFormat::from_path(Path::new(&self.output)));
+
         // Load existing config if supplementing, otherwise check for overwrite
         let mut existing_config = if self.supplement && Path::new(&self.output).exists() {
+            // The file is rewritten from the loaded values, which are expanded
+            let expansions = Config::load_expansions(&self.output, format)?;
+            if !expansions.is_empty() {
+                anyhow::bail!(
+                    "Cannot supplement '{}': it uses {}, which saving would expand; add the new repositories by hand",
+                    self.output,
+                    expansions.join(" and ")
+                );
+            }
             println!("{}", "Loading existing configuration...".green());
-            Config::load(&self.output)?
+            Config::load_as(&self.output, format)?
         } else {
             if Path::new(&self.output).exists() && !self.overwrite {
                 return Err(anyhow::anyhow!(
@@ -60,6 +78,7 @@ impl Command for InitCommand {
                                 .to_string_lossy()
                                 .to_string(),
                         )
+                        .with_tags(tags_from_ancestors(&current_dir, repo_dir, self.tag_depth))
                         .build();
                     discovered_repositories.push(repo);
                 }
@@ -108,7 +127,7 @@ impl Command for InitCommand {
 
             // Only save if we have new repositories to add or if config already existed
             if added_count > 0 || has_existing_config {
-                existing_config.save(&self.output)?;
+                existing_config.save_as(&self.output, format)?;
 
                 if added_count > 0 {
                     println!(
@@ -129,7 +148,7 @@ impl Command for InitCommand {
                 format!("Found {} repositories", existing_config.repositories.len()).green()
             );
 
-            existing_config.save(&self.output)?;
+            existing_config.save_as(&self.output, format)?;
             println!(
                 "{}",
                 format!("Configuration saved to '{}'", self.output).green()
@@ -159,6 +178,7 @@ fn get_git_remote_url(repo_path: &Path) -> Result<String> {
 #[cfg(test)]
 mod tests {
     use super::*;
+    use crate::commands::OutcomeRecorder;
     use std::fs;
     use tempfile::TempDir;
 
@@ -176,17 +196,21 @@ mod tests {
             output: output_path.to_string_lossy().to_string(),
             overwrite: false,
             supplement: false,
+            tag_depth: 0,
+            config_format: None,
         };
 
         let context = CommandContext {
             config: Config {
-                repositories: vec![],
-                recipes: vec![],
+                version: None,
+                ..Default::default()
             },
             tag: vec![],
             exclude_tag: vec![],
             repos: None,
             parallel: false,
+            outcomes: OutcomeRecorder::new(),
+            jobs: None,
         };
 
         let result = command.execute(&context).await;
@@ -211,17 +235,21 @@ mod tests {
             output: output_path.to_string_lossy().to_string(),
             overwrite: false, // Should not overwrite
             supplement: false,
+            tag_depth: 0,
+            config_format: None,
         };
 
         let context = CommandContext {
             config: Config {
-                repositories: vec![],
-                recipes: vec![],
+                version: None,
+                ..Default::default()
             },
             tag: vec![],
             exclude_tag: vec![],
             repos: None,
             parallel: false,
+            outcomes: OutcomeRecorder::new(),
+            jobs: None,
         };
 
         let result = command.execute(&context).await;
@@ -240,6 +268,8 @@ mod tests {
             output: "test.yaml".to_string(),
             overwrite: true,
             supplement: false,
+            tag_depth: 0,
+            config_format: None,
         };
 
         assert_eq!(command.output, "test.yaml");
@@ -259,7 +289,8 @@ mod tests {
                 "existing-repo".to_string(),
                 "git@github.com:owner/existing-repo.git".to_string(),
             )],
-            recipes: vec![],
+            version: None,
+            ..Default::default()
         };
         existing_config
             .save(&output_path.to_string_lossy())
@@ -274,17 +305,21 @@ mod tests {
             output: output_path.to_string_lossy().to_string(),
             overwrite: false,
             supplement: true, // Should supplement existing config
+            tag_depth: 0,
+            config_format: None,
         };
 
         let context = CommandContext {
             config: Config {
-                repositories: vec![],
-                recipes: vec![],
+                version: None,
+                ..Default::default()
             },
             tag: vec![],
             exclude_tag: vec![],
             repos: None,
             parallel: false,
+            outcomes: OutcomeRecorder::new(),
+            jobs: None,
         };
 
         let result = command.execute(&context).await;
@@ -299,6 +334,37 @@ mod tests {
         std::env::set_current_dir(original_dir).unwrap();
     }
 
+    #[tokio::test]
+    #[serial]
+    async fn test_init_command_supplement_refuses_expanded_config() {
+        let temp_dir = TempDir::new().unwrap();
+        let output_path = temp_dir.path().join("repos.yaml");
+        let content =
+            "repositories:\n  - name: api\n    url: git@${GIT_HOST:-github.com}:o/api.git\n";
+        fs::write(&output_path, content).unwrap();
+
+        let command = InitCommand {
+            output: output_path.to_string_lossy().to_string(),
+            overwrite: false,
+            supplement: true,
+            tag_depth: 0,
+            config_format: None,
+        };
+        let context = CommandContext {
+            config: Config::new(),
+            tag: vec![],
+            exclude_tag: vec![],
+            repos: None,
+            parallel: false,
+            outcomes: OutcomeRecorder::new(),
+            jobs: None,
+        };
+
+        let err = command.execute(&context).await.unwrap_err();
+        assert!(err.to_string().contains("uses ${VAR} references"), "{err}");
+        assert_eq!(fs::read_to_string(&output_path).unwrap(), content);
+    }
+
     #[tokio::test]
     #[serial]
     async fn test_init_command_supplement_without_existing_config() {
@@ -313,17 +379,21 @@ mod tests {
             output: output_path.to_string_lossy().to_string(),
             overwrite: false,
             supplement: true, // Should create new config since none exists
+            tag_depth: 0,
+            config_format: None,
         };
 
         let context = CommandContext {
             config: Config {
-                repositories: vec![],
-                recipes: vec![],
+                version: None,
+                ..Default::default()
             },
             tag: vec![],
             exclude_tag: vec![],
             repos: None,
             parallel: false,
+            outcomes: OutcomeRecorder::new(),
+            jobs: None,
         };
 
         let result = command.execute(&context).await;
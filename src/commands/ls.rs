@@ -71,10 +71,8 @@ impl Command for ListCommand {
                 filter_parts.join(" and ")
             };
 
-            println!(
-                "{}",
-                format!("No repositories found with {filter_desc}").yellow()
-            );
+            let message = format!("No repositories found with {filter_desc}");
+            println!("{}", context.with_tag_hint(&message).yellow());
             return Ok(());
         }
 
@@ -118,6 +116,7 @@ impl Command for ListCommand {
 #[cfg(test)]
 mod tests {
     use super::*;
+    use crate::commands::OutcomeRecorder;
     use crate::config::{Config, Repository};
 
     /// Helper function to create a test config with repositories
@@ -142,7 +141,8 @@ mod tests {
 
         Config {
             repositories: vec![repo1, repo2, repo3],
-            recipes: vec![],
+            version: None,
+            ..Default::default()
         }
     }
 
@@ -159,6 +159,8 @@ mod tests {
             exclude_tag,
             repos,
             parallel: false,
+            outcomes: OutcomeRecorder::new(),
+            jobs: None,
         }
     }
 
@@ -241,8 +243,8 @@ mod tests {
     #[tokio::test]
     async fn test_list_command_empty_config() {
         let config = Config {
-            repositories: vec![],
-            recipes: vec![],
+            version: None,
+            ..Default::default()
         };
         let command = ListCommand { json: false };
 
@@ -309,8 +311,8 @@ mod tests {
     #[tokio::test]
     async fn test_list_command_json_empty() {
         let config = Config {
-            repositories: vec![],
-            recipes: vec![],
+            version: None,
+            ..Default::default()
         };
         let command = ListCommand { json: true };
 
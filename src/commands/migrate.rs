@@ -0,0 +1,132 @@
+//! Config migrate command implementation
+
+use super::{Command, CommandContext};
+use crate::config::loader::save_config;
+use crate::config::migration::{self, CURRENT_CONFIG_VERSION, UNVERSIONED_CONFIG_VERSION};
+use crate::config::{Config, ConfigFormat};
+use anyhow::{Context, Result};
+use async_trait::async_trait;
+use colored::*;
+
+/// Upgrade a config file to the current schema version in place
+///
+/// The original file is kept next to it with a `.bak` suffix.
+pub struct MigrateCommand {
+    pub config: String,
+}
+
+#[async_trait]
+impl Command for MigrateCommand {
+    async fn execute(&self, _context: &CommandContext) -> Result<()> {
+        let format = ConfigFormat::from_path(std::path::Path::new(&self.config));
+        if format != ConfigFormat::Yaml {
+            anyhow::bail!(
+                "config migrate only rewrites YAML files; convert {} ({}) by hand",
+                self.config,
+                format
+            );
+        }
+        let content = std::fs::read_to_string(&self.config)
+            .with_context(|| format!("Failed to read config file: {}", self.config))?;
+        let mut document: serde_yaml::Value = serde_yaml::from_str(&content)
+            .with_context(|| format!("Failed to parse config file: {}", self.config))?;
+        let from = migration::declared_version(&content).unwrap_or(UNVERSIONED_CONFIG_VERSION);
+
+        let applied = migration::migrate(&mut document)?;
+        if applied.is_empty() {
+            println!(
+                "{}",
+                format!(
+                    "{} is already at config version {}",
+                    self.config, CURRENT_CONFIG_VERSION
+                )
+                .green()
+            );
+            return Ok(());
+        }
+
+        // Never replace a working file with one that doesn't load
+        serde_yaml::from_value::<Config>(document.clone())
+            .context("Migrated config is invalid; the original file was left unchanged")?;
+
+        let backup = format!("{}.bak", self.config);
+        std::fs::write(&backup, &content)
+            .with_context(|| format!("Failed to write backup: {}", backup))?;
+        save_config(&document, &self.config)?;
+
+        println!(
+            "{}",
+            format!(
+                "Migrated {} from config version {} to {} (backup: {})",
+                self.config, from, CURRENT_CONFIG_VERSION, backup
+            )
+            .green()
+        );
+        for step in applied {
+            println!("  {} -> {}: {}", step.from, step.from + 1, step.description);
+        }
+        Ok(())
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::commands::OutcomeRecorder;
+    use tempfile::TempDir;
+
+    fn context() -> CommandContext {
+        CommandContext {
+            config: Config::new(),
+            tag: vec![],
+            exclude_tag: vec![],
+            parallel: false,
+            repos: None,
+            outcomes: OutcomeRecorder::new(),
+            jobs: None,
+        }
+    }
+
+    #[tokio::test]
+    async fn test_migrate_upgrades_file_in_place() {
+        let temp_dir = TempDir::new().unwrap();
+        let path = temp_dir.path().join("repos.yaml");
+        let original = "# Team repositories\nrepositories:\n  - name: api\n    url: git@github.com:acme/api.git\n    tags: [backend]\n    provider: gh\n";
+        std::fs::write(&path, original).unwrap();
+
+        let command = MigrateCommand {
+            config: path.to_string_lossy().to_string(),
+        };
+        command.execute(&context()).await.unwrap();
+
+        let migrated = std::fs::read_to_string(&path).unwrap();
+        assert!(migrated.starts_with("# Team repositories\n"));
+        assert!(migrated.contains("version: 2"));
+        assert!(migrated.contains("provider: github"));
+        assert_eq!(
+            std::fs::read_to_string(temp_dir.path().join("repos.yaml.bak")).unwrap(),
+            original
+        );
+
+        let config = Config::load(&path.to_string_lossy()).unwrap();
+        assert_eq!(config.version, Some(CURRENT_CONFIG_VERSION));
+
+        // A second run has nothing to do and leaves the file alone
+        command.execute(&context()).await.unwrap();
+        assert_eq!(std::fs::read_to_string(&path).unwrap(), migrated);
+    }
+
+    #[tokio::test]
+    async fn test_migrate_refuses_non_yaml_files() {
+        let temp_dir = TempDir::new().unwrap();
+        let path = temp_dir.path().join("repos.json");
+        std::fs::write(&path, r#"{"repositories": []}"#).unwrap();
+
+        let command = MigrateCommand {
+            config: path.to_string_lossy().to_string(),
+        };
+        let err = command.execute(&context()).await.unwrap_err();
+        assert!(err.to_string().contains("only rewrites YAML files"));
+        assert!(!temp_dir.path().join("repos.json.bak").exists());
+    }
+}
@@ -1,19 +1,38 @@
 //! Command pattern implementation for CLI operations
 
+pub mod active;
 pub mod base;
 pub mod clone;
+pub mod count;
+pub mod git_config;
+pub mod hooks;
+pub mod info;
 pub mod init;
 pub mod ls;
+pub mod migrate;
 pub mod pr;
+pub mod prune_branches;
+pub mod pull;
 pub mod remove;
+pub mod report;
 pub mod run;
+pub mod status;
 pub mod validators;
 
 // Re-export the base types and all commands
-pub use base::{Command, CommandContext};
+pub use base::{Command, CommandContext, JobLimits, join_limited};
 pub use clone::CloneCommand;
+pub use count::CountCommand;
+pub use git_config::GitConfigCommand;
+pub use hooks::OutcomeHooks;
+pub use info::{InfoCommand, InfoFormat, RepositoryInfo};
 pub use init::InitCommand;
 pub use ls::ListCommand;
+pub use migrate::MigrateCommand;
 pub use pr::PrCommand;
+pub use prune_branches::PruneBranchesCommand;
+pub use pull::PullCommand;
 pub use remove::RemoveCommand;
-pub use run::RunCommand;
+pub use report::{ExitPolicy, OutcomeRecorder, RepoOutcome, RunReport};
+pub use run::{RunCommand, RunSummary, SummaryFormat};
+pub use status::{CloneStatus, StatusCommand};
@@ -1,13 +1,20 @@
 //! Pull request command implementation
 
-use super::{Command, CommandContext};
-use crate::github::PrOptions;
-use crate::github::api::create_pr_from_workspace;
+use super::{Command, CommandContext, ExitPolicy};
+use crate::config::Repository;
+use crate::git;
+use crate::github::{
+    ApiScheduler, PrOptions, open_pull_request, prepare_pr_branch, warn_about_base_protection,
+};
 use anyhow::Result;
 use async_trait::async_trait;
 use colored::*;
+use std::sync::Arc;
+use std::time::{Duration, Instant};
+use tokio::sync::Semaphore;
 
 /// Pull request command for creating PRs with changes
+#[derive(Default)]
 pub struct PrCommand {
     pub title: String,
     pub body: String,
@@ -16,7 +23,32 @@ pub struct PrCommand {
     pub commit_msg: Option<String>,
     pub draft: bool,
     pub token: String,
+    pub bitbucket_token: String,
     pub create_only: bool,
+    /// Minimum gap between pull request API calls, also under `--parallel`
+    pub api_interval: Duration,
+    /// Warn about the base branch's protection rules before committing
+    pub check_protection: bool,
+    /// Skip repositories none of whose changed files match one of these
+    pub changed_files: Vec<glob::Pattern>,
+    /// Take each repository's lock before touching its branches
+    pub lock: Option<git::LockMode>,
+    /// GitHub API base URL (`--github-api-url`), e.g. for GitHub Enterprise
+    pub github_api_url: Option<String>,
+    /// Hosts served by GitHub Enterprise Server (`defaults.github_enterprise_hosts`)
+    pub github_enterprise_hosts: Vec<String>,
+    /// Owner of the pushed branch, when not the one in the URL (`--head-owner`)
+    pub head_owner: Option<String>,
+    /// Owner of the repository to open pull requests against (`--base-owner`)
+    pub base_owner: Option<String>,
+    /// Failed repositories tolerated before the command fails (`--max-pr-failures`)
+    pub max_failures: Option<usize>,
+    /// Stage every change before committing instead of only the staged ones (`--commit-all`)
+    pub commit_all: bool,
+    /// Author and committer of the commits (`--author-name`, `--author-email`)
+    pub author: git::CommitIdentity,
+    /// Archived repositories left out of the selection, reported in the summary
+    pub archived_skipped: usize,
 }
 
 #[async_trait]
@@ -47,10 +79,8 @@ impl Command for PrCommand {
                 filter_parts.join(" and ")
             };
 
-            println!(
-                "{}",
-                format!("No repositories found with {filter_desc}").yellow()
-            );
+            let message = format!("No repositories found with {filter_desc}");
+            println!("{}", context.with_tag_hint(&message).yellow());
             return Ok(());
         }
 
@@ -71,40 +101,75 @@ impl Command for PrCommand {
             commit_msg: self.commit_msg.clone(),
             draft: self.draft,
             token: self.token.clone(),
+            bitbucket_token: self.bitbucket_token.clone(),
             create_only: self.create_only,
+            check_protection: self.check_protection,
+            changed_files: self.changed_files.clone(),
+            lock: self.lock,
+            github_api_url: self.github_api_url.clone(),
+            github_enterprise_hosts: self.github_enterprise_hosts.clone(),
+            head_owner: self.head_owner.clone(),
+            base_owner: self.base_owner.clone(),
+            commit_all: self.commit_all,
+            author: self.author.clone(),
         };
 
         let mut errors = Vec::new();
         let mut successful = 0;
+        let mut skipped = 0;
+
+        let scheduler = Arc::new(ApiScheduler::new(self.api_interval));
 
         if context.parallel {
+            // Local git work runs concurrently (bounded by --jobs); the API
+            // calls queue up in the scheduler
+            let permits = Arc::new(Semaphore::new(
+                context.jobs.unwrap_or(repositories.len()).max(1),
+            ));
             let tasks: Vec<_> = repositories
                 .into_iter()
                 .map(|repo| {
                     let pr_options = pr_options.clone();
-                    async move {
-                        (
-                            repo.name.clone(),
-                            create_pr_from_workspace(&repo, &pr_options).await,
-                        )
-                    }
+                    let outcomes = context.outcomes.clone();
+                    let permits = permits.clone();
+                    let scheduler = scheduler.clone();
+                    tokio::spawn(async move {
+                        let started = Instant::now();
+                        let result =
+                            create_pr(repo.clone(), pr_options, &scheduler, Some(&permits)).await;
+                        outcomes.record_result(&repo.name, &result, started.elapsed());
+                        Ok::<_, anyhow::Error>((repo.name, result))
+                    })
                 })
                 .collect();
 
             for task in tasks {
-                let (repo_name, result) = task.await;
-                match result {
-                    Ok(_) => successful += 1,
-                    Err(e) => {
+                match task.await? {
+                    Ok((_, Ok(()))) => successful += 1,
+                    Ok((_, Err(e))) if e.downcast_ref::<git::RepositoryLocked>().is_some() => {
+                        skipped += 1
+                    }
+                    Ok((repo_name, Err(e))) => {
                         eprintln!("{}", format!("Error: {e}").red());
                         errors.push((repo_name, e));
                     }
+                    Err(e) => {
+                        eprintln!("{}", format!("Task error: {e}").red());
+                        errors.push(("unknown".to_string(), e));
+                    }
                 }
             }
         } else {
             for repo in repositories {
-                match create_pr_from_workspace(&repo, &pr_options).await {
+                let started = Instant::now();
+                let result = create_pr(repo.clone(), pr_options.clone(), &scheduler, None).await;
+                context
+                    .outcomes
+                    .record_result(&repo.name, &result, started.elapsed());
+
+                match result {
                     Ok(_) => successful += 1,
+                    Err(e) if e.downcast_ref::<git::RepositoryLocked>().is_some() => skipped += 1,
                     Err(e) => {
                         eprintln!(
                             "{} | {}",
@@ -118,25 +183,59 @@ impl Command for PrCommand {
         }
 
         // Report summary
+        let archived = match self.archived_skipped {
+            0 => String::new(),
+            count => format!(", {} archived skipped", count),
+        };
         if errors.is_empty() {
-            println!("{}", "Done processing pull requests".green());
+            println!(
+                "{}",
+                format!("Done processing pull requests{}", archived).green()
+            );
         } else {
             println!(
                 "{}",
                 format!(
-                    "Completed with {} successful, {} failed",
+                    "Completed with {} successful, {} failed, {} skipped{}",
                     successful,
-                    errors.len()
+                    errors.len(),
+                    skipped,
+                    archived
                 )
                 .yellow()
             );
 
-            // If all operations failed, return an error to propagate to main
-            if successful == 0 {
-                return Err(anyhow::anyhow!(
-                    "All pull request operations failed. First error: {}",
-                    errors[0].1
-                ));
+            // Without --max-pr-failures the command itself only fails when
+            // nothing succeeded; the --exit-policy judges the rest
+            let policy = self
+                .max_failures
+                .map_or(ExitPolicy::AllFailure, ExitPolicy::AtMostFailures);
+            let fails = policy.fails(false, &context.outcomes.outcomes());
+            match self.max_failures {
+                Some(max_failures) if fails => {
+                    return Err(anyhow::anyhow!(
+                        "{} pull request operations failed, more than --max-pr-failures {}. First error: {}",
+                        errors.len(),
+                        max_failures,
+                        errors[0].1
+                    ));
+                }
+                Some(max_failures) => println!(
+                    "{}",
+                    format!(
+                        "{} failed within --max-pr-failures {}",
+                        errors.len(),
+                        max_failures
+                    )
+                    .yellow()
+                ),
+                None if fails => {
+                    return Err(anyhow::anyhow!(
+                        "All pull request operations failed. First error: {}",
+                        errors[0].1
+                    ));
+                }
+                None => {}
             }
         }
 
@@ -144,16 +243,52 @@ impl Command for PrCommand {
     }
 }
 
+/// Prepare the branch, holding one of `permits` if given, then open the PR through `scheduler`
+///
+/// The permit is released before queueing for the API, so the next
+/// repository's local work can start while this one waits its turn.
+async fn create_pr(
+    repo: Repository,
+    options: PrOptions,
+    scheduler: &ApiScheduler,
+    permits: Option<&Semaphore>,
+) -> Result<()> {
+    let permit = match permits {
+        Some(permits) => Some(permits.acquire().await?),
+        None => None,
+    };
+    if options.check_protection {
+        warn_about_base_protection(&repo, &options).await;
+    }
+    let branch = tokio::task::spawn_blocking({
+        let repo = repo.clone();
+        let options = options.clone();
+        move || prepare_pr_branch(&repo, &options)
+    })
+    .await??;
+    drop(permit);
+
+    match branch {
+        Some(branch) => {
+            scheduler
+                .call(|| open_pull_request(&repo, &branch, &options))
+                .await
+        }
+        None => Ok(()),
+    }
+}
+
 #[cfg(test)]
 mod tests {
     use super::*;
+    use crate::commands::OutcomeRecorder;
     use crate::config::{Config, Repository};
 
     #[tokio::test]
     async fn test_pr_command_no_repositories() {
         let config = Config {
-            repositories: vec![],
-            recipes: vec![],
+            version: None,
+            ..Default::default()
         };
         let context = CommandContext {
             config,
@@ -161,17 +296,15 @@ mod tests {
             exclude_tag: vec![],
             repos: None,
             parallel: false,
+            outcomes: OutcomeRecorder::new(),
+            jobs: None,
         };
 
         let pr_command = PrCommand {
             title: "Test PR".to_string(),
             body: "Test body".to_string(),
-            branch_name: None,
-            base_branch: None,
-            commit_msg: None,
-            draft: false,
             token: "test_token".to_string(),
-            create_only: false,
+            ..Default::default()
         };
 
         let result = pr_command.execute(&context).await;
@@ -184,14 +317,14 @@ mod tests {
             name: "test-repo".to_string(),
             url: "https://github.com/test/repo.git".to_string(),
             path: Some("./test-repo".to_string()),
-            branch: None,
             tags: vec!["api".to_string()],
-            config_dir: None,
+            ..Default::default()
         };
 
         let config = Config {
             repositories: vec![repository],
-            recipes: vec![],
+            version: None,
+            ..Default::default()
         };
 
         let context = CommandContext {
@@ -200,6 +333,8 @@ mod tests {
             exclude_tag: vec![],
             repos: None,
             parallel: false,
+            outcomes: OutcomeRecorder::new(),
+            jobs: None,
         };
 
         let pr_command = PrCommand {
@@ -211,6 +346,7 @@ mod tests {
             draft: true,
             token: "test_token".to_string(),
             create_only: true,
+            ..Default::default()
         };
 
         let result = pr_command.execute(&context).await;
@@ -223,14 +359,14 @@ mod tests {
             name: "test-repo".to_string(),
             url: "https://github.com/test/repo.git".to_string(),
             path: Some("./nonexistent-path".to_string()),
-            branch: None,
             tags: vec!["backend".to_string()],
-            config_dir: None,
+            ..Default::default()
         };
 
         let config = Config {
             repositories: vec![repository],
-            recipes: vec![],
+            version: None,
+            ..Default::default()
         };
 
         let context = CommandContext {
@@ -239,17 +375,15 @@ mod tests {
             exclude_tag: vec![],
             repos: None,
             parallel: false,
+            outcomes: OutcomeRecorder::new(),
+            jobs: None,
         };
 
         let pr_command = PrCommand {
             title: "Integration Test PR".to_string(),
             body: "Integration test body".to_string(),
-            branch_name: None,
-            base_branch: None,
-            commit_msg: None,
-            draft: false,
             token: "test_token".to_string(),
-            create_only: false,
+            ..Default::default()
         };
 
         // This will hit the error handling paths since the repo doesn't exist
@@ -263,14 +397,14 @@ mod tests {
             name: "test-repo-parallel".to_string(),
             url: "https://github.com/test/repo.git".to_string(),
             path: Some("./nonexistent-parallel".to_string()),
-            branch: None,
             tags: vec!["test".to_string()],
-            config_dir: None,
+            ..Default::default()
         };
 
         let config = Config {
             repositories: vec![repository],
-            recipes: vec![],
+            version: None,
+            ..Default::default()
         };
 
         let context = CommandContext {
@@ -279,17 +413,15 @@ mod tests {
             exclude_tag: vec![],
             repos: None,
             parallel: true, // Test parallel execution path
+            outcomes: OutcomeRecorder::new(),
+            jobs: None,
         };
 
         let pr_command = PrCommand {
             title: "Parallel Test PR".to_string(),
             body: "Parallel test body".to_string(),
-            branch_name: None,
-            base_branch: None,
-            commit_msg: None,
-            draft: false,
             token: "test_token".to_string(),
-            create_only: false,
+            ..Default::default()
         };
 
         // This will hit the parallel execution error handling paths
@@ -303,12 +435,8 @@ mod tests {
         let pr_command = PrCommand {
             title: "Module Test".to_string(),
             body: "Module test body".to_string(),
-            branch_name: None,
-            base_branch: None,
-            commit_msg: None,
-            draft: false,
             token: "test_token".to_string(),
-            create_only: false,
+            ..Default::default()
         };
 
         assert_eq!(pr_command.title, "Module Test");
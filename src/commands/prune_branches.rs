@@ -0,0 +1,128 @@
+//! Prune branches command implementation
+
+use super::{Command, CommandContext};
+use crate::config::Repository;
+use crate::git;
+use anyhow::Result;
+use async_trait::async_trait;
+use colored::*;
+use std::time::Instant;
+
+/// Prune branches command for deleting branches already merged into the default branch
+pub struct PruneBranchesCommand {
+    /// List the branches that would be deleted without deleting them
+    pub dry_run: bool,
+    /// Also delete merged branches on `origin`
+    pub remote: bool,
+}
+
+impl PruneBranchesCommand {
+    /// Delete (or, on a dry run, list) one clone's merged branches
+    fn prune(&self, repo: &Repository) -> Result<()> {
+        let repo_path = repo.get_target_dir();
+        let default_branch = git::get_default_branch(&repo_path)?;
+        let target = git::merge_target(&repo_path, &default_branch);
+
+        let local = git::merged_local_branches(&repo_path, &target, &default_branch)?;
+        let remote = if self.remote {
+            git::merged_remote_branches(&repo_path, &target, &default_branch)?
+        } else {
+            Vec::new()
+        };
+
+        if local.is_empty() && remote.is_empty() {
+            println!(
+                "{} | No branches merged into {}",
+                repo.name.cyan().bold(),
+                target
+            );
+            return Ok(());
+        }
+
+        let verb = if self.dry_run {
+            "Would delete"
+        } else {
+            "Deleted"
+        };
+        for branch in &local {
+            if !self.dry_run {
+                git::delete_local_branch(&repo_path, branch)?;
+            }
+            println!("{} | {} branch {}", repo.name.cyan().bold(), verb, branch);
+        }
+        for branch in &remote {
+            if !self.dry_run {
+                git::delete_remote_branch(&repo_path, branch, repo.ssh_key.as_deref())?;
+            }
+            println!(
+                "{} | {} remote branch origin/{}",
+                repo.name.cyan().bold(),
+                verb,
+                branch
+            );
+        }
+        Ok(())
+    }
+}
+
+#[async_trait]
+impl Command for PruneBranchesCommand {
+    async fn execute(&self, context: &CommandContext) -> Result<()> {
+        let repositories = context.config.filter_repositories(
+            &context.tag,
+            &context.exclude_tag,
+            context.repos.as_deref(),
+        );
+
+        if repositories.is_empty() {
+            println!(
+                "{}",
+                context.with_tag_hint("No repositories found").yellow()
+            );
+            return Ok(());
+        }
+
+        let action = if self.dry_run { "Checking" } else { "Pruning" };
+        println!(
+            "{}",
+            format!(
+                "{} merged branches in {} repositories...",
+                action,
+                repositories.len()
+            )
+            .green()
+        );
+
+        let mut failed = 0;
+        for repo in &repositories {
+            if !repo.exists() {
+                println!(
+                    "{} | {}",
+                    repo.name.cyan().bold(),
+                    "Not cloned, skipping".yellow()
+                );
+                continue;
+            }
+
+            let started = Instant::now();
+            let result = self.prune(repo);
+            context
+                .outcomes
+                .record_result(&repo.name, &result, started.elapsed());
+            if let Err(e) = result {
+                eprintln!(
+                    "{} | {}",
+                    repo.name.cyan().bold(),
+                    format!("Error: {e}").red()
+                );
+                failed += 1;
+            }
+        }
+
+        if failed > 0 {
+            anyhow::bail!("Failed to prune branches in {} repositories", failed);
+        }
+        println!("{}", "Done pruning branches".green());
+        Ok(())
+    }
+}
@@ -0,0 +1,222 @@
+//! Pull command implementation
+
+use super::{Command, CommandContext};
+use crate::config::Repository;
+use crate::git;
+use anyhow::Result;
+use async_trait::async_trait;
+use colored::*;
+use std::sync::Arc;
+use std::time::Instant;
+use tokio::sync::Semaphore;
+
+/// Pull command for updating cloned repositories from their remotes
+#[derive(Debug, Default)]
+pub struct PullCommand {
+    pub options: git::PullOptions,
+}
+
+#[async_trait]
+impl Command for PullCommand {
+    async fn execute(&self, context: &CommandContext) -> Result<()> {
+        let repositories = context.config.filter_repositories(
+            &context.tag,
+            &context.exclude_tag,
+            context.repos.as_deref(),
+        );
+
+        let (repositories, missing): (Vec<Repository>, Vec<Repository>) =
+            repositories.into_iter().partition(Repository::exists);
+        for repo in &missing {
+            git::Logger.warn(repo, "Not cloned, skipping");
+        }
+
+        if repositories.is_empty() {
+            println!("{}", "No cloned repositories to pull".yellow());
+            return Ok(());
+        }
+
+        println!(
+            "{}",
+            format!("Pulling {} repositories...", repositories.len()).green()
+        );
+
+        let mut results = Vec::new();
+        if context.parallel {
+            let permits = Arc::new(Semaphore::new(
+                context.jobs.unwrap_or(repositories.len()).max(1),
+            ));
+            let tasks: Vec<_> = repositories
+                .into_iter()
+                .map(|repo| {
+                    let repo_name = repo.name.clone();
+                    let outcomes = context.outcomes.clone();
+                    let permits = permits.clone();
+                    let options = self.options.for_repository(&repo);
+                    tokio::spawn(async move {
+                        let _permit = permits.acquire_owned().await?;
+                        if outcomes.skip_if_aborted(&repo_name) {
+                            return Ok(None);
+                        }
+                        let started = Instant::now();
+                        let result = tokio::task::spawn_blocking(move || {
+                            git::pull_repository_with(&repo, &options)
+                        })
+                        .await?;
+                        outcomes.record_result(&repo_name, &result, started.elapsed());
+                        Ok::<_, anyhow::Error>(Some((repo_name, result)))
+                    })
+                })
+                .collect();
+
+            for task in tasks {
+                match task.await? {
+                    Ok(Some(result)) => results.push(result),
+                    Ok(None) => {}
+                    Err(e) => results.push(("unknown".to_string(), Err(e))),
+                }
+            }
+        } else {
+            for repo in repositories {
+                if context.outcomes.skip_if_aborted(&repo.name) {
+                    continue;
+                }
+                let repo_name = repo.name.clone();
+                let started = Instant::now();
+                let options = self.options.for_repository(&repo);
+                let result =
+                    tokio::task::spawn_blocking(move || git::pull_repository_with(&repo, &options))
+                        .await?;
+                context
+                    .outcomes
+                    .record_result(&repo_name, &result, started.elapsed());
+                results.push((repo_name, result));
+            }
+        }
+
+        report_results(&results)
+    }
+}
+
+/// Print the per-repository failures and the summary, listing merge conflicts
+/// separately; repositories locked by another process count as skipped
+fn report_results(results: &[(String, Result<()>)]) -> Result<()> {
+    let successful = results.iter().filter(|(_, result)| result.is_ok()).count();
+    let mut skipped = 0;
+    let mut conflicts = Vec::new();
+    let mut errors = Vec::new();
+    for (repo_name, result) in results {
+        let Err(e) = result else { continue };
+        if e.downcast_ref::<git::RepositoryLocked>().is_some() {
+            // lock_repository has already warned about it
+            skipped += 1;
+            continue;
+        }
+        match e.downcast_ref::<git::MergeConflict>() {
+            Some(conflict) => conflicts.push((repo_name, conflict)),
+            None => {
+                eprintln!(
+                    "{} | {}",
+                    repo_name.cyan().bold(),
+                    format!("Error: {e}").red()
+                );
+                errors.push(e);
+            }
+        }
+    }
+
+    if !conflicts.is_empty() {
+        eprintln!();
+        eprintln!(
+            "{}",
+            format!("Merge conflicts in {} repositories:", conflicts.len())
+                .red()
+                .bold()
+        );
+        for (repo_name, conflict) in &conflicts {
+            let state = if conflict.aborted {
+                "merge aborted"
+            } else {
+                "resolve and commit, or run `git merge --abort`"
+            };
+            eprintln!("  {} ({})", repo_name.cyan().bold(), state);
+            for file in &conflict.files {
+                eprintln!("    {}", file.red());
+            }
+        }
+    }
+
+    let failed = conflicts.len() + errors.len();
+    if failed == 0 {
+        println!("{}", "Done pulling repositories".green());
+        return Ok(());
+    }
+
+    println!(
+        "{}",
+        format!(
+            "Completed with {} successful, {} failed ({} with merge conflicts), {} skipped",
+            successful,
+            failed,
+            conflicts.len(),
+            skipped
+        )
+        .yellow()
+    );
+
+    // If all operations failed, return an error to propagate to main
+    if successful == 0 {
+        let first = results
+            .iter()
+            .filter_map(|(_, result)| result.as_ref().err())
+            .find(|e| e.downcast_ref::<git::RepositoryLocked>().is_none())
+            .expect("at least one failure");
+        return Err(anyhow::anyhow!(
+            "All pull operations failed. First error: {}",
+            first
+        ));
+    }
+    Ok(())
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn conflict(files: &[&str]) -> anyhow::Error {
+        git::MergeConflict {
+            files: files.iter().map(|file| file.to_string()).collect(),
+            aborted: false,
+        }
+        .into()
+    }
+
+    #[test]
+    fn test_report_results_fails_only_when_every_pull_failed() {
+        let mixed = vec![
+            ("api".to_string(), Ok(())),
+            ("web".to_string(), Err(conflict(&["index.html"]))),
+        ];
+        assert!(report_results(&mixed).is_ok());
+
+        let all_failed = vec![
+            ("web".to_string(), Err(conflict(&["index.html"]))),
+            ("docs".to_string(), Err(anyhow::anyhow!("network down"))),
+        ];
+        let err = report_results(&all_failed).unwrap_err();
+        assert!(err.to_string().contains("Merge conflict in 1 files"));
+    }
+
+    #[test]
+    fn test_report_results_does_not_count_locked_repositories_as_failed() {
+        let locked = vec![("api".to_string(), Err(git::RepositoryLocked.into()))];
+        assert!(report_results(&locked).is_ok());
+
+        let locked_and_failed = vec![
+            ("api".to_string(), Err(git::RepositoryLocked.into())),
+            ("docs".to_string(), Err(anyhow::anyhow!("network down"))),
+        ];
+        let err = report_results(&locked_and_failed).unwrap_err();
+        assert!(err.to_string().contains("network down"));
+    }
+}
@@ -5,6 +5,7 @@ use crate::git;
 use anyhow::Result;
 use async_trait::async_trait;
 use colored::*;
+use std::time::Instant;
 
 /// Remove command for deleting cloned repositories
 pub struct RemoveCommand;
@@ -25,10 +26,8 @@ impl Command for RemoveCommand {
                 (true, Some(repos)) => format!("repositories {repos:?}"),
                 (true, None) => "no repositories found".to_string(),
             };
-            println!(
-                "{}",
-                format!("No repositories found with {filter_desc}").yellow()
-            );
+            let message = format!("No repositories found with {filter_desc}");
+            println!("{}", context.with_tag_hint(&message).yellow());
             return Ok(());
         }
 
@@ -45,7 +44,9 @@ impl Command for RemoveCommand {
                 .into_iter()
                 .map(|repo| {
                     let repo_name = repo.name.clone();
+                    let outcomes = context.outcomes.clone();
                     tokio::spawn(async move {
+                        let started = Instant::now();
                         let result = tokio::task::spawn_blocking(move || {
                             match git::remove_repository(&repo) {
                                 Ok(_) => Ok(()),
@@ -63,6 +64,7 @@ impl Command for RemoveCommand {
                             }
                         })
                         .await?;
+                        outcomes.record_result(&repo_name, &result, started.elapsed());
                         Ok::<_, anyhow::Error>((repo_name, result))
                     })
                 })
@@ -83,8 +85,10 @@ impl Command for RemoveCommand {
             }
         } else {
             for repo in repositories {
+                let started = Instant::now();
                 match git::remove_repository(&repo) {
                     Ok(_) => {
+                        context.outcomes.record(&repo.name, None, started.elapsed());
                         successful += 1;
                     }
                     Err(e)
@@ -92,9 +96,13 @@ impl Command for RemoveCommand {
                             .contains("Repository directory does not exist") =>
                     {
                         println!("{} | Directory does not exist", repo.name.cyan().bold());
+                        context.outcomes.record(&repo.name, None, started.elapsed());
                         successful += 1; // Count as success since the desired state is achieved
                     }
                     Err(e) => {
+                        context
+                            .outcomes
+                            .record(&repo.name, Some(e.to_string()), started.elapsed());
                         eprintln!(
                             "{} | {}",
                             repo.name.cyan().bold(),
@@ -136,6 +144,7 @@ impl Command for RemoveCommand {
 #[cfg(test)]
 mod tests {
     use super::*;
+    use crate::commands::OutcomeRecorder;
     use crate::config::{Config, Repository};
     use std::fs;
     use tempfile::TempDir;
@@ -154,20 +163,22 @@ mod tests {
             url: "https://github.com/user/test-repo.git".to_string(),
             tags: vec!["test".to_string()],
             path: Some(repo_dir.to_string_lossy().to_string()),
-            branch: None,
-            config_dir: None,
+            ..Default::default()
         };
 
         let command = RemoveCommand;
         let context = CommandContext {
             config: Config {
                 repositories: vec![repo],
-                recipes: vec![],
+                version: None,
+                ..Default::default()
             },
             tag: vec![],
             exclude_tag: vec![],
             repos: None,
             parallel: false,
+            outcomes: OutcomeRecorder::new(),
+            jobs: None,
         };
 
         assert!(repo_dir.exists());
@@ -197,8 +208,7 @@ mod tests {
                 url: format!("https://github.com/user/repo-{}.git", i),
                 tags: vec!["test".to_string()],
                 path: Some(repo_dir.to_string_lossy().to_string()),
-                branch: None,
-                config_dir: None,
+                ..Default::default()
             };
 
             repositories.push(repo);
@@ -209,12 +219,15 @@ mod tests {
         let context = CommandContext {
             config: Config {
                 repositories,
-                recipes: vec![],
+                version: None,
+                ..Default::default()
             },
             tag: vec![],
             exclude_tag: vec![],
             repos: None,
             parallel: false,
+            outcomes: OutcomeRecorder::new(),
+            jobs: None,
         };
 
         // Verify all directories exist
@@ -249,8 +262,7 @@ mod tests {
                 url: format!("https://github.com/user/parallel-repo-{}.git", i),
                 tags: vec!["test".to_string()],
                 path: Some(repo_dir.to_string_lossy().to_string()),
-                branch: None,
-                config_dir: None,
+                ..Default::default()
             };
 
             repositories.push(repo);
@@ -261,12 +273,15 @@ mod tests {
         let context = CommandContext {
             config: Config {
                 repositories,
-                recipes: vec![],
+                version: None,
+                ..Default::default()
             },
             tag: vec![],
             exclude_tag: vec![],
             repos: None,
             parallel: true, // Enable parallel execution
+            outcomes: OutcomeRecorder::new(),
+            jobs: None,
         };
 
         // Verify all directories exist
@@ -295,20 +310,22 @@ mod tests {
             url: "https://github.com/user/nonexistent-repo.git".to_string(),
             tags: vec!["test".to_string()],
             path: Some(repo_dir.to_string_lossy().to_string()),
-            branch: None,
-            config_dir: None,
+            ..Default::default()
         };
 
         let command = RemoveCommand;
         let context = CommandContext {
             config: Config {
                 repositories: vec![repo],
-                recipes: vec![],
+                version: None,
+                ..Default::default()
             },
             tag: vec![],
             exclude_tag: vec![],
             repos: None,
             parallel: false,
+            outcomes: OutcomeRecorder::new(),
+            jobs: None,
         };
 
         assert!(!repo_dir.exists());
@@ -330,8 +347,7 @@ mod tests {
             url: "https://github.com/user/matching-repo.git".to_string(),
             tags: vec!["backend".to_string()],
             path: Some(matching_repo_dir.to_string_lossy().to_string()),
-            branch: None,
-            config_dir: None,
+            ..Default::default()
         };
 
         // Create repository with non-matching tag
@@ -343,20 +359,22 @@ mod tests {
             url: "https://github.com/user/non-matching-repo.git".to_string(),
             tags: vec!["frontend".to_string()],
             path: Some(non_matching_repo_dir.to_string_lossy().to_string()),
-            branch: None,
-            config_dir: None,
+            ..Default::default()
         };
 
         let command = RemoveCommand;
         let context = CommandContext {
             config: Config {
                 repositories: vec![matching_repo, non_matching_repo],
-                recipes: vec![],
+                version: None,
+                ..Default::default()
             },
             tag: vec!["backend".to_string()],
             exclude_tag: vec![],
             repos: None,
             parallel: false,
+            outcomes: OutcomeRecorder::new(),
+            jobs: None,
         };
 
         assert!(matching_repo_dir.exists());
@@ -386,8 +404,7 @@ mod tests {
             url: "https://github.com/user/repo1.git".to_string(),
             tags: vec!["test".to_string()],
             path: Some(repo1_dir.to_string_lossy().to_string()),
-            branch: None,
-            config_dir: None,
+            ..Default::default()
         };
 
         let repo2 = Repository {
@@ -395,20 +412,22 @@ mod tests {
             url: "https://github.com/user/repo2.git".to_string(),
             tags: vec!["test".to_string()],
             path: Some(repo2_dir.to_string_lossy().to_string()),
-            branch: None,
-            config_dir: None,
+            ..Default::default()
         };
 
         let command = RemoveCommand;
         let context = CommandContext {
             config: Config {
                 repositories: vec![repo1, repo2],
-                recipes: vec![],
+                version: None,
+                ..Default::default()
             },
             tag: vec![],
             exclude_tag: vec![],
             repos: Some(vec!["repo1".to_string()]), // Only remove repo1
             parallel: false,
+            outcomes: OutcomeRecorder::new(),
+            jobs: None,
         };
 
         assert!(repo1_dir.exists());
@@ -437,20 +456,22 @@ mod tests {
                     .to_string_lossy()
                     .to_string(),
             ),
-            branch: None,
-            config_dir: None,
+            ..Default::default()
         };
 
         let command = RemoveCommand;
         let context = CommandContext {
             config: Config {
                 repositories: vec![repo],
-                recipes: vec![],
+                version: None,
+                ..Default::default()
             },
             tag: vec!["frontend".to_string()], // Non-matching tag
             exclude_tag: vec![],
             repos: None,
             parallel: false,
+            outcomes: OutcomeRecorder::new(),
+            jobs: None,
         };
 
         let result = command.execute(&context).await;
@@ -462,13 +483,15 @@ mod tests {
         let command = RemoveCommand;
         let context = CommandContext {
             config: Config {
-                repositories: vec![],
-                recipes: vec![],
+                version: None,
+                ..Default::default()
             },
             tag: vec![],
             exclude_tag: vec![],
             repos: None,
             parallel: false,
+            outcomes: OutcomeRecorder::new(),
+            jobs: None,
         };
 
         let result = command.execute(&context).await;
@@ -493,20 +516,22 @@ mod tests {
             url: "https://github.com/user/protected-repo.git".to_string(),
             tags: vec!["test".to_string()],
             path: Some(repo_dir.to_string_lossy().to_string()),
-            branch: None,
-            config_dir: None,
+            ..Default::default()
         };
 
         let command = RemoveCommand;
         let context = CommandContext {
             config: Config {
                 repositories: vec![repo],
-                recipes: vec![],
+                version: None,
+                ..Default::default()
             },
             tag: vec![],
             exclude_tag: vec![],
             repos: None,
             parallel: false,
+            outcomes: OutcomeRecorder::new(),
+            jobs: None,
         };
 
         let result = command.execute(&context).await;
@@ -528,8 +553,7 @@ mod tests {
             url: "https://github.com/user/matching-repo.git".to_string(),
             tags: vec!["backend".to_string()],
             path: Some(matching_repo_dir.to_string_lossy().to_string()),
-            branch: None,
-            config_dir: None,
+            ..Default::default()
         };
 
         // Create repository with matching tag but wrong name
@@ -541,20 +565,22 @@ mod tests {
             url: "https://github.com/user/wrong-name-repo.git".to_string(),
             tags: vec!["backend".to_string()],
             path: Some(wrong_name_repo_dir.to_string_lossy().to_string()),
-            branch: None,
-            config_dir: None,
+            ..Default::default()
         };
 
         let command = RemoveCommand;
         let context = CommandContext {
             config: Config {
                 repositories: vec![matching_repo, wrong_name_repo],
-                recipes: vec![],
+                version: None,
+                ..Default::default()
             },
             tag: vec!["backend".to_string()],
             exclude_tag: vec![],
             repos: Some(vec!["matching-repo".to_string()]),
             parallel: false,
+            outcomes: OutcomeRecorder::new(),
+            jobs: None,
         };
 
         assert!(matching_repo_dir.exists());
@@ -581,8 +607,7 @@ mod tests {
             url: "https://github.com/user/success-repo.git".to_string(),
             tags: vec!["test".to_string()],
             path: Some(success_repo_dir.to_string_lossy().to_string()),
-            branch: None,
-            config_dir: None,
+            ..Default::default()
         };
 
         // Create a repository pointing to a nonexistent directory (should succeed as desired state)
@@ -597,20 +622,22 @@ mod tests {
                     .to_string_lossy()
                     .to_string(),
             ),
-            branch: None,
-            config_dir: None,
+            ..Default::default()
         };
 
         let command = RemoveCommand;
         let context = CommandContext {
             config: Config {
                 repositories: vec![success_repo, nonexistent_repo],
-                recipes: vec![],
+                version: None,
+                ..Default::default()
             },
             tag: vec![],
             exclude_tag: vec![],
             repos: None,
             parallel: true, // Test parallel execution with mixed scenarios
+            outcomes: OutcomeRecorder::new(),
+            jobs: None,
         };
 
         assert!(success_repo_dir.exists());
@@ -0,0 +1,895 @@
+//! Machine-readable run reports
+//!
+//! Commands record a [`RepoOutcome`] for every repository they operate on via
+//! the [`OutcomeRecorder`] carried in the [`CommandContext`](super::CommandContext).
+//! At the end of the invocation the CLI assembles a [`RunReport`] from those
+//! outcomes and, when `--report-file` is given, writes it as JSON. With
+//! `--resume`, the recorder also marks each successful repository in a
+//! [`Checkpoint`] as soon as it finishes. The same outcomes decide the exit
+//! code under the `--exit-policy` [`ExitPolicy`]. With `--max-failures`, the
+//! recorder counts failures across parallel tasks and tells commands when to
+//! stop starting repositories; with `--deadline`, it does so once the deadline
+//! passes and keeps the names of the repositories left incomplete. With
+//! `--on-result`, every outcome is also piped as JSON to a user command, in
+//! recording order and without holding up the command. Repositories skipped
+//! because another `repos` process holds their lock are recorded as skipped,
+//! neither succeeded nor failed. A written report can be read back with
+//! [`RunReport::load`], so `--rerun-failed` can target the repositories that
+//! failed in it.
+
+use super::hooks::ShellHook;
+use crate::config::RunPolicy;
+use crate::runner::{DeadlineExceeded, Shell};
+use crate::utils::Checkpoint;
+use anyhow::{Context, Result};
+use chrono::{DateTime, Utc};
+use serde::{Deserialize, Serialize};
+use std::collections::BTreeSet;
+use std::path::Path;
+use std::str::FromStr;
+use std::sync::atomic::{AtomicUsize, Ordering};
+use std::sync::{Arc, Mutex};
+use std::time::{Duration, Instant};
+use tokio::sync::{mpsc, oneshot};
+
+/// Error recorded for repositories the `--deadline` stopped or kept from starting
+pub const DEADLINE_ERROR: &str = "Incomplete: the --deadline passed";
+
+/// Result of a command for a single repository
+#[derive(Debug, Clone, PartialEq, Serialize, Deserialize)]
+pub struct RepoOutcome {
+    /// Repository name
+    pub name: String,
+    /// Whether the operation succeeded for this repository
+    pub success: bool,
+    /// Error message when the operation failed
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub error: Option<String>,
+    /// Whether the repository was skipped, being locked by another process
+    #[serde(default, skip_serializing_if = "std::ops::Not::not")]
+    pub skipped: bool,
+    /// Wall-clock duration of the operation in milliseconds
+    pub duration_ms: u64,
+}
+
+impl RepoOutcome {
+    /// Whether the operation ran for this repository and failed
+    pub fn failed(&self) -> bool {
+        !self.success && !self.skipped
+    }
+}
+
+/// Thread-safe collector for per-repository outcomes
+///
+/// Cloning the recorder is cheap and all clones share the same storage, so it
+/// can be handed to parallel tasks freely.
+#[derive(Debug, Clone, Default)]
+pub struct OutcomeRecorder {
+    outcomes: Arc<Mutex<Vec<RepoOutcome>>>,
+    checkpoint: Option<Arc<Mutex<Checkpoint>>>,
+    /// `--max-failures`: failures after which no further repository starts
+    max_failures: Option<usize>,
+    failures: Arc<AtomicUsize>,
+    /// Repositories not started because `max_failures` was reached
+    aborted: Arc<AtomicUsize>,
+    /// `--on-result`: queue of outcomes for the task piping them to the command
+    on_result: Option<mpsc::UnboundedSender<OnResult>>,
+    /// `--deadline`: point after which no further repository starts
+    deadline: Option<Instant>,
+    /// Repositories stopped or not started because `deadline` passed
+    incomplete: Arc<Mutex<Vec<String>>>,
+}
+
+impl OutcomeRecorder {
+    pub fn new() -> Self {
+        Self::default()
+    }
+
+    /// Create a recorder that also marks successful repositories in `checkpoint`
+    pub fn with_checkpoint(checkpoint: Arc<Mutex<Checkpoint>>) -> Self {
+        Self {
+            checkpoint: Some(checkpoint),
+            ..Self::default()
+        }
+    }
+
+    /// Stop starting repositories once `max_failures` of them have failed
+    pub fn with_max_failures(self, max_failures: Option<usize>) -> Self {
+        Self {
+            max_failures,
+            ..self
+        }
+    }
+
+    /// Pipe every recorded outcome, as JSON, to the shell command `on_result`
+    ///
+    /// The command runs on a task of its own, once per outcome in recording
+    /// order, so recording never waits for it; [`flush`](Self::flush) does.
+    /// Must be called within a Tokio runtime. The command runs with the default
+    /// shell and no policy until [`configure_on_result`](Self::configure_on_result).
+    pub fn with_on_result(self, on_result: Option<String>) -> Self {
+        let on_result = on_result.map(|command| {
+            let (sender, mut receiver) = mpsc::unbounded_channel();
+            tokio::spawn(async move {
+                let mut shell = Shell::default();
+                let mut policy = RunPolicy::default();
+                while let Some(message) = receiver.recv().await {
+                    match message {
+                        OnResult::Outcome(outcome) => {
+                            if let Err(e) = run_on_result(&command, &shell, &policy, &outcome).await
+                            {
+                                eprintln!("Warning: {:#}", e);
+                            }
+                        }
+                        OnResult::Configure {
+                            shell: next_shell,
+                            policy: next_policy,
+                        } => {
+                            shell = next_shell;
+                            policy = next_policy;
+                        }
+                        OnResult::Flush(done) => {
+                            let _ = done.send(());
+                        }
+                    }
+                }
+            });
+            sender
+        });
+        Self { on_result, ..self }
+    }
+
+    /// Run the `--on-result` command with `shell`, and only when `policy` allows it
+    ///
+    /// Applies to the outcomes recorded from now on; `run` passes its `--shell`
+    /// and the config's `run_policy`.
+    pub fn configure_on_result(&self, shell: Shell, policy: RunPolicy) {
+        if let Some(sender) = &self.on_result {
+            let _ = sender.send(OnResult::Configure { shell, policy });
+        }
+    }
+
+    /// Wait until every outcome recorded so far has been piped to `--on-result`
+    pub async fn flush(&self) {
+        let Some(sender) = &self.on_result else {
+            return;
+        };
+        let (done, flushed) = oneshot::channel();
+        if sender.send(OnResult::Flush(done)).is_ok() {
+            let _ = flushed.await;
+        }
+    }
+
+    /// Stop starting repositories once `deadline` has passed
+    pub fn with_deadline(self, deadline: Option<Instant>) -> Self {
+        Self { deadline, ..self }
+    }
+
+    /// The `--deadline`, for commands to stop what is still running at it
+    pub fn deadline(&self) -> Option<Instant> {
+        self.deadline
+    }
+
+    /// Whether the `--deadline` has passed
+    pub fn deadline_passed(&self) -> bool {
+        self.deadline
+            .is_some_and(|deadline| Instant::now() >= deadline)
+    }
+
+    /// Whether `--max-failures` was reached or the `--deadline` passed, so
+    /// remaining repositories should be skipped
+    pub fn should_abort(&self) -> bool {
+        self.deadline_passed()
+            || self
+                .max_failures
+                .is_some_and(|max| self.failures.load(Ordering::SeqCst) >= max)
+    }
+
+    /// Whether repository `name`, about to start, must be skipped, counting it if so
+    ///
+    /// Checked right before each repository starts, so repositories already
+    /// running when the limit is reached still finish. Past the deadline the
+    /// repository is recorded as incomplete instead.
+    pub fn skip_if_aborted(&self, name: &str) -> bool {
+        if self.deadline_passed() {
+            self.record_incomplete(name, Duration::ZERO);
+            return true;
+        }
+        if !self.should_abort() {
+            return false;
+        }
+        self.aborted.fetch_add(1, Ordering::SeqCst);
+        true
+    }
+
+    /// Record repository `name`, stopped by the deadline or never started, as failed
+    pub fn record_incomplete(&self, name: &str, duration: Duration) {
+        self.incomplete
+            .lock()
+            .expect("outcome recorder lock poisoned")
+            .push(name.to_string());
+        self.record(name, Some(DEADLINE_ERROR.to_string()), duration);
+    }
+
+    /// Repositories the `--deadline` left incomplete, in recording order
+    pub fn incomplete(&self) -> Vec<String> {
+        self.incomplete
+            .lock()
+            .expect("outcome recorder lock poisoned")
+            .clone()
+    }
+
+    /// Number of repositories skipped because `--max-failures` was reached
+    pub fn aborted(&self) -> usize {
+        self.aborted.load(Ordering::SeqCst)
+    }
+
+    /// Record the outcome for a repository
+    pub fn record(&self, name: &str, error: Option<String>, duration: Duration) {
+        if error.is_some() {
+            self.failures.fetch_add(1, Ordering::SeqCst);
+        }
+
+        if error.is_none()
+            && let Some(checkpoint) = &self.checkpoint
+            && let Err(e) = checkpoint
+                .lock()
+                .expect("checkpoint lock poisoned")
+                .mark_completed(name)
+        {
+            // Losing checkpoint progress must not fail the operation itself
+            eprintln!("Warning: {}", e);
+        }
+
+        self.push(RepoOutcome {
+            name: name.to_string(),
+            success: error.is_none(),
+            error,
+            skipped: false,
+            duration_ms: duration.as_millis() as u64,
+        });
+    }
+
+    /// Record repository `name` as skipped, neither succeeded nor failed
+    ///
+    /// The repository is not marked in the checkpoint, so `--resume` retries it.
+    pub fn record_skipped(&self, name: &str, duration: Duration) {
+        self.push(RepoOutcome {
+            name: name.to_string(),
+            success: false,
+            error: None,
+            skipped: true,
+            duration_ms: duration.as_millis() as u64,
+        });
+    }
+
+    fn push(&self, outcome: RepoOutcome) {
+        if let Some(sender) = &self.on_result {
+            let _ = sender.send(OnResult::Outcome(outcome.clone()));
+        }
+        self.outcomes
+            .lock()
+            .expect("outcome recorder lock poisoned")
+            .push(outcome);
+    }
+
+    /// Record the outcome of a fallible operation for a repository
+    ///
+    /// A [`RepositoryLocked`](crate::git::RepositoryLocked) error records the
+    /// repository as skipped, and a [`DeadlineExceeded`] one as incomplete.
+    pub fn record_result<T>(&self, name: &str, result: &Result<T>, duration: Duration) {
+        match result {
+            Err(e) if e.is::<DeadlineExceeded>() => self.record_incomplete(name, duration),
+            Err(e) if e.downcast_ref::<crate::git::RepositoryLocked>().is_some() => {
+                self.record_skipped(name, duration)
+            }
+            _ => self.record(name, result.as_ref().err().map(|e| e.to_string()), duration),
+        }
+    }
+
+    /// Snapshot of all outcomes recorded so far, in recording order
+    pub fn outcomes(&self) -> Vec<RepoOutcome> {
+        self.outcomes
+            .lock()
+            .expect("outcome recorder lock poisoned")
+            .clone()
+    }
+}
+
+/// Work for the `--on-result` task
+#[derive(Debug)]
+enum OnResult {
+    Outcome(RepoOutcome),
+    /// Shell and policy for the outcomes queued after it
+    Configure {
+        shell: Shell,
+        policy: RunPolicy,
+    },
+    /// Answered once every outcome queued before it has been piped
+    Flush(oneshot::Sender<()>),
+}
+
+/// Run the `--on-result` shell command with `outcome` as one line of JSON on stdin
+async fn run_on_result(
+    command: &str,
+    shell: &Shell,
+    policy: &RunPolicy,
+    outcome: &RepoOutcome,
+) -> Result<()> {
+    ShellHook {
+        label: "--on-result command",
+        command,
+        shell,
+        policy,
+        trace: &outcome.name,
+        dir: None,
+        env: Vec::new(),
+        stdin: Some(format!("{}\n", serde_json::to_string(outcome)?).into_bytes()),
+    }
+    .run()
+    .await
+    .with_context(|| format!("--on-result failed for {}", outcome.name))
+}
+
+/// Summary of a whole CLI invocation
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct RunReport {
+    /// Name of the command that was executed (e.g. `clone`, `run`)
+    pub command: String,
+    /// Options the command was invoked with
+    pub options: serde_json::Value,
+    /// Start time in RFC 3339 format
+    pub started_at: String,
+    /// Completion time in RFC 3339 format
+    pub finished_at: String,
+    /// Total duration of the command in milliseconds
+    pub duration_ms: u64,
+    /// Whether the command and every repository operation succeeded
+    pub success: bool,
+    /// Top-level error message when the command failed
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub error: Option<String>,
+    /// Per-repository outcomes
+    pub repositories: Vec<RepoOutcome>,
+}
+
+impl RunReport {
+    /// Build a report for a finished command
+    ///
+    /// The run counts as successful only if the command itself returned `Ok`
+    /// and no repository recorded a failure; skipped repositories do not count.
+    pub fn new(
+        command: &str,
+        options: serde_json::Value,
+        started_at: DateTime<Utc>,
+        repositories: Vec<RepoOutcome>,
+        result: &Result<()>,
+    ) -> Self {
+        let finished_at = Utc::now();
+        let duration_ms = (finished_at - started_at).num_milliseconds().max(0) as u64;
+        let success = result.is_ok() && !repositories.iter().any(RepoOutcome::failed);
+
+        Self {
+            command: command.to_string(),
+            options,
+            started_at: started_at.to_rfc3339(),
+            finished_at: finished_at.to_rfc3339(),
+            duration_ms,
+            success,
+            error: result.as_ref().err().map(|e| e.to_string()),
+            repositories,
+        }
+    }
+
+    /// Write the report as pretty-printed JSON, creating parent directories as needed
+    pub fn write_to(&self, path: &Path) -> Result<()> {
+        if let Some(parent) = path.parent()
+            && !parent.as_os_str().is_empty()
+        {
+            std::fs::create_dir_all(parent).with_context(|| {
+                format!("Failed to create report directory: {}", parent.display())
+            })?;
+        }
+
+        let content = serde_json::to_string_pretty(self)?;
+        std::fs::write(path, content)
+            .with_context(|| format!("Failed to write report file: {}", path.display()))?;
+        Ok(())
+    }
+
+    /// Read a report written by [`RunReport::write_to`]
+    pub fn load(path: &Path) -> Result<Self> {
+        let content = std::fs::read_to_string(path)
+            .with_context(|| format!("Failed to read report file: {}", path.display()))?;
+        serde_json::from_str(&content)
+            .with_context(|| format!("Invalid report file: {}", path.display()))
+    }
+
+    /// Names of the repositories that failed
+    pub fn failed_repositories(&self) -> BTreeSet<String> {
+        self.repositories
+            .iter()
+            .filter(|outcome| outcome.failed())
+            .map(|outcome| outcome.name.clone())
+            .collect()
+    }
+}
+
+/// How repository failures translate to the exit code (`--exit-policy`)
+#[derive(Debug, Clone, Copy, Default, PartialEq, Eq)]
+pub enum ExitPolicy {
+    /// Fail if any repository failed
+    #[default]
+    AnyFailure,
+    /// Fail only if every repository failed
+    AllFailure,
+    /// Never fail because of repositories, for best-effort runs
+    Never,
+    /// Fail only if more than this many repositories failed (`pr --max-pr-failures`)
+    AtMostFailures(usize),
+}
+
+impl ExitPolicy {
+    /// Decide the result of the invocation from the command's own result and its outcomes
+    ///
+    /// Errors raised before any repository was processed, such as an invalid
+    /// config, always fail the invocation. Once repositories have outcomes,
+    /// a command error the policy tolerates is printed as a warning instead.
+    pub fn apply(self, result: Result<()>, outcomes: &[RepoOutcome]) -> Result<()> {
+        if outcomes.is_empty() {
+            return result;
+        }
+        let failed = outcomes.iter().filter(|outcome| outcome.failed()).count();
+        let fails = self.fails(result.is_err(), outcomes);
+
+        match result {
+            Err(e) if fails => Err(e),
+            Err(e) => {
+                eprintln!("Warning: {:#}", e);
+                Ok(())
+            }
+            Ok(()) if fails => {
+                anyhow::bail!("{} of {} repositories failed", failed, outcomes.len())
+            }
+            Ok(()) => Ok(()),
+        }
+    }
+
+    /// Whether `outcomes`, and a command error if `command_failed`, fail the invocation
+    pub fn fails(self, command_failed: bool, outcomes: &[RepoOutcome]) -> bool {
+        let failed = outcomes.iter().filter(|outcome| outcome.failed()).count();
+        let skipped = outcomes.iter().filter(|outcome| outcome.skipped).count();
+        match self {
+            Self::AnyFailure => failed > 0 || command_failed,
+            Self::AllFailure => failed > 0 && failed + skipped == outcomes.len(),
+            Self::Never => false,
+            Self::AtMostFailures(max) => failed > max || command_failed,
+        }
+    }
+}
+
+impl FromStr for ExitPolicy {
+    type Err = anyhow::Error;
+
+    fn from_str(value: &str) -> Result<Self> {
+        match value {
+            "any-failure" => Ok(Self::AnyFailure),
+            "all-failure" => Ok(Self::AllFailure),
+            "never" => Ok(Self::Never),
+            _ => anyhow::bail!(
+                "Unknown exit policy '{}': expected any-failure, all-failure or never",
+                value
+            ),
+        }
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use tempfile::TempDir;
+
+    #[test]
+    fn test_recorder_records_success_and_failure() {
+        let recorder = OutcomeRecorder::new();
+        recorder.record("repo-a", None, Duration::from_millis(12));
+        recorder.record("repo-b", Some("boom".to_string()), Duration::from_millis(3));
+
+        let outcomes = recorder.outcomes();
+        assert_eq!(outcomes.len(), 2);
+        assert!(outcomes[0].success);
+        assert_eq!(outcomes[0].duration_ms, 12);
+        assert!(!outcomes[1].success);
+        assert_eq!(outcomes[1].error.as_deref(), Some("boom"));
+    }
+
+    #[test]
+    fn test_recorder_aborts_after_max_failures_across_clones() {
+        let recorder = OutcomeRecorder::new().with_max_failures(Some(2));
+        let worker = recorder.clone();
+        assert!(!worker.skip_if_aborted("a"));
+        worker.record("a", Some("boom".to_string()), Duration::ZERO);
+        recorder.record("b", None, Duration::ZERO);
+        assert!(!recorder.should_abort());
+
+        recorder.record("c", Some("boom".to_string()), Duration::ZERO);
+        assert!(worker.should_abort());
+        assert!(worker.skip_if_aborted("d"));
+        assert!(recorder.skip_if_aborted("e"));
+        assert_eq!(recorder.aborted(), 2);
+        // Skipped repositories have no outcome of their own
+        assert_eq!(recorder.outcomes().len(), 3);
+
+        let unlimited = OutcomeRecorder::new();
+        unlimited.record("a", Some("boom".to_string()), Duration::ZERO);
+        assert!(!unlimited.skip_if_aborted("b"));
+    }
+
+    #[test]
+    fn test_recorder_marks_repositories_past_the_deadline_incomplete() {
+        let recorder =
+            OutcomeRecorder::new().with_deadline(Some(Instant::now() + Duration::from_secs(60)));
+        assert!(!recorder.skip_if_aborted("a"));
+        recorder.record("a", None, Duration::ZERO);
+        assert!(recorder.incomplete().is_empty());
+
+        let recorder = recorder.with_deadline(Some(Instant::now()));
+        assert!(recorder.should_abort());
+        recorder.record_incomplete("b", Duration::from_millis(40));
+        assert!(recorder.skip_if_aborted("c"));
+        // A git process stopped at the deadline
+        let stopped: Result<()> =
+            Err(anyhow::Error::new(DeadlineExceeded).context("Failed to pull"));
+        recorder.record_result("d", &stopped, Duration::from_millis(10));
+        assert_eq!(recorder.incomplete(), vec!["b", "c", "d"]);
+        // Unlike --max-failures, skipped repositories get an outcome
+        assert_eq!(recorder.aborted(), 0);
+        let outcomes = recorder.outcomes();
+        assert_eq!(outcomes.len(), 4);
+        assert_eq!(outcomes[2].error.as_deref(), Some(DEADLINE_ERROR));
+        assert_eq!(outcomes[3].error.as_deref(), Some(DEADLINE_ERROR));
+    }
+
+    #[tokio::test]
+    async fn test_recorder_pipes_each_outcome_to_on_result() {
+        let temp_dir = TempDir::new().unwrap();
+        let captured = temp_dir.path().join("results.jsonl");
+        let recorder =
+            OutcomeRecorder::new().with_on_result(Some(format!("cat >> '{}'", captured.display())));
+
+        recorder.record("api", None, Duration::from_millis(12));
+        recorder.clone().record(
+            "web",
+            Some("exit code 2".to_string()),
+            Duration::from_millis(30),
+        );
+        recorder.flush().await;
+
+        let lines: Vec<RepoOutcome> = std::fs::read_to_string(&captured)
+            .unwrap()
+            .lines()
+            .map(|line| serde_json::from_str(line).unwrap())
+            .collect();
+        assert_eq!(lines, recorder.outcomes());
+        assert_eq!(
+            std::fs::read_to_string(&captured).unwrap().lines().nth(1),
+            Some(r#"{"name":"web","success":false,"error":"exit code 2","duration_ms":30}"#)
+        );
+    }
+
+    #[tokio::test]
+    async fn test_failing_on_result_still_records_outcome() {
+        let recorder = OutcomeRecorder::new().with_on_result(Some("exit 3".to_string()));
+        recorder.record("api", None, Duration::from_millis(1));
+        recorder.flush().await;
+        assert_eq!(recorder.outcomes().len(), 1);
+        assert!(recorder.outcomes()[0].success);
+
+        let outcome = &recorder.outcomes()[0];
+        let err = run_on_result("exit 3", &Shell::default(), &RunPolicy::default(), outcome)
+            .await
+            .unwrap_err();
+        assert!(err.to_string().contains("failed for api"));
+    }
+
+    #[tokio::test]
+    async fn test_on_result_uses_the_configured_shell_and_policy() {
+        let temp_dir = TempDir::new().unwrap();
+        let captured = temp_dir.path().join("results.jsonl");
+        // `[[` is a bash builtin that plain `sh` may not have
+        let recorder = OutcomeRecorder::new().with_on_result(Some(format!(
+            "[[ -n x ]] && cat >> '{}'",
+            captured.display()
+        )));
+        recorder.configure_on_result(Shell::new("bash"), RunPolicy::default());
+        recorder.record("api", None, Duration::from_millis(1));
+        recorder.configure_on_result(
+            Shell::new("bash"),
+            RunPolicy {
+                deny: vec![r"^\[\[".to_string()],
+                ..RunPolicy::default()
+            },
+        );
+        recorder.record("web", None, Duration::from_millis(1));
+        recorder.flush().await;
+
+        // The refused command never ran for web, which is still recorded
+        let piped = std::fs::read_to_string(&captured).unwrap();
+        assert_eq!(piped.lines().count(), 1);
+        assert!(piped.contains(r#""name":"api""#));
+        assert_eq!(recorder.outcomes().len(), 2);
+    }
+
+    #[test]
+    fn test_recorder_clones_share_storage() {
+        let recorder = OutcomeRecorder::new();
+        let clone = recorder.clone();
+        clone.record_result::<()>("repo", &Ok(()), Duration::ZERO);
+
+        assert_eq!(recorder.outcomes().len(), 1);
+    }
+
+    #[test]
+    fn test_recorder_marks_only_successes_in_checkpoint() {
+        let temp_dir = TempDir::new().unwrap();
+        let checkpoint = Arc::new(Mutex::new(
+            Checkpoint::open(temp_dir.path(), "key", "clone").unwrap(),
+        ));
+        let recorder = OutcomeRecorder::with_checkpoint(checkpoint.clone());
+
+        recorder.record("done", None, Duration::ZERO);
+        recorder.record("failed", Some("boom".to_string()), Duration::ZERO);
+
+        let checkpoint = checkpoint.lock().unwrap();
+        assert!(checkpoint.is_completed("done"));
+        assert!(!checkpoint.is_completed("failed"));
+    }
+
+    #[test]
+    fn test_record_result_uses_error_message() {
+        let recorder = OutcomeRecorder::new();
+        let result: Result<()> = Err(anyhow::anyhow!("clone failed"));
+        recorder.record_result("repo", &result, Duration::ZERO);
+
+        let outcomes = recorder.outcomes();
+        assert_eq!(outcomes[0].error.as_deref(), Some("clone failed"));
+    }
+
+    #[test]
+    fn test_locked_repository_is_recorded_as_skipped() {
+        let temp_dir = TempDir::new().unwrap();
+        let checkpoint = Arc::new(Mutex::new(
+            Checkpoint::open(temp_dir.path(), "key", "pull").unwrap(),
+        ));
+        let recorder =
+            OutcomeRecorder::with_checkpoint(checkpoint.clone()).with_max_failures(Some(1));
+        let result: Result<()> = Err(crate::git::RepositoryLocked.into());
+        recorder.record_result("locked", &result, Duration::ZERO);
+
+        let outcome = &recorder.outcomes()[0];
+        assert!(outcome.skipped && !outcome.success && !outcome.failed());
+        assert_eq!(outcome.error, None);
+        assert!(!recorder.should_abort());
+        assert!(
+            ExitPolicy::AnyFailure
+                .apply(Ok(()), &recorder.outcomes())
+                .is_ok()
+        );
+        let report = RunReport::new(
+            "pull",
+            serde_json::json!({}),
+            Utc::now(),
+            recorder.outcomes(),
+            &Ok(()),
+        );
+        assert!(report.success);
+        assert!(report.failed_repositories().is_empty());
+        assert!(!checkpoint.lock().unwrap().is_completed("locked"));
+    }
+
+    #[test]
+    fn test_report_new_success_requires_all_outcomes_ok() {
+        let outcomes = vec![
+            RepoOutcome {
+                name: "ok".to_string(),
+                success: true,
+                error: None,
+                skipped: false,
+                duration_ms: 1,
+            },
+            RepoOutcome {
+                name: "failed".to_string(),
+                success: false,
+                error: Some("exit 1".to_string()),
+                skipped: false,
+                duration_ms: 1,
+            },
+        ];
+
+        let report = RunReport::new("run", serde_json::json!({}), Utc::now(), outcomes, &Ok(()));
+        assert!(!report.success);
+        assert!(report.error.is_none());
+        assert_eq!(report.repositories.len(), 2);
+    }
+
+    #[test]
+    fn test_report_new_records_command_error() {
+        let result: Result<()> = Err(anyhow::anyhow!("config not found"));
+        let report = RunReport::new("ls", serde_json::json!({}), Utc::now(), Vec::new(), &result);
+
+        assert!(!report.success);
+        assert_eq!(report.error.as_deref(), Some("config not found"));
+        assert_eq!(report.command, "ls");
+    }
+
+    fn outcomes(results: &[bool]) -> Vec<RepoOutcome> {
+        results
+            .iter()
+            .enumerate()
+            .map(|(i, &success)| RepoOutcome {
+                name: format!("repo-{}", i),
+                success,
+                error: (!success).then(|| "exit 1".to_string()),
+                skipped: false,
+                duration_ms: 1,
+            })
+            .collect()
+    }
+
+    #[test]
+    fn test_exit_policy_any_failure() {
+        let policy = ExitPolicy::default();
+        assert!(policy.apply(Ok(()), &outcomes(&[true, true])).is_ok());
+        assert_eq!(
+            policy
+                .apply(Ok(()), &outcomes(&[true, false, true]))
+                .unwrap_err()
+                .to_string(),
+            "1 of 3 repositories failed"
+        );
+        assert!(policy.apply(Ok(()), &outcomes(&[false, false])).is_err());
+    }
+
+    #[test]
+    fn test_exit_policy_all_failure() {
+        let policy = ExitPolicy::AllFailure;
+        assert!(policy.apply(Ok(()), &outcomes(&[true, true])).is_ok());
+        assert!(
+            policy
+                .apply(Ok(()), &outcomes(&[true, false, true]))
+                .is_ok()
+        );
+        assert_eq!(
+            policy
+                .apply(Ok(()), &outcomes(&[false, false]))
+                .unwrap_err()
+                .to_string(),
+            "2 of 2 repositories failed"
+        );
+
+        // A command that stopped at its first failure keeps its own error
+        let stopped = Err(anyhow::anyhow!("Command failed with exit code: 1"));
+        assert_eq!(
+            policy
+                .apply(stopped, &outcomes(&[false]))
+                .unwrap_err()
+                .to_string(),
+            "Command failed with exit code: 1"
+        );
+        let stopped = Err(anyhow::anyhow!("Command failed with exit code: 1"));
+        assert!(policy.apply(stopped, &outcomes(&[true, false])).is_ok());
+    }
+
+    #[test]
+    fn test_exit_policy_never() {
+        let policy = ExitPolicy::Never;
+        assert!(policy.apply(Ok(()), &outcomes(&[true, false])).is_ok());
+        assert!(policy.apply(Ok(()), &outcomes(&[false, false])).is_ok());
+        let stopped = Err(anyhow::anyhow!("Command failed with exit code: 1"));
+        assert!(policy.apply(stopped, &outcomes(&[false])).is_ok());
+    }
+
+    #[test]
+    fn test_exit_policy_at_most_failures() {
+        let policy = ExitPolicy::AtMostFailures(2);
+        assert!(
+            policy
+                .apply(Ok(()), &outcomes(&[true, false, false]))
+                .is_ok()
+        );
+        assert_eq!(
+            policy
+                .apply(Ok(()), &outcomes(&[false, false, false]))
+                .unwrap_err()
+                .to_string(),
+            "3 of 3 repositories failed"
+        );
+        let over = Err(anyhow::anyhow!("3 pull request operations failed"));
+        assert!(policy.apply(over, &outcomes(&[false])).is_err());
+    }
+
+    #[test]
+    fn test_exit_policy_keeps_errors_before_any_repository() {
+        for policy in [
+            ExitPolicy::AnyFailure,
+            ExitPolicy::AllFailure,
+            ExitPolicy::Never,
+            ExitPolicy::AtMostFailures(1),
+        ] {
+            let result = Err(anyhow::anyhow!("config not found"));
+            assert!(policy.apply(result, &[]).is_err());
+            assert!(policy.apply(Ok(()), &[]).is_ok());
+        }
+    }
+
+    #[test]
+    fn test_exit_policy_from_str() {
+        assert_eq!(
+            "all-failure".parse::<ExitPolicy>().unwrap(),
+            ExitPolicy::AllFailure
+        );
+        assert_eq!("never".parse::<ExitPolicy>().unwrap(), ExitPolicy::Never);
+        assert_eq!(
+            "sometimes".parse::<ExitPolicy>().unwrap_err().to_string(),
+            "Unknown exit policy 'sometimes': expected any-failure, all-failure or never"
+        );
+    }
+
+    #[test]
+    fn test_report_write_to_creates_parent_dirs() {
+        let temp_dir = TempDir::new().unwrap();
+        let path = temp_dir.path().join("nested/dir/report.json");
+
+        let report = RunReport {
+            command: "clone".to_string(),
+            options: serde_json::json!({ "parallel": true }),
+            started_at: "2024-01-01T00:00:00Z".to_string(),
+            finished_at: "2024-01-01T00:00:01Z".to_string(),
+            duration_ms: 1000,
+            success: true,
+            error: None,
+            repositories: vec![RepoOutcome {
+                name: "repo".to_string(),
+                success: true,
+                error: None,
+                skipped: false,
+                duration_ms: 5,
+            }],
+        };
+        report.write_to(&path).unwrap();
+
+        let parsed: serde_json::Value =
+            serde_json::from_str(&std::fs::read_to_string(&path).unwrap()).unwrap();
+        assert_eq!(parsed["command"], "clone");
+        assert_eq!(parsed["success"], true);
+        assert_eq!(parsed["options"]["parallel"], true);
+        assert_eq!(parsed["repositories"][0]["name"], "repo");
+        assert!(parsed.get("error").is_none());
+    }
+
+    #[test]
+    fn test_failed_repositories_of_fixture_report() {
+        let path =
+            Path::new(env!("CARGO_MANIFEST_DIR")).join("tests/fixtures/reports/failed-run.json");
+        let report = RunReport::load(&path).unwrap();
+        assert_eq!(report.command, "run");
+        assert_eq!(
+            report.failed_repositories(),
+            BTreeSet::from(["billing".to_string(), "legacy".to_string()])
+        );
+
+        // A report written by a run round-trips
+        let temp_dir = TempDir::new().unwrap();
+        let copy = temp_dir.path().join("report.json");
+        report.write_to(&copy).unwrap();
+        assert_eq!(
+            RunReport::load(&copy).unwrap().failed_repositories(),
+            report.failed_repositories()
+        );
+
+        std::fs::write(&copy, "not json").unwrap();
+        let err = RunReport::load(&copy).unwrap_err();
+        assert!(err.to_string().starts_with("Invalid report file: "));
+        assert!(RunReport::load(&temp_dir.path().join("missing.json")).is_err());
+    }
+}
@@ -1,26 +1,151 @@
 //! Run command implementation
 
-use super::{Command, CommandContext};
-use crate::runner::CommandRunner;
+use super::active::{ACTIVE_HEARTBEAT, ActiveSet};
+use super::hooks::OutcomeHooks;
+use super::{Command, CommandContext, OutcomeRecorder, RepoOutcome, join_limited};
+use crate::config::{RecipeArgs, Repository, arg_env};
+use crate::runner::{
+    CommandRunner, Container, DeadlineExceeded, OutputMatch, OutputTemplate, RetryPolicy, Shell,
+    command_line,
+};
 use crate::utils::sanitizers::{sanitize_for_filename, sanitize_script_name};
-use anyhow::Result;
+use crate::utils::table::{Align, Cell, Color, Table};
+use crate::utils::{OutputBuffer, format_elapsed};
+use anyhow::{Context, Result};
 use async_trait::async_trait;
+use serde::{Deserialize, Serialize};
 
 use std::fs::create_dir_all;
+use std::future::Future;
 use std::path::{Path, PathBuf};
+use std::str::FromStr;
+use std::sync::Arc;
+use std::time::{Duration, Instant};
 
 #[derive(Debug)]
 pub enum RunType {
     Command(String),
     Recipe(String),
+    /// Per-repository command looked up by name in each repository's `commands`,
+    /// falling back to `default` for repositories that don't define it
+    Named {
+        name: String,
+        default: Option<String>,
+    },
+    /// Program and arguments started directly, without a shell (`repos exec`)
+    Exec(Vec<String>),
+}
+
+impl Default for RunType {
+    fn default() -> Self {
+        Self::Command(String::new())
+    }
+}
+
+/// How the end-of-run summary is printed (`--summary-format`)
+#[derive(Debug, Clone, Copy, Default, PartialEq, Eq)]
+pub enum SummaryFormat {
+    /// Counts line and per-repository table for people
+    #[default]
+    Table,
+    Json,
+    Yaml,
+}
+
+impl FromStr for SummaryFormat {
+    type Err = anyhow::Error;
+
+    fn from_str(value: &str) -> Result<Self> {
+        match value {
+            "table" => Ok(Self::Table),
+            "json" => Ok(Self::Json),
+            "yaml" => Ok(Self::Yaml),
+            _ => anyhow::bail!(
+                "Unknown summary format '{}': expected table, json or yaml",
+                value
+            ),
+        }
+    }
+}
+
+/// Structured end-of-run summary, as printed with `--summary-format json|yaml`
+#[derive(Debug, Clone, PartialEq, Serialize, Deserialize)]
+pub struct RunSummary {
+    pub repositories: usize,
+    pub succeeded: usize,
+    pub failed: usize,
+    #[serde(default, skip_serializing_if = "is_zero")]
+    pub archived_skipped: usize,
+    /// One entry per repository, sorted by name
+    pub results: Vec<RepoOutcome>,
+}
+
+impl RunSummary {
+    pub fn new(outcomes: &[RepoOutcome], archived_skipped: usize) -> Self {
+        let mut results = outcomes.to_vec();
+        results.sort_by(|a, b| a.name.cmp(&b.name));
+        let failed = results.iter().filter(|outcome| !outcome.success).count();
+        Self {
+            repositories: results.len(),
+            succeeded: results.len() - failed,
+            failed,
+            archived_skipped,
+            results,
+        }
+    }
+
+    /// The summary as a JSON or YAML document; `None` for the table format
+    pub fn render(&self, format: SummaryFormat) -> Result<Option<String>> {
+        Ok(match format {
+            SummaryFormat::Table => None,
+            SummaryFormat::Json => Some(serde_json::to_string_pretty(self)?),
+            SummaryFormat::Yaml => Some(serde_yaml::to_string(self)?),
+        })
+    }
+}
+
+fn is_zero(count: &usize) -> bool {
+    *count == 0
 }
 
 /// Run command for executing commands or recipes in repositories
-#[derive(Debug)]
+#[derive(Debug, Default)]
 pub struct RunCommand {
     pub run_type: RunType,
     pub no_save: bool,
     pub output_dir: Option<PathBuf>,
+    /// Default time limit per repository (`--timeout`)
+    pub timeout: Option<Duration>,
+    /// Number of slowest repositories to list after the summary (`--timings`)
+    pub timings: Option<usize>,
+    /// Exit codes that count as success (`--allow-exit-codes`); empty means only `0`
+    pub allowed_exit_codes: Vec<i32>,
+    /// Buffer each repository's output in parallel mode and print it in config order
+    pub ordered_output: bool,
+    /// Archived repositories left out of the selection, reported in the summary
+    pub archived_skipped: usize,
+    /// Input replayed to every repository's command (`--stdin`, `--stdin-file`)
+    pub stdin: Option<Arc<[u8]>>,
+    /// Path each repository's stdout is written to (`--output-template`)
+    pub output_template: Option<OutputTemplate>,
+    /// Format of the end-of-run summary (`--summary-format`)
+    pub summary_format: SummaryFormat,
+    /// Image each repository's command runs in (`--container`)
+    pub container: Option<Container>,
+    /// Group saved logs under each repository's first tag (`--logs-by-tag`)
+    pub logs_by_tag: bool,
+    /// In parallel mode, print the repositories still running every few seconds (`--show-active`)
+    pub show_active: bool,
+    /// Run a failing command again (`--command-retries`)
+    pub retry: RetryPolicy,
+    /// Values for the recipe's `{{.name}}` arguments (`--arg`)
+    pub recipe_args: RecipeArgs,
+    /// Commands run after each repository by its result (`--on-success`, `--on-failure`)
+    pub hooks: OutcomeHooks,
+    /// Shell each command line runs with (`--shell`, `defaults.shell`)
+    pub shell: Shell,
+    /// Output patterns deciding each result instead of the exit code (`--success-if`, `--failure-if`)
+    pub output_match: OutputMatch,
 }
 
 impl RunCommand {
@@ -29,6 +154,7 @@ impl RunCommand {
             run_type: RunType::Command(command),
             no_save,
             output_dir,
+            ..Default::default()
         }
     }
 
@@ -37,6 +163,30 @@ impl RunCommand {
             run_type: RunType::Recipe(recipe_name),
             no_save,
             output_dir,
+            ..Default::default()
+        }
+    }
+
+    pub fn new_exec(argv: Vec<String>, no_save: bool, output_dir: Option<PathBuf>) -> Self {
+        Self {
+            run_type: RunType::Exec(argv),
+            no_save,
+            output_dir,
+            ..Default::default()
+        }
+    }
+
+    pub fn new_named(
+        name: String,
+        default: Option<String>,
+        no_save: bool,
+        output_dir: Option<PathBuf>,
+    ) -> Self {
+        Self {
+            run_type: RunType::Named { name, default },
+            no_save,
+            output_dir,
+            ..Default::default()
         }
     }
 }
@@ -44,10 +194,17 @@ impl RunCommand {
 #[async_trait]
 impl Command for RunCommand {
     async fn execute(&self, context: &CommandContext) -> Result<()> {
-        match &self.run_type {
+        let result = match &self.run_type {
             RunType::Command(command) => self.execute_command(context, command).await,
             RunType::Recipe(recipe_name) => self.execute_recipe(context, recipe_name).await,
-        }
+            RunType::Named { name, default } => {
+                self.execute_named(context, name, default.as_deref()).await
+            }
+            // Logged, checked against run_policy and reported as its quoted command line
+            RunType::Exec(argv) => self.execute_command(context, &command_line(argv)).await,
+        };
+        self.print_summary(&context.outcomes.outcomes());
+        result
     }
 }
 
@@ -56,8 +213,179 @@ impl RunCommand {
     pub fn new_for_test(command: String, output_dir: String) -> Self {
         Self {
             run_type: RunType::Command(command),
-            no_save: false,
             output_dir: Some(PathBuf::from(output_dir)),
+            ..Default::default()
+        }
+    }
+
+    /// Set the default time limit for repositories without their own `timeout`
+    pub fn with_timeout(mut self, timeout: Option<Duration>) -> Self {
+        self.timeout = timeout;
+        self
+    }
+
+    /// List the `count` slowest repositories after the run summary
+    pub fn with_timings(mut self, count: Option<usize>) -> Self {
+        self.timings = count;
+        self
+    }
+
+    /// Count these child exit codes as success instead of only `0`
+    pub fn with_allowed_exit_codes(mut self, codes: Vec<i32>) -> Self {
+        self.allowed_exit_codes = codes;
+        self
+    }
+
+    /// Hold back parallel output and print it in config order once all repositories finish
+    pub fn with_ordered_output(mut self, ordered: bool) -> Self {
+        self.ordered_output = ordered;
+        self
+    }
+
+    /// Mention `count` skipped archived repositories in the summary
+    pub fn with_archived_skipped(mut self, count: usize) -> Self {
+        self.archived_skipped = count;
+        self
+    }
+
+    /// Feed `input` to the stdin of each repository's command
+    pub fn with_stdin(mut self, input: Option<Vec<u8>>) -> Self {
+        self.stdin = input.map(Arc::from);
+        self
+    }
+
+    /// Write each repository's stdout to the path `template` renders for it
+    pub fn with_output_template(mut self, template: Option<OutputTemplate>) -> Self {
+        self.output_template = template;
+        self
+    }
+
+    /// Print the end-of-run summary as a table (the default), JSON or YAML
+    pub fn with_summary_format(mut self, format: SummaryFormat) -> Self {
+        self.summary_format = format;
+        self
+    }
+
+    /// Run each repository's command in `container` instead of on the host
+    pub fn with_container(mut self, container: Option<Container>) -> Self {
+        self.container = container;
+        self
+    }
+
+    /// Save each repository's logs under `<run>/<first tag>/<name>`
+    pub fn with_logs_by_tag(mut self, by_tag: bool) -> Self {
+        self.logs_by_tag = by_tag;
+        self
+    }
+
+    pub fn with_show_active(mut self, show_active: bool) -> Self {
+        self.show_active = show_active;
+        self
+    }
+
+    /// Run each repository's command again while it fails, as `retry` allows
+    pub fn with_retry(mut self, retry: RetryPolicy) -> Self {
+        self.retry = retry;
+        self
+    }
+
+    /// Substitute `args` into the recipe's steps and export them as `ARG_*`
+    pub fn with_recipe_args(mut self, args: RecipeArgs) -> Self {
+        self.recipe_args = args;
+        self
+    }
+
+    pub fn with_outcome_hooks(mut self, hooks: OutcomeHooks) -> Self {
+        self.hooks = hooks;
+        self
+    }
+
+    /// Pass each command line to `shell` instead of the default shell
+    pub fn with_shell(mut self, shell: Shell) -> Self {
+        self.shell = shell;
+        self
+    }
+
+    /// Judge each repository's result by its output instead of its exit code
+    pub fn with_output_match(mut self, output_match: OutputMatch) -> Self {
+        self.output_match = output_match;
+        self
+    }
+
+    /// Await a parallel batch, reporting its active repositories with `--show-active`
+    async fn watch_active<F: Future>(&self, active: &ActiveSet, batch: F) -> F::Output {
+        if self.show_active {
+            active.report_while(ACTIVE_HEARTBEAT, batch).await
+        } else {
+            batch.await
+        }
+    }
+
+    fn runner(&self, timeout: Option<Duration>, deadline: Option<Instant>) -> CommandRunner {
+        let argv = match &self.run_type {
+            RunType::Exec(argv) => Some(argv.clone()),
+            _ => None,
+        };
+        CommandRunner::new()
+            .with_argv(argv)
+            .with_timeout(timeout)
+            .with_allowed_exit_codes(&self.allowed_exit_codes)
+            .with_stdin(self.stdin.clone())
+            .with_output_template(self.output_template.clone())
+            .with_container(self.container.clone())
+            .with_shell(self.shell.clone())
+            .with_logs_by_tag(self.logs_by_tag)
+            .with_retry(self.retry.clone())
+            .with_deadline(deadline)
+            .with_output_match(self.output_match.clone())
+    }
+
+    /// Runner for a parallel task, buffering its output with `--ordered-output`
+    fn parallel_runner(
+        &self,
+        timeout: Option<Duration>,
+        deadline: Option<Instant>,
+    ) -> CommandRunner {
+        let runner = self.runner(timeout, deadline);
+        if self.ordered_output {
+            runner.with_buffered_output()
+        } else {
+            runner
+        }
+    }
+
+    /// Print the status and duration of every repository, plus the slowest when requested
+    fn print_summary(&self, outcomes: &[RepoOutcome]) {
+        if outcomes.is_empty() {
+            return;
+        }
+
+        match RunSummary::new(outcomes, self.archived_skipped).render(self.summary_format) {
+            Ok(Some(document)) => {
+                println!("{}", document.trim_end());
+                return;
+            }
+            Ok(None) => {}
+            Err(e) => eprintln!("Failed to format run summary: {}", e),
+        }
+
+        let failed = outcomes.iter().filter(|outcome| !outcome.success).count();
+        let archived = match self.archived_skipped {
+            0 => String::new(),
+            count => format!(", {} archived skipped", count),
+        };
+        println!(
+            "\nSummary: {} repositories, {} succeeded, {} failed{}",
+            outcomes.len(),
+            outcomes.len() - failed,
+            failed,
+            archived
+        );
+        summary_table(outcomes).print();
+
+        if let Some(count) = self.timings {
+            println!("\nSlowest repositories:");
+            timings_table(&slowest(outcomes, count)).print();
         }
     }
 
@@ -68,18 +396,83 @@ impl RunCommand {
             context.repos.as_deref(),
         );
 
-        if repositories.is_empty() {
+        let jobs = repositories
+            .into_iter()
+            .map(|repo| (repo, command.to_string()))
+            .collect();
+
+        self.run_jobs(context, command, jobs).await
+    }
+
+    async fn execute_named(
+        &self,
+        context: &CommandContext,
+        name: &str,
+        default: Option<&str>,
+    ) -> Result<()> {
+        let repositories = context.config.filter_repositories(
+            &context.tag,
+            &context.exclude_tag,
+            context.repos.as_deref(),
+        );
+
+        let mut jobs = Vec::new();
+        let mut missing = Vec::new();
+        for repo in repositories {
+            match resolve_named_command(&repo, name, default) {
+                Some(command) => {
+                    let command = command.to_string();
+                    jobs.push((repo, command));
+                }
+                None => missing.push(repo.name),
+            }
+        }
+
+        // Refuse to start a partial batch when some repositories have nothing to run
+        if !missing.is_empty() {
+            anyhow::bail!(
+                "No '{}' command configured for: {} (add it to their `commands` or pass a default command)",
+                name,
+                missing.join(", ")
+            );
+        }
+
+        self.run_jobs(context, name, jobs).await
+    }
+
+    /// Run a command per repository, each repository with its own command line
+    ///
+    /// `label` names the run directory when outputs are saved.
+    async fn run_jobs(
+        &self,
+        context: &CommandContext,
+        label: &str,
+        jobs: Vec<(Repository, String)>,
+    ) -> Result<()> {
+        if jobs.is_empty() {
             return Ok(());
         }
 
-        let runner = CommandRunner::new();
+        // Refuse the whole batch if any command breaks the config's run_policy
+        for (_, command) in &jobs {
+            context.config.run_policy.check(command)?;
+        }
+
+        // Resolve every time limit up front so a bad setting fails before anything runs
+        let jobs = jobs
+            .into_iter()
+            .map(|(repo, command)| {
+                let timeout = effective_timeout(&repo, self.timeout)?;
+                Ok((repo, command, timeout))
+            })
+            .collect::<Result<Vec<_>>>()?;
 
         // Setup persistent output directory if saving is enabled
         let run_root = if !self.no_save {
             // Use local time instead of UTC
             let timestamp = chrono::Local::now().format("%Y%m%d-%H%M%S").to_string();
             // Sanitize command for directory name
-            let command_suffix = sanitize_for_filename(command);
+            let command_suffix = sanitize_for_filename(label);
             // Use provided output directory or default to "output"
             let base_dir = self
                 .output_dir
@@ -95,14 +488,23 @@ impl RunCommand {
 
         if context.parallel {
             // Parallel execution
-            let tasks: Vec<_> = repositories
+            let active = ActiveSet::new();
+            let tasks: Vec<_> = jobs
                 .into_iter()
-                .map(|repo| {
-                    let command = command.to_string();
+                .enumerate()
+                .map(|(index, (repo, command, timeout))| {
                     let run_root = run_root.clone();
+                    let outcomes = context.outcomes.clone();
+                    let active = active.clone();
                     async move {
-                        let runner = CommandRunner::new();
-                        if let Some(ref run_root) = run_root {
+                        // Workers start late under --jobs; --max-failures may have been reached
+                        if outcomes.skip_if_aborted(&repo.name) {
+                            return (index, None);
+                        }
+                        let _active = active.enter(&repo.name);
+                        let started = Instant::now();
+                        let runner = self.parallel_runner(timeout, outcomes.deadline());
+                        let result = if let Some(ref run_root) = run_root {
                             runner
                                 .run_command_with_capture(
                                     &repo,
@@ -114,25 +516,74 @@ impl RunCommand {
                             runner
                                 .run_command_with_capture_no_logs(&repo, &command, None)
                                 .await
-                        }
+                        };
+                        record_run_outcome(
+                            &outcomes,
+                            &self.hooks,
+                            &repo,
+                            &result,
+                            started.elapsed(),
+                            &runner,
+                        )
+                        .await;
+                        (index, runner.into_output())
                     }
                 })
                 .collect();
 
-            futures::future::join_all(tasks).await;
+            flush_in_order(
+                self.watch_active(&active, join_limited(tasks, context.jobs))
+                    .await,
+            );
         } else {
             // Sequential execution
-            for repo in repositories {
-                if let Some(ref run_root) = run_root {
-                    runner
-                        .run_command_with_capture(
-                            &repo,
-                            command,
-                            Some(run_root.to_string_lossy().as_ref()),
-                        )
-                        .await?;
+            for (repo, command, timeout) in jobs {
+                if context.outcomes.skip_if_aborted(&repo.name) {
+                    continue;
+                }
+                let started = Instant::now();
+                let runner = self.runner(timeout, context.outcomes.deadline());
+                // Output templates and patterns need the output captured even without saved logs
+                if run_root.is_some()
+                    || self.output_template.is_some()
+                    || !self.output_match.is_empty()
+                {
+                    let log_dir = run_root
+                        .as_ref()
+                        .map(|run_root| run_root.to_string_lossy().to_string());
+                    let result = runner
+                        .run_command_with_capture(&repo, &command, log_dir.as_deref())
+                        .await;
+                    record_run_outcome(
+                        &context.outcomes,
+                        &self.hooks,
+                        &repo,
+                        &result,
+                        started.elapsed(),
+                        &runner,
+                    )
+                    .await;
+                    // Past the deadline the remaining repositories are marked incomplete
+                    if !stopped_at_deadline(&result) {
+                        result?;
+                    }
                 } else {
-                    runner.run_command(&repo, command, None).await?;
+                    let result = runner.run_command(&repo, &command, None).await;
+                    if stopped_at_deadline(&result) {
+                        context
+                            .outcomes
+                            .record_incomplete(&repo.name, started.elapsed());
+                        continue;
+                    }
+                    record_outcome(
+                        &context.outcomes,
+                        &self.hooks,
+                        &repo,
+                        result.as_ref().err().map(|e| e.to_string()),
+                        started.elapsed(),
+                    )
+                    .await;
+                    result?;
                 }
             }
         }
@@ -146,6 +597,12 @@ impl RunCommand {
             .config
             .find_recipe(recipe_name)
             .ok_or_else(|| anyhow::anyhow!("Recipe '{}' not found", recipe_name))?;
+        let args = recipe.resolve_args(&self.recipe_args)?;
+        let steps = recipe.render_steps(&args);
+        let env = arg_env(&args);
+        for step in &steps {
+            context.config.run_policy.check(step)?;
+        }
 
         let repositories = context.config.filter_repositories(
             &context.tag,
@@ -157,7 +614,13 @@ impl RunCommand {
             return Ok(());
         }
 
-        let runner = CommandRunner::new();
+        let repositories = repositories
+            .into_iter()
+            .map(|repo| {
+                let timeout = effective_timeout(&repo, self.timeout)?;
+                Ok((repo, timeout))
+            })
+            .collect::<Result<Vec<_>>>()?;
 
         // Setup persistent output directory if saving is enabled
         let run_root = if !self.no_save {
@@ -180,64 +643,112 @@ impl RunCommand {
 
         if context.parallel {
             // Parallel execution
-            let tasks: Vec<_> = repositories
-                .into_iter()
-                .map(|repo| {
-                    let recipe_steps = recipe.steps.clone();
-                    let recipe_name = recipe.name.clone();
-                    let run_root = run_root.clone();
-                    async move {
-                        let script_path =
-                            Self::materialize_script(&repo, &recipe_name, &recipe_steps).await?;
-
-                        // Convert absolute script path to relative path from repository directory
-                        let repo_target_dir = repo.get_target_dir();
-                        let repo_dir = Path::new(&repo_target_dir);
-                        let relative_script_path = script_path
-                            .strip_prefix(repo_dir)
-                            .unwrap_or(&script_path)
-                            .to_string_lossy();
-
-                        // Ensure script path is executable from current directory
-                        let executable_script_path = if relative_script_path.contains('/') {
-                            relative_script_path.to_string()
-                        } else {
-                            format!("./{}", relative_script_path)
-                        };
-
-                        let runner = CommandRunner::new();
-                        let result = if let Some(ref run_root) = run_root {
-                            runner
-                                .run_command_with_recipe_context(
-                                    &repo,
-                                    &executable_script_path,
-                                    Some(run_root.to_string_lossy().as_ref()),
-                                    &recipe_name,
-                                    &recipe_steps,
-                                )
-                                .await
-                        } else {
-                            runner
-                                .run_command_with_capture_no_logs(
-                                    &repo,
-                                    &executable_script_path,
-                                    None,
-                                )
-                                .await
-                        };
-                        // Optionally remove script file after execution
-                        let _ = std::fs::remove_file(script_path);
-                        result
-                    }
-                })
-                .collect();
+            let active = ActiveSet::new();
+            let tasks: Vec<_> =
+                repositories
+                    .into_iter()
+                    .enumerate()
+                    .map(|(index, (repo, timeout))| {
+                        let recipe_steps = steps.clone();
+                        let recipe_name = recipe.name.clone();
+                        let env = env.clone();
+                        let run_root = run_root.clone();
+                        let outcomes = context.outcomes.clone();
+                        let active = active.clone();
+                        async move {
+                            if outcomes.skip_if_aborted(&repo.name) {
+                                return (index, None);
+                            }
+                            let _active = active.enter(&repo.name);
+                            let started = Instant::now();
+                            let runner = self
+                                .parallel_runner(timeout, outcomes.deadline())
+                                .with_env(env);
+                            let script_path =
+                                match Self::materialize_script(&repo, &recipe_name, &recipe_steps)
+                                    .await
+                                {
+                                    Ok(path) => path,
+                                    Err(e) => {
+                                        let result: Result<(String, String, i32)> = Err(e);
+                                        record_run_outcome(
+                                            &outcomes,
+                                            &self.hooks,
+                                            &repo,
+                                            &result,
+                                            started.elapsed(),
+                                            &runner,
+                                        )
+                                        .await;
+                                        return (index, runner.into_output());
+                                    }
+                                };
+
+                            // Convert absolute script path to relative path from repository directory
+                            let repo_target_dir = repo.get_target_dir();
+                            let repo_dir = Path::new(&repo_target_dir);
+                            let relative_script_path = script_path
+                                .strip_prefix(repo_dir)
+                                .unwrap_or(&script_path)
+                                .to_string_lossy();
+
+                            // Ensure script path is executable from current directory
+                            let executable_script_path = if relative_script_path.contains('/') {
+                                relative_script_path.to_string()
+                            } else {
+                                format!("./{}", relative_script_path)
+                            };
+
+                            let result = if let Some(ref run_root) = run_root {
+                                runner
+                                    .run_command_with_recipe_context(
+                                        &repo,
+                                        &executable_script_path,
+                                        Some(run_root.to_string_lossy().as_ref()),
+                                        &recipe_name,
+                                        &recipe_steps,
+                                    )
+                                    .await
+                            } else {
+                                runner
+                                    .run_command_with_capture_no_logs(
+                                        &repo,
+                                        &executable_script_path,
+                                        None,
+                                    )
+                                    .await
+                            };
+                            // Optionally remove script file after execution
+                            let _ = std::fs::remove_file(script_path);
+                            record_run_outcome(
+                                &outcomes,
+                                &self.hooks,
+                                &repo,
+                                &result,
+                                started.elapsed(),
+                                &runner,
+                            )
+                            .await;
+                            (index, runner.into_output())
+                        }
+                    })
+                    .collect();
 
-            futures::future::join_all(tasks).await;
+            flush_in_order(
+                self.watch_active(&active, join_limited(tasks, context.jobs))
+                    .await,
+            );
         } else {
             // Sequential execution
-            for repo in repositories {
-                let script_path =
-                    Self::materialize_script(&repo, &recipe.name, &recipe.steps).await?;
+            for (repo, timeout) in repositories {
+                if context.outcomes.skip_if_aborted(&repo.name) {
+                    continue;
+                }
+                let started = Instant::now();
+                let runner = self
+                    .runner(timeout, context.outcomes.deadline())
+                    .with_env(env.clone());
+                let script_path = Self::materialize_script(&repo, &recipe.name, &steps).await?;
 
                 // Convert absolute script path to relative path from repository directory
                 let repo_target_dir = repo.get_target_dir();
@@ -261,7 +772,7 @@ impl RunCommand {
                             &executable_script_path,
                             Some(run_root.to_string_lossy().as_ref()),
                             &recipe.name,
-                            &recipe.steps,
+                            &steps,
                         )
                         .await
                 } else {
@@ -271,7 +782,18 @@ impl RunCommand {
                 };
                 // Optionally remove script file after execution
                 let _ = std::fs::remove_file(script_path);
-                result?;
+                record_run_outcome(
+                    &context.outcomes,
+                    &self.hooks,
+                    &repo,
+                    &result,
+                    started.elapsed(),
+                    &runner,
+                )
+                .await;
+                if !stopped_at_deadline(&result) {
+                    result?;
+                }
             }
         }
 
@@ -279,7 +801,7 @@ impl RunCommand {
     }
 
     async fn materialize_script(
-        repo: &crate::config::Repository,
+        repo: &Repository,
         recipe_name: &str,
         steps: &[String],
     ) -> Result<PathBuf> {
@@ -312,10 +834,137 @@ impl RunCommand {
     }
 }
 
+/// Pick the command to run for `repo` under `name`
+///
+/// A repository's own `commands` entry wins over the global default.
+fn resolve_named_command<'a>(
+    repo: &'a Repository,
+    name: &str,
+    default: Option<&'a str>,
+) -> Option<&'a str> {
+    repo.named_command(name).or(default)
+}
+
+/// Time limit for `repo`: its own `timeout` setting wins over the CLI default
+fn effective_timeout(repo: &Repository, default: Option<Duration>) -> Result<Option<Duration>> {
+    let own = repo
+        .timeout()
+        .with_context(|| format!("Invalid timeout for repository '{}'", repo.name))?;
+    Ok(own.or(default))
+}
+
+fn elapsed(outcome: &RepoOutcome) -> String {
+    format_elapsed(Duration::from_millis(outcome.duration_ms))
+}
+
+/// One row per repository, in name order, with status and duration
+fn summary_table(outcomes: &[RepoOutcome]) -> Table {
+    let mut sorted: Vec<&RepoOutcome> = outcomes.iter().collect();
+    sorted.sort_by(|a, b| a.name.cmp(&b.name));
+
+    let mut table = Table::new(["REPOSITORY", "STATUS", "DURATION"])
+        .align(2, Align::Right)
+        .indent(2);
+    for outcome in sorted {
+        let status = if outcome.success {
+            Cell::colored("ok", Color::Green)
+        } else {
+            Cell::colored("failed", Color::Red)
+        };
+        table.add_row([
+            Cell::new(outcome.name.as_str()),
+            status,
+            Cell::new(elapsed(outcome)),
+        ]);
+    }
+    table
+}
+
+fn timings_table(slowest: &[&RepoOutcome]) -> Table {
+    let mut table = Table::new(["#", "REPOSITORY", "DURATION"])
+        .align(0, Align::Right)
+        .align(2, Align::Right)
+        .indent(2);
+    for (rank, outcome) in slowest.iter().enumerate() {
+        table.add_row([
+            Cell::new((rank + 1).to_string()),
+            Cell::new(outcome.name.as_str()),
+            Cell::new(elapsed(outcome)),
+        ]);
+    }
+    table
+}
+
+/// The `count` slowest outcomes, longest first; ties are ordered by name
+fn slowest(outcomes: &[RepoOutcome], count: usize) -> Vec<&RepoOutcome> {
+    let mut sorted: Vec<&RepoOutcome> = outcomes.iter().collect();
+    sorted.sort_by(|a, b| {
+        b.duration_ms
+            .cmp(&a.duration_ms)
+            .then_with(|| a.name.cmp(&b.name))
+    });
+    sorted.truncate(count);
+    sorted
+}
+
+/// Print buffered parallel output in config order, whatever order the tasks finished in
+fn flush_in_order(outputs: Vec<(usize, Option<OutputBuffer>)>) {
+    for output in in_config_order(outputs) {
+        // A closed stdout is not worth failing the run over
+        let _ = output.flush();
+    }
+}
+
+/// Buffered outputs sorted by the index of their repository in the selection
+fn in_config_order(mut outputs: Vec<(usize, Option<OutputBuffer>)>) -> Vec<OutputBuffer> {
+    outputs.sort_by_key(|(index, _)| *index);
+    outputs
+        .into_iter()
+        .filter_map(|(_, output)| output)
+        .collect()
+}
+
+/// Record the outcome of a captured run, judged as failed or not by `runner`
+async fn record_run_outcome(
+    outcomes: &OutcomeRecorder,
+    hooks: &OutcomeHooks,
+    repo: &Repository,
+    result: &Result<(String, String, i32)>,
+    duration: Duration,
+    runner: &CommandRunner,
+) {
+    if stopped_at_deadline(result) {
+        outcomes.record_incomplete(&repo.name, duration);
+        return;
+    }
+    let error = match result {
+        Ok((stdout, stderr, exit_code)) => runner.failure(stdout, stderr, *exit_code),
+        Err(e) => Some(e.to_string()),
+    };
+    record_outcome(outcomes, hooks, repo, error, duration).await;
+}
+
+/// Whether `result` is that of a command the `--deadline` stopped
+fn stopped_at_deadline<T>(result: &Result<T>) -> bool {
+    result.as_ref().is_err_and(|e| e.is::<DeadlineExceeded>())
+}
+
+/// Record a repository's outcome, then run its `on_success` or `on_failure` hook
+async fn record_outcome(
+    outcomes: &OutcomeRecorder,
+    hooks: &OutcomeHooks,
+    repo: &Repository,
+    error: Option<String>,
+    duration: Duration,
+) {
+    outcomes.record(&repo.name, error.clone(), duration);
+    hooks.run(repo, error.as_deref(), duration).await;
+}
+
 #[cfg(test)]
 mod tests {
     use super::*;
-    use crate::config::{Config, Recipe, Repository};
+    use crate::config::{Config, Recipe};
     use std::fs;
     use tempfile::TempDir;
 
@@ -329,6 +978,7 @@ mod tests {
         let recipe = Recipe {
             name: "test-recipe".to_string(),
             steps: vec!["echo step1".to_string(), "echo step2".to_string()],
+            args: Vec::new(),
         };
 
         let failing_recipe = Recipe {
@@ -338,11 +988,14 @@ mod tests {
                 "false".to_string(),
                 "echo step3".to_string(),
             ],
+            args: Vec::new(),
         };
 
         Config {
             repositories: vec![repo1],
             recipes: vec![recipe, failing_recipe],
+            version: None,
+            ..Default::default()
         }
     }
 
@@ -353,6 +1006,8 @@ mod tests {
             exclude_tag: vec![],
             parallel: false,
             repos: None,
+            outcomes: OutcomeRecorder::new(),
+            jobs: None,
         }
     }
 
@@ -384,8 +1039,8 @@ mod tests {
     #[test]
     fn test_execute_with_empty_repositories_sync() {
         let config = Config {
-            repositories: vec![],
-            recipes: vec![],
+            version: None,
+            ..Default::default()
         };
         let context = create_test_context(config);
 
@@ -834,12 +1489,302 @@ mod tests {
         // These test the pattern matching in execute() method
         match cmd_run_type {
             RunType::Command(_) => {} // Expected path
-            RunType::Recipe(_) => panic!("Should be Command type"),
+            RunType::Recipe(_) | RunType::Named { .. } | RunType::Exec(_) => {
+                panic!("Should be Command type")
+            }
         }
 
         match recipe_run_type {
-            RunType::Command(_) => panic!("Should be Recipe type"),
+            RunType::Command(_) | RunType::Named { .. } | RunType::Exec(_) => {
+                panic!("Should be Recipe type")
+            }
             RunType::Recipe(_) => {} // Expected path
         }
     }
+
+    fn repo_with_commands(name: &str, commands: &[(&str, &str)]) -> Repository {
+        let mut repo = Repository::new(
+            name.to_string(),
+            format!("https://github.com/test/{}.git", name),
+        );
+        repo.commands = commands
+            .iter()
+            .map(|(key, command)| (key.to_string(), command.to_string()))
+            .collect();
+        repo
+    }
+
+    #[test]
+    fn test_resolve_named_command_prefers_repository_entry() {
+        let repo = repo_with_commands("gradle-app", &[("build", "./gradlew build")]);
+        assert_eq!(
+            resolve_named_command(&repo, "build", Some("make build")),
+            Some("./gradlew build")
+        );
+    }
+
+    #[test]
+    fn test_resolve_named_command_falls_back_to_default() {
+        let repo = repo_with_commands("make-app", &[("test", "make test")]);
+        assert_eq!(
+            resolve_named_command(&repo, "build", Some("make build")),
+            Some("make build")
+        );
+        assert_eq!(resolve_named_command(&repo, "build", None), None);
+    }
+
+    #[tokio::test]
+    async fn test_execute_named_runs_per_repository_commands() {
+        let temp_dir = TempDir::new().unwrap();
+
+        let mut custom = repo_with_commands("custom", &[("build", "echo custom > built.txt")]);
+        custom.path = Some(temp_dir.path().join("custom").to_string_lossy().to_string());
+        let mut fallback = repo_with_commands("fallback", &[]);
+        fallback.path = Some(
+            temp_dir
+                .path()
+                .join("fallback")
+                .to_string_lossy()
+                .to_string(),
+        );
+        for repo in [&custom, &fallback] {
+            fs::create_dir_all(repo.get_target_dir()).unwrap();
+        }
+
+        let mut config = Config::new();
+        config.repositories = vec![custom.clone(), fallback.clone()];
+        let context = create_test_context(config);
+
+        let run_cmd = RunCommand::new_named(
+            "build".to_string(),
+            Some("echo default > built.txt".to_string()),
+            true,
+            None,
+        );
+        run_cmd.execute(&context).await.unwrap();
+
+        let read = |repo: &Repository| {
+            fs::read_to_string(Path::new(&repo.get_target_dir()).join("built.txt")).unwrap()
+        };
+        assert_eq!(read(&custom).trim(), "custom");
+        assert_eq!(read(&fallback).trim(), "default");
+        assert_eq!(context.outcomes.outcomes().len(), 2);
+    }
+
+    #[tokio::test]
+    async fn test_execute_named_fails_without_command_or_default() {
+        let mut config = Config::new();
+        config.repositories = vec![
+            repo_with_commands("has-build", &[("build", "true")]),
+            repo_with_commands("no-build", &[]),
+        ];
+        let context = create_test_context(config);
+
+        let run_cmd = RunCommand::new_named("build".to_string(), None, true, None);
+        let err = run_cmd.execute(&context).await.unwrap_err().to_string();
+
+        assert!(err.contains("No 'build' command configured for: no-build"));
+        // Nothing runs when the batch is incomplete
+        assert!(context.outcomes.outcomes().is_empty());
+    }
+
+    #[tokio::test]
+    async fn test_run_policy_refuses_denied_commands_before_running() {
+        let temp_dir = TempDir::new().unwrap();
+        let mut repo = repo_with_commands("app", &[]);
+        repo.path = Some(temp_dir.path().to_string_lossy().to_string());
+
+        let mut config = Config::new();
+        config.repositories = vec![repo];
+        config.run_policy.deny = vec![r"\brm\s+-rf\b".to_string()];
+        config.recipes = vec![Recipe {
+            name: "cleanup".to_string(),
+            steps: vec!["touch step1.txt".to_string(), "rm -rf build".to_string()],
+            args: Vec::new(),
+        }];
+        let context = create_test_context(config);
+
+        let err = RunCommand::new_command("rm -rf build".to_string(), true, None)
+            .execute(&context)
+            .await
+            .unwrap_err()
+            .to_string();
+        assert!(err.contains("is denied by run_policy"), "{}", err);
+
+        let err = RunCommand::new_recipe("cleanup".to_string(), true, None)
+            .execute(&context)
+            .await
+            .unwrap_err()
+            .to_string();
+        assert!(err.contains("Command 'rm -rf build' is denied"), "{}", err);
+        // No step of a refused recipe runs
+        assert!(!temp_dir.path().join("step1.txt").exists());
+        assert!(context.outcomes.outcomes().is_empty());
+
+        RunCommand::new_command("touch allowed.txt".to_string(), true, None)
+            .execute(&context)
+            .await
+            .unwrap();
+        assert!(temp_dir.path().join("allowed.txt").exists());
+    }
+
+    #[test]
+    fn test_effective_timeout_precedence() {
+        let cli = Some(Duration::from_secs(30));
+        let mut repo = Repository::new("r".to_string(), "https://github.com/o/r".to_string());
+
+        assert_eq!(effective_timeout(&repo, None).unwrap(), None);
+        assert_eq!(effective_timeout(&repo, cli).unwrap(), cli);
+
+        repo.timeout = Some("10m".to_string());
+        assert_eq!(
+            effective_timeout(&repo, cli).unwrap(),
+            Some(Duration::from_secs(600))
+        );
+        assert_eq!(
+            effective_timeout(&repo, None).unwrap(),
+            Some(Duration::from_secs(600))
+        );
+
+        repo.timeout = Some("soon".to_string());
+        let err = effective_timeout(&repo, cli).unwrap_err();
+        assert!(
+            err.to_string()
+                .contains("Invalid timeout for repository 'r'")
+        );
+    }
+
+    fn outcome(name: &str, duration_ms: u64, success: bool) -> RepoOutcome {
+        RepoOutcome {
+            name: name.to_string(),
+            success,
+            error: (!success).then(|| "boom".to_string()),
+            skipped: false,
+            duration_ms,
+        }
+    }
+
+    #[tokio::test]
+    async fn test_execute_records_duration_per_repository() {
+        let temp_dir = TempDir::new().unwrap();
+        let mut repo = Repository::new(
+            "slow".to_string(),
+            "https://github.com/test/slow.git".to_string(),
+        );
+        repo.path = Some(temp_dir.path().join("slow").to_string_lossy().to_string());
+        fs::create_dir_all(repo.get_target_dir()).unwrap();
+
+        let mut config = Config::new();
+        config.repositories = vec![repo];
+        let context = create_test_context(config);
+
+        RunCommand::new_command("sleep 0.2".to_string(), true, None)
+            .with_timings(Some(1))
+            .execute(&context)
+            .await
+            .unwrap();
+
+        let outcomes = context.outcomes.outcomes();
+        assert_eq!(outcomes.len(), 1);
+        assert!(outcomes[0].duration_ms >= 200, "{:?}", outcomes[0]);
+    }
+
+    #[test]
+    fn test_slowest_orders_by_duration_then_name() {
+        let outcomes = vec![
+            outcome("fast", 10, true),
+            outcome("slow", 900, false),
+            outcome("b-medium", 300, true),
+            outcome("a-medium", 300, true),
+        ];
+
+        let names: Vec<&str> = slowest(&outcomes, 3)
+            .iter()
+            .map(|o| o.name.as_str())
+            .collect();
+        assert_eq!(names, vec!["slow", "a-medium", "b-medium"]);
+        assert_eq!(slowest(&outcomes, 10).len(), 4);
+    }
+
+    #[test]
+    fn test_summary_tables_show_durations() {
+        let outcomes = vec![outcome("web", 1500, false), outcome("api", 850, true)];
+
+        assert_eq!(
+            summary_table(&outcomes).render(false),
+            "  REPOSITORY  STATUS  DURATION\n  \
+             api         ok         850ms\n  \
+             web         failed      1.5s\n"
+        );
+        assert_eq!(
+            timings_table(&slowest(&outcomes, 1)).render(false),
+            "  #  REPOSITORY  DURATION\n  \
+             1  web             1.5s\n"
+        );
+    }
+
+    #[test]
+    fn test_structured_summaries_unmarshal_with_per_repo_fields() {
+        let mut failed = outcome("web", 1500, false);
+        failed.error = Some("exit code 2".to_string());
+        let summary = RunSummary::new(&[failed, outcome("api", 850, true)], 1);
+
+        let json = summary.render(SummaryFormat::Json).unwrap().unwrap();
+        let parsed: RunSummary = serde_json::from_str(&json).unwrap();
+        assert_eq!(parsed, summary);
+        assert_eq!(
+            (parsed.repositories, parsed.succeeded, parsed.failed),
+            (2, 1, 1)
+        );
+        assert_eq!(parsed.archived_skipped, 1);
+        assert_eq!(parsed.results[0].name, "api");
+        assert_eq!(parsed.results[0].duration_ms, 850);
+        assert_eq!(parsed.results[1].error.as_deref(), Some("exit code 2"));
+
+        let yaml = summary.render(SummaryFormat::Yaml).unwrap().unwrap();
+        let parsed: RunSummary = serde_yaml::from_str(&yaml).unwrap();
+        assert_eq!(parsed, summary);
+        assert!(!parsed.results[1].success);
+    }
+
+    #[test]
+    fn test_table_summary_format_stays_human_readable() {
+        let summary = RunSummary::new(&[outcome("api", 850, true)], 0);
+        assert_eq!(summary.render(SummaryFormat::Table).unwrap(), None);
+        assert_eq!(SummaryFormat::default(), SummaryFormat::Table);
+
+        assert_eq!(
+            "yaml".parse::<SummaryFormat>().unwrap(),
+            SummaryFormat::Yaml
+        );
+        assert!("csv".parse::<SummaryFormat>().is_err());
+    }
+
+    #[test]
+    fn test_buffered_outputs_follow_config_order() {
+        let buffer = |name: &str| {
+            let mut output = OutputBuffer::new();
+            output.out_line(&format!("{name} | Running 'make'"));
+            output.out_line(&format!("{name} | done"));
+            Some(output)
+        };
+        // Completion order: the last repository finished first
+        let outputs = vec![
+            (2, buffer("c")),
+            (0, buffer("a")),
+            (1, None),
+            (3, buffer("d")),
+        ];
+
+        let mut out = Vec::new();
+        for output in in_config_order(outputs) {
+            output.write_to(&mut out, &mut std::io::sink()).unwrap();
+        }
+        assert_eq!(
+            String::from_utf8(out).unwrap(),
+            "a | Running 'make'\na | done\n\
+             c | Running 'make'\nc | done\n\
+             d | Running 'make'\nd | done\n"
+        );
+    }
 }
@@ -0,0 +1,157 @@
+//! Status command implementation
+//!
+//! Reports what each clone has checked out and whether it has uncommitted
+//! changes, warning about a detached `HEAD`, where new commits belong to no
+//! branch and are easily lost.
+
+use super::{Command, CommandContext};
+use crate::git;
+use anyhow::Result;
+use async_trait::async_trait;
+use colored::*;
+use std::time::Instant;
+
+/// Status command for reporting the branch and work tree state of each clone
+pub struct StatusCommand;
+
+/// Checked-out state of one clone
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct CloneStatus {
+    /// Checked-out branch; `None` when `HEAD` is detached
+    pub branch: Option<String>,
+    /// Whether the work tree or index has uncommitted changes
+    pub has_changes: bool,
+}
+
+impl CloneStatus {
+    /// Read the status of the clone at `repo_path`
+    pub fn read(repo_path: &str) -> Result<Self> {
+        let branch = if git::is_detached_head(repo_path)? {
+            None
+        } else {
+            Some(git::get_current_branch(repo_path)?)
+        };
+        Ok(Self {
+            branch,
+            has_changes: git::has_changes(repo_path)?,
+        })
+    }
+}
+
+#[async_trait]
+impl Command for StatusCommand {
+    async fn execute(&self, context: &CommandContext) -> Result<()> {
+        let repositories = context.config.filter_repositories(
+            &context.tag,
+            &context.exclude_tag,
+            context.repos.as_deref(),
+        );
+
+        if repositories.is_empty() {
+            println!(
+                "{}",
+                context.with_tag_hint("No repositories found").yellow()
+            );
+            return Ok(());
+        }
+
+        let mut failed = 0;
+        for repo in &repositories {
+            if !repo.exists() {
+                println!(
+                    "{} | {}",
+                    repo.name.cyan().bold(),
+                    "Not cloned, skipping".yellow()
+                );
+                continue;
+            }
+
+            let started = Instant::now();
+            let result = CloneStatus::read(&repo.get_target_dir());
+            context
+                .outcomes
+                .record_result(&repo.name, &result, started.elapsed());
+            match result {
+                Ok(status) => {
+                    let changes = if status.has_changes {
+                        format!(", {}", "uncommitted changes".yellow())
+                    } else {
+                        String::new()
+                    };
+                    match status.branch {
+                        Some(branch) => {
+                            println!("{} | On {}{}", repo.name.cyan().bold(), branch, changes)
+                        }
+                        None => println!(
+                            "{} | {}{}",
+                            repo.name.cyan().bold(),
+                            "Warning: HEAD is detached; new commits will not be on any branch"
+                                .yellow(),
+                            changes
+                        ),
+                    }
+                }
+                Err(e) => {
+                    eprintln!(
+                        "{} | {}",
+                        repo.name.cyan().bold(),
+                        format!("Error: {e}").red()
+                    );
+                    failed += 1;
+                }
+            }
+        }
+
+        if failed > 0 {
+            anyhow::bail!("Failed to read the status of {} repositories", failed);
+        }
+        Ok(())
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use std::process::Command;
+    use tempfile::TempDir;
+
+    fn git(dir: &std::path::Path, args: &[&str]) {
+        let status = Command::new("git")
+            .args(args)
+            .current_dir(dir)
+            .status()
+            .unwrap();
+        assert!(status.success(), "git {:?}", args);
+    }
+
+    #[test]
+    fn test_clone_status_reports_branch_changes_and_detached_head() {
+        let temp_dir = TempDir::new().unwrap();
+        let dir = temp_dir.path();
+        git(dir, &["init", "--quiet", "--initial-branch", "main"]);
+        git(dir, &["config", "user.email", "test@example.com"]);
+        git(dir, &["config", "user.name", "Test"]);
+        std::fs::write(dir.join("README.md"), "# Test\n").unwrap();
+        git(dir, &["add", "README.md"]);
+        git(dir, &["commit", "--quiet", "-m", "Initial"]);
+        let path = dir.to_string_lossy().to_string();
+
+        assert_eq!(
+            CloneStatus::read(&path).unwrap(),
+            CloneStatus {
+                branch: Some("main".to_string()),
+                has_changes: false,
+            }
+        );
+
+        git(dir, &["checkout", "--quiet", "--detach"]);
+        std::fs::write(dir.join("notes.txt"), "draft").unwrap();
+        assert_eq!(
+            CloneStatus::read(&path).unwrap(),
+            CloneStatus {
+                branch: None,
+                has_changes: true,
+            }
+        );
+    }
+}
@@ -91,15 +91,19 @@ pub fn validate_run_args(command: &Option<String>, recipe: &Option<String>) -> R
 
 /// Validate PR command arguments
 ///
-/// Ensures that required GitHub authentication is available
+/// Ensures that required GitHub or Bitbucket authentication is available
 pub fn validate_pr_args(token: &Option<String>) -> Result<()> {
-    if token.is_none() && std::env::var("GITHUB_TOKEN").is_err() {
+    if token.is_none()
+        && std::env::var("GITHUB_TOKEN").is_err()
+        && std::env::var("BITBUCKET_TOKEN").is_err()
+    {
         return Err(validation_error_to_anyhow(
             CommandValidationError::MissingRequired {
-                argument: "GitHub token".to_string(),
+                argument: "GitHub or Bitbucket token".to_string(),
                 alternatives: vec![
                     "--token".to_string(),
                     "GITHUB_TOKEN environment variable".to_string(),
+                    "BITBUCKET_TOKEN environment variable".to_string(),
                 ],
             },
         ));
@@ -161,6 +165,22 @@ pub fn validate_output_directory(output_dir: &Option<String>) -> Result<()> {
     Ok(())
 }
 
+/// Validate allowed exit codes
+///
+/// Ensures every code can actually be returned by a process
+pub fn validate_exit_codes(codes: &[i32]) -> Result<()> {
+    if let Some(code) = codes.iter().find(|code| !(0..=255).contains(*code)) {
+        return Err(validation_error_to_anyhow(
+            CommandValidationError::InvalidValue {
+                argument: "allow-exit-codes".to_string(),
+                value: code.to_string(),
+                reason: "exit codes must be between 0 and 255".to_string(),
+            },
+        ));
+    }
+    Ok(())
+}
+
 /// Validate branch name
 ///
 /// Ensures branch names follow basic Git naming conventions
@@ -326,6 +346,19 @@ mod tests {
         );
     }
 
+    #[test]
+    fn test_validate_exit_codes() {
+        assert!(validate_exit_codes(&[]).is_ok());
+        assert!(validate_exit_codes(&[0, 1, 255]).is_ok());
+
+        let err = validate_exit_codes(&[0, 256]).unwrap_err();
+        assert!(
+            err.to_string()
+                .contains("Invalid value '256' for allow-exit-codes")
+        );
+        assert!(validate_exit_codes(&[-1]).is_err());
+    }
+
     #[test]
     fn test_validate_branch_name_valid() {
         let branch = Some("feature/new-feature".to_string());
@@ -439,24 +472,60 @@ mod tests {
     fn test_validate_pr_args_missing_token() {
         // Save the current environment variable state
         let original_token = std::env::var("GITHUB_TOKEN").ok();
+        let original_bitbucket_token = std::env::var("BITBUCKET_TOKEN").ok();
 
-        // Temporarily remove the environment variable
+        // Temporarily remove the environment variables
         unsafe {
             std::env::remove_var("GITHUB_TOKEN");
+            std::env::remove_var("BITBUCKET_TOKEN");
         }
 
         let token = None;
         let result = validate_pr_args(&token);
 
-        // Restore the original environment variable if it existed
-        if let Some(token_value) = original_token {
-            unsafe {
+        // Restore the original environment variables if they existed
+        unsafe {
+            if let Some(token_value) = original_token {
                 std::env::set_var("GITHUB_TOKEN", token_value);
             }
+            if let Some(token_value) = original_bitbucket_token {
+                std::env::set_var("BITBUCKET_TOKEN", token_value);
+            }
         }
 
         assert!(result.is_err());
-        assert!(result.unwrap_err().to_string().contains("GitHub token"));
+        assert!(
+            result
+                .unwrap_err()
+                .to_string()
+                .contains("GitHub or Bitbucket token")
+        );
+    }
+
+    #[test]
+    #[serial]
+    fn test_validate_pr_args_with_bitbucket_env_var() {
+        let original_token = std::env::var("GITHUB_TOKEN").ok();
+        let original_bitbucket_token = std::env::var("BITBUCKET_TOKEN").ok();
+
+        unsafe {
+            std::env::remove_var("GITHUB_TOKEN");
+            std::env::set_var("BITBUCKET_TOKEN", "user:app-password");
+        }
+
+        let result = validate_pr_args(&None);
+
+        unsafe {
+            if let Some(token_value) = original_token {
+                std::env::set_var("GITHUB_TOKEN", token_value);
+            }
+            match original_bitbucket_token {
+                Some(token_value) => std::env::set_var("BITBUCKET_TOKEN", token_value),
+                None => std::env::remove_var("BITBUCKET_TOKEN"),
+            }
+        }
+
+        assert!(result.is_ok());
     }
 
     #[test]
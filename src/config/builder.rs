@@ -1,6 +1,7 @@
 //! Repository builder utilities
 
-use super::Repository;
+use super::{Provider, Repository};
+use std::collections::BTreeMap;
 
 /// Builder for creating repository configurations
 pub struct RepositoryBuilder {
@@ -9,6 +10,10 @@ pub struct RepositoryBuilder {
     tags: Vec<String>,
     path: Option<String>,
     branch: Option<String>,
+    ssh_key: Option<String>,
+    provider: Option<Provider>,
+    commands: BTreeMap<String, String>,
+    timeout: Option<String>,
 }
 
 impl RepositoryBuilder {
@@ -20,6 +25,10 @@ impl RepositoryBuilder {
             tags: Vec::new(),
             path: None,
             branch: None,
+            ssh_key: None,
+            provider: None,
+            commands: BTreeMap::new(),
+            timeout: None,
         }
     }
 
@@ -41,6 +50,30 @@ impl RepositoryBuilder {
         self
     }
 
+    /// Set the SSH key used for git operations on the repository
+    pub fn with_ssh_key(mut self, ssh_key: String) -> Self {
+        self.ssh_key = Some(ssh_key);
+        self
+    }
+
+    /// Set the hosting provider used for pull requests
+    pub fn with_provider(mut self, provider: Provider) -> Self {
+        self.provider = Some(provider);
+        self
+    }
+
+    /// Add a named command for the repository
+    pub fn with_command(mut self, name: String, command: String) -> Self {
+        self.commands.insert(name, command);
+        self
+    }
+
+    /// Set the time limit for commands run in the repository
+    pub fn with_timeout(mut self, timeout: String) -> Self {
+        self.timeout = Some(timeout);
+        self
+    }
+
     /// Build the repository
     pub fn build(self) -> Repository {
         Repository {
@@ -49,7 +82,11 @@ impl RepositoryBuilder {
             tags: self.tags,
             path: self.path,
             branch: self.branch,
-            config_dir: None,
+            ssh_key: self.ssh_key,
+            provider: self.provider,
+            commands: self.commands,
+            timeout: self.timeout,
+            ..Default::default()
         }
     }
 }
@@ -0,0 +1,128 @@
+//! Finding the config file when `-c/--config` is not given
+//!
+//! Starting in the current directory and moving up through its parents, the
+//! first directory containing `repos.yaml`, `.repos.yaml` or `config.yaml`
+//! (checked in that order) provides the config. Failing that, the user-wide
+//! `$XDG_CONFIG_HOME/repos/config.yaml` is used, with `XDG_CONFIG_HOME`
+//! defaulting to `~/.config`.
+
+use std::path::{Path, PathBuf};
+
+/// File names looked for in each directory, most specific first
+pub const CONFIG_FILE_NAMES: [&str; 3] = ["repos.yaml", ".repos.yaml", "config.yaml"];
+
+/// The nearest config file at or above `start`, else the one in `config_home`
+///
+/// `config_home` is the XDG config directory; the file is read from its
+/// `repos/config.yaml`.
+pub fn discover_config_from(start: &Path, config_home: Option<&Path>) -> Option<PathBuf> {
+    start
+        .ancestors()
+        .flat_map(|dir| CONFIG_FILE_NAMES.iter().map(move |name| dir.join(name)))
+        .chain(config_home.map(|home| home.join("repos").join("config.yaml")))
+        .find(|candidate| candidate.is_file())
+}
+
+/// The XDG config directory: `$XDG_CONFIG_HOME`, or `~/.config` when unset or empty
+pub fn config_home() -> Option<PathBuf> {
+    match std::env::var_os("XDG_CONFIG_HOME") {
+        Some(dir) if !dir.is_empty() => Some(PathBuf::from(dir)),
+        _ => std::env::var_os("HOME").map(|home| PathBuf::from(home).join(".config")),
+    }
+}
+
+/// The config file to use from the current directory, if any
+pub fn discover_config() -> Option<PathBuf> {
+    let start = std::env::current_dir().ok()?;
+    discover_config_from(&start, config_home().as_deref())
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use serial_test::serial;
+    use tempfile::TempDir;
+
+    fn write(path: &Path) {
+        std::fs::create_dir_all(path.parent().unwrap()).unwrap();
+        std::fs::write(path, "repositories: []\n").unwrap();
+    }
+
+    #[test]
+    fn test_nearest_ancestor_config_wins() {
+        let root = TempDir::new().unwrap();
+        let nested = root.path().join("team/service/src");
+        std::fs::create_dir_all(&nested).unwrap();
+        write(&root.path().join("repos.yaml"));
+        write(&root.path().join("team/.repos.yaml"));
+
+        assert_eq!(
+            discover_config_from(&nested, None),
+            Some(root.path().join("team/.repos.yaml"))
+        );
+        assert_eq!(
+            discover_config_from(root.path(), None),
+            Some(root.path().join("repos.yaml"))
+        );
+
+        // repos.yaml is preferred over the other names in the same directory
+        write(&root.path().join("team/config.yaml"));
+        write(&root.path().join("team/repos.yaml"));
+        assert_eq!(
+            discover_config_from(&nested, None),
+            Some(root.path().join("team/repos.yaml"))
+        );
+    }
+
+    #[test]
+    fn test_config_home_is_the_fallback() {
+        let root = TempDir::new().unwrap();
+        let work = root.path().join("work");
+        let home = root.path().join("xdg");
+        std::fs::create_dir_all(&work).unwrap();
+
+        assert_eq!(discover_config_from(&work, Some(&home)), None);
+
+        write(&home.join("repos/config.yaml"));
+        assert_eq!(
+            discover_config_from(&work, Some(&home)),
+            Some(home.join("repos/config.yaml"))
+        );
+
+        // A directory named like a config file does not count
+        std::fs::create_dir_all(work.join("config.yaml")).unwrap();
+        assert_eq!(
+            discover_config_from(&work, Some(&home)),
+            Some(home.join("repos/config.yaml"))
+        );
+    }
+
+    #[test]
+    #[serial]
+    fn test_config_home_reads_environment() {
+        let original_xdg = std::env::var_os("XDG_CONFIG_HOME");
+        let original_home = std::env::var_os("HOME");
+
+        unsafe {
+            std::env::set_var("XDG_CONFIG_HOME", "/tmp/xdg-config");
+        }
+        assert_eq!(config_home(), Some(PathBuf::from("/tmp/xdg-config")));
+
+        unsafe {
+            std::env::set_var("XDG_CONFIG_HOME", "");
+            std::env::set_var("HOME", "/home/dev");
+        }
+        assert_eq!(config_home(), Some(PathBuf::from("/home/dev/.config")));
+
+        unsafe {
+            match original_xdg {
+                Some(value) => std::env::set_var("XDG_CONFIG_HOME", value),
+                None => std::env::remove_var("XDG_CONFIG_HOME"),
+            }
+            match original_home {
+                Some(value) => std::env::set_var("HOME", value),
+                None => std::env::remove_var("HOME"),
+            }
+        }
+    }
+}
@@ -0,0 +1,154 @@
+//! Config file formats
+//!
+//! Besides YAML, a config may be written as JSON or TOML with the same
+//! structure. The format follows the file extension (`.yaml`/`.yml`, `.json`,
+//! `.toml`); other and missing extensions, and a config read from stdin, are
+//! read as YAML. `--config-format` overrides the detection. Saving a config
+//! writes it back in the same format.
+
+use anyhow::{Context, Result};
+use serde::Serialize;
+use serde_yaml::Value;
+use std::fmt;
+use std::path::Path;
+use std::str::FromStr;
+
+/// Syntax a config file is written in
+#[derive(Debug, Clone, Copy, Default, PartialEq, Eq)]
+pub enum ConfigFormat {
+    #[default]
+    Yaml,
+    Json,
+    Toml,
+}
+
+impl ConfigFormat {
+    /// The format implied by a file's extension, YAML when it has no known one
+    pub fn from_path(path: &Path) -> Self {
+        let extension = path
+            .extension()
+            .and_then(|extension| extension.to_str())
+            .map(str::to_lowercase);
+        match extension.as_deref() {
+            Some("json") => Self::Json,
+            Some("toml") => Self::Toml,
+            _ => Self::Yaml,
+        }
+    }
+
+    /// Decode a document into a YAML value, whatever its format
+    ///
+    /// Loading then proceeds the same way for every format (version check,
+    /// merge keys, deserialization into [`Config`](super::Config)).
+    pub fn parse(self, content: &str) -> Result<Value> {
+        match self {
+            Self::Yaml => serde_yaml::from_str(content).context("Invalid YAML"),
+            Self::Json => serde_json::from_str(content).context("Invalid JSON"),
+            Self::Toml => toml::from_str(content).context("Invalid TOML"),
+        }
+    }
+
+    /// Encode `value` as a document in this format
+    pub fn render<T: Serialize>(self, value: &T) -> Result<String> {
+        match self {
+            Self::Yaml => serde_yaml::to_string(value).context("Cannot encode YAML"),
+            Self::Json => serde_json::to_string_pretty(value).context("Cannot encode JSON"),
+            Self::Toml => toml::to_string_pretty(value).context("Cannot encode TOML"),
+        }
+    }
+}
+
+impl FromStr for ConfigFormat {
+    type Err = anyhow::Error;
+
+    fn from_str(s: &str) -> Result<Self> {
+        match s.to_lowercase().as_str() {
+            "yaml" | "yml" => Ok(Self::Yaml),
+            "json" => Ok(Self::Json),
+            "toml" => Ok(Self::Toml),
+            other => anyhow::bail!(
+                "Unknown config format '{}': expected yaml, json or toml",
+                other
+            ),
+        }
+    }
+}
+
+impl fmt::Display for ConfigFormat {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        match self {
+            Self::Yaml => write!(f, "yaml"),
+            Self::Json => write!(f, "json"),
+            Self::Toml => write!(f, "toml"),
+        }
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_format_from_path() {
+        assert_eq!(
+            ConfigFormat::from_path(Path::new("repos.yaml")),
+            ConfigFormat::Yaml
+        );
+        assert_eq!(
+            ConfigFormat::from_path(Path::new("ci/repos.yml")),
+            ConfigFormat::Yaml
+        );
+        assert_eq!(
+            ConfigFormat::from_path(Path::new("repos.JSON")),
+            ConfigFormat::Json
+        );
+        assert_eq!(
+            ConfigFormat::from_path(Path::new("repos.toml")),
+            ConfigFormat::Toml
+        );
+        assert_eq!(
+            ConfigFormat::from_path(Path::new("repos")),
+            ConfigFormat::Yaml
+        );
+        assert_eq!(
+            ConfigFormat::from_path(Path::new("repos.conf")),
+            ConfigFormat::Yaml
+        );
+    }
+
+    #[test]
+    fn test_format_from_str() {
+        assert_eq!("json".parse::<ConfigFormat>().unwrap(), ConfigFormat::Json);
+        assert_eq!("YML".parse::<ConfigFormat>().unwrap(), ConfigFormat::Yaml);
+        assert_eq!(
+            "ini".parse::<ConfigFormat>().unwrap_err().to_string(),
+            "Unknown config format 'ini': expected yaml, json or toml"
+        );
+    }
+
+    #[test]
+    fn test_render_parses_back_in_each_format() {
+        let document: Value =
+            serde_yaml::from_str("version: 1\nrepositories:\n  - name: api\n    url: u\n").unwrap();
+        for format in [ConfigFormat::Yaml, ConfigFormat::Json, ConfigFormat::Toml] {
+            let rendered = format.render(&document).unwrap();
+            assert_eq!(format.parse(&rendered).unwrap(), document, "{format}");
+        }
+        assert!(
+            ConfigFormat::Json
+                .render(&document)
+                .unwrap()
+                .starts_with("{\n")
+        );
+    }
+
+    #[test]
+    fn test_parse_reports_the_format() {
+        let err = ConfigFormat::Json
+            .parse("{\"repositories\": [")
+            .unwrap_err();
+        assert!(err.to_string().starts_with("Invalid JSON"));
+        let err = ConfigFormat::Toml.parse("[[repositories]\n").unwrap_err();
+        assert!(err.to_string().starts_with("Invalid TOML"));
+    }
+}
@@ -0,0 +1,94 @@
+//! Selection by remote host
+//!
+//! `--host github.com,gitlab.com` keeps the repositories whose URL points at
+//! one of the hosts, read from the URL the same way as for provider
+//! detection. Hosts compare case-insensitively and must match exactly, so
+//! `github.com` does not select a GitHub Enterprise host.
+
+use super::Repository;
+use super::provider::url_host;
+
+/// Hosts a repository's URL must point at to be selected
+#[derive(Debug, Clone, Default, PartialEq, Eq)]
+pub struct HostFilter {
+    hosts: Vec<String>,
+}
+
+impl HostFilter {
+    pub fn new(hosts: &[String]) -> Self {
+        Self {
+            hosts: hosts
+                .iter()
+                .map(|host| host.trim().to_lowercase())
+                .filter(|host| !host.is_empty())
+                .collect(),
+        }
+    }
+
+    /// Whether the filter selects every repository
+    pub fn is_empty(&self) -> bool {
+        self.hosts.is_empty()
+    }
+
+    /// Whether `repo`'s URL points at one of the hosts
+    pub fn matches(&self, repo: &Repository) -> bool {
+        url_host(&repo.url)
+            .map(str::to_lowercase)
+            .is_some_and(|host| self.hosts.contains(&host))
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn fleet() -> Vec<Repository> {
+        [
+            ("api", "git@github.com:acme/api.git"),
+            ("web", "https://github.com/acme/web.git"),
+            ("infra", "https://gitlab.com/acme/infra.git"),
+            ("billing", "git@bitbucket.org:acme/billing.git"),
+            ("legacy", "ssh://git@git.acme.internal:2222/legacy.git"),
+            ("enterprise", "git@github.acme.com:platform/core.git"),
+        ]
+        .iter()
+        .map(|(name, url)| Repository::new(name.to_string(), url.to_string()))
+        .collect()
+    }
+
+    fn selected(filter: &HostFilter) -> Vec<String> {
+        fleet()
+            .into_iter()
+            .filter(|repo| filter.matches(repo))
+            .map(|repo| repo.name)
+            .collect()
+    }
+
+    fn hosts(values: &[&str]) -> HostFilter {
+        HostFilter::new(&values.iter().map(|v| v.to_string()).collect::<Vec<_>>())
+    }
+
+    #[test]
+    fn test_single_host_matches_ssh_and_https_urls() {
+        assert_eq!(selected(&hosts(&["github.com"])), vec!["api", "web"]);
+        assert_eq!(selected(&hosts(&["GitLab.com"])), vec!["infra"]);
+    }
+
+    #[test]
+    fn test_multiple_hosts_are_alternatives() {
+        assert_eq!(
+            selected(&hosts(&["bitbucket.org", "gitlab.com"])),
+            vec!["infra", "billing"]
+        );
+        // The port is not part of the host
+        assert_eq!(selected(&hosts(&["git.acme.internal"])), vec!["legacy"]);
+    }
+
+    #[test]
+    fn test_hosts_match_exactly() {
+        assert!(selected(&hosts(&["acme.com"])).is_empty());
+        assert_eq!(selected(&hosts(&["github.acme.com"])), vec!["enterprise"]);
+        assert!(hosts(&[]).is_empty());
+        assert!(hosts(&[" "]).is_empty());
+    }
+}
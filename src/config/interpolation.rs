@@ -0,0 +1,163 @@
+//! `${VAR}` references in config values
+//!
+//! After parsing, the `url`, `path`, `branch` and `ssh_key` of every
+//! repository have environment references replaced:
+//!
+//! - `${NAME}` is the value of `NAME`; loading fails when it is unset.
+//! - `${NAME:-default}` falls back to `default` when `NAME` is unset or empty.
+//! - `$${` is a literal `${`.
+
+use super::Repository;
+use anyhow::{Context, Result, bail};
+
+/// Replace `${VAR}` references in `value` from the process environment
+pub fn expand_env(value: &str) -> Result<String> {
+    expand_env_with(value, |name| std::env::var(name).ok())
+}
+
+/// Replace `${VAR}` references in `value`, reading variables with `lookup`
+pub fn expand_env_with(value: &str, lookup: impl Fn(&str) -> Option<String>) -> Result<String> {
+    let mut expanded = String::with_capacity(value.len());
+    let mut rest = value;
+    while let Some(start) = rest.find('$') {
+        expanded.push_str(&rest[..start]);
+        let after = &rest[start + 1..];
+        if let Some(escaped) = after.strip_prefix("${") {
+            expanded.push_str("${");
+            rest = escaped;
+            continue;
+        }
+        let Some(reference) = after.strip_prefix('{') else {
+            expanded.push('$');
+            rest = after;
+            continue;
+        };
+        let end = reference
+            .find('}')
+            .with_context(|| format!("Unterminated '${{' in '{}'", value))?;
+        expanded.push_str(&resolve(&reference[..end], &lookup)?);
+        rest = &reference[end + 1..];
+    }
+    expanded.push_str(rest);
+    Ok(expanded)
+}
+
+/// The value of one reference body: `NAME` or `NAME:-default`
+fn resolve(reference: &str, lookup: &impl Fn(&str) -> Option<String>) -> Result<String> {
+    let (name, default) = match reference.split_once(":-") {
+        Some((name, default)) => (name, Some(default)),
+        None => (reference, None),
+    };
+    let valid = name.starts_with(|c: char| c.is_ascii_alphabetic() || c == '_')
+        && name.chars().all(|c| c.is_ascii_alphanumeric() || c == '_');
+    if !valid {
+        bail!("Invalid environment variable name '{}'", name);
+    }
+
+    match (lookup(name), default) {
+        (Some(value), Some(default)) if value.is_empty() => Ok(default.to_string()),
+        (Some(value), _) => Ok(value),
+        (None, Some(default)) => Ok(default.to_string()),
+        (None, None) => bail!(
+            "Environment variable '{}' is not set (use ${{{}:-default}} to give a default)",
+            name,
+            name
+        ),
+    }
+}
+
+/// Whether `content` holds `${` references, escaped or not: loading replaces
+/// both, so saving the loaded values would not write them back
+pub fn has_references(content: &str) -> bool {
+    content.contains("${")
+}
+
+/// Expand the environment references in a repository's string fields
+pub fn expand_repository(repo: &mut Repository) -> Result<()> {
+    let name = repo.name.clone();
+    let context = |field: &str| format!("Invalid {} of repository '{}'", field, name);
+
+    repo.url = expand_env(&repo.url).with_context(|| context("url"))?;
+    for (field, value) in [
+        ("path", &mut repo.path),
+        ("branch", &mut repo.branch),
+        ("ssh_key", &mut repo.ssh_key),
+    ] {
+        if let Some(value) = value {
+            *value = expand_env(value).with_context(|| context(field))?;
+        }
+    }
+    Ok(())
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn lookup(name: &str) -> Option<String> {
+        match name {
+            "GIT_HOST" => Some("git.example.com".to_string()),
+            "EMPTY" => Some(String::new()),
+            _ => None,
+        }
+    }
+
+    #[test]
+    fn test_expand_env_replaces_references() {
+        assert_eq!(
+            expand_env_with("git@${GIT_HOST}:team/api.git", lookup).unwrap(),
+            "git@git.example.com:team/api.git"
+        );
+        assert_eq!(
+            expand_env_with("${GIT_HOST}/${GIT_HOST}", lookup).unwrap(),
+            "git.example.com/git.example.com"
+        );
+        assert_eq!(
+            expand_env_with("no references", lookup).unwrap(),
+            "no references"
+        );
+    }
+
+    #[test]
+    fn test_expand_env_defaults() {
+        assert_eq!(
+            expand_env_with("${WORKSPACE:-~/src}/api", lookup).unwrap(),
+            "~/src/api"
+        );
+        // Empty values fall back too, like the shell's `:-`
+        assert_eq!(expand_env_with("${EMPTY:-main}", lookup).unwrap(), "main");
+        assert_eq!(
+            expand_env_with("${GIT_HOST:-github.com}", lookup).unwrap(),
+            "git.example.com"
+        );
+        assert_eq!(expand_env_with("${WORKSPACE:-}", lookup).unwrap(), "");
+    }
+
+    #[test]
+    fn test_expand_env_undefined_variable_fails() {
+        let err = expand_env_with("git@${MISSING}:team/api.git", lookup).unwrap_err();
+        assert_eq!(
+            err.to_string(),
+            "Environment variable 'MISSING' is not set (use ${MISSING:-default} to give a default)"
+        );
+
+        assert!(expand_env_with("${GIT_HOST", lookup).is_err());
+        assert!(expand_env_with("${1ST}", lookup).is_err());
+        assert!(expand_env_with("${}", lookup).is_err());
+    }
+
+    #[test]
+    fn test_expand_env_keeps_other_dollar_signs() {
+        assert_eq!(
+            expand_env_with("$HOME/$${GIT_HOST}/cost$", lookup).unwrap(),
+            "$HOME/${GIT_HOST}/cost$"
+        );
+    }
+
+    #[test]
+    fn test_has_references() {
+        assert!(has_references("url: git@${GIT_HOST}:team/api.git"));
+        assert!(has_references("path: $${literal}"));
+        assert!(!has_references("path: $HOME/src"));
+    }
+}
@@ -1,52 +1,270 @@
 //! Configuration file loading and saving
 
 use super::Repository;
+use super::format::ConfigFormat;
+use super::interpolation;
+use super::migration::{self, CURRENT_CONFIG_VERSION};
+use super::recipe_args::RecipeArg;
+use super::repo_tags;
+use super::repository::resolve_base_dir;
+use super::run_policy::RunPolicy;
+use super::tags;
 use crate::utils::filters;
 use crate::utils::validators;
-use anyhow::Result;
+use anyhow::{Context, Result};
 use serde::{Deserialize, Serialize};
+use std::collections::BTreeMap;
+use std::io::Read;
 use std::path::Path;
+use std::sync::OnceLock;
+
+/// `-c/--config` value that reads the configuration from stdin
+pub const STDIN_PATH: &str = "-";
 
 #[derive(Debug, Clone, Serialize, Deserialize)]
 pub struct Recipe {
     pub name: String,
     pub steps: Vec<String>,
+    /// Arguments given with `--arg` and used as `{{.name}}` in the steps
+    #[serde(default, skip_serializing_if = "Vec::is_empty")]
+    pub args: Vec<RecipeArg>,
+}
+
+/// Which repositories of an organization to include
+#[derive(Debug, Clone, Copy, Default, PartialEq, Eq, Serialize, Deserialize)]
+#[serde(rename_all = "lowercase")]
+pub enum Visibility {
+    #[default]
+    All,
+    Public,
+    Private,
+}
+
+impl Visibility {
+    pub fn as_str(&self) -> &'static str {
+        match self {
+            Self::All => "all",
+            Self::Public => "public",
+            Self::Private => "private",
+        }
+    }
+}
+
+/// An organization whose repositories are added to the configuration at load time
+#[derive(Debug, Clone, PartialEq, Serialize, Deserialize)]
+pub struct OrgSource {
+    pub org: String,
+    /// Only include repositories with this GitHub topic
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub topic: Option<String>,
+    #[serde(default, skip_serializing_if = "is_default_visibility")]
+    pub visibility: Visibility,
+    /// Tags applied to every repository expanded from this organization
+    #[serde(default, skip_serializing_if = "Vec::is_empty")]
+    pub tags: Vec<String>,
+}
+
+fn is_default_visibility(visibility: &Visibility) -> bool {
+    *visibility == Visibility::All
+}
+
+/// Named flag defaults selected with `--profile`
+///
+/// A value only applies when the corresponding flag is not given on the
+/// command line.
+#[derive(Debug, Clone, Default, PartialEq, Serialize, Deserialize)]
+pub struct Profile {
+    /// Default for `--tag`
+    #[serde(default, skip_serializing_if = "Vec::is_empty")]
+    pub tag: Vec<String>,
+    /// Default for `--exclude-tag`
+    #[serde(default, skip_serializing_if = "Vec::is_empty")]
+    pub exclude_tag: Vec<String>,
+    /// Default for `--parallel`
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub parallel: Option<bool>,
+    /// Default for `--jobs`
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub jobs: Option<usize>,
+    /// Default for `run --output-dir`
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub output_dir: Option<String>,
+}
+
+/// Flag values of one invocation that a [`Profile`] can fill in
+///
+/// Flags a command does not have are `None`.
+pub struct ProfileFlags<'a> {
+    pub tag: &'a mut Vec<String>,
+    pub exclude_tag: &'a mut Vec<String>,
+    pub parallel: Option<&'a mut bool>,
+    pub jobs: &'a mut Option<usize>,
+    pub output_dir: Option<&'a mut Option<String>>,
+}
+
+impl Profile {
+    /// Fill in the flags that were not given on the command line
+    pub fn apply(&self, flags: ProfileFlags<'_>) {
+        if flags.tag.is_empty() {
+            flags.tag.clone_from(&self.tag);
+        }
+        if flags.exclude_tag.is_empty() {
+            flags.exclude_tag.clone_from(&self.exclude_tag);
+        }
+        if let (Some(parallel), Some(default)) = (flags.parallel, self.parallel) {
+            *parallel |= default;
+        }
+        if flags.jobs.is_none() {
+            *flags.jobs = self.jobs;
+        }
+        if let Some(output_dir) = flags.output_dir
+            && output_dir.is_none()
+        {
+            output_dir.clone_from(&self.output_dir);
+        }
+    }
+}
+
+/// Settings that apply to every repository in the file
+#[derive(Debug, Clone, Default, Serialize, Deserialize)]
+pub struct Defaults {
+    /// Directory clones live under instead of the config file's directory (e.g. `~/work`)
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub root: Option<String>,
+    /// GitHub API base URL when `--github-api-url` and `GITHUB_API_URL` are not set
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub github_api_url: Option<String>,
+    /// GitHub Enterprise Server hosts whose repositories' API calls go to
+    /// `https://<host>/api/v3` when no GitHub API base URL is set
+    #[serde(default, skip_serializing_if = "Vec::is_empty")]
+    pub github_enterprise_hosts: Vec<String>,
+    /// Shell `run` passes commands to when `--shell` is not given (e.g. `bash`)
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub shell: Option<String>,
+    /// Name `pr` commits under when `--author-name` is not given
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub author_name: Option<String>,
+    /// Email `pr` commits under when `--author-email` is not given
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub author_email: Option<String>,
+}
+
+impl Defaults {
+    /// Whether no defaults are set
+    pub fn is_empty(&self) -> bool {
+        self.root.is_none()
+            && self.github_api_url.is_none()
+            && self.github_enterprise_hosts.is_empty()
+            && self.shell.is_none()
+            && self.author_name.is_none()
+            && self.author_email.is_none()
+    }
 }
 
 #[derive(Debug, Clone, Serialize, Deserialize)]
 pub struct Config {
+    /// Schema version the file was written for; absent in files that predate versioning
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub version: Option<u32>,
+    #[serde(default)]
     pub repositories: Vec<Repository>,
     #[serde(default)]
     pub recipes: Vec<Recipe>,
+    #[serde(default, skip_serializing_if = "Vec::is_empty")]
+    pub orgs: Vec<OrgSource>,
+    /// Flag defaults selectable with `--profile <name>`
+    #[serde(default, skip_serializing_if = "BTreeMap::is_empty")]
+    pub profiles: BTreeMap<String, Profile>,
+    /// Custom health categories, each a list of checker names (used by `health check --categories`)
+    #[serde(default, skip_serializing_if = "BTreeMap::is_empty")]
+    pub categories: BTreeMap<String, Vec<String>>,
+    /// Glob patterns of paths that file-walking health checks skip, e.g. `vendor`
+    #[serde(default, skip_serializing_if = "Vec::is_empty")]
+    pub scan_exclude: Vec<String>,
+    /// Glob paths the `sensitive-files` health check accepts, e.g. `tests/fixtures/**/*.pem`
+    #[serde(default, skip_serializing_if = "Vec::is_empty")]
+    pub sensitive_files_allow: Vec<String>,
+    /// Regex patterns `run` checks every command against before executing it
+    #[serde(default, skip_serializing_if = "RunPolicy::is_empty")]
+    pub run_policy: RunPolicy,
+    /// Settings shared by all repositories, such as the clone `root`
+    #[serde(default, skip_serializing_if = "Defaults::is_empty")]
+    pub defaults: Defaults,
 }
 
 impl Config {
-    /// Load configuration from a file
+    /// Load configuration from a file, in the format its extension implies
     pub fn load(path: &str) -> Result<Self> {
-        let content = std::fs::read_to_string(path)?;
+        Self::load_as(path, ConfigFormat::from_path(Path::new(path)))
+    }
 
-        let mut config: Config = serde_yaml::from_str(&content)?;
+    /// Load configuration from a file written in `format`; a `path` of
+    /// [`STDIN_PATH`] reads it from standard input
+    pub fn load_as(path: &str, format: ConfigFormat) -> Result<Self> {
+        let content = read_source(path)?;
+        let mut document = format
+            .parse(&content)
+            .with_context(|| format!("Cannot load {}", path))?;
+
+        // Reject files from newer releases before their fields fail to parse
+        if let Some(version) = migration::document_version(&document) {
+            migration::check_version(version).with_context(|| format!("Cannot load {}", path))?;
+        }
+
+        // Resolve `<<: *anchor` merge keys, which plain deserialization ignores
+        document.apply_merge()?;
+        let mut config: Config = serde_yaml::from_value(document)?;
 
-        // Set the config directory for each repository
-        let config_path = Path::new(path);
-        let config_dir = config_path.parent().map(|p| p.to_path_buf());
+        // Resolve relative repository paths against the clone root or config directory
+        let config_dir = if path == STDIN_PATH {
+            None
+        } else {
+            Path::new(path).parent()
+        };
+        let base_dir = resolve_base_dir(config.defaults.root.as_deref(), config_dir);
 
         for repo in &mut config.repositories {
-            repo.set_config_dir(config_dir.clone());
+            interpolation::expand_repository(repo)
+                .with_context(|| format!("Cannot load {}", path))?;
+            repo.set_config_dir(base_dir.clone());
         }
 
         // Validate the loaded configuration
         validators::validate_repositories(&config.repositories)
             .map_err(validators::validation_errors_to_anyhow)?;
+        config
+            .run_policy
+            .validate()
+            .with_context(|| format!("Cannot load {}", path))?;
 
         Ok(config)
     }
 
-    /// Save configuration to a file
+    /// Save configuration to a file, in the format its extension implies
     pub fn save(&self, path: &str) -> Result<()> {
         save_config(self, path)
     }
 
+    /// Save configuration to a file in `format`
+    pub fn save_as(&self, path: &str, format: ConfigFormat) -> Result<()> {
+        save_config_as(self, path, format)
+    }
+
+    /// What loading the file at `path`, written in `format`, resolves, so that
+    /// saving the loaded configuration would write it back expanded: `${VAR}`
+    /// references and YAML anchors
+    pub fn load_expansions(path: &str, format: ConfigFormat) -> Result<Vec<&'static str>> {
+        let content = std::fs::read_to_string(path)?;
+        let mut expansions = Vec::new();
+        if interpolation::has_references(&content) {
+            expansions.push("${VAR} references");
+        }
+        if format == ConfigFormat::Yaml && has_yaml_anchors(&content) {
+            expansions.push("YAML anchors");
+        }
+        Ok(expansions)
+    }
+
     /// Filter repositories by specific names
     pub fn filter_by_names(&self, names: &[String]) -> Vec<Repository> {
         filters::filter_by_names(&self.repositories, names)
@@ -121,9 +339,144 @@ impl Config {
     /// Create a new empty configuration
     pub fn new() -> Self {
         Self {
+            version: Some(CURRENT_CONFIG_VERSION),
             repositories: Vec::new(),
             recipes: Vec::new(),
+            orgs: Vec::new(),
+            profiles: BTreeMap::new(),
+            categories: BTreeMap::new(),
+            scan_exclude: Vec::new(),
+            sensitive_files_allow: Vec::new(),
+            run_policy: RunPolicy::default(),
+            defaults: Defaults::default(),
+        }
+    }
+
+    /// Clone every repository under `root` (`--dir`), overriding `defaults.root`
+    ///
+    /// A relative `root` is taken from the current directory.
+    pub fn apply_clone_root(&mut self, root: &str) {
+        self.defaults.root = Some(root.to_string());
+        let base_dir = resolve_base_dir(Some(root), None);
+        for repo in &mut self.repositories {
+            repo.set_config_dir(base_dir.clone());
+        }
+    }
+
+    /// Add the tags each cloned repository lists in its `.repos-tags` file
+    ///
+    /// # Errors
+    /// Returns an error if a tags file exists but cannot be read
+    pub fn merge_repo_tags(&mut self) -> Result<()> {
+        for repo in &mut self.repositories {
+            repo_tags::merge_repo_tags(repo)?;
+        }
+        Ok(())
+    }
+
+    /// Use `ssh_key` for every repository that doesn't configure its own key
+    pub fn apply_default_ssh_key(&mut self, ssh_key: &str) {
+        for repo in &mut self.repositories {
+            if repo.ssh_key.is_none() {
+                repo.ssh_key = Some(ssh_key.to_string());
+            }
+        }
+    }
+
+    /// Add `git config` entries to every repository, keeping keys a repository sets itself
+    pub fn apply_default_git_config(&mut self, entries: &[(String, String)]) {
+        for repo in &mut self.repositories {
+            for (key, value) in entries {
+                repo.git_config
+                    .entry(key.clone())
+                    .or_insert_with(|| value.clone());
+            }
+        }
+    }
+
+    /// Put `args` before every repository's own `clone_args`
+    pub fn apply_default_clone_args(&mut self, args: &[String]) {
+        for repo in &mut self.repositories {
+            repo.clone_args.splice(0..0, args.iter().cloned());
+        }
+    }
+
+    /// Append repositories expanded from `orgs`, keeping explicitly listed ones
+    ///
+    /// Repositories whose name is already configured are skipped. Returns the
+    /// number of repositories added.
+    pub fn merge_repositories(&mut self, repositories: Vec<Repository>) -> usize {
+        let mut added = 0;
+        for repo in repositories {
+            if self.get_repository(&repo.name).is_some() {
+                continue;
+            }
+            self.repositories.push(repo);
+            added += 1;
         }
+        added
+    }
+
+    /// Resolve `--repo` values, each a repository name or alias, to repositories
+    ///
+    /// The result follows config order and lists each repository once.
+    ///
+    /// # Errors
+    /// Returns an error for a value matching no repository, or matching more
+    /// than one (e.g. an alias shared by two repositories)
+    pub fn resolve_repositories(&self, targets: &[String]) -> Result<Vec<Repository>> {
+        let mut selected = vec![false; self.repositories.len()];
+        for target in targets {
+            let matches: Vec<usize> = self
+                .repositories
+                .iter()
+                .enumerate()
+                .filter(|(_, repo)| repo.name == *target || repo.has_alias(target))
+                .map(|(index, _)| index)
+                .collect();
+            match matches.as_slice() {
+                [] => anyhow::bail!("Unknown repository or alias '{}'", target),
+                [index] => selected[*index] = true,
+                _ => {
+                    let names: Vec<&str> = matches
+                        .iter()
+                        .map(|&index| self.repositories[index].name.as_str())
+                        .collect();
+                    anyhow::bail!(
+                        "Ambiguous repository or alias '{}' (matches {})",
+                        target,
+                        names.join(", ")
+                    )
+                }
+            }
+        }
+
+        Ok(self
+            .repositories
+            .iter()
+            .zip(selected)
+            .filter(|(_, selected)| *selected)
+            .map(|(repo, _)| repo.clone())
+            .collect())
+    }
+
+    /// Find a profile by name
+    ///
+    /// # Errors
+    /// Returns an error naming the available profiles if `name` is not defined
+    pub fn profile(&self, name: &str) -> Result<&Profile> {
+        self.profiles.get(name).ok_or_else(|| {
+            let available: Vec<&str> = self.profiles.keys().map(String::as_str).collect();
+            if available.is_empty() {
+                anyhow::anyhow!("Unknown profile '{}': the config defines no profiles", name)
+            } else {
+                anyhow::anyhow!(
+                    "Unknown profile '{}' (available: {})",
+                    name,
+                    available.join(", ")
+                )
+            }
+        })
     }
 
     /// Find a recipe by name
@@ -150,6 +503,20 @@ impl Config {
     ) -> Vec<Repository> {
         filters::filter_repositories(&self.repositories, include_tags, exclude_tags, repos)
     }
+
+    /// A "did you mean" hint for `--tag` values no repository has
+    ///
+    /// Returns `None` when every tag is known or no known tag is close.
+    pub fn tag_hint(&self, include_tags: &[String]) -> Option<String> {
+        let known = self.get_all_tags();
+        let hints: Vec<String> = include_tags
+            .iter()
+            .filter_map(|tag| {
+                tags::did_you_mean(&tags::suggest_tags(tag, known.iter().map(String::as_str)))
+            })
+            .collect();
+        (!hints.is_empty()).then(|| hints.join("; "))
+    }
 }
 
 impl Default for Config {
@@ -158,7 +525,8 @@ impl Default for Config {
     }
 }
 
-/// Save a config to a file with proper YAML formatting and comment preservation
+/// Save a config to a file, in the format its extension implies, with proper
+/// YAML formatting and comment preservation
 ///
 /// This is the centralized function for writing repos.yaml files. It ensures:
 /// - Leading comments are preserved
@@ -166,39 +534,99 @@ impl Default for Config {
 /// - Proper indentation for yamllint compliance
 /// - Trailing newline
 ///
+/// JSON and TOML files are written in their own format instead, see
+/// [`save_config_as`].
+///
 /// Use this function or Config::save() for all config file writes to ensure consistency.
 pub fn save_config<T: Serialize>(config: &T, path: &str) -> Result<()> {
+    save_config_as(config, path, ConfigFormat::from_path(Path::new(path)))
+}
+
+/// Save a serializable config to a file in `format`
+///
+/// YAML is written as described for [`save_config`]. TOML keeps the file's
+/// leading comments too; JSON, which has no comments, is pretty-printed.
+pub fn save_config_as<T: Serialize>(config: &T, path: &str, format: ConfigFormat) -> Result<()> {
+    if path == STDIN_PATH {
+        anyhow::bail!("Cannot save a config read from stdin");
+    }
+
     // Read existing file to preserve leading comments
-    let existing_comments = if Path::new(path).exists() {
+    let existing_comments = if format != ConfigFormat::Json && Path::new(path).exists() {
         extract_leading_comments(path)?
     } else {
         Vec::new()
     };
 
-    // Serialize config to YAML
-    let yaml = serde_yaml::to_string(config)?;
-
-    // Apply minimal indentation fix for yamllint compliance
-    let fixed_yaml = yaml
-        .lines()
-        .map(|line| {
-            if line.starts_with("- ") || (line.starts_with(" ") && !line.starts_with("   ")) {
-                format!("  {}", line)
-            } else {
-                line.to_string()
+    let content = match format {
+        ConfigFormat::Yaml => {
+            let yaml = format.render(config)?;
+
+            // Apply minimal indentation fix for yamllint compliance
+            let fixed_yaml = yaml
+                .lines()
+                .map(|line| {
+                    if line.starts_with("- ") || (line.starts_with(" ") && !line.starts_with("   "))
+                    {
+                        format!("  {}", line)
+                    } else {
+                        line.to_string()
+                    }
+                })
+                .collect::<Vec<_>>()
+                .join("\n");
+
+            // Combine comments, document marker, and content
+            add_document_start_preserving_comments(&existing_comments, &fixed_yaml)
+        }
+        ConfigFormat::Json | ConfigFormat::Toml => {
+            let mut content = existing_comments.join("\n");
+            if !content.is_empty() {
+                content.push('\n');
             }
-        })
-        .collect::<Vec<_>>()
-        .join("\n");
-
-    // Combine comments, document marker, and content
-    let yaml_content = add_document_start_preserving_comments(&existing_comments, &fixed_yaml);
+            content.push_str(format.render(config)?.trim_end());
+            content.push('\n');
+            content
+        }
+    };
 
-    std::fs::write(path, yaml_content)?;
+    std::fs::write(path, content)?;
 
     Ok(())
 }
 
+/// Read a config document from `path`, or from stdin for [`STDIN_PATH`]
+///
+/// Stdin is read once; later reads, such as loading a `--profile` before the
+/// configuration itself, get the same content.
+fn read_source(path: &str) -> Result<String> {
+    static STDIN_CONTENT: OnceLock<String> = OnceLock::new();
+
+    if path != STDIN_PATH {
+        return Ok(std::fs::read_to_string(path)?);
+    }
+    if let Some(content) = STDIN_CONTENT.get() {
+        return Ok(content.clone());
+    }
+    let mut content = String::new();
+    std::io::stdin()
+        .read_to_string(&mut content)
+        .context("Cannot read the config from stdin")?;
+    Ok(STDIN_CONTENT.get_or_init(|| content).clone())
+}
+
+/// Whether a YAML document defines an anchor (`&name`) or uses an alias
+/// (`*name`) where a node starts: at the start of a line, after `key:`, `- `
+/// or an opening bracket or comma of a flow collection
+fn has_yaml_anchors(content: &str) -> bool {
+    let node_property = regex::Regex::new(r"(?m)(?:^|:\s|-\s|[\[{,])\s*[&*][^\s,\[\]{}]+")
+        .expect("valid anchor pattern");
+    content
+        .lines()
+        .filter(|line| !line.trim_start().starts_with('#'))
+        .any(|line| node_property.is_match(line))
+}
+
 /// Extract leading comments from a YAML file
 fn extract_leading_comments(path: &str) -> Result<Vec<String>> {
     let content = std::fs::read_to_string(path)?;
@@ -247,6 +675,7 @@ fn add_document_start_preserving_comments(comments: &[String], yaml: &str) -> St
 #[cfg(test)]
 mod tests {
     use super::*;
+    use serial_test::serial;
 
     fn create_test_config() -> Config {
         let mut repo1 = Repository::new(
@@ -265,7 +694,8 @@ mod tests {
 
         Config {
             repositories: vec![repo1, repo2],
-            recipes: Vec::new(),
+            version: None,
+            ..Default::default()
         }
     }
 
@@ -300,6 +730,19 @@ mod tests {
         assert_eq!(tags, vec!["api", "backend", "frontend", "web"]);
     }
 
+    #[test]
+    fn test_tag_hint_suggests_near_misses() {
+        let config = create_test_config();
+
+        assert_eq!(
+            config.tag_hint(&["backnd".to_string()]).unwrap(),
+            "did you mean 'backend'?"
+        );
+        assert_eq!(config.tag_hint(&["backend".to_string()]), None);
+        assert_eq!(config.tag_hint(&["mobile".to_string()]), None);
+        assert_eq!(config.tag_hint(&[]), None);
+    }
+
     #[test]
     fn test_filter_by_names() {
         let config = create_test_config();
@@ -522,12 +965,62 @@ mod tests {
         assert_eq!(config.repositories.len(), 1);
     }
 
+    #[test]
+    fn test_resolve_repositories_by_name_or_alias() {
+        let mut config = create_test_config();
+        config.repositories[0].aliases = vec!["fe".to_string(), "ui".to_string()];
+        config.repositories[1].aliases = vec!["be".to_string()];
+
+        let targets =
+            |values: &[&str]| -> Vec<String> { values.iter().map(|v| v.to_string()).collect() };
+        let names = |repos: Vec<Repository>| -> Vec<String> {
+            repos.into_iter().map(|repo| repo.name).collect()
+        };
+
+        assert_eq!(
+            names(config.resolve_repositories(&targets(&["be"])).unwrap()),
+            vec!["repo2"]
+        );
+        // Config order, and a repository named twice is listed once
+        assert_eq!(
+            names(
+                config
+                    .resolve_repositories(&targets(&["repo2", "ui", "fe"]))
+                    .unwrap()
+            ),
+            vec!["repo1", "repo2"]
+        );
+
+        let unknown = config.resolve_repositories(&targets(&["fe", "ops"]));
+        assert_eq!(
+            unknown.unwrap_err().to_string(),
+            "Unknown repository or alias 'ops'"
+        );
+    }
+
+    #[test]
+    fn test_resolve_repositories_rejects_ambiguous_values() {
+        let mut config = create_test_config();
+        config.repositories[0].aliases = vec!["shared".to_string()];
+        config.repositories[1].aliases = vec!["shared".to_string(), "repo1".to_string()];
+
+        let shared = config.resolve_repositories(&["shared".to_string()]);
+        assert_eq!(
+            shared.unwrap_err().to_string(),
+            "Ambiguous repository or alias 'shared' (matches repo1, repo2)"
+        );
+
+        // An alias equal to another repository's name is ambiguous too
+        assert!(config.resolve_repositories(&["repo1".to_string()]).is_err());
+    }
+
     #[test]
     fn test_find_recipe() {
         let mut config = Config::new();
         let recipe = Recipe {
             name: "test-recipe".to_string(),
             steps: vec!["echo hello".to_string()],
+            args: Vec::new(),
         };
         config.recipes.push(recipe);
 
@@ -539,6 +1032,157 @@ mod tests {
         assert!(not_found.is_none());
     }
 
+    #[test]
+    fn test_apply_default_ssh_key_keeps_per_repo_keys() {
+        let mut config = create_test_config();
+        config.repositories[1].ssh_key = Some("/keys/repo2".to_string());
+
+        config.apply_default_ssh_key("/keys/default");
+
+        assert_eq!(
+            config.repositories[0].ssh_key.as_deref(),
+            Some("/keys/default")
+        );
+        assert_eq!(
+            config.repositories[1].ssh_key.as_deref(),
+            Some("/keys/repo2")
+        );
+    }
+
+    #[test]
+    fn test_apply_default_git_config_keeps_per_repo_values() {
+        let mut config = create_test_config();
+        config.repositories[1]
+            .git_config
+            .insert("user.email".to_string(), "bot@repo2.example".to_string());
+
+        config.apply_default_git_config(&[
+            ("user.email".to_string(), "ci@example.com".to_string()),
+            ("core.hooksPath".to_string(), ".githooks".to_string()),
+        ]);
+
+        let repo1 = &config.repositories[0].git_config;
+        assert_eq!(repo1["user.email"], "ci@example.com");
+        assert_eq!(repo1["core.hooksPath"], ".githooks");
+        let repo2 = &config.repositories[1].git_config;
+        assert_eq!(repo2["user.email"], "bot@repo2.example");
+        assert_eq!(repo2["core.hooksPath"], ".githooks");
+    }
+
+    #[test]
+    fn test_apply_default_clone_args_come_first() {
+        let mut config = create_test_config();
+        config.repositories[1].clone_args = vec!["--depth=1".to_string()];
+
+        config.apply_default_clone_args(&["--filter=blob:none".to_string()]);
+
+        assert_eq!(
+            config.repositories[0].clone_args,
+            vec!["--filter=blob:none"]
+        );
+        assert_eq!(
+            config.repositories[1].clone_args,
+            vec!["--filter=blob:none", "--depth=1"]
+        );
+    }
+
+    #[test]
+    fn test_load_config_with_profiles() {
+        let temp_dir = tempfile::TempDir::new().unwrap();
+        let config_path = temp_dir.path().join("repos.yaml");
+        std::fs::write(
+            &config_path,
+            r#"
+repositories: []
+profiles:
+  ci:
+    tag: [backend]
+    parallel: true
+    jobs: 4
+    output_dir: ci-logs
+"#,
+        )
+        .unwrap();
+
+        let config = Config::load(config_path.to_str().unwrap()).unwrap();
+        let ci = config.profile("ci").unwrap();
+        assert_eq!(ci.tag, vec!["backend"]);
+        assert_eq!(ci.parallel, Some(true));
+        assert_eq!(ci.jobs, Some(4));
+        assert_eq!(ci.output_dir.as_deref(), Some("ci-logs"));
+
+        let err = config.profile("nightly").unwrap_err();
+        assert!(err.to_string().contains("available: ci"));
+        assert!(Config::new().profile("ci").is_err());
+    }
+
+    #[test]
+    fn test_profile_fills_only_unset_flags() {
+        let profile = Profile {
+            tag: vec!["backend".to_string()],
+            exclude_tag: vec!["legacy".to_string()],
+            parallel: Some(true),
+            jobs: Some(4),
+            output_dir: Some("ci-logs".to_string()),
+        };
+
+        // Nothing given on the command line: every default applies
+        let (mut tag, mut exclude_tag, mut parallel, mut jobs, mut output_dir) =
+            (Vec::new(), Vec::new(), false, None, None);
+        profile.apply(ProfileFlags {
+            tag: &mut tag,
+            exclude_tag: &mut exclude_tag,
+            parallel: Some(&mut parallel),
+            jobs: &mut jobs,
+            output_dir: Some(&mut output_dir),
+        });
+        assert_eq!(tag, vec!["backend"]);
+        assert_eq!(exclude_tag, vec!["legacy"]);
+        assert!(parallel);
+        assert_eq!(jobs, Some(4));
+        assert_eq!(output_dir.as_deref(), Some("ci-logs"));
+
+        // Flags given on the command line win
+        let mut tag = vec!["frontend".to_string()];
+        let mut exclude_tag = Vec::new();
+        let mut jobs = Some(2);
+        let mut output_dir = Some("mine".to_string());
+        profile.apply(ProfileFlags {
+            tag: &mut tag,
+            exclude_tag: &mut exclude_tag,
+            parallel: None,
+            jobs: &mut jobs,
+            output_dir: Some(&mut output_dir),
+        });
+        assert_eq!(tag, vec!["frontend"]);
+        assert_eq!(exclude_tag, vec!["legacy"]);
+        assert_eq!(jobs, Some(2));
+        assert_eq!(output_dir.as_deref(), Some("mine"));
+    }
+
+    #[test]
+    fn test_load_config_with_ssh_key() {
+        let temp_dir = tempfile::TempDir::new().unwrap();
+        let config_path = temp_dir.path().join("repos.yaml");
+        std::fs::write(
+            &config_path,
+            r#"
+repositories:
+  - name: deploy
+    url: git@github.com:owner/deploy.git
+    tags: []
+    ssh_key: ~/.ssh/deploy_key
+"#,
+        )
+        .unwrap();
+
+        let config = Config::load(config_path.to_str().unwrap()).unwrap();
+        assert_eq!(
+            config.repositories[0].ssh_key.as_deref(),
+            Some("~/.ssh/deploy_key")
+        );
+    }
+
     #[test]
     fn test_config_new_default() {
         let config1 = Config::new();
@@ -556,6 +1200,238 @@ mod tests {
         assert!(config.validate().is_ok());
     }
 
+    #[test]
+    fn test_load_gates_on_config_version() {
+        let temp_dir = tempfile::TempDir::new().unwrap();
+        let path = temp_dir.path().join("repos.yaml");
+        let path_str = path.to_string_lossy().to_string();
+
+        // Unversioned and current files load
+        std::fs::write(&path, "repositories: []\n").unwrap();
+        assert_eq!(Config::load(&path_str).unwrap().version, None);
+        std::fs::write(&path, "version: 2\nrepositories: []\n").unwrap();
+        assert_eq!(Config::load(&path_str).unwrap().version, Some(2));
+
+        // A newer file is rejected by version, even if it uses unknown syntax
+        std::fs::write(&path, "version: 3\nrepositories:\n  future: {}\n").unwrap();
+        let err = format!("{:#}", Config::load(&path_str).unwrap_err());
+        assert!(err.contains("supports config versions up to 2"), "{}", err);
+        assert!(err.contains("Upgrade repos"));
+    }
+
+    #[test]
+    fn test_load_equivalent_configs_in_each_format() {
+        let temp_dir = tempfile::TempDir::new().unwrap();
+        let files = [
+            (
+                "repos.yaml",
+                "version: 2\nrepositories:\n  - name: api\n    url: git@github.com:acme/api.git\n    tags: [backend]\n    branch: develop\n    git_config:\n      user.email: bot@acme.dev\nrecipes:\n  - name: setup\n    steps: [make setup]\n",
+            ),
+            (
+                "repos.json",
+                r#"{"version": 2, "repositories": [{"name": "api", "url": "git@github.com:acme/api.git", "tags": ["backend"], "branch": "develop", "git_config": {"user.email": "bot@acme.dev"}}], "recipes": [{"name": "setup", "steps": ["make setup"]}]}"#,
+            ),
+            (
+                "repos.toml",
+                "version = 2\n\n[[repositories]]\nname = \"api\"\nurl = \"git@github.com:acme/api.git\"\ntags = [\"backend\"]\nbranch = \"develop\"\n\n[repositories.git_config]\n\"user.email\" = \"bot@acme.dev\"\n\n[[recipes]]\nname = \"setup\"\nsteps = [\"make setup\"]\n",
+            ),
+        ];
+
+        for (name, content) in files {
+            let path = temp_dir.path().join(name);
+            std::fs::write(&path, content).unwrap();
+            let config = Config::load(&path.to_string_lossy())
+                .unwrap_or_else(|e| panic!("{}: {:#}", name, e));
+
+            assert_eq!(config.version, Some(2), "{}", name);
+            let api = config.get_repository("api").unwrap();
+            assert_eq!(api.url, "git@github.com:acme/api.git", "{}", name);
+            assert_eq!(api.tags, vec!["backend"], "{}", name);
+            assert_eq!(api.branch.as_deref(), Some("develop"), "{}", name);
+            assert_eq!(api.git_config["user.email"], "bot@acme.dev", "{}", name);
+            assert_eq!(
+                config.find_recipe("setup").unwrap().steps,
+                vec!["make setup"]
+            );
+        }
+    }
+
+    #[test]
+    fn test_load_as_overrides_the_extension() {
+        let temp_dir = tempfile::TempDir::new().unwrap();
+        let path = temp_dir.path().join("repos.conf");
+        let path_str = path.to_string_lossy().to_string();
+        std::fs::write(
+            &path,
+            "[[repositories]]\nname = \"api\"\nurl = \"git@github.com:acme/api.git\"\ntags = []\n",
+        )
+        .unwrap();
+
+        // Unknown extensions are read as YAML, which this is not
+        assert!(Config::load(&path_str).is_err());
+
+        let config = Config::load_as(&path_str, ConfigFormat::Toml).unwrap();
+        assert_eq!(config.repositories[0].name, "api");
+
+        // A newer version is still rejected before the fields are read
+        std::fs::write(
+            temp_dir.path().join("new.json"),
+            r#"{"version": 9, "repositories": {}}"#,
+        )
+        .unwrap();
+        let err = format!(
+            "{:#}",
+            Config::load(&temp_dir.path().join("new.json").to_string_lossy()).unwrap_err()
+        );
+        assert!(err.contains("supports config versions up to"), "{}", err);
+    }
+
+    #[test]
+    fn test_load_config_with_anchors_and_merge_keys() {
+        let temp_dir = tempfile::TempDir::new().unwrap();
+        let path = temp_dir.path().join("repos.yaml");
+        std::fs::write(
+            &path,
+            r#"
+x-backend: &backend
+  tags: [backend, java]
+  branch: develop
+  git_config:
+    user.email: bot@example.com
+
+repositories:
+  - name: api
+    url: git@github.com:example/api.git
+    <<: *backend
+  - name: billing
+    url: git@github.com:example/billing.git
+    <<: *backend
+    branch: main
+  - name: web
+    url: git@github.com:example/web.git
+    tags: &frontend [frontend]
+  - name: docs
+    url: git@github.com:example/docs.git
+    tags: *frontend
+"#,
+        )
+        .unwrap();
+
+        let config = Config::load(&path.to_string_lossy()).unwrap();
+        let api = config.get_repository("api").unwrap();
+        assert_eq!(api.tags, vec!["backend", "java"]);
+        assert_eq!(api.branch.as_deref(), Some("develop"));
+        assert_eq!(api.git_config["user.email"], "bot@example.com");
+        // Keys set next to the merge key win over the merged ones
+        let billing = config.get_repository("billing").unwrap();
+        assert_eq!(billing.branch.as_deref(), Some("main"));
+        assert_eq!(billing.tags, vec!["backend", "java"]);
+        assert_eq!(
+            config.get_repository("docs").unwrap().tags,
+            vec!["frontend"]
+        );
+    }
+
+    #[test]
+    fn test_save_keeps_the_file_format() {
+        let temp_dir = tempfile::TempDir::new().unwrap();
+        let mut config = create_test_config();
+        config.version = Some(CURRENT_CONFIG_VERSION);
+
+        for (file, start) in [("repos.json", "{"), ("repos.toml", "version = ")] {
+            let path = temp_dir.path().join(file).to_string_lossy().to_string();
+            config.save(&path).unwrap();
+            assert!(std::fs::read_to_string(&path).unwrap().starts_with(start));
+            let loaded = Config::load(&path).unwrap();
+            assert_eq!(loaded.repositories.len(), config.repositories.len());
+            assert_eq!(loaded.repositories[0].tags, config.repositories[0].tags);
+        }
+
+        // An explicit format wins over the extension
+        let path = temp_dir
+            .path()
+            .join("repos.conf")
+            .to_string_lossy()
+            .to_string();
+        config.save_as(&path, ConfigFormat::Json).unwrap();
+        assert!(Config::load_as(&path, ConfigFormat::Json).is_ok());
+
+        let err = config.save(STDIN_PATH).unwrap_err();
+        assert_eq!(err.to_string(), "Cannot save a config read from stdin");
+    }
+
+    #[test]
+    fn test_load_expansions_finds_references_and_anchors() {
+        let temp_dir = tempfile::TempDir::new().unwrap();
+        let path = temp_dir.path().join("repos.yaml");
+        let path_str = path.to_string_lossy().to_string();
+
+        std::fs::write(
+            &path,
+            "# Shared: &not-an-anchor\nrepositories:\n  - name: api\n    url: git@github.com:o/api.git\n    tags: [a&b, \"*\"]\n",
+        )
+        .unwrap();
+        assert!(
+            Config::load_expansions(&path_str, ConfigFormat::Yaml)
+                .unwrap()
+                .is_empty()
+        );
+
+        std::fs::write(
+            &path,
+            "repositories:\n  - name: api\n    url: git@${GIT_HOST}:o/api.git\n    tags: &shared [backend]\n",
+        )
+        .unwrap();
+        assert_eq!(
+            Config::load_expansions(&path_str, ConfigFormat::Yaml).unwrap(),
+            vec!["${VAR} references", "YAML anchors"]
+        );
+
+        std::fs::write(&path, "x: &base {branch: main}\ny: {<<: *base}\n").unwrap();
+        assert_eq!(
+            Config::load_expansions(&path_str, ConfigFormat::Yaml).unwrap(),
+            vec!["YAML anchors"]
+        );
+    }
+
+    #[test]
+    #[serial]
+    fn test_load_config_interpolates_environment() {
+        let temp_dir = tempfile::TempDir::new().unwrap();
+        let path = temp_dir.path().join("repos.yaml");
+        let path_str = path.to_string_lossy().to_string();
+        std::fs::write(
+            &path,
+            r#"repositories:
+  - name: api
+    url: git@${REPOS_TEST_GIT_HOST}:example/api.git
+    path: ${REPOS_TEST_WORKSPACE:-/tmp/src}/api
+    branch: ${REPOS_TEST_BRANCH:-main}
+    tags: [backend]
+"#,
+        )
+        .unwrap();
+
+        unsafe {
+            std::env::set_var("REPOS_TEST_GIT_HOST", "git.example.com");
+            std::env::set_var("REPOS_TEST_BRANCH", "develop");
+            std::env::remove_var("REPOS_TEST_WORKSPACE");
+        }
+        let config = Config::load(&path_str).unwrap();
+        let api = config.get_repository("api").unwrap();
+        assert_eq!(api.url, "git@git.example.com:example/api.git");
+        assert_eq!(api.path.as_deref(), Some("/tmp/src/api"));
+        assert_eq!(api.branch.as_deref(), Some("develop"));
+
+        unsafe {
+            std::env::remove_var("REPOS_TEST_GIT_HOST");
+            std::env::remove_var("REPOS_TEST_BRANCH");
+        }
+        let err = format!("{:#}", Config::load(&path_str).unwrap_err());
+        assert!(err.contains("Invalid url of repository 'api'"), "{}", err);
+        assert!(err.contains("'REPOS_TEST_GIT_HOST' is not set"), "{}", err);
+    }
+
     #[test]
     fn test_load_config_alias() {
         // Test that load_config is an alias for load
@@ -605,12 +1481,12 @@ mod tests {
 
         // Test include and exclude together
         let filtered = config.filter_repositories(
-            &["backend".to_string(), "api".to_string()], // include backend AND api (only repo2 has both)
-            &["frontend".to_string()],                   // but exclude frontend
+            &["backend".to_string(), "frontend".to_string()], // include backend OR frontend
+            &["frontend".to_string()],                        // but exclude frontend
             None,
         );
         assert_eq!(filtered.len(), 1);
-        assert_eq!(filtered[0].name, "repo2"); // repo2 has backend AND api, not frontend
+        assert_eq!(filtered[0].name, "repo2"); // repo2 has backend, not frontend
     }
 
     #[test]
@@ -0,0 +1,216 @@
+//! Configuration schema versions and upgrades
+//!
+//! A config file may declare a top-level `version`. Files without one are
+//! treated as version 1, the schema used before versioning was introduced.
+//! Files declaring a version newer than [`CURRENT_CONFIG_VERSION`] are
+//! rejected before they are parsed, so that unknown fields produce a clear
+//! "upgrade repos" message instead of a confusing parse error.
+//! `repos config migrate` rewrites older files with the steps in [`MIGRATIONS`].
+
+use anyhow::{Context, Result, bail};
+use serde::Deserialize;
+use serde_yaml::Value;
+
+/// Schema version written by this build
+pub const CURRENT_CONFIG_VERSION: u32 = 2;
+
+/// Oldest schema version this build can still load
+pub const MIN_CONFIG_VERSION: u32 = 1;
+
+/// Version assumed for files that do not declare one
+pub const UNVERSIONED_CONFIG_VERSION: u32 = 1;
+
+/// A single upgrade from `from` to `from + 1`
+pub struct Migration {
+    pub from: u32,
+    pub description: &'static str,
+    apply: fn(&mut Value) -> Result<()>,
+}
+
+/// Every upgrade step, in order
+pub const MIGRATIONS: &[Migration] = &[Migration {
+    from: 1,
+    description: "declare the schema version and spell out provider aliases (gh, bb)",
+    apply: migrate_v1_to_v2,
+}];
+
+#[derive(Deserialize)]
+struct VersionProbe {
+    #[serde(default)]
+    version: Option<u32>,
+}
+
+/// Version declared by a config document, if it can be read
+///
+/// Returns `None` when the document does not parse far enough to tell; the
+/// full parse then reports the actual problem.
+pub fn declared_version(content: &str) -> Option<u32> {
+    serde_yaml::from_str::<VersionProbe>(content)
+        .ok()
+        .map(|probe| probe.version.unwrap_or(UNVERSIONED_CONFIG_VERSION))
+}
+
+/// Version declared by an already parsed config document, if it can be read
+pub fn document_version(document: &Value) -> Option<u32> {
+    serde_yaml::from_value::<VersionProbe>(document.clone())
+        .ok()
+        .map(|probe| probe.version.unwrap_or(UNVERSIONED_CONFIG_VERSION))
+}
+
+/// Ensure this build understands the given schema version
+pub fn check_version(version: u32) -> Result<()> {
+    if version > CURRENT_CONFIG_VERSION {
+        bail!(
+            "Config declares version {}, but this version of repos supports config versions up to {}. Upgrade repos to use this file.",
+            version,
+            CURRENT_CONFIG_VERSION
+        );
+    }
+    if version < MIN_CONFIG_VERSION {
+        bail!(
+            "Unsupported config version {} (supported: {} to {})",
+            version,
+            MIN_CONFIG_VERSION,
+            CURRENT_CONFIG_VERSION
+        );
+    }
+    Ok(())
+}
+
+/// Upgrade a config document to [`CURRENT_CONFIG_VERSION`] in place
+///
+/// Returns the steps that were applied; an empty list means the document was
+/// already current.
+pub fn migrate(document: &mut Value) -> Result<Vec<&'static Migration>> {
+    let mut version = match document.get("version") {
+        None => UNVERSIONED_CONFIG_VERSION,
+        Some(value) => value
+            .as_u64()
+            .and_then(|v| u32::try_from(v).ok())
+            .context("Config `version` must be a positive integer")?,
+    };
+    check_version(version)?;
+
+    let mut applied = Vec::new();
+    while version < CURRENT_CONFIG_VERSION {
+        let migration = MIGRATIONS
+            .iter()
+            .find(|migration| migration.from == version)
+            .with_context(|| format!("No migration from config version {}", version))?;
+        (migration.apply)(document)?;
+        version += 1;
+        set_version(document, version)?;
+        applied.push(migration);
+    }
+    Ok(applied)
+}
+
+/// Set the top-level `version`, keeping it as the first key
+fn set_version(document: &mut Value, version: u32) -> Result<()> {
+    let Value::Mapping(mapping) = document else {
+        bail!("Config must be a YAML mapping");
+    };
+    let mut upgraded = serde_yaml::Mapping::new();
+    upgraded.insert(Value::from("version"), Value::Number(version.into()));
+    for (key, value) in std::mem::take(mapping) {
+        if key.as_str() != Some("version") {
+            upgraded.insert(key, value);
+        }
+    }
+    *mapping = upgraded;
+    Ok(())
+}
+
+fn migrate_v1_to_v2(document: &mut Value) -> Result<()> {
+    let Some(Value::Sequence(repositories)) = document.get_mut("repositories") else {
+        return Ok(());
+    };
+    for repo in repositories {
+        if let Some(provider) = repo.get_mut("provider") {
+            let canonical = match provider.as_str() {
+                Some("gh") => "github",
+                Some("bb") => "bitbucket",
+                _ => continue,
+            };
+            *provider = Value::from(canonical);
+        }
+    }
+    Ok(())
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_declared_version_defaults_to_unversioned() {
+        assert_eq!(declared_version("repositories: []\n"), Some(1));
+        assert_eq!(declared_version("version: 2\nrepositories: []\n"), Some(2));
+        assert_eq!(declared_version("version: latest\n"), None);
+    }
+
+    #[test]
+    fn test_check_version_range() {
+        assert!(check_version(MIN_CONFIG_VERSION).is_ok());
+        assert!(check_version(CURRENT_CONFIG_VERSION).is_ok());
+
+        let newer = check_version(CURRENT_CONFIG_VERSION + 1).unwrap_err();
+        assert!(newer.to_string().contains("Upgrade repos"));
+        let older = check_version(0).unwrap_err();
+        assert!(older.to_string().contains("Unsupported config version 0"));
+    }
+
+    #[test]
+    fn test_migrate_v1_sample() {
+        let mut document: Value = serde_yaml::from_str(
+            r#"
+repositories:
+  - name: api
+    url: git@github.com:acme/api.git
+    tags: [backend]
+    provider: gh
+  - name: web
+    url: git@bitbucket.org:acme/web.git
+    tags: []
+    provider: bb
+recipes: []
+"#,
+        )
+        .unwrap();
+
+        let applied = migrate(&mut document).unwrap();
+        assert_eq!(applied.len(), 1);
+        assert_eq!(applied[0].from, 1);
+
+        let provider = |i: usize| {
+            document
+                .get("repositories")
+                .and_then(|repos| repos.get(i))
+                .and_then(|repo| repo.get("provider"))
+                .and_then(Value::as_str)
+        };
+        assert_eq!(provider(0), Some("github"));
+        assert_eq!(provider(1), Some("bitbucket"));
+        assert_eq!(
+            document.get("version").and_then(Value::as_u64),
+            Some(u64::from(CURRENT_CONFIG_VERSION))
+        );
+        // The version is written first
+        let first_key = document.as_mapping().unwrap().keys().next().unwrap();
+        assert_eq!(first_key.as_str(), Some("version"));
+    }
+
+    #[test]
+    fn test_migrate_current_is_noop() {
+        let mut document: Value = serde_yaml::from_str("version: 2\nrepositories: []\n").unwrap();
+        let before = document.clone();
+        assert!(migrate(&mut document).unwrap().is_empty());
+        assert_eq!(document, before);
+    }
+
+    #[test]
+    fn test_migrate_rejects_newer_version() {
+        let mut document: Value = serde_yaml::from_str("version: 99\n").unwrap();
+        assert!(migrate(&mut document).is_err());
+    }
+}
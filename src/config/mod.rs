@@ -1,9 +1,35 @@
 //! Configuration management module
 
 pub mod builder;
+pub mod discovery;
+pub mod format;
+pub mod host_filter;
+pub mod interpolation;
 pub mod loader;
+pub mod migration;
+pub mod provider;
+pub mod pull_strategy;
+pub mod recipe_args;
+pub mod repo_list;
+pub mod repo_tags;
 pub mod repository;
+pub mod run_policy;
+pub mod selection;
+pub mod tag_predicate;
+pub mod tags;
 
 pub use builder::RepositoryBuilder;
-pub use loader::{Config, Recipe};
-pub use repository::Repository;
+pub use discovery::{discover_config, discover_config_from};
+pub use format::ConfigFormat;
+pub use host_filter::HostFilter;
+pub use loader::{
+    Config, Defaults, OrgSource, Profile, ProfileFlags, Recipe, STDIN_PATH, Visibility,
+};
+pub use provider::Provider;
+pub use pull_strategy::PullStrategy;
+pub use recipe_args::{RecipeArg, RecipeArgs, arg_env, parse_recipe_arg};
+pub use repo_list::RepoList;
+pub use repository::{Repository, resolve_base_dir, resolve_target_dir};
+pub use run_policy::RunPolicy;
+pub use selection::{SelectionFile, SelectionFiles};
+pub use tag_predicate::TagPredicate;
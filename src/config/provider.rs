@@ -0,0 +1,87 @@
+//! Hosting provider selection
+
+pub use repos_github::url_host;
+use serde::{Deserialize, Serialize};
+use std::fmt;
+
+/// Git hosting service used to open pull requests for a repository
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize, Deserialize)]
+#[serde(rename_all = "lowercase")]
+pub enum Provider {
+    #[serde(alias = "gh")]
+    GitHub,
+    #[serde(alias = "bb")]
+    Bitbucket,
+}
+
+impl Provider {
+    /// Detect the provider from the host part of a repository URL
+    ///
+    /// Returns `None` for hosts that are not recognised, so callers can
+    /// decide on a fallback.
+    pub fn from_url(url: &str) -> Option<Self> {
+        let host = url_host(url)?.to_lowercase();
+        if host == "bitbucket.org" || host.ends_with(".bitbucket.org") {
+            Some(Self::Bitbucket)
+        } else if host == "github.com" || host.ends_with(".github.com") {
+            Some(Self::GitHub)
+        } else {
+            None
+        }
+    }
+}
+
+impl fmt::Display for Provider {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        match self {
+            Self::GitHub => write!(f, "github"),
+            Self::Bitbucket => write!(f, "bitbucket"),
+        }
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_from_url_detects_bitbucket() {
+        assert_eq!(
+            Provider::from_url("git@bitbucket.org:workspace/repo.git"),
+            Some(Provider::Bitbucket)
+        );
+        assert_eq!(
+            Provider::from_url("https://user@bitbucket.org/workspace/repo.git"),
+            Some(Provider::Bitbucket)
+        );
+    }
+
+    #[test]
+    fn test_from_url_detects_github() {
+        assert_eq!(
+            Provider::from_url("git@github.com:owner/repo.git"),
+            Some(Provider::GitHub)
+        );
+        assert_eq!(
+            Provider::from_url("https://github.com/owner/repo"),
+            Some(Provider::GitHub)
+        );
+    }
+
+    #[test]
+    fn test_from_url_unknown_host() {
+        assert_eq!(
+            Provider::from_url("https://git.example.com/team/repo"),
+            None
+        );
+        assert_eq!(Provider::from_url(""), None);
+    }
+
+    #[test]
+    fn test_provider_deserializes_lowercase() {
+        let provider: Provider = serde_yaml::from_str("bitbucket").unwrap();
+        assert_eq!(provider, Provider::Bitbucket);
+        let provider: Provider = serde_yaml::from_str("github").unwrap();
+        assert_eq!(provider, Provider::GitHub);
+    }
+}
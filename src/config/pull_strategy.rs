@@ -0,0 +1,63 @@
+//! How `repos pull` integrates upstream changes
+
+use serde::{Deserialize, Serialize};
+use std::fmt;
+
+/// Strategy used by `git pull` for a repository
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize, Deserialize)]
+#[serde(rename_all = "kebab-case")]
+pub enum PullStrategy {
+    /// Only fast-forward (`--ff-only`); a diverged branch fails
+    FfOnly,
+    /// Replay local commits on top of the upstream branch (`--rebase`)
+    Rebase,
+    /// Merge the upstream branch (`--no-rebase`)
+    Merge,
+}
+
+impl PullStrategy {
+    /// The `git pull` option selecting this strategy
+    pub fn git_arg(self) -> &'static str {
+        match self {
+            Self::FfOnly => "--ff-only",
+            Self::Rebase => "--rebase",
+            Self::Merge => "--no-rebase",
+        }
+    }
+}
+
+impl fmt::Display for PullStrategy {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        match self {
+            Self::FfOnly => write!(f, "ff-only"),
+            Self::Rebase => write!(f, "rebase"),
+            Self::Merge => write!(f, "merge"),
+        }
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_pull_strategy_deserializes_kebab_case() {
+        let strategy: PullStrategy = serde_yaml::from_str("ff-only").unwrap();
+        assert_eq!(strategy, PullStrategy::FfOnly);
+        let strategy: PullStrategy = serde_yaml::from_str("rebase").unwrap();
+        assert_eq!(strategy, PullStrategy::Rebase);
+        assert!(serde_yaml::from_str::<PullStrategy>("squash").is_err());
+    }
+
+    #[test]
+    fn test_pull_strategy_display_round_trips() {
+        for strategy in [
+            PullStrategy::FfOnly,
+            PullStrategy::Rebase,
+            PullStrategy::Merge,
+        ] {
+            let parsed: PullStrategy = serde_yaml::from_str(&strategy.to_string()).unwrap();
+            assert_eq!(parsed, strategy);
+        }
+    }
+}
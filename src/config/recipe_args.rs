@@ -0,0 +1,229 @@
+//! Recipe arguments
+//!
+//! A recipe may declare named arguments, given at invocation with
+//! `repos run --recipe deploy --arg version=1.2.3`. Each `{{.version}}` in the
+//! recipe's steps is replaced by the value, which is also exported to the
+//! steps as `ARG_VERSION`. Declared arguments without a `default` are
+//! required. Template actions naming neither a declared nor a given argument
+//! are left untouched, so steps may still contain other `{{ }}` syntax.
+
+use super::Recipe;
+use anyhow::{Context, Result};
+use serde::{Deserialize, Serialize};
+use std::collections::BTreeMap;
+
+/// Values of recipe arguments, keyed by name
+pub type RecipeArgs = BTreeMap<String, String>;
+
+/// An argument declared by a recipe
+#[derive(Debug, Clone, PartialEq, Eq, Serialize, Deserialize)]
+pub struct RecipeArg {
+    pub name: String,
+    /// Value used when the argument is not given; without one it is required
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub default: Option<String>,
+}
+
+/// Parse a `name=value` entry as given to `--arg`
+///
+/// Names start with a letter or underscore and continue with letters, digits,
+/// `_` or `-`; the value may be empty and may itself contain `=`.
+pub fn parse_recipe_arg(entry: &str) -> Result<(String, String)> {
+    let (name, value) = entry
+        .split_once('=')
+        .with_context(|| format!("Invalid recipe argument '{}': expected name=value", entry))?;
+    let name = name.trim();
+    let valid = name
+        .chars()
+        .next()
+        .is_some_and(|first| first.is_ascii_alphabetic() || first == '_')
+        && name
+            .chars()
+            .all(|c| c.is_ascii_alphanumeric() || c == '_' || c == '-');
+    if !valid {
+        anyhow::bail!(
+            "Invalid recipe argument name '{}': use letters, digits, '_' and '-'",
+            name
+        );
+    }
+    Ok((name.to_string(), value.to_string()))
+}
+
+/// Environment variable exporting argument `name`: `ARG_` and the name in
+/// upper case, with `-` turned into `_`
+pub fn arg_env_name(name: &str) -> String {
+    format!("ARG_{}", name.to_ascii_uppercase().replace('-', "_"))
+}
+
+/// `ARG_*` environment variables for `args`, in name order
+pub fn arg_env(args: &RecipeArgs) -> Vec<(String, String)> {
+    args.iter()
+        .map(|(name, value)| (arg_env_name(name), value.clone()))
+        .collect()
+}
+
+impl Recipe {
+    /// Complete `given` with the declared defaults
+    ///
+    /// Arguments the recipe does not declare are passed through.
+    ///
+    /// # Errors
+    /// Returns an error naming every required argument missing from `given`
+    pub fn resolve_args(&self, given: &RecipeArgs) -> Result<RecipeArgs> {
+        let mut args = given.clone();
+        let mut missing = Vec::new();
+        for arg in &self.args {
+            if args.contains_key(&arg.name) {
+                continue;
+            }
+            match &arg.default {
+                Some(default) => {
+                    args.insert(arg.name.clone(), default.clone());
+                }
+                None => missing.push(format!("--arg {}=<value>", arg.name)),
+            }
+        }
+        if !missing.is_empty() {
+            anyhow::bail!("Recipe '{}' requires {}", self.name, missing.join(", "));
+        }
+        Ok(args)
+    }
+
+    /// The steps with each `{{.name}}` of an argument in `args` replaced by its value
+    pub fn render_steps(&self, args: &RecipeArgs) -> Vec<String> {
+        self.steps
+            .iter()
+            .map(|step| render_actions(step, args))
+            .collect()
+    }
+}
+
+/// `text` with each `{{.name}}` action naming a key of `values` replaced by
+/// its value; other actions are left as written
+pub(crate) fn render_actions(text: &str, values: &BTreeMap<String, String>) -> String {
+    let mut rendered = String::with_capacity(text.len());
+    let mut rest = text;
+    while let Some(start) = rest.find("{{") {
+        let Some(end) = rest[start..].find("}}") else {
+            break;
+        };
+        let action = &rest[start + 2..start + end];
+        let value = action
+            .trim()
+            .strip_prefix('.')
+            .and_then(|name| values.get(name));
+        rendered.push_str(&rest[..start]);
+        match value {
+            Some(value) => rendered.push_str(value),
+            None => rendered.push_str(&rest[start..start + end + 2]),
+        }
+        rest = &rest[start + end + 2..];
+    }
+    rendered.push_str(rest);
+    rendered
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn deploy() -> Recipe {
+        Recipe {
+            name: "deploy".to_string(),
+            steps: vec![
+                "echo deploying {{.version}} to {{ .env }}".to_string(),
+                "docker ps --format '{{.Names}}'".to_string(),
+            ],
+            args: vec![
+                RecipeArg {
+                    name: "version".to_string(),
+                    default: None,
+                },
+                RecipeArg {
+                    name: "env".to_string(),
+                    default: Some("staging".to_string()),
+                },
+            ],
+        }
+    }
+
+    fn args(entries: &[(&str, &str)]) -> RecipeArgs {
+        entries
+            .iter()
+            .map(|(name, value)| (name.to_string(), value.to_string()))
+            .collect()
+    }
+
+    #[test]
+    fn test_parse_recipe_arg() {
+        assert_eq!(
+            parse_recipe_arg("version=1.2.3").unwrap(),
+            ("version".to_string(), "1.2.3".to_string())
+        );
+        assert_eq!(
+            parse_recipe_arg("flags=a=b").unwrap(),
+            ("flags".to_string(), "a=b".to_string())
+        );
+        assert_eq!(
+            parse_recipe_arg("empty=").unwrap(),
+            ("empty".to_string(), String::new())
+        );
+        let err = parse_recipe_arg("version").unwrap_err();
+        assert!(err.to_string().contains("expected name=value"));
+        let err = parse_recipe_arg("1st=x").unwrap_err();
+        assert!(err.to_string().contains("Invalid recipe argument name"));
+    }
+
+    #[test]
+    fn test_parameterized_recipe_is_rendered() {
+        let recipe = deploy();
+        let resolved = recipe.resolve_args(&args(&[("version", "1.2.3")])).unwrap();
+        assert_eq!(resolved, args(&[("env", "staging"), ("version", "1.2.3")]));
+        assert_eq!(
+            recipe.render_steps(&resolved),
+            vec![
+                "echo deploying 1.2.3 to staging",
+                // Not an argument, so left as written
+                "docker ps --format '{{.Names}}'",
+            ]
+        );
+
+        let resolved = recipe
+            .resolve_args(&args(&[("version", "2.0"), ("env", "prod")]))
+            .unwrap();
+        assert_eq!(
+            recipe.render_steps(&resolved)[0],
+            "echo deploying 2.0 to prod"
+        );
+    }
+
+    #[test]
+    fn test_missing_required_args_are_an_error() {
+        let err = deploy().resolve_args(&RecipeArgs::new()).unwrap_err();
+        assert_eq!(
+            err.to_string(),
+            "Recipe 'deploy' requires --arg version=<value>"
+        );
+
+        // Undeclared arguments are still substituted
+        let recipe = Recipe {
+            name: "tag".to_string(),
+            steps: vec!["git tag {{.tag}}".to_string()],
+            args: vec![],
+        };
+        let resolved = recipe.resolve_args(&args(&[("tag", "v1")])).unwrap();
+        assert_eq!(recipe.render_steps(&resolved), vec!["git tag v1"]);
+    }
+
+    #[test]
+    fn test_args_are_exported_as_environment() {
+        assert_eq!(arg_env_name("release-channel"), "ARG_RELEASE_CHANNEL");
+        assert_eq!(
+            arg_env(&args(&[("version", "1.2.3"), ("env", "prod")])),
+            vec![
+                ("ARG_ENV".to_string(), "prod".to_string()),
+                ("ARG_VERSION".to_string(), "1.2.3".to_string()),
+            ]
+        );
+    }
+}
@@ -0,0 +1,137 @@
+//! Repository name lists
+//!
+//! A `--repo-file` lists repository names, one per line, generated by another
+//! tool or kept by hand. Surrounding whitespace, blank lines and lines starting
+//! with `#` are ignored. Only the listed repositories are kept, in config
+//! order, and other filters such as `--tag` narrow them further.
+
+use super::Repository;
+use anyhow::{Context, Result};
+use std::collections::BTreeSet;
+use std::path::Path;
+
+/// Repository names read from a `--repo-file`
+#[derive(Debug, Clone, Default, PartialEq, Eq)]
+pub struct RepoList {
+    names: BTreeSet<String>,
+}
+
+impl RepoList {
+    /// Parse newline-delimited names
+    pub fn parse(content: &str) -> Self {
+        let names = content
+            .lines()
+            .map(str::trim)
+            .filter(|line| !line.is_empty() && !line.starts_with('#'))
+            .map(str::to_string)
+            .collect();
+        Self { names }
+    }
+
+    /// Read and parse a repository list file
+    ///
+    /// # Errors
+    /// Returns an error if the file cannot be read
+    pub fn load(path: &Path) -> Result<Self> {
+        let content = std::fs::read_to_string(path)
+            .with_context(|| format!("Failed to read repository list {}", path.display()))?;
+        Ok(Self::parse(&content))
+    }
+
+    /// The listed names, sorted
+    pub fn names(&self) -> impl Iterator<Item = &str> {
+        self.names.iter().map(String::as_str)
+    }
+
+    /// Keep the listed repositories, in config order
+    ///
+    /// # Errors
+    /// Returns an error naming every listed repository that is not in
+    /// `repositories`, unless `ignore_missing` is set
+    pub fn select(
+        &self,
+        repositories: &[Repository],
+        ignore_missing: bool,
+    ) -> Result<Vec<Repository>> {
+        if !ignore_missing {
+            let missing: Vec<&str> = self
+                .names()
+                .filter(|name| !repositories.iter().any(|repo| repo.name == *name))
+                .collect();
+            if !missing.is_empty() {
+                anyhow::bail!(
+                    "Unknown repositories in repository list: {} (pass --ignore-missing to skip them)",
+                    missing.join(", ")
+                );
+            }
+        }
+
+        Ok(repositories
+            .iter()
+            .filter(|repo| self.names.contains(&repo.name))
+            .cloned()
+            .collect())
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use tempfile::TempDir;
+
+    fn fleet() -> Vec<Repository> {
+        ["api", "web", "worker", "docs"]
+            .iter()
+            .map(|name| {
+                Repository::new(
+                    name.to_string(),
+                    format!("git@github.com:acme/{}.git", name),
+                )
+            })
+            .collect()
+    }
+
+    fn names(repos: &[Repository]) -> Vec<&str> {
+        repos.iter().map(|repo| repo.name.as_str()).collect()
+    }
+
+    #[test]
+    fn test_parse_skips_blanks_and_comments() {
+        let list = RepoList::parse("# generated\nworker\n\n  api  \n#docs\napi\n");
+        assert_eq!(list.names().collect::<Vec<_>>(), vec!["api", "worker"]);
+        assert_eq!(RepoList::parse(""), RepoList::default());
+    }
+
+    #[test]
+    fn test_select_keeps_listed_repositories_in_config_order() {
+        let list = RepoList::parse("worker\napi\n");
+        let selected = list.select(&fleet(), false).unwrap();
+        assert_eq!(names(&selected), vec!["api", "worker"]);
+    }
+
+    #[test]
+    fn test_missing_names_are_an_error_unless_ignored() {
+        let list = RepoList::parse("api\nbilling\nlegacy\n");
+        let err = list.select(&fleet(), false).unwrap_err().to_string();
+        assert!(err.contains("billing, legacy"), "{}", err);
+        assert!(err.contains("--ignore-missing"), "{}", err);
+
+        let selected = list.select(&fleet(), true).unwrap();
+        assert_eq!(names(&selected), vec!["api"]);
+    }
+
+    #[test]
+    fn test_load_reads_file() {
+        let temp_dir = TempDir::new().unwrap();
+        let path = temp_dir.path().join("repos.txt");
+        std::fs::write(&path, "web\ndocs\n").unwrap();
+        let list = RepoList::load(&path).unwrap();
+        assert_eq!(
+            names(&list.select(&fleet(), false).unwrap()),
+            vec!["web", "docs"]
+        );
+
+        let err = RepoList::load(&temp_dir.path().join("absent.txt")).unwrap_err();
+        assert!(err.to_string().contains("Failed to read repository list"));
+    }
+}
@@ -0,0 +1,104 @@
+//! Tags declared by a repository itself
+//!
+//! With `--merge-repo-tags`, a `.repos-tags` file at the root of each clone
+//! adds tags to those the config declares, so teams can tag their own
+//! repositories. Tags are separated by newlines or commas; surrounding
+//! whitespace, blank entries and lines starting with `#` are ignored. Tags the
+//! config already declares are not repeated, and repositories that are not
+//! cloned or have no tags file keep their config tags.
+
+use super::Repository;
+use anyhow::{Context, Result};
+use std::path::Path;
+
+/// File at the root of a clone listing its own tags
+pub const REPO_TAGS_FILE: &str = ".repos-tags";
+
+/// The tags listed in a tags file, in file order
+pub fn parse_repo_tags(content: &str) -> Vec<String> {
+    content
+        .lines()
+        .map(str::trim)
+        .filter(|line| !line.starts_with('#'))
+        .flat_map(|line| line.split(','))
+        .map(str::trim)
+        .filter(|tag| !tag.is_empty())
+        .map(str::to_string)
+        .collect()
+}
+
+/// Add the tags from the repository's tags file, if it has one
+///
+/// # Errors
+/// Returns an error if the tags file exists but cannot be read
+pub fn merge_repo_tags(repo: &mut Repository) -> Result<()> {
+    let path = Path::new(&repo.get_target_dir()).join(REPO_TAGS_FILE);
+    if !path.is_file() {
+        return Ok(());
+    }
+    let content = std::fs::read_to_string(&path)
+        .with_context(|| format!("Failed to read {}", path.display()))?;
+    for tag in parse_repo_tags(&content) {
+        repo.add_tag(tag);
+    }
+    Ok(())
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use std::path::PathBuf;
+    use tempfile::TempDir;
+
+    fn cloned(root: &Path, name: &str, tags_file: Option<&str>) -> Repository {
+        let mut repo = Repository::new(
+            name.to_string(),
+            format!("git@github.com:acme/{}.git", name),
+        );
+        repo.tags = vec!["backend".to_string()];
+        repo.set_config_dir(Some(PathBuf::from(root)));
+        std::fs::create_dir_all(root.join(name)).unwrap();
+        if let Some(content) = tags_file {
+            std::fs::write(root.join(name).join(REPO_TAGS_FILE), content).unwrap();
+        }
+        repo
+    }
+
+    #[test]
+    fn test_parse_newline_and_comma_separated_tags() {
+        assert_eq!(
+            parse_repo_tags("# owned by payments\nteam-payments, java\n\n  critical  \n,pci,\n"),
+            vec!["team-payments", "java", "critical", "pci"]
+        );
+        assert!(parse_repo_tags("").is_empty());
+    }
+
+    #[test]
+    fn test_tags_file_merges_with_config_tags() {
+        let temp_dir = TempDir::new().unwrap();
+        let mut repo = cloned(
+            temp_dir.path(),
+            "api",
+            Some("backend\nteam-payments,java\n"),
+        );
+
+        merge_repo_tags(&mut repo).unwrap();
+        assert_eq!(repo.tags, vec!["backend", "team-payments", "java"]);
+    }
+
+    #[test]
+    fn test_repository_without_tags_file_keeps_config_tags() {
+        let temp_dir = TempDir::new().unwrap();
+        let mut plain = cloned(temp_dir.path(), "web", None);
+        merge_repo_tags(&mut plain).unwrap();
+        assert_eq!(plain.tags, vec!["backend"]);
+
+        let mut missing = Repository::new(
+            "missing".to_string(),
+            "git@github.com:acme/missing.git".to_string(),
+        );
+        missing.set_config_dir(Some(temp_dir.path().to_path_buf()));
+        merge_repo_tags(&mut missing).unwrap();
+        assert!(missing.tags.is_empty());
+    }
+}
@@ -1,32 +1,128 @@
 //! Repository configuration and utilities
 
+use super::{Provider, PullStrategy};
 use anyhow::Result;
 use serde::{Deserialize, Serialize};
+use std::collections::BTreeMap;
 use std::path::{Path, PathBuf};
+use std::time::Duration;
 
-#[derive(Debug, Clone, Serialize, Deserialize)]
+#[derive(Debug, Clone, Default, Serialize, Deserialize)]
 pub struct Repository {
     pub name: String,
     pub url: String,
     pub tags: Vec<String>,
+    /// Short names accepted by `--repo` in place of `name` (`alias: x` or a list)
+    #[serde(
+        default,
+        alias = "alias",
+        deserialize_with = "one_or_many",
+        skip_serializing_if = "Vec::is_empty"
+    )]
+    pub aliases: Vec<String>,
     #[serde(skip_serializing_if = "Option::is_none")]
     pub path: Option<String>,
     #[serde(skip_serializing_if = "Option::is_none")]
     pub branch: Option<String>,
+    /// Private key used for SSH git operations on this repository
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub ssh_key: Option<String>,
+    /// Hosting provider used for pull requests; detected from the URL when unset
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub provider: Option<Provider>,
+    /// Environment variable holding the API token for this repository's pull requests,
+    /// used instead of `--token` / `GITHUB_TOKEN`
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub token_env: Option<String>,
+    /// GitHub API base URL for this repository's pull requests (GitHub Enterprise),
+    /// used instead of `--github-api-url` and `defaults.github_api_url`
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub github_api_url: Option<String>,
+    /// Repository-specific commands keyed by logical name (used by `run --named`)
+    #[serde(default, skip_serializing_if = "BTreeMap::is_empty")]
+    pub commands: BTreeMap<String, String>,
+    /// Time limit for `run` in this repository (e.g. `90s`, `10m`), overriding `--timeout`
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub timeout: Option<String>,
+    /// Kept for reference only: `clone`, `run` and `pr` skip it unless `--include-archived`
+    #[serde(default, skip_serializing_if = "std::ops::Not::not")]
+    pub archived: bool,
+    /// `git config` entries applied in the clone after cloning (e.g. `user.email`)
+    #[serde(default, skip_serializing_if = "BTreeMap::is_empty")]
+    pub git_config: BTreeMap<String, String>,
+    /// Extra `git clone` options for this repository (e.g. `--filter=blob:none`)
+    #[serde(default, skip_serializing_if = "Vec::is_empty")]
+    pub clone_args: Vec<String>,
+    /// How `pull` updates this clone, overriding `--rebase` and git's default
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub pull_strategy: Option<PullStrategy>,
+    /// Clone as a bare mirror (`git clone --mirror`), updated with `git remote update`
+    #[serde(default, skip_serializing_if = "std::ops::Not::not")]
+    pub mirror: bool,
+    /// Shell command run in the clone after `run` succeeds here, overriding `--on-success`
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub on_success: Option<String>,
+    /// Shell command run in the clone after `run` fails here, overriding `--on-failure`
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub on_failure: Option<String>,
+    /// Health checkers skipped for this repository (e.g. `[gitignore, signing]`)
+    #[serde(default, skip_serializing_if = "Vec::is_empty")]
+    pub health_ignore: Vec<String>,
+    /// Directory relative paths resolve against: the clone root, else the config file's directory
     #[serde(skip)]
     pub config_dir: Option<PathBuf>,
 }
 
+/// Where a repository is cloned: `path`, or else `name`, under `base`
+///
+/// Absolute paths are used as-is. Relative ones, including nested layouts such
+/// as `org/name`, resolve against `base` (the clone root or config directory),
+/// falling back to the current directory. Every command that locates a clone
+/// goes through here.
+pub fn resolve_target_dir(base: Option<&Path>, path: Option<&str>, name: &str) -> PathBuf {
+    resolve_against(base, Path::new(path.unwrap_or(name)))
+}
+
+/// Directory relative repository paths resolve against
+///
+/// That is `root` when set (`defaults.root` or `--dir`), else `config_dir`. A
+/// leading `~` in `root` is the home directory, and a relative `root` resolves
+/// against `config_dir`, or the current directory without one.
+pub fn resolve_base_dir(root: Option<&str>, config_dir: Option<&Path>) -> Option<PathBuf> {
+    let Some(root) = root else {
+        return config_dir.map(Path::to_path_buf);
+    };
+    let root = match root.strip_prefix('~') {
+        Some(rest) if rest.is_empty() || rest.starts_with('/') => match std::env::var_os("HOME") {
+            Some(home) if rest.is_empty() => PathBuf::from(home),
+            Some(home) => PathBuf::from(home).join(&rest[1..]),
+            None => PathBuf::from(root),
+        },
+        _ => PathBuf::from(root),
+    };
+    Some(resolve_against(config_dir, &root))
+}
+
+/// `path` if absolute, else `path` under `base` or the current directory
+fn resolve_against(base: Option<&Path>, path: &Path) -> PathBuf {
+    if path.is_absolute() {
+        return path.to_path_buf();
+    }
+    match base {
+        Some(base) => base.join(path),
+        None => std::env::current_dir()
+            .unwrap_or_else(|_| PathBuf::from("."))
+            .join(path),
+    }
+}
+
 impl Repository {
     /// Create a new repository configuration
     pub fn new(name: String, url: String) -> Self {
         Self {
             name,
             url,
-            tags: Vec::new(),
-            path: None,
-            branch: None,
-            config_dir: None,
+            ..Self::default()
         }
     }
 
@@ -40,6 +136,29 @@ impl Repository {
         tags.iter().any(|tag| self.has_tag(tag))
     }
 
+    /// Hosting provider for this repository
+    ///
+    /// An explicit `provider` setting wins; otherwise the provider is detected
+    /// from the URL host, defaulting to GitHub.
+    pub fn provider(&self) -> Provider {
+        self.provider
+            .or_else(|| Provider::from_url(&self.url))
+            .unwrap_or(Provider::GitHub)
+    }
+
+    /// Command configured for this repository under a logical name
+    pub fn named_command(&self, name: &str) -> Option<&str> {
+        self.commands.get(name).map(String::as_str)
+    }
+
+    /// Parsed `timeout` setting, if any
+    pub fn timeout(&self) -> Result<Option<Duration>> {
+        self.timeout
+            .as_deref()
+            .map(crate::utils::parse_duration)
+            .transpose()
+    }
+
     /// Check if the repository URL has a valid format
     pub fn is_url_valid(&self) -> bool {
         self.url.starts_with("git@")
@@ -55,42 +174,12 @@ impl Repository {
 
     /// Get the target directory for cloning
     pub fn get_target_dir(&self) -> String {
-        match &self.path {
-            Some(path) => {
-                let path_buf = PathBuf::from(path);
-                if path_buf.is_absolute() {
-                    // If it's already an absolute path, use it as-is
-                    path.clone()
-                } else {
-                    // If it's a relative path, resolve it relative to the config file directory
-                    if let Some(config_dir) = &self.config_dir {
-                        config_dir.join(path).to_string_lossy().to_string()
-                    } else {
-                        // Fallback to current directory if config_dir is not available
-                        std::env::current_dir()
-                            .unwrap_or_else(|_| PathBuf::from("."))
-                            .join(path)
-                            .to_string_lossy()
-                            .to_string()
-                    }
-                }
-            }
-            None => {
-                // Default to repository name as relative path
-                if let Some(config_dir) = &self.config_dir {
-                    config_dir.join(&self.name).to_string_lossy().to_string()
-                } else {
-                    std::env::current_dir()
-                        .unwrap_or_else(|_| PathBuf::from("."))
-                        .join(&self.name)
-                        .to_string_lossy()
-                        .to_string()
-                }
-            }
-        }
+        resolve_target_dir(self.config_dir.as_deref(), self.path.as_deref(), &self.name)
+            .to_string_lossy()
+            .to_string()
     }
 
-    /// Set the configuration directory (used by config loader)
+    /// Set the directory relative paths resolve against (used by config loader)
     pub fn set_config_dir(&mut self, config_dir: Option<PathBuf>) {
         self.config_dir = config_dir;
     }
@@ -111,6 +200,29 @@ impl Repository {
     pub fn exists(&self) -> bool {
         Path::new(&self.get_target_dir()).exists()
     }
+
+    /// Whether `alias` is one of this repository's aliases
+    pub fn has_alias(&self, alias: &str) -> bool {
+        self.aliases.iter().any(|a| a == alias)
+    }
+}
+
+/// Accept either a single string or a list of strings
+fn one_or_many<'de, D>(deserializer: D) -> std::result::Result<Vec<String>, D::Error>
+where
+    D: serde::Deserializer<'de>,
+{
+    #[derive(Deserialize)]
+    #[serde(untagged)]
+    enum OneOrMany {
+        One(String),
+        Many(Vec<String>),
+    }
+
+    Ok(match OneOrMany::deserialize(deserializer)? {
+        OneOrMany::One(value) => vec![value],
+        OneOrMany::Many(values) => values,
+    })
 }
 
 #[cfg(test)]
@@ -123,10 +235,9 @@ mod tests {
         let mut repo = Repository {
             name: "test-repo".to_string(),
             url: "git@github.com:owner/repo.git".to_string(),
-            tags: vec![],
             path: Some("journey".to_string()),
-            branch: None,
             config_dir: Some(PathBuf::from("/some/config/dir")),
+            ..Default::default()
         };
 
         let target_dir = repo.get_target_dir();
@@ -143,6 +254,56 @@ mod tests {
         assert_eq!(target_dir, "/some/config/dir/test-repo");
     }
 
+    #[test]
+    fn test_root_with_name() {
+        let base = resolve_base_dir(Some("/work"), Some(Path::new("/config/dir")));
+        assert_eq!(base, Some(PathBuf::from("/work")));
+        assert_eq!(
+            resolve_target_dir(base.as_deref(), None, "api"),
+            PathBuf::from("/work/api")
+        );
+    }
+
+    #[test]
+    fn test_root_with_path() {
+        let base = resolve_base_dir(Some("/work"), Some(Path::new("/config/dir")));
+        assert_eq!(
+            resolve_target_dir(base.as_deref(), Some("services/api"), "api"),
+            PathBuf::from("/work/services/api")
+        );
+        // An absolute path ignores the root
+        assert_eq!(
+            resolve_target_dir(base.as_deref(), Some("/srv/api"), "api"),
+            PathBuf::from("/srv/api")
+        );
+    }
+
+    #[test]
+    fn test_root_with_nested_layout() {
+        // A relative root sits next to the config file; `org/name` layouts nest under it
+        let base = resolve_base_dir(Some("clones"), Some(Path::new("/config/dir")));
+        assert_eq!(base, Some(PathBuf::from("/config/dir/clones")));
+        assert_eq!(
+            resolve_target_dir(base.as_deref(), Some("acme/api"), "api"),
+            PathBuf::from("/config/dir/clones/acme/api")
+        );
+    }
+
+    #[test]
+    fn test_root_expands_home_and_defaults_to_config_dir() {
+        if let Some(home) = env::var_os("HOME") {
+            assert_eq!(
+                resolve_base_dir(Some("~/work"), None),
+                Some(PathBuf::from(home).join("work"))
+            );
+        }
+        assert_eq!(
+            resolve_base_dir(None, Some(Path::new("/config/dir"))),
+            Some(PathBuf::from("/config/dir"))
+        );
+        assert_eq!(resolve_base_dir(None, None), None);
+    }
+
     #[test]
     fn test_no_config_dir_fallback() {
         let current_dir = env::current_dir().unwrap();
@@ -150,10 +311,8 @@ mod tests {
         let repo = Repository {
             name: "test-repo".to_string(),
             url: "git@github.com:owner/repo.git".to_string(),
-            tags: vec![],
             path: Some("journey".to_string()),
-            branch: None,
-            config_dir: None,
+            ..Default::default()
         };
 
         let target_dir = repo.get_target_dir();
@@ -253,4 +412,52 @@ mod tests {
         let invalid_url = Repository::new("test".to_string(), "invalid-url".to_string());
         assert!(invalid_url.validate().is_err());
     }
+
+    #[test]
+    fn test_provider_detection_and_override() {
+        let github = Repository::new(
+            "gh".to_string(),
+            "git@github.com:owner/repo.git".to_string(),
+        );
+        assert_eq!(github.provider(), Provider::GitHub);
+
+        let mut bitbucket = Repository::new(
+            "bb".to_string(),
+            "git@bitbucket.org:workspace/repo.git".to_string(),
+        );
+        assert_eq!(bitbucket.provider(), Provider::Bitbucket);
+
+        // Unknown hosts fall back to GitHub unless configured explicitly
+        let mut self_hosted = Repository::new(
+            "internal".to_string(),
+            "https://git.example.com/team/repo.git".to_string(),
+        );
+        assert_eq!(self_hosted.provider(), Provider::GitHub);
+        self_hosted.provider = Some(Provider::Bitbucket);
+        assert_eq!(self_hosted.provider(), Provider::Bitbucket);
+
+        bitbucket.provider = Some(Provider::GitHub);
+        assert_eq!(bitbucket.provider(), Provider::GitHub);
+    }
+
+    #[test]
+    fn test_aliases_accept_one_or_many() {
+        let single: Repository = serde_yaml::from_str(
+            "name: api-gateway\nurl: git@github.com:o/api-gateway.git\ntags: []\nalias: gw\n",
+        )
+        .unwrap();
+        assert_eq!(single.aliases, vec!["gw"]);
+        assert!(single.has_alias("gw"));
+
+        let list: Repository = serde_yaml::from_str(
+            "name: api-gateway\nurl: git@github.com:o/api-gateway.git\ntags: []\naliases: [gw, gateway]\n",
+        )
+        .unwrap();
+        assert_eq!(list.aliases, vec!["gw", "gateway"]);
+
+        let none: Repository =
+            serde_yaml::from_str("name: web\nurl: git@github.com:o/web.git\ntags: []\n").unwrap();
+        assert!(none.aliases.is_empty());
+        assert!(!serde_yaml::to_string(&none).unwrap().contains("aliases"));
+    }
 }
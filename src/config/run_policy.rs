@@ -0,0 +1,132 @@
+//! Guard rails for the commands `repos run` executes
+//!
+//! The config's `run_policy` section lists regular expressions matched against
+//! each command line before anything runs: a command matching a `deny`
+//! pattern is refused, and when `allow` patterns are given a command must
+//! match one of them. Recipe steps are checked one by one.
+
+use anyhow::{Context, Result, bail};
+use regex::Regex;
+use serde::{Deserialize, Serialize};
+
+/// Allowed and denied command patterns
+#[derive(Debug, Clone, Default, PartialEq, Eq, Serialize, Deserialize)]
+pub struct RunPolicy {
+    /// Commands must match one of these when any are given
+    #[serde(default, skip_serializing_if = "Vec::is_empty")]
+    pub allow: Vec<String>,
+    /// Commands matching any of these are refused, even if allowed
+    #[serde(default, skip_serializing_if = "Vec::is_empty")]
+    pub deny: Vec<String>,
+}
+
+impl RunPolicy {
+    pub fn is_empty(&self) -> bool {
+        self.allow.is_empty() && self.deny.is_empty()
+    }
+
+    /// Compile every pattern, so a typo is reported when the config loads
+    ///
+    /// # Errors
+    /// Returns an error naming the first pattern that is not a valid regex
+    pub fn validate(&self) -> Result<()> {
+        compile(&self.allow)?;
+        compile(&self.deny)?;
+        Ok(())
+    }
+
+    /// Refuse `command` if the policy does not permit it
+    ///
+    /// # Errors
+    /// Returns an error naming the deny pattern the command matched, or
+    /// saying that no allow pattern matched
+    pub fn check(&self, command: &str) -> Result<()> {
+        if let Some(pattern) = compile(&self.deny)?
+            .into_iter()
+            .find(|pattern| pattern.is_match(command))
+        {
+            bail!(
+                "Command '{}' is denied by run_policy pattern '{}'",
+                command,
+                pattern.as_str()
+            );
+        }
+        let allow = compile(&self.allow)?;
+        if !allow.is_empty() && !allow.iter().any(|pattern| pattern.is_match(command)) {
+            bail!(
+                "Command '{}' is not allowed by run_policy (it matches no allow pattern)",
+                command
+            );
+        }
+        Ok(())
+    }
+}
+
+fn compile(patterns: &[String]) -> Result<Vec<Regex>> {
+    patterns
+        .iter()
+        .map(|pattern| {
+            Regex::new(pattern).with_context(|| format!("Invalid run_policy pattern '{}'", pattern))
+        })
+        .collect()
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn policy(allow: &[&str], deny: &[&str]) -> RunPolicy {
+        RunPolicy {
+            allow: allow.iter().map(|p| p.to_string()).collect(),
+            deny: deny.iter().map(|p| p.to_string()).collect(),
+        }
+    }
+
+    #[test]
+    fn test_empty_policy_allows_everything() {
+        let policy = RunPolicy::default();
+        assert!(policy.is_empty());
+        assert!(policy.check("rm -rf build").is_ok());
+    }
+
+    #[test]
+    fn test_denied_command_is_refused() {
+        let policy = policy(&[], &[r"\brm\s+-[a-z]*r[a-z]*f", r"git\s+push\s+.*--force"]);
+        assert!(policy.check("git status").is_ok());
+        assert!(policy.check("rm build.log").is_ok());
+        assert_eq!(
+            policy.check("rm -rf /").unwrap_err().to_string(),
+            r"Command 'rm -rf /' is denied by run_policy pattern '\brm\s+-[a-z]*r[a-z]*f'"
+        );
+        assert!(policy.check("git push origin main --force").is_err());
+    }
+
+    #[test]
+    fn test_allow_list_restricts_commands() {
+        let policy = policy(&["^git ", "^make( |$)"], &["^git push"]);
+        assert!(policy.check("git status").is_ok());
+        assert!(policy.check("make").is_ok());
+        assert_eq!(
+            policy.check("curl example.com").unwrap_err().to_string(),
+            "Command 'curl example.com' is not allowed by run_policy (it matches no allow pattern)"
+        );
+        // Deny patterns win over allow patterns
+        assert!(policy.check("git push origin main").is_err());
+    }
+
+    #[test]
+    fn test_invalid_pattern_is_reported() {
+        let policy = policy(&["("], &[]);
+        assert_eq!(
+            policy.validate().unwrap_err().to_string(),
+            "Invalid run_policy pattern '('"
+        );
+        assert!(policy.check("ls").is_err());
+    }
+
+    #[test]
+    fn test_policy_deserializes_from_yaml() {
+        let parsed: RunPolicy = serde_yaml::from_str("deny: ['rm -rf']\n").unwrap();
+        assert_eq!(parsed, policy(&[], &["rm -rf"]));
+    }
+}
@@ -0,0 +1,173 @@
+//! Project-local repository selection files
+//!
+//! A `.reposinclude` or `.reposexclude` file next to the config narrows the
+//! repositories every command works on, like a persistent `--tag` filter. Each
+//! line is a glob matched against a repository's name and each of its tags;
+//! blank lines and lines starting with `#` are ignored. As in `.gitignore`, a
+//! line starting with `!` negates the pattern and the last matching line
+//! decides, so `sandbox-*` followed by `!sandbox-shared` matches every sandbox
+//! but one.
+//!
+//! With an include file only matching repositories are kept; an exclude file
+//! then drops the repositories it matches.
+
+use super::Repository;
+use anyhow::{Context, Result};
+use glob::Pattern;
+use std::path::Path;
+
+/// File of patterns selecting the repositories to work on
+pub const INCLUDE_FILE: &str = ".reposinclude";
+/// File of patterns selecting repositories to leave out
+pub const EXCLUDE_FILE: &str = ".reposexclude";
+
+/// The patterns of one selection file, in file order
+#[derive(Debug, Clone, Default)]
+pub struct SelectionFile {
+    /// Each pattern with whether it was negated with `!`
+    patterns: Vec<(Pattern, bool)>,
+}
+
+impl SelectionFile {
+    /// Parse the lines of a selection file
+    ///
+    /// # Errors
+    /// Returns an error with the line number of the first invalid glob
+    pub fn parse(content: &str) -> Result<Self> {
+        let patterns = content
+            .lines()
+            .enumerate()
+            .map(|(index, line)| (index, line.trim()))
+            .filter(|(_, line)| !line.is_empty() && !line.starts_with('#'))
+            .map(|(index, line)| {
+                let (negated, glob) = match line.strip_prefix('!') {
+                    Some(glob) => (true, glob.trim_start()),
+                    None => (false, line),
+                };
+                Pattern::new(glob)
+                    .map(|pattern| (pattern, negated))
+                    .with_context(|| format!("Invalid pattern '{}' on line {}", glob, index + 1))
+            })
+            .collect::<Result<_>>()?;
+        Ok(Self { patterns })
+    }
+
+    /// Read a selection file, or `None` when there is none at `path`
+    pub fn load(path: &Path) -> Result<Option<Self>> {
+        if !path.exists() {
+            return Ok(None);
+        }
+        let content = std::fs::read_to_string(path)
+            .with_context(|| format!("Failed to read {}", path.display()))?;
+        Self::parse(&content)
+            .map(Some)
+            .with_context(|| format!("Cannot load {}", path.display()))
+    }
+
+    /// Whether the last pattern matching the repository's name or a tag is not negated
+    pub fn matches(&self, repo: &Repository) -> bool {
+        self.patterns
+            .iter()
+            .rev()
+            .find(|(pattern, _)| {
+                pattern.matches(&repo.name) || repo.tags.iter().any(|tag| pattern.matches(tag))
+            })
+            .is_some_and(|(_, negated)| !negated)
+    }
+}
+
+/// The include and exclude files found in a directory
+#[derive(Debug, Clone, Default)]
+pub struct SelectionFiles {
+    pub include: Option<SelectionFile>,
+    pub exclude: Option<SelectionFile>,
+}
+
+impl SelectionFiles {
+    /// Read `.reposinclude` and `.reposexclude` from `dir`, if present
+    pub fn load(dir: &Path) -> Result<Self> {
+        Ok(Self {
+            include: SelectionFile::load(&dir.join(INCLUDE_FILE))?,
+            exclude: SelectionFile::load(&dir.join(EXCLUDE_FILE))?,
+        })
+    }
+
+    pub fn is_empty(&self) -> bool {
+        self.include.is_none() && self.exclude.is_none()
+    }
+
+    /// Whether `repo` is in the working set the files describe
+    pub fn selects(&self, repo: &Repository) -> bool {
+        self.include.as_ref().is_none_or(|file| file.matches(repo))
+            && !self.exclude.as_ref().is_some_and(|file| file.matches(repo))
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::config::Config;
+    use std::path::PathBuf;
+
+    fn fixture_dir() -> PathBuf {
+        Path::new(env!("CARGO_MANIFEST_DIR")).join("tests/fixtures/selection")
+    }
+
+    fn repo(name: &str, tags: &[&str]) -> Repository {
+        let mut repo = Repository::new(name.to_string(), format!("git@github.com:acme/{}", name));
+        repo.tags = tags.iter().map(|tag| tag.to_string()).collect();
+        repo
+    }
+
+    #[test]
+    fn test_fixture_files_select_working_set() {
+        let config =
+            Config::load_config(fixture_dir().join("repos.yaml").to_str().unwrap()).unwrap();
+        let selection = SelectionFiles::load(&fixture_dir()).unwrap();
+        assert!(!selection.is_empty());
+
+        let selected: Vec<&str> = config
+            .repositories
+            .iter()
+            .filter(|repo| selection.selects(repo))
+            .map(|repo| repo.name.as_str())
+            .collect();
+        assert_eq!(selected, vec!["api", "web", "sandbox-shared"]);
+    }
+
+    #[test]
+    fn test_patterns_match_names_and_tags() {
+        let file = SelectionFile::parse("# comment\n\n  api-*  \nteam-?\n").unwrap();
+        assert!(file.matches(&repo("api-gateway", &[])));
+        assert!(file.matches(&repo("billing", &["team-a"])));
+        assert!(!file.matches(&repo("billing", &["team-ab"])));
+        assert!(!file.matches(&repo("web", &["api"])));
+    }
+
+    #[test]
+    fn test_last_matching_pattern_wins() {
+        let file = SelectionFile::parse("*\n!frontend\nweb\n").unwrap();
+        assert!(file.matches(&repo("api", &["backend"])));
+        assert!(!file.matches(&repo("admin", &["frontend"])));
+        assert!(file.matches(&repo("web", &["frontend"])));
+    }
+
+    #[test]
+    fn test_missing_files_select_everything() {
+        let temp_dir = tempfile::TempDir::new().unwrap();
+        let selection = SelectionFiles::load(temp_dir.path()).unwrap();
+        assert!(selection.is_empty());
+        assert!(selection.selects(&repo("api", &[])));
+
+        std::fs::write(temp_dir.path().join(EXCLUDE_FILE), "api\n").unwrap();
+        let selection = SelectionFiles::load(temp_dir.path()).unwrap();
+        assert!(!selection.selects(&repo("api", &[])));
+        assert!(selection.selects(&repo("web", &[])));
+    }
+
+    #[test]
+    fn test_invalid_pattern_reports_line() {
+        let error = SelectionFile::parse("api\n# ok\n[web\n").unwrap_err();
+        assert_eq!(error.to_string(), "Invalid pattern '[web' on line 3");
+    }
+}
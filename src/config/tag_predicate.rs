@@ -0,0 +1,116 @@
+//! Boolean tag selection
+//!
+//! A repository is selected when it has at least one of the `any` tags
+//! (`--tag`, `--tags-any`), every one of the `all` tags (`--tags-all`) and
+//! none of the `none` tags (`--exclude-tag`, `--tags-none`). An empty list
+//! places no condition, and the three conditions must all hold.
+
+use super::Repository;
+
+/// Which tags a repository must, or must not, carry to be selected
+#[derive(Debug, Clone, Default, PartialEq, Eq)]
+pub struct TagPredicate {
+    /// At least one of these (OR)
+    pub any: Vec<String>,
+    /// Every one of these (AND)
+    pub all: Vec<String>,
+    /// None of these (exclusion)
+    pub none: Vec<String>,
+}
+
+impl TagPredicate {
+    pub fn new(any: &[String], all: &[String], none: &[String]) -> Self {
+        Self {
+            any: any.to_vec(),
+            all: all.to_vec(),
+            none: none.to_vec(),
+        }
+    }
+
+    /// Whether the predicate selects every repository
+    pub fn is_empty(&self) -> bool {
+        self.any.is_empty() && self.all.is_empty() && self.none.is_empty()
+    }
+
+    /// Whether `repo` satisfies every condition
+    pub fn matches(&self, repo: &Repository) -> bool {
+        (self.any.is_empty() || repo.has_any_tag(&self.any))
+            && self.all.iter().all(|tag| repo.has_tag(tag))
+            && !self.none.iter().any(|tag| repo.has_tag(tag))
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn tags(values: &[&str]) -> Vec<String> {
+        values.iter().map(|tag| tag.to_string()).collect()
+    }
+
+    fn tagged(name: &str, values: &[&str]) -> Repository {
+        let mut repo = Repository::new(
+            name.to_string(),
+            format!("git@github.com:acme/{}.git", name),
+        );
+        repo.tags = tags(values);
+        repo
+    }
+
+    fn selected(predicate: &TagPredicate, repos: &[Repository]) -> Vec<String> {
+        repos
+            .iter()
+            .filter(|repo| predicate.matches(repo))
+            .map(|repo| repo.name.clone())
+            .collect()
+    }
+
+    fn fleet() -> Vec<Repository> {
+        vec![
+            tagged("api", &["backend", "rust", "team-a"]),
+            tagged("worker", &["backend", "go", "team-a"]),
+            tagged("web", &["frontend", "team-b"]),
+            tagged("legacy", &["backend", "rust", "deprecated"]),
+            tagged("docs", &[]),
+        ]
+    }
+
+    #[test]
+    fn test_empty_predicate_selects_everything() {
+        let predicate = TagPredicate::default();
+        assert!(predicate.is_empty());
+        assert_eq!(selected(&predicate, &fleet()).len(), 5);
+    }
+
+    #[test]
+    fn test_any_all_and_none_on_their_own() {
+        let any = TagPredicate::new(&tags(&["go", "frontend"]), &[], &[]);
+        assert_eq!(selected(&any, &fleet()), vec!["worker", "web"]);
+
+        let all = TagPredicate::new(&[], &tags(&["backend", "rust"]), &[]);
+        assert_eq!(selected(&all, &fleet()), vec!["api", "legacy"]);
+
+        let none = TagPredicate::new(&[], &[], &tags(&["backend", "team-b"]));
+        assert_eq!(selected(&none, &fleet()), vec!["docs"]);
+    }
+
+    #[test]
+    fn test_conditions_combine_with_and() {
+        // Rust or Go backends of team A: legacy has rust and backend but not team-a
+        let predicate =
+            TagPredicate::new(&tags(&["rust", "go"]), &tags(&["backend", "team-a"]), &[]);
+        assert_eq!(selected(&predicate, &fleet()), vec!["api", "worker"]);
+
+        // Rust backends that are not deprecated: legacy satisfies any and all but not none
+        let predicate = TagPredicate::new(
+            &tags(&["rust"]),
+            &tags(&["backend"]),
+            &tags(&["deprecated"]),
+        );
+        assert_eq!(selected(&predicate, &fleet()), vec!["api"]);
+
+        // An exclusion wins over a matching any tag
+        let predicate = TagPredicate::new(&tags(&["frontend"]), &[], &tags(&["team-b"]));
+        assert!(selected(&predicate, &fleet()).is_empty());
+    }
+}
@@ -0,0 +1,121 @@
+//! Suggestions for mistyped tags
+//!
+//! When a `--tag` value matches no repository, the tags the config does know
+//! are searched for near misses: tags within a small edit distance (so
+//! `backnd` finds `backend`) and tags containing the value (so `front` finds
+//! `frontend`). Comparisons ignore case.
+
+/// Most tags offered for a single value
+pub const MAX_SUGGESTIONS: usize = 3;
+
+/// Edit distance between `a` and `b`: the fewest single-character insertions,
+/// deletions or substitutions turning one into the other
+pub fn levenshtein(a: &str, b: &str) -> usize {
+    let b: Vec<char> = b.chars().collect();
+    let mut previous: Vec<usize> = (0..=b.len()).collect();
+    let mut current = vec![0; b.len() + 1];
+
+    for (i, ca) in a.chars().enumerate() {
+        current[0] = i + 1;
+        for (j, cb) in b.iter().enumerate() {
+            let substitution = previous[j] + usize::from(ca != *cb);
+            current[j + 1] = substitution.min(previous[j + 1] + 1).min(current[j] + 1);
+        }
+        std::mem::swap(&mut previous, &mut current);
+    }
+
+    previous[b.len()]
+}
+
+/// Known tags close to `tag`, closest first
+///
+/// Returns nothing when `tag` is itself known. A tag qualifies when it
+/// contains `tag` or is within a third of its length in edits (at least one).
+pub fn suggest_tags<'a>(tag: &str, known: impl IntoIterator<Item = &'a str>) -> Vec<String> {
+    let wanted = tag.to_lowercase();
+    let limit = (wanted.chars().count() / 3).max(1);
+
+    let mut candidates = Vec::new();
+    for candidate in known {
+        if candidate == tag {
+            return Vec::new();
+        }
+        let lowered = candidate.to_lowercase();
+        let distance = levenshtein(&wanted, &lowered);
+        if distance <= limit || (!wanted.is_empty() && lowered.contains(&wanted)) {
+            candidates.push((distance, candidate));
+        }
+    }
+
+    candidates.sort();
+    candidates.dedup();
+    candidates
+        .into_iter()
+        .take(MAX_SUGGESTIONS)
+        .map(|(_, candidate)| candidate.to_string())
+        .collect()
+}
+
+/// `did you mean 'a', 'b' or 'c'?` for a list of suggestions
+pub fn did_you_mean(suggestions: &[String]) -> Option<String> {
+    let quoted: Vec<String> = suggestions.iter().map(|s| format!("'{}'", s)).collect();
+    match quoted.as_slice() {
+        [] => None,
+        [only] => Some(format!("did you mean {}?", only)),
+        [rest @ .., last] => Some(format!("did you mean {} or {}?", rest.join(", "), last)),
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    const KNOWN: [&str; 5] = ["backend", "frontend", "infra", "backend-legacy", "docs"];
+
+    #[test]
+    fn test_levenshtein() {
+        assert_eq!(levenshtein("backend", "backend"), 0);
+        assert_eq!(levenshtein("backnd", "backend"), 1);
+        assert_eq!(levenshtein("kitten", "sitting"), 3);
+        assert_eq!(levenshtein("", "docs"), 4);
+        assert_eq!(levenshtein("infra", ""), 5);
+    }
+
+    #[test]
+    fn test_near_miss_suggests_closest_tags() {
+        assert_eq!(suggest_tags("backnd", KNOWN), vec!["backend"]);
+        assert_eq!(suggest_tags("frontnd", KNOWN), vec!["frontend"]);
+        assert_eq!(suggest_tags("Infra", KNOWN), vec!["infra"]);
+        assert_eq!(suggest_tags("doc", KNOWN), vec!["docs"]);
+    }
+
+    #[test]
+    fn test_partial_match_suggests_tags_containing_value() {
+        assert_eq!(
+            suggest_tags("back", KNOWN),
+            vec!["backend", "backend-legacy"]
+        );
+        assert_eq!(suggest_tags("legacy", KNOWN), vec!["backend-legacy"]);
+    }
+
+    #[test]
+    fn test_exact_match_or_unrelated_value_suggests_nothing() {
+        assert!(suggest_tags("backend", KNOWN).is_empty());
+        assert!(suggest_tags("payments", KNOWN).is_empty());
+        assert!(suggest_tags("", KNOWN).is_empty());
+    }
+
+    #[test]
+    fn test_did_you_mean() {
+        let tags = |names: &[&str]| names.iter().map(|s| s.to_string()).collect::<Vec<_>>();
+        assert_eq!(did_you_mean(&[]), None);
+        assert_eq!(
+            did_you_mean(&tags(&["backend"])).unwrap(),
+            "did you mean 'backend'?"
+        );
+        assert_eq!(
+            did_you_mean(&tags(&["backend", "frontend", "infra"])).unwrap(),
+            "did you mean 'backend', 'frontend' or 'infra'?"
+        );
+    }
+}
@@ -22,6 +22,15 @@ pub mod github {
 
     /// Default User-Agent header for API requests
     pub const DEFAULT_USER_AGENT: &str = concat!("repos/", env!("CARGO_PKG_VERSION"));
+
+    /// Default minimum gap between pull request API calls (`pr --api-interval`)
+    pub const DEFAULT_API_INTERVAL: &str = "1s";
+
+    /// Retries of a rate-limited pull request API call before giving up
+    pub const RATE_LIMIT_RETRIES: u32 = 3;
+
+    /// Wait before the first retry of a rate-limited call; doubled for each further retry
+    pub const RATE_LIMIT_BACKOFF_SECS: u64 = 10;
 }
 
 /// Default values for configuration
@@ -31,4 +40,13 @@ pub mod config {
 
     /// Default output directory
     pub const DEFAULT_LOGS_DIR: &str = "output";
+
+    /// Subdirectory of the output directory holding `--resume` checkpoints
+    pub const CHECKPOINTS_DIR: &str = "checkpoints";
+
+    /// Path below the output directory of the `--fetch-topics` cache
+    pub const TOPICS_CACHE_FILE: &str = "cache/github-topics.json";
+
+    /// Path below the output directory of the `orgs` expansion cache
+    pub const ORGS_CACHE_FILE: &str = "cache/github-orgs.json";
 }
@@ -0,0 +1,178 @@
+//! Finding and deleting branches that are already merged
+//!
+//! `repos prune-branches` removes branches whose work has landed on the
+//! default branch. A branch counts as merged when its tip is reachable from
+//! the default branch as known on `origin` (or locally, without a remote
+//! copy), so fast-forward and merge-commit merges are found; squash merges,
+//! which leave the branch's own commits unreachable, are not.
+//!
+//! The current branch, the default branch and [`PROTECTED_BRANCHES`] are
+//! never reported, locally or on the remote.
+//!
+//! ## Functions
+//!
+//! - [`merge_target`]: The ref branches must be merged into
+//! - [`merged_local_branches`]: Local branches merged into it
+//! - [`merged_remote_branches`]: `origin` branches merged into it
+//! - [`delete_local_branch`]: Delete a merged local branch
+//! - [`delete_remote_branch`]: Delete a branch on `origin`
+
+use anyhow::{Context, Result};
+
+use super::common::{TraceCommand, git_command, git_error};
+use super::pull_request::get_current_branch;
+
+/// Branch names that are never pruned
+pub const PROTECTED_BRANCHES: &[&str] = &["main", "master", "develop"];
+
+/// Remote whose branches are pruned with `--remote`
+const REMOTE: &str = "origin";
+
+/// The ref merged branches are measured against: `origin/<default>` when the
+/// remote-tracking branch exists, else the local `default` branch
+pub fn merge_target(repo_path: &str, default_branch: &str) -> String {
+    let remote_ref = format!("{}/{}", REMOTE, default_branch);
+    let exists = git_command(None)
+        .args(["rev-parse", "--verify", "--quiet"])
+        .arg(format!("refs/remotes/{}", remote_ref))
+        .current_dir(repo_path)
+        .traced(repo_path)
+        .output()
+        .is_ok_and(|output| output.status.success());
+    if exists {
+        remote_ref
+    } else {
+        default_branch.to_string()
+    }
+}
+
+/// Local branches merged into `target`, except the current, default and protected ones
+pub fn merged_local_branches(
+    repo_path: &str,
+    target: &str,
+    default_branch: &str,
+) -> Result<Vec<String>> {
+    // A detached HEAD has no current branch to protect
+    let current = get_current_branch(repo_path).ok();
+    let listed = list_merged(repo_path, &["branch"], target)?;
+    Ok(listed
+        .into_iter()
+        .filter(|name| Some(name.as_str()) != current.as_deref())
+        .filter(|name| is_prunable(name, default_branch))
+        .collect())
+}
+
+/// Branches on `origin` merged into `target`, without the `origin/` prefix
+///
+/// The default branch, the remote's `HEAD` and protected names are left out.
+pub fn merged_remote_branches(
+    repo_path: &str,
+    target: &str,
+    default_branch: &str,
+) -> Result<Vec<String>> {
+    let prefix = format!("{}/", REMOTE);
+    let listed = list_merged(repo_path, &["branch", "--remotes"], target)?;
+    Ok(listed
+        .iter()
+        .filter_map(|name| name.strip_prefix(&prefix))
+        .filter(|name| *name != "HEAD" && is_prunable(name, default_branch))
+        .map(str::to_string)
+        .collect())
+}
+
+/// Delete a local branch, refusing (as `git branch -d` does) if it is not merged
+pub fn delete_local_branch(repo_path: &str, branch: &str) -> Result<()> {
+    let output = git_command(None)
+        .args(["branch", "-d", branch])
+        .current_dir(repo_path)
+        .traced(repo_path)
+        .output()
+        .context("Failed to execute git branch command")?;
+
+    if !output.status.success() {
+        anyhow::bail!(
+            "Failed to delete branch '{}': {}",
+            branch,
+            git_error(&String::from_utf8_lossy(&output.stderr))
+        );
+    }
+    Ok(())
+}
+
+/// Delete `branch` on `origin`, authenticating with an SSH key if given
+pub fn delete_remote_branch(repo_path: &str, branch: &str, ssh_key: Option<&str>) -> Result<()> {
+    let output = git_command(ssh_key)
+        .args(["push", REMOTE, "--delete", branch])
+        .current_dir(repo_path)
+        .traced(repo_path)
+        .output()
+        .context("Failed to execute git push command")?;
+
+    if !output.status.success() {
+        anyhow::bail!(
+            "Failed to delete remote branch '{}/{}': {}",
+            REMOTE,
+            branch,
+            git_error(&String::from_utf8_lossy(&output.stderr))
+        );
+    }
+    Ok(())
+}
+
+/// Branch names printed by `git <list> --merged <target>`
+fn list_merged(repo_path: &str, list: &[&str], target: &str) -> Result<Vec<String>> {
+    let output = git_command(None)
+        .args(list)
+        .args(["--format=%(refname:short)", "--merged", target])
+        .current_dir(repo_path)
+        .traced(repo_path)
+        .output()
+        .context("Failed to execute git branch command")?;
+
+    if !output.status.success() {
+        anyhow::bail!(
+            "Failed to list branches merged into '{}': {}",
+            target,
+            git_error(&String::from_utf8_lossy(&output.stderr))
+        );
+    }
+    Ok(parse_branch_names(&String::from_utf8_lossy(&output.stdout)))
+}
+
+/// One branch name per non-empty line
+fn parse_branch_names(output: &str) -> Vec<String> {
+    output
+        .lines()
+        .map(str::trim)
+        .filter(|line| !line.is_empty())
+        .map(str::to_string)
+        .collect()
+}
+
+/// Whether `name` may be pruned: neither the default branch nor a protected one
+fn is_prunable(name: &str, default_branch: &str) -> bool {
+    name != default_branch && !PROTECTED_BRANCHES.contains(&name)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_parse_branch_names() {
+        assert_eq!(
+            parse_branch_names("feature/a\n  main\n\nfix-b\n"),
+            vec!["feature/a", "main", "fix-b"]
+        );
+        assert!(parse_branch_names("").is_empty());
+    }
+
+    #[test]
+    fn test_default_and_protected_branches_are_kept() {
+        assert!(is_prunable("feature/a", "trunk"));
+        assert!(!is_prunable("trunk", "trunk"));
+        for name in PROTECTED_BRANCHES {
+            assert!(!is_prunable(name, "trunk"));
+        }
+    }
+}
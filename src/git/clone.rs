@@ -7,34 +7,273 @@
 //! ## Functions
 //!
 //! - [`clone_repository`]: Clone a repository from its remote URL
+//! - [`clone_repository_with`]: Clone with [`CloneOptions`] (e.g. repairing
+//!   directories left behind by an interrupted clone)
+//! - [`clone_command_args`]: The `git clone` arguments, including the
+//!   repository's `clone_args`
 //! - [`remove_repository`]: Remove a cloned repository directory
+//! - [`is_bare_clone`]: Whether a directory holds a bare (`mirror`) clone
 //!
-//! Both functions work with the [`Repository`] configuration type and
+//! Repositories marked `mirror` are cloned with `git clone --mirror` into a
+//! bare directory without a `.git` or working tree. A fresh clone gets the
+//! repository's `git_config` entries applied (see [`super::config`]).
+//!
+//! These functions work with the [`Repository`] configuration type and
 //! provide detailed logging throughout the operation.
 
-use crate::config::Repository;
+use crate::config::{PullStrategy, Repository};
 use anyhow::{Context, Result};
 use std::path::Path;
-use std::process::Command;
 
-use super::common::Logger;
+use super::common::{Logger, TraceCommand, git_command, git_error, output_until_deadline};
+use super::config::apply_git_config;
+use super::lock::{LockMode, lock_repository};
+use super::pull::{PullOptions, pull_repository_with};
+
+/// Options controlling how [`clone_repository_with`] treats existing directories
+#[derive(Debug, Clone, Copy, Default, PartialEq, Eq)]
+pub struct CloneOptions {
+    /// Remove and re-clone directories left behind by an interrupted clone of
+    /// the repository's URL; anything else is refused
+    pub repair: bool,
+    /// Fast-forward existing clones from their remote instead of skipping them
+    pub update_existing: bool,
+    /// Fail instead of skipping when the target directory already exists,
+    /// unless `update_existing` or `repair` handles it
+    pub error_on_existing: bool,
+    /// Take the repository's lock first, skipping or waiting while another
+    /// process holds it
+    pub lock: Option<LockMode>,
+}
+
+/// What [`clone_repository_with`] did for a repository
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum CloneOutcome {
+    /// A fresh clone was made
+    Cloned,
+    /// An existing clone was fast-forwarded (`update_existing`)
+    Updated,
+    /// The target directory already existed and was left alone
+    Skipped,
+}
+
+/// State of a repository's target directory before cloning
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub enum CloneState {
+    /// Nothing exists at the target path
+    Missing,
+    /// A git checkout, or a bare mirror, with a valid HEAD
+    Complete,
+    /// Left behind by an interrupted clone (empty, or a `.git` without a valid HEAD)
+    Incomplete(String),
+    /// Holds files but no `.git`; never removed automatically
+    NotARepository,
+    /// Cannot be listed, or its `.git` is not a repository git can open;
+    /// never removed automatically
+    Unreadable(String),
+}
+
+/// Inspect the target directory of a clone
+pub fn inspect_clone(target_dir: &Path) -> CloneState {
+    if !target_dir.exists() {
+        return CloneState::Missing;
+    }
+
+    let is_empty = match std::fs::read_dir(target_dir) {
+        Ok(mut entries) => entries.next().is_none(),
+        Err(e) => return CloneState::Unreadable(format!("cannot read directory: {}", e)),
+    };
+
+    let git_dir = if is_bare_clone(target_dir) {
+        target_dir.to_path_buf()
+    } else {
+        target_dir.join(".git")
+    };
+    if !git_dir.exists() {
+        return if is_empty {
+            CloneState::Incomplete("directory is empty".to_string())
+        } else {
+            CloneState::NotARepository
+        };
+    }
+
+    // Only the `.git` (or bare directory) itself is opened, so a broken one
+    // is not mistaken for a repository further up the tree
+    let opened = git_command(None)
+        .arg("--git-dir")
+        .arg(&git_dir)
+        .args(["rev-parse", "--git-dir"])
+        .traced(&target_dir.to_string_lossy())
+        .output();
+    match opened {
+        Ok(output) if output.status.success() => {}
+        Ok(output) => {
+            let stderr = String::from_utf8_lossy(&output.stderr);
+            return CloneState::Unreadable(format!("corrupt .git: {}", stderr.trim()));
+        }
+        Err(e) => return CloneState::Unreadable(format!("cannot run git: {}", e)),
+    }
+
+    let head = git_command(None)
+        .arg("--git-dir")
+        .arg(&git_dir)
+        .args(["rev-parse", "--verify", "--quiet", "HEAD"])
+        .traced(&target_dir.to_string_lossy())
+        .output();
+    match head {
+        Ok(output) if output.status.success() => CloneState::Complete,
+        _ => CloneState::Incomplete("HEAD does not point to a commit".to_string()),
+    }
+}
+
+/// Why `--repair` must not remove the incomplete clone at `target_dir`, if it must not
+///
+/// Only what an interrupted `git clone` of `repo` leaves behind is removed: an
+/// empty directory, or a repository whose `origin` is `repo.url` and whose
+/// work tree holds nothing but `.git`. A repository started with `git init`,
+/// or one with files of its own, is kept.
+fn repair_refusal(repo: &Repository, target_dir: &Path) -> Option<String> {
+    let entries: Vec<_> = match std::fs::read_dir(target_dir) {
+        Ok(entries) => entries.filter_map(|entry| entry.ok()).collect(),
+        Err(e) => return Some(format!("cannot read directory: {}", e)),
+    };
+    if entries.is_empty() {
+        return None;
+    }
+
+    let bare = is_bare_clone(target_dir);
+    let git_dir = if bare {
+        target_dir.to_path_buf()
+    } else {
+        target_dir.join(".git")
+    };
+    let origin = git_command(None)
+        .arg("--git-dir")
+        .arg(&git_dir)
+        .args(["config", "--get", "remote.origin.url"])
+        .traced(&repo.name)
+        .output()
+        .ok()
+        .filter(|output| output.status.success())
+        .map(|output| String::from_utf8_lossy(&output.stdout).trim().to_string());
+    match origin {
+        Some(url) if url == repo.url => {}
+        Some(url) => return Some(format!("its origin is {}, not {}", url, repo.url)),
+        None => return Some("it has no origin remote, so repos did not clone it".to_string()),
+    }
+
+    if !bare && entries.iter().any(|entry| entry.file_name() != ".git") {
+        return Some("its work tree holds files".to_string());
+    }
+    None
+}
+
+/// Whether `target_dir` holds a bare repository, as left by `git clone --mirror`
+///
+/// A bare clone has no `.git`; its `HEAD`, `objects` and `refs` sit directly
+/// in the directory.
+pub fn is_bare_clone(target_dir: &Path) -> bool {
+    !target_dir.join(".git").exists()
+        && target_dir.join("HEAD").is_file()
+        && target_dir.join("objects").is_dir()
+        && target_dir.join("refs").is_dir()
+}
 
 /// Clone a repository from its URL to the target directory
+///
+/// Existing directories are left alone; see [`clone_repository_with`] to
+/// repair incomplete clones.
 pub fn clone_repository(repo: &Repository) -> Result<()> {
+    clone_repository_with(repo, &CloneOptions::default()).map(|_| ())
+}
+
+/// Clone a repository, handling existing target directories according to `options`
+///
+/// With `update_existing`, an existing clone is pulled with `--ff-only`
+/// (see [`pull_repository_with`]) instead of skipped. With
+/// `error_on_existing`, a directory that would be skipped is an error.
+pub fn clone_repository_with(repo: &Repository, options: &CloneOptions) -> Result<CloneOutcome> {
     let logger = Logger;
     let target_dir = repo.get_target_dir();
+    let _lock = match options.lock {
+        Some(mode) => match lock_repository(repo, mode)? {
+            Some(lock) => Some(lock),
+            None => return Ok(CloneOutcome::Skipped),
+        },
+        None => None,
+    };
 
-    // Check if directory already exists
-    if Path::new(&target_dir).exists() {
-        logger.warn(repo, "Repository directory already exists, skipping");
-        return Ok(());
+    let state = inspect_clone(Path::new(&target_dir));
+    let handled = match &state {
+        CloneState::Missing => true,
+        CloneState::Complete => options.update_existing,
+        CloneState::Incomplete(_) => options.repair,
+        CloneState::NotARepository | CloneState::Unreadable(_) => false,
+    };
+    if options.error_on_existing && !handled {
+        anyhow::bail!("Target directory already exists: {}", target_dir);
     }
 
-    let mut args = vec!["clone"];
+    match state {
+        CloneState::Missing => {}
+        CloneState::Complete if options.update_existing => {
+            // The lock, if requested, is already held
+            let pull = PullOptions {
+                strategy: Some(PullStrategy::FfOnly),
+                ..PullOptions::default()
+            };
+            return pull_repository_with(repo, &pull).map(|_| CloneOutcome::Updated);
+        }
+        CloneState::Complete => {
+            logger.warn(repo, "Repository directory already exists, skipping");
+            return Ok(CloneOutcome::Skipped);
+        }
+        CloneState::NotARepository => {
+            logger.warn(
+                repo,
+                "Directory already exists but is not a git repository, skipping",
+            );
+            return Ok(CloneOutcome::Skipped);
+        }
+        CloneState::Unreadable(reason) => {
+            logger.warn(
+                repo,
+                &format!("Directory is unreadable ({}), skipping", reason),
+            );
+            return Ok(CloneOutcome::Skipped);
+        }
+        CloneState::Incomplete(reason) if options.repair => {
+            if let Some(refusal) = repair_refusal(repo, Path::new(&target_dir)) {
+                anyhow::bail!(
+                    "Refusing to remove incomplete clone ({}): {}; remove {} by hand to re-clone it",
+                    reason,
+                    refusal,
+                    target_dir
+                );
+            }
+            logger.warn(
+                repo,
+                &format!("Removing incomplete clone ({}) and cloning again", reason),
+            );
+            std::fs::remove_dir_all(&target_dir)
+                .with_context(|| format!("Failed to remove incomplete clone: {}", target_dir))?;
+        }
+        CloneState::Incomplete(reason) => {
+            logger.warn(
+                repo,
+                &format!(
+                    "Directory looks like an incomplete clone ({}), skipping; run `repos clone --repair` to re-clone it",
+                    reason
+                ),
+            );
+            return Ok(CloneOutcome::Skipped);
+        }
+    }
 
-    // Add branch flag if a branch is specified
-    if let Some(branch) = &repo.branch {
-        args.extend_from_slice(&["-b", branch]);
+    let args = clone_command_args(repo)?;
+    if repo.mirror {
+        logger.info(repo, &format!("Cloning mirror of {}", repo.url));
+    } else if let Some(branch) = &repo.branch {
         logger.info(
             repo,
             &format!("Cloning branch '{}' from {}", branch, repo.url),
@@ -43,21 +282,103 @@ pub fn clone_repository(repo: &Repository) -> Result<()> {
         logger.info(repo, &format!("Cloning default branch from {}", repo.url));
     }
 
-    // Add repository URL and target directory
-    args.push(&repo.url);
-    args.push(&target_dir);
-
-    let output = Command::new("git")
-        .args(&args)
-        .output()
-        .context("Failed to execute git clone command")?;
+    let output = output_until_deadline(
+        git_command(repo.ssh_key.as_deref())
+            .args(&args)
+            .traced(&repo.name),
+        "git clone",
+    )?;
 
     if !output.status.success() {
         let stderr = String::from_utf8_lossy(&output.stderr);
-        anyhow::bail!("Failed to clone repository: {}", stderr);
+        anyhow::bail!("Failed to clone repository: {}", git_error(&stderr));
     }
 
     logger.success(repo, "Successfully cloned");
+    apply_git_config(repo)?;
+    Ok(CloneOutcome::Cloned)
+}
+
+/// Arguments of the `git clone` invocation for a repository
+///
+/// The branch (or, for a `mirror`, `--mirror`) flag and the repository's
+/// `clone_args` come before `--`, the URL and target directory, which stay the
+/// last three arguments. A mirror copies every ref, so its `branch` is not
+/// passed.
+///
+/// # Errors
+/// Returns an error if a `clone_args` entry is rejected by [`check_clone_arg`]
+pub fn clone_command_args(repo: &Repository) -> Result<Vec<String>> {
+    let mut args = vec!["clone".to_string()];
+    if repo.mirror {
+        args.push("--mirror".to_string());
+    } else if let Some(branch) = &repo.branch {
+        args.extend(["-b".to_string(), branch.clone()]);
+    }
+    for arg in &repo.clone_args {
+        check_clone_arg(arg).with_context(|| format!("Repository '{}'", repo.name))?;
+        args.push(arg.clone());
+    }
+    args.push("--".to_string());
+    args.push(repo.url.clone());
+    args.push(repo.get_target_dir());
+    Ok(args)
+}
+
+/// Long `git clone` options that take a value
+const VALUE_OPTIONS: [&str; 16] = [
+    "--branch",
+    "--bundle-uri",
+    "--config",
+    "--depth",
+    "--filter",
+    "--jobs",
+    "--origin",
+    "--ref-format",
+    "--reference",
+    "--reference-if-able",
+    "--separate-git-dir",
+    "--server-option",
+    "--shallow-exclude",
+    "--shallow-since",
+    "--template",
+    "--upload-pack",
+];
+
+/// Short `git clone` options that take a value
+const SHORT_VALUE_OPTIONS: [char; 5] = ['b', 'c', 'j', 'o', 'u'];
+
+/// Check that an extra `git clone` argument is a self-contained option
+///
+/// Anything that is not an option, and `--` itself, would be taken as the URL
+/// or target directory, and an option whose value is not attached would take
+/// the `--` after it as its value, so option values must be attached
+/// (`--depth=1` or `-bmain`, not `--depth 1`). Git accepts unambiguous
+/// prefixes of long options, so prefixes of value-taking ones count as well.
+pub fn check_clone_arg(arg: &str) -> Result<()> {
+    if !arg.starts_with('-') || arg == "-" || arg == "--" {
+        anyhow::bail!(
+            "Invalid clone argument '{}': expected an option such as --filter=blob:none \
+             (attach values with '='; the URL and target directory are set by repos)",
+            arg
+        );
+    }
+
+    let detached_value = if arg.starts_with("--") {
+        !arg.contains('=') && VALUE_OPTIONS.iter().any(|option| option.starts_with(arg))
+    } else {
+        // `-qb` is `-q -b`; a value-taking flag takes the rest of the cluster
+        let flags = &arg[1..];
+        flags
+            .find(|flag| SHORT_VALUE_OPTIONS.contains(&flag))
+            .is_some_and(|at| at + 1 == flags.len())
+    };
+    if detached_value {
+        anyhow::bail!(
+            "Invalid clone argument '{}': its value must be attached, as in --depth=1 or -bmain",
+            arg
+        );
+    }
     Ok(())
 }
 
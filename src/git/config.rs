@@ -0,0 +1,148 @@
+//! Per-repository git configuration
+//!
+//! A repository's `git_config` entries (plus any global `--git-config`
+//! defaults merged into them) are written to the clone's local config with
+//! `git config <key> <value>`. Cloning applies them as a post-clone step;
+//! `repos git-config` re-applies them to existing clones.
+//!
+//! ## Functions
+//!
+//! - [`parse_git_config_entry`]: Parse a `key=value` command-line entry
+//! - [`git_config_commands`]: The `git config` invocations for a repository
+//! - [`apply_git_config`]: Run them in the repository's clone
+
+use crate::config::Repository;
+use anyhow::{Context, Result};
+use std::process::Command;
+
+use super::common::{Logger, TraceCommand, git_command};
+
+/// Parse a `key=value` entry as given to `--git-config`
+///
+/// The key must name a `section.option` (e.g. `user.email`); the value may be
+/// empty and may itself contain `=`.
+pub fn parse_git_config_entry(entry: &str) -> Result<(String, String)> {
+    let (key, value) = entry
+        .split_once('=')
+        .with_context(|| format!("Invalid git config entry '{}': expected key=value", entry))?;
+    let key = key.trim();
+    if !key.contains('.') || key.starts_with('.') || key.ends_with('.') {
+        anyhow::bail!(
+            "Invalid git config key '{}': expected section.option (e.g. user.email)",
+            key
+        );
+    }
+    Ok((key.to_string(), value.to_string()))
+}
+
+/// One `git -C <clone> config <key> <value>` command per `git_config` entry, in key order
+pub fn git_config_commands(repo: &Repository) -> Vec<Command> {
+    let target_dir = repo.get_target_dir();
+    repo.git_config
+        .iter()
+        .map(|(key, value)| {
+            let mut command = git_command(None);
+            command
+                .arg("-C")
+                .arg(&target_dir)
+                .arg("config")
+                .arg(key)
+                .arg(value);
+            command
+        })
+        .collect()
+}
+
+/// Apply the repository's `git_config` entries to its clone
+///
+/// Does nothing when the repository has no entries.
+pub fn apply_git_config(repo: &Repository) -> Result<()> {
+    if repo.git_config.is_empty() {
+        return Ok(());
+    }
+
+    for (mut command, key) in git_config_commands(repo)
+        .into_iter()
+        .zip(repo.git_config.keys())
+    {
+        let output = command
+            .traced(&repo.name)
+            .output()
+            .context("Failed to execute git config command")?;
+        if !output.status.success() {
+            let stderr = String::from_utf8_lossy(&output.stderr);
+            anyhow::bail!("Failed to set git config '{}': {}", key, stderr.trim());
+        }
+    }
+
+    Logger.info(
+        repo,
+        &format!("Applied {} git config entries", repo.git_config.len()),
+    );
+    Ok(())
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn repo_with_config(entries: &[(&str, &str)]) -> Repository {
+        let mut repo = Repository::new(
+            "cfg".to_string(),
+            "git@github.com:owner/cfg.git".to_string(),
+        );
+        repo.path = Some("/work/cfg".to_string());
+        repo.git_config = entries
+            .iter()
+            .map(|(k, v)| (k.to_string(), v.to_string()))
+            .collect();
+        repo
+    }
+
+    #[test]
+    fn test_parse_git_config_entry() {
+        assert_eq!(
+            parse_git_config_entry("user.email=ci@example.com").unwrap(),
+            ("user.email".to_string(), "ci@example.com".to_string())
+        );
+        // Only the first `=` separates key and value
+        assert_eq!(
+            parse_git_config_entry("alias.eq=log --format=%h")
+                .unwrap()
+                .1,
+            "log --format=%h"
+        );
+        assert_eq!(parse_git_config_entry("core.hooksPath=").unwrap().1, "");
+
+        let err = parse_git_config_entry("user.email").unwrap_err();
+        assert!(err.to_string().contains("expected key=value"));
+        let err = parse_git_config_entry("email=x").unwrap_err();
+        assert!(err.to_string().contains("expected section.option"));
+    }
+
+    #[test]
+    fn test_git_config_commands_pass_key_and_value() {
+        let repo = repo_with_config(&[
+            ("user.email", "ci@example.com"),
+            ("core.hooksPath", ".githooks"),
+        ]);
+
+        let commands: Vec<Vec<String>> = git_config_commands(&repo)
+            .iter()
+            .map(|command| {
+                command
+                    .get_args()
+                    .map(|arg| arg.to_string_lossy().to_string())
+                    .collect()
+            })
+            .collect();
+        assert_eq!(
+            commands,
+            vec![
+                vec!["-C", "/work/cfg", "config", "core.hooksPath", ".githooks"],
+                vec!["-C", "/work/cfg", "config", "user.email", "ci@example.com"],
+            ]
+        );
+        assert!(git_config_commands(&repo_with_config(&[])).is_empty());
+    }
+}
@@ -0,0 +1,60 @@
+//! Git history queries
+//!
+//! Read-only lookups into a repository's commit history and `HEAD`.
+//!
+//! ## Functions
+//!
+//! - [`last_commit_date`]: Committer date of the most recent commit on `HEAD`
+//! - [`is_detached_head`]: Whether `HEAD` points at a commit instead of a branch
+
+use super::common::{TraceCommand, git_command};
+use anyhow::{Context, Result};
+use chrono::{DateTime, FixedOffset};
+
+/// Get the committer date of the latest commit on `HEAD`
+pub fn last_commit_date(repo_path: &str) -> Result<DateTime<FixedOffset>> {
+    let output = git_command(None)
+        .args(["log", "-1", "--format=%cI"])
+        .current_dir(repo_path)
+        .traced(repo_path)
+        .output()
+        .context("Failed to execute git log command")?;
+
+    if !output.status.success() {
+        anyhow::bail!(
+            "Failed to read latest commit: {}",
+            String::from_utf8_lossy(&output.stderr).trim()
+        );
+    }
+
+    let date = String::from_utf8_lossy(&output.stdout).trim().to_string();
+    if date.is_empty() {
+        anyhow::bail!("Repository has no commits");
+    }
+
+    DateTime::parse_from_rfc3339(&date)
+        .with_context(|| format!("Failed to parse commit date '{}'", date))
+}
+
+/// Check whether `HEAD` is detached, i.e. not a symbolic ref to a branch
+///
+/// Commits made on a detached `HEAD` belong to no branch and are easily lost.
+pub fn is_detached_head(repo_path: &str) -> Result<bool> {
+    let output = git_command(None)
+        .args(["symbolic-ref", "-q", "HEAD"])
+        .current_dir(repo_path)
+        .traced(repo_path)
+        .output()
+        .context("Failed to execute git symbolic-ref command")?;
+
+    // With -q, exit code 1 means HEAD is not a symbolic ref; anything else
+    // is a real failure (e.g. not a git repository)
+    match output.status.code() {
+        Some(0) => Ok(false),
+        Some(1) => Ok(true),
+        _ => anyhow::bail!(
+            "Failed to read HEAD: {}",
+            String::from_utf8_lossy(&output.stderr).trim()
+        ),
+    }
+}
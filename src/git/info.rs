@@ -0,0 +1,105 @@
+//! Live facts about a clone for `repos info`
+//!
+//! Where the config says what a repository should be, these lookups report
+//! what its clone actually is: the checked-out branch, the latest commit and
+//! where `origin` points. Each fact is optional so that a detached `HEAD`, a
+//! repository without commits or one without `origin` still reports the rest.
+
+use super::clone::is_bare_clone;
+use super::common::{TraceCommand, git_command};
+use anyhow::{Context, Result};
+use serde::Serialize;
+use std::path::Path;
+
+/// Field separator in the `git log` format, which never occurs in its values
+const FIELD_SEPARATOR: char = '\x1f';
+
+/// The latest commit on `HEAD`
+#[derive(Debug, Clone, PartialEq, Eq, Serialize)]
+pub struct CommitSummary {
+    pub sha: String,
+    pub subject: String,
+    pub author: String,
+    /// Committer date in RFC 3339 format
+    pub date: String,
+}
+
+/// What a clone looks like right now
+#[derive(Debug, Clone, Default, PartialEq, Eq, Serialize)]
+pub struct RepoFacts {
+    /// Checked-out branch; `None` when `HEAD` is detached
+    pub current_branch: Option<String>,
+    /// `None` when the repository has no commits yet
+    pub last_commit: Option<CommitSummary>,
+    /// URL of `origin`; `None` when there is no such remote
+    pub remote_url: Option<String>,
+}
+
+/// Read the current branch, latest commit and `origin` URL of a clone
+///
+/// Only the clone's own `.git` (or the bare clone itself) is read, so a
+/// directory inside another repository does not report that repository.
+///
+/// # Errors
+/// Returns an error if git cannot run or `repo_path` is not a git repository
+pub fn repository_facts(repo_path: &str) -> Result<RepoFacts> {
+    let target_dir = Path::new(repo_path);
+    let git_dir = if is_bare_clone(target_dir) {
+        target_dir.to_path_buf()
+    } else {
+        target_dir.join(".git")
+    };
+    let output = git_command(None)
+        .arg("--git-dir")
+        .arg(&git_dir)
+        .args(["rev-parse", "--git-dir"])
+        .traced(repo_path)
+        .output()
+        .context("Failed to execute git rev-parse command")?;
+    if !output.status.success() {
+        anyhow::bail!(
+            "Not a git repository: {}",
+            String::from_utf8_lossy(&output.stderr).trim()
+        );
+    }
+
+    Ok(RepoFacts {
+        current_branch: git_value(repo_path, &git_dir, &["branch", "--show-current"])?,
+        last_commit: last_commit(repo_path, &git_dir)?,
+        remote_url: git_value(repo_path, &git_dir, &["remote", "get-url", "origin"])?,
+    })
+}
+
+fn last_commit(repo_path: &str, git_dir: &Path) -> Result<Option<CommitSummary>> {
+    let format = format!("--format=%H{0}%s{0}%an{0}%cI", FIELD_SEPARATOR);
+    let Some(line) = git_value(repo_path, git_dir, &["log", "-1", &format])? else {
+        return Ok(None);
+    };
+
+    let mut fields = line.split(FIELD_SEPARATOR).map(str::to_string);
+    let mut next = || fields.next().unwrap_or_default();
+    Ok(Some(CommitSummary {
+        sha: next(),
+        subject: next(),
+        author: next(),
+        date: next(),
+    }))
+}
+
+/// Trimmed output of a git command on `git_dir`, or `None` when it fails or
+/// prints nothing
+fn git_value(repo_path: &str, git_dir: &Path, args: &[&str]) -> Result<Option<String>> {
+    let output = git_command(None)
+        .arg("--git-dir")
+        .arg(git_dir)
+        .args(args)
+        .traced(repo_path)
+        .output()
+        .with_context(|| format!("Failed to execute git {} command", args[0]))?;
+
+    let value = String::from_utf8_lossy(&output.stdout).trim().to_string();
+    if !output.status.success() || value.is_empty() {
+        return Ok(None);
+    }
+    Ok(Some(value))
+}
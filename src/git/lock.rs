@@ -0,0 +1,235 @@
+//! Advisory locks keeping two `repos` processes off the same clone
+//!
+//! Operations that change a clone (clone, pull, preparing a pull request)
+//! first take an exclusive `flock`-style lock on a small file next to the
+//! repository's directory, `.<name>.repos.lock`. The file sits beside the
+//! clone rather than in it, so it never shows up as a change in the working
+//! tree and can be taken before the directory exists. The lock is released
+//! when the guard is dropped, and by the operating system if the process
+//! dies. The guard removes the file while still holding the lock, so a locker
+//! that was waiting on the removed file finds it gone once it gets the lock
+//! and starts over on a fresh one; only a process that dies holding the lock
+//! leaves its file behind.
+//!
+//! ## Functions
+//!
+//! - [`lock_repository`]: Take a repository's lock, skipping or waiting if held
+//! - [`lock_path`]: The lock file for a repository directory
+
+use crate::config::Repository;
+use anyhow::{Context, Result};
+use std::fmt;
+use std::fs::{File, OpenOptions, TryLockError};
+use std::path::{Path, PathBuf};
+use std::str::FromStr;
+
+use super::common::Logger;
+
+/// What to do when another process holds a repository's lock
+#[derive(Debug, Clone, Copy, Default, PartialEq, Eq)]
+pub enum LockMode {
+    /// Leave the repository alone and move on
+    #[default]
+    Skip,
+    /// Block until the other process is done
+    Wait,
+}
+
+impl FromStr for LockMode {
+    type Err = anyhow::Error;
+
+    fn from_str(value: &str) -> Result<Self> {
+        match value {
+            "skip" => Ok(Self::Skip),
+            "wait" => Ok(Self::Wait),
+            _ => anyhow::bail!("Unknown lock mode '{}': expected skip or wait", value),
+        }
+    }
+}
+
+/// Error for an operation left undone because another process holds the
+/// repository's lock and the [`LockMode`] is [`LockMode::Skip`]
+///
+/// Commands record such repositories as skipped rather than failed.
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub struct RepositoryLocked;
+
+impl fmt::Display for RepositoryLocked {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        write!(f, "Locked by another repos process, skipped")
+    }
+}
+
+impl std::error::Error for RepositoryLocked {}
+
+/// A held repository lock, released when dropped
+#[derive(Debug)]
+pub struct RepoLock {
+    // Closing the file releases the lock
+    _file: File,
+    path: PathBuf,
+}
+
+impl RepoLock {
+    /// The lock file this guard holds
+    pub fn path(&self) -> &Path {
+        &self.path
+    }
+}
+
+impl Drop for RepoLock {
+    fn drop(&mut self) {
+        // Still locked here: the file is closed only after this returns
+        let _ = std::fs::remove_file(&self.path);
+    }
+}
+
+/// The lock file guarding `target_dir`: `.<name>.repos.lock` in its parent
+pub fn lock_path(target_dir: &Path) -> PathBuf {
+    let name = target_dir
+        .file_name()
+        .map(|name| name.to_string_lossy().to_string())
+        .unwrap_or_default();
+    target_dir
+        .parent()
+        .unwrap_or(Path::new(""))
+        .join(format!(".{}.repos.lock", name))
+}
+
+/// Take the lock on `repo`'s clone
+///
+/// Returns `None`, after logging a warning, when another process holds the
+/// lock and `mode` is [`LockMode::Skip`]; with [`LockMode::Wait`] blocks until
+/// the lock is free.
+///
+/// # Errors
+/// Returns an error if the lock file cannot be created or locked
+pub fn lock_repository(repo: &Repository, mode: LockMode) -> Result<Option<RepoLock>> {
+    let path = lock_path(Path::new(&repo.get_target_dir()));
+    if let Some(parent) = path.parent() {
+        std::fs::create_dir_all(parent)
+            .with_context(|| format!("Failed to create directory: {}", parent.display()))?;
+    }
+    loop {
+        let file = OpenOptions::new()
+            .create(true)
+            .truncate(false)
+            .write(true)
+            .open(&path)
+            .with_context(|| format!("Failed to open lock file: {}", path.display()))?;
+
+        match file.try_lock() {
+            Ok(()) => {}
+            Err(TryLockError::WouldBlock) => match mode {
+                LockMode::Skip => {
+                    Logger.warn(repo, "Locked by another repos process, skipping");
+                    return Ok(None);
+                }
+                LockMode::Wait => {
+                    Logger.info(repo, "Waiting for another repos process to finish");
+                    file.lock()
+                        .with_context(|| format!("Failed to lock {}", path.display()))?;
+                }
+            },
+            Err(TryLockError::Error(e)) => {
+                return Err(e).with_context(|| format!("Failed to lock {}", path.display()));
+            }
+        }
+
+        // The previous holder may have removed the file before we locked it
+        if is_current(&file, &path) {
+            return Ok(Some(RepoLock { _file: file, path }));
+        }
+    }
+}
+
+/// Whether the open `file` is still the one at `path`
+#[cfg(unix)]
+fn is_current(file: &File, path: &Path) -> bool {
+    use std::os::unix::fs::MetadataExt;
+
+    match (file.metadata(), std::fs::metadata(path)) {
+        (Ok(open), Ok(current)) => open.dev() == current.dev() && open.ino() == current.ino(),
+        _ => false,
+    }
+}
+
+/// Whether the open `file` is still the one at `path`
+///
+/// Without inode numbers to compare, a file still at `path` is taken to be it.
+#[cfg(not(unix))]
+fn is_current(_file: &File, path: &Path) -> bool {
+    path.exists()
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use std::sync::mpsc;
+    use std::thread;
+    use std::time::Duration;
+
+    fn repository(dir: &Path) -> Repository {
+        let mut repo = Repository::new(
+            "api".to_string(),
+            "git@github.com:owner/api.git".to_string(),
+        );
+        repo.path = Some(dir.join("api").to_string_lossy().to_string());
+        repo
+    }
+
+    #[test]
+    fn test_lock_path_sits_next_to_the_clone() {
+        assert_eq!(
+            lock_path(Path::new("/work/api")),
+            PathBuf::from("/work/.api.repos.lock")
+        );
+        assert_eq!(
+            lock_path(Path::new("api")),
+            PathBuf::from(".api.repos.lock")
+        );
+    }
+
+    #[test]
+    fn test_lock_mode_from_str() {
+        assert_eq!("skip".parse::<LockMode>().unwrap(), LockMode::Skip);
+        assert_eq!("wait".parse::<LockMode>().unwrap(), LockMode::Wait);
+        assert!("block".parse::<LockMode>().is_err());
+    }
+
+    #[test]
+    fn test_held_lock_skips_second_locker() {
+        let temp = tempfile::tempdir().unwrap();
+        let repo = repository(temp.path());
+
+        let held = lock_repository(&repo, LockMode::Skip).unwrap().unwrap();
+        assert_eq!(held.path(), temp.path().join(".api.repos.lock"));
+        assert!(lock_repository(&repo, LockMode::Skip).unwrap().is_none());
+
+        drop(held);
+        // Releasing the lock removes its file
+        assert!(!temp.path().join(".api.repos.lock").exists());
+        assert!(lock_repository(&repo, LockMode::Skip).unwrap().is_some());
+    }
+
+    #[test]
+    fn test_held_lock_blocks_waiting_locker_until_released() {
+        let temp = tempfile::tempdir().unwrap();
+        let repo = repository(temp.path());
+        let held = lock_repository(&repo, LockMode::Wait).unwrap().unwrap();
+
+        let (sender, receiver) = mpsc::channel();
+        let waiter = thread::spawn({
+            let repo = repo.clone();
+            move || {
+                let lock = lock_repository(&repo, LockMode::Wait).unwrap();
+                sender.send(lock.is_some()).unwrap();
+            }
+        });
+
+        assert!(receiver.recv_timeout(Duration::from_millis(200)).is_err());
+        drop(held);
+        assert!(receiver.recv_timeout(Duration::from_secs(5)).unwrap());
+        waiter.join().unwrap();
+    }
+}
@@ -6,18 +6,60 @@
 //!
 //! - [`clone`]: Repository cloning and removal operations
 //!   - `clone_repository()` - Clone a repository from URL
+//!   - `clone_repository_with()` - Clone with options such as repairing incomplete clones or updating existing ones
+//!   - `clone_command_args()` - The `git clone` arguments, with a repository's `clone_args`
+//!   - `inspect_clone()` - Tell complete, incomplete, foreign and unreadable target directories apart
+//!   - `is_bare_clone()` - Whether a directory holds a bare mirror clone
 //!   - `remove_repository()` - Remove a cloned repository directory
 //!
+//! - [`config`]: Per-repository `git config` settings
+//!   - `apply_git_config()` - Write a repository's `git_config` entries to its clone
+//!   - `git_config_commands()` - The `git config` invocations for a repository
+//!   - `parse_git_config_entry()` - Parse a `key=value` entry from the command line
+//!
+//! - [`pull`]: Updating existing clones from their remote
+//!   - `pull_repository()` - Pull the current branch of a clone
+//!   - `pull_repository_with()` - Pull with options such as aborting on conflicts
+//!   - `is_mirror()` - Whether a clone is updated with `git remote update` as a mirror
+//!   - `unmerged_paths()` - Files left unmerged by a conflicting pull
+//!
 //! - [`pull_request`]: Git operations specific to pull request workflows
 //!   - `has_changes()` - Check for uncommitted changes
 //!   - `create_and_checkout_branch()` - Create and switch to new branch
 //!   - `add_all_changes()` - Stage all changes
+//!   - `staged_files()` - List the staged changes
 //!   - `commit_changes()` - Commit staged changes
+//!   - `commit_changes_as()` - Commit staged changes under a given author and committer
 //!   - `push_branch()` - Push branch to remote
+//!   - `push_branch_with_ssh_key()` - Push branch using a specific SSH key
 //!   - `get_default_branch()` - Get repository's default branch
+//!   - `changed_files()` - List the files with uncommitted changes
+//!
+//! - [`branches`]: Finding and deleting branches already merged into the default branch
+//!   - `merge_target()` - The ref branches must be merged into
+//!   - `merged_local_branches()` - Merged local branches, minus current and protected ones
+//!   - `merged_remote_branches()` - Merged branches on `origin`
+//!   - `delete_local_branch()` / `delete_remote_branch()` - Delete a merged branch
+//!
+//! - [`lock`]: Advisory locks keeping concurrent `repos` processes apart
+//!   - `lock_repository()` - Take a repository's lock, skipping or waiting if held
+//!   - `lock_path()` - The lock file next to a repository directory
+//!   - `RepositoryLocked` - Error for a repository skipped because it was locked
+//!
+//! - [`history`]: Read-only commit history queries
+//!   - `last_commit_date()` - Committer date of the latest commit
+//!   - `is_detached_head()` - Whether `HEAD` is detached from any branch
+//!
+//! - [`info`]: Live facts about a clone for `repos info`
+//!   - `repository_facts()` - Current branch, latest commit and `origin` URL
 //!
 //! - [`common`]: Shared utilities and helpers
 //!   - `Logger` - Consistent logging for git operations
+//!   - `git_command()` - Build a git process, optionally bound to an SSH key, that
+//!     fails instead of prompting for credentials unless `set_interactive_auth()` allows it
+//!   - `TraceCommand` - Log each command line before it runs with `--verbose`
+//!   - `output_until_deadline()` - Run a clone or pull, stopped at the `--deadline`
+//!     given to `set_deadline()`
 //!
 //! ## Benefits of this organization
 //!
@@ -26,14 +68,40 @@
 //! - **Maintainability**: Clear separation of concerns between different git operations
 //! - **Backward compatibility**: All functions are re-exported at the module level
 
+pub mod branches;
 pub mod clone;
 pub mod common;
+pub mod config;
+pub mod history;
+pub mod info;
+pub mod lock;
+pub mod pull;
 pub mod pull_request;
 
 // Re-export all public functions to maintain backward compatibility
-pub use clone::{clone_repository, remove_repository};
-pub use common::Logger;
+pub use branches::{
+    PROTECTED_BRANCHES, delete_local_branch, delete_remote_branch, merge_target,
+    merged_local_branches, merged_remote_branches,
+};
+pub use clone::{
+    CloneOptions, CloneOutcome, CloneState, check_clone_arg, clone_command_args, clone_repository,
+    clone_repository_with, inspect_clone, is_bare_clone, remove_repository,
+};
+pub use common::{
+    Logger, TraceCommand, describe_command, git_command, git_error, is_interactive_auth,
+    is_verbose, output_until_deadline, proxy_env, set_deadline, set_interactive_auth, set_verbose,
+    ssh_command,
+};
+pub use config::{apply_git_config, git_config_commands, parse_git_config_entry};
+pub use history::{is_detached_head, last_commit_date};
+pub use info::{CommitSummary, RepoFacts, repository_facts};
+pub use lock::{LockMode, RepoLock, RepositoryLocked, lock_path, lock_repository};
+pub use pull::{
+    MIRROR_UPDATE_ARGS, MergeConflict, PullOptions, is_mirror, pull_args, pull_repository,
+    pull_repository_with, unmerged_paths,
+};
 pub use pull_request::{
-    add_all_changes, checkout_branch, commit_changes, create_and_checkout_branch,
-    get_current_branch, get_default_branch, has_changes, push_branch,
+    CommitIdentity, add_all_changes, changed_files, checkout_branch, commit_changes,
+    commit_changes_as, create_and_checkout_branch, get_current_branch, get_default_branch,
+    has_changes, push_branch, push_branch_with_ssh_key, staged_files,
 };
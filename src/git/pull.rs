@@ -0,0 +1,285 @@
+//! Updating existing clones from their remote
+//!
+//! A pull that stops on conflicting changes is reported as a typed
+//! [`MergeConflict`] listing the unmerged paths, instead of git's raw
+//! output, so callers can tell conflicts apart from other failures.
+//!
+//! ## Functions
+//!
+//! - [`pull_repository`]: Pull the current branch of a clone
+//! - [`pull_repository_with`]: Pull with [`PullOptions`] (e.g. aborting on conflicts)
+//! - [`pull_args`]: The `git pull` arguments for a [`PullStrategy`]
+//! - [`is_mirror`]: Whether a clone is updated as a bare mirror
+//!
+//! Mirrors (see [`super::clone::is_bare_clone`]) have no working tree to
+//! merge into, so they are updated with `git remote update --prune` instead.
+//! - [`unmerged_paths`]: Files `git status` reports as unmerged
+
+use crate::config::{PullStrategy, Repository};
+use anyhow::{Context, Result};
+use std::fmt;
+use std::path::Path;
+
+use super::clone::is_bare_clone;
+use super::common::{Logger, TraceCommand, git_command, git_error, output_until_deadline};
+use super::lock::{LockMode, RepositoryLocked, lock_repository};
+
+/// Options controlling [`pull_repository_with`]
+#[derive(Debug, Clone, Copy, Default, PartialEq, Eq)]
+pub struct PullOptions {
+    /// Abort the merge (or rebase) when the pull stops on conflicts
+    pub abort_on_conflict: bool,
+    /// How upstream changes are integrated; git's own `pull.rebase`/`pull.ff`
+    /// settings apply when unset
+    pub strategy: Option<PullStrategy>,
+    /// Take the repository's lock first, skipping or waiting while another
+    /// process holds it
+    pub lock: Option<LockMode>,
+}
+
+impl PullOptions {
+    /// These options with the repository's `pull_strategy`, when set, taking precedence
+    pub fn for_repository(&self, repo: &Repository) -> Self {
+        Self {
+            strategy: repo.pull_strategy.or(self.strategy),
+            ..*self
+        }
+    }
+}
+
+/// A pull stopped because of conflicting changes
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct MergeConflict {
+    /// Paths left unmerged by the pull
+    pub files: Vec<String>,
+    /// Whether the merge was aborted, restoring the pre-pull state
+    pub aborted: bool,
+}
+
+impl fmt::Display for MergeConflict {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        write!(
+            f,
+            "Merge conflict in {} files: {}",
+            self.files.len(),
+            self.files.join(", ")
+        )?;
+        if self.aborted {
+            write!(f, " (merge aborted)")?;
+        }
+        Ok(())
+    }
+}
+
+impl std::error::Error for MergeConflict {}
+
+/// Pull the current branch of a repository's clone
+pub fn pull_repository(repo: &Repository) -> Result<()> {
+    pull_repository_with(repo, &PullOptions::default())
+}
+
+/// Pull the current branch of a repository's clone according to `options`
+///
+/// # Errors
+/// Returns a [`MergeConflict`] (reachable with `downcast_ref`) when the pull
+/// leaves unmerged paths, a [`RepositoryLocked`] when another process holds
+/// the lock under [`LockMode::Skip`], and a plain error for any other failure
+pub fn pull_repository_with(repo: &Repository, options: &PullOptions) -> Result<()> {
+    let logger = Logger;
+    let target_dir = repo.get_target_dir();
+    let _lock = match options.lock {
+        Some(mode) => match lock_repository(repo, mode)? {
+            Some(lock) => Some(lock),
+            None => return Err(RepositoryLocked.into()),
+        },
+        None => None,
+    };
+
+    if is_mirror(repo) {
+        return update_mirror(repo);
+    }
+
+    let strategy = options
+        .strategy
+        .map(|strategy| format!(" ({strategy})"))
+        .unwrap_or_default();
+    logger.info(repo, &format!("Pulling latest changes{strategy}"));
+    let output = output_until_deadline(
+        git_command(repo.ssh_key.as_deref())
+            .arg("-C")
+            .arg(&target_dir)
+            .args(pull_args(options.strategy))
+            .traced(&repo.name),
+        "git pull",
+    )?;
+
+    if output.status.success() {
+        logger.success(repo, &format!("Successfully pulled{strategy}"));
+        return Ok(());
+    }
+
+    let files = unmerged_paths(Path::new(&target_dir))?;
+    if files.is_empty() {
+        let stderr = String::from_utf8_lossy(&output.stderr);
+        anyhow::bail!("Failed to pull repository: {}", git_error(&stderr));
+    }
+
+    let aborted = options.abort_on_conflict && abort_pull(Path::new(&target_dir))?;
+    Err(MergeConflict { files, aborted }.into())
+}
+
+/// The arguments `pull` runs in a mirror instead of `git pull`
+pub const MIRROR_UPDATE_ARGS: [&str; 3] = ["remote", "update", "--prune"];
+
+/// Whether `repo` is updated as a mirror: marked `mirror`, or cloned bare
+pub fn is_mirror(repo: &Repository) -> bool {
+    repo.mirror || is_bare_clone(Path::new(&repo.get_target_dir()))
+}
+
+/// Fetch every ref of a mirror, dropping those deleted upstream
+fn update_mirror(repo: &Repository) -> Result<()> {
+    let logger = Logger;
+    logger.info(repo, "Updating mirror");
+    let output = output_until_deadline(
+        git_command(repo.ssh_key.as_deref())
+            .arg("-C")
+            .arg(repo.get_target_dir())
+            .args(MIRROR_UPDATE_ARGS)
+            .traced(&repo.name),
+        "git remote update",
+    )?;
+
+    if !output.status.success() {
+        let stderr = String::from_utf8_lossy(&output.stderr);
+        anyhow::bail!("Failed to update mirror: {}", git_error(&stderr));
+    }
+    logger.success(repo, "Successfully updated mirror");
+    Ok(())
+}
+
+/// The `git pull` arguments for `strategy`; plain `pull` when unset
+pub fn pull_args(strategy: Option<PullStrategy>) -> Vec<&'static str> {
+    std::iter::once("pull")
+        .chain(strategy.map(PullStrategy::git_arg))
+        .collect()
+}
+
+/// Paths that `git status` reports as unmerged in a working tree
+pub fn unmerged_paths(target_dir: &Path) -> Result<Vec<String>> {
+    let output = git_command(None)
+        .arg("-C")
+        .arg(target_dir)
+        .args(["status", "--porcelain"])
+        .traced(&target_dir.to_string_lossy())
+        .output()
+        .context("Failed to execute git status command")?;
+
+    if !output.status.success() {
+        let stderr = String::from_utf8_lossy(&output.stderr);
+        anyhow::bail!("Failed to read repository status: {}", stderr.trim());
+    }
+
+    Ok(parse_unmerged_paths(&String::from_utf8_lossy(
+        &output.stdout,
+    )))
+}
+
+/// Extract the unmerged entries (`DD`, `AU`, `UD`, `UA`, `DU`, `AA`, `UU`) of
+/// `git status --porcelain` output
+fn parse_unmerged_paths(status: &str) -> Vec<String> {
+    status
+        .lines()
+        .filter_map(|line| {
+            let (code, path) = (line.get(..2)?, line.get(3..)?);
+            matches!(code, "DD" | "AU" | "UD" | "UA" | "DU" | "AA" | "UU").then(|| path.to_string())
+        })
+        .collect()
+}
+
+/// Abort the merge or rebase a conflicting pull left in progress
+///
+/// Returns whether the abort succeeded.
+fn abort_pull(target_dir: &Path) -> Result<bool> {
+    let git_dir = target_dir.join(".git");
+    let operation =
+        if git_dir.join("rebase-merge").exists() || git_dir.join("rebase-apply").exists() {
+            "rebase"
+        } else {
+            "merge"
+        };
+
+    let status = git_command(None)
+        .arg("-C")
+        .arg(target_dir)
+        .args([operation, "--abort"])
+        .traced(&target_dir.to_string_lossy())
+        .output()
+        .with_context(|| format!("Failed to execute git {} --abort", operation))?
+        .status;
+    Ok(status.success())
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_parse_unmerged_paths() {
+        let status = "UU src/lib.rs\nM  README.md\nAA docs/new.md\n?? scratch.txt\nDU old.rs\n";
+        assert_eq!(
+            parse_unmerged_paths(status),
+            vec!["src/lib.rs", "docs/new.md", "old.rs"]
+        );
+        assert!(parse_unmerged_paths("").is_empty());
+    }
+
+    #[test]
+    fn test_pull_args_per_strategy() {
+        assert_eq!(pull_args(None), vec!["pull"]);
+        assert_eq!(
+            pull_args(Some(PullStrategy::FfOnly)),
+            vec!["pull", "--ff-only"]
+        );
+        assert_eq!(
+            pull_args(Some(PullStrategy::Rebase)),
+            vec!["pull", "--rebase"]
+        );
+        assert_eq!(
+            pull_args(Some(PullStrategy::Merge)),
+            vec!["pull", "--no-rebase"]
+        );
+    }
+
+    #[test]
+    fn test_repository_strategy_overrides_options() {
+        let mut repo = Repository::new("api".to_string(), "git@github.com:o/api.git".to_string());
+        let options = PullOptions {
+            abort_on_conflict: true,
+            strategy: Some(PullStrategy::Rebase),
+        };
+        assert_eq!(options.for_repository(&repo), options);
+
+        repo.pull_strategy = Some(PullStrategy::FfOnly);
+        let resolved = options.for_repository(&repo);
+        assert_eq!(resolved.strategy, Some(PullStrategy::FfOnly));
+        assert!(resolved.abort_on_conflict);
+    }
+
+    #[test]
+    fn test_merge_conflict_display() {
+        let conflict = MergeConflict {
+            files: vec!["a.txt".to_string(), "b.txt".to_string()],
+            aborted: false,
+        };
+        assert_eq!(
+            conflict.to_string(),
+            "Merge conflict in 2 files: a.txt, b.txt"
+        );
+
+        let aborted = MergeConflict {
+            aborted: true,
+            ..conflict
+        };
+        assert!(aborted.to_string().ends_with("(merge aborted)"));
+    }
+}
@@ -8,24 +8,30 @@
 //!
 //! 1. [`has_changes`] - Check if repository has uncommitted changes
 //! 2. [`create_and_checkout_branch`] - Create and switch to a new branch
-//! 3. [`add_all_changes`] - Stage all changes for commit
+//! 3. [`add_all_changes`] - Stage all changes for commit, unless only the
+//!    already staged changes ([`staged_files`]) are to be committed
 //! 4. [`commit_changes`] - Commit the staged changes with a message
+//!    (or [`commit_changes_as`] to set the author and committer)
 //! 5. [`push_branch`] - Push the branch to the remote repository
+//!    (or [`push_branch_with_ssh_key`] to authenticate with a specific key)
 //!
 //! ## Additional Utilities
 //!
 //! - [`get_default_branch`] - Determine the repository's default branch
+//! - [`changed_files`] - List the files with uncommitted changes
+//! - [`staged_files`] - List the files with staged changes
 
+use super::common::{TraceCommand, git_command, git_error};
 use anyhow::{Context, Result};
-use std::process::Command;
 
 /// Check if a repository has uncommitted changes
 pub fn has_changes(repo_path: &str) -> Result<bool> {
     // Check if there are any uncommitted changes using git status
-    let output = Command::new("git")
+    let output = git_command(None)
         .arg("status")
         .arg("--porcelain")
         .current_dir(repo_path)
+        .traced(repo_path)
         .output()
         .context("Failed to execute git status command")?;
 
@@ -40,14 +46,80 @@ pub fn has_changes(repo_path: &str) -> Result<bool> {
     Ok(!output.stdout.is_empty())
 }
 
+/// Paths with uncommitted changes, including each file of untracked directories
+///
+/// Renamed files are listed under their new path.
+pub fn changed_files(repo_path: &str) -> Result<Vec<String>> {
+    let output = git_command(None)
+        .args(["status", "--porcelain", "-z", "--untracked-files=all"])
+        .current_dir(repo_path)
+        .traced(repo_path)
+        .output()
+        .context("Failed to execute git status command")?;
+
+    if !output.status.success() {
+        anyhow::bail!(
+            "Failed to check repository status: {}",
+            String::from_utf8_lossy(&output.stderr)
+        );
+    }
+
+    Ok(parse_status_paths(&String::from_utf8_lossy(&output.stdout)))
+}
+
+/// Paths with changes staged for the next commit
+///
+/// Renamed files are listed under their new path.
+pub fn staged_files(repo_path: &str) -> Result<Vec<String>> {
+    let output = git_command(None)
+        .args(["diff", "--cached", "--name-only", "-z"])
+        .current_dir(repo_path)
+        .traced(repo_path)
+        .output()
+        .context("Failed to execute git diff command")?;
+
+    if !output.status.success() {
+        anyhow::bail!(
+            "Failed to list staged changes: {}",
+            String::from_utf8_lossy(&output.stderr)
+        );
+    }
+
+    Ok(String::from_utf8_lossy(&output.stdout)
+        .split('\0')
+        .filter(|path| !path.is_empty())
+        .map(str::to_string)
+        .collect())
+}
+
+/// Extract the paths of `git status --porcelain -z` output
+///
+/// Entries are `XY path`, NUL-terminated; a rename or copy entry is followed
+/// by an extra entry holding the original path, which is skipped.
+fn parse_status_paths(status: &str) -> Vec<String> {
+    let mut paths = Vec::new();
+    let mut entries = status.split('\0').filter(|entry| !entry.is_empty());
+    while let Some(entry) = entries.next() {
+        let (Some(code), Some(path)) = (entry.get(..2), entry.get(3..)) else {
+            continue;
+        };
+        if code.contains(['R', 'C']) {
+            entries.next();
+        }
+        paths.push(path.to_string());
+    }
+    paths
+}
+
 /// Create and checkout a new branch
 pub fn create_and_checkout_branch(repo_path: &str, branch_name: &str) -> Result<()> {
     // Create and checkout a new branch using git checkout -b
-    let output = Command::new("git")
+    let output = git_command(None)
         .arg("checkout")
         .arg("-b")
         .arg(branch_name)
         .current_dir(repo_path)
+        .traced(repo_path)
         .output()
         .context("Failed to execute git checkout command")?;
 
@@ -65,10 +137,11 @@ pub fn create_and_checkout_branch(repo_path: &str, branch_name: &str) -> Result<
 /// Add all changes to the staging area
 pub fn add_all_changes(repo_path: &str) -> Result<()> {
     // Add all changes using git add .
-    let output = Command::new("git")
+    let output = git_command(None)
         .arg("add")
         .arg(".")
         .current_dir(repo_path)
+        .traced(repo_path)
         .output()
         .context("Failed to execute git add command")?;
 
@@ -82,14 +155,45 @@ pub fn add_all_changes(repo_path: &str) -> Result<()> {
     Ok(())
 }
 
+/// Name and email that automated commits are made under
+///
+/// Each part left unset comes from git's own configuration, as for any
+/// other commit.
+#[derive(Debug, Clone, Default, PartialEq, Eq)]
+pub struct CommitIdentity {
+    pub name: Option<String>,
+    pub email: Option<String>,
+}
+
+impl CommitIdentity {
+    /// `-c user.name=...` / `-c user.email=...` options placed before the git
+    /// subcommand, setting both the author and the committer
+    pub fn config_args(&self) -> Vec<String> {
+        let mut args = Vec::new();
+        if let Some(name) = &self.name {
+            args.extend(["-c".to_string(), format!("user.name={}", name)]);
+        }
+        if let Some(email) = &self.email {
+            args.extend(["-c".to_string(), format!("user.email={}", email)]);
+        }
+        args
+    }
+}
+
 /// Commit staged changes with a message
 pub fn commit_changes(repo_path: &str, message: &str) -> Result<()> {
-    // Commit changes using git commit
-    let output = Command::new("git")
+    commit_changes_as(repo_path, message, &CommitIdentity::default())
+}
+
+/// Commit staged changes with a message, authored and committed as `identity`
+pub fn commit_changes_as(repo_path: &str, message: &str, identity: &CommitIdentity) -> Result<()> {
+    let output = git_command(None)
+        .args(identity.config_args())
         .arg("commit")
         .arg("-m")
         .arg(message)
         .current_dir(repo_path)
+        .traced(repo_path)
         .output()
         .context("Failed to execute git commit command")?;
 
@@ -105,13 +209,23 @@ pub fn commit_changes(repo_path: &str, message: &str) -> Result<()> {
 
 /// Push a branch to remote and set upstream
 pub fn push_branch(repo_path: &str, branch_name: &str) -> Result<()> {
+    push_branch_with_ssh_key(repo_path, branch_name, None)
+}
+
+/// Push a branch to remote and set upstream, authenticating with an SSH key if given
+pub fn push_branch_with_ssh_key(
+    repo_path: &str,
+    branch_name: &str,
+    ssh_key: Option<&str>,
+) -> Result<()> {
     // Push branch using git push
-    let output = Command::new("git")
+    let output = git_command(ssh_key)
         .arg("push")
         .arg("--set-upstream")
         .arg("origin")
         .arg(branch_name)
         .current_dir(repo_path)
+        .traced(repo_path)
         .output()
         .context("Failed to execute git push command")?;
 
@@ -121,7 +235,7 @@ pub fn push_branch(repo_path: &str, branch_name: &str) -> Result<()> {
         anyhow::bail!(
             "Failed to push branch '{}' to remote 'origin':\nstderr: {}\nstdout: {}",
             branch_name,
-            stderr.trim(),
+            git_error(&stderr),
             stdout.trim()
         );
     }
@@ -132,9 +246,10 @@ pub fn push_branch(repo_path: &str, branch_name: &str) -> Result<()> {
 /// Get the default branch of a repository
 pub fn get_default_branch(repo_path: &str) -> Result<String> {
     // Try to get the default branch using git symbolic-ref
-    let output = Command::new("git")
+    let output = git_command(None)
         .args(["symbolic-ref", "refs/remotes/origin/HEAD"])
         .current_dir(repo_path)
+        .traced(repo_path)
         .output();
 
     if let Ok(output) = output
@@ -147,9 +262,10 @@ pub fn get_default_branch(repo_path: &str) -> Result<String> {
     }
 
     // Fallback: try to get the current branch
-    let output = Command::new("git")
+    let output = git_command(None)
         .args(["branch", "--show-current"])
         .current_dir(repo_path)
+        .traced(repo_path)
         .output()
         .context("Failed to execute git branch command")?;
 
@@ -166,9 +282,10 @@ pub fn get_default_branch(repo_path: &str) -> Result<String> {
 
 /// Get the current branch name
 pub fn get_current_branch(repo_path: &str) -> Result<String> {
-    let output = Command::new("git")
+    let output = git_command(None)
         .args(["branch", "--show-current"])
         .current_dir(repo_path)
+        .traced(repo_path)
         .output()
         .context("Failed to execute git branch command")?;
 
@@ -189,9 +306,10 @@ pub fn get_current_branch(repo_path: &str) -> Result<String> {
 
 /// Checkout an existing branch
 pub fn checkout_branch(repo_path: &str, branch_name: &str) -> Result<()> {
-    let output = Command::new("git")
+    let output = git_command(None)
         .args(["checkout", branch_name])
         .current_dir(repo_path)
+        .traced(repo_path)
         .output()
         .context("Failed to execute git checkout command")?;
 
@@ -205,3 +323,46 @@ pub fn checkout_branch(repo_path: &str, branch_name: &str) -> Result<()> {
 
     Ok(())
 }
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_parse_status_paths() {
+        let status =
+            " M src/lib.rs\0?? docs/new file.md\0R  src/new.rs\0src/old.rs\0A  README.md\0";
+        assert_eq!(
+            parse_status_paths(status),
+            vec!["src/lib.rs", "docs/new file.md", "src/new.rs", "README.md"]
+        );
+        assert!(parse_status_paths("").is_empty());
+    }
+
+    #[test]
+    fn test_commit_identity_config_args() {
+        let identity = CommitIdentity {
+            name: Some("Release Bot".to_string()),
+            email: Some("bot@acme.dev".to_string()),
+        };
+        assert_eq!(
+            identity.config_args(),
+            vec![
+                "-c",
+                "user.name=Release Bot",
+                "-c",
+                "user.email=bot@acme.dev"
+            ]
+        );
+
+        let email_only = CommitIdentity {
+            name: None,
+            email: Some("bot@acme.dev".to_string()),
+        };
+        assert_eq!(
+            email_only.config_args(),
+            vec!["-c", "user.email=bot@acme.dev"]
+        );
+        assert!(CommitIdentity::default().config_args().is_empty());
+    }
+}
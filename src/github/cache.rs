@@ -0,0 +1,123 @@
+//! On-disk cache for GitHub API responses
+//!
+//! Entries are keyed by a caller-chosen string and expire after
+//! [`CACHE_TTL_HOURS`], so repeated invocations do not hit the API for data
+//! that rarely changes.
+
+use anyhow::{Context, Result};
+use chrono::{DateTime, Duration, Utc};
+use serde::de::DeserializeOwned;
+use serde::{Deserialize, Serialize};
+use std::collections::BTreeMap;
+use std::path::{Path, PathBuf};
+
+/// How long cached responses are reused before being fetched again
+pub const CACHE_TTL_HOURS: i64 = 24;
+
+#[derive(Debug, Clone, Serialize, Deserialize)]
+struct CacheEntry<T> {
+    fetched_at: DateTime<Utc>,
+    #[serde(alias = "topics")]
+    value: T,
+}
+
+/// JSON file cache of API responses
+#[derive(Debug)]
+pub struct ApiCache<T> {
+    path: PathBuf,
+    entries: BTreeMap<String, CacheEntry<T>>,
+    dirty: bool,
+}
+
+impl<T: Serialize + DeserializeOwned> ApiCache<T> {
+    /// Open the cache at `path`, starting empty if it does not exist or is unreadable
+    pub fn open(path: impl Into<PathBuf>) -> Self {
+        let path = path.into();
+        let entries = std::fs::read_to_string(&path)
+            .ok()
+            .and_then(|content| serde_json::from_str(&content).ok())
+            .unwrap_or_default();
+        Self {
+            path,
+            entries,
+            dirty: false,
+        }
+    }
+
+    /// Location of the cache file
+    pub fn path(&self) -> &Path {
+        &self.path
+    }
+
+    /// Cached value for `key` if it was fetched within the TTL
+    pub fn get(&self, key: &str, now: DateTime<Utc>) -> Option<&T> {
+        self.entries
+            .get(key)
+            .filter(|entry| now - entry.fetched_at < Duration::hours(CACHE_TTL_HOURS))
+            .map(|entry| &entry.value)
+    }
+
+    pub fn insert(&mut self, key: String, value: T, now: DateTime<Utc>) {
+        self.entries.insert(
+            key,
+            CacheEntry {
+                fetched_at: now,
+                value,
+            },
+        );
+        self.dirty = true;
+    }
+
+    /// Write the cache back to disk if anything changed
+    pub fn save(&mut self) -> Result<()> {
+        if !self.dirty {
+            return Ok(());
+        }
+        if let Some(parent) = self.path.parent() {
+            std::fs::create_dir_all(parent).with_context(|| {
+                format!("Failed to create cache directory: {}", parent.display())
+            })?;
+        }
+        std::fs::write(&self.path, serde_json::to_string_pretty(&self.entries)?)
+            .with_context(|| format!("Failed to write cache: {}", self.path.display()))?;
+        self.dirty = false;
+        Ok(())
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use tempfile::TempDir;
+
+    #[test]
+    fn test_cache_expires_after_ttl() {
+        let temp_dir = TempDir::new().unwrap();
+        let mut cache: ApiCache<Vec<String>> = ApiCache::open(temp_dir.path().join("c.json"));
+        let fetched = Utc::now() - Duration::hours(CACHE_TTL_HOURS + 1);
+        cache.insert("acme/old".to_string(), vec!["rust".to_string()], fetched);
+        cache.insert("acme/new".to_string(), vec!["go".to_string()], Utc::now());
+
+        assert!(cache.get("acme/old", Utc::now()).is_none());
+        assert_eq!(
+            cache.get("acme/new", Utc::now()),
+            Some(&vec!["go".to_string()])
+        );
+    }
+
+    #[test]
+    fn test_save_round_trips_and_skips_when_clean() {
+        let temp_dir = TempDir::new().unwrap();
+        let path = temp_dir.path().join("nested").join("c.json");
+
+        let mut cache: ApiCache<u32> = ApiCache::open(&path);
+        cache.save().unwrap();
+        assert!(!path.exists());
+
+        cache.insert("answer".to_string(), 42, Utc::now());
+        cache.save().unwrap();
+
+        let reopened: ApiCache<u32> = ApiCache::open(&path);
+        assert_eq!(reopened.get("answer", Utc::now()), Some(&42));
+    }
+}
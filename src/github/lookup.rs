@@ -0,0 +1,155 @@
+//! Per-repository GitHub API lookups
+//!
+//! Sizes for `clone --prioritize-size` and branch protection for the health
+//! check are looked up the same way: every GitHub-hosted repository is asked
+//! for at its own API base URL, all repositories at once, with the API calls
+//! going through one [`ApiScheduler`] so that a rate-limited call backs off
+//! instead of failing the lookup.
+
+use super::ApiScheduler;
+use crate::config::{Defaults, Repository};
+use anyhow::Result;
+use futures::future::join_all;
+use repos_github::GitHubClient;
+use std::time::Duration;
+
+/// API base URL for a GitHub repository
+///
+/// The repository's `github_api_url` wins, then `api_url` (`--github-api-url`,
+/// `GITHUB_API_URL` or `defaults.github_api_url`). Otherwise it is derived
+/// from the repository's host when that is one of `enterprise_hosts`, and is
+/// `api.github.com` for any other host, so the token never goes to a host
+/// nobody configured.
+pub fn repository_api_url(
+    repo: &Repository,
+    api_url: Option<&str>,
+    enterprise_hosts: &[String],
+) -> String {
+    repo.github_api_url
+        .as_deref()
+        .or(api_url)
+        .map(str::to_string)
+        .unwrap_or_else(|| repos_github::api_base_url(&repo.url, enterprise_hosts))
+}
+
+/// Where to reach the GitHub API for each repository
+#[derive(Debug)]
+pub struct GitHubApi {
+    token: Option<String>,
+    api_url: Option<String>,
+    enterprise_hosts: Vec<String>,
+    scheduler: ApiScheduler,
+}
+
+impl GitHubApi {
+    /// Authenticate with `token`, or `GITHUB_TOKEN` when `None`, against
+    /// `api.github.com` for every repository without its own `github_api_url`
+    pub fn new(token: Option<String>) -> Self {
+        Self {
+            token,
+            api_url: None,
+            enterprise_hosts: Vec::new(),
+            scheduler: ApiScheduler::new(Duration::ZERO),
+        }
+    }
+
+    /// The API as configured for `pr`: `GITHUB_API_URL`, else
+    /// `defaults.github_api_url`, and `defaults.github_enterprise_hosts`
+    pub fn from_defaults(token: Option<String>, defaults: &Defaults) -> Self {
+        let api_url = std::env::var("GITHUB_API_URL")
+            .ok()
+            .filter(|url| !url.is_empty())
+            .or_else(|| defaults.github_api_url.clone());
+        Self::new(token)
+            .with_api_url(api_url)
+            .with_enterprise_hosts(defaults.github_enterprise_hosts.clone())
+    }
+
+    /// Use `api_url` for repositories without their own `github_api_url`
+    pub fn with_api_url(mut self, api_url: Option<String>) -> Self {
+        self.api_url = api_url;
+        self
+    }
+
+    /// Derive the API base URL from the host of repositories on `hosts`
+    pub fn with_enterprise_hosts(mut self, hosts: Vec<String>) -> Self {
+        self.enterprise_hosts = hosts;
+        self
+    }
+
+    /// Client for `repo`, pointed at its API base URL
+    pub fn client(&self, repo: &Repository) -> GitHubClient {
+        GitHubClient::new(self.token.clone()).with_base_url(repository_api_url(
+            repo,
+            self.api_url.as_deref(),
+            &self.enterprise_hosts,
+        ))
+    }
+
+    /// Run `lookup` for each of `repositories` with a client for its API
+    ///
+    /// Lookups run concurrently; `lookup` is run again when its API call is
+    /// rate limited. Results keep the order of `repositories`. Failures are
+    /// returned as `(name, error)` pairs so that one inaccessible repository
+    /// does not prevent looking up the rest.
+    pub async fn look_up<'a, T>(
+        &self,
+        repositories: impl IntoIterator<Item = &'a Repository>,
+        lookup: impl AsyncFn(&'a Repository, &GitHubClient) -> Result<T>,
+    ) -> (Vec<(&'a Repository, T)>, Vec<(String, anyhow::Error)>) {
+        let lookup = &lookup;
+        let lookups = repositories.into_iter().map(|repo| async move {
+            let client = self.client(repo);
+            (repo, self.scheduler.call(|| lookup(repo, &client)).await)
+        });
+
+        let mut found = Vec::new();
+        let mut failures = Vec::new();
+        for (repo, result) in join_all(lookups).await {
+            match result {
+                Ok(value) => found.push((repo, value)),
+                Err(e) => failures.push((repo.name.clone(), e)),
+            }
+        }
+        (found, failures)
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use repos_mock_server::{MockServer, Response};
+
+    fn repo(name: &str, url: &str) -> Repository {
+        Repository::new(name.to_string(), url.to_string())
+    }
+
+    #[tokio::test]
+    async fn test_lookups_go_to_each_repository_api_url() {
+        let server = MockServer::start(vec![Response::new(
+            "200 OK",
+            r#"{"topics": [], "size": 7}"#,
+        )]);
+        let mut own = repo("own", "git@github.com:acme/own.git");
+        own.github_api_url = Some(server.url.clone());
+        // Nothing listens on the discard port
+        let api = GitHubApi::new(Some("token".to_string()))
+            .with_api_url(Some("http://127.0.0.1:9".to_string()));
+        let repos = vec![own, repo("shared", "git@github.com:acme/shared.git")];
+
+        let (found, failures) = api
+            .look_up(&repos, async |repo, client| {
+                client
+                    .get_repository_details("acme", &repo.name)
+                    .await
+                    .map(|details| details.size)
+            })
+            .await;
+        assert_eq!(found.len(), 1);
+        assert_eq!(found[0].0.name, "own");
+        assert_eq!(found[0].1, Some(7));
+        assert_eq!(failures.len(), 1);
+        assert_eq!(failures[0].0, "shared");
+        assert_eq!(server.request_lines(), vec!["GET /repos/acme/own HTTP/1.1"]);
+    }
+}
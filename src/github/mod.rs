@@ -6,15 +6,41 @@
 //! ## Architecture
 //!
 //! - [`api`]: High-level workflow functions (e.g., create PR from workspace)
+//! - [`cache`]: On-disk cache for API responses
+//! - [`lookup`]: Per-repository API lookups at each repository's API base URL
+//! - [`scheduler`]: Spacing and rate-limit backoff for pull request API calls
+//! - [`orgs`]: Repository lists expanded from `orgs` config entries
+//! - [`protection`]: Default branch protection for the `branching` health check
+//! - [`sizes`]: Repository sizes for `clone --prioritize-size`
+//! - [`title`]: Per-repository pull request titles from a `--title` template
+//! - [`topics`]: Tag enrichment from repository topics (`--fetch-topics`)
 //! - [`types`]: Workflow-specific types like PrOptions
 //!
 //! For low-level GitHub API operations, see the `repos-github` crate.
 
 pub mod api;
+pub mod cache;
+pub mod lookup;
+pub mod orgs;
+pub mod protection;
+pub mod scheduler;
+pub mod sizes;
+pub mod title;
+pub mod topics;
 pub mod types;
 
 // Re-export commonly used items for convenience
-pub use api::create_pr_from_workspace;
+pub use api::{
+    create_pr_from_workspace, open_pull_request, prepare_pr_branch, warn_about_base_protection,
+};
+pub use lookup::{GitHubApi, repository_api_url};
+pub use orgs::{OrgCache, expand_orgs};
+pub use protection::default_branch_protection;
+pub use repos_github::GitHubClient;
+pub use scheduler::ApiScheduler;
+pub use sizes::{order_by_size, repository_sizes};
+pub use title::render_title;
+pub use topics::{TopicCache, enrich_with_topics};
 pub use types::PrOptions;
 
 // Re-export constants for easy access
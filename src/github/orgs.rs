@@ -0,0 +1,187 @@
+//! Repository lists expanded from `orgs` config entries
+//!
+//! Each entry lists an organization's repositories through the GitHub API,
+//! optionally narrowed to a topic. Listings are cached on disk so that loading
+//! the configuration does not page through the API on every invocation.
+
+use super::cache::ApiCache;
+use super::topics::merge_topics;
+use crate::config::{OrgSource, Repository};
+use anyhow::anyhow;
+use chrono::Utc;
+use repos_github::{GitHubClient, OrgRepository};
+
+/// On-disk cache of organization listings keyed by `org?type=<visibility>`
+pub type OrgCache = ApiCache<Vec<OrgRepository>>;
+
+/// Build the repositories described by `source` from an organization listing
+pub fn repositories_from_listing(source: &OrgSource, listing: &[OrgRepository]) -> Vec<Repository> {
+    listing
+        .iter()
+        .filter(|repo| {
+            source
+                .topic
+                .as_ref()
+                .is_none_or(|topic| repo.topics.contains(topic))
+        })
+        .map(|listed| {
+            let mut repo = Repository::new(listed.name.clone(), listed.ssh_url.clone());
+            repo.tags = source.tags.clone();
+            repo.archived = listed.archived;
+            merge_topics(&mut repo, &listed.topics);
+            repo
+        })
+        .collect()
+}
+
+/// Expand every org entry into repositories
+///
+/// Listings come from `cache` when fresh and from the API otherwise; without a
+/// `client` only cached listings can be used. Failures are returned per
+/// organization as `(org, error)` pairs so one inaccessible organization does
+/// not prevent loading the rest.
+pub async fn expand_orgs(
+    sources: &[OrgSource],
+    client: Option<&GitHubClient>,
+    cache: &mut OrgCache,
+) -> (Vec<Repository>, Vec<(String, anyhow::Error)>) {
+    let now = Utc::now();
+    let mut repositories = Vec::new();
+    let mut failures = Vec::new();
+
+    for source in sources {
+        let key = format!("{}?type={}", source.org, source.visibility.as_str());
+
+        if cache.get(&key, now).is_none() {
+            let Some(client) = client else {
+                failures.push((
+                    source.org.clone(),
+                    anyhow!("listing organization repositories requires a GitHub token: set GITHUB_TOKEN"),
+                ));
+                continue;
+            };
+            match client
+                .list_org_repositories(&source.org, source.visibility.as_str())
+                .await
+            {
+                Ok(listing) => cache.insert(key.clone(), listing, now),
+                Err(e) => {
+                    failures.push((source.org.clone(), e));
+                    continue;
+                }
+            }
+        }
+
+        if let Some(listing) = cache.get(&key, now) {
+            repositories.extend(repositories_from_listing(source, listing));
+        }
+    }
+
+    (repositories, failures)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::config::Visibility;
+    use repos_mock_server::{MockServer, Response};
+    use tempfile::TempDir;
+
+    fn source(org: &str, topic: Option<&str>) -> OrgSource {
+        OrgSource {
+            org: org.to_string(),
+            topic: topic.map(str::to_string),
+            visibility: Visibility::All,
+            tags: vec!["org".to_string()],
+        }
+    }
+
+    fn listed(name: &str, topics: &[&str]) -> OrgRepository {
+        OrgRepository {
+            name: name.to_string(),
+            clone_url: format!("https://github.com/acme/{}.git", name),
+            ssh_url: format!("git@github.com:acme/{}.git", name),
+            topics: topics.iter().map(|t| t.to_string()).collect(),
+            archived: false,
+            visibility: None,
+        }
+    }
+
+    #[test]
+    fn test_repositories_from_listing_filters_by_topic() {
+        let listing = vec![listed("api", &["service"]), listed("docs", &[])];
+
+        let all = repositories_from_listing(&source("acme", None), &listing);
+        assert_eq!(all.len(), 2);
+        assert_eq!(all[0].url, "git@github.com:acme/api.git");
+        assert_eq!(all[0].tags, vec!["org", "gh:service"]);
+
+        let services = repositories_from_listing(&source("acme", Some("service")), &listing);
+        assert_eq!(services.len(), 1);
+        assert_eq!(services[0].name, "api");
+    }
+
+    #[test]
+    fn test_repositories_from_listing_carries_archived_status() {
+        let mut retired = listed("legacy", &[]);
+        retired.archived = true;
+        let listing = vec![listed("api", &[]), retired];
+
+        let repos = repositories_from_listing(&source("acme", None), &listing);
+        assert!(!repos[0].archived);
+        assert!(repos[1].archived);
+    }
+
+    #[tokio::test]
+    async fn test_expand_orgs_pages_through_listing_and_caches_it() {
+        let temp_dir = TempDir::new().unwrap();
+        let cache_path = temp_dir.path().join("orgs.json");
+        // Two linked pages, served once
+        let server = MockServer::start_with(|base_url| {
+            vec![
+                Response::new(
+                    "200 OK",
+                    r#"[{"name": "api", "clone_url": "https://github.com/acme/api.git", "ssh_url": "git@github.com:acme/api.git", "topics": ["service"]}]"#,
+                )
+                .with_header(format!(
+                    "Link: <{}/orgs/acme/repos?page=2>; rel=\"next\"",
+                    base_url
+                )),
+                Response::new(
+                    "200 OK",
+                    r#"[{"name": "docs", "clone_url": "https://github.com/acme/docs.git", "ssh_url": "git@github.com:acme/docs.git", "topics": []}]"#,
+                ),
+            ]
+        });
+        let client = GitHubClient::new(Some("token".to_string())).with_base_url(&server.url);
+
+        let mut cache = OrgCache::open(&cache_path);
+        let (repos, failures) =
+            expand_orgs(&[source("acme", None)], Some(&client), &mut cache).await;
+        assert!(failures.is_empty());
+        cache.save().unwrap();
+        let names: Vec<&str> = repos.iter().map(|r| r.name.as_str()).collect();
+        assert_eq!(names, vec!["api", "docs"]);
+
+        // The server is gone: a second expansion must be served from the cache,
+        // even without a token
+        let mut cache = OrgCache::open(&cache_path);
+        let (repos, failures) =
+            expand_orgs(&[source("acme", Some("service"))], None, &mut cache).await;
+        assert!(failures.is_empty());
+        assert_eq!(repos.len(), 1);
+        assert_eq!(repos[0].name, "api");
+    }
+
+    #[tokio::test]
+    async fn test_expand_orgs_without_token_or_cache_fails() {
+        let temp_dir = TempDir::new().unwrap();
+        let mut cache = OrgCache::open(temp_dir.path().join("orgs.json"));
+
+        let (repos, failures) = expand_orgs(&[source("acme", None)], None, &mut cache).await;
+        assert!(repos.is_empty());
+        assert_eq!(failures.len(), 1);
+        assert_eq!(failures[0].0, "acme");
+        assert!(failures[0].1.to_string().contains("GITHUB_TOKEN"));
+    }
+}
@@ -0,0 +1,126 @@
+//! Default branch protection for the `branching` health check
+//!
+//! The health checkers only read the working tree, so protection is looked up
+//! up front and handed to [`BranchingChecker`](crate::health::BranchingChecker)
+//! keyed by clone path.
+
+use super::GitHubApi;
+use crate::config::{Provider, Repository};
+use crate::git;
+use repos_github::parse_github_url;
+use std::collections::BTreeMap;
+use std::path::PathBuf;
+
+/// Whether the default branch of each cloned GitHub repository is protected
+///
+/// The default branch is read from the clone, as the checker does.
+/// Repositories that are not cloned or not hosted on GitHub are left out; the
+/// others are looked up concurrently, each at its own API base URL. Failures
+/// for individual repositories are returned as `(name, error)` pairs so that
+/// one inaccessible repository does not prevent checking the rest.
+pub async fn default_branch_protection(
+    repositories: &[Repository],
+    api: &GitHubApi,
+) -> (BTreeMap<PathBuf, bool>, Vec<(String, anyhow::Error)>) {
+    let cloned = repositories
+        .iter()
+        .filter(|repo| repo.provider() == Provider::GitHub && repo.exists());
+    let (rules, failures) = api
+        .look_up(cloned, async |repo, client| {
+            let (owner, name) = parse_github_url(&repo.url)?;
+            let branch = git::get_default_branch(&repo.get_target_dir())?;
+            client.get_branch_protection(&owner, &name, &branch).await
+        })
+        .await;
+
+    let protection = rules
+        .into_iter()
+        .map(|(repo, rules)| (PathBuf::from(repo.get_target_dir()), rules.is_some()))
+        .collect();
+    (protection, failures)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use repos_mock_server::{MockServer, Response};
+    use tempfile::TempDir;
+
+    /// A repository cloned under `temp_dir` whose default branch is `branch`
+    fn cloned(temp_dir: &TempDir, name: &str, url: &str, branch: &str) -> Repository {
+        let mut repo = Repository::new(name.to_string(), url.to_string());
+        repo.set_config_dir(Some(temp_dir.path().to_path_buf()));
+        let target_dir = temp_dir.path().join(name);
+        std::fs::create_dir_all(&target_dir).unwrap();
+        let status = std::process::Command::new("git")
+            .args(["init", "--quiet", "--initial-branch", branch])
+            .current_dir(&target_dir)
+            .status()
+            .unwrap();
+        assert!(status.success());
+        repo
+    }
+
+    #[tokio::test]
+    async fn test_protection_is_looked_up_for_cloned_github_repositories() {
+        let temp_dir = TempDir::new().unwrap();
+        let server = MockServer::start(vec![
+            Response::new("200 OK", r#"{"required_pull_request_reviews": {}}"#),
+            Response::new("404 Not Found", r#"{"message": "Branch not protected"}"#),
+        ]);
+        let api = GitHubApi::new(Some("token".to_string())).with_api_url(Some(server.url.clone()));
+
+        let mut missing = Repository::new(
+            "missing".to_string(),
+            "git@github.com:acme/missing.git".to_string(),
+        );
+        missing.set_config_dir(Some(temp_dir.path().to_path_buf()));
+        let repos = vec![
+            cloned(&temp_dir, "api", "git@github.com:acme/api.git", "trunk"),
+            cloned(&temp_dir, "web", "https://github.com/acme/web.git", "main"),
+            cloned(
+                &temp_dir,
+                "mirror",
+                "https://bitbucket.org/acme/mirror.git",
+                "main",
+            ),
+            missing,
+        ];
+
+        let (protection, failures) = default_branch_protection(&repos, &api).await;
+        assert!(failures.is_empty());
+        assert_eq!(
+            protection,
+            BTreeMap::from([
+                (temp_dir.path().join("api"), true),
+                (temp_dir.path().join("web"), false),
+            ])
+        );
+        assert_eq!(
+            server.request_lines(),
+            vec![
+                "GET /repos/acme/api/branches/trunk/protection HTTP/1.1",
+                "GET /repos/acme/web/branches/main/protection HTTP/1.1",
+            ]
+        );
+    }
+
+    #[tokio::test]
+    async fn test_protection_failures_are_reported_per_repository() {
+        let temp_dir = TempDir::new().unwrap();
+        // Nothing listens on the discard port
+        let api = GitHubApi::new(Some("token".to_string()))
+            .with_api_url(Some("http://127.0.0.1:9".to_string()));
+
+        let repos = vec![cloned(
+            &temp_dir,
+            "api",
+            "git@github.com:acme/api.git",
+            "main",
+        )];
+        let (protection, failures) = default_branch_protection(&repos, &api).await;
+        assert!(protection.is_empty());
+        assert_eq!(failures.len(), 1);
+        assert_eq!(failures[0].0, "api");
+    }
+}
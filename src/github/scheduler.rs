@@ -0,0 +1,195 @@
+//! Spacing out pull request API calls
+//!
+//! Hosting providers rate-limit content-creating requests, so `repos pr`
+//! sends its API calls through one [`ApiScheduler`]: calls run one at a time,
+//! at least `interval` apart, while the local git work (branch, commit, push)
+//! of other repositories carries on concurrently. A call rejected for rate
+//! limiting is retried after an exponentially growing backoff, holding back
+//! every other call meanwhile.
+
+use crate::constants::github::{RATE_LIMIT_BACKOFF_SECS, RATE_LIMIT_RETRIES};
+use anyhow::Result;
+use colored::*;
+use std::future::Future;
+use std::time::Duration;
+use tokio::sync::Mutex;
+use tokio::time::Instant;
+
+/// Serializes API calls and keeps a minimum gap between them
+#[derive(Debug)]
+pub struct ApiScheduler {
+    interval: Duration,
+    backoff: Duration,
+    retries: u32,
+    /// When the previous call finished; held for the duration of each call
+    last_call: Mutex<Option<Instant>>,
+}
+
+impl ApiScheduler {
+    /// Schedule calls at least `interval` apart, with the default rate-limit backoff
+    pub fn new(interval: Duration) -> Self {
+        Self {
+            interval,
+            backoff: Duration::from_secs(RATE_LIMIT_BACKOFF_SECS),
+            retries: RATE_LIMIT_RETRIES,
+            last_call: Mutex::new(None),
+        }
+    }
+
+    /// Retry rate-limited calls up to `retries` times, waiting `backoff`, then twice as long, ...
+    pub fn with_backoff(mut self, backoff: Duration, retries: u32) -> Self {
+        self.backoff = backoff;
+        self.retries = retries;
+        self
+    }
+
+    /// Run `call` once no other call is running and the interval has passed
+    ///
+    /// `call` is invoked again for each retry after a rate-limit error; other
+    /// errors and the final rate-limit error are returned as they are.
+    pub async fn call<T, F, Fut>(&self, mut call: F) -> Result<T>
+    where
+        F: FnMut() -> Fut,
+        Fut: Future<Output = Result<T>>,
+    {
+        let mut last_call = self.last_call.lock().await;
+        let mut attempt = 0;
+        loop {
+            if let Some(finished) = *last_call {
+                tokio::time::sleep_until(finished + self.interval).await;
+            }
+            let result = call().await;
+            *last_call = Some(Instant::now());
+
+            match result {
+                Err(e) if attempt < self.retries && is_rate_limited(&e) => {
+                    let delay = self.backoff * 2u32.pow(attempt);
+                    eprintln!(
+                        "{}",
+                        format!("Rate limited, retrying in {}s: {}", delay.as_secs(), e).yellow()
+                    );
+                    tokio::time::sleep(delay).await;
+                    attempt += 1;
+                }
+                result => return result,
+            }
+        }
+    }
+}
+
+/// Whether an API error reports a rate limit (429, or 403 with a rate-limit message)
+pub fn is_rate_limited(error: &anyhow::Error) -> bool {
+    let message = error.to_string();
+    message.contains("(429 ")
+        || (message.contains("(403 ") && message.to_lowercase().contains("rate limit"))
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use std::sync::Arc;
+    use std::sync::atomic::{AtomicU32, Ordering};
+
+    /// One repository's run: local work that may overlap, then a scheduled API call
+    async fn run_repository(
+        scheduler: Arc<ApiScheduler>,
+        local: Duration,
+    ) -> ((Instant, Instant), Instant) {
+        let local_start = Instant::now();
+        tokio::time::sleep(local).await;
+        let local_end = Instant::now();
+        let called = scheduler
+            .call(|| async { Ok(Instant::now()) })
+            .await
+            .unwrap();
+        ((local_start, local_end), called)
+    }
+
+    #[tokio::test]
+    async fn test_api_calls_are_spaced_while_local_steps_overlap() {
+        let scheduler = Arc::new(ApiScheduler::new(Duration::from_millis(50)));
+        let tasks: Vec<_> = (0..4)
+            .map(|_| {
+                tokio::spawn(run_repository(
+                    scheduler.clone(),
+                    Duration::from_millis(100),
+                ))
+            })
+            .collect();
+        let mut runs = Vec::new();
+        for task in tasks {
+            runs.push(task.await.unwrap());
+        }
+
+        // Every local step started before any of them finished
+        let first_local_end = runs.iter().map(|(local, _)| local.1).min().unwrap();
+        assert!(runs.iter().all(|(local, _)| local.0 < first_local_end));
+
+        let mut calls: Vec<Instant> = runs.iter().map(|(_, called)| *called).collect();
+        calls.sort();
+        for pair in calls.windows(2) {
+            assert!(pair[1] - pair[0] >= Duration::from_millis(50));
+        }
+    }
+
+    #[tokio::test]
+    async fn test_rate_limited_call_is_retried_with_backoff() {
+        let scheduler =
+            ApiScheduler::new(Duration::ZERO).with_backoff(Duration::from_millis(10), 3);
+        let attempts = AtomicU32::new(0);
+        let started = Instant::now();
+
+        let result = scheduler
+            .call(|| async {
+                match attempts.fetch_add(1, Ordering::SeqCst) {
+                    0 | 1 => anyhow::bail!(
+                        "Failed to create pull request (429 Too Many Requests): slow down"
+                    ),
+                    _ => Ok("created"),
+                }
+            })
+            .await;
+        assert_eq!(result.unwrap(), "created");
+        assert_eq!(attempts.load(Ordering::SeqCst), 3);
+        // 10ms, then 20ms
+        assert!(started.elapsed() >= Duration::from_millis(30));
+    }
+
+    #[tokio::test]
+    async fn test_other_errors_and_exhausted_retries_are_returned() {
+        let scheduler = ApiScheduler::new(Duration::ZERO).with_backoff(Duration::from_millis(1), 1);
+        let attempts = AtomicU32::new(0);
+
+        let result: Result<()> = scheduler
+            .call(|| async {
+                attempts.fetch_add(1, Ordering::SeqCst);
+                anyhow::bail!("Failed to create pull request (422 Unprocessable Entity): exists")
+            })
+            .await;
+        assert!(result.is_err());
+        assert_eq!(attempts.swap(0, Ordering::SeqCst), 1);
+
+        let result: Result<()> = scheduler
+            .call(|| async {
+                attempts.fetch_add(1, Ordering::SeqCst);
+                anyhow::bail!("Failed to create pull request (429 Too Many Requests): slow down")
+            })
+            .await;
+        assert!(result.is_err());
+        assert_eq!(attempts.load(Ordering::SeqCst), 2);
+    }
+
+    #[test]
+    fn test_is_rate_limited() {
+        let error = |message: &str| anyhow::anyhow!(message.to_string());
+        assert!(is_rate_limited(&error(
+            "Failed to create pull request (429 Too Many Requests): {}"
+        )));
+        assert!(is_rate_limited(&error(
+            "Failed to create pull request (403 Forbidden): You have exceeded a secondary rate limit"
+        )));
+        assert!(!is_rate_limited(&error(
+            "Failed to create pull request (403 Forbidden): Resource not accessible"
+        )));
+    }
+}
@@ -0,0 +1,149 @@
+//! Repository sizes for `clone --prioritize-size`
+//!
+//! Cloning the smallest repositories first gets quick successes out of a
+//! parallel clone while the large ones are still transferring. Sizes come
+//! from the GitHub API; repositories it cannot size keep their config order.
+
+use super::GitHubApi;
+use crate::config::{Provider, Repository};
+use repos_github::parse_github_url;
+use std::collections::BTreeMap;
+
+/// Size in kilobytes of each GitHub-hosted repository, keyed by name
+///
+/// Repositories not hosted on GitHub are left out. The others are looked up
+/// concurrently, each at its own API base URL. Failures for individual
+/// repositories are returned as `(name, error)` pairs so that one
+/// inaccessible repository does not prevent sizing the rest.
+pub async fn repository_sizes(
+    repositories: &[Repository],
+    api: &GitHubApi,
+) -> (BTreeMap<String, u64>, Vec<(String, anyhow::Error)>) {
+    let github = repositories
+        .iter()
+        .filter(|repo| repo.provider() == Provider::GitHub);
+    let (details, failures) = api
+        .look_up(github, async |repo, client| {
+            let (owner, name) = parse_github_url(&repo.url)?;
+            client.get_repository_details(&owner, &name).await
+        })
+        .await;
+
+    let sizes = details
+        .into_iter()
+        .filter_map(|(repo, details)| Some((repo.name.clone(), details.size?)))
+        .collect();
+    (sizes, failures)
+}
+
+/// `repositories` with the smallest first
+///
+/// Repositories without a known size follow the sized ones in their original
+/// order, so without any sizes the order is unchanged.
+pub fn order_by_size(
+    mut repositories: Vec<Repository>,
+    sizes: &BTreeMap<String, u64>,
+) -> Vec<Repository> {
+    // A stable sort keeps config order among equal and unknown sizes
+    repositories.sort_by_key(|repo| match sizes.get(&repo.name) {
+        Some(size) => (false, *size),
+        None => (true, 0),
+    });
+    repositories
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use repos_mock_server::{MockServer, Response};
+
+    fn repo(name: &str, url: &str) -> Repository {
+        Repository::new(name.to_string(), url.to_string())
+    }
+
+    fn names(repositories: &[Repository]) -> Vec<&str> {
+        repositories.iter().map(|repo| repo.name.as_str()).collect()
+    }
+
+    fn fleet() -> Vec<Repository> {
+        ["monorepo", "api", "docs", "web", "infra"]
+            .iter()
+            .map(|name| repo(name, &format!("git@github.com:acme/{}.git", name)))
+            .collect()
+    }
+
+    #[test]
+    fn test_smallest_repositories_come_first() {
+        let sizes = BTreeMap::from([
+            ("monorepo".to_string(), 2_400_000),
+            ("api".to_string(), 5_100),
+            ("docs".to_string(), 320),
+            ("web".to_string(), 5_100),
+            ("infra".to_string(), 48),
+        ]);
+        assert_eq!(
+            names(&order_by_size(fleet(), &sizes)),
+            vec!["infra", "docs", "api", "web", "monorepo"]
+        );
+    }
+
+    #[test]
+    fn test_unknown_sizes_keep_config_order() {
+        assert_eq!(
+            names(&order_by_size(fleet(), &BTreeMap::new())),
+            vec!["monorepo", "api", "docs", "web", "infra"]
+        );
+
+        // Sized repositories first, then the rest as configured
+        let sizes = BTreeMap::from([("web".to_string(), 900), ("docs".to_string(), 10)]);
+        assert_eq!(
+            names(&order_by_size(fleet(), &sizes)),
+            vec!["docs", "web", "monorepo", "api", "infra"]
+        );
+    }
+
+    #[tokio::test]
+    async fn test_sizes_are_looked_up_for_github_repositories() {
+        let server = MockServer::start(vec![
+            Response::new("200 OK", r#"{"topics": [], "size": 51200}"#),
+            Response::new("200 OK", r#"{"topics": ["docs"], "size": 12}"#),
+        ]);
+        let api = GitHubApi::new(Some("token".to_string())).with_api_url(Some(server.url.clone()));
+        let repos = vec![
+            repo("api", "git@github.com:acme/api.git"),
+            repo("mirror", "https://bitbucket.org/acme/mirror.git"),
+            repo("docs", "https://github.com/acme/docs.git"),
+        ];
+
+        let (sizes, failures) = repository_sizes(&repos, &api).await;
+        assert!(failures.is_empty());
+        assert_eq!(
+            sizes,
+            BTreeMap::from([("api".to_string(), 51200), ("docs".to_string(), 12)])
+        );
+        assert_eq!(
+            server.request_lines(),
+            vec![
+                "GET /repos/acme/api HTTP/1.1",
+                "GET /repos/acme/docs HTTP/1.1"
+            ]
+        );
+        assert_eq!(
+            names(&order_by_size(repos, &sizes)),
+            vec!["docs", "api", "mirror"]
+        );
+    }
+
+    #[tokio::test]
+    async fn test_size_failures_are_reported_per_repository() {
+        // Nothing listens on the discard port
+        let api = GitHubApi::new(Some("token".to_string()))
+            .with_api_url(Some("http://127.0.0.1:9".to_string()));
+        let repos = vec![repo("api", "git@github.com:acme/api.git")];
+
+        let (sizes, failures) = repository_sizes(&repos, &api).await;
+        assert!(sizes.is_empty());
+        assert_eq!(failures.len(), 1);
+        assert_eq!(failures[0].0, "api");
+    }
+}
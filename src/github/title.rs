@@ -0,0 +1,104 @@
+//! Pull request title templates
+//!
+//! `--title` may name facts about each repository, so one `repos pr` gives
+//! every pull request its own title, e.g. `--title "chore({{.Name}}): update
+//! deps"`:
+//!
+//! - `{{.Name}}`: the repository's name in the config
+//! - `{{.Owner}}`: the owner (or workspace) in its URL
+//! - `{{.Branch}}`: the branch the pull request is opened from
+//! - `{{.Base}}`: the branch it is opened against
+//! - `{{.Tags}}`: its tags, comma-separated
+//!
+//! As in recipe steps, other `{{ }}` actions are left as written, and a title
+//! without actions is sent unchanged.
+
+use crate::config::Repository;
+use crate::config::recipe_args::render_actions;
+use std::collections::BTreeMap;
+
+/// Whether `title` contains template actions to render per repository
+pub fn is_title_template(title: &str) -> bool {
+    title.contains("{{")
+}
+
+/// `template` rendered for `repo`'s pull request from `branch` into `base`
+pub fn render_title(template: &str, repo: &Repository, branch: &str, base: &str) -> String {
+    if !is_title_template(template) {
+        return template.to_string();
+    }
+    let mut values = BTreeMap::from([
+        ("Name".to_string(), repo.name.clone()),
+        ("Branch".to_string(), branch.to_string()),
+        ("Base".to_string(), base.to_string()),
+        ("Tags".to_string(), repo.tags.join(",")),
+    ]);
+    if let Ok((owner, _)) = super::api::parse_github_url(&repo.url) {
+        values.insert("Owner".to_string(), owner);
+    }
+    render_actions(template, &values)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn repo(name: &str, url: &str, tags: &[&str]) -> Repository {
+        let mut repo = Repository::new(name.to_string(), url.to_string());
+        repo.tags = tags.iter().map(|tag| tag.to_string()).collect();
+        repo
+    }
+
+    #[test]
+    fn test_title_is_rendered_per_repository() {
+        let template = "chore({{.Name}}): update deps";
+        let core = repo("core-lib", "git@github.com:acme/core-lib.git", &["rust"]);
+        let web = repo("web", "https://github.com/acme/web", &[]);
+        assert_eq!(
+            render_title(template, &core, "deps", "main"),
+            "chore(core-lib): update deps"
+        );
+        assert_eq!(
+            render_title(template, &web, "deps", "main"),
+            "chore(web): update deps"
+        );
+    }
+
+    #[test]
+    fn test_every_field_is_available() {
+        let api = repo(
+            "api",
+            "git@bitbucket.org:platform/api.git",
+            &["backend", "java"],
+        );
+        assert_eq!(
+            render_title(
+                "[{{ .Tags }}] {{.Owner}}/{{.Name}}: {{.Branch}} into {{.Base}}",
+                &api,
+                "repos/upgrade",
+                "develop"
+            ),
+            "[backend,java] platform/api: repos/upgrade into develop"
+        );
+    }
+
+    #[test]
+    fn test_literal_and_unknown_actions_are_kept() {
+        let api = repo("api", "git@github.com:acme/api.git", &[]);
+        assert!(!is_title_template("Update deps"));
+        assert_eq!(
+            render_title("Update deps", &api, "deps", "main"),
+            "Update deps"
+        );
+        assert_eq!(
+            render_title("{{.Name}}: bump {{.Version}}", &api, "deps", "main"),
+            "api: bump {{.Version}}"
+        );
+        // Without an owner in the URL the action stays
+        let local = repo("local", "local", &[]);
+        assert_eq!(
+            render_title("{{.Owner}}/{{.Name}}", &local, "deps", "main"),
+            "{{.Owner}}/local"
+        );
+    }
+}
@@ -0,0 +1,142 @@
+//! Tag enrichment from GitHub repository topics
+//!
+//! Topics are merged into repository tags with a `gh:` prefix, matching the
+//! tags written by `repos validate --sync-topics`. Fetched topics are cached on
+//! disk so repeated invocations do not hit the API for every repository.
+
+use super::cache::ApiCache;
+use crate::config::{Provider, Repository};
+use chrono::Utc;
+use repos_github::{GitHubClient, parse_github_url};
+
+/// Prefix applied to tags derived from GitHub topics
+pub const TOPIC_TAG_PREFIX: &str = "gh:";
+
+/// On-disk cache of topics keyed by `owner/repo`
+pub type TopicCache = ApiCache<Vec<String>>;
+
+/// Add `gh:<topic>` tags for topics the repository is not tagged with yet
+pub fn merge_topics(repo: &mut Repository, topics: &[String]) {
+    for topic in topics {
+        let tag = format!("{}{}", TOPIC_TAG_PREFIX, topic);
+        if !repo.tags.contains(&tag) {
+            repo.tags.push(tag);
+        }
+    }
+}
+
+/// Merge GitHub topics into the tags of every GitHub-hosted repository
+///
+/// Topics come from `cache` when fresh and from the API otherwise. Failures
+/// for individual repositories are returned as `(name, error)` pairs so that
+/// a single inaccessible repository does not prevent loading the rest.
+pub async fn enrich_with_topics(
+    repositories: &mut [Repository],
+    client: &GitHubClient,
+    cache: &mut TopicCache,
+) -> Vec<(String, anyhow::Error)> {
+    let now = Utc::now();
+    let mut failures = Vec::new();
+
+    for repo in repositories
+        .iter_mut()
+        .filter(|repo| repo.provider() == Provider::GitHub)
+    {
+        let (owner, name) = match parse_github_url(&repo.url) {
+            Ok(parsed) => parsed,
+            Err(e) => {
+                failures.push((repo.name.clone(), e));
+                continue;
+            }
+        };
+        let key = format!("{}/{}", owner, name);
+
+        let topics = match cache.get(&key, now) {
+            Some(topics) => topics.clone(),
+            None => match client.get_repository_topics(&owner, &name).await {
+                Ok(topics) => {
+                    cache.insert(key, topics.clone(), now);
+                    topics
+                }
+                Err(e) => {
+                    failures.push((repo.name.clone(), e));
+                    continue;
+                }
+            },
+        };
+
+        merge_topics(repo, &topics);
+    }
+
+    failures
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use repos_mock_server::{MockServer, Response};
+    use tempfile::TempDir;
+
+    fn repo(name: &str, url: &str, tags: &[&str]) -> Repository {
+        let mut repo = Repository::new(name.to_string(), url.to_string());
+        repo.tags = tags.iter().map(|t| t.to_string()).collect();
+        repo
+    }
+
+    #[test]
+    fn test_merge_topics_prefixes_and_deduplicates() {
+        let mut repo = repo("a", "git@github.com:acme/a.git", &["backend", "gh:rust"]);
+        merge_topics(&mut repo, &["rust".to_string(), "cli".to_string()]);
+        assert_eq!(repo.tags, vec!["backend", "gh:rust", "gh:cli"]);
+    }
+
+    #[tokio::test]
+    async fn test_enrich_fetches_and_caches_topics() {
+        let temp_dir = TempDir::new().unwrap();
+        let cache_path = temp_dir.path().join("topics.json");
+        // Only one request is served: the second enrichment must use the cache
+        let server = MockServer::start(vec![Response::new(
+            "200 OK",
+            r#"{"names": ["rust", "cli"]}"#,
+        )]);
+        let client = GitHubClient::new(Some("token".to_string())).with_base_url(&server.url);
+
+        let mut repos = vec![
+            repo("widgets", "git@github.com:acme/widgets.git", &["backend"]),
+            repo("mirror", "https://bitbucket.org/acme/mirror.git", &[]),
+        ];
+        let mut cache = TopicCache::open(&cache_path);
+        let failures = enrich_with_topics(&mut repos, &client, &mut cache).await;
+        assert!(failures.is_empty());
+        cache.save().unwrap();
+
+        assert_eq!(repos[0].tags, vec!["backend", "gh:rust", "gh:cli"]);
+        // Non-GitHub repositories are left alone
+        assert!(repos[1].tags.is_empty());
+
+        let mut repos = vec![repo("widgets", "git@github.com:acme/widgets.git", &[])];
+        let mut cache = TopicCache::open(&cache_path);
+        let failures = enrich_with_topics(&mut repos, &client, &mut cache).await;
+        assert!(failures.is_empty());
+        assert_eq!(repos[0].tags, vec!["gh:rust", "gh:cli"]);
+    }
+
+    #[tokio::test]
+    async fn test_enrich_reports_failures_per_repository() {
+        let temp_dir = TempDir::new().unwrap();
+        // Nothing listens on the discard port
+        let client =
+            GitHubClient::new(Some("token".to_string())).with_base_url("http://127.0.0.1:9");
+
+        let mut repos = vec![repo("widgets", "git@github.com:acme/widgets.git", &["x"])];
+        let mut cache = TopicCache::open(temp_dir.path().join("topics.json"));
+        let failures = enrich_with_topics(&mut repos, &client, &mut cache).await;
+
+        assert_eq!(failures.len(), 1);
+        assert_eq!(failures[0].0, "widgets");
+        assert_eq!(repos[0].tags, vec!["x"]);
+        // Failures are not cached
+        cache.save().unwrap();
+        assert!(!cache.path().exists());
+    }
+}
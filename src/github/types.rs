@@ -3,8 +3,11 @@
 //! This module contains workflow-specific types for GitHub operations.
 //! For low-level GitHub API types, see the `repos-github` crate.
 
+use crate::git::{CommitIdentity, LockMode};
+use glob::Pattern;
+
 /// Pull request options for creation workflow
-#[derive(Debug, Clone)]
+#[derive(Debug, Clone, Default)]
 pub struct PrOptions {
     pub title: String,
     pub body: String,
@@ -13,7 +16,33 @@ pub struct PrOptions {
     pub commit_msg: Option<String>,
     pub draft: bool,
     pub token: String,
+    /// Token for Bitbucket repositories without their own `token_env`: the
+    /// `--token` value or `BITBUCKET_TOKEN`, never the GitHub token
+    pub bitbucket_token: String,
     pub create_only: bool,
+    /// Look up the base branch's protection first and warn about its rules
+    pub check_protection: bool,
+    /// When non-empty, only repositories with a changed file matching one of these get a PR
+    pub changed_files: Vec<Pattern>,
+    /// Take the repository's lock before touching its branches, skipping or
+    /// waiting while another process holds it
+    pub lock: Option<LockMode>,
+    /// GitHub API base URL for repositories without their own `github_api_url`
+    pub github_api_url: Option<String>,
+    /// GitHub Enterprise Server hosts whose API base URL is derived from the
+    /// repository's URL when no base URL is set; others use `api.github.com`
+    pub github_enterprise_hosts: Vec<String>,
+    /// Owner sent in the head as `owner:branch` instead of the one in the
+    /// repository's URL; the branch is still pushed to the clone's `origin`
+    pub head_owner: Option<String>,
+    /// Owner of the repository to open the pull request against, e.g. the
+    /// upstream of a fork; the repository keeps its own name
+    pub base_owner: Option<String>,
+    /// Stage every change, untracked files included, before committing;
+    /// otherwise only the changes already staged are committed
+    pub commit_all: bool,
+    /// Author and committer of the commit; git's configuration fills in what is unset
+    pub author: CommitIdentity,
 }
 
 impl PrOptions {
@@ -21,12 +50,8 @@ impl PrOptions {
         Self {
             title,
             body,
-            branch_name: None,
-            base_branch: None,
-            commit_msg: None,
-            draft: false,
             token,
-            create_only: false,
+            ..Self::default()
         }
     }
 
@@ -54,4 +79,24 @@ impl PrOptions {
         self.create_only = true;
         self
     }
+
+    pub fn with_protection_check(mut self) -> Self {
+        self.check_protection = true;
+        self
+    }
+
+    pub fn with_changed_files(mut self, patterns: Vec<Pattern>) -> Self {
+        self.changed_files = patterns;
+        self
+    }
+
+    pub fn with_commit_all(mut self) -> Self {
+        self.commit_all = true;
+        self
+    }
+
+    pub fn with_author(mut self, author: CommitIdentity) -> Self {
+        self.author = author;
+        self
+    }
 }
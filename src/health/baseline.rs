@@ -0,0 +1,233 @@
+//! Regressions against a baseline health report
+//!
+//! `repos health check --baseline <file>` compares the current run with a
+//! report written earlier by `check --format json`, e.g. on the target branch
+//! of a pull request. A repository regresses when its overall score or the
+//! score of one of its categories drops, or when a check flags it critical
+//! that did not before. Repositories missing from either report are not
+//! compared, so adding or removing a repository is never a regression.
+
+use super::{Category, FleetReport, HealthReport};
+use std::collections::BTreeMap;
+use std::fmt;
+
+/// Score drops smaller than this are rounding noise, not regressions
+const SCORE_TOLERANCE: f64 = 1e-9;
+
+/// What got worse in a repository
+#[derive(Debug, Clone, PartialEq)]
+pub enum RegressionKind {
+    /// The overall score dropped
+    Score { before: f64, after: f64 },
+    /// The average score of one category's checks dropped
+    Category {
+        category: Category,
+        before: f64,
+        after: f64,
+    },
+    /// A check marks the repository critical that did not in the baseline
+    NewCritical { checker: String },
+}
+
+/// One way a repository is worse than in the baseline
+#[derive(Debug, Clone, PartialEq)]
+pub struct Regression {
+    pub repository: String,
+    pub kind: RegressionKind,
+}
+
+impl fmt::Display for Regression {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        match &self.kind {
+            RegressionKind::Score { before, after } => write!(
+                f,
+                "{}: score {:.0}% -> {:.0}%",
+                self.repository,
+                before * 100.0,
+                after * 100.0
+            ),
+            RegressionKind::Category {
+                category,
+                before,
+                after,
+            } => write!(
+                f,
+                "{}: {} {:.0}% -> {:.0}%",
+                self.repository,
+                category,
+                before * 100.0,
+                after * 100.0
+            ),
+            RegressionKind::NewCritical { checker } => {
+                write!(f, "{}: {} is now critical", self.repository, checker)
+            }
+        }
+    }
+}
+
+/// Every regression of `current` against `baseline`, in `current`'s repository order
+///
+/// For each repository the overall score comes first, then categories in
+/// their declared order, then new critical checks.
+pub fn find_regressions(baseline: &FleetReport, current: &FleetReport) -> Vec<Regression> {
+    let mut regressions = Vec::new();
+    for after in &current.repositories {
+        let Some(before) = baseline
+            .repositories
+            .iter()
+            .find(|report| report.repository == after.repository)
+        else {
+            continue;
+        };
+        let mut push = |kind| {
+            regressions.push(Regression {
+                repository: after.repository.clone(),
+                kind,
+            })
+        };
+
+        if dropped(before.score(), after.score()) {
+            push(RegressionKind::Score {
+                before: before.score(),
+                after: after.score(),
+            });
+        }
+
+        let before_categories = category_scores(before);
+        for (category, after_score) in category_scores(after) {
+            if let Some(&before_score) = before_categories.get(&category)
+                && dropped(before_score, after_score)
+            {
+                push(RegressionKind::Category {
+                    category,
+                    before: before_score,
+                    after: after_score,
+                });
+            }
+        }
+
+        for result in after.results.iter().filter(|result| result.critical) {
+            let was_critical = before
+                .results
+                .iter()
+                .any(|earlier| earlier.checker == result.checker && earlier.critical);
+            if !was_critical {
+                push(RegressionKind::NewCritical {
+                    checker: result.checker.clone(),
+                });
+            }
+        }
+    }
+    regressions
+}
+
+/// Average score of each category's checks
+fn category_scores(report: &HealthReport) -> BTreeMap<Category, f64> {
+    let mut totals: BTreeMap<Category, (f64, usize)> = BTreeMap::new();
+    for result in &report.results {
+        let entry = totals.entry(result.category).or_default();
+        entry.0 += result.score;
+        entry.1 += 1;
+    }
+    totals
+        .into_iter()
+        .map(|(category, (sum, count))| (category, sum / count as f64))
+        .collect()
+}
+
+fn dropped(before: f64, after: f64) -> bool {
+    after < before - SCORE_TOLERANCE
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::health::CheckResult;
+
+    fn report(repository: &str, results: Vec<CheckResult>) -> HealthReport {
+        HealthReport {
+            repository: repository.to_string(),
+            results,
+            ignored: Vec::new(),
+        }
+    }
+
+    fn check(checker: &str, category: Category, passed: usize) -> CheckResult {
+        CheckResult::from_criteria(checker, category, passed, 4, vec![])
+    }
+
+    fn baseline() -> FleetReport {
+        FleetReport::new(vec![
+            report(
+                "api",
+                vec![
+                    check("readme", Category::Documentation, 4),
+                    check("license", Category::Documentation, 4),
+                    check("vulnerabilities", Category::Security, 4),
+                ],
+            ),
+            report("web", vec![check("readme", Category::Documentation, 2)]),
+            report("retired", vec![check("readme", Category::Documentation, 4)]),
+        ])
+    }
+
+    #[test]
+    fn test_worse_report_lists_score_category_and_critical_regressions() {
+        let current = FleetReport::new(vec![
+            report(
+                "api",
+                vec![
+                    check("readme", Category::Documentation, 2),
+                    check("license", Category::Documentation, 4),
+                    check("vulnerabilities", Category::Security, 4).mark_critical(),
+                ],
+            ),
+            // Improved: not a regression
+            report("web", vec![check("readme", Category::Documentation, 3)]),
+            // Not in the baseline: not compared
+            report("new", vec![check("readme", Category::Documentation, 0)]),
+        ]);
+
+        let regressions = find_regressions(&baseline(), &current);
+        let lines: Vec<String> = regressions.iter().map(ToString::to_string).collect();
+        assert_eq!(
+            lines,
+            vec![
+                "api: score 100% -> 83%",
+                "api: documentation 100% -> 75%",
+                "api: vulnerabilities is now critical",
+            ]
+        );
+        assert_eq!(
+            regressions[1].kind,
+            RegressionKind::Category {
+                category: Category::Documentation,
+                before: 1.0,
+                after: 0.75,
+            }
+        );
+    }
+
+    #[test]
+    fn test_unchanged_report_has_no_regressions() {
+        assert!(find_regressions(&baseline(), &baseline()).is_empty());
+    }
+
+    #[test]
+    fn test_critical_already_in_baseline_is_not_new() {
+        let flagged = || {
+            FleetReport::new(vec![report(
+                "api",
+                vec![check("vulnerabilities", Category::Security, 4).mark_critical()],
+            )])
+        };
+        assert!(find_regressions(&flagged(), &flagged()).is_empty());
+    }
+
+    #[test]
+    fn test_baseline_round_trips_through_json() {
+        let json = serde_json::to_string(&baseline()).unwrap();
+        let loaded: FleetReport = serde_json::from_str(&json).unwrap();
+        assert!(find_regressions(&loaded, &baseline()).is_empty());
+    }
+}
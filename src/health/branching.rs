@@ -0,0 +1,182 @@
+//! Default branch conventions
+//!
+//! The default branch of a clone (as `origin/HEAD` names it, else the current
+//! branch) must be one of the allowed names, `main` unless configured. When
+//! branch protection was looked up beforehand, e.g. with
+//! [`default_branch_protection`](crate::github::default_branch_protection),
+//! the default branch must also be protected. The checker itself never calls
+//! the hosting API, so clones without protection data are scored on their
+//! branch name alone. Directories that are not git repositories are not scored.
+
+use super::{Category, CheckResult, Checker};
+use crate::git;
+use std::collections::BTreeMap;
+use std::path::{Path, PathBuf};
+
+/// Default branch names allowed when none are configured
+pub const DEFAULT_ALLOWED_BRANCHES: &[&str] = &["main"];
+
+/// Which default branch names are allowed and which clones are protected
+#[derive(Debug, Clone, PartialEq)]
+pub struct BranchingOptions {
+    /// Names the default branch may have
+    pub allowed: Vec<String>,
+    /// Whether each clone's default branch is protected, keyed by clone path
+    pub protection: BTreeMap<PathBuf, bool>,
+}
+
+impl Default for BranchingOptions {
+    fn default() -> Self {
+        Self {
+            allowed: DEFAULT_ALLOWED_BRANCHES
+                .iter()
+                .map(|name| name.to_string())
+                .collect(),
+            protection: BTreeMap::new(),
+        }
+    }
+}
+
+/// Checks the default branch's name and, when known, its protection
+pub struct BranchingChecker {
+    options: BranchingOptions,
+}
+
+impl BranchingChecker {
+    pub fn new(options: BranchingOptions) -> Self {
+        Self { options }
+    }
+}
+
+impl Default for BranchingChecker {
+    fn default() -> Self {
+        Self::new(BranchingOptions::default())
+    }
+}
+
+impl Checker for BranchingChecker {
+    fn name(&self) -> &'static str {
+        "branching"
+    }
+
+    fn category(&self) -> Category {
+        Category::Governance
+    }
+
+    fn check(&self, repo_path: &Path) -> CheckResult {
+        if !repo_path.join(".git").exists() {
+            return CheckResult::from_criteria(self.name(), self.category(), 0, 0, vec![]);
+        }
+
+        let branch = match git::get_default_branch(&repo_path.to_string_lossy()) {
+            Ok(branch) => branch,
+            Err(e) => {
+                return CheckResult::from_criteria(
+                    self.name(),
+                    self.category(),
+                    0,
+                    0,
+                    vec![format!("default branch not found: {}", e)],
+                );
+            }
+        };
+
+        let mut passed = 0;
+        let mut total = 1;
+        let mut findings = Vec::new();
+        if self.options.allowed.contains(&branch) {
+            passed += 1;
+        } else {
+            findings.push(format!(
+                "default branch '{}' is not an allowed name (allowed: {})",
+                branch,
+                self.options.allowed.join(", ")
+            ));
+        }
+
+        if let Some(&protected) = self.options.protection.get(repo_path) {
+            total += 1;
+            if protected {
+                passed += 1;
+            } else {
+                findings.push(format!("default branch '{}' is not protected", branch));
+            }
+        }
+
+        CheckResult::from_criteria(self.name(), self.category(), passed, total, findings)
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use std::process::Command;
+    use tempfile::TempDir;
+
+    /// A repository whose only (and so default) branch is `branch`
+    fn repository_on(branch: &str) -> TempDir {
+        let temp_dir = TempDir::new().unwrap();
+        let status = Command::new("git")
+            .args(["init", "--quiet", "--initial-branch", branch])
+            .current_dir(temp_dir.path())
+            .status()
+            .unwrap();
+        assert!(status.success());
+        temp_dir
+    }
+
+    fn protected(path: &Path, protected: bool) -> BranchingOptions {
+        BranchingOptions {
+            protection: BTreeMap::from([(path.to_path_buf(), protected)]),
+            ..BranchingOptions::default()
+        }
+    }
+
+    #[test]
+    fn test_disallowed_default_branch_is_flagged() {
+        let repo = repository_on("master");
+
+        let result = BranchingChecker::default().check(repo.path());
+        assert_eq!(result.category, Category::Governance);
+        assert_eq!(result.score, 0.0);
+        assert_eq!(
+            result.findings,
+            vec!["default branch 'master' is not an allowed name (allowed: main)"]
+        );
+
+        let checker = BranchingChecker::new(BranchingOptions {
+            allowed: vec!["main".to_string(), "master".to_string()],
+            ..BranchingOptions::default()
+        });
+        assert_eq!(checker.check(repo.path()).score, 1.0);
+    }
+
+    #[test]
+    fn test_protection_data_is_scored_when_present() {
+        let repo = repository_on("main");
+
+        let result = BranchingChecker::new(protected(repo.path(), false)).check(repo.path());
+        assert_eq!(result.score, 0.5);
+        assert_eq!(
+            result.findings,
+            vec!["default branch 'main' is not protected"]
+        );
+
+        let result = BranchingChecker::new(protected(repo.path(), true)).check(repo.path());
+        assert_eq!(result.score, 1.0);
+        assert!(result.findings.is_empty());
+
+        // Protection of another clone does not apply
+        let other = repository_on("main");
+        let result = BranchingChecker::new(protected(other.path(), false)).check(repo.path());
+        assert_eq!(result.score, 1.0);
+    }
+
+    #[test]
+    fn test_directory_without_git_is_not_scored() {
+        let temp_dir = TempDir::new().unwrap();
+        let result = BranchingChecker::default().check(temp_dir.path());
+        assert_eq!(result.score, 1.0);
+        assert!(result.findings.is_empty());
+    }
+}
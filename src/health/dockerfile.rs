@@ -0,0 +1,366 @@
+//! Dockerfile hygiene check
+//!
+//! Repositories without a Dockerfile at the root are not penalised. When one
+//! is present, each enabled [`DockerfileRule`] is a criterion and every
+//! violation is reported as a finding.
+
+use super::{Category, CheckResult, Checker, find_root_file};
+use anyhow::{Result, bail};
+use std::collections::HashSet;
+use std::path::Path;
+use std::str::FromStr;
+
+/// A Dockerfile best practice
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Hash)]
+pub enum DockerfileRule {
+    /// Base images are pinned to a tag other than `latest` (or a digest)
+    LatestTag,
+    /// The final stage switches to a non-root `USER`
+    RootUser,
+    /// A `HEALTHCHECK` is declared
+    MissingHealthcheck,
+}
+
+impl DockerfileRule {
+    pub const ALL: [Self; 3] = [Self::LatestTag, Self::RootUser, Self::MissingHealthcheck];
+
+    pub fn as_str(&self) -> &'static str {
+        match self {
+            Self::LatestTag => "latest-tag",
+            Self::RootUser => "root-user",
+            Self::MissingHealthcheck => "missing-healthcheck",
+        }
+    }
+}
+
+impl FromStr for DockerfileRule {
+    type Err = anyhow::Error;
+
+    fn from_str(value: &str) -> Result<Self> {
+        match Self::ALL.iter().find(|rule| rule.as_str() == value) {
+            Some(rule) => Ok(*rule),
+            None => bail!(
+                "Unknown Dockerfile rule '{}': expected latest-tag, root-user or missing-healthcheck",
+                value
+            ),
+        }
+    }
+}
+
+/// Which rules the Dockerfile check enforces
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct DockerfileOptions {
+    pub rules: Vec<DockerfileRule>,
+}
+
+impl DockerfileOptions {
+    /// Enforce every rule except those in `skipped`
+    pub fn without(skipped: &[DockerfileRule]) -> Self {
+        Self {
+            rules: DockerfileRule::ALL
+                .into_iter()
+                .filter(|rule| !skipped.contains(rule))
+                .collect(),
+        }
+    }
+}
+
+impl Default for DockerfileOptions {
+    fn default() -> Self {
+        Self {
+            rules: DockerfileRule::ALL.to_vec(),
+        }
+    }
+}
+
+/// Checks the root Dockerfile against the enabled rules
+pub struct DockerfileChecker {
+    options: DockerfileOptions,
+}
+
+impl DockerfileChecker {
+    pub fn new(options: DockerfileOptions) -> Self {
+        Self { options }
+    }
+
+    fn assess(&self, content: &str) -> (usize, Vec<String>) {
+        let instructions = instructions(content);
+        let mut passed = 0;
+        let mut findings = Vec::new();
+
+        for rule in &self.options.rules {
+            let violations = match rule {
+                DockerfileRule::LatestTag => unpinned_base_images(&instructions)
+                    .into_iter()
+                    .map(|image| {
+                        format!(
+                            "Dockerfile base image '{}' is not pinned to a tag other than latest",
+                            image
+                        )
+                    })
+                    .collect(),
+                DockerfileRule::RootUser if runs_as_root(&instructions) => {
+                    vec!["Dockerfile runs as root (no non-root USER directive)".to_string()]
+                }
+                DockerfileRule::MissingHealthcheck
+                    if !instructions.iter().any(|(name, _)| name == "HEALTHCHECK") =>
+                {
+                    vec!["Dockerfile has no HEALTHCHECK".to_string()]
+                }
+                _ => Vec::new(),
+            };
+            if violations.is_empty() {
+                passed += 1;
+            }
+            findings.extend(violations);
+        }
+
+        (passed, findings)
+    }
+}
+
+impl Checker for DockerfileChecker {
+    fn name(&self) -> &'static str {
+        "dockerfile"
+    }
+
+    fn category(&self) -> Category {
+        Category::Infrastructure
+    }
+
+    fn check(&self, repo_path: &Path) -> CheckResult {
+        let content = find_root_file(repo_path, &["Dockerfile"])
+            .and_then(|path| std::fs::read_to_string(path).ok());
+        let Some(content) = content else {
+            return CheckResult::from_criteria(self.name(), self.category(), 0, 0, vec![]);
+        };
+
+        let (passed, findings) = self.assess(&content);
+        CheckResult::from_criteria(
+            self.name(),
+            self.category(),
+            passed,
+            self.options.rules.len(),
+            findings,
+        )
+    }
+}
+
+/// Instructions as `(UPPERCASE_NAME, arguments)`, with comments dropped and
+/// continuation lines joined
+fn instructions(content: &str) -> Vec<(String, String)> {
+    let mut result = Vec::new();
+    let mut current = String::new();
+
+    for line in content.lines() {
+        let trimmed = line.trim();
+        if current.is_empty() && (trimmed.is_empty() || trimmed.starts_with('#')) {
+            continue;
+        }
+        match trimmed.strip_suffix('\\') {
+            Some(continued) => {
+                current.push_str(continued);
+                current.push(' ');
+            }
+            None => {
+                current.push_str(trimmed);
+                if let Some(instruction) = split_instruction(&current) {
+                    result.push(instruction);
+                }
+                current.clear();
+            }
+        }
+    }
+    if let Some(instruction) = split_instruction(&current) {
+        result.push(instruction);
+    }
+    result
+}
+
+fn split_instruction(line: &str) -> Option<(String, String)> {
+    let line = line.trim();
+    if line.is_empty() {
+        return None;
+    }
+    let (name, args) = line.split_once(char::is_whitespace).unwrap_or((line, ""));
+    Some((name.to_uppercase(), args.trim().to_string()))
+}
+
+/// Base images in `FROM` lines that have no tag or use `latest`
+///
+/// Images referring to an earlier build stage, `scratch`, digests and
+/// images built from `ARG` values are not flagged.
+fn unpinned_base_images(instructions: &[(String, String)]) -> Vec<String> {
+    let mut stages = HashSet::new();
+    let mut unpinned = Vec::new();
+
+    for (name, args) in instructions {
+        if name != "FROM" {
+            continue;
+        }
+        let mut words = args
+            .split_whitespace()
+            .filter(|word| !word.starts_with("--"));
+        let Some(image) = words.next() else { continue };
+        if let (Some(keyword), Some(alias)) = (words.next(), words.next())
+            && keyword.eq_ignore_ascii_case("as")
+        {
+            stages.insert(alias.to_lowercase());
+        }
+
+        if image.contains('$')
+            || image.contains('@')
+            || image.eq_ignore_ascii_case("scratch")
+            || stages.contains(&image.to_lowercase())
+        {
+            continue;
+        }
+        // A colon before the last `/` belongs to a registry port, not a tag
+        let name_part = image.rsplit('/').next().unwrap_or(image);
+        match name_part.split_once(':') {
+            Some((_, tag)) if tag != "latest" => {}
+            _ => unpinned.push(image.to_string()),
+        }
+    }
+    unpinned
+}
+
+/// Whether the final stage has no `USER`, or last switches to root
+fn runs_as_root(instructions: &[(String, String)]) -> bool {
+    let final_stage = instructions
+        .iter()
+        .rposition(|(name, _)| name == "FROM")
+        .map_or(instructions, |start| &instructions[start..]);
+    match final_stage.iter().rev().find(|(name, _)| name == "USER") {
+        Some((_, user)) => {
+            let user = user.split(':').next().unwrap_or(user);
+            user == "root" || user == "0"
+        }
+        None => true,
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use tempfile::TempDir;
+
+    const GOOD: &str = "\
+# syntax=docker/dockerfile:1
+FROM rust:1.80 AS build
+RUN cargo build --release
+
+FROM gcr.io/distroless/cc-debian12:nonroot
+COPY --from=build /app/target/release/app /app
+USER nonroot
+HEALTHCHECK --interval=30s \\
+  CMD [\"/app\", \"--health\"]
+ENTRYPOINT [\"/app\"]
+";
+
+    fn check(content: &str, options: DockerfileOptions) -> CheckResult {
+        let temp_dir = TempDir::new().unwrap();
+        std::fs::write(temp_dir.path().join("Dockerfile"), content).unwrap();
+        DockerfileChecker::new(options).check(temp_dir.path())
+    }
+
+    #[test]
+    fn test_dockerfile_following_every_rule() {
+        let result = check(GOOD, DockerfileOptions::default());
+        assert_eq!(result.category, Category::Infrastructure);
+        assert_eq!(result.score, 1.0);
+        assert!(result.findings.is_empty(), "{:?}", result.findings);
+    }
+
+    #[test]
+    fn test_no_dockerfile_is_not_penalised() {
+        let temp_dir = TempDir::new().unwrap();
+        let result = DockerfileChecker::new(DockerfileOptions::default()).check(temp_dir.path());
+        assert_eq!(result.score, 1.0);
+        assert!(result.findings.is_empty());
+    }
+
+    #[test]
+    fn test_latest_tag_rule() {
+        let result = check(
+            "FROM node\nFROM python:latest\nFROM registry:5000/team/app\nUSER app\nHEALTHCHECK NONE\n",
+            DockerfileOptions::default(),
+        );
+        assert_eq!(
+            result.findings,
+            vec![
+                "Dockerfile base image 'node' is not pinned to a tag other than latest",
+                "Dockerfile base image 'python:latest' is not pinned to a tag other than latest",
+                "Dockerfile base image 'registry:5000/team/app' is not pinned to a tag other than latest",
+            ]
+        );
+        assert!((result.score - 2.0 / 3.0).abs() < 1e-9);
+
+        // Digests, earlier stages, scratch and ARG-built images are fine
+        let pinned = check(
+            "ARG BASE=alpine:3.20\nFROM ${BASE} AS base\nFROM base\nFROM scratch\n\
+             FROM --platform=linux/amd64 debian@sha256:abc123\nUSER 1000\nHEALTHCHECK CMD true\n",
+            DockerfileOptions::default(),
+        );
+        assert!(pinned.findings.is_empty(), "{:?}", pinned.findings);
+    }
+
+    #[test]
+    fn test_root_user_rule() {
+        let no_user = check(
+            "FROM alpine:3.20\nHEALTHCHECK CMD true\n",
+            DockerfileOptions::default(),
+        );
+        assert_eq!(
+            no_user.findings,
+            vec!["Dockerfile runs as root (no non-root USER directive)"]
+        );
+
+        // Only the final stage counts, and switching back to root is flagged
+        let back_to_root = check(
+            "FROM alpine:3.20 AS build\nUSER builder\nFROM alpine:3.20\nUSER app\nUSER root:root\nHEALTHCHECK CMD true\n",
+            DockerfileOptions::default(),
+        );
+        assert_eq!(back_to_root.findings.len(), 1);
+
+        let non_root = check(
+            "FROM alpine:3.20\nuser app\nHEALTHCHECK CMD true\n",
+            DockerfileOptions::default(),
+        );
+        assert!(non_root.findings.is_empty());
+    }
+
+    #[test]
+    fn test_missing_healthcheck_rule() {
+        let result = check("FROM alpine:3.20\nUSER app\n", DockerfileOptions::default());
+        assert_eq!(result.findings, vec!["Dockerfile has no HEALTHCHECK"]);
+
+        // A healthcheck mentioned only in a comment does not count
+        let commented = check(
+            "FROM alpine:3.20\nUSER app\n# HEALTHCHECK CMD true\n",
+            DockerfileOptions::default(),
+        );
+        assert_eq!(commented.findings, vec!["Dockerfile has no HEALTHCHECK"]);
+    }
+
+    #[test]
+    fn test_skipped_rules_are_not_scored() {
+        let options = DockerfileOptions::without(&[
+            DockerfileRule::RootUser,
+            DockerfileRule::MissingHealthcheck,
+        ]);
+        assert_eq!(options.rules, vec![DockerfileRule::LatestTag]);
+
+        let result = check("FROM alpine:3.20\n", options);
+        assert_eq!(result.score, 1.0);
+        assert!(result.findings.is_empty());
+    }
+
+    #[test]
+    fn test_parse_rule_names() {
+        for rule in DockerfileRule::ALL {
+            assert_eq!(rule.as_str().parse::<DockerfileRule>().unwrap(), rule);
+        }
+        assert!("no-sudo".parse::<DockerfileRule>().is_err());
+    }
+}
@@ -0,0 +1,271 @@
+//! Selecting checkers by category
+//!
+//! Every checker belongs to one built-in [`Category`]. The config's
+//! `categories` section adds custom categories that group existing checkers by
+//! name, e.g. `compliance: [license, readme]`. [`CheckerFactory`] builds the
+//! checkers for a mix of built-in and custom category names.
+
+use super::{
+    BranchingChecker, Category, Checker, DockerfileChecker, DockerfileOptions, GitignoreChecker,
+    GoModChecker, HealthOptions, LicenseChecker, ReadmeChecker, ReadmeOptions,
+    SensitiveFilesChecker, SigningChecker, VulnerabilityChecker,
+};
+use anyhow::{Result, bail};
+use std::collections::BTreeMap;
+use std::path::PathBuf;
+
+/// A category and the checkers it selects
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct CategoryDefinition {
+    pub name: String,
+    pub checkers: Vec<String>,
+    /// Defined in the config rather than built in
+    pub custom: bool,
+}
+
+/// Builds the health checkers, optionally limited to some categories
+pub struct CheckerFactory {
+    options: HealthOptions,
+    custom: BTreeMap<String, Vec<String>>,
+}
+
+impl CheckerFactory {
+    pub fn new(readme: ReadmeOptions, dockerfile: DockerfileOptions) -> Self {
+        Self::with_options(HealthOptions {
+            readme,
+            dockerfile,
+            ..HealthOptions::default()
+        })
+    }
+
+    pub fn with_options(options: HealthOptions) -> Self {
+        Self {
+            options,
+            custom: BTreeMap::new(),
+        }
+    }
+
+    /// Score default branch protection from `protection`, keyed by clone path
+    pub fn with_branch_protection(mut self, protection: BTreeMap<PathBuf, bool>) -> Self {
+        self.options.branching.protection = protection;
+        self
+    }
+
+    /// Add custom categories mapping a name to checker names
+    ///
+    /// # Errors
+    /// Returns an error if a category reuses a built-in category name, is
+    /// empty, or lists a checker that does not exist
+    pub fn with_custom_categories(
+        mut self,
+        categories: BTreeMap<String, Vec<String>>,
+    ) -> Result<Self> {
+        let known = self.checker_names();
+        for (name, members) in &categories {
+            if name.parse::<Category>().is_ok() {
+                bail!(
+                    "Custom category '{}' clashes with a built-in category",
+                    name
+                );
+            }
+            if members.is_empty() {
+                bail!("Custom category '{}' lists no checkers", name);
+            }
+            if let Some(unknown) = members.iter().find(|member| !known.contains(member)) {
+                bail!(
+                    "Custom category '{}' lists unknown checker '{}' (available: {})",
+                    name,
+                    unknown,
+                    known.join(", ")
+                );
+            }
+        }
+        self.custom = categories;
+        Ok(self)
+    }
+
+    /// Every checker, in reporting order
+    pub fn checkers(&self) -> Vec<Box<dyn Checker>> {
+        vec![
+            Box::new(ReadmeChecker::new(self.options.readme.clone())),
+            Box::new(LicenseChecker),
+            Box::new(DockerfileChecker::new(self.options.dockerfile.clone())),
+            Box::new(VulnerabilityChecker::new()),
+            Box::new(GoModChecker::new().with_scan_exclude(self.options.scan_exclude.clone())),
+            Box::new(SigningChecker::new(self.options.signing.clone())),
+            Box::new(
+                SensitiveFilesChecker::new(self.options.sensitive_files.clone())
+                    .with_scan_exclude(self.options.scan_exclude.clone()),
+            ),
+            Box::new(BranchingChecker::new(self.options.branching.clone())),
+            Box::new(GitignoreChecker),
+        ]
+    }
+
+    /// The checkers in any of the named built-in or custom categories
+    ///
+    /// Checkers keep their reporting order and are included once even when
+    /// several categories select them.
+    ///
+    /// # Errors
+    /// Returns an error naming the available categories if a name is unknown
+    pub fn for_categories(&self, names: &[String]) -> Result<Vec<Box<dyn Checker>>> {
+        let definitions = self.categories();
+        let mut selected = Vec::new();
+        for name in names {
+            let Some(definition) = definitions.iter().find(|d| d.name == *name) else {
+                let available: Vec<&str> = definitions.iter().map(|d| d.name.as_str()).collect();
+                bail!(
+                    "Unknown category '{}' (available: {})",
+                    name,
+                    available.join(", ")
+                );
+            };
+            selected.extend(definition.checkers.iter().cloned());
+        }
+
+        Ok(self
+            .checkers()
+            .into_iter()
+            .filter(|checker| selected.iter().any(|name| name == checker.name()))
+            .collect())
+    }
+
+    /// Built-in categories followed by custom ones, with their checkers
+    pub fn categories(&self) -> Vec<CategoryDefinition> {
+        let checkers = self.checkers();
+        let built_in = Category::ALL
+            .into_iter()
+            .map(|category| CategoryDefinition {
+                name: category.to_string(),
+                checkers: checkers
+                    .iter()
+                    .filter(|checker| checker.category() == category)
+                    .map(|checker| checker.name().to_string())
+                    .collect(),
+                custom: false,
+            });
+        let custom = self
+            .custom
+            .iter()
+            .map(|(name, members)| CategoryDefinition {
+                name: name.clone(),
+                checkers: members.clone(),
+                custom: true,
+            });
+        built_in.chain(custom).collect()
+    }
+
+    /// Names of every checker, in reporting order
+    pub fn checker_names(&self) -> Vec<String> {
+        self.checkers()
+            .iter()
+            .map(|checker| checker.name().to_string())
+            .collect()
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn factory() -> CheckerFactory {
+        CheckerFactory::new(ReadmeOptions::default(), DockerfileOptions::default())
+    }
+
+    fn custom(entries: &[(&str, &[&str])]) -> BTreeMap<String, Vec<String>> {
+        entries
+            .iter()
+            .map(|(name, members)| {
+                (
+                    name.to_string(),
+                    members.iter().map(|m| m.to_string()).collect(),
+                )
+            })
+            .collect()
+    }
+
+    fn names(checkers: &[Box<dyn Checker>]) -> Vec<&'static str> {
+        checkers.iter().map(|checker| checker.name()).collect()
+    }
+
+    #[test]
+    fn test_custom_category_selects_exactly_its_members() {
+        let factory = factory()
+            .with_custom_categories(custom(&[("compliance", &["license", "readme"])]))
+            .unwrap();
+
+        let selected = factory.for_categories(&["compliance".to_string()]).unwrap();
+        assert_eq!(names(&selected), vec!["readme", "license"]);
+    }
+
+    #[test]
+    fn test_categories_combine_without_duplicates() {
+        let factory = factory()
+            .with_custom_categories(custom(&[("compliance", &["license", "vulnerabilities"])]))
+            .unwrap();
+
+        let selected = factory
+            .for_categories(&["security".to_string(), "compliance".to_string()])
+            .unwrap();
+        assert_eq!(
+            names(&selected),
+            vec!["license", "vulnerabilities", "signing", "sensitive-files"]
+        );
+
+        let documentation = factory
+            .for_categories(&["documentation".to_string()])
+            .unwrap();
+        assert_eq!(names(&documentation), vec!["readme", "license"]);
+
+        let unknown = factory.for_categories(&["ops".to_string()]);
+        assert_eq!(
+            unknown.err().unwrap().to_string(),
+            "Unknown category 'ops' (available: documentation, infrastructure, security, dependencies, governance, code-quality, compliance)"
+        );
+    }
+
+    #[test]
+    fn test_categories_lists_built_in_and_custom() {
+        let factory = factory()
+            .with_custom_categories(custom(&[("compliance", &["license"])]))
+            .unwrap();
+        let categories = factory.categories();
+
+        assert_eq!(
+            categories.last().unwrap(),
+            &CategoryDefinition {
+                name: "compliance".to_string(),
+                checkers: vec!["license".to_string()],
+                custom: true,
+            }
+        );
+        let infrastructure = categories
+            .iter()
+            .find(|c| c.name == "infrastructure")
+            .unwrap();
+        assert_eq!(infrastructure.checkers, vec!["dockerfile"]);
+        assert!(!infrastructure.custom);
+    }
+
+    #[test]
+    fn test_invalid_custom_categories_are_rejected() {
+        let unknown = factory().with_custom_categories(custom(&[("compliance", &["ci"])]));
+        assert_eq!(
+            unknown.err().unwrap().to_string(),
+            "Custom category 'compliance' lists unknown checker 'ci' \
+             (available: readme, license, dockerfile, vulnerabilities, gomod, signing, \
+             sensitive-files, branching, gitignore)"
+        );
+        assert!(
+            factory()
+                .with_custom_categories(custom(&[("security", &["license"])]))
+                .is_err()
+        );
+        assert!(
+            factory()
+                .with_custom_categories(custom(&[("empty", &[])]))
+                .is_err()
+        );
+    }
+}
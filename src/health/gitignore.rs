@@ -0,0 +1,184 @@
+//! `.gitignore` adequacy check
+//!
+//! The repository root must have a `.gitignore`, and it must ignore the build
+//! and dependency artifacts of each language found by
+//! [`detect_tags_from_path`]: `node_modules` for Node, `__pycache__` or
+//! `*.pyc` for Python, `target/` for Rust and Java and `bin/` for Go. Each is
+//! a criterion, and every artifact that is not ignored is listed as a finding.
+//! Gaps are warnings: the score does not drop below [`CRITICAL_BELOW`], so
+//! they never make a repository critical. Only the root `.gitignore` is read;
+//! negated patterns are not followed.
+
+use super::{CRITICAL_BELOW, Category, CheckResult, Checker};
+use crate::utils::detect_tags_from_path;
+use std::path::Path;
+
+/// An artifact that should be ignored, and the patterns that ignore it
+struct Artifact {
+    /// Language tag from [`detect_tags_from_path`]
+    language: &'static str,
+    label: &'static str,
+    patterns: &'static [&'static str],
+}
+
+const ARTIFACTS: &[Artifact] = &[
+    Artifact {
+        language: "node",
+        label: "node_modules",
+        patterns: &["node_modules"],
+    },
+    Artifact {
+        language: "python",
+        label: "__pycache__/*.pyc",
+        patterns: &["__pycache__", "*.pyc", "*.py[cod]", "*.py[co]"],
+    },
+    Artifact {
+        language: "rust",
+        label: "target/",
+        patterns: &["target"],
+    },
+    Artifact {
+        language: "java",
+        label: "target/",
+        patterns: &["target"],
+    },
+    Artifact {
+        language: "go",
+        label: "bin/",
+        patterns: &["bin"],
+    },
+];
+
+/// Checks that `.gitignore` covers the artifacts of the detected languages
+pub struct GitignoreChecker;
+
+impl Checker for GitignoreChecker {
+    fn name(&self) -> &'static str {
+        "gitignore"
+    }
+
+    fn category(&self) -> Category {
+        Category::CodeQuality
+    }
+
+    fn check(&self, repo_path: &Path) -> CheckResult {
+        let languages = detect_tags_from_path(repo_path);
+        let mut expected: Vec<&Artifact> = Vec::new();
+        for artifact in ARTIFACTS {
+            let detected = languages.iter().any(|tag| tag == artifact.language);
+            if detected && !expected.iter().any(|e| e.label == artifact.label) {
+                expected.push(artifact);
+            }
+        }
+
+        let mut findings = Vec::new();
+        let patterns = match std::fs::read_to_string(repo_path.join(".gitignore")) {
+            Ok(content) => ignore_patterns(&content),
+            Err(_) => {
+                findings.push("no .gitignore file".to_string());
+                Vec::new()
+            }
+        };
+        let mut passed = usize::from(findings.is_empty());
+        for artifact in &expected {
+            if artifact.patterns.iter().any(|p| patterns.contains(p)) {
+                passed += 1;
+            } else {
+                findings.push(format!("{} is not ignored", artifact.label));
+            }
+        }
+
+        CheckResult::from_criteria(
+            self.name(),
+            self.category(),
+            passed,
+            expected.len() + 1,
+            findings,
+        )
+        .at_most_warning()
+    }
+}
+
+/// The patterns of a `.gitignore`, without anchors and trailing slashes
+///
+/// `/target/`, `**/target` and `target/**` all become `target`; comments,
+/// blank lines and negations are dropped.
+fn ignore_patterns(content: &str) -> Vec<&str> {
+    content
+        .lines()
+        .map(str::trim)
+        .filter(|line| !line.is_empty() && !line.starts_with('#') && !line.starts_with('!'))
+        .map(|line| {
+            let line = line.strip_prefix("**/").unwrap_or(line);
+            let line = line.strip_prefix('/').unwrap_or(line);
+            let line = line.strip_suffix("/**").unwrap_or(line);
+            line.strip_suffix('/').unwrap_or(line)
+        })
+        .collect()
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use tempfile::TempDir;
+
+    /// A repository with the given root files; `gitignore` is omitted when `None`
+    fn fixture(files: &[&str], gitignore: Option<&str>) -> TempDir {
+        let temp_dir = TempDir::new().unwrap();
+        for file in files {
+            std::fs::write(temp_dir.path().join(file), "").unwrap();
+        }
+        if let Some(gitignore) = gitignore {
+            std::fs::write(temp_dir.path().join(".gitignore"), gitignore).unwrap();
+        }
+        temp_dir
+    }
+
+    #[test]
+    fn test_complete_gitignore_passes() {
+        let repo = fixture(
+            &["package.json", "pyproject.toml", "Cargo.toml", "go.mod"],
+            Some("# deps\n/node_modules/\n**/__pycache__\ntarget/**\nbin/\n"),
+        );
+
+        let result = GitignoreChecker.check(repo.path());
+        assert_eq!(result.category, Category::CodeQuality);
+        assert_eq!(result.findings, Vec::<String>::new());
+        assert_eq!(result.score, 1.0);
+    }
+
+    #[test]
+    fn test_partial_gitignore_lists_gaps() {
+        let repo = fixture(
+            &["package.json", "setup.py", "pom.xml"],
+            Some("*.py[cod]\n!node_modules\n"),
+        );
+
+        let result = GitignoreChecker.check(repo.path());
+        assert_eq!(
+            result.findings,
+            vec!["node_modules is not ignored", "target/ is not ignored"]
+        );
+        // The file and the Python artifacts of four criteria
+        assert_eq!(result.score, 0.5);
+    }
+
+    #[test]
+    fn test_missing_gitignore() {
+        let repo = fixture(&["Cargo.toml"], None);
+        let result = GitignoreChecker.check(repo.path());
+        assert_eq!(
+            result.findings,
+            vec!["no .gitignore file", "target/ is not ignored"]
+        );
+        // Gaps are a warning, not critical
+        assert_eq!(result.score, CRITICAL_BELOW);
+        assert!(!result.critical);
+
+        // Without a detected language only the file itself counts
+        let repo = fixture(&[], Some(".DS_Store\n"));
+        let result = GitignoreChecker.check(repo.path());
+        assert!(result.findings.is_empty());
+        assert_eq!(result.score, 1.0);
+    }
+}
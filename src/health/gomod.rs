@@ -0,0 +1,342 @@
+//! Go module dependency drift check
+//!
+//! For repositories with a `go.mod`, `go mod verify` must confirm that the
+//! downloaded dependencies match `go.sum`, and `go mod tidy` must leave
+//! `go.mod` and `go.sum` unchanged. Tidiness is tried on a temporary copy of
+//! the working tree, so the checkout itself is never modified. Paths ignored
+//! by git and paths matching the [`ScanExclude`] patterns are left out of the
+//! copy. An untidy module lowers the score without marking the repository
+//! critical. Without the `go` toolchain the module is reported as a finding
+//! but not scored.
+
+use super::{Category, CheckResult, Checker, ScanExclude};
+use std::io;
+use std::path::{Path, PathBuf};
+use std::process::{Command, Output};
+use walkdir::WalkDir;
+
+/// The files `go mod tidy` may rewrite
+const MODULE_FILES: [&str; 2] = ["go.mod", "go.sum"];
+
+/// Checks that a Go module is verified and tidy
+pub struct GoModChecker {
+    program: String,
+    scan_exclude: ScanExclude,
+}
+
+impl GoModChecker {
+    pub fn new() -> Self {
+        Self::with_program("go")
+    }
+
+    /// Use `program` as the go toolchain instead of `go` on the `PATH`
+    pub fn with_program(program: impl Into<String>) -> Self {
+        Self {
+            program: program.into(),
+            scan_exclude: ScanExclude::default(),
+        }
+    }
+
+    /// Leave paths matching `scan_exclude` out of the tidied copy
+    pub fn with_scan_exclude(mut self, scan_exclude: ScanExclude) -> Self {
+        self.scan_exclude = scan_exclude;
+        self
+    }
+
+    fn go(&self, dir: &Path, args: &[&str]) -> io::Result<Output> {
+        Command::new(&self.program)
+            .args(args)
+            .current_dir(dir)
+            .output()
+    }
+
+    /// The module files `go mod tidy` changes, found by tidying a copy of the tree
+    fn tidy_changes(&self, repo_path: &Path) -> Result<Vec<&'static str>, String> {
+        let copy = tempfile::TempDir::new().map_err(|e| e.to_string())?;
+        copy_tree(
+            repo_path,
+            copy.path(),
+            &self.scan_exclude,
+            &ignored_paths(repo_path),
+        )
+        .map_err(|e| format!("cannot copy the module: {e}"))?;
+
+        let output = self
+            .go(copy.path(), &["mod", "tidy"])
+            .map_err(|e| e.to_string())?;
+        if !output.status.success() {
+            return Err(first_line(&output.stderr));
+        }
+
+        Ok(MODULE_FILES
+            .into_iter()
+            .filter(|file| {
+                std::fs::read(repo_path.join(file)).ok()
+                    != std::fs::read(copy.path().join(file)).ok()
+            })
+            .collect())
+    }
+}
+
+impl Default for GoModChecker {
+    fn default() -> Self {
+        Self::new()
+    }
+}
+
+impl Checker for GoModChecker {
+    fn name(&self) -> &'static str {
+        "gomod"
+    }
+
+    fn category(&self) -> Category {
+        Category::Dependencies
+    }
+
+    fn check(&self, repo_path: &Path) -> CheckResult {
+        if !repo_path.join("go.mod").is_file() {
+            return CheckResult::from_criteria(self.name(), self.category(), 0, 0, vec![]);
+        }
+
+        let mut checked = 0;
+        let mut passed = 0;
+        let mut findings = Vec::new();
+
+        match self.go(repo_path, &["mod", "verify"]) {
+            Err(e) if e.kind() == io::ErrorKind::NotFound => {
+                findings.push(format!(
+                    "{} not installed; Go module not checked",
+                    self.program
+                ));
+                return CheckResult::from_criteria(self.name(), self.category(), 0, 0, findings);
+            }
+            Err(e) => findings.push(format!("go mod verify failed: {}", e)),
+            Ok(output) => {
+                checked += 1;
+                if output.status.success() {
+                    passed += 1;
+                } else {
+                    findings.push(format!(
+                        "go mod verify failed: {}",
+                        first_line(&output.stderr)
+                    ));
+                }
+            }
+        }
+
+        match self.tidy_changes(repo_path) {
+            Ok(changed) => {
+                checked += 1;
+                if changed.is_empty() {
+                    passed += 1;
+                } else {
+                    findings.push(format!(
+                        "go mod tidy would change {}",
+                        changed.join(" and ")
+                    ));
+                }
+            }
+            Err(e) => findings.push(format!("go mod tidy failed: {}", e)),
+        }
+
+        CheckResult::from_criteria(self.name(), self.category(), passed, checked, findings)
+    }
+}
+
+/// Paths below `repo_path` that git ignores, relative to it; empty outside a
+/// git checkout or without `git`
+fn ignored_paths(repo_path: &Path) -> Vec<PathBuf> {
+    let output = Command::new("git")
+        .args([
+            "ls-files",
+            "-z",
+            "--others",
+            "--ignored",
+            "--exclude-standard",
+            "--directory",
+        ])
+        .current_dir(repo_path)
+        .output();
+    match output {
+        Ok(output) if output.status.success() => String::from_utf8_lossy(&output.stdout)
+            .split('\0')
+            .filter(|path| !path.is_empty())
+            .map(|path| PathBuf::from(path.trim_end_matches('/')))
+            .collect(),
+        _ => Vec::new(),
+    }
+}
+
+/// Copy the working tree at `from` into `to`, leaving out `.git`, symlinks,
+/// `ignored` paths and excluded paths
+fn copy_tree(from: &Path, to: &Path, exclude: &ScanExclude, ignored: &[PathBuf]) -> io::Result<()> {
+    let walk = WalkDir::new(from).into_iter().filter_entry(|entry| {
+        // `.git`, ignored and excluded directories are skipped with everything below them
+        entry.path().strip_prefix(from).is_ok_and(|relative| {
+            relative.as_os_str().is_empty()
+                || !(relative.ends_with(".git")
+                    || ignored.iter().any(|path| path == relative)
+                    || exclude.is_excluded(relative))
+        })
+    });
+    for entry in walk {
+        let entry = entry.map_err(io::Error::other)?;
+        if !entry.file_type().is_file() {
+            continue;
+        }
+        let Ok(relative) = entry.path().strip_prefix(from) else {
+            continue;
+        };
+        let target = to.join(relative);
+        if let Some(parent) = target.parent() {
+            std::fs::create_dir_all(parent)?;
+        }
+        std::fs::copy(entry.path(), &target)?;
+    }
+    Ok(())
+}
+
+fn first_line(output: &[u8]) -> String {
+    let text = String::from_utf8_lossy(output);
+    text.lines()
+        .find(|line| !line.trim().is_empty())
+        .unwrap_or("no output")
+        .trim()
+        .to_string()
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use std::path::PathBuf;
+
+    fn fixture(name: &str) -> PathBuf {
+        Path::new(env!("CARGO_MANIFEST_DIR"))
+            .join("tests/fixtures/health/gomod")
+            .join(name)
+    }
+
+    /// The tidiness tests need a real toolchain; skip them without one
+    fn go_installed() -> bool {
+        Command::new("go").arg("version").output().is_ok()
+    }
+
+    #[test]
+    fn test_tidy_module_passes() {
+        if !go_installed() {
+            return;
+        }
+        let result = GoModChecker::new().check(&fixture("tidy"));
+        assert_eq!(result.category, Category::Dependencies);
+        assert_eq!(result.findings, Vec::<String>::new());
+        assert_eq!(result.score, 1.0);
+    }
+
+    #[test]
+    fn test_untidy_module_is_a_warning() {
+        if !go_installed() {
+            return;
+        }
+        let module = fixture("untidy");
+        let go_sum = std::fs::read_to_string(module.join("go.sum")).unwrap();
+
+        let result = GoModChecker::new().check(&module);
+        assert_eq!(result.findings, vec!["go mod tidy would change go.sum"]);
+        assert_eq!(result.score, 0.5);
+        assert!(!result.critical);
+        // Only the temporary copy was tidied
+        assert_eq!(
+            std::fs::read_to_string(module.join("go.sum")).unwrap(),
+            go_sum
+        );
+    }
+
+    #[test]
+    fn test_missing_toolchain_is_not_scored() {
+        let result = GoModChecker::with_program("go-not-installed").check(&fixture("tidy"));
+        assert_eq!(result.score, 1.0);
+        assert_eq!(
+            result.findings,
+            vec!["go-not-installed not installed; Go module not checked"]
+        );
+    }
+
+    #[test]
+    fn test_repository_without_go_mod_is_skipped() {
+        let temp_dir = tempfile::TempDir::new().unwrap();
+        std::fs::write(temp_dir.path().join("main.go"), "package main\n").unwrap();
+
+        let result = GoModChecker::with_program("go-not-installed").check(temp_dir.path());
+        assert_eq!(result.score, 1.0);
+        assert!(result.findings.is_empty());
+    }
+
+    #[test]
+    fn test_excluded_paths_are_not_copied() {
+        let from = tempfile::TempDir::new().unwrap();
+        let to = tempfile::TempDir::new().unwrap();
+        for file in [
+            ".git/HEAD",
+            "pkg/api/api.go",
+            "bin/tool.go",
+            "vendor/example.com/lib/lib.go",
+            "pkg/api/generated/api.pb.go",
+        ] {
+            let path = from.path().join(file);
+            std::fs::create_dir_all(path.parent().unwrap()).unwrap();
+            std::fs::write(path, "package x\n").unwrap();
+        }
+
+        let exclude = ScanExclude::new(["vendor", "**/generated"]).unwrap();
+        copy_tree(from.path(), to.path(), &exclude, &[PathBuf::from("bin")]).unwrap();
+        assert!(to.path().join("pkg/api/api.go").is_file());
+        for left_out in [".git", "bin", "vendor", "pkg/api/generated"] {
+            assert!(!to.path().join(left_out).exists(), "{}", left_out);
+        }
+    }
+
+    /// Only the copy's contents decide the result: this stand-in for `go`
+    /// dirties go.sum on `mod tidy` when the excluded `generated` package or the
+    /// ignored `build` directory was copied
+    #[cfg(unix)]
+    #[test]
+    fn test_excluded_and_ignored_paths_cause_no_drift() {
+        use std::os::unix::fs::PermissionsExt;
+
+        let temp_dir = tempfile::TempDir::new().unwrap();
+        let go = temp_dir.path().join("fake-go");
+        std::fs::write(
+            &go,
+            "#!/bin/sh\nif [ \"$2\" = tidy ] && { [ -d generated ] || [ -d build ]; }; \
+             then echo drift >> go.sum; fi\n",
+        )
+        .unwrap();
+        std::fs::set_permissions(&go, std::fs::Permissions::from_mode(0o755)).unwrap();
+
+        let module = temp_dir.path().join("module");
+        std::fs::create_dir_all(module.join("generated")).unwrap();
+        std::fs::create_dir_all(module.join("build")).unwrap();
+        std::fs::write(module.join("go.mod"), "module example.com/m\n").unwrap();
+        std::fs::write(module.join("generated/gen.go"), "package generated\n").unwrap();
+        std::fs::write(module.join("generated/schema.json"), "{}").unwrap();
+        std::fs::write(module.join("build/out.go"), "package build\n").unwrap();
+
+        let checker = GoModChecker::with_program(go.to_string_lossy())
+            .with_scan_exclude(ScanExclude::new(["generated"]).unwrap());
+        assert_eq!(
+            checker.check(&module).findings,
+            vec!["go mod tidy would change go.sum"]
+        );
+
+        std::fs::write(module.join(".gitignore"), "build/\n").unwrap();
+        let status = Command::new("git")
+            .args(["init", "--quiet"])
+            .current_dir(&module)
+            .status()
+            .unwrap();
+        assert!(status.success());
+        let result = checker.check(&module);
+        assert_eq!(result.findings, Vec::<String>::new());
+        assert_eq!(result.score, 1.0);
+    }
+}
@@ -0,0 +1,198 @@
+//! Health scores recorded over time
+//!
+//! `repos health check --history-file <path>` appends one [`HistoryEntry`]
+//! per run to a JSON Lines file: when the run happened and the score of each
+//! checked repository. Repositories are identified by their configured name,
+//! as in merged reports. `repos health trend` reads the file back and
+//! compares every repository in the latest run with its previous score.
+
+use super::HealthReport;
+use anyhow::{Context, Result};
+use chrono::{DateTime, Utc};
+use serde::{Deserialize, Serialize};
+use std::collections::BTreeMap;
+use std::fs::OpenOptions;
+use std::io::Write;
+use std::path::Path;
+
+/// The scores of one health check run
+#[derive(Debug, Clone, PartialEq, Serialize, Deserialize)]
+pub struct HistoryEntry {
+    pub timestamp: DateTime<Utc>,
+    /// Score per repository name, from 0.0 to 1.0
+    pub scores: BTreeMap<String, f64>,
+}
+
+impl HistoryEntry {
+    pub fn from_reports(timestamp: DateTime<Utc>, reports: &[HealthReport]) -> Self {
+        Self {
+            timestamp,
+            scores: reports
+                .iter()
+                .map(|report| (report.repository.clone(), report.score()))
+                .collect(),
+        }
+    }
+
+    /// Add this entry as the last line of the history file, creating it if needed
+    ///
+    /// The line is written with a single append so that concurrent runs do
+    /// not interleave their entries.
+    pub fn append(&self, path: &Path) -> Result<()> {
+        let mut line = serde_json::to_string(self)?;
+        line.push('\n');
+        let mut file = OpenOptions::new()
+            .create(true)
+            .append(true)
+            .open(path)
+            .with_context(|| format!("Failed to open health history {}", path.display()))?;
+        file.write_all(line.as_bytes())
+            .with_context(|| format!("Failed to write health history {}", path.display()))
+    }
+}
+
+/// Read every entry of a history file, oldest first
+pub fn load_history(path: &Path) -> Result<Vec<HistoryEntry>> {
+    let content = std::fs::read_to_string(path)
+        .with_context(|| format!("Failed to read health history {}", path.display()))?;
+    content
+        .lines()
+        .enumerate()
+        .filter(|(_, line)| !line.trim().is_empty())
+        .map(|(index, line)| {
+            serde_json::from_str(line).with_context(|| {
+                format!(
+                    "Invalid health history entry at {}:{}",
+                    path.display(),
+                    index + 1
+                )
+            })
+        })
+        .collect()
+}
+
+/// A repository's latest score and the one before it
+#[derive(Debug, Clone, PartialEq)]
+pub struct ScoreTrend {
+    pub repository: String,
+    pub score: f64,
+    /// Score in the most recent earlier run that checked the repository
+    pub previous: Option<f64>,
+}
+
+impl ScoreTrend {
+    /// Change since the previous score; `None` for a newly checked repository
+    pub fn delta(&self) -> Option<f64> {
+        self.previous.map(|previous| self.score - previous)
+    }
+}
+
+/// Compare each repository in the latest entry with its previous score
+pub fn score_trends(history: &[HistoryEntry]) -> Vec<ScoreTrend> {
+    let Some((latest, earlier)) = history.split_last() else {
+        return Vec::new();
+    };
+    latest
+        .scores
+        .iter()
+        .map(|(repository, score)| ScoreTrend {
+            repository: repository.clone(),
+            score: *score,
+            previous: earlier
+                .iter()
+                .rev()
+                .find_map(|entry| entry.scores.get(repository).copied()),
+        })
+        .collect()
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::health::{Category, CheckResult};
+    use chrono::TimeZone;
+
+    fn reports(scores: &[(&str, usize)]) -> Vec<HealthReport> {
+        scores
+            .iter()
+            .map(|(name, passed)| HealthReport {
+                repository: name.to_string(),
+                results: vec![CheckResult::from_criteria(
+                    "readme",
+                    Category::Documentation,
+                    *passed,
+                    4,
+                    vec![],
+                )],
+                ignored: Vec::new(),
+            })
+            .collect()
+    }
+
+    #[test]
+    fn test_append_two_runs_and_compute_deltas() {
+        let temp_dir = tempfile::TempDir::new().unwrap();
+        let path = temp_dir.path().join("history.jsonl");
+
+        let first = Utc.with_ymd_and_hms(2026, 1, 5, 9, 0, 0).unwrap();
+        HistoryEntry::from_reports(first, &reports(&[("api", 2), ("web", 4)]))
+            .append(&path)
+            .unwrap();
+        let second = Utc.with_ymd_and_hms(2026, 1, 12, 9, 0, 0).unwrap();
+        HistoryEntry::from_reports(second, &reports(&[("api", 3), ("web", 3), ("docs", 4)]))
+            .append(&path)
+            .unwrap();
+
+        let history = load_history(&path).unwrap();
+        assert_eq!(history.len(), 2);
+        assert_eq!(history[0].timestamp, first);
+        assert_eq!(history[1].scores["docs"], 1.0);
+
+        let trends = score_trends(&history);
+        let deltas: Vec<(&str, Option<f64>)> = trends
+            .iter()
+            .map(|trend| (trend.repository.as_str(), trend.delta()))
+            .collect();
+        assert_eq!(
+            deltas,
+            vec![("api", Some(0.25)), ("docs", None), ("web", Some(-0.25))]
+        );
+    }
+
+    #[test]
+    fn test_previous_score_comes_from_last_run_checking_the_repository() {
+        let at = |day| Utc.with_ymd_and_hms(2026, 2, day, 0, 0, 0).unwrap();
+        let history = vec![
+            HistoryEntry::from_reports(at(1), &reports(&[("api", 1)])),
+            HistoryEntry::from_reports(at(2), &reports(&[("web", 4)])),
+            HistoryEntry::from_reports(at(3), &reports(&[("api", 4)])),
+        ];
+        assert_eq!(
+            score_trends(&history),
+            vec![ScoreTrend {
+                repository: "api".to_string(),
+                score: 1.0,
+                previous: Some(0.25),
+            }]
+        );
+        assert!(score_trends(&[]).is_empty());
+    }
+
+    #[test]
+    fn test_load_history_reports_invalid_lines() {
+        let temp_dir = tempfile::TempDir::new().unwrap();
+        let path = temp_dir.path().join("history.jsonl");
+        std::fs::write(
+            &path,
+            "{\"timestamp\":\"2026-01-05T09:00:00Z\",\"scores\":{}}\n\nnot json\n",
+        )
+        .unwrap();
+
+        let error = load_history(&path).unwrap_err();
+        assert_eq!(
+            error.to_string(),
+            format!("Invalid health history entry at {}:3", path.display())
+        );
+        assert!(load_history(&temp_dir.path().join("missing.jsonl")).is_err());
+    }
+}
@@ -0,0 +1,108 @@
+//! Checks skipped for a single repository
+//!
+//! A check can be irrelevant to a repository, or flag a known false positive
+//! there. Listing it under the repository's `health_ignore` in the config, or
+//! in a `# repos:ignore <check>` line of a `.repos-health` file at the
+//! repository root, skips it for that repository only: it does not run and
+//! does not count towards the score. Other lines of the file are ignored, but
+//! a name that matches no checker is reported by [`unknown_checks`], since a
+//! typo such as `gitgnore` would otherwise skip nothing without a word.
+
+use crate::config::Repository;
+use std::path::Path;
+
+/// File at the repository root that may hold ignore markers
+pub const MARKER_FILE: &str = ".repos-health";
+
+/// Prefix of a line in [`MARKER_FILE`] naming a check to skip
+const MARKER: &str = "# repos:ignore";
+
+/// Checks named by `# repos:ignore <check>` lines of `repo_path`'s marker file
+pub fn marker_ignores(repo_path: &Path) -> Vec<String> {
+    let Ok(content) = std::fs::read_to_string(repo_path.join(MARKER_FILE)) else {
+        return Vec::new();
+    };
+    content
+        .lines()
+        .filter_map(|line| line.trim().strip_prefix(MARKER))
+        // `# repos:ignored` is not a marker
+        .filter(|rest| rest.starts_with(char::is_whitespace))
+        .flat_map(str::split_whitespace)
+        .map(str::to_string)
+        .collect()
+}
+
+/// Checks to skip for `repo` checked out at `repo_path`: its `health_ignore`
+/// followed by the marker file's, each named once
+pub fn ignored_checks(repo: &Repository, repo_path: &Path) -> Vec<String> {
+    let mut ignored: Vec<String> = Vec::new();
+    for check in repo
+        .health_ignore
+        .iter()
+        .cloned()
+        .chain(marker_ignores(repo_path))
+    {
+        if !ignored.contains(&check) {
+            ignored.push(check);
+        }
+    }
+    ignored
+}
+
+/// Names in `ignored` that are not among the `known` checker names
+pub fn unknown_checks<'a>(ignored: &'a [String], known: &[String]) -> Vec<&'a str> {
+    ignored
+        .iter()
+        .filter(|check| !known.contains(check))
+        .map(String::as_str)
+        .collect()
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use tempfile::TempDir;
+
+    #[test]
+    fn test_marker_ignores_reads_marker_lines() {
+        let temp_dir = TempDir::new().unwrap();
+        assert!(marker_ignores(temp_dir.path()).is_empty());
+
+        std::fs::write(
+            temp_dir.path().join(MARKER_FILE),
+            "# Generated client, vendored as is\n\
+             # repos:ignore readme\n  # repos:ignore gitignore dockerfile\n\
+             # repos:ignored license\n# repos:ignore\n",
+        )
+        .unwrap();
+        assert_eq!(
+            marker_ignores(temp_dir.path()),
+            vec!["readme", "gitignore", "dockerfile"]
+        );
+    }
+
+    #[test]
+    fn test_ignored_checks_combines_config_and_marker() {
+        let temp_dir = TempDir::new().unwrap();
+        std::fs::write(
+            temp_dir.path().join(MARKER_FILE),
+            "# repos:ignore license\n# repos:ignore signing\n",
+        )
+        .unwrap();
+        let mut repo = Repository::new("api".to_string(), "git@github.com:o/api.git".to_string());
+        repo.health_ignore = vec!["signing".to_string(), "readme".to_string()];
+
+        assert_eq!(
+            ignored_checks(&repo, temp_dir.path()),
+            vec!["signing", "readme", "license"]
+        );
+    }
+
+    #[test]
+    fn test_unknown_checks_reports_misspelled_names() {
+        let known = vec!["readme".to_string(), "gitignore".to_string()];
+        let ignored = vec!["gitgnore".to_string(), "readme".to_string()];
+        assert_eq!(unknown_checks(&ignored, &known), vec!["gitgnore"]);
+        assert!(unknown_checks(&[], &known).is_empty());
+    }
+}
@@ -0,0 +1,61 @@
+//! License file presence check
+
+use super::{Category, CheckResult, Checker, find_root_file};
+use std::path::Path;
+
+const LICENSE_STEMS: &[&str] = &["LICENSE", "LICENCE", "COPYING"];
+
+/// Checks that the repository ships a license file
+pub struct LicenseChecker;
+
+impl Checker for LicenseChecker {
+    fn name(&self) -> &'static str {
+        "license"
+    }
+
+    fn category(&self) -> Category {
+        Category::Documentation
+    }
+
+    fn check(&self, repo_path: &Path) -> CheckResult {
+        let found = find_root_file(repo_path, LICENSE_STEMS).is_some();
+        let findings = if found {
+            vec![]
+        } else {
+            vec!["no LICENSE file".to_string()]
+        };
+        CheckResult::from_criteria(
+            self.name(),
+            self.category(),
+            usize::from(found),
+            1,
+            findings,
+        )
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use tempfile::TempDir;
+
+    #[test]
+    fn test_license_present() {
+        let temp_dir = TempDir::new().unwrap();
+        std::fs::write(temp_dir.path().join("License.txt"), "MIT").unwrap();
+        std::fs::write(temp_dir.path().join("COPYING"), "GPL").unwrap();
+
+        let result = LicenseChecker.check(temp_dir.path());
+        assert_eq!(result.score, 1.0);
+        assert!(result.findings.is_empty());
+    }
+
+    #[test]
+    fn test_license_missing() {
+        let temp_dir = TempDir::new().unwrap();
+
+        let result = LicenseChecker.check(temp_dir.path());
+        assert_eq!(result.score, 0.0);
+        assert_eq!(result.findings, vec!["no LICENSE file"]);
+    }
+}
@@ -0,0 +1,563 @@
+//! Static health checks run against a repository checkout
+//!
+//! Each checker inspects the working tree and returns a score between 0 and 1
+//! together with human-readable findings explaining any missing credit. The
+//! `repos-health` plugin reports these scores; `repos run --where-health` uses
+//! them to select repositories.
+
+pub mod baseline;
+pub mod branching;
+pub mod dockerfile;
+pub mod factory;
+pub mod gitignore;
+pub mod gomod;
+pub mod history;
+pub mod ignore;
+mod license;
+pub mod parallel;
+pub mod readme;
+pub mod report;
+pub mod scan;
+pub mod sensitive;
+pub mod signing;
+pub mod vulnerabilities;
+
+pub use baseline::{Regression, RegressionKind, find_regressions};
+pub use branching::{BranchingChecker, BranchingOptions};
+pub use dockerfile::{DockerfileChecker, DockerfileOptions, DockerfileRule};
+pub use factory::{CategoryDefinition, CheckerFactory};
+pub use gitignore::GitignoreChecker;
+pub use gomod::GoModChecker;
+pub use history::{HistoryEntry, ScoreTrend, load_history, score_trends};
+pub use ignore::{ignored_checks, unknown_checks};
+pub use license::LicenseChecker;
+pub use parallel::{ReportOrder, check_parallel};
+pub use readme::{ReadmeChecker, ReadmeOptions};
+pub use report::{FleetReport, HealthSummary};
+pub use scan::ScanExclude;
+pub use sensitive::{SensitiveFilesChecker, SensitiveFilesOptions};
+pub use signing::{SigningChecker, SigningOptions};
+pub use vulnerabilities::{Severity, VulnerabilityChecker};
+
+use crate::config::Repository;
+use anyhow::{Result, bail};
+use serde::{Deserialize, Serialize};
+use std::fmt;
+use std::path::{Path, PathBuf};
+use std::str::FromStr;
+
+/// Repositories scoring below this are critical
+pub const CRITICAL_BELOW: f64 = 0.5;
+
+/// Repositories scoring below this (but not critical) need attention
+pub const WARNING_BELOW: f64 = 0.8;
+
+/// Area of repository health a checker contributes to
+#[derive(Debug, Clone, Copy, PartialEq, Eq, PartialOrd, Ord, Serialize, Deserialize)]
+#[serde(rename_all = "kebab-case")]
+pub enum Category {
+    Documentation,
+    Infrastructure,
+    Security,
+    Dependencies,
+    Governance,
+    CodeQuality,
+}
+
+impl Category {
+    pub const ALL: [Self; 6] = [
+        Self::Documentation,
+        Self::Infrastructure,
+        Self::Security,
+        Self::Dependencies,
+        Self::Governance,
+        Self::CodeQuality,
+    ];
+}
+
+impl FromStr for Category {
+    type Err = anyhow::Error;
+
+    fn from_str(value: &str) -> Result<Self> {
+        match Self::ALL
+            .into_iter()
+            .find(|category| category.to_string() == value)
+        {
+            Some(category) => Ok(category),
+            None => bail!(
+                "Unknown category '{}': expected documentation, infrastructure, security, dependencies, governance or code-quality",
+                value
+            ),
+        }
+    }
+}
+
+impl fmt::Display for Category {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        match self {
+            Self::Documentation => write!(f, "documentation"),
+            Self::Infrastructure => write!(f, "infrastructure"),
+            Self::Security => write!(f, "security"),
+            Self::Dependencies => write!(f, "dependencies"),
+            Self::Governance => write!(f, "governance"),
+            Self::CodeQuality => write!(f, "code-quality"),
+        }
+    }
+}
+
+/// Outcome of a single checker for one repository
+#[derive(Debug, Clone, PartialEq, Serialize, Deserialize)]
+pub struct CheckResult {
+    pub checker: String,
+    pub category: Category,
+    /// Fraction of the available credit earned, from 0.0 to 1.0
+    pub score: f64,
+    pub findings: Vec<String>,
+    /// Makes the repository critical regardless of its score
+    pub critical: bool,
+}
+
+impl CheckResult {
+    /// Score a check from the number of criteria it satisfied
+    pub fn from_criteria(
+        checker: &str,
+        category: Category,
+        passed: usize,
+        total: usize,
+        findings: Vec<String>,
+    ) -> Self {
+        let score = if total == 0 {
+            1.0
+        } else {
+            passed as f64 / total as f64
+        };
+        Self {
+            checker: checker.to_string(),
+            category,
+            score,
+            findings,
+            critical: false,
+        }
+    }
+
+    /// Flag the result as critical, e.g. for known high-severity vulnerabilities
+    pub fn mark_critical(self) -> Self {
+        Self {
+            critical: true,
+            ..self
+        }
+    }
+
+    /// Raise the score to at least [`CRITICAL_BELOW`], for checks whose gaps
+    /// warrant attention but never make a repository critical
+    pub fn at_most_warning(self) -> Self {
+        Self {
+            score: self.score.max(CRITICAL_BELOW),
+            ..self
+        }
+    }
+}
+
+/// Settings for the checkers built by [`CheckerFactory`]
+#[derive(Debug, Clone, Default)]
+pub struct HealthOptions {
+    pub readme: ReadmeOptions,
+    pub dockerfile: DockerfileOptions,
+    pub signing: SigningOptions,
+    pub sensitive_files: SensitiveFilesOptions,
+    pub branching: BranchingOptions,
+    /// Paths skipped by checkers that walk the working tree
+    pub scan_exclude: ScanExclude,
+}
+
+/// A single health check
+///
+/// Checkers are shared between threads by [`check_parallel`].
+pub trait Checker: Send + Sync {
+    /// Short identifier shown in reports
+    fn name(&self) -> &'static str;
+
+    fn category(&self) -> Category;
+
+    /// Inspect the repository checked out at `repo_path`
+    fn check(&self, repo_path: &Path) -> CheckResult;
+}
+
+/// Build the default set of checkers
+pub fn default_checkers(
+    readme: ReadmeOptions,
+    dockerfile: DockerfileOptions,
+) -> Vec<Box<dyn Checker>> {
+    CheckerFactory::new(readme, dockerfile).checkers()
+}
+
+/// Run every checker against a repository
+pub fn run_checks(repo_path: &Path, checkers: &[Box<dyn Checker>]) -> Vec<CheckResult> {
+    checkers
+        .iter()
+        .map(|checker| checker.check(repo_path))
+        .collect()
+}
+
+/// Average score across results, or `None` when nothing was checked
+pub fn overall_score(results: &[CheckResult]) -> Option<f64> {
+    if results.is_empty() {
+        return None;
+    }
+    Some(results.iter().map(|r| r.score).sum::<f64>() / results.len() as f64)
+}
+
+/// Overall health derived from a repository's score
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize, Deserialize)]
+#[serde(rename_all = "lowercase")]
+pub enum HealthStatus {
+    Healthy,
+    Warning,
+    Critical,
+}
+
+impl HealthStatus {
+    pub fn from_score(score: f64) -> Self {
+        if score < CRITICAL_BELOW {
+            Self::Critical
+        } else if score < WARNING_BELOW {
+            Self::Warning
+        } else {
+            Self::Healthy
+        }
+    }
+}
+
+impl fmt::Display for HealthStatus {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        match self {
+            Self::Healthy => write!(f, "healthy"),
+            Self::Warning => write!(f, "warning"),
+            Self::Critical => write!(f, "critical"),
+        }
+    }
+}
+
+/// Results of every checker for one repository
+#[derive(Debug, Clone, PartialEq, Serialize, Deserialize)]
+pub struct HealthReport {
+    pub repository: String,
+    pub results: Vec<CheckResult>,
+    /// Checkers skipped for this repository (see [`ignore`])
+    #[serde(default, skip_serializing_if = "Vec::is_empty")]
+    pub ignored: Vec<String>,
+}
+
+impl HealthReport {
+    /// Average score, treating a repository with no checks as fully healthy
+    pub fn score(&self) -> f64 {
+        overall_score(&self.results).unwrap_or(1.0)
+    }
+
+    /// Status from the score, or critical when any check flagged the repository
+    pub fn status(&self) -> HealthStatus {
+        if self.results.iter().any(|result| result.critical) {
+            return HealthStatus::Critical;
+        }
+        HealthStatus::from_score(self.score())
+    }
+}
+
+/// A repository checkout to check, with the checkers it ignores
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct HealthTarget {
+    pub name: String,
+    pub path: PathBuf,
+    /// Checker names skipped for this repository
+    pub ignore: Vec<String>,
+}
+
+impl HealthTarget {
+    pub fn new(name: String, path: PathBuf) -> Self {
+        Self {
+            name,
+            path,
+            ignore: Vec::new(),
+        }
+    }
+
+    /// The clone of `repo`, ignoring its `health_ignore` and marker file checks
+    pub fn from_repository(repo: &Repository) -> Self {
+        let path = PathBuf::from(repo.get_target_dir());
+        Self {
+            name: repo.name.clone(),
+            ignore: ignored_checks(repo, &path),
+            path,
+        }
+    }
+
+    /// Run the checkers that are not ignored
+    pub fn check(&self, checkers: &[Box<dyn Checker>]) -> HealthReport {
+        let (ignored, checkers): (Vec<_>, Vec<_>) = checkers
+            .iter()
+            .partition(|checker| self.ignore.iter().any(|name| name == checker.name()));
+        HealthReport {
+            repository: self.name.clone(),
+            results: checkers
+                .iter()
+                .map(|checker| checker.check(&self.path))
+                .collect(),
+            ignored: ignored
+                .iter()
+                .map(|checker| checker.name().to_string())
+                .collect(),
+        }
+    }
+}
+
+/// Check every cloned repository; repositories that are not cloned get no report
+pub fn check_all_repositories(
+    repositories: &[Repository],
+    checkers: &[Box<dyn Checker>],
+) -> Vec<HealthReport> {
+    repositories
+        .iter()
+        .filter(|repo| repo.exists())
+        .map(|repo| HealthTarget::from_repository(repo).check(checkers))
+        .collect()
+}
+
+/// Which repositories `--where-health` selects
+#[derive(Debug, Clone, Copy, PartialEq)]
+pub enum HealthFilter {
+    /// Status is critical
+    Critical,
+    /// Status is warning (critical repositories are not included)
+    Warning,
+    /// Score is below this fraction
+    BelowScore(f64),
+}
+
+impl HealthFilter {
+    pub fn matches(&self, report: &HealthReport) -> bool {
+        match self {
+            Self::Critical => report.status() == HealthStatus::Critical,
+            Self::Warning => report.status() == HealthStatus::Warning,
+            Self::BelowScore(threshold) => report.score() < *threshold,
+        }
+    }
+}
+
+impl FromStr for HealthFilter {
+    type Err = anyhow::Error;
+
+    /// Parse `critical`, `warning` or `below-score=<percent>`
+    fn from_str(value: &str) -> Result<Self> {
+        match value {
+            "critical" => Ok(Self::Critical),
+            "warning" => Ok(Self::Warning),
+            _ => {
+                let Some(percent) = value.strip_prefix("below-score=") else {
+                    bail!(
+                        "Invalid health filter '{}': expected critical, warning or below-score=<percent>",
+                        value
+                    );
+                };
+                match percent.trim_end_matches('%').parse::<f64>() {
+                    Ok(percent) if (0.0..=100.0).contains(&percent) => {
+                        Ok(Self::BelowScore(percent / 100.0))
+                    }
+                    _ => bail!(
+                        "Invalid score '{}' in health filter: expected a percentage between 0 and 100",
+                        percent
+                    ),
+                }
+            }
+        }
+    }
+}
+
+/// Find a file in the repository root whose name, ignoring case and
+/// extension, matches one of `stems`
+pub(crate) fn find_root_file(repo_path: &Path, stems: &[&str]) -> Option<std::path::PathBuf> {
+    let mut candidates: Vec<_> = std::fs::read_dir(repo_path)
+        .ok()?
+        .filter_map(|entry| entry.ok())
+        .map(|entry| entry.path())
+        .filter(|path| path.is_file())
+        .filter(|path| {
+            path.file_name()
+                .and_then(|name| name.to_str())
+                .map(|name| {
+                    let stem = name.split('.').next().unwrap_or(name);
+                    stems.iter().any(|s| stem.eq_ignore_ascii_case(s))
+                })
+                .unwrap_or(false)
+        })
+        .collect();
+    // Prefer the shortest name so `README.md` wins over `README.ja.md`
+    candidates.sort_by_key(|path| path.as_os_str().len());
+    candidates.into_iter().next()
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use tempfile::TempDir;
+
+    #[test]
+    fn test_from_criteria_partial_credit() {
+        let result = CheckResult::from_criteria("x", Category::Documentation, 3, 4, vec![]);
+        assert_eq!(result.score, 0.75);
+        let result = CheckResult::from_criteria("x", Category::Documentation, 0, 0, vec![]);
+        assert_eq!(result.score, 1.0);
+    }
+
+    #[test]
+    fn test_overall_score_averages_results() {
+        let results = vec![
+            CheckResult::from_criteria("a", Category::Documentation, 1, 2, vec![]),
+            CheckResult::from_criteria("b", Category::Documentation, 1, 1, vec![]),
+        ];
+        assert_eq!(overall_score(&results), Some(0.75));
+        assert_eq!(overall_score(&[]), None);
+    }
+
+    fn report(score: f64) -> HealthReport {
+        HealthReport {
+            repository: "r".to_string(),
+            results: vec![CheckResult {
+                checker: "stub".to_string(),
+                category: Category::Documentation,
+                score,
+                findings: vec![],
+                critical: false,
+            }],
+            ignored: Vec::new(),
+        }
+    }
+
+    #[test]
+    fn test_status_thresholds() {
+        assert_eq!(report(0.2).status(), HealthStatus::Critical);
+        assert_eq!(report(0.5).status(), HealthStatus::Warning);
+        assert_eq!(report(0.8).status(), HealthStatus::Healthy);
+        let unchecked = HealthReport {
+            repository: "r".to_string(),
+            results: vec![],
+            ignored: Vec::new(),
+        };
+        assert_eq!(unchecked.status(), HealthStatus::Healthy);
+    }
+
+    #[test]
+    fn test_critical_result_overrides_score() {
+        let mut flagged = report(1.0);
+        flagged.results.push(
+            CheckResult::from_criteria("vulnerabilities", Category::Security, 1, 1, vec![])
+                .mark_critical(),
+        );
+        assert_eq!(flagged.score(), 1.0);
+        assert_eq!(flagged.status(), HealthStatus::Critical);
+    }
+
+    #[test]
+    fn test_health_filter_parse_and_match() {
+        let critical: HealthFilter = "critical".parse().unwrap();
+        assert!(critical.matches(&report(0.1)));
+        assert!(!critical.matches(&report(0.6)));
+
+        let warning: HealthFilter = "warning".parse().unwrap();
+        assert!(warning.matches(&report(0.6)));
+        assert!(!warning.matches(&report(0.1)));
+
+        let below: HealthFilter = "below-score=90".parse().unwrap();
+        assert_eq!(below, HealthFilter::BelowScore(0.9));
+        assert!(below.matches(&report(0.85)));
+        assert!(!below.matches(&report(0.9)));
+
+        assert!("unhealthy".parse::<HealthFilter>().is_err());
+        assert!("below-score=150".parse::<HealthFilter>().is_err());
+        assert!("below-score=".parse::<HealthFilter>().is_err());
+    }
+
+    #[test]
+    fn test_check_all_repositories_skips_missing_checkouts() {
+        let temp_dir = TempDir::new().unwrap();
+        let cloned_dir = temp_dir.path().join("cloned");
+        std::fs::create_dir(&cloned_dir).unwrap();
+        std::fs::write(cloned_dir.join("LICENSE"), "MIT").unwrap();
+
+        let mut cloned =
+            Repository::new("cloned".to_string(), "git@github.com:o/c.git".to_string());
+        cloned.path = Some(cloned_dir.to_string_lossy().to_string());
+        let mut missing =
+            Repository::new("missing".to_string(), "git@github.com:o/m.git".to_string());
+        missing.path = Some(
+            temp_dir
+                .path()
+                .join("missing")
+                .to_string_lossy()
+                .to_string(),
+        );
+
+        let checkers: Vec<Box<dyn Checker>> = vec![Box::new(LicenseChecker)];
+        let reports = check_all_repositories(&[cloned, missing], &checkers);
+        assert_eq!(reports.len(), 1);
+        assert_eq!(reports[0].repository, "cloned");
+        assert_eq!(reports[0].score(), 1.0);
+    }
+
+    #[test]
+    fn test_ignored_checks_are_skipped_for_that_repository_only() {
+        let temp_dir = TempDir::new().unwrap();
+        let clone = |name: &str, health_ignore: &[&str]| {
+            let dir = temp_dir.path().join(name);
+            std::fs::create_dir(&dir).unwrap();
+            let mut repo =
+                Repository::new(name.to_string(), format!("git@github.com:o/{name}.git"));
+            repo.path = Some(dir.to_string_lossy().to_string());
+            repo.health_ignore = health_ignore.iter().map(|c| c.to_string()).collect();
+            (repo, dir)
+        };
+        let (api, _) = clone("api", &["license"]);
+        let (web, web_dir) = clone("web", &[]);
+        std::fs::write(web_dir.join(ignore::MARKER_FILE), "# repos:ignore readme\n").unwrap();
+        let (docs, _) = clone("docs", &[]);
+
+        let checkers: Vec<Box<dyn Checker>> = vec![
+            Box::new(ReadmeChecker::new(ReadmeOptions::default())),
+            Box::new(LicenseChecker),
+        ];
+        let reports = check_all_repositories(&[api, web, docs], &checkers);
+        let checked = |report: &HealthReport| -> Vec<String> {
+            report.results.iter().map(|r| r.checker.clone()).collect()
+        };
+
+        assert_eq!(checked(&reports[0]), vec!["readme"]);
+        assert_eq!(reports[0].ignored, vec!["license"]);
+        assert_eq!(checked(&reports[1]), vec!["license"]);
+        assert_eq!(reports[1].ignored, vec!["readme"]);
+        assert_eq!(checked(&reports[2]), vec!["readme", "license"]);
+        assert!(reports[2].ignored.is_empty());
+        // An ignored check no longer counts towards the score
+        assert_eq!(reports[1].score(), 0.0);
+        assert_eq!(
+            HealthTarget {
+                ignore: vec!["readme".to_string(), "license".to_string()],
+                ..HealthTarget::new("web".to_string(), web_dir)
+            }
+            .check(&checkers)
+            .score(),
+            1.0
+        );
+    }
+
+    #[test]
+    fn test_find_root_file_ignores_case_and_extension() {
+        let temp_dir = TempDir::new().unwrap();
+        std::fs::write(temp_dir.path().join("Readme.ja.md"), "x").unwrap();
+        std::fs::write(temp_dir.path().join("readme.md"), "x").unwrap();
+        std::fs::create_dir(temp_dir.path().join("license")).unwrap();
+
+        let found = find_root_file(temp_dir.path(), &["README"]).unwrap();
+        assert_eq!(found.file_name().unwrap(), "readme.md");
+        // Directories are not files
+        assert!(find_root_file(temp_dir.path(), &["LICENSE"]).is_none());
+    }
+}
@@ -0,0 +1,166 @@
+//! Checking several repositories at once
+//!
+//! `repos health check --parallel` runs the checkers for each repository on
+//! a pool of threads. Every report is handed over whole, so the caller can
+//! print a repository's results as one block instead of interleaving them
+//! with other repositories. By default reports are released in config order,
+//! each as soon as every repository before it has finished; with
+//! [`ReportOrder::Completion`] each one is released as soon as it is ready.
+
+use super::{Checker, HealthReport, HealthTarget};
+use std::collections::BTreeMap;
+use std::sync::atomic::{AtomicUsize, Ordering};
+use std::sync::mpsc;
+use std::thread;
+
+/// Order in which [`check_parallel`] releases reports
+#[derive(Debug, Clone, Copy, Default, PartialEq, Eq)]
+pub enum ReportOrder {
+    /// The order of the repositories given, as in the config
+    #[default]
+    Config,
+    /// The order in which repositories finish
+    Completion,
+}
+
+/// Check each repository on up to `jobs` threads
+///
+/// `emit` is called once per repository with its full report, in `order`.
+/// The reports are also returned, in the order the repositories were given.
+pub fn check_parallel(
+    repositories: &[HealthTarget],
+    checkers: &[Box<dyn Checker>],
+    jobs: usize,
+    order: ReportOrder,
+    mut emit: impl FnMut(&HealthReport),
+) -> Vec<HealthReport> {
+    let next = AtomicUsize::new(0);
+    let (sender, receiver) = mpsc::channel();
+    let mut reports: Vec<Option<HealthReport>> = vec![None; repositories.len()];
+
+    thread::scope(|scope| {
+        for _ in 0..jobs.clamp(1, repositories.len().max(1)) {
+            let sender = sender.clone();
+            let next = &next;
+            scope.spawn(move || {
+                loop {
+                    let index = next.fetch_add(1, Ordering::Relaxed);
+                    let Some(target) = repositories.get(index) else {
+                        break;
+                    };
+                    let report = target.check(checkers);
+                    if sender.send((index, report)).is_err() {
+                        break;
+                    }
+                }
+            });
+        }
+        drop(sender);
+
+        // Reports that finished ahead of an earlier repository, for config order
+        let mut pending = BTreeMap::new();
+        let mut released = 0;
+        for (index, report) in receiver {
+            match order {
+                ReportOrder::Completion => {
+                    emit(&report);
+                    reports[index] = Some(report);
+                }
+                ReportOrder::Config => {
+                    pending.insert(index, report);
+                    while let Some(report) = pending.remove(&released) {
+                        emit(&report);
+                        reports[released] = Some(report);
+                        released += 1;
+                    }
+                }
+            }
+        }
+    });
+
+    reports.into_iter().flatten().collect()
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::health::{Category, CheckResult};
+    use std::path::{Path, PathBuf};
+    use std::time::Duration;
+
+    /// Takes as many tens of milliseconds as the directory name says
+    struct SlowChecker;
+
+    impl Checker for SlowChecker {
+        fn name(&self) -> &'static str {
+            "slow"
+        }
+
+        fn category(&self) -> Category {
+            Category::Documentation
+        }
+
+        fn check(&self, repo_path: &Path) -> CheckResult {
+            let delay: u64 = repo_path
+                .file_name()
+                .and_then(|name| name.to_str())
+                .and_then(|name| name.parse().ok())
+                .unwrap_or(0);
+            thread::sleep(Duration::from_millis(delay * 10));
+            CheckResult::from_criteria(self.name(), self.category(), 1, 1, vec![])
+        }
+    }
+
+    fn repositories(delays: &[&str]) -> Vec<HealthTarget> {
+        delays
+            .iter()
+            .map(|delay| HealthTarget::new(format!("repo-{}", delay), PathBuf::from(delay)))
+            .collect()
+    }
+
+    fn emitted(order: ReportOrder) -> (Vec<String>, Vec<String>) {
+        let checkers: Vec<Box<dyn Checker>> = vec![Box::new(SlowChecker), Box::new(SlowChecker)];
+        let mut emitted = Vec::new();
+        let reports = check_parallel(
+            &repositories(&["30", "0", "15"]),
+            &checkers,
+            3,
+            order,
+            |r| emitted.push(r.repository.clone()),
+        );
+        assert!(reports.iter().all(|report| report.results.len() == 2));
+        let returned = reports
+            .into_iter()
+            .map(|report| report.repository)
+            .collect();
+        (emitted, returned)
+    }
+
+    #[test]
+    fn test_config_order_releases_reports_in_given_order() {
+        let (emitted, returned) = emitted(ReportOrder::Config);
+        assert_eq!(emitted, vec!["repo-30", "repo-0", "repo-15"]);
+        assert_eq!(returned, emitted);
+    }
+
+    #[test]
+    fn test_completion_order_releases_reports_as_they_finish() {
+        let (emitted, returned) = emitted(ReportOrder::Completion);
+        assert_eq!(emitted, vec!["repo-0", "repo-15", "repo-30"]);
+        assert_eq!(returned, vec!["repo-30", "repo-0", "repo-15"]);
+    }
+
+    #[test]
+    fn test_single_job_and_no_repositories() {
+        let checkers: Vec<Box<dyn Checker>> = vec![Box::new(SlowChecker)];
+        let reports = check_parallel(
+            &repositories(&["1", "0"]),
+            &checkers,
+            0,
+            ReportOrder::Completion,
+            |_| {},
+        );
+        assert_eq!(reports.len(), 2);
+        assert!(check_parallel(&[], &checkers, 4, ReportOrder::Config, |_| {}).is_empty());
+    }
+}
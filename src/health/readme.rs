@@ -0,0 +1,257 @@
+//! README quality check
+//!
+//! Presence alone earns only part of the credit; the rest comes from having a
+//! title, the required sections and enough prose to be useful.
+
+use super::{Category, CheckResult, Checker, find_root_file};
+use std::path::Path;
+
+/// Section keywords required when none are configured
+pub const DEFAULT_SECTIONS: &[&str] = &["installation|install|usage|getting started|quick start"];
+
+/// Minimum word count when none is configured
+pub const DEFAULT_MIN_WORDS: usize = 50;
+
+/// What a README must contain to earn full credit
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct ReadmeOptions {
+    /// Required sections; each entry lists alternative heading keywords, any
+    /// of which satisfies it (matched case-insensitively as substrings)
+    pub sections: Vec<Vec<String>>,
+    pub min_words: usize,
+}
+
+impl ReadmeOptions {
+    /// Parse a required section given as `keyword|keyword|...`
+    pub fn parse_section(value: &str) -> Vec<String> {
+        value
+            .split('|')
+            .map(|keyword| keyword.trim().to_lowercase())
+            .filter(|keyword| !keyword.is_empty())
+            .collect()
+    }
+}
+
+impl Default for ReadmeOptions {
+    fn default() -> Self {
+        Self {
+            sections: DEFAULT_SECTIONS
+                .iter()
+                .map(|s| Self::parse_section(s))
+                .collect(),
+            min_words: DEFAULT_MIN_WORDS,
+        }
+    }
+}
+
+/// Scores the README on presence, title, required sections and length
+pub struct ReadmeChecker {
+    options: ReadmeOptions,
+}
+
+impl ReadmeChecker {
+    pub fn new(options: ReadmeOptions) -> Self {
+        Self { options }
+    }
+
+    fn assess(&self, content: &str) -> (usize, Vec<String>) {
+        let headings = headings(content);
+        let mut passed = 0;
+        let mut findings = Vec::new();
+
+        if headings.is_empty() {
+            findings.push("README has no title heading".to_string());
+        } else {
+            passed += 1;
+        }
+
+        for keywords in &self.options.sections {
+            let present = headings
+                .iter()
+                .any(|heading| keywords.iter().any(|k| heading.contains(k.as_str())));
+            if present {
+                passed += 1;
+            } else {
+                findings.push(format!(
+                    "README has no section matching: {}",
+                    keywords.join(", ")
+                ));
+            }
+        }
+
+        let words = content.split_whitespace().count();
+        if words > self.options.min_words {
+            passed += 1;
+        } else {
+            findings.push(format!(
+                "README has only {} words (expected more than {})",
+                words, self.options.min_words
+            ));
+        }
+
+        (passed, findings)
+    }
+}
+
+impl Checker for ReadmeChecker {
+    fn name(&self) -> &'static str {
+        "readme"
+    }
+
+    fn category(&self) -> Category {
+        Category::Documentation
+    }
+
+    fn check(&self, repo_path: &Path) -> CheckResult {
+        // Presence, title and length, plus one point per required section
+        let total = 3 + self.options.sections.len();
+
+        let content = match find_root_file(repo_path, &["README"]) {
+            Some(path) => std::fs::read_to_string(path).unwrap_or_default(),
+            None => {
+                return CheckResult::from_criteria(
+                    self.name(),
+                    self.category(),
+                    0,
+                    total,
+                    vec!["no README file".to_string()],
+                );
+            }
+        };
+
+        let (passed, findings) = self.assess(&content);
+        CheckResult::from_criteria(self.name(), self.category(), passed + 1, total, findings)
+    }
+}
+
+/// Lower-cased heading texts in Markdown (ATX and setext) or reStructuredText
+///
+/// Lines inside fenced code blocks are not headings, so a shell comment in an
+/// example does not count as a section.
+fn headings(content: &str) -> Vec<String> {
+    let mut headings = Vec::new();
+    let mut fence: Option<&str> = None;
+    // Text of the previous line when it could be a setext title
+    let mut previous: Option<&str> = None;
+
+    for line in content.lines() {
+        let trimmed = line.trim();
+        if let Some(open) = fence {
+            if trimmed.starts_with(open) && trimmed.trim_start_matches(&open[..1]).is_empty() {
+                fence = None;
+            }
+            continue;
+        }
+        if let Some(open) = fence_marker(trimmed) {
+            fence = Some(open);
+            previous = None;
+            continue;
+        }
+
+        if trimmed.starts_with('#') {
+            let text = trimmed.trim_start_matches('#').trim();
+            if !text.is_empty() {
+                headings.push(text.to_lowercase());
+            }
+        } else if is_underline(trimmed) {
+            if let Some(text) = previous.filter(|text| !text.is_empty() && !is_underline(text)) {
+                headings.push(text.to_lowercase());
+            }
+        }
+        previous = Some(trimmed);
+    }
+
+    headings
+}
+
+/// The run of backticks or tildes opening a fenced code block on `line`
+fn fence_marker(line: &str) -> Option<&str> {
+    ['`', '~'].into_iter().find_map(|c| {
+        let run = line.len() - line.trim_start_matches(c).len();
+        (run >= 3).then(|| &line[..run])
+    })
+}
+
+fn is_underline(line: &str) -> bool {
+    line.len() >= 3
+        && ['=', '-', '~']
+            .iter()
+            .any(|c| line.chars().all(|ch| ch == *c))
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use tempfile::TempDir;
+
+    const COMPLETE: &str = include_str!("../../tests/fixtures/health/readme/complete.md");
+    const PARTIAL: &str = include_str!("../../tests/fixtures/health/readme/partial.md");
+    const MINIMAL: &str = include_str!("../../tests/fixtures/health/readme/minimal.md");
+
+    fn check_fixture(file_name: &str, content: &str, options: ReadmeOptions) -> CheckResult {
+        let temp_dir = TempDir::new().unwrap();
+        std::fs::write(temp_dir.path().join(file_name), content).unwrap();
+        ReadmeChecker::new(options).check(temp_dir.path())
+    }
+
+    #[test]
+    fn test_complete_readme_scores_full() {
+        let result = check_fixture("README.md", COMPLETE, ReadmeOptions::default());
+        assert_eq!(result.score, 1.0);
+        assert!(result.findings.is_empty(), "{:?}", result.findings);
+    }
+
+    #[test]
+    fn test_partial_readme_misses_usage_section() {
+        let result = check_fixture("README.md", PARTIAL, ReadmeOptions::default());
+        assert_eq!(result.score, 0.75);
+        assert_eq!(result.findings.len(), 1);
+        assert!(result.findings[0].contains("installation, install, usage"));
+    }
+
+    #[test]
+    fn test_minimal_readme_earns_presence_only() {
+        let result = check_fixture("readme.md", MINIMAL, ReadmeOptions::default());
+        assert_eq!(result.score, 0.25);
+        assert_eq!(result.findings.len(), 3);
+        assert!(result.findings[2].contains("only 3 words"));
+    }
+
+    #[test]
+    fn test_missing_readme_scores_zero() {
+        let temp_dir = TempDir::new().unwrap();
+        let result = ReadmeChecker::new(ReadmeOptions::default()).check(temp_dir.path());
+        assert_eq!(result.score, 0.0);
+        assert_eq!(result.findings, vec!["no README file"]);
+    }
+
+    #[test]
+    fn test_configured_sections_are_required() {
+        let options = ReadmeOptions {
+            sections: vec![
+                ReadmeOptions::parse_section("architecture"),
+                ReadmeOptions::parse_section("Contributing | Development"),
+            ],
+            min_words: 10,
+        };
+        let result = check_fixture("README.md", PARTIAL, options);
+        // Presence, title, architecture and length pass; contributing does not
+        assert_eq!(result.score, 0.8);
+        assert_eq!(
+            result.findings,
+            vec!["README has no section matching: contributing, development"]
+        );
+    }
+
+    #[test]
+    fn test_setext_and_rst_headings() {
+        let content = "Widget Service\n==============\n\nUsage\n-----\n\nRun it.\n";
+        assert_eq!(headings(content), vec!["widget service", "usage"]);
+    }
+
+    #[test]
+    fn test_fenced_code_is_not_headings() {
+        let content = "# Tool\n\n```bash\n# install\nmake\n---\n```\n\n~~~~\n## Usage\n~~~\n~~~~\n\n## License\n";
+        assert_eq!(headings(content), vec!["tool", "license"]);
+    }
+}
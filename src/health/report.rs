@@ -0,0 +1,400 @@
+//! Health reports as JSON documents
+//!
+//! `repos health check --format json` writes a [`FleetReport`]: the report of
+//! every checked repository plus a [`HealthSummary`] over them. Reports
+//! written by separate runs (e.g. one per team) can be read back and merged
+//! with [`FleetReport::merge`], which recomputes the summary.
+//!
+//! [`FleetReport::slack_summary`] condenses a report into a short Slack
+//! message (`--format slack`): the summary line and the repositories scoring
+//! below a threshold.
+//!
+//! [`FleetReport::github_annotations`] turns failing checks into GitHub
+//! Actions workflow commands (`--format github-actions`), so they show up as
+//! annotations on the workflow run.
+
+use super::{HealthReport, HealthStatus};
+use anyhow::{Context, Result};
+use serde::{Deserialize, Serialize};
+use std::fmt;
+use std::path::Path;
+
+/// Counts of repositories per status and their average score
+#[derive(Debug, Clone, PartialEq, Serialize, Deserialize)]
+pub struct HealthSummary {
+    pub repositories: usize,
+    pub healthy: usize,
+    pub warning: usize,
+    pub critical: usize,
+    /// Mean of the repository scores; `None` when no repository was checked
+    pub average_score: Option<f64>,
+}
+
+impl HealthSummary {
+    pub fn from_reports(reports: &[HealthReport]) -> Self {
+        let count = |status: HealthStatus| {
+            reports
+                .iter()
+                .filter(|report| report.status() == status)
+                .count()
+        };
+        let average_score = (!reports.is_empty())
+            .then(|| reports.iter().map(HealthReport::score).sum::<f64>() / reports.len() as f64);
+
+        Self {
+            repositories: reports.len(),
+            healthy: count(HealthStatus::Healthy),
+            warning: count(HealthStatus::Warning),
+            critical: count(HealthStatus::Critical),
+            average_score,
+        }
+    }
+}
+
+impl fmt::Display for HealthSummary {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        write!(
+            f,
+            "{} repositories ({} healthy, {} warning, {} critical)",
+            self.repositories, self.healthy, self.warning, self.critical
+        )?;
+        if let Some(score) = self.average_score {
+            write!(f, ", average score {:.0}%", score * 100.0)?;
+        }
+        Ok(())
+    }
+}
+
+/// Health reports of a set of repositories with their summary
+#[derive(Debug, Clone, PartialEq, Serialize, Deserialize)]
+pub struct FleetReport {
+    pub summary: HealthSummary,
+    pub repositories: Vec<HealthReport>,
+}
+
+impl FleetReport {
+    pub fn new(repositories: Vec<HealthReport>) -> Self {
+        Self {
+            summary: HealthSummary::from_reports(&repositories),
+            repositories,
+        }
+    }
+
+    /// Read a report previously written as JSON
+    pub fn load(path: &Path) -> Result<Self> {
+        let content = std::fs::read_to_string(path)
+            .with_context(|| format!("Failed to read health report {}", path.display()))?;
+        serde_json::from_str(&content)
+            .with_context(|| format!("Invalid health report {}", path.display()))
+    }
+
+    /// Combine reports into one, recomputing the summary
+    ///
+    /// Repositories keep the order in which they first appear. A repository
+    /// present in several reports keeps its entry from the last one.
+    pub fn merge(reports: impl IntoIterator<Item = FleetReport>) -> Self {
+        let mut repositories: Vec<HealthReport> = Vec::new();
+        for report in reports {
+            for entry in report.repositories {
+                match repositories
+                    .iter_mut()
+                    .find(|existing| existing.repository == entry.repository)
+                {
+                    Some(existing) => *existing = entry,
+                    None => repositories.push(entry),
+                }
+            }
+        }
+        Self::new(repositories)
+    }
+
+    /// The summary and every repository scoring below `below`, in Slack mrkdwn
+    ///
+    /// Repositories are listed worst first, one bullet each with their score
+    /// and status, ready to paste into Slack or post to a webhook.
+    pub fn slack_summary(&self, below: f64) -> String {
+        let mut message = format!("*Health check:* {}\n", self.summary);
+        let mut low: Vec<&HealthReport> = self
+            .repositories
+            .iter()
+            .filter(|report| report.score() < below)
+            .collect();
+        if low.is_empty() {
+            message.push_str(&format!(
+                "All repositories score at least {:.0}%\n",
+                below * 100.0
+            ));
+            return message;
+        }
+
+        low.sort_by(|a, b| a.score().total_cmp(&b.score()));
+        message.push_str(&format!("*Below {:.0}%:*\n", below * 100.0));
+        for report in low {
+            message.push_str(&format!(
+                "• *{}* {:.0}% ({})\n",
+                slack_escape(&report.repository),
+                report.score() * 100.0,
+                report.status()
+            ));
+        }
+        message
+    }
+
+    /// An `::error` or `::warning` workflow command for every failing check
+    ///
+    /// A check is an error when it flags its repository critical or scores
+    /// below [`super::CRITICAL_BELOW`], and a warning below
+    /// [`super::WARNING_BELOW`]; passing checks are left out. The title names
+    /// the repository and the message gives the check, its score and findings.
+    pub fn github_annotations(&self) -> String {
+        let mut commands = String::new();
+        for report in &self.repositories {
+            for result in &report.results {
+                let level = match (result.critical, HealthStatus::from_score(result.score)) {
+                    (true, _) | (_, HealthStatus::Critical) => "error",
+                    (_, HealthStatus::Warning) => "warning",
+                    (_, HealthStatus::Healthy) => continue,
+                };
+                let mut message = format!("{} {:.0}%", result.checker, result.score * 100.0);
+                if !result.findings.is_empty() {
+                    message.push_str(&format!(": {}", result.findings.join("; ")));
+                }
+                commands.push_str(&format!(
+                    "::{} title={}::{}\n",
+                    level,
+                    annotation_property(&format!("health: {}", report.repository)),
+                    annotation_data(&message)
+                ));
+            }
+        }
+        commands
+    }
+}
+
+/// Escape a workflow command's message so newlines and `%` survive
+fn annotation_data(text: &str) -> String {
+    text.replace('%', "%25")
+        .replace('\r', "%0D")
+        .replace('\n', "%0A")
+}
+
+/// Escape a workflow command property, which also ends at `:` or `,`
+fn annotation_property(text: &str) -> String {
+    annotation_data(text)
+        .replace(':', "%3A")
+        .replace(',', "%2C")
+}
+
+/// Escape the characters Slack treats as markup in message text
+fn slack_escape(text: &str) -> String {
+    text.replace('&', "&amp;")
+        .replace('<', "&lt;")
+        .replace('>', "&gt;")
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::health::{Category, CheckResult};
+
+    const TEAM_A: &str = include_str!("../../tests/fixtures/health/reports/team-a.json");
+    const TEAM_B: &str = include_str!("../../tests/fixtures/health/reports/team-b.json");
+
+    fn names(report: &FleetReport) -> Vec<&str> {
+        report
+            .repositories
+            .iter()
+            .map(|entry| entry.repository.as_str())
+            .collect()
+    }
+
+    #[test]
+    fn test_report_round_trips_through_json() {
+        let report = FleetReport::new(vec![HealthReport {
+            repository: "api".to_string(),
+            results: vec![
+                CheckResult::from_criteria(
+                    "readme",
+                    Category::Documentation,
+                    3,
+                    4,
+                    vec!["README has no section matching: usage".to_string()],
+                ),
+                CheckResult::from_criteria("vulnerabilities", Category::Security, 0, 1, vec![])
+                    .mark_critical(),
+            ],
+            ignored: Vec::new(),
+        }]);
+
+        let json = serde_json::to_string_pretty(&report).unwrap();
+        let parsed: FleetReport = serde_json::from_str(&json).unwrap();
+        assert_eq!(parsed, report);
+        assert_eq!(parsed.summary.critical, 1);
+    }
+
+    #[test]
+    fn test_merge_recomputes_summary() {
+        let team_a: FleetReport = serde_json::from_str(TEAM_A).unwrap();
+        let team_b: FleetReport = serde_json::from_str(TEAM_B).unwrap();
+        assert_eq!(team_a.summary.repositories, 2);
+        assert_eq!(team_b.summary.repositories, 2);
+
+        let merged = FleetReport::merge([team_a, team_b]);
+        // "shared" is in both reports; team-b's newer entry replaces team-a's
+        assert_eq!(names(&merged), vec!["api", "shared", "web"]);
+        assert_eq!(merged.repositories[1].results[0].score, 1.0);
+        assert_eq!(
+            merged.summary,
+            HealthSummary {
+                repositories: 3,
+                healthy: 2,
+                warning: 0,
+                critical: 1,
+                average_score: Some(0.75),
+            }
+        );
+        assert_eq!(
+            merged.summary.to_string(),
+            "3 repositories (2 healthy, 0 warning, 1 critical), average score 75%"
+        );
+    }
+
+    #[test]
+    fn test_slack_summary_lists_repositories_below_threshold() {
+        let team_a: FleetReport = serde_json::from_str(TEAM_A).unwrap();
+        let team_b: FleetReport = serde_json::from_str(TEAM_B).unwrap();
+        let merged = FleetReport::merge([team_a, team_b]);
+
+        assert_eq!(
+            merged.slack_summary(0.8),
+            "*Health check:* 3 repositories (2 healthy, 0 warning, 1 critical), average score 75%\n\
+             *Below 80%:*\n\
+             • *web* 25% (critical)\n"
+        );
+        assert_eq!(
+            merged.slack_summary(0.2),
+            "*Health check:* 3 repositories (2 healthy, 0 warning, 1 critical), average score 75%\n\
+             All repositories score at least 20%\n"
+        );
+    }
+
+    #[test]
+    fn test_slack_summary_escapes_markup() {
+        let report = FleetReport::new(vec![HealthReport {
+            repository: "<api&co>".to_string(),
+            results: vec![CheckResult::from_criteria(
+                "readme",
+                Category::Documentation,
+                0,
+                1,
+                vec![],
+            )],
+            ignored: Vec::new(),
+        }]);
+        assert!(
+            report
+                .slack_summary(0.5)
+                .contains("• *&lt;api&amp;co&gt;* 0% (critical)\n")
+        );
+    }
+
+    #[test]
+    fn test_github_annotations_for_failing_checks() {
+        let report = FleetReport::new(vec![
+            HealthReport {
+                repository: "api".to_string(),
+                results: vec![
+                    CheckResult::from_criteria(
+                        "readme",
+                        Category::Documentation,
+                        3,
+                        4,
+                        vec!["README has no section matching: usage".to_string()],
+                    ),
+                    CheckResult::from_criteria("license", Category::Governance, 1, 1, vec![]),
+                    CheckResult::from_criteria(
+                        "vulnerabilities",
+                        Category::Security,
+                        1,
+                        1,
+                        vec!["2 high severity advisories".to_string()],
+                    )
+                    .mark_critical(),
+                ],
+                ignored: Vec::new(),
+            },
+            HealthReport {
+                repository: "web".to_string(),
+                results: vec![CheckResult::from_criteria(
+                    "dockerfile",
+                    Category::Infrastructure,
+                    0,
+                    2,
+                    vec![
+                        "no USER instruction".to_string(),
+                        "base image uses :latest".to_string(),
+                    ],
+                )],
+                ignored: Vec::new(),
+            },
+        ]);
+
+        assert_eq!(
+            report.github_annotations(),
+            "::warning title=health%3A api::readme 75%25: README has no section matching: usage\n\
+             ::error title=health%3A api::vulnerabilities 100%25: 2 high severity advisories\n\
+             ::error title=health%3A web::dockerfile 0%25: no USER instruction; base image uses :latest\n"
+        );
+    }
+
+    #[test]
+    fn test_github_annotations_escape_workflow_command_syntax() {
+        let report = FleetReport::new(vec![HealthReport {
+            repository: "team,api".to_string(),
+            results: vec![CheckResult::from_criteria(
+                "readme",
+                Category::Documentation,
+                0,
+                1,
+                vec!["line one\nline two at 100%".to_string()],
+            )],
+            ignored: Vec::new(),
+        }]);
+        assert_eq!(
+            report.github_annotations(),
+            "::error title=health%3A team%2Capi::readme 0%25: line one%0Aline two at 100%25\n"
+        );
+        assert!(FleetReport::merge([]).github_annotations().is_empty());
+    }
+
+    #[test]
+    fn test_empty_summary_has_no_average() {
+        let summary = FleetReport::merge([]).summary;
+        assert_eq!(summary.average_score, None);
+        assert_eq!(
+            summary.to_string(),
+            "0 repositories (0 healthy, 0 warning, 0 critical)"
+        );
+    }
+
+    #[test]
+    fn test_load_reports_unreadable_files() {
+        let temp_dir = tempfile::TempDir::new().unwrap();
+        let missing = FleetReport::load(&temp_dir.path().join("missing.json"));
+        assert!(
+            missing
+                .unwrap_err()
+                .to_string()
+                .starts_with("Failed to read health report")
+        );
+
+        let invalid = temp_dir.path().join("invalid.json");
+        std::fs::write(&invalid, "{\"repositories\": 3}").unwrap();
+        assert!(
+            FleetReport::load(&invalid)
+                .unwrap_err()
+                .to_string()
+                .starts_with("Invalid health report")
+        );
+    }
+}
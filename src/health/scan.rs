@@ -0,0 +1,120 @@
+//! Paths left out by checkers that walk the working tree
+//!
+//! Generated and vendored code makes file-walking checks slow and noisy. The
+//! config's `scan_exclude` list and the plugin's `--scan-exclude` flag give
+//! glob patterns matched against paths relative to the repository root, e.g.
+//! `vendor` or `**/generated`. A matching directory is skipped with
+//! everything below it. `*` does not cross `/`; `**` does.
+
+use anyhow::{Context, Result};
+use glob::Pattern;
+use std::path::Path;
+
+/// One `/`-separated part of a pattern
+#[derive(Debug, Clone)]
+enum Segment {
+    /// `**`: any number of path components, including none
+    AnyDepth,
+    /// A glob matched against a single path component
+    Glob(Pattern),
+}
+
+/// Glob patterns of repository paths to skip
+#[derive(Debug, Clone, Default)]
+pub struct ScanExclude {
+    patterns: Vec<Vec<Segment>>,
+}
+
+impl ScanExclude {
+    /// Compile `patterns`
+    ///
+    /// # Errors
+    /// Returns an error naming the first pattern that is not a valid glob
+    pub fn new<I, S>(patterns: I) -> Result<Self>
+    where
+        I: IntoIterator<Item = S>,
+        S: AsRef<str>,
+    {
+        let patterns = patterns
+            .into_iter()
+            .map(|pattern| {
+                let pattern = pattern.as_ref().trim_end_matches('/');
+                pattern
+                    .split('/')
+                    .map(|segment| match segment {
+                        "**" => Ok(Segment::AnyDepth),
+                        _ => Pattern::new(segment).map(Segment::Glob),
+                    })
+                    .collect::<Result<Vec<_>, _>>()
+                    .with_context(|| format!("Invalid scan_exclude pattern '{}'", pattern))
+            })
+            .collect::<Result<_>>()?;
+        Ok(Self { patterns })
+    }
+
+    pub fn is_empty(&self) -> bool {
+        self.patterns.is_empty()
+    }
+
+    /// Whether `relative`, a path below the repository root, is excluded
+    pub fn is_excluded(&self, relative: &Path) -> bool {
+        let components: Vec<String> = relative
+            .components()
+            .map(|component| component.as_os_str().to_string_lossy().into_owned())
+            .collect();
+        let components: Vec<&str> = components.iter().map(String::as_str).collect();
+        self.patterns
+            .iter()
+            .any(|pattern| matches(pattern, &components))
+    }
+
+    /// Whether `relative` or a directory above it is excluded
+    pub fn covers(&self, relative: &Path) -> bool {
+        relative
+            .ancestors()
+            .filter(|path| !path.as_os_str().is_empty())
+            .any(|path| self.is_excluded(path))
+    }
+}
+
+fn matches(pattern: &[Segment], components: &[&str]) -> bool {
+    match pattern.split_first() {
+        None => components.is_empty(),
+        Some((Segment::AnyDepth, rest)) => {
+            (0..=components.len()).any(|skip| matches(rest, &components[skip..]))
+        }
+        Some((Segment::Glob(glob), rest)) => components
+            .split_first()
+            .is_some_and(|(first, tail)| glob.matches(first) && matches(rest, tail)),
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_patterns_match_relative_paths() {
+        let exclude = ScanExclude::new(["vendor/", "**/generated", "docs/*.md"]).unwrap();
+        assert!(exclude.is_excluded(Path::new("vendor")));
+        assert!(exclude.is_excluded(Path::new("generated")));
+        assert!(exclude.is_excluded(Path::new("api/v1/generated")));
+        assert!(exclude.is_excluded(Path::new("docs/intro.md")));
+
+        assert!(!exclude.is_excluded(Path::new("src/vendor")));
+        assert!(!exclude.is_excluded(Path::new("docs/guide/intro.md")));
+        assert!(!exclude.is_excluded(Path::new("generated.go")));
+
+        // Files below an excluded directory are covered by it
+        assert!(exclude.covers(Path::new("vendor/github.com/x/y.go")));
+        assert!(exclude.covers(Path::new("api/generated/api.pb.go")));
+        assert!(!exclude.covers(Path::new("api/api.go")));
+    }
+
+    #[test]
+    fn test_invalid_pattern_is_rejected() {
+        let error = ScanExclude::new(["src/[", "vendor"]).unwrap_err();
+        assert_eq!(error.to_string(), "Invalid scan_exclude pattern 'src/['");
+        assert!(ScanExclude::default().is_empty());
+    }
+}
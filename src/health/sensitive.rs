@@ -0,0 +1,385 @@
+//! Sensitive file presence check
+//!
+//! Tracked files whose names suggest secrets, such as `.env`, `*.pem`,
+//! `id_rsa` or `credentials.json`, are listed with `git ls-files` and reported
+//! as critical. Only file names are inspected, never contents, so the check is
+//! fast on large repositories. Templates like `.env.example` are not flagged,
+//! and the config's `sensitive_files_allow` globs accept known test fixtures,
+//! matched against paths relative to the repository root. Paths matching the
+//! [`ScanExclude`] patterns are not inspected. Directories that are not git
+//! repositories are not scored.
+
+use super::{Category, CheckResult, Checker, ScanExclude};
+use anyhow::{Context, Result};
+use glob::{MatchOptions, Pattern};
+use std::io;
+use std::path::Path;
+use std::process::Command;
+use std::sync::LazyLock;
+
+/// File name globs that suggest a committed secret, matched case-insensitively
+pub const SENSITIVE_FILE_PATTERNS: &[&str] = &[
+    ".env",
+    ".env.*",
+    "*.pem",
+    "*.key",
+    "*.p12",
+    "*.pfx",
+    "*.keystore",
+    "*.jks",
+    "id_rsa",
+    "id_dsa",
+    "id_ecdsa",
+    "id_ed25519",
+    ".netrc",
+    ".pgpass",
+    ".htpasswd",
+    "credentials",
+    "credentials.*",
+    "*-credentials.*",
+    "*_credentials.*",
+    "secrets.*",
+];
+
+/// [`SENSITIVE_FILE_PATTERNS`], compiled once
+static SENSITIVE_FILE_GLOBS: LazyLock<Vec<Pattern>> = LazyLock::new(|| {
+    SENSITIVE_FILE_PATTERNS
+        .iter()
+        .map(|pattern| Pattern::new(pattern).expect("valid sensitive file pattern"))
+        .collect()
+});
+
+/// Suffixes of example files that match a pattern but hold no real secrets
+pub const TEMPLATE_SUFFIXES: &[&str] = &[".example", ".sample", ".template", ".dist"];
+
+/// `*` stays within one path component, as in `scan_exclude`
+const PATH_MATCH: MatchOptions = MatchOptions {
+    case_sensitive: true,
+    require_literal_separator: true,
+    require_literal_leading_dot: false,
+};
+
+/// Tracked paths accepted despite a sensitive-looking name
+#[derive(Debug, Clone, Default)]
+pub struct SensitiveFilesOptions {
+    allow: Vec<Pattern>,
+}
+
+impl SensitiveFilesOptions {
+    /// Compile the `sensitive_files_allow` globs
+    ///
+    /// # Errors
+    /// Returns an error naming the first pattern that is not a valid glob
+    pub fn new<I, S>(allow: I) -> Result<Self>
+    where
+        I: IntoIterator<Item = S>,
+        S: AsRef<str>,
+    {
+        let allow = allow
+            .into_iter()
+            .map(|pattern| {
+                let pattern = pattern.as_ref();
+                Pattern::new(pattern)
+                    .with_context(|| format!("Invalid sensitive_files_allow pattern '{}'", pattern))
+            })
+            .collect::<Result<_>>()?;
+        Ok(Self { allow })
+    }
+
+    /// Whether `path`, relative to the repository root, is allowlisted
+    pub fn is_allowed(&self, path: &str) -> bool {
+        self.allow
+            .iter()
+            .any(|pattern| pattern.matches_with(path, PATH_MATCH))
+    }
+}
+
+/// Whether the file name of `path` suggests it holds secrets
+pub fn is_sensitive(path: &str) -> bool {
+    let name = path.rsplit('/').next().unwrap_or(path).to_lowercase();
+    if TEMPLATE_SUFFIXES
+        .iter()
+        .any(|suffix| name.ends_with(suffix))
+    {
+        return false;
+    }
+    SENSITIVE_FILE_GLOBS
+        .iter()
+        .any(|pattern| pattern.matches(&name))
+}
+
+/// The sensitive paths among `paths` that are not allowlisted, in input order
+pub fn find_sensitive<'a>(
+    paths: impl IntoIterator<Item = &'a str>,
+    options: &SensitiveFilesOptions,
+) -> Vec<&'a str> {
+    paths
+        .into_iter()
+        .filter(|path| is_sensitive(path) && !options.is_allowed(path))
+        .collect()
+}
+
+/// Checks that no tracked file looks like a credential or private key
+pub struct SensitiveFilesChecker {
+    program: String,
+    options: SensitiveFilesOptions,
+    scan_exclude: ScanExclude,
+}
+
+impl SensitiveFilesChecker {
+    pub fn new(options: SensitiveFilesOptions) -> Self {
+        Self::with_program("git", options)
+    }
+
+    /// Use `program` instead of `git` on the `PATH`
+    pub fn with_program(program: impl Into<String>, options: SensitiveFilesOptions) -> Self {
+        Self {
+            program: program.into(),
+            options,
+            scan_exclude: ScanExclude::default(),
+        }
+    }
+
+    /// Leave tracked paths matching `scan_exclude` uninspected
+    pub fn with_scan_exclude(mut self, scan_exclude: ScanExclude) -> Self {
+        self.scan_exclude = scan_exclude;
+        self
+    }
+
+    fn tracked_files(&self, repo_path: &Path) -> io::Result<Result<String, String>> {
+        let output = Command::new(&self.program)
+            .args(["ls-files", "-z"])
+            .current_dir(repo_path)
+            .output()?;
+        if !output.status.success() {
+            let stderr = String::from_utf8_lossy(&output.stderr);
+            let message = stderr
+                .lines()
+                .find(|line| !line.trim().is_empty())
+                .unwrap_or("no output")
+                .trim()
+                .to_string();
+            return Ok(Err(message));
+        }
+        Ok(Ok(String::from_utf8_lossy(&output.stdout).into_owned()))
+    }
+}
+
+impl Default for SensitiveFilesChecker {
+    fn default() -> Self {
+        Self::new(SensitiveFilesOptions::default())
+    }
+}
+
+impl Checker for SensitiveFilesChecker {
+    fn name(&self) -> &'static str {
+        "sensitive-files"
+    }
+
+    fn category(&self) -> Category {
+        Category::Security
+    }
+
+    fn check(&self, repo_path: &Path) -> CheckResult {
+        if !repo_path.join(".git").exists() {
+            return CheckResult::from_criteria(self.name(), self.category(), 0, 0, vec![]);
+        }
+
+        let tracked = match self.tracked_files(repo_path) {
+            Ok(Ok(tracked)) => tracked,
+            Ok(Err(message)) => {
+                let findings = vec![format!("git ls-files failed: {}", message)];
+                return CheckResult::from_criteria(self.name(), self.category(), 0, 0, findings);
+            }
+            Err(e) => {
+                let finding = if e.kind() == io::ErrorKind::NotFound {
+                    format!(
+                        "{} not installed; sensitive files not checked",
+                        self.program
+                    )
+                } else {
+                    format!("Failed to run {}: {}", self.program, e)
+                };
+                return CheckResult::from_criteria(
+                    self.name(),
+                    self.category(),
+                    0,
+                    0,
+                    vec![finding],
+                );
+            }
+        };
+
+        let sensitive = find_sensitive(
+            tracked
+                .split('\0')
+                .filter(|path| !path.is_empty() && !self.scan_exclude.covers(Path::new(path))),
+            &self.options,
+        );
+        if sensitive.is_empty() {
+            return CheckResult::from_criteria(self.name(), self.category(), 1, 1, vec![]);
+        }
+        let findings = sensitive
+            .iter()
+            .map(|path| format!("Sensitive file committed: {}", path))
+            .collect();
+        CheckResult::from_criteria(self.name(), self.category(), 0, 1, findings).mark_critical()
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    const TRACKED: &str = include_str!("../../tests/fixtures/health/sensitive/tracked.txt");
+
+    fn tracked() -> Vec<&'static str> {
+        TRACKED.lines().filter(|line| !line.is_empty()).collect()
+    }
+
+    #[test]
+    fn test_sensitive_names_are_recognized() {
+        for path in [
+            ".env",
+            "deploy/.env.production",
+            "certs/server.PEM",
+            "keys/id_rsa",
+            "android/release.keystore",
+            "config/credentials.json",
+            ".aws/credentials",
+            "ci/gcp-credentials.json",
+        ] {
+            assert!(is_sensitive(path), "{}", path);
+        }
+        for path in [
+            ".env.example",
+            "config/secrets.yaml.template",
+            "keys/id_rsa.pub",
+            "src/auth/credential_store.rs",
+            "docs/environment.md",
+            "src/keystore.rs",
+        ] {
+            assert!(!is_sensitive(path), "{}", path);
+        }
+    }
+
+    #[test]
+    fn test_fixture_files_are_found_in_order() {
+        let found = find_sensitive(tracked(), &SensitiveFilesOptions::default());
+        assert_eq!(
+            found,
+            vec![
+                ".env",
+                "config/credentials.json",
+                "deploy/tls/server.pem",
+                "tests/fixtures/certs/test.pem",
+                "tests/fixtures/certs/test.key",
+            ]
+        );
+    }
+
+    #[test]
+    fn test_allowlist_accepts_matching_paths() {
+        let options =
+            SensitiveFilesOptions::new(["tests/fixtures/**/*", "config/credentials.json"]).unwrap();
+        assert!(options.is_allowed("tests/fixtures/certs/test.pem"));
+        assert!(!options.is_allowed("deploy/tls/server.pem"));
+        assert_eq!(
+            find_sensitive(tracked(), &options),
+            vec![".env", "deploy/tls/server.pem"]
+        );
+
+        // `*` does not cross directories
+        let options = SensitiveFilesOptions::new(["tests/*.pem"]).unwrap();
+        assert!(!options.is_allowed("tests/fixtures/certs/test.pem"));
+
+        let err = SensitiveFilesOptions::new(["certs/[.pem"]).unwrap_err();
+        assert!(
+            err.to_string()
+                .contains("Invalid sensitive_files_allow pattern")
+        );
+    }
+
+    #[test]
+    fn test_committed_secrets_in_real_repository_are_critical() {
+        let temp_dir = tempfile::TempDir::new().unwrap();
+        let git = |args: &[&str]| {
+            Command::new("git")
+                .args(args)
+                .current_dir(temp_dir.path())
+                .output()
+        };
+        if git(&["init"]).is_err() {
+            return;
+        }
+        for path in [
+            "README.md",
+            ".env.example",
+            "certs/server.pem",
+            "fixtures/test.pem",
+        ] {
+            let path = temp_dir.path().join(path);
+            std::fs::create_dir_all(path.parent().unwrap()).unwrap();
+            std::fs::write(path, "placeholder\n").unwrap();
+        }
+        // Untracked files are not reported
+        std::fs::write(temp_dir.path().join(".env"), "TOKEN=local\n").unwrap();
+        git(&[
+            "add",
+            "README.md",
+            ".env.example",
+            "certs/server.pem",
+            "fixtures/test.pem",
+        ])
+        .unwrap();
+
+        let result = SensitiveFilesChecker::default().check(temp_dir.path());
+        assert_eq!(result.category, Category::Security);
+        assert_eq!(result.score, 0.0);
+        assert!(result.critical);
+        assert_eq!(
+            result.findings,
+            vec![
+                "Sensitive file committed: certs/server.pem",
+                "Sensitive file committed: fixtures/test.pem",
+            ]
+        );
+
+        let options = SensitiveFilesOptions::new(["certs/*", "fixtures/*.pem"]).unwrap();
+        let result = SensitiveFilesChecker::new(options).check(temp_dir.path());
+        assert_eq!(result.score, 1.0);
+        assert!(!result.critical);
+        assert!(result.findings.is_empty());
+
+        // Excluded paths are not counted
+        let result = SensitiveFilesChecker::default()
+            .with_scan_exclude(ScanExclude::new(["fixtures"]).unwrap())
+            .check(temp_dir.path());
+        assert_eq!(
+            result.findings,
+            vec!["Sensitive file committed: certs/server.pem"]
+        );
+    }
+
+    #[test]
+    fn test_non_repository_and_missing_git_are_not_scored() {
+        let temp_dir = tempfile::TempDir::new().unwrap();
+        std::fs::write(temp_dir.path().join("id_rsa"), "placeholder\n").unwrap();
+        let result = SensitiveFilesChecker::default().check(temp_dir.path());
+        assert_eq!(result.score, 1.0);
+        assert!(result.findings.is_empty());
+
+        std::fs::create_dir(temp_dir.path().join(".git")).unwrap();
+        let result = SensitiveFilesChecker::with_program(
+            "git-not-installed",
+            SensitiveFilesOptions::default(),
+        )
+        .check(temp_dir.path());
+        assert_eq!(result.score, 1.0);
+        assert!(!result.critical);
+        assert_eq!(
+            result.findings,
+            vec!["git-not-installed not installed; sensitive files not checked"]
+        );
+    }
+}
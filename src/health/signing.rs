@@ -0,0 +1,332 @@
+//! Commit signing compliance check
+//!
+//! The signature status of the most recent commits is read with
+//! `git log --format=%G?`. A commit counts as signed when it carries a
+//! signature that is not bad, even if the signing key is unknown or expired
+//! on the machine running the check. The check passes when the signed
+//! fraction reaches the configured ratio. Directories that are not git
+//! repositories, or have no commits yet, are not scored.
+
+use super::{Category, CheckResult, Checker};
+use std::io;
+use std::path::Path;
+use std::process::Command;
+
+/// Number of recent commits inspected when none is configured
+pub const DEFAULT_COMMITS: usize = 20;
+
+/// Fraction of signed commits required when none is configured
+pub const DEFAULT_MIN_SIGNED_RATIO: f64 = 1.0;
+
+/// Which commits are inspected and how many must be signed
+#[derive(Debug, Clone, PartialEq)]
+pub struct SigningOptions {
+    /// Number of most recent commits on `HEAD` to inspect
+    pub commits: usize,
+    /// Fraction of inspected commits that must be signed, from 0.0 to 1.0
+    pub min_signed_ratio: f64,
+}
+
+impl Default for SigningOptions {
+    fn default() -> Self {
+        Self {
+            commits: DEFAULT_COMMITS,
+            min_signed_ratio: DEFAULT_MIN_SIGNED_RATIO,
+        }
+    }
+}
+
+/// Signed and total commits among those inspected
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub struct SignatureCounts {
+    pub signed: usize,
+    pub total: usize,
+}
+
+impl SignatureCounts {
+    /// Count the `%G?` status letters, one per line
+    ///
+    /// `N` (no signature) and `B` (bad signature) are unsigned; every other
+    /// status means the commit is signed.
+    pub fn parse(log: &str) -> Self {
+        let statuses: Vec<&str> = log
+            .lines()
+            .map(str::trim)
+            .filter(|line| !line.is_empty())
+            .collect();
+        Self {
+            signed: statuses
+                .iter()
+                .filter(|status| !matches!(**status, "N" | "B"))
+                .count(),
+            total: statuses.len(),
+        }
+    }
+
+    pub fn ratio(&self) -> f64 {
+        if self.total == 0 {
+            1.0
+        } else {
+            self.signed as f64 / self.total as f64
+        }
+    }
+}
+
+/// Checks that recent commits are signed
+pub struct SigningChecker {
+    program: String,
+    options: SigningOptions,
+}
+
+impl SigningChecker {
+    pub fn new(options: SigningOptions) -> Self {
+        Self::with_program("git", options)
+    }
+
+    /// Use `program` instead of `git` on the `PATH`
+    pub fn with_program(program: impl Into<String>, options: SigningOptions) -> Self {
+        Self {
+            program: program.into(),
+            options,
+        }
+    }
+
+    fn signatures(&self, repo_path: &Path) -> io::Result<Result<SignatureCounts, String>> {
+        let output = Command::new(&self.program)
+            .args([
+                "log",
+                &format!("--max-count={}", self.options.commits),
+                "--format=%G?",
+            ])
+            .current_dir(repo_path)
+            .output()?;
+        if !output.status.success() {
+            let stderr = String::from_utf8_lossy(&output.stderr);
+            let message = stderr
+                .lines()
+                .find(|line| !line.trim().is_empty())
+                .unwrap_or("no output")
+                .trim()
+                .to_string();
+            return Ok(Err(message));
+        }
+        Ok(Ok(SignatureCounts::parse(&String::from_utf8_lossy(
+            &output.stdout,
+        ))))
+    }
+}
+
+impl Default for SigningChecker {
+    fn default() -> Self {
+        Self::new(SigningOptions::default())
+    }
+}
+
+impl Checker for SigningChecker {
+    fn name(&self) -> &'static str {
+        "signing"
+    }
+
+    fn category(&self) -> Category {
+        Category::Security
+    }
+
+    fn check(&self, repo_path: &Path) -> CheckResult {
+        if !repo_path.join(".git").exists() {
+            return CheckResult::from_criteria(self.name(), self.category(), 0, 0, vec![]);
+        }
+
+        let counts = match self.signatures(repo_path) {
+            Ok(Ok(counts)) => counts,
+            Ok(Err(message)) => {
+                // A repository without commits has nothing to sign yet
+                let findings = if message.contains("does not have any commits") {
+                    vec![]
+                } else {
+                    vec![format!("git log failed: {}", message)]
+                };
+                return CheckResult::from_criteria(self.name(), self.category(), 0, 0, findings);
+            }
+            Err(e) => {
+                let finding = if e.kind() == io::ErrorKind::NotFound {
+                    format!("{} not installed; commit signing not checked", self.program)
+                } else {
+                    format!("git log failed: {}", e)
+                };
+                return CheckResult::from_criteria(
+                    self.name(),
+                    self.category(),
+                    0,
+                    0,
+                    vec![finding],
+                );
+            }
+        };
+        if counts.total == 0 {
+            return CheckResult::from_criteria(self.name(), self.category(), 0, 0, vec![]);
+        }
+
+        let passed = counts.ratio() >= self.options.min_signed_ratio;
+        let findings = if passed {
+            vec![]
+        } else {
+            vec![format!(
+                "{} of the last {} commits signed ({:.0}%, expected at least {:.0}%)",
+                counts.signed,
+                counts.total,
+                counts.ratio() * 100.0,
+                self.options.min_signed_ratio * 100.0
+            )]
+        };
+        CheckResult::from_criteria(
+            self.name(),
+            self.category(),
+            usize::from(passed),
+            1,
+            findings,
+        )
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use std::path::PathBuf;
+
+    fn fixture(name: &str) -> PathBuf {
+        Path::new(env!("CARGO_MANIFEST_DIR"))
+            .join("tests/fixtures/health/signing")
+            .join(name)
+    }
+
+    #[test]
+    fn test_parse_counts_signature_statuses() {
+        let log = std::fs::read_to_string(fixture("mixed.txt")).unwrap();
+        let counts = SignatureCounts::parse(&log);
+        assert_eq!(
+            counts,
+            SignatureCounts {
+                signed: 6,
+                total: 10
+            }
+        );
+        assert_eq!(counts.ratio(), 0.6);
+        assert_eq!(SignatureCounts::parse("").ratio(), 1.0);
+    }
+
+    /// A stand-in for `git` that prints a recorded `%G?` log from a temp repo
+    #[cfg(unix)]
+    fn fake_git(dir: &Path, log: &str) -> PathBuf {
+        use std::os::unix::fs::PermissionsExt;
+
+        let git = dir.join("fake-git");
+        std::fs::write(
+            &git,
+            format!("#!/bin/sh\ncat '{}'\n", fixture(log).display()),
+        )
+        .unwrap();
+        std::fs::set_permissions(&git, std::fs::Permissions::from_mode(0o755)).unwrap();
+        git
+    }
+
+    #[cfg(unix)]
+    #[test]
+    fn test_repository_below_threshold_is_flagged() {
+        let temp_dir = tempfile::TempDir::new().unwrap();
+        std::fs::create_dir(temp_dir.path().join(".git")).unwrap();
+        let git = fake_git(temp_dir.path(), "mixed.txt");
+
+        let checker = SigningChecker::with_program(
+            git.to_string_lossy(),
+            SigningOptions {
+                commits: 10,
+                min_signed_ratio: 0.8,
+            },
+        );
+        let result = checker.check(temp_dir.path());
+        assert_eq!(result.category, Category::Security);
+        assert_eq!(result.score, 0.0);
+        assert_eq!(
+            result.findings,
+            vec!["6 of the last 10 commits signed (60%, expected at least 80%)"]
+        );
+
+        let checker = SigningChecker::with_program(
+            git.to_string_lossy(),
+            SigningOptions {
+                commits: 10,
+                min_signed_ratio: 0.5,
+            },
+        );
+        let result = checker.check(temp_dir.path());
+        assert_eq!(result.score, 1.0);
+        assert!(result.findings.is_empty());
+    }
+
+    #[test]
+    fn test_unsigned_commits_in_real_repository() {
+        let temp_dir = tempfile::TempDir::new().unwrap();
+        let git = |args: &[&str]| {
+            Command::new("git")
+                .args(args)
+                .current_dir(temp_dir.path())
+                .output()
+        };
+        if git(&["init"]).is_err() {
+            return;
+        }
+        git(&[
+            "-c",
+            "user.name=Test",
+            "-c",
+            "user.email=test@example.com",
+            "commit",
+            "--allow-empty",
+            "--no-gpg-sign",
+            "-m",
+            "unsigned",
+        ])
+        .unwrap();
+
+        let result = SigningChecker::default().check(temp_dir.path());
+        assert_eq!(result.score, 0.0);
+        assert_eq!(
+            result.findings,
+            vec!["0 of the last 1 commits signed (0%, expected at least 100%)"]
+        );
+    }
+
+    #[test]
+    fn test_repository_without_commits_is_not_scored() {
+        let temp_dir = tempfile::TempDir::new().unwrap();
+        if Command::new("git")
+            .arg("init")
+            .current_dir(temp_dir.path())
+            .output()
+            .is_err()
+        {
+            return;
+        }
+
+        let result = SigningChecker::default().check(temp_dir.path());
+        assert_eq!(result.score, 1.0);
+        assert!(result.findings.is_empty());
+    }
+
+    #[test]
+    fn test_non_repository_and_missing_git_are_not_scored() {
+        let temp_dir = tempfile::TempDir::new().unwrap();
+        let result = SigningChecker::default().check(temp_dir.path());
+        assert_eq!(result.score, 1.0);
+        assert!(result.findings.is_empty());
+
+        std::fs::create_dir(temp_dir.path().join(".git")).unwrap();
+        let result = SigningChecker::with_program("git-not-installed", SigningOptions::default())
+            .check(temp_dir.path());
+        assert_eq!(result.score, 1.0);
+        assert_eq!(
+            result.findings,
+            vec!["git-not-installed not installed; commit signing not checked"]
+        );
+    }
+}
@@ -0,0 +1,434 @@
+//! Known-vulnerability scan of a repository's dependencies
+//!
+//! Each detected ecosystem is scanned with its own tool: `govulncheck` for Go
+//! modules, `npm audit` for npm packages and `pip-audit` for Python projects.
+//! An ecosystem scanned without findings is a passed criterion; any known
+//! vulnerability fails it, and high or critical ones mark the result
+//! [`critical`](CheckResult::critical). Ecosystems whose scanner is not
+//! installed, or whose scan fails, are reported as findings but not scored.
+
+use super::{Category, CheckResult, Checker};
+use serde_json::Value;
+use std::collections::BTreeMap;
+use std::fmt;
+use std::io;
+use std::path::Path;
+use std::process::Command;
+
+/// Severity of a known vulnerability
+#[derive(Debug, Clone, Copy, PartialEq, Eq, PartialOrd, Ord)]
+pub enum Severity {
+    Critical,
+    High,
+    Moderate,
+    Low,
+    /// The scanner reports no severity (govulncheck, pip-audit)
+    Unknown,
+}
+
+impl Severity {
+    fn parse(value: &str) -> Self {
+        match value.to_ascii_lowercase().as_str() {
+            "critical" => Self::Critical,
+            "high" => Self::High,
+            "moderate" | "medium" => Self::Moderate,
+            "low" | "info" => Self::Low,
+            _ => Self::Unknown,
+        }
+    }
+
+    fn is_severe(self) -> bool {
+        matches!(self, Self::Critical | Self::High)
+    }
+}
+
+impl fmt::Display for Severity {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        match self {
+            Self::Critical => write!(f, "critical"),
+            Self::High => write!(f, "high"),
+            Self::Moderate => write!(f, "moderate"),
+            Self::Low => write!(f, "low"),
+            Self::Unknown => write!(f, "unrated"),
+        }
+    }
+}
+
+/// Number of known vulnerabilities per severity
+#[derive(Debug, Clone, Default, PartialEq, Eq)]
+pub struct VulnerabilityCounts(BTreeMap<Severity, usize>);
+
+impl VulnerabilityCounts {
+    fn add(&mut self, severity: Severity, count: usize) {
+        if count > 0 {
+            *self.0.entry(severity).or_default() += count;
+        }
+    }
+
+    pub fn get(&self, severity: Severity) -> usize {
+        self.0.get(&severity).copied().unwrap_or(0)
+    }
+
+    pub fn total(&self) -> usize {
+        self.0.values().sum()
+    }
+
+    /// Whether any high or critical vulnerability is known
+    pub fn has_severe(&self) -> bool {
+        self.0.keys().any(|severity| severity.is_severe())
+    }
+}
+
+impl fmt::Display for VulnerabilityCounts {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        let parts: Vec<String> = self
+            .0
+            .iter()
+            .map(|(severity, count)| format!("{} {}", count, severity))
+            .collect();
+        write!(
+            f,
+            "{} known vulnerabilities ({})",
+            self.total(),
+            parts.join(", ")
+        )
+    }
+}
+
+/// Runs scanner commands; replaced in tests to feed recorded output
+pub trait ScanRunner: Send + Sync {
+    /// Run `program` with `args` in `dir` and return its stdout
+    ///
+    /// Scanners exit non-zero when they find vulnerabilities, so the exit
+    /// status is not an error. A missing program is reported as
+    /// [`io::ErrorKind::NotFound`].
+    fn run(&self, dir: &Path, program: &str, args: &[&str]) -> io::Result<String>;
+}
+
+/// Runs scanners as child processes
+pub struct SystemScanRunner;
+
+impl ScanRunner for SystemScanRunner {
+    fn run(&self, dir: &Path, program: &str, args: &[&str]) -> io::Result<String> {
+        let output = Command::new(program).args(args).current_dir(dir).output()?;
+        Ok(String::from_utf8_lossy(&output.stdout).into_owned())
+    }
+}
+
+/// A dependency ecosystem and the scanner used for it
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+enum Ecosystem {
+    Go,
+    Npm,
+    Python,
+}
+
+impl Ecosystem {
+    const ALL: [Self; 3] = [Self::Go, Self::Npm, Self::Python];
+
+    fn label(self) -> &'static str {
+        match self {
+            Self::Go => "govulncheck",
+            Self::Npm => "npm audit",
+            Self::Python => "pip-audit",
+        }
+    }
+
+    fn language(self) -> &'static str {
+        match self {
+            Self::Go => "Go",
+            Self::Npm => "npm",
+            Self::Python => "Python",
+        }
+    }
+
+    /// The scanner invocation for a repository, or `None` when the ecosystem is absent
+    fn command(self, repo_path: &Path) -> Option<(&'static str, Vec<&'static str>)> {
+        match self {
+            Self::Go if repo_path.join("go.mod").is_file() => {
+                Some(("govulncheck", vec!["-json", "./..."]))
+            }
+            Self::Npm if repo_path.join("package.json").is_file() => {
+                Some(("npm", vec!["audit", "--json"]))
+            }
+            Self::Python if repo_path.join("requirements.txt").is_file() => Some((
+                "pip-audit",
+                vec!["--format", "json", "--requirement", "requirements.txt"],
+            )),
+            Self::Python if repo_path.join("pyproject.toml").is_file() => {
+                Some(("pip-audit", vec!["--format", "json", "."]))
+            }
+            _ => None,
+        }
+    }
+
+    fn parse(self, output: &str) -> Result<VulnerabilityCounts, String> {
+        match self {
+            Self::Go => parse_govulncheck(output),
+            Self::Npm => parse_npm_audit(output),
+            Self::Python => parse_pip_audit(output),
+        }
+    }
+}
+
+/// Scans dependencies for known vulnerabilities
+pub struct VulnerabilityChecker {
+    runner: Box<dyn ScanRunner>,
+}
+
+impl VulnerabilityChecker {
+    pub fn new() -> Self {
+        Self::with_runner(SystemScanRunner)
+    }
+
+    pub fn with_runner(runner: impl ScanRunner + 'static) -> Self {
+        Self {
+            runner: Box::new(runner),
+        }
+    }
+}
+
+impl Default for VulnerabilityChecker {
+    fn default() -> Self {
+        Self::new()
+    }
+}
+
+impl Checker for VulnerabilityChecker {
+    fn name(&self) -> &'static str {
+        "vulnerabilities"
+    }
+
+    fn category(&self) -> Category {
+        Category::Security
+    }
+
+    fn check(&self, repo_path: &Path) -> CheckResult {
+        let mut scanned = 0;
+        let mut passed = 0;
+        let mut severe = false;
+        let mut findings = Vec::new();
+
+        for ecosystem in Ecosystem::ALL {
+            let Some((program, args)) = ecosystem.command(repo_path) else {
+                continue;
+            };
+            let output = match self.runner.run(repo_path, program, &args) {
+                Ok(output) => output,
+                Err(e) if e.kind() == io::ErrorKind::NotFound => {
+                    findings.push(format!(
+                        "{} not installed; {} dependencies not scanned",
+                        program,
+                        ecosystem.language()
+                    ));
+                    continue;
+                }
+                Err(e) => {
+                    findings.push(format!("{} failed: {}", ecosystem.label(), e));
+                    continue;
+                }
+            };
+            let counts = match ecosystem.parse(&output) {
+                Ok(counts) => counts,
+                Err(e) => {
+                    findings.push(format!("{} failed: {}", ecosystem.label(), e));
+                    continue;
+                }
+            };
+
+            scanned += 1;
+            if counts.total() == 0 {
+                passed += 1;
+            } else {
+                severe |= counts.has_severe();
+                findings.push(format!("{}: {}", ecosystem.label(), counts));
+            }
+        }
+
+        let result =
+            CheckResult::from_criteria(self.name(), self.category(), passed, scanned, findings);
+        if severe {
+            result.mark_critical()
+        } else {
+            result
+        }
+    }
+}
+
+/// Count the distinct vulnerabilities in `govulncheck -json` output
+///
+/// The output is a stream of JSON messages; every `finding` names an OSV ID,
+/// and several findings (one per call path) can share one.
+fn parse_govulncheck(output: &str) -> Result<VulnerabilityCounts, String> {
+    let mut ids = std::collections::BTreeSet::new();
+    for message in serde_json::Deserializer::from_str(output).into_iter::<Value>() {
+        let message = message.map_err(|e| format!("unreadable output: {e}"))?;
+        if let Some(id) = message.pointer("/finding/osv").and_then(Value::as_str) {
+            ids.insert(id.to_string());
+        }
+    }
+    let mut counts = VulnerabilityCounts::default();
+    counts.add(Severity::Unknown, ids.len());
+    Ok(counts)
+}
+
+/// Read the per-severity totals of `npm audit --json`
+fn parse_npm_audit(output: &str) -> Result<VulnerabilityCounts, String> {
+    let report: Value =
+        serde_json::from_str(output).map_err(|e| format!("unreadable output: {e}"))?;
+    if let Some(error) = report.get("error") {
+        let summary = error
+            .get("summary")
+            .and_then(Value::as_str)
+            .unwrap_or("unknown error");
+        return Err(summary.lines().next().unwrap_or(summary).to_string());
+    }
+    let totals = report
+        .pointer("/metadata/vulnerabilities")
+        .and_then(Value::as_object)
+        .ok_or("output has no metadata.vulnerabilities")?;
+
+    let mut counts = VulnerabilityCounts::default();
+    for (severity, count) in totals {
+        if severity == "total" {
+            continue;
+        }
+        let count = count.as_u64().unwrap_or(0) as usize;
+        counts.add(Severity::parse(severity), count);
+    }
+    Ok(counts)
+}
+
+/// Count the vulnerabilities of `pip-audit --format json`
+///
+/// Accepts both the current `{"dependencies": [...]}` layout and the bare
+/// list written by older releases.
+fn parse_pip_audit(output: &str) -> Result<VulnerabilityCounts, String> {
+    let report: Value =
+        serde_json::from_str(output).map_err(|e| format!("unreadable output: {e}"))?;
+    let dependencies = report
+        .get("dependencies")
+        .unwrap_or(&report)
+        .as_array()
+        .ok_or("output has no dependency list")?;
+
+    let total = dependencies
+        .iter()
+        .filter_map(|dependency| dependency.get("vulns").and_then(Value::as_array))
+        .map(Vec::len)
+        .sum();
+    let mut counts = VulnerabilityCounts::default();
+    counts.add(Severity::Unknown, total);
+    Ok(counts)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use std::collections::HashMap;
+    use tempfile::TempDir;
+
+    const GOVULNCHECK: &str =
+        include_str!("../../tests/fixtures/health/vulnerabilities/govulncheck.json");
+    const NPM_AUDIT: &str =
+        include_str!("../../tests/fixtures/health/vulnerabilities/npm-audit.json");
+    const NPM_AUDIT_CLEAN: &str =
+        include_str!("../../tests/fixtures/health/vulnerabilities/npm-audit-clean.json");
+    const PIP_AUDIT: &str =
+        include_str!("../../tests/fixtures/health/vulnerabilities/pip-audit.json");
+
+    /// Returns recorded output per program; other programs are "not installed"
+    struct FixtureRunner(HashMap<&'static str, &'static str>);
+
+    impl ScanRunner for FixtureRunner {
+        fn run(&self, _dir: &Path, program: &str, _args: &[&str]) -> io::Result<String> {
+            self.0
+                .get(program)
+                .map(|output| output.to_string())
+                .ok_or_else(|| io::Error::from(io::ErrorKind::NotFound))
+        }
+    }
+
+    fn check(files: &[&str], outputs: &[(&'static str, &'static str)]) -> CheckResult {
+        let temp_dir = TempDir::new().unwrap();
+        for file in files {
+            std::fs::write(temp_dir.path().join(file), "").unwrap();
+        }
+        VulnerabilityChecker::with_runner(FixtureRunner(outputs.iter().copied().collect()))
+            .check(temp_dir.path())
+    }
+
+    #[test]
+    fn test_npm_audit_counts_by_severity() {
+        let result = check(&["package.json"], &[("npm", NPM_AUDIT)]);
+        assert_eq!(result.category, Category::Security);
+        assert_eq!(result.score, 0.0);
+        assert!(result.critical);
+        assert_eq!(
+            result.findings,
+            vec!["npm audit: 6 known vulnerabilities (1 critical, 2 high, 3 moderate)"]
+        );
+    }
+
+    #[test]
+    fn test_clean_scan_passes() {
+        let result = check(&["package.json"], &[("npm", NPM_AUDIT_CLEAN)]);
+        assert_eq!(result.score, 1.0);
+        assert!(!result.critical);
+        assert!(result.findings.is_empty());
+    }
+
+    #[test]
+    fn test_unrated_vulnerabilities_fail_without_marking_critical() {
+        let result = check(
+            &["go.mod", "requirements.txt"],
+            &[("govulncheck", GOVULNCHECK), ("pip-audit", PIP_AUDIT)],
+        );
+        assert_eq!(result.score, 0.0);
+        assert!(!result.critical);
+        assert_eq!(
+            result.findings,
+            vec![
+                "govulncheck: 2 known vulnerabilities (2 unrated)",
+                "pip-audit: 3 known vulnerabilities (3 unrated)",
+            ]
+        );
+    }
+
+    #[test]
+    fn test_missing_scanner_is_not_scored() {
+        let result = check(&["go.mod", "package.json"], &[("npm", NPM_AUDIT_CLEAN)]);
+        assert_eq!(result.score, 1.0);
+        assert_eq!(
+            result.findings,
+            vec!["govulncheck not installed; Go dependencies not scanned"]
+        );
+    }
+
+    #[test]
+    fn test_no_manifest_runs_no_scanner() {
+        let result = check(&["README.md"], &[]);
+        assert_eq!(result.score, 1.0);
+        assert!(result.findings.is_empty());
+    }
+
+    #[test]
+    fn test_scanner_errors_are_reported() {
+        let error = r#"{"error": {"code": "ENOLOCK", "summary": "This command requires an existing lockfile.\nTry creating one first with: npm i --package-lock-only"}}"#;
+        let result = check(&["package.json"], &[("npm", error)]);
+        assert_eq!(result.score, 1.0);
+        assert_eq!(
+            result.findings,
+            vec!["npm audit failed: This command requires an existing lockfile."]
+        );
+
+        assert!(parse_pip_audit("not json").is_err());
+    }
+
+    #[test]
+    fn test_pip_audit_legacy_list_format() {
+        let legacy =
+            r#"[{"name": "flask", "version": "0.5", "vulns": [{"id": "PYSEC-2019-179"}]}]"#;
+        assert_eq!(parse_pip_audit(legacy).unwrap().total(), 1);
+    }
+}
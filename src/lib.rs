@@ -1,10 +1,16 @@
 //! Repos - A CLI tool for managing multiple GitHub repositories
+//!
+//! The crate can also be embedded: [`Repos`] loads a config, selects
+//! repositories by tag or name and runs operations across them, returning
+//! per-repository results. See the [`api`] module and `examples/embed.rs`.
 
+pub mod api;
 pub mod commands;
 pub mod config;
 pub mod constants;
 pub mod git;
 pub mod github;
+pub mod health;
 pub mod plugins;
 pub mod runner;
 pub mod utils;
@@ -12,6 +18,7 @@ pub mod utils;
 pub type Result<T> = anyhow::Result<T>;
 
 // Re-export commonly used types
+pub use api::{RepoFilter, RepoResult, Repos, RunOutput};
 pub use commands::{Command, CommandContext};
 pub use config::loader::save_config;
 pub use config::{Config, Repository};
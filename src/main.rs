@@ -1,9 +1,39 @@
-use anyhow::Result;
+use anyhow::{Context, Result};
+use chrono::{DateTime, Utc};
 use clap::{CommandFactory, Parser, Subcommand};
 use clap_complete::{Shell, generate};
+use colored::*;
 use repos::commands::validators;
-use repos::{commands::*, config::Config, constants, plugins};
-use std::{env, io, path::PathBuf};
+use repos::github::{
+    GitHubApi, OrgCache, TopicCache, enrich_with_topics, expand_orgs, repository_sizes,
+};
+use repos::health::{
+    CheckerFactory, HealthFilter, HealthOptions, ScanExclude, SensitiveFilesOptions,
+    check_all_repositories, ignored_checks, unknown_checks,
+};
+use repos::runner::{Container, OutputMatch, OutputTemplate, RetryPolicy};
+use repos::utils::filters::SkippedRepository;
+use repos::utils::{
+    Checkpoint, Notification, NotifyOn, Presence, RepoSlice, filter_active_since, filter_archived,
+    filter_by_health, filter_by_presence, filter_detached, filter_unreadable, parse_duration,
+    parse_since, pick_repositories, send_notification, slice_repositories,
+};
+use repos::{
+    commands::*,
+    config::{
+        Config, ConfigFormat, HostFilter, ProfileFlags, Provider, PullStrategy, RecipeArgs,
+        RepoList, Repository, STDIN_PATH, SelectionFiles, TagPredicate, discover_config,
+        parse_recipe_arg, resolve_base_dir,
+    },
+    constants, git, plugins,
+};
+use std::collections::{BTreeMap, BTreeSet};
+use std::env;
+use std::io::{self, IsTerminal, Read};
+use std::num::NonZeroUsize;
+use std::path::{Path, PathBuf};
+use std::sync::{Arc, Mutex};
+use std::time::Instant;
 
 #[derive(Parser)]
 #[command(name = "repos")]
@@ -15,6 +45,176 @@ struct Cli {
     #[arg(long)]
     list_plugins: bool,
 
+    /// Log each git and shell command, with its directory and environment, before running it
+    #[arg(short, long, global = true)]
+    verbose: bool,
+
+    /// Let git prompt for credentials instead of failing on repositories that need them
+    #[arg(long, global = true)]
+    interactive_auth: bool,
+
+    /// Proxy for git and GitHub API requests, overriding HTTP_PROXY and HTTPS_PROXY
+    #[arg(long, global = true, value_name = "URL")]
+    proxy: Option<String>,
+
+    /// Comma-separated hosts reached without the proxy (`*` for all), overriding NO_PROXY
+    #[arg(long, global = true, value_name = "HOSTS")]
+    no_proxy: Option<String>,
+
+    /// Write a JSON summary of the run to this file on completion
+    #[arg(long, global = true, value_name = "PATH")]
+    report_file: Option<PathBuf>,
+
+    /// Shell command run after each repository finishes, with its result as JSON on stdin
+    #[arg(long, global = true, value_name = "COMMAND")]
+    on_result: Option<String>,
+
+    /// POST a JSON summary of the run to this webhook on completion
+    #[arg(long, global = true, value_name = "URL")]
+    notify_url: Option<String>,
+
+    /// When to send the --notify-url summary: failure or always
+    #[arg(
+        long,
+        global = true,
+        value_name = "WHEN",
+        default_value = "always",
+        requires = "notify_url"
+    )]
+    notify_on: String,
+
+    /// When repository failures fail the invocation: any-failure, all-failure or never
+    #[arg(
+        long,
+        global = true,
+        value_name = "POLICY",
+        default_value = "any-failure"
+    )]
+    exit_policy: String,
+
+    /// When another repos process holds a repository's lock: skip it or wait
+    #[arg(long, global = true, value_name = "ACTION", default_value = "skip")]
+    on_locked: String,
+
+    /// Directory clones live under, overriding the config's `defaults.root`
+    #[arg(long, global = true, value_name = "ROOT")]
+    dir: Option<String>,
+
+    /// Read and write the config file as yaml, json or toml instead of going by its extension
+    #[arg(long, global = true, value_name = "FORMAT")]
+    config_format: Option<String>,
+
+    /// Only operate on cloned repositories with a commit in this window (e.g. 30d, 2w, 2024-05-01)
+    #[arg(long, global = true, value_name = "DURATION|DATE")]
+    active_since: Option<String>,
+
+    /// Merge GitHub topics into repository tags as `gh:<topic>` (requires GITHUB_TOKEN)
+    #[arg(long, global = true)]
+    fetch_topics: bool,
+
+    /// Add the tags each clone lists in its .repos-tags file to its config tags
+    #[arg(long, global = true)]
+    merge_repo_tags: bool,
+
+    /// Only operate on repositories whose clone directory exists
+    #[arg(long, global = true, conflicts_with = "only_missing")]
+    only_cloned: bool,
+
+    /// Only operate on repositories that have not been cloned yet
+    #[arg(long, global = true)]
+    only_missing: bool,
+
+    /// Maximum repositories cloned or run at once with --parallel
+    #[arg(short = 'j', long, global = true, value_name = "N")]
+    jobs: Option<NonZeroUsize>,
+
+    /// Stop starting repositories once this many have failed (clone, pull, run and exec)
+    #[arg(long, global = true, value_name = "N")]
+    max_failures: Option<NonZeroUsize>,
+
+    /// Stop the whole batch after this long, e.g. 30m: running commands are stopped and
+    /// repositories not yet started are skipped (clone, pull, run and exec)
+    #[arg(long, global = true, value_name = "DURATION")]
+    deadline: Option<String>,
+
+    /// Parallel limit for clone, overriding --jobs
+    #[arg(long, global = true, value_name = "N")]
+    clone_jobs: Option<NonZeroUsize>,
+
+    /// Parallel limit for run, overriding --jobs
+    #[arg(long, global = true, value_name = "N")]
+    run_jobs: Option<NonZeroUsize>,
+
+    /// Take defaults for flags not given here from this `profiles` entry of the config
+    #[arg(long, global = true, value_name = "NAME")]
+    profile: Option<String>,
+
+    /// Default `git config` entry for every repository (can be specified multiple times)
+    #[arg(long, global = true, value_name = "KEY=VALUE")]
+    git_config: Vec<String>,
+
+    /// Extra `git clone` option for every repository, e.g. --clone-arg=--filter=blob:none (can be specified multiple times)
+    #[arg(
+        long = "clone-arg",
+        global = true,
+        value_name = "ARG",
+        allow_hyphen_values = true
+    )]
+    clone_args: Vec<String>,
+
+    /// Target a repository by name or alias, ignoring tag filters (can be specified multiple times)
+    #[arg(long = "repo", global = true, value_name = "ALIAS_OR_NAME")]
+    targets: Vec<String>,
+
+    /// Only operate on the repositories that failed in this --report-file report, ignoring tag filters
+    #[arg(long, global = true, value_name = "PATH", conflicts_with = "targets")]
+    rerun_failed: Option<PathBuf>,
+
+    /// Only operate on the repositories named in this file, one per line; tag filters narrow them further
+    #[arg(
+        long,
+        global = true,
+        value_name = "PATH",
+        conflicts_with_all = ["targets", "rerun_failed"]
+    )]
+    repo_file: Option<PathBuf>,
+
+    /// Skip names in --repo-file that are not in the config instead of failing
+    #[arg(long, global = true, requires = "repo_file")]
+    ignore_missing: bool,
+
+    /// Only repositories with at least one of these tags, comma-separated (same as --tag)
+    #[arg(long, global = true, value_name = "TAGS", value_delimiter = ',')]
+    tags_any: Vec<String>,
+
+    /// Only repositories with every one of these tags, comma-separated
+    #[arg(long, global = true, value_name = "TAGS", value_delimiter = ',')]
+    tags_all: Vec<String>,
+
+    /// Leave out repositories with any of these tags, comma-separated (same as --exclude-tag)
+    #[arg(long, global = true, value_name = "TAGS", value_delimiter = ',')]
+    tags_none: Vec<String>,
+
+    /// Only repositories whose URL points at one of these hosts, comma-separated (e.g. github.com)
+    #[arg(long, global = true, value_name = "HOSTS", value_delimiter = ',')]
+    host: Vec<String>,
+
+    /// Only operate on the first N repositories left after filtering
+    #[arg(long, global = true, value_name = "N")]
+    limit: Option<usize>,
+
+    /// Skip the first N repositories left after filtering
+    #[arg(long, global = true, value_name = "N", default_value_t = 0)]
+    offset: usize,
+
+    /// Only operate on shard I of N of the filtered repositories, e.g. 2/4
+    #[arg(long, global = true, value_name = "I/N")]
+    shard: Option<String>,
+
+    /// Choose the repositories to operate on from a list before running
+    #[arg(long, global = true)]
+    interactive: bool,
+
     #[command(subcommand)]
     command: Option<Commands>,
 }
@@ -41,6 +241,76 @@ enum Commands {
         /// Execute operations in parallel
         #[arg(short, long)]
         parallel: bool,
+
+        /// SSH private key for git operations (repos with their own `ssh_key` keep it)
+        #[arg(long, value_name = "PATH")]
+        ssh_key: Option<String>,
+
+        /// Checkpoint progress and skip repositories completed by an interrupted run
+        #[arg(long)]
+        resume: bool,
+
+        /// Remove and re-clone directories left behind by an interrupted clone
+        #[arg(long)]
+        repair: bool,
+
+        /// Fast-forward repositories that are already cloned instead of skipping them
+        #[arg(long)]
+        update_existing: bool,
+
+        /// Fail for repositories whose directory already exists instead of skipping them
+        #[arg(long, conflicts_with = "update_existing")]
+        error_on_existing: bool,
+
+        /// Clone bare mirrors (`git clone --mirror`), as if every repository set `mirror: true`
+        #[arg(long)]
+        mirror: bool,
+
+        /// Also operate on repositories marked `archived: true`
+        #[arg(long)]
+        include_archived: bool,
+
+        /// Print the directory each repository would be cloned into, without cloning
+        #[arg(long)]
+        print_paths: bool,
+
+        /// Clone the smallest repositories first, by GitHub repository size (requires GITHUB_TOKEN)
+        #[arg(long)]
+        prioritize_size: bool,
+    },
+
+    /// Pull the latest changes into cloned repositories
+    Pull {
+        /// Specific repository names to pull (if not provided, uses tag filter or all repos)
+        repos: Vec<String>,
+
+        /// Configuration file path
+        #[arg(short, long, default_value_t = constants::config::DEFAULT_CONFIG_FILE.to_string())]
+        config: String,
+
+        /// Filter repositories by tag (can be specified multiple times)
+        #[arg(short, long)]
+        tag: Vec<String>,
+
+        /// Exclude repositories with these tags (can be specified multiple times)
+        #[arg(short = 'e', long)]
+        exclude_tag: Vec<String>,
+
+        /// Execute operations in parallel
+        #[arg(short, long)]
+        parallel: bool,
+
+        /// Run `git merge --abort` when a pull stops on conflicts
+        #[arg(long)]
+        abort_on_conflict: bool,
+
+        /// Rebase instead of merging, for repositories without a `pull_strategy`
+        #[arg(long)]
+        rebase: bool,
+
+        /// SSH private key for git operations (repos with their own `ssh_key` keep it)
+        #[arg(long, value_name = "PATH")]
+        ssh_key: Option<String>,
     },
 
     /// Run a command in each repository
@@ -53,6 +323,19 @@ enum Commands {
         #[arg(long, help = "Name of a recipe defined in repos.yaml")]
         recipe: Option<String>,
 
+        /// Recipe argument substituted for {{.NAME}} and exported as ARG_NAME (can be specified multiple times)
+        #[arg(long, value_name = "NAME=VALUE", requires = "recipe")]
+        arg: Vec<String>,
+
+        /// Run each repository's command with this name from its `commands` map
+        #[arg(
+            long,
+            value_name = "NAME",
+            conflicts_with = "recipe",
+            help = "Run each repository's command with this name from its `commands` map; COMMAND becomes the default for repositories without one"
+        )]
+        named: Option<String>,
+
         /// Specific repository names to run command in (if not provided, uses tag filter or all repos)
         repos: Vec<String>,
 
@@ -79,6 +362,172 @@ enum Commands {
         /// Custom directory for output files (default: output)
         #[arg(long)]
         output_dir: Option<String>,
+
+        /// Checkpoint progress and skip repositories completed by an interrupted run
+        #[arg(long)]
+        resume: bool,
+
+        /// Kill the command in a repository after this long (e.g. 90s, 10m); a repository's own `timeout` wins
+        #[arg(long, value_name = "DURATION")]
+        timeout: Option<String>,
+
+        /// Only run in repositories whose health matches: critical, warning or below-score=<percent>
+        #[arg(long, value_name = "FILTER")]
+        where_health: Option<String>,
+
+        /// List the N slowest repositories after the summary (default 5)
+        #[arg(long, value_name = "N", num_args = 0..=1, default_missing_value = "5")]
+        timings: Option<usize>,
+
+        /// Treat any non-zero exit code as a failure (the default)
+        #[arg(long, conflicts_with = "allow_exit_codes")]
+        strict: bool,
+
+        /// With --parallel, print each repository's output in config order once all finish
+        #[arg(long)]
+        ordered_output: bool,
+
+        /// With --parallel, print the repositories still running to stderr every few seconds
+        #[arg(long)]
+        show_active: bool,
+
+        /// Exit codes that count as success, comma-separated (e.g. 0,1 for diff)
+        #[arg(
+            long,
+            value_name = "CODES",
+            value_delimiter = ',',
+            allow_negative_numbers = true
+        )]
+        allow_exit_codes: Vec<i32>,
+
+        /// A repository succeeds only if its output matches this regex, whatever its exit code
+        #[arg(long, value_name = "REGEX", conflicts_with_all = ["allow_exit_codes", "strict"])]
+        success_if: Option<String>,
+
+        /// A repository fails if its output matches this regex, whatever its exit code
+        #[arg(long, value_name = "REGEX", conflicts_with_all = ["allow_exit_codes", "strict"])]
+        failure_if: Option<String>,
+
+        /// Run a command again up to N times while it exits with a failing code
+        #[arg(long, value_name = "N")]
+        command_retries: Option<u32>,
+
+        /// Wait this long before each retry (e.g. 5s, 1m)
+        #[arg(long, value_name = "DURATION", requires = "command_retries")]
+        command_retry_delay: Option<String>,
+
+        /// Only retry these exit codes, comma-separated (default: any failing code)
+        #[arg(
+            long,
+            value_name = "CODES",
+            value_delimiter = ',',
+            requires = "command_retries"
+        )]
+        command_retry_on: Vec<i32>,
+
+        /// Also operate on repositories marked `archived: true`
+        #[arg(long)]
+        include_archived: bool,
+
+        /// Feed the contents of this file to each repository's command on stdin
+        #[arg(long, value_name = "PATH", conflicts_with = "stdin")]
+        stdin_file: Option<PathBuf>,
+
+        /// Read stdin once and replay it to each repository's command
+        #[arg(long)]
+        stdin: bool,
+
+        /// Also write each repository's stdout to this path; {{.Name}} expands to the repository name
+        #[arg(long, value_name = "TEMPLATE")]
+        output_template: Option<String>,
+
+        /// Warn about repositories whose HEAD is detached before running
+        #[arg(long)]
+        warn_detached: bool,
+
+        /// Leave out repositories whose HEAD is detached
+        #[arg(long)]
+        skip_detached: bool,
+
+        /// Print the end-of-run summary as table, json or yaml
+        #[arg(long, value_name = "FORMAT", default_value = "table")]
+        summary_format: String,
+
+        /// Run each command inside this container image, with the repository mounted
+        #[arg(long, value_name = "IMAGE")]
+        container: Option<String>,
+
+        /// Container runtime for --container (default: docker, else podman)
+        #[arg(long, value_name = "RUNTIME", requires = "container")]
+        container_runtime: Option<String>,
+
+        /// Save each repository's logs under a directory named after its first tag
+        #[arg(long, conflicts_with = "no_save")]
+        logs_by_tag: bool,
+
+        /// Shell command run in each repository whose command succeeded (repos with their own `on_success` keep it)
+        #[arg(long, value_name = "COMMAND")]
+        on_success: Option<String>,
+
+        /// Shell command run in each repository whose command failed (repos with their own `on_failure` keep it)
+        #[arg(long, value_name = "COMMAND")]
+        on_failure: Option<String>,
+
+        /// Shell the command is passed to, e.g. bash or pwsh (default: defaults.shell, else sh; on Windows pwsh if installed, else cmd)
+        #[arg(long, value_name = "PATH")]
+        shell: Option<String>,
+
+        /// Command and arguments after `--`, started directly without a shell
+        #[arg(
+            last = true,
+            value_name = "ARGS",
+            conflicts_with_all = ["command", "recipe", "named"]
+        )]
+        argv: Vec<String>,
+    },
+
+    /// Run a program in each repository directly, without a shell
+    Exec {
+        /// Program and its arguments, passed exactly as given
+        #[arg(
+            value_name = "COMMAND",
+            required = true,
+            trailing_var_arg = true,
+            allow_hyphen_values = true
+        )]
+        argv: Vec<String>,
+
+        /// Configuration file path
+        #[arg(short, long, default_value_t = constants::config::DEFAULT_CONFIG_FILE.to_string())]
+        config: String,
+
+        /// Filter repositories by tag (can be specified multiple times)
+        #[arg(short, long)]
+        tag: Vec<String>,
+
+        /// Exclude repositories with these tags (can be specified multiple times)
+        #[arg(short = 'e', long)]
+        exclude_tag: Vec<String>,
+
+        /// Execute operations in parallel
+        #[arg(short, long)]
+        parallel: bool,
+
+        /// Don't save command outputs to files
+        #[arg(long)]
+        no_save: bool,
+
+        /// Custom directory for output files (default: output)
+        #[arg(long)]
+        output_dir: Option<String>,
+
+        /// Kill the program in a repository after this long (e.g. 90s, 10m)
+        #[arg(long, value_name = "DURATION")]
+        timeout: Option<String>,
+
+        /// Also operate on repositories marked `archived: true`
+        #[arg(long)]
+        include_archived: bool,
     },
 
     /// Create pull requests for repositories with changes
@@ -86,7 +535,7 @@ enum Commands {
         /// Specific repository names to create PRs for (if not provided, uses tag filter or all repos)
         repos: Vec<String>,
 
-        /// Title for the pull request
+        /// Title for the pull request; {{.Name}}, {{.Owner}}, {{.Branch}}, {{.Base}} and {{.Tags}} are filled in per repository
         #[arg(long, default_value = "Automated changes")]
         title: String,
 
@@ -110,7 +559,7 @@ enum Commands {
         #[arg(long)]
         draft: bool,
 
-        /// GitHub token
+        /// API token (GitHub token, or Bitbucket token / `username:app_password`)
         #[arg(long)]
         token: Option<String>,
 
@@ -118,6 +567,18 @@ enum Commands {
         #[arg(long)]
         create_only: bool,
 
+        /// Stage and commit every change, untracked files included (default: only already staged changes)
+        #[arg(long)]
+        commit_all: bool,
+
+        /// Author and committer name of the commits (default: defaults.author_name, else git config)
+        #[arg(long, value_name = "NAME")]
+        author_name: Option<String>,
+
+        /// Author and committer email of the commits (default: defaults.author_email, else git config)
+        #[arg(long, value_name = "EMAIL")]
+        author_email: Option<String>,
+
         /// Configuration file path
         #[arg(short, long, default_value_t = constants::config::DEFAULT_CONFIG_FILE.to_string())]
         config: String,
@@ -133,6 +594,42 @@ enum Commands {
         /// Execute operations in parallel
         #[arg(short, long)]
         parallel: bool,
+
+        /// SSH private key for git operations (repos with their own `ssh_key` keep it)
+        #[arg(long, value_name = "PATH")]
+        ssh_key: Option<String>,
+
+        /// Also operate on repositories marked `archived: true`
+        #[arg(long)]
+        include_archived: bool,
+
+        /// Minimum gap between pull request API calls, also with --parallel (e.g. 2s; 0 for none)
+        #[arg(long, value_name = "DURATION", default_value = constants::github::DEFAULT_API_INTERVAL)]
+        api_interval: String,
+
+        /// Warn when the base branch is protected (required reviews or checks, locked) before committing
+        #[arg(long)]
+        check_protection: bool,
+
+        /// Only create PRs where a changed file matches this glob (can be specified multiple times)
+        #[arg(long, value_name = "GLOB")]
+        changed_files: Vec<String>,
+
+        /// GitHub API base URL, e.g. https://github.example.com/api/v3 (default: GITHUB_API_URL, else defaults.github_api_url)
+        #[arg(long, value_name = "URL")]
+        github_api_url: Option<String>,
+
+        /// Owner sent as the PR head's owner:branch prefix (default: the owner in the repository's URL; the branch is still pushed to origin)
+        #[arg(long, value_name = "OWNER")]
+        head_owner: Option<String>,
+
+        /// Owner of the repository to open each PR against, e.g. the upstream of forks; each repository keeps its own name
+        #[arg(long, value_name = "OWNER")]
+        base_owner: Option<String>,
+
+        /// Exit successfully as long as no more than N repositories failed (failures are still reported)
+        #[arg(long, value_name = "N")]
+        max_pr_failures: Option<usize>,
     },
 
     /// Remove cloned repositories
@@ -157,6 +654,68 @@ enum Commands {
         parallel: bool,
     },
 
+    /// Apply configured `git_config` entries to existing clones
+    GitConfig {
+        /// Specific repository names to configure (if not provided, uses tag filter or all repos)
+        repos: Vec<String>,
+
+        /// Configuration file path
+        #[arg(short, long, default_value_t = constants::config::DEFAULT_CONFIG_FILE.to_string())]
+        config: String,
+
+        /// Filter repositories by tag (can be specified multiple times)
+        #[arg(short, long)]
+        tag: Vec<String>,
+
+        /// Exclude repositories with these tags (can be specified multiple times)
+        #[arg(short = 'e', long)]
+        exclude_tag: Vec<String>,
+    },
+
+    /// Show each clone's checked-out branch and uncommitted changes, warning about a detached HEAD
+    Status {
+        /// Specific repository names to check (if not provided, uses tag filter or all repos)
+        repos: Vec<String>,
+
+        /// Configuration file path
+        #[arg(short, long, default_value_t = constants::config::DEFAULT_CONFIG_FILE.to_string())]
+        config: String,
+
+        /// Filter repositories by tag (can be specified multiple times)
+        #[arg(short, long)]
+        tag: Vec<String>,
+
+        /// Exclude repositories with these tags (can be specified multiple times)
+        #[arg(short = 'e', long)]
+        exclude_tag: Vec<String>,
+    },
+
+    /// Delete branches already merged into each repository's default branch
+    PruneBranches {
+        /// Specific repository names to prune (if not provided, uses tag filter or all repos)
+        repos: Vec<String>,
+
+        /// Configuration file path
+        #[arg(short, long, default_value_t = constants::config::DEFAULT_CONFIG_FILE.to_string())]
+        config: String,
+
+        /// Filter repositories by tag (can be specified multiple times)
+        #[arg(short, long)]
+        tag: Vec<String>,
+
+        /// Exclude repositories with these tags (can be specified multiple times)
+        #[arg(short = 'e', long)]
+        exclude_tag: Vec<String>,
+
+        /// List the branches that would be deleted without deleting them
+        #[arg(long)]
+        dry_run: bool,
+
+        /// Also delete merged branches on the origin remote
+        #[arg(long)]
+        remote: bool,
+    },
+
     /// List repositories with optional filtering
     Ls {
         /// Specific repository names to list (if not provided, uses tag filter or all repos)
@@ -179,6 +738,50 @@ enum Commands {
         json: bool,
     },
 
+    /// Print how many repositories the filters select, without operating on them
+    Count {
+        /// Specific repository names to count (if not provided, uses tag filter or all repos)
+        repos: Vec<String>,
+
+        /// Configuration file path
+        #[arg(short, long, default_value_t = constants::config::DEFAULT_CONFIG_FILE.to_string())]
+        config: String,
+
+        /// Filter repositories by tag (can be specified multiple times)
+        #[arg(short, long)]
+        tag: Vec<String>,
+
+        /// Exclude repositories with these tags (can be specified multiple times)
+        #[arg(short = 'e', long)]
+        exclude_tag: Vec<String>,
+
+        /// Also print the name of each selected repository, one per line
+        #[arg(long)]
+        list: bool,
+    },
+
+    /// Show config metadata and live git facts for each repository
+    Info {
+        /// Specific repository names to show (if not provided, uses tag filter or all repos)
+        repos: Vec<String>,
+
+        /// Configuration file path
+        #[arg(short, long, default_value_t = constants::config::DEFAULT_CONFIG_FILE.to_string())]
+        config: String,
+
+        /// Filter repositories by tag (can be specified multiple times)
+        #[arg(short, long)]
+        tag: Vec<String>,
+
+        /// Exclude repositories with these tags (can be specified multiple times)
+        #[arg(short = 'e', long)]
+        exclude_tag: Vec<String>,
+
+        /// Print repositories as text or json
+        #[arg(long, value_name = "FORMAT", default_value = "text")]
+        output: String,
+    },
+
     /// Create a repos.yaml file from discovered Git repositories
     Init {
         /// Output file name
@@ -192,6 +795,16 @@ enum Commands {
         /// Supplement existing config with newly discovered repositories
         #[arg(long)]
         supplement: bool,
+
+        /// Tag repositories with up to N ancestor directory names below the current directory
+        #[arg(long, value_name = "N", default_value_t = 0)]
+        tag_depth: usize,
+    },
+
+    /// Inspect or maintain the configuration file
+    Config {
+        #[command(subcommand)]
+        action: ConfigAction,
     },
 
     /// Generate shell completions
@@ -206,9 +819,25 @@ enum Commands {
     External(Vec<String>),
 }
 
+#[derive(Subcommand)]
+enum ConfigAction {
+    /// Upgrade a config file to the current schema version in place (keeps a .bak copy)
+    Migrate {
+        /// Configuration file path
+        #[arg(short, long, default_value_t = constants::config::DEFAULT_CONFIG_FILE.to_string())]
+        config: String,
+    },
+}
+
 #[tokio::main]
 async fn main() -> Result<()> {
     let cli = Cli::parse();
+    git::set_verbose(cli.verbose);
+    git::set_interactive_auth(cli.interactive_auth);
+    repos_github::set_proxy(repos_github::ProxyConfig {
+        url: cli.proxy.clone(),
+        no_proxy: cli.no_proxy.clone(),
+    })?;
 
     // Handle list-plugins option first
     if cli.list_plugins {
@@ -227,6 +856,57 @@ async fn main() -> Result<()> {
         return Ok(());
     }
 
+    let active_since = cli
+        .active_since
+        .as_deref()
+        .map(|value| parse_since(value, Utc::now()))
+        .transpose()?;
+
+    let topics_token = if cli.fetch_topics {
+        match env::var("GITHUB_TOKEN") {
+            Ok(token) if !token.is_empty() => Some(token),
+            _ => anyhow::bail!("--fetch-topics requires a GitHub token: set GITHUB_TOKEN"),
+        }
+    } else {
+        None
+    };
+
+    let presence = if cli.only_cloned {
+        Some(Presence::Cloned)
+    } else if cli.only_missing {
+        Some(Presence::Missing)
+    } else {
+        None
+    };
+    let slice = RepoSlice {
+        shard: cli.shard.as_deref().map(str::parse).transpose()?,
+        offset: cli.offset,
+        limit: cli.limit,
+    };
+    let git_config = cli
+        .git_config
+        .iter()
+        .map(|entry| git::parse_git_config_entry(entry))
+        .collect::<Result<Vec<_>>>()?;
+    let notify_on = cli.notify_on.parse::<NotifyOn>()?;
+    let exit_policy = cli.exit_policy.parse::<ExitPolicy>()?;
+    let lock = cli.on_locked.parse::<git::LockMode>()?;
+    let config_format = cli
+        .config_format
+        .as_deref()
+        .map(str::parse::<ConfigFormat>)
+        .transpose()?;
+    for arg in &cli.clone_args {
+        git::check_clone_arg(arg)?;
+    }
+    // Read before the run, which may write its own report to the same path
+    let rerun_failed = cli
+        .rerun_failed
+        .as_deref()
+        .map(|path| RunReport::load(path).map(|report| report.failed_repositories()))
+        .transpose()?;
+    let repo_list = cli.repo_file.as_deref().map(RepoList::load).transpose()?;
+
     // Handle commands
     match cli.command {
         Some(Commands::Completions { shell }) => {
@@ -238,89 +918,212 @@ async fn main() -> Result<()> {
             if args.is_empty() {
                 anyhow::bail!("External command provided but no arguments given");
             }
+            if cli.profile.is_some() {
+                anyhow::bail!("--profile is not supported for plugin commands");
+            }
 
-            let plugin_name = &args[0];
-
-            // Parse common options from plugin args
-            let mut config_path = constants::config::DEFAULT_CONFIG_FILE.to_string();
-            let mut include_tags = Vec::new();
-            let mut exclude_tags = Vec::new();
-            let mut debug = false;
-            let mut plugin_args = Vec::new();
-
-            let mut i = 1;
-            while i < args.len() {
-                match args[i].as_str() {
-                    "--config" | "-c" => {
-                        if i + 1 < args.len() {
-                            config_path = args[i + 1].clone();
-                            i += 2;
-                        } else {
-                            anyhow::bail!("--config requires a path argument");
-                        }
-                    }
-                    "--tag" | "-t" => {
-                        if i + 1 < args.len() {
-                            include_tags.push(args[i + 1].clone());
-                            i += 2;
-                        } else {
-                            anyhow::bail!("--tag requires a tag argument");
-                        }
-                    }
-                    "--exclude-tag" | "-e" => {
-                        if i + 1 < args.len() {
-                            exclude_tags.push(args[i + 1].clone());
-                            i += 2;
-                        } else {
-                            anyhow::bail!("--exclude-tag requires a tag argument");
-                        }
-                    }
-                    "--debug" | "-d" => {
-                        debug = true;
-                        i += 1;
+            let started_at = chrono::Utc::now();
+            let selection = Selection {
+                active_since,
+                topics_token,
+                presence,
+                git_config,
+                clone_args: cli.clone_args,
+                targets: cli.targets,
+                slice,
+                interactive: cli.interactive,
+                config_format,
+                root: cli.dir,
+                repo_tags: cli.merge_repo_tags,
+                tags: TagPredicate::new(&cli.tags_any, &cli.tags_all, &cli.tags_none),
+                hosts: HostFilter::new(&cli.host),
+                rerun_failed,
+                repo_list,
+                ignore_missing: cli.ignore_missing,
+                ..Selection::default()
+            };
+            let result = execute_external_command(&args, &selection).await;
+            let options = serde_json::json!({ "args": &args[1..] });
+            let report = RunReport::new(&args[0], options, started_at, Vec::new(), &result);
+            if let Some(path) = &cli.report_file {
+                report.write_to(path)?;
+            }
+            if let Some(url) = &cli.notify_url {
+                notify_completion(url, notify_on, &report).await;
+            }
+            result?;
+        }
+        Some(mut command) => {
+            discover_config_path(&mut command);
+            let mut jobs = cli.jobs.map(NonZeroUsize::get);
+            // Before the profile, which only fills in tag filters not given here
+            if !cli.tags_any.is_empty() || !cli.tags_none.is_empty() {
+                let Some((tag, exclude_tag)) = tag_filters(&mut command) else {
+                    anyhow::bail!("--tags-any and --tags-none are not supported by this command");
+                };
+                tag.extend(cli.tags_any);
+                exclude_tag.extend(cli.tags_none);
+            }
+            if !cli.tags_all.is_empty() && !selects_repositories(&command) {
+                anyhow::bail!("--tags-all is not supported by this command");
+            }
+            if !cli.host.is_empty() && !selects_repositories(&command) {
+                anyhow::bail!("--host is not supported by this command");
+            }
+            if let Some(profile) = &cli.profile {
+                apply_profile(&mut command, profile, &mut jobs, config_format)?;
+            }
+            if !cli.targets.is_empty() {
+                clear_tag_filters(&mut command, "--repo")?;
+            }
+            if rerun_failed.is_some() {
+                clear_tag_filters(&mut command, "--rerun-failed")?;
+            }
+            if !slice.is_full() && !selects_repositories(&command) {
+                anyhow::bail!("--limit, --offset and --shard are not supported by this command");
+            }
+            if cli.interactive && !selects_repositories(&command) {
+                anyhow::bail!("--interactive is not supported by this command");
+            }
+            if cli.max_failures.is_some()
+                && !matches!(
+                    command,
+                    Commands::Clone { .. }
+                        | Commands::Pull { .. }
+                        | Commands::Run { .. }
+                        | Commands::Exec { .. }
+                )
+            {
+                anyhow::bail!("--max-failures is not supported by this command");
+            }
+            // The pr command decides itself whether its failures are tolerated
+            let exit_policy = match &command {
+                Commands::Pr {
+                    max_pr_failures: Some(max_failures),
+                    ..
+                } => {
+                    if exit_policy != ExitPolicy::default() {
+                        anyhow::bail!("--max-pr-failures cannot be combined with --exit-policy");
                     }
-                    _ => {
-                        // Plugin-specific arg
-                        plugin_args.push(args[i].clone());
-                        i += 1;
+                    ExitPolicy::AtMostFailures(*max_failures)
+                }
+                _ => exit_policy,
+            };
+            let deadline = match &cli.deadline {
+                Some(deadline) => {
+                    if !matches!(
+                        command,
+                        Commands::Clone { .. }
+                            | Commands::Pull { .. }
+                            | Commands::Run { .. }
+                            | Commands::Exec { .. }
+                    ) {
+                        anyhow::bail!("--deadline is not supported by this command");
                     }
+                    let duration = parse_duration(deadline)
+                        .with_context(|| format!("Invalid --deadline '{}'", deadline))?;
+                    let at = Instant::now().checked_add(duration).with_context(|| {
+                        format!("Invalid --deadline '{}': too far in the future", deadline)
+                    })?;
+                    Some((deadline.as_str(), at))
                 }
-            }
-
-            // Load config and filter repositories (only if needed or if config exists)
-            let needs_config = !include_tags.is_empty()
-                || !exclude_tags.is_empty()
-                || std::path::Path::new(&config_path).exists();
-
-            let (config, filtered_repos) = if needs_config {
-                let config = Config::load_config(&config_path)?;
-                let filtered_repos = if include_tags.is_empty() && exclude_tags.is_empty() {
-                    config.repositories.clone()
-                } else {
-                    config.filter_repositories(&include_tags, &exclude_tags, None)
-                };
-                (config, filtered_repos)
-            } else {
-                // No config available, pass empty data
-                (Config::new(), Vec::new())
+                None => None,
             };
-
-            // Build plugin context
-            let context = if needs_config {
-                plugins::PluginContext::with_config_path(
-                    config,
-                    filtered_repos,
-                    plugin_args,
-                    debug,
-                    config_path,
-                )
-            } else {
-                plugins::PluginContext::new(config, filtered_repos, plugin_args, debug)
+            let (name, options) = describe_command(&command);
+            let checkpoint = open_checkpoint(&command, name, &options)?;
+            let selection = Selection {
+                active_since,
+                topics_token,
+                presence,
+                git_config,
+                clone_args: cli.clone_args,
+                targets: cli.targets,
+                slice,
+                interactive: cli.interactive,
+                config_format,
+                root: cli.dir,
+                repo_tags: cli.merge_repo_tags,
+                // --tags-any and --tags-none became the command's --tag and --exclude-tag
+                tags: TagPredicate::new(&[], &cli.tags_all, &[]),
+                hosts: HostFilter::new(&cli.host),
+                rerun_failed,
+                repo_list,
+                ignore_missing: cli.ignore_missing,
+                lock,
+                completed: checkpoint
+                    .as_ref()
+                    .map(|checkpoint| checkpoint.completed().clone())
+                    .unwrap_or_default(),
+            };
+            let checkpoint = checkpoint.map(|checkpoint| Arc::new(Mutex::new(checkpoint)));
+            let outcomes = match &checkpoint {
+                Some(checkpoint) => OutcomeRecorder::with_checkpoint(checkpoint.clone()),
+                None => OutcomeRecorder::new(),
+            }
+            .with_max_failures(cli.max_failures.map(NonZeroUsize::get))
+            .with_on_result(cli.on_result)
+            .with_deadline(deadline.map(|(_, at)| at));
+            // Clones and pulls still running at the deadline are stopped too
+            git::set_deadline(deadline.map(|(_, at)| at));
+            let started_at = chrono::Utc::now();
+            let limits = JobLimits {
+                jobs,
+                clone_jobs: cli.clone_jobs.map(NonZeroUsize::get),
+                run_jobs: cli.run_jobs.map(NonZeroUsize::get),
             };
+            let result =
+                execute_builtin_command(command, outcomes.clone(), &selection, limits).await;
+            outcomes.flush().await;
+            if outcomes.aborted() > 0 {
+                eprintln!(
+                    "{}",
+                    format!(
+                        "Stopped after {} failures (--max-failures); skipped {} repositories",
+                        outcomes
+                            .outcomes()
+                            .iter()
+                            .filter(|outcome| outcome.failed())
+                            .count(),
+                        outcomes.aborted()
+                    )
+                    .red()
+                );
+            }
+            let incomplete = outcomes.incomplete();
+            if let Some((deadline, _)) = deadline
+                && !incomplete.is_empty()
+            {
+                eprintln!(
+                    "{}",
+                    format!(
+                        "Deadline of {} reached (--deadline); {} repositories incomplete: {}",
+                        deadline,
+                        incomplete.len(),
+                        incomplete.join(", ")
+                    )
+                    .red()
+                );
+            }
 
-            plugins::try_external_plugin(plugin_name, &context)?;
+            // A fully successful batch no longer needs its checkpoint
+            if let Some(checkpoint) = &checkpoint
+                && result.is_ok()
+                && outcomes.outcomes().iter().all(|outcome| outcome.success)
+            {
+                checkpoint
+                    .lock()
+                    .expect("checkpoint lock poisoned")
+                    .clear()?;
+            }
+            let report = RunReport::new(name, options, started_at, outcomes.outcomes(), &result);
+            if let Some(path) = &cli.report_file {
+                report.write_to(path)?;
+            }
+            if let Some(url) = &cli.notify_url {
+                notify_completion(url, notify_on, &report).await;
+            }
+            exit_policy.apply(result, &report.repositories)?;
         }
-        Some(command) => execute_builtin_command(command).await?,
         None => {
             // No command provided, print help
             anyhow::bail!("No command provided. Use --help for usage information.");
@@ -330,39 +1133,1242 @@ async fn main() -> Result<()> {
     Ok(())
 }
 
-async fn execute_builtin_command(command: Commands) -> Result<()> {
-    // Execute the appropriate command
-    match command {
-        Commands::External(_) => {
-            // These cases are handled in main(), this should not be reached
-            unreachable!("External commands should be handled in main()")
-        }
-        Commands::Clone {
-            repos,
-            config,
-            tag,
-            exclude_tag,
-            parallel,
-        } => {
-            let config = Config::load_config(&config)?;
-
-            // Validate clone command arguments using centralized validators
+/// Send the `--notify-url` summary of `report` if `notify_on` applies
+///
+/// A failed delivery is only logged; it never changes the exit code.
+async fn notify_completion(url: &str, notify_on: NotifyOn, report: &RunReport) {
+    if !notify_on.applies(report.success) {
+        return;
+    }
+    if let Err(e) = send_notification(url, &Notification::from(report)).await {
+        eprintln!("{}", format!("Warning: {:#}", e).yellow());
+    }
+}
+
+/// Run an external plugin, parsing the common options it shares with built-in commands
+async fn execute_external_command(args: &[String], selection: &Selection) -> Result<()> {
+    let plugin_name = &args[0];
+
+    // Parse common options from plugin args
+    let mut config_path = constants::config::DEFAULT_CONFIG_FILE.to_string();
+    let mut include_tags = Vec::new();
+    let mut exclude_tags = Vec::new();
+    let mut debug = false;
+    let mut plugin_args = Vec::new();
+
+    let mut i = 1;
+    while i < args.len() {
+        match args[i].as_str() {
+            "--config" | "-c" => {
+                if i + 1 < args.len() {
+                    config_path = args[i + 1].clone();
+                    i += 2;
+                } else {
+                    anyhow::bail!("--config requires a path argument");
+                }
+            }
+            "--tag" | "-t" => {
+                if i + 1 < args.len() {
+                    include_tags.push(args[i + 1].clone());
+                    i += 2;
+                } else {
+                    anyhow::bail!("--tag requires a tag argument");
+                }
+            }
+            "--exclude-tag" | "-e" => {
+                if i + 1 < args.len() {
+                    exclude_tags.push(args[i + 1].clone());
+                    i += 2;
+                } else {
+                    anyhow::bail!("--exclude-tag requires a tag argument");
+                }
+            }
+            "--debug" | "-d" => {
+                debug = true;
+                i += 1;
+            }
+            _ => {
+                // Plugin-specific arg
+                plugin_args.push(args[i].clone());
+                i += 1;
+            }
+        }
+    }
+
+    resolve_default_config(&mut config_path);
+
+    // Load config and filter repositories (only if needed or if config exists)
+    let needs_config = !include_tags.is_empty()
+        || !exclude_tags.is_empty()
+        || config_path == STDIN_PATH
+        || std::path::Path::new(&config_path).exists();
+
+    let (config, filtered_repos) = if needs_config {
+        let config = load_config(&config_path, selection).await?;
+        // Repositories picked with --repo or --rerun-failed bypass the plugin's tag filters
+        let filtered_repos = if (include_tags.is_empty() && exclude_tags.is_empty())
+            || !selection.targets.is_empty()
+            || selection.rerun_failed.is_some()
+        {
+            config.repositories.clone()
+        } else {
+            config.filter_repositories(&include_tags, &exclude_tags, None)
+        };
+        let (filtered_repos, unreadable) = filter_unreadable(&filtered_repos);
+        note_unreadable(&unreadable);
+        let filtered_repos = narrow(&filtered_repos, selection)?;
+        (config, filtered_repos)
+    } else {
+        // No config available, pass empty data
+        (Config::new(), Vec::new())
+    };
+
+    // Build plugin context
+    let context = if needs_config {
+        plugins::PluginContext::with_config_path(
+            config,
+            filtered_repos,
+            plugin_args,
+            debug,
+            config_path,
+        )
+    } else {
+        plugins::PluginContext::new(config, filtered_repos, plugin_args, debug)
+    };
+
+    plugins::try_external_plugin(plugin_name, &context)?;
+
+    Ok(())
+}
+
+/// Fill in flags not given on the command line from the named config profile
+fn apply_profile(
+    command: &mut Commands,
+    name: &str,
+    jobs: &mut Option<usize>,
+    config_format: Option<ConfigFormat>,
+) -> Result<()> {
+    let (config, tag, exclude_tag, parallel, output_dir) = match command {
+        Commands::Clone {
+            config,
+            tag,
+            exclude_tag,
+            parallel,
+            ..
+        }
+        | Commands::Pull {
+            config,
+            tag,
+            exclude_tag,
+            parallel,
+            ..
+        }
+        | Commands::Pr {
+            config,
+            tag,
+            exclude_tag,
+            parallel,
+            ..
+        }
+        | Commands::Rm {
+            config,
+            tag,
+            exclude_tag,
+            parallel,
+            ..
+        } => (&*config, tag, exclude_tag, Some(parallel), None),
+        Commands::Run {
+            config,
+            tag,
+            exclude_tag,
+            parallel,
+            output_dir,
+            ..
+        }
+        | Commands::Exec {
+            config,
+            tag,
+            exclude_tag,
+            parallel,
+            output_dir,
+            ..
+        } => (&*config, tag, exclude_tag, Some(parallel), Some(output_dir)),
+        Commands::Ls {
+            config,
+            tag,
+            exclude_tag,
+            ..
+        }
+        | Commands::Count {
+            config,
+            tag,
+            exclude_tag,
+            ..
+        }
+        | Commands::Info {
+            config,
+            tag,
+            exclude_tag,
+            ..
+        }
+        | Commands::GitConfig {
+            config,
+            tag,
+            exclude_tag,
+            ..
+        }
+        | Commands::Status {
+            config,
+            tag,
+            exclude_tag,
+            ..
+        }
+        | Commands::PruneBranches {
+            config,
+            tag,
+            exclude_tag,
+            ..
+        } => (&*config, tag, exclude_tag, None, None),
+        _ => anyhow::bail!("--profile is not supported by this command"),
+    };
+
+    read_config(config, config_format)?
+        .profile(name)?
+        .apply(ProfileFlags {
+            tag,
+            exclude_tag,
+            parallel,
+            jobs,
+            output_dir,
+        });
+    Ok(())
+}
+
+/// Drop `--tag` / `--exclude-tag` values, which `--repo` and `--rerun-failed` override
+fn clear_tag_filters(command: &mut Commands, flag: &str) -> Result<()> {
+    let Some((tag, exclude_tag)) = tag_filters(command) else {
+        anyhow::bail!("{} is not supported by this command", flag);
+    };
+    tag.clear();
+    exclude_tag.clear();
+    Ok(())
+}
+
+/// The `--tag` and `--exclude-tag` values of a command that filters by tag
+fn tag_filters(command: &mut Commands) -> Option<(&mut Vec<String>, &mut Vec<String>)> {
+    match command {
+        Commands::Clone {
+            tag, exclude_tag, ..
+        }
+        | Commands::Pull {
+            tag, exclude_tag, ..
+        }
+        | Commands::Run {
+            tag, exclude_tag, ..
+        }
+        | Commands::Exec {
+            tag, exclude_tag, ..
+        }
+        | Commands::Pr {
+            tag, exclude_tag, ..
+        }
+        | Commands::Rm {
+            tag, exclude_tag, ..
+        }
+        | Commands::Ls {
+            tag, exclude_tag, ..
+        }
+        | Commands::Count {
+            tag, exclude_tag, ..
+        }
+        | Commands::Info {
+            tag, exclude_tag, ..
+        }
+        | Commands::GitConfig {
+            tag, exclude_tag, ..
+        }
+        | Commands::Status {
+            tag, exclude_tag, ..
+        }
+        | Commands::PruneBranches {
+            tag, exclude_tag, ..
+        } => Some((tag, exclude_tag)),
+        _ => None,
+    }
+}
+
+/// Point a command left at the default `--config` to a discovered config file
+///
+/// The default `repos.yaml` in the current directory still wins; otherwise the
+/// nearest config in a parent directory or the XDG config directory is used
+/// (see [`repos::config::discovery`]). Without either, the default stays and
+/// the command reports it missing as before.
+fn discover_config_path(command: &mut Commands) {
+    let config = match command {
+        Commands::Clone { config, .. }
+        | Commands::Pull { config, .. }
+        | Commands::Run { config, .. }
+        | Commands::Exec { config, .. }
+        | Commands::Pr { config, .. }
+        | Commands::Rm { config, .. }
+        | Commands::GitConfig { config, .. }
+        | Commands::Status { config, .. }
+        | Commands::PruneBranches { config, .. }
+        | Commands::Ls { config, .. }
+        | Commands::Count { config, .. }
+        | Commands::Info { config, .. }
+        | Commands::Config {
+            action: ConfigAction::Migrate { config },
+        } => config,
+        _ => return,
+    };
+    resolve_default_config(config);
+}
+
+/// Replace the default config path with a discovered one, reporting the file used in debug mode
+fn resolve_default_config(config: &mut String) {
+    if config == constants::config::DEFAULT_CONFIG_FILE
+        && !Path::new(config.as_str()).exists()
+        && let Some(found) = discover_config()
+    {
+        *config = found.to_string_lossy().into_owned();
+    }
+    if repos::is_debug_mode() {
+        eprintln!("Using config {}", config);
+    }
+}
+
+/// Whether a command operates on a selection of the configured repositories
+fn selects_repositories(command: &Commands) -> bool {
+    matches!(
+        command,
+        Commands::Clone { .. }
+            | Commands::Pull { .. }
+            | Commands::Run { .. }
+            | Commands::Exec { .. }
+            | Commands::Pr { .. }
+            | Commands::Rm { .. }
+            | Commands::Ls { .. }
+            | Commands::Count { .. }
+            | Commands::Info { .. }
+            | Commands::GitConfig { .. }
+            | Commands::Status { .. }
+            | Commands::PruneBranches { .. }
+    )
+}
+
+/// Command name and options recorded in run reports
+fn describe_command(command: &Commands) -> (&'static str, serde_json::Value) {
+    match command {
+        Commands::Clone {
+            repos,
+            config,
+            tag,
+            exclude_tag,
+            parallel,
+            ssh_key,
+            resume,
+            repair,
+            update_existing,
+            error_on_existing,
+            mirror,
+            include_archived,
+            print_paths,
+            // Changes the order only, not which repositories are cloned
+            prioritize_size: _,
+        } => (
+            "clone",
+            serde_json::json!({
+                "repos": repos,
+                "config": config,
+                "tag": tag,
+                "exclude_tag": exclude_tag,
+                "parallel": parallel,
+                "ssh_key": ssh_key,
+                "resume": resume,
+                "repair": repair,
+                "update_existing": update_existing,
+                "error_on_existing": error_on_existing,
+                "mirror": mirror,
+                "include_archived": include_archived,
+                "print_paths": print_paths,
+            }),
+        ),
+        Commands::Pull {
+            repos,
+            config,
+            tag,
+            exclude_tag,
+            parallel,
+            abort_on_conflict,
+            rebase,
+            ssh_key,
+        } => (
+            "pull",
+            serde_json::json!({
+                "repos": repos,
+                "config": config,
+                "tag": tag,
+                "exclude_tag": exclude_tag,
+                "parallel": parallel,
+                "abort_on_conflict": abort_on_conflict,
+                "rebase": rebase,
+                "ssh_key": ssh_key,
+            }),
+        ),
+        Commands::Run {
+            command,
+            recipe,
+            named,
+            repos,
+            config,
+            tag,
+            exclude_tag,
+            parallel,
+            no_save,
+            output_dir,
+            resume,
+            timeout,
+            where_health,
+            // Display-only, so they stay out of the options (and the --resume key)
+            timings: _,
+            ordered_output: _,
+            show_active: _,
+            warn_detached: _,
+            summary_format: _,
+            strict,
+            allow_exit_codes,
+            success_if,
+            failure_if,
+            command_retries,
+            command_retry_delay,
+            command_retry_on,
+            include_archived,
+            stdin_file,
+            stdin,
+            output_template,
+            arg,
+            skip_detached,
+            container,
+            container_runtime,
+            logs_by_tag,
+            on_success,
+            on_failure,
+            shell,
+            argv,
+        } => (
+            "run",
+            serde_json::json!({
+                "command": command,
+                "argv": argv,
+                "recipe": recipe,
+                "arg": arg,
+                "named": named,
+                "repos": repos,
+                "config": config,
+                "tag": tag,
+                "exclude_tag": exclude_tag,
+                "parallel": parallel,
+                "no_save": no_save,
+                "output_dir": output_dir,
+                "resume": resume,
+                "timeout": timeout,
+                "where_health": where_health,
+                "strict": strict,
+                "allow_exit_codes": allow_exit_codes,
+                "success_if": success_if,
+                "failure_if": failure_if,
+                "command_retries": command_retries,
+                "command_retry_delay": command_retry_delay,
+                "command_retry_on": command_retry_on,
+                "include_archived": include_archived,
+                "stdin_file": stdin_file,
+                "stdin": stdin,
+                "output_template": output_template,
+                "skip_detached": skip_detached,
+                "container": container,
+                "container_runtime": container_runtime,
+                "logs_by_tag": logs_by_tag,
+                "on_success": on_success,
+                "on_failure": on_failure,
+                "shell": shell,
+            }),
+        ),
+        Commands::Exec {
+            argv,
+            config,
+            tag,
+            exclude_tag,
+            parallel,
+            no_save,
+            output_dir,
+            timeout,
+            include_archived,
+        } => (
+            "exec",
+            serde_json::json!({
+                "argv": argv,
+                "config": config,
+                "tag": tag,
+                "exclude_tag": exclude_tag,
+                "parallel": parallel,
+                "no_save": no_save,
+                "output_dir": output_dir,
+                "timeout": timeout,
+                "include_archived": include_archived,
+            }),
+        ),
+        // The token is deliberately left out of the report
+        Commands::Pr {
+            repos,
+            title,
+            body,
+            branch,
+            base,
+            message,
+            draft,
+            token: _,
+            create_only,
+            commit_all,
+            author_name,
+            author_email,
+            config,
+            tag,
+            exclude_tag,
+            parallel,
+            ssh_key,
+            include_archived,
+            api_interval,
+            check_protection,
+            changed_files,
+            github_api_url,
+            head_owner,
+            base_owner,
+            max_pr_failures,
+        } => (
+            "pr",
+            serde_json::json!({
+                "repos": repos,
+                "title": title,
+                "body": body,
+                "branch": branch,
+                "base": base,
+                "message": message,
+                "draft": draft,
+                "create_only": create_only,
+                "commit_all": commit_all,
+                "author_name": author_name,
+                "author_email": author_email,
+                "config": config,
+                "tag": tag,
+                "exclude_tag": exclude_tag,
+                "parallel": parallel,
+                "ssh_key": ssh_key,
+                "include_archived": include_archived,
+                "api_interval": api_interval,
+                "check_protection": check_protection,
+                "changed_files": changed_files,
+                "github_api_url": github_api_url,
+                "head_owner": head_owner,
+                "base_owner": base_owner,
+                "max_pr_failures": max_pr_failures,
+            }),
+        ),
+        Commands::Rm {
+            repos,
+            config,
+            tag,
+            exclude_tag,
+            parallel,
+        } => (
+            "rm",
+            serde_json::json!({
+                "repos": repos,
+                "config": config,
+                "tag": tag,
+                "exclude_tag": exclude_tag,
+                "parallel": parallel,
+            }),
+        ),
+        Commands::GitConfig {
+            repos,
+            config,
+            tag,
+            exclude_tag,
+        } => (
+            "git-config",
+            serde_json::json!({
+                "repos": repos,
+                "config": config,
+                "tag": tag,
+                "exclude_tag": exclude_tag,
+            }),
+        ),
+        Commands::Status {
+            repos,
+            config,
+            tag,
+            exclude_tag,
+        } => (
+            "status",
+            serde_json::json!({
+                "repos": repos,
+                "config": config,
+                "tag": tag,
+                "exclude_tag": exclude_tag,
+            }),
+        ),
+        Commands::PruneBranches {
+            repos,
+            config,
+            tag,
+            exclude_tag,
+            dry_run,
+            remote,
+        } => (
+            "prune-branches",
+            serde_json::json!({
+                "repos": repos,
+                "config": config,
+                "tag": tag,
+                "exclude_tag": exclude_tag,
+                "dry_run": dry_run,
+                "remote": remote,
+            }),
+        ),
+        Commands::Ls {
+            repos,
+            config,
+            tag,
+            exclude_tag,
+            json,
+        } => (
+            "ls",
+            serde_json::json!({
+                "repos": repos,
+                "config": config,
+                "tag": tag,
+                "exclude_tag": exclude_tag,
+                "json": json,
+            }),
+        ),
+        Commands::Count {
+            repos,
+            config,
+            tag,
+            exclude_tag,
+            list,
+        } => (
+            "count",
+            serde_json::json!({
+                "repos": repos,
+                "config": config,
+                "tag": tag,
+                "exclude_tag": exclude_tag,
+                "list": list,
+            }),
+        ),
+        Commands::Info {
+            repos,
+            config,
+            tag,
+            exclude_tag,
+            output,
+        } => (
+            "info",
+            serde_json::json!({
+                "repos": repos,
+                "config": config,
+                "tag": tag,
+                "exclude_tag": exclude_tag,
+                "output": output,
+            }),
+        ),
+        Commands::Init {
+            output,
+            overwrite,
+            supplement,
+            tag_depth,
+        } => (
+            "init",
+            serde_json::json!({
+                "output": output,
+                "overwrite": overwrite,
+                "supplement": supplement,
+                "tag_depth": tag_depth,
+            }),
+        ),
+        Commands::Config {
+            action: ConfigAction::Migrate { config },
+        } => (
+            "config migrate",
+            serde_json::json!({
+                "config": config,
+            }),
+        ),
+        Commands::Completions { shell } => (
+            "completions",
+            serde_json::json!({ "shell": shell.to_string() }),
+        ),
+        Commands::External(args) => ("external", serde_json::json!({ "args": args })),
+    }
+}
+
+/// Invocation-wide narrowing and defaults for the configured repositories
+#[derive(Default)]
+struct Selection {
+    /// `--active-since` cut-off
+    active_since: Option<DateTime<Utc>>,
+    /// Repositories already completed according to a `--resume` checkpoint
+    completed: BTreeSet<String>,
+    /// GitHub token for `--fetch-topics` enrichment
+    topics_token: Option<String>,
+    /// `--only-cloned` / `--only-missing`
+    presence: Option<Presence>,
+    /// `--git-config` defaults merged into every repository's `git_config`
+    git_config: Vec<(String, String)>,
+    /// `--clone-arg` options given to `git clone` before each repository's own `clone_args`
+    clone_args: Vec<String>,
+    /// `--repo` names or aliases; when set, only these repositories are loaded
+    targets: Vec<String>,
+    /// `--shard`, `--offset` and `--limit`, applied after all other filtering
+    slice: RepoSlice,
+    /// Choose from the remaining repositories with a picker (`--interactive`)
+    interactive: bool,
+    /// `--config-format`, overriding detection by file extension
+    config_format: Option<ConfigFormat>,
+    /// `--on-locked`: skip or wait for repositories another process has locked
+    lock: git::LockMode,
+    /// `--dir`: directory clones live under, overriding `defaults.root`
+    root: Option<String>,
+    /// `--merge-repo-tags`: add tags from each clone's `.repos-tags` file
+    repo_tags: bool,
+    /// `--tags-any`, `--tags-all` and `--tags-none`; ignored for `--repo` targets
+    tags: TagPredicate,
+    /// `--host`: remote hosts to keep; ignored for `--repo` targets
+    hosts: HostFilter,
+    /// `--rerun-failed`: names of the repositories that failed in a previous report
+    rerun_failed: Option<BTreeSet<String>>,
+    /// `--repo-file`: names to keep before the selection files and tag filters
+    repo_list: Option<RepoList>,
+    /// `--ignore-missing`: skip `--repo-file` names that are not configured
+    ignore_missing: bool,
+}
+
+/// Read a config file in the `--config-format` if given, else by its extension
+fn read_config(path: &str, format: Option<ConfigFormat>) -> Result<Config> {
+    match format {
+        Some(format) => Config::load_as(path, format),
+        None => Config::load_config(path),
+    }
+}
+
+/// Load the configuration and apply the invocation-wide selection
+async fn load_config(path: &str, selection: &Selection) -> Result<Config> {
+    let mut config = read_config(path, selection.config_format)?;
+    if !config.orgs.is_empty() {
+        expand_org_sources(&mut config, path).await?;
+    }
+    if let Some(root) = &selection.root {
+        config.apply_clone_root(root);
+    }
+    // Before any filtering, so tags from the clones select like config tags
+    if selection.repo_tags {
+        config.merge_repo_tags()?;
+    }
+    if !selection.targets.is_empty() {
+        config.repositories = config.resolve_repositories(&selection.targets)?;
+    } else if let Some(failed) = &selection.rerun_failed {
+        // Like --repo targets, bypassing the selection files and tag filters
+        config
+            .repositories
+            .retain(|repo| failed.contains(&repo.name));
+    } else {
+        if let Some(list) = &selection.repo_list {
+            config.repositories = list.select(&config.repositories, selection.ignore_missing)?;
+        }
+        // Repositories picked with --repo bypass the selection files
+        let config_dir = Path::new(path).parent().unwrap_or(Path::new(""));
+        let files = SelectionFiles::load(config_dir)?;
+        if !files.is_empty() {
+            config.repositories.retain(|repo| files.selects(repo));
+        }
+        if !selection.tags.is_empty() {
+            config
+                .repositories
+                .retain(|repo| selection.tags.matches(repo));
+        }
+        if !selection.hosts.is_empty() {
+            config
+                .repositories
+                .retain(|repo| selection.hosts.matches(repo));
+        }
+    }
+    if !selection.git_config.is_empty() {
+        config.apply_default_git_config(&selection.git_config);
+    }
+    if !selection.clone_args.is_empty() {
+        config.apply_default_clone_args(&selection.clone_args);
+    }
+    if let Some(token) = &selection.topics_token {
+        fetch_topics(&mut config.repositories, token).await?;
+    }
+    if let Some(presence) = selection.presence {
+        config.repositories = retain_by_presence(&config.repositories, presence);
+    }
+    if let Some(since) = selection.active_since {
+        config.repositories = retain_active_since(&config.repositories, since);
+    }
+    if !selection.completed.is_empty() {
+        config.repositories.retain(|repo| {
+            let done = selection.completed.contains(&repo.name);
+            if done {
+                eprintln!(
+                    "{} | {}",
+                    repo.name.cyan().bold(),
+                    "Already completed, skipping (--resume)".yellow()
+                );
+            }
+            !done
+        });
+    }
+    Ok(config)
+}
+
+/// Open the checkpoint for a `--resume` invocation of `clone` or `run`
+fn open_checkpoint(
+    command: &Commands,
+    name: &str,
+    options: &serde_json::Value,
+) -> Result<Option<Checkpoint>> {
+    let (config_path, output_dir) = match command {
+        Commands::Clone {
+            config,
+            resume: true,
+            ..
+        } => (config, None),
+        Commands::Run {
+            config,
+            output_dir,
+            resume: true,
+            ..
+        } => (config, output_dir.as_deref()),
+        _ => return Ok(None),
+    };
+
+    // A missing config is reported by the command itself
+    let config_content = std::fs::read_to_string(config_path).unwrap_or_default();
+    let command_key = format!("{} {}", name, options);
+    let dir = PathBuf::from(output_dir.unwrap_or(constants::config::DEFAULT_LOGS_DIR))
+        .join(constants::config::CHECKPOINTS_DIR);
+
+    let checkpoint = Checkpoint::open(
+        &dir,
+        &Checkpoint::key(&command_key, &config_content),
+        &command_key,
+    )?;
+    if !checkpoint.completed().is_empty() {
+        println!(
+            "{}",
+            format!(
+                "Resuming from {} ({} repositories already completed)",
+                checkpoint.path().display(),
+                checkpoint.completed().len()
+            )
+            .cyan()
+        );
+    }
+    Ok(Some(checkpoint))
+}
+
+/// Add the repositories of every `orgs` entry, reusing cached listings when fresh
+async fn expand_org_sources(config: &mut Config, path: &str) -> Result<()> {
+    let client = env::var("GITHUB_TOKEN")
+        .ok()
+        .filter(|token| !token.is_empty())
+        .map(|token| repos_github::GitHubClient::new(Some(token)));
+    let mut cache = OrgCache::open(
+        PathBuf::from(constants::config::DEFAULT_LOGS_DIR).join(constants::config::ORGS_CACHE_FILE),
+    );
+
+    let (mut expanded, failures) = expand_orgs(&config.orgs, client.as_ref(), &mut cache).await;
+    for (org, error) in failures {
+        eprintln!(
+            "{} | {}",
+            org.cyan().bold(),
+            format!("Failed to list organization repositories: {}", error).yellow()
+        );
+    }
+
+    let base_dir = resolve_base_dir(config.defaults.root.as_deref(), Path::new(path).parent());
+    for repo in &mut expanded {
+        repo.set_config_dir(base_dir.clone());
+    }
+    config.merge_repositories(expanded);
+    cache.save()
+}
+
+/// Merge GitHub topics into repository tags, reusing cached topics when fresh
+async fn fetch_topics(repositories: &mut [Repository], token: &str) -> Result<()> {
+    let client = repos_github::GitHubClient::new(Some(token.to_string()));
+    let mut cache = TopicCache::open(
+        PathBuf::from(constants::config::DEFAULT_LOGS_DIR)
+            .join(constants::config::TOPICS_CACHE_FILE),
+    );
+
+    for (name, error) in enrich_with_topics(repositories, &client, &mut cache).await {
+        eprintln!(
+            "{} | {}",
+            name.cyan().bold(),
+            format!("Failed to fetch topics: {}", error).yellow()
+        );
+    }
+    cache.save()
+}
+
+/// Look up GitHub repository sizes for `clone --prioritize-size`
+///
+/// Sizes are looked up at the API base URL `pr` would use for each
+/// repository. Without a token, or for repositories that cannot be sized,
+/// clones keep their config order.
+async fn fetch_repository_sizes(config: &Config) -> BTreeMap<String, u64> {
+    let Some(token) = env::var("GITHUB_TOKEN")
+        .ok()
+        .filter(|token| !token.is_empty())
+    else {
+        eprintln!(
+            "{}",
+            "--prioritize-size needs GITHUB_TOKEN to look up sizes; cloning in config order"
+                .yellow()
+        );
+        return BTreeMap::new();
+    };
+    let api = GitHubApi::from_defaults(Some(token), &config.defaults);
+
+    let (sizes, failures) = repository_sizes(&config.repositories, &api).await;
+    for (name, error) in failures {
+        eprintln!(
+            "{} | {}",
+            name.cyan().bold(),
+            format!("Failed to look up repository size: {}", error).yellow()
+        );
+    }
+    sizes
+}
+
+/// Drop archived repositories, noting on stderr how many the selection loses
+///
+/// Returns the number of selected repositories that were skipped.
+fn skip_archived(
+    config: &mut Config,
+    tag: &[String],
+    exclude_tag: &[String],
+    repos: &[String],
+) -> usize {
+    let names = (!repos.is_empty()).then_some(repos);
+    let (_, skipped) = filter_archived(&config.filter_repositories(tag, exclude_tag, names));
+    config.repositories.retain(|repo| !repo.archived);
+
+    if !skipped.is_empty() {
+        let names: Vec<&str> = skipped.iter().map(|repo| repo.name.as_str()).collect();
+        eprintln!(
+            "{}",
+            format!(
+                "Skipping {} archived repositories ({}); pass --include-archived to include them",
+                skipped.len(),
+                names.join(", ")
+            )
+            .yellow()
+        );
+    }
+    skipped.len()
+}
+
+/// Warn about selected repositories with a detached HEAD, or leave them out when `skip` is set
+fn check_detached(
+    config: &mut Config,
+    tag: &[String],
+    exclude_tag: &[String],
+    repos: &[String],
+    skip: bool,
+) {
+    let names = (!repos.is_empty()).then_some(repos);
+    let (_, detached) = filter_detached(&config.filter_repositories(tag, exclude_tag, names));
+    if skip {
+        config
+            .repositories
+            .retain(|repo| !detached.iter().any(|d| d.name == repo.name));
+        note_skipped(&detached, "--skip-detached");
+        return;
+    }
+    for repo in &detached {
+        eprintln!(
+            "{} | {}",
+            repo.name.cyan().bold(),
+            "Warning: HEAD is detached; new commits will not be on any branch".yellow()
+        );
+    }
+}
+
+/// Narrow the selected repositories to `--shard`, `--offset` and `--limit`,
+/// then to those picked with `--interactive`
+///
+/// Called once a command's other filters have run, so the slice is taken from
+/// the repositories the command would otherwise operate on.
+fn narrow_selected(
+    config: &mut Config,
+    tag: &[String],
+    exclude_tag: &[String],
+    repos: &[String],
+    selection: &Selection,
+) -> Result<()> {
+    if selection.slice.is_full() && !selection.interactive {
+        return Ok(());
+    }
+    let names = (!repos.is_empty()).then_some(repos);
+    let kept = narrow(
+        &config.filter_repositories(tag, exclude_tag, names),
+        selection,
+    )?;
+    config
+        .repositories
+        .retain(|repo| kept.iter().any(|k| k.name == repo.name));
+    Ok(())
+}
+
+fn narrow(repositories: &[Repository], selection: &Selection) -> Result<Vec<Repository>> {
+    let sliced = slice_repositories(repositories, selection.slice);
+    if !selection.interactive {
+        return Ok(sliced);
+    }
+    // Checkboxes need a terminal to answer on; piped input gets a numbered prompt
+    let checkboxes = io::stdin().is_terminal();
+    pick_repositories(&sliced, io::stdin().lock(), io::stderr(), checkboxes)
+}
+
+/// Leave out selected clones that cannot be listed or opened by git, noting each one on stderr
+///
+/// Used by the commands that work inside clones, so one broken checkout does
+/// not abort the batch; `clone` and `rm` inspect their targets themselves.
+fn skip_unreadable(config: &mut Config, tag: &[String], exclude_tag: &[String], repos: &[String]) {
+    let names = (!repos.is_empty()).then_some(repos);
+    let (_, unreadable) = filter_unreadable(&config.filter_repositories(tag, exclude_tag, names));
+    config
+        .repositories
+        .retain(|repo| !unreadable.iter().any(|u| u.name == repo.name));
+    note_unreadable(&unreadable);
+}
+
+fn note_unreadable(unreadable: &[SkippedRepository]) {
+    for repo in unreadable {
+        eprintln!(
+            "{} | {}",
+            repo.name.cyan().bold(),
+            format!("Skipped (unreadable): {}", repo.reason).yellow()
+        );
+    }
+}
+
+/// Keep repositories by on-disk presence, noting the others on stderr
+fn retain_by_presence(repositories: &[Repository], presence: Presence) -> Vec<Repository> {
+    let (selected, skipped) = filter_by_presence(repositories, presence);
+    let flag = match presence {
+        Presence::Cloned => "--only-cloned",
+        Presence::Missing => "--only-missing",
+    };
+    note_skipped(&skipped, flag);
+    selected
+}
+
+/// Drop repositories without a commit since `since`, noting each one on stderr
+fn retain_active_since(repositories: &[Repository], since: DateTime<Utc>) -> Vec<Repository> {
+    let (active, skipped) = filter_active_since(repositories, since);
+    note_skipped(&skipped, "--active-since");
+    active
+}
+
+/// Health-check the selected repositories and leave out those not matching `filter`
+///
+/// The vulnerability check is left out, as it runs networked audit tools.
+/// File-walking checks skip the config's `scan_exclude` paths, and the
+/// sensitive files check accepts its `sensitive_files_allow` paths. Ignored
+/// checks that match no checker are warned about.
+fn retain_by_health(
+    config: &mut Config,
+    tag: &[String],
+    exclude_tag: &[String],
+    repos: &[String],
+    filter: HealthFilter,
+) -> Result<()> {
+    let factory = CheckerFactory::with_options(HealthOptions {
+        scan_exclude: ScanExclude::new(&config.scan_exclude)?,
+        sensitive_files: SensitiveFilesOptions::new(&config.sensitive_files_allow)?,
+        ..HealthOptions::default()
+    });
+    let names = (!repos.is_empty()).then_some(repos);
+    let selected = config.filter_repositories(tag, exclude_tag, names);
+    let known_checks = factory.checker_names();
+    for repo in selected.iter().filter(|repo| repo.exists()) {
+        let ignored = ignored_checks(repo, Path::new(&repo.get_target_dir()));
+        for check in unknown_checks(&ignored, &known_checks) {
+            eprintln!(
+                "{} | {}",
+                repo.name.cyan().bold(),
+                format!(
+                    "Warning: ignores unknown health check '{}' (available: {})",
+                    check,
+                    known_checks.join(", ")
+                )
+                .yellow()
+            );
+        }
+    }
+    let checkers: Vec<_> = factory
+        .checkers()
+        .into_iter()
+        .filter(|checker| checker.name() != "vulnerabilities")
+        .collect();
+    let reports = check_all_repositories(&selected, &checkers);
+    let (_, skipped) = filter_by_health(&selected, &reports, filter);
+    config
+        .repositories
+        .retain(|repo| !skipped.iter().any(|s| s.name == repo.name));
+    note_skipped(&skipped, "--where-health");
+    Ok(())
+}
+
+fn note_skipped(skipped: &[SkippedRepository], flag: &str) {
+    for repo in skipped {
+        eprintln!(
+            "{} | {}",
+            repo.name.cyan().bold(),
+            format!("Skipped by {} ({})", flag, repo.reason).yellow()
+        );
+    }
+}
+
+async fn execute_builtin_command(
+    command: Commands,
+    outcomes: OutcomeRecorder,
+    selection: &Selection,
+    limits: JobLimits,
+) -> Result<()> {
+    // Execute the appropriate command
+    match command {
+        Commands::External(_) => {
+            // These cases are handled in main(), this should not be reached
+            unreachable!("External commands should be handled in main()")
+        }
+        Commands::Clone {
+            repos,
+            config,
+            tag,
+            exclude_tag,
+            parallel,
+            ssh_key,
+            resume: _,
+            repair,
+            update_existing,
+            error_on_existing,
+            mirror,
+            include_archived,
+            print_paths,
+            prioritize_size,
+        } => {
+            let mut config = load_config(&config, selection).await?;
+            let archived_skipped = if include_archived {
+                0
+            } else {
+                skip_archived(&mut config, &tag, &exclude_tag, &repos)
+            };
+            if let Some(ssh_key) = &ssh_key {
+                config.apply_default_ssh_key(ssh_key);
+            }
+            if mirror {
+                for repo in &mut config.repositories {
+                    repo.mirror = true;
+                }
+            }
+
+            narrow_selected(&mut config, &tag, &exclude_tag, &repos, selection)?;
+
+            // Validate clone command arguments using centralized validators
             validators::validate_tag_filters(&tag)?;
             validators::validate_tag_filters(&exclude_tag)?;
             validators::validate_repository_names(&repos)?;
 
+            let sizes = if prioritize_size && !print_paths {
+                Some(fetch_repository_sizes(&config).await)
+            } else {
+                None
+            };
             let context = CommandContext {
                 config,
                 tag,
                 exclude_tag,
                 parallel,
                 repos: if repos.is_empty() { None } else { Some(repos) },
+                outcomes: outcomes.clone(),
+                jobs: limits.for_clone(),
             };
-            CloneCommand.execute(&context).await?;
+            CloneCommand {
+                options: git::CloneOptions {
+                    repair,
+                    update_existing,
+                    error_on_existing,
+                    lock: Some(selection.lock),
+                },
+                print_paths,
+                sizes,
+                archived_skipped,
+            }
+            .execute(&context)
+            .await?;
+        }
+        Commands::Pull {
+            repos,
+            config,
+            tag,
+            exclude_tag,
+            parallel,
+            abort_on_conflict,
+            rebase,
+            ssh_key,
+        } => {
+            let mut config = load_config(&config, selection).await?;
+            skip_unreadable(&mut config, &tag, &exclude_tag, &repos);
+            if let Some(ssh_key) = &ssh_key {
+                config.apply_default_ssh_key(ssh_key);
+            }
+            narrow_selected(&mut config, &tag, &exclude_tag, &repos, selection)?;
+
+            validators::validate_tag_filters(&tag)?;
+            validators::validate_tag_filters(&exclude_tag)?;
+            validators::validate_repository_names(&repos)?;
+
+            let context = CommandContext {
+                config,
+                tag,
+                exclude_tag,
+                parallel,
+                repos: if repos.is_empty() { None } else { Some(repos) },
+                outcomes: outcomes.clone(),
+                jobs: limits.jobs,
+            };
+            PullCommand {
+                options: git::PullOptions {
+                    abort_on_conflict,
+                    strategy: rebase.then_some(PullStrategy::Rebase),
+                    lock: Some(selection.lock),
+                },
+            }
+            .execute(&context)
+            .await?;
         }
         Commands::Run {
             command,
             recipe,
+            named,
             repos,
             config,
             tag,
@@ -370,15 +2376,105 @@ async fn execute_builtin_command(command: Commands) -> Result<()> {
             parallel,
             no_save,
             output_dir,
+            resume: _,
+            timeout,
+            where_health,
+            timings,
+            ordered_output,
+            show_active,
+            strict: _,
+            allow_exit_codes,
+            success_if,
+            failure_if,
+            command_retries,
+            command_retry_delay,
+            command_retry_on,
+            include_archived,
+            stdin_file,
+            stdin,
+            output_template,
+            arg,
+            warn_detached,
+            skip_detached,
+            summary_format,
+            container,
+            container_runtime,
+            logs_by_tag,
+            on_success,
+            on_failure,
+            shell,
+            argv,
         } => {
-            let config = Config::load_config(&config)?;
+            let summary_format = summary_format.parse::<SummaryFormat>()?;
+            let container = container
+                .as_deref()
+                .map(|image| Container::new(image, container_runtime.as_deref()))
+                .transpose()?;
+            let where_health = where_health
+                .as_deref()
+                .map(str::parse::<HealthFilter>)
+                .transpose()?;
+            let output_template = output_template
+                .as_deref()
+                .map(str::parse::<OutputTemplate>)
+                .transpose()?;
+            let output_match = OutputMatch::new(success_if.as_deref(), failure_if.as_deref())?;
+            let mut config = load_config(&config, selection).await?;
+            let archived_skipped = if include_archived {
+                0
+            } else {
+                skip_archived(&mut config, &tag, &exclude_tag, &repos)
+            };
+            skip_unreadable(&mut config, &tag, &exclude_tag, &repos);
+            if stdin && selection.interactive {
+                anyhow::bail!("--stdin cannot be combined with --interactive");
+            }
+            let timeout = timeout.as_deref().map(parse_duration).transpose()?;
+            let recipe_args = arg
+                .iter()
+                .map(|entry| parse_recipe_arg(entry))
+                .collect::<Result<RecipeArgs>>()?;
+            let retry = RetryPolicy {
+                retries: command_retries.unwrap_or(0),
+                delay: command_retry_delay
+                    .as_deref()
+                    .map(parse_duration)
+                    .transpose()?
+                    .unwrap_or_default(),
+                exit_codes: command_retry_on,
+            };
+            let input =
+                if let Some(path) = &stdin_file {
+                    Some(std::fs::read(path).with_context(|| {
+                        format!("Failed to read --stdin-file {}", path.display())
+                    })?)
+                } else if stdin {
+                    let mut buffer = Vec::new();
+                    io::stdin()
+                        .read_to_end(&mut buffer)
+                        .context("Failed to read stdin")?;
+                    Some(buffer)
+                } else {
+                    None
+                };
+            if let Some(filter) = where_health {
+                retain_by_health(&mut config, &tag, &exclude_tag, &repos, filter)?;
+            }
+            if warn_detached || skip_detached {
+                check_detached(&mut config, &tag, &exclude_tag, &repos, skip_detached);
+            }
+            narrow_selected(&mut config, &tag, &exclude_tag, &repos, selection)?;
 
-            // Validate run command arguments using centralized validators
-            validators::validate_run_args(&command, &recipe)?;
+            // Validate run command arguments using centralized validators;
+            // with --named the command is an optional default
+            if named.is_none() && argv.is_empty() {
+                validators::validate_run_args(&command, &recipe)?;
+            }
             validators::validate_tag_filters(&tag)?;
             validators::validate_tag_filters(&exclude_tag)?;
             validators::validate_repository_names(&repos)?;
             validators::validate_output_directory(&output_dir)?;
+            validators::validate_exit_codes(&allow_exit_codes)?;
 
             let context = CommandContext {
                 config,
@@ -386,17 +2482,104 @@ async fn execute_builtin_command(command: Commands) -> Result<()> {
                 exclude_tag,
                 parallel,
                 repos: if repos.is_empty() { None } else { Some(repos) },
+                outcomes: outcomes.clone(),
+                jobs: limits.for_run(),
             };
 
-            if let Some(cmd) = command {
-                RunCommand::new_command(cmd, no_save, output_dir.map(PathBuf::from))
-                    .execute(&context)
-                    .await?;
+            let shell = shell
+                .or_else(|| context.config.defaults.shell.clone())
+                .map(|program| repos::runner::Shell::new(&program))
+                .unwrap_or_default();
+            outcomes.configure_on_result(shell.clone(), context.config.run_policy.clone());
+
+            let output_dir = output_dir.map(PathBuf::from);
+            let run = if !argv.is_empty() {
+                // Arguments after `--` start the program directly, no shell involved
+                RunCommand::new_exec(argv, no_save, output_dir)
+            } else if let Some(name) = named {
+                RunCommand::new_named(name, command, no_save, output_dir)
+            } else if let Some(cmd) = command {
+                RunCommand::new_command(cmd, no_save, output_dir)
             } else if let Some(recipe_name) = recipe {
-                RunCommand::new_recipe(recipe_name, no_save, output_dir.map(PathBuf::from))
-                    .execute(&context)
-                    .await?;
-            }
+                RunCommand::new_recipe(recipe_name, no_save, output_dir)
+                    .with_recipe_args(recipe_args)
+            } else {
+                // validate_run_args guarantees a command or recipe
+                return Ok(());
+            };
+            run.with_timeout(timeout)
+                .with_timings(timings)
+                .with_allowed_exit_codes(allow_exit_codes)
+                .with_ordered_output(ordered_output)
+                .with_show_active(show_active)
+                .with_retry(retry)
+                .with_archived_skipped(archived_skipped)
+                .with_stdin(input)
+                .with_output_template(output_template)
+                .with_summary_format(summary_format)
+                .with_container(container)
+                .with_logs_by_tag(logs_by_tag)
+                .with_outcome_hooks(OutcomeHooks {
+                    on_success,
+                    on_failure,
+                    shell: shell.clone(),
+                    policy: context.config.run_policy.clone(),
+                })
+                .with_shell(shell)
+                .with_output_match(output_match)
+                .execute(&context)
+                .await?;
+        }
+        Commands::Exec {
+            argv,
+            config,
+            tag,
+            exclude_tag,
+            parallel,
+            no_save,
+            output_dir,
+            timeout,
+            include_archived,
+        } => {
+            let mut config = load_config(&config, selection).await?;
+            let archived_skipped = if include_archived {
+                0
+            } else {
+                skip_archived(&mut config, &tag, &exclude_tag, &[])
+            };
+            skip_unreadable(&mut config, &tag, &exclude_tag, &[]);
+            narrow_selected(&mut config, &tag, &exclude_tag, &[], selection)?;
+            let timeout = timeout.as_deref().map(parse_duration).transpose()?;
+
+            validators::validate_tag_filters(&tag)?;
+            validators::validate_tag_filters(&exclude_tag)?;
+            validators::validate_output_directory(&output_dir)?;
+
+            let context = CommandContext {
+                config,
+                tag,
+                exclude_tag,
+                parallel,
+                repos: None,
+                outcomes: outcomes.clone(),
+                jobs: limits.for_run(),
+            };
+
+            // No --shell here, but --on-result still goes through the config's shell
+            let shell = context
+                .config
+                .defaults
+                .shell
+                .as_deref()
+                .map(repos::runner::Shell::new)
+                .unwrap_or_default();
+            outcomes.configure_on_result(shell, context.config.run_policy.clone());
+
+            RunCommand::new_exec(argv, no_save, output_dir.map(PathBuf::from))
+                .with_timeout(timeout)
+                .with_archived_skipped(archived_skipped)
+                .execute(&context)
+                .await?;
         }
         Commands::Pr {
             repos,
@@ -408,15 +2591,70 @@ async fn execute_builtin_command(command: Commands) -> Result<()> {
             draft,
             token,
             create_only,
+            commit_all,
+            author_name,
+            author_email,
             config,
             tag,
             exclude_tag,
             parallel,
+            ssh_key,
+            include_archived,
+            api_interval,
+            check_protection,
+            changed_files,
+            github_api_url,
+            head_owner,
+            base_owner,
+            max_pr_failures,
         } => {
-            let config = Config::load_config(&config)?;
-
-            // Validate PR command arguments using centralized validators
-            validators::validate_pr_args(&token)?;
+            let api_interval = parse_duration(&api_interval)
+                .with_context(|| format!("Invalid --api-interval '{}'", api_interval))?;
+            for (option, owner) in [("--head-owner", &head_owner), ("--base-owner", &base_owner)] {
+                if let Some(owner) = owner
+                    && (owner.is_empty() || owner.contains('/'))
+                {
+                    anyhow::bail!(
+                        "Invalid {} '{}': expected an owner such as acme, not a repository",
+                        option,
+                        owner
+                    );
+                }
+            }
+            let changed_files = changed_files
+                .iter()
+                .map(|glob| {
+                    glob::Pattern::new(glob)
+                        .with_context(|| format!("Invalid --changed-files pattern '{}'", glob))
+                })
+                .collect::<Result<Vec<_>>>()?;
+            let mut config = load_config(&config, selection).await?;
+            let archived_skipped = if include_archived {
+                0
+            } else {
+                skip_archived(&mut config, &tag, &exclude_tag, &repos)
+            };
+            skip_unreadable(&mut config, &tag, &exclude_tag, &repos);
+            if let Some(ssh_key) = &ssh_key {
+                config.apply_default_ssh_key(ssh_key);
+            }
+            narrow_selected(&mut config, &tag, &exclude_tag, &repos, selection)?;
+
+            // Validate PR command arguments using centralized validators; a
+            // provider's global token is only needed for its repositories
+            // without a token_env
+            let names = (!repos.is_empty()).then_some(repos.as_slice());
+            let selected = config.filter_repositories(&tag, &exclude_tag, names);
+            let needs_token = |provider: Provider| {
+                selected
+                    .iter()
+                    .any(|repo| repo.token_env.is_none() && repo.provider() == provider)
+            };
+            let needs_github_token = needs_token(Provider::GitHub);
+            let needs_bitbucket_token = needs_token(Provider::Bitbucket);
+            if needs_github_token || needs_bitbucket_token {
+                validators::validate_pr_args(&token)?;
+            }
             validators::validate_tag_filters(&tag)?;
             validators::validate_tag_filters(&exclude_tag)?;
             validators::validate_repository_names(&repos)?;
@@ -430,10 +2668,37 @@ async fn execute_builtin_command(command: Commands) -> Result<()> {
                 exclude_tag,
                 parallel,
                 repos: if repos.is_empty() { None } else { Some(repos) },
+                outcomes: outcomes.clone(),
+                jobs: limits.jobs,
             };
 
-            let token = token.or_else(|| env::var("GITHUB_TOKEN").ok())
-                .ok_or_else(|| anyhow::anyhow!("GitHub token not provided. Use --token flag or set GITHUB_TOKEN environment variable."))?;
+            // An explicit --token serves both providers; each environment
+            // variable only ever reaches its own provider
+            let bitbucket_token = token.clone().or_else(|| env::var("BITBUCKET_TOKEN").ok());
+            let token = token.or_else(|| env::var("GITHUB_TOKEN").ok());
+            let github_api_url = github_api_url
+                .or_else(|| env::var("GITHUB_API_URL").ok())
+                .filter(|url| !url.is_empty())
+                .or_else(|| context.config.defaults.github_api_url.clone());
+            let author = repos::git::CommitIdentity {
+                name: author_name.or_else(|| context.config.defaults.author_name.clone()),
+                email: author_email.or_else(|| context.config.defaults.author_email.clone()),
+            };
+            let token = match token {
+                Some(token) => token,
+                // Every selected GitHub repository reads its own token_env
+                None if !needs_github_token => String::new(),
+                None => anyhow::bail!(
+                    "Token not provided. Use --token flag or set GITHUB_TOKEN environment variable."
+                ),
+            };
+            let bitbucket_token = match bitbucket_token {
+                Some(token) => token,
+                None if !needs_bitbucket_token => String::new(),
+                None => anyhow::bail!(
+                    "Bitbucket token not provided. Use --token flag or set BITBUCKET_TOKEN environment variable."
+                ),
+            };
 
             PrCommand {
                 title,
@@ -443,7 +2708,20 @@ async fn execute_builtin_command(command: Commands) -> Result<()> {
                 commit_msg: message,
                 draft,
                 token,
+                bitbucket_token,
                 create_only,
+                api_interval,
+                check_protection,
+                changed_files,
+                lock: Some(selection.lock),
+                github_api_url,
+                github_enterprise_hosts: context.config.defaults.github_enterprise_hosts.clone(),
+                head_owner,
+                base_owner,
+                max_failures: max_pr_failures,
+                commit_all,
+                author,
+                archived_skipped,
             }
             .execute(&context)
             .await?;
@@ -455,7 +2733,8 @@ async fn execute_builtin_command(command: Commands) -> Result<()> {
             exclude_tag,
             parallel,
         } => {
-            let config = Config::load_config(&config)?;
+            let mut config = load_config(&config, selection).await?;
+            narrow_selected(&mut config, &tag, &exclude_tag, &repos, selection)?;
 
             // Validate remove command arguments using centralized validators
             validators::validate_tag_filters(&tag)?;
@@ -468,9 +2747,90 @@ async fn execute_builtin_command(command: Commands) -> Result<()> {
                 exclude_tag,
                 parallel,
                 repos: if repos.is_empty() { None } else { Some(repos) },
+                outcomes: outcomes.clone(),
+                jobs: None,
             };
             RemoveCommand.execute(&context).await?;
         }
+        Commands::GitConfig {
+            repos,
+            config,
+            tag,
+            exclude_tag,
+        } => {
+            let mut config = load_config(&config, selection).await?;
+            skip_unreadable(&mut config, &tag, &exclude_tag, &repos);
+            narrow_selected(&mut config, &tag, &exclude_tag, &repos, selection)?;
+
+            validators::validate_tag_filters(&tag)?;
+            validators::validate_tag_filters(&exclude_tag)?;
+            validators::validate_repository_names(&repos)?;
+
+            let context = CommandContext {
+                config,
+                tag,
+                exclude_tag,
+                parallel: false,
+                repos: if repos.is_empty() { None } else { Some(repos) },
+                outcomes: outcomes.clone(),
+                jobs: None,
+            };
+            GitConfigCommand.execute(&context).await?;
+        }
+        Commands::Status {
+            repos,
+            config,
+            tag,
+            exclude_tag,
+        } => {
+            let mut config = load_config(&config, selection).await?;
+            skip_unreadable(&mut config, &tag, &exclude_tag, &repos);
+            narrow_selected(&mut config, &tag, &exclude_tag, &repos, selection)?;
+
+            validators::validate_tag_filters(&tag)?;
+            validators::validate_tag_filters(&exclude_tag)?;
+            validators::validate_repository_names(&repos)?;
+
+            let context = CommandContext {
+                config,
+                tag,
+                exclude_tag,
+                parallel: false,
+                repos: if repos.is_empty() { None } else { Some(repos) },
+                outcomes: outcomes.clone(),
+                jobs: None,
+            };
+            StatusCommand.execute(&context).await?;
+        }
+        Commands::PruneBranches {
+            repos,
+            config,
+            tag,
+            exclude_tag,
+            dry_run,
+            remote,
+        } => {
+            let mut config = load_config(&config, selection).await?;
+            skip_unreadable(&mut config, &tag, &exclude_tag, &repos);
+            narrow_selected(&mut config, &tag, &exclude_tag, &repos, selection)?;
+
+            validators::validate_tag_filters(&tag)?;
+            validators::validate_tag_filters(&exclude_tag)?;
+            validators::validate_repository_names(&repos)?;
+
+            let context = CommandContext {
+                config,
+                tag,
+                exclude_tag,
+                parallel: false,
+                repos: if repos.is_empty() { None } else { Some(repos) },
+                outcomes: outcomes.clone(),
+                jobs: None,
+            };
+            PruneBranchesCommand { dry_run, remote }
+                .execute(&context)
+                .await?;
+        }
         Commands::Ls {
             repos,
             config,
@@ -478,7 +2838,8 @@ async fn execute_builtin_command(command: Commands) -> Result<()> {
             exclude_tag,
             json,
         } => {
-            let config = Config::load_config(&config)?;
+            let mut config = load_config(&config, selection).await?;
+            narrow_selected(&mut config, &tag, &exclude_tag, &repos, selection)?;
 
             // Validate list command arguments using centralized validators
             validators::validate_tag_filters(&tag)?;
@@ -491,13 +2852,67 @@ async fn execute_builtin_command(command: Commands) -> Result<()> {
                 exclude_tag,
                 parallel: false, // List command doesn't need parallel execution
                 repos: if repos.is_empty() { None } else { Some(repos) },
+                outcomes: outcomes.clone(),
+                jobs: None,
             };
             ListCommand { json }.execute(&context).await?;
         }
+        Commands::Count {
+            repos,
+            config,
+            tag,
+            exclude_tag,
+            list,
+        } => {
+            let mut config = load_config(&config, selection).await?;
+            narrow_selected(&mut config, &tag, &exclude_tag, &repos, selection)?;
+
+            validators::validate_tag_filters(&tag)?;
+            validators::validate_tag_filters(&exclude_tag)?;
+            validators::validate_repository_names(&repos)?;
+
+            let context = CommandContext {
+                config,
+                tag,
+                exclude_tag,
+                parallel: false,
+                repos: if repos.is_empty() { None } else { Some(repos) },
+                outcomes: outcomes.clone(),
+                jobs: None,
+            };
+            CountCommand { list }.execute(&context).await?;
+        }
+        Commands::Info {
+            repos,
+            config,
+            tag,
+            exclude_tag,
+            output,
+        } => {
+            let format = output.parse::<InfoFormat>()?;
+            let mut config = load_config(&config, selection).await?;
+            narrow_selected(&mut config, &tag, &exclude_tag, &repos, selection)?;
+
+            validators::validate_tag_filters(&tag)?;
+            validators::validate_tag_filters(&exclude_tag)?;
+            validators::validate_repository_names(&repos)?;
+
+            let context = CommandContext {
+                config,
+                tag,
+                exclude_tag,
+                parallel: false,
+                repos: if repos.is_empty() { None } else { Some(repos) },
+                outcomes: outcomes.clone(),
+                jobs: None,
+            };
+            InfoCommand { format }.execute(&context).await?;
+        }
         Commands::Init {
             output,
             overwrite,
             supplement,
+            tag_depth,
         } => {
             // Init command doesn't need config since it creates one
             let context = CommandContext {
@@ -506,15 +2921,34 @@ async fn execute_builtin_command(command: Commands) -> Result<()> {
                 exclude_tag: Vec::new(),
                 parallel: false,
                 repos: None,
+                outcomes: outcomes.clone(),
+                jobs: None,
             };
             InitCommand {
                 output,
                 overwrite,
                 supplement,
+                tag_depth,
+                config_format: selection.config_format,
             }
             .execute(&context)
             .await?;
         }
+        Commands::Config {
+            action: ConfigAction::Migrate { config },
+        } => {
+            // Migrate works on the raw file, so the config is not loaded here
+            let context = CommandContext {
+                config: Config::new(),
+                tag: Vec::new(),
+                exclude_tag: Vec::new(),
+                parallel: false,
+                repos: None,
+                outcomes: outcomes.clone(),
+                jobs: None,
+            };
+            MigrateCommand { config }.execute(&context).await?;
+        }
         Commands::Completions { .. } => {
             // Handled in main(), this should not be reached
             unreachable!("Completions command should be handled in main()")
@@ -85,6 +85,9 @@ pub fn try_external_plugin(plugin_name: &str, context: &PluginContext) -> Result
             context.repositories.len().to_string(),
         );
 
+    // --proxy / --no-proxy apply to the plugin's own git and HTTP requests too
+    cmd.envs(crate::git::proxy_env(&repos_github::proxy()));
+
     // Set config file path if available
     if let Some(config_path) = &context.config_path {
         cmd.env("REPOS_CONFIG_FILE", config_path);
@@ -1,14 +1,24 @@
 //! Command execution runner for managing operations across multiple repositories
 
 use crate::config::Repository;
-use crate::git::Logger;
-use crate::utils::get_exit_code_description;
-use anyhow::Result;
+use crate::git::{Logger, TraceCommand};
+use crate::utils::sanitizers::sanitize_for_filename;
+use crate::utils::{OutputBuffer, format_duration, get_exit_code_description};
+use anyhow::{Context, Result};
+use colored::Colorize;
+use regex::Regex;
 use serde_json;
 
-use std::io::{BufRead, BufReader};
-use std::path::Path;
+use std::ffi::OsStr;
+use std::fmt;
+use std::io::{BufRead, BufReader, Write};
+use std::path::{Path, PathBuf};
 use std::process::{Command, Stdio};
+use std::str::FromStr;
+use std::sync::atomic::{AtomicBool, Ordering};
+use std::sync::mpsc;
+use std::sync::{Arc, Mutex};
+use std::time::{Duration, Instant};
 
 #[derive(Debug, Clone)]
 struct RecipeContext {
@@ -19,6 +29,433 @@ struct RecipeContext {
 #[derive(Default)]
 pub struct CommandRunner {
     logger: Logger,
+    timeout: Option<Duration>,
+    allowed_exit_codes: Vec<i32>,
+    /// Progress lines held back for `--ordered-output` instead of printed
+    output: Option<Mutex<OutputBuffer>>,
+    /// Bytes written to every command's stdin (`--stdin`, `--stdin-file`)
+    stdin: Option<Arc<[u8]>>,
+    /// Where each repository's captured stdout is written (`--output-template`)
+    output_template: Option<OutputTemplate>,
+    /// Image the commands run in instead of the host (`--container`)
+    container: Option<Container>,
+    /// Group log directories under each repository's first tag (`--logs-by-tag`)
+    logs_by_tag: bool,
+    /// Shell each command line is passed to (`--shell`, `defaults.shell`)
+    shell: Shell,
+    /// Program and arguments started in place of the shell (`repos exec`)
+    argv: Option<Vec<String>>,
+    /// Run a failing command again (`--command-retries`)
+    retry: RetryPolicy,
+    /// Variables added to every command's environment, such as recipe `ARG_*` values
+    env: Vec<(String, String)>,
+    /// End of the whole batch (`--deadline`): commands still running then are stopped
+    deadline: Option<Instant>,
+    /// Patterns judging captured output instead of the exit code (`--success-if`, `--failure-if`)
+    output_match: OutputMatch,
+}
+
+/// A command stopped because the `--deadline` of the whole batch passed
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub struct DeadlineExceeded;
+
+impl fmt::Display for DeadlineExceeded {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        write!(f, "Stopped at the --deadline")
+    }
+}
+
+impl std::error::Error for DeadlineExceeded {}
+
+/// When a command that exited with a failing code is run again
+#[derive(Debug, Clone, Default, PartialEq, Eq)]
+pub struct RetryPolicy {
+    /// Attempts after the first (`--command-retries`)
+    pub retries: u32,
+    /// Wait before each retry (`--command-retry-delay`)
+    pub delay: Duration,
+    /// Only retry these exit codes (`--command-retry-on`); empty retries every failing code
+    pub exit_codes: Vec<i32>,
+}
+
+impl RetryPolicy {
+    /// Whether to try again after `attempt` (counting from 1) ended with `exit_code`
+    ///
+    /// Only attempts that `failed`, as judged by [`CommandRunner::failure`],
+    /// are retried.
+    pub fn should_retry(&self, attempt: u32, exit_code: i32, failed: bool) -> bool {
+        attempt <= self.retries
+            && failed
+            && (self.exit_codes.is_empty() || self.exit_codes.contains(&exit_code))
+    }
+}
+
+/// Output patterns that decide a command's result instead of its exit code
+///
+/// A command whose stdout or stderr matches `failure_if` fails. Otherwise,
+/// when `success_if` is set, it succeeds only if its output matches. With
+/// either pattern set the exit code is not consulted, though a command that
+/// timed out still fails.
+#[derive(Debug, Clone, Default)]
+pub struct OutputMatch {
+    /// `--success-if`
+    pub success_if: Option<Regex>,
+    /// `--failure-if`
+    pub failure_if: Option<Regex>,
+}
+
+impl OutputMatch {
+    /// Compile the `--success-if` and `--failure-if` patterns
+    ///
+    /// # Errors
+    /// Returns an error naming the option whose pattern is not a valid regex
+    pub fn new(success_if: Option<&str>, failure_if: Option<&str>) -> Result<Self> {
+        let compile = |pattern: Option<&str>, option: &str| {
+            pattern
+                .map(|pattern| {
+                    Regex::new(pattern)
+                        .with_context(|| format!("Invalid {} pattern '{}'", option, pattern))
+                })
+                .transpose()
+        };
+        Ok(Self {
+            success_if: compile(success_if, "--success-if")?,
+            failure_if: compile(failure_if, "--failure-if")?,
+        })
+    }
+
+    /// Whether no pattern is set, leaving the result to the exit code
+    pub fn is_empty(&self) -> bool {
+        self.success_if.is_none() && self.failure_if.is_none()
+    }
+
+    /// Why the output makes the command fail, or `None` when it succeeds
+    pub fn failure(&self, stdout: &str, stderr: &str) -> Option<String> {
+        let matches = |pattern: &Regex| pattern.is_match(stdout) || pattern.is_match(stderr);
+        if let Some(pattern) = self.failure_if.as_ref().filter(|p| matches(p)) {
+            return Some(format!(
+                "Output matched --failure-if '{}'",
+                pattern.as_str()
+            ));
+        }
+        match &self.success_if {
+            Some(pattern) if !matches(pattern) => Some(format!(
+                "Output did not match --success-if '{}'",
+                pattern.as_str()
+            )),
+            _ => None,
+        }
+    }
+}
+
+/// Output of one run of a command
+struct Attempt {
+    stdout: String,
+    stderr: String,
+    exit_code: i32,
+    timed_out: bool,
+}
+
+/// Log group for repositories without tags under `--logs-by-tag`
+pub const UNTAGGED_LOG_DIR: &str = "untagged";
+
+/// Directory receiving a repository's `metadata.json`, `stdout.log` and `stderr.log`
+///
+/// `<log_dir>/<name>`, or with `by_tag` `<log_dir>/<first tag>/<name>`, using
+/// [`UNTAGGED_LOG_DIR`] for repositories without tags.
+pub fn repo_log_dir(log_dir: &Path, repo: &Repository, by_tag: bool) -> PathBuf {
+    if !by_tag {
+        return log_dir.join(&repo.name);
+    }
+    let group = repo
+        .tags
+        .first()
+        .map(|tag| sanitize_for_filename(tag))
+        .unwrap_or_else(|| UNTAGGED_LOG_DIR.to_string());
+    log_dir.join(group).join(&repo.name)
+}
+
+/// A shell command line that runs `argv` exactly as given
+///
+/// An argv run directly (`repos run -- <args>...`, `repos exec`) is logged,
+/// checked against the `run_policy` and passed to a `--container` shell as
+/// this line. The quoting is POSIX, so on the host the argv is always started
+/// directly and never handed to a `--shell` such as `cmd`. Each argument is
+/// single-quoted unless it is made only of characters the shell leaves alone,
+/// so a POSIX shell hands the program the same arguments, spaces, quotes and
+/// `$` included, instead of splitting or expanding them.
+pub fn command_line(argv: &[String]) -> String {
+    argv.iter()
+        .map(|arg| {
+            if !arg.is_empty()
+                && arg
+                    .chars()
+                    .all(|c| c.is_ascii_alphanumeric() || "/._-,:@+%".contains(c))
+            {
+                arg.clone()
+            } else {
+                format!("'{}'", arg.replace('\'', "'\\''"))
+            }
+        })
+        .collect::<Vec<_>>()
+        .join(" ")
+}
+
+/// Shells tried, in order, when neither `--shell` nor `defaults.shell` names
+/// one: PowerShell 7 when it is installed, else `cmd`, which every Windows has
+#[cfg(windows)]
+pub const DEFAULT_SHELLS: &[&str] = &["pwsh", "cmd"];
+/// Shells tried, in order, when neither `--shell` nor `defaults.shell` names one
+#[cfg(not(windows))]
+pub const DEFAULT_SHELLS: &[&str] = &["sh"];
+
+/// The shell command lines run through (`--shell`, `defaults.shell`)
+///
+/// The command line is passed the way the shell expects it, going by the
+/// program's name: `cmd /C <command>`, `pwsh -NoProfile -Command <command>`
+/// (also for `powershell`), and `<shell> -c <command>` for every other shell,
+/// such as `bash` or `/usr/local/bin/zsh`.
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct Shell {
+    /// Name on `PATH` or path of the shell's program
+    pub program: String,
+}
+
+impl Shell {
+    pub fn new(program: &str) -> Self {
+        Self {
+            program: program.to_string(),
+        }
+    }
+
+    /// The first of `candidates` found in `search_path`, else the last one
+    pub fn detect(candidates: &[&str], search_path: Option<&OsStr>) -> Self {
+        let found = candidates.iter().find(|program| {
+            let file = format!("{}{}", program, std::env::consts::EXE_SUFFIX);
+            find_program(&file, search_path).is_some()
+        });
+        Self::new(found.or(candidates.last()).copied().unwrap_or("sh"))
+    }
+
+    /// Arguments that make the shell run `command`
+    pub fn args(&self, command: &str) -> Vec<String> {
+        let name = Path::new(&self.program)
+            .file_stem()
+            .map(|stem| stem.to_string_lossy().to_ascii_lowercase())
+            .unwrap_or_default();
+        let flags: &[&str] = match name.as_str() {
+            "cmd" => &["/C"],
+            "powershell" | "pwsh" => &["-NoProfile", "-Command"],
+            _ => &["-c"],
+        };
+        flags
+            .iter()
+            .map(|flag| flag.to_string())
+            .chain([command.to_string()])
+            .collect()
+    }
+}
+
+impl Default for Shell {
+    /// The first of [`DEFAULT_SHELLS`] on `PATH`
+    fn default() -> Self {
+        Self::detect(DEFAULT_SHELLS, std::env::var_os("PATH").as_deref())
+    }
+}
+
+/// Runtimes tried, in order, when `--container-runtime` is not given
+pub const CONTAINER_RUNTIMES: &[&str] = &["docker", "podman"];
+
+/// Directory the repository is mounted at inside the container
+const CONTAINER_WORKDIR: &str = "/workspace";
+
+/// Runs each command inside a container image, with the repository mounted
+///
+/// The command is started as `<runtime> run --rm -v <repo>:/workspace -w
+/// /workspace <image> sh -c <command>`, so a command behaves as it would on
+/// the host but sees only the repository and the image's tools.
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct Container {
+    /// Program that runs the container: `docker`, `podman` or a path to either
+    pub runtime: String,
+    pub image: String,
+}
+
+impl Container {
+    /// Use `runtime` if given, else the first of [`CONTAINER_RUNTIMES`] on `PATH`
+    ///
+    /// # Errors
+    /// Returns an error when the chosen runtime, or any runtime at all, is not installed
+    pub fn new(image: &str, runtime: Option<&str>) -> Result<Self> {
+        Self::with_search_path(image, runtime, std::env::var_os("PATH").as_deref())
+    }
+
+    /// Like [`new`](Self::new), looking for runtimes in `search_path` instead of `PATH`
+    pub fn with_search_path(
+        image: &str,
+        runtime: Option<&str>,
+        search_path: Option<&OsStr>,
+    ) -> Result<Self> {
+        let runtime = match runtime {
+            Some(runtime) => {
+                if find_program(runtime, search_path).is_none() {
+                    anyhow::bail!("Container runtime '{}' not found", runtime);
+                }
+                runtime.to_string()
+            }
+            None => CONTAINER_RUNTIMES
+                .iter()
+                .find(|runtime| find_program(runtime, search_path).is_some())
+                .ok_or_else(|| {
+                    anyhow::anyhow!(
+                        "No container runtime found for --container: install {} or pass --container-runtime",
+                        CONTAINER_RUNTIMES.join(" or ")
+                    )
+                })?
+                .to_string(),
+        };
+        Ok(Self {
+            runtime,
+            image: image.to_string(),
+        })
+    }
+
+    /// Arguments for the runtime that run `command` with `repo_dir` mounted
+    ///
+    /// `interactive` keeps the container's stdin open for `--stdin` input.
+    /// The variables in `env` are passed on by name; their values come from
+    /// the runtime's own environment.
+    pub fn args(
+        &self,
+        command: &str,
+        repo_dir: &Path,
+        interactive: bool,
+        env: &[(String, String)],
+    ) -> Vec<String> {
+        let mut args = vec!["run".to_string(), "--rm".to_string()];
+        if interactive {
+            args.push("-i".to_string());
+        }
+        for (name, _) in env {
+            args.extend(["-e".to_string(), name.clone()]);
+        }
+        args.extend([
+            "-v".to_string(),
+            format!("{}:{}", repo_dir.display(), CONTAINER_WORKDIR),
+            "-w".to_string(),
+            CONTAINER_WORKDIR.to_string(),
+            self.image.clone(),
+            "sh".to_string(),
+            "-c".to_string(),
+            command.to_string(),
+        ]);
+        args
+    }
+}
+
+/// Locate `program` in `search_path`, or check it exists when given as a path
+fn find_program(program: &str, search_path: Option<&OsStr>) -> Option<PathBuf> {
+    if program.contains(std::path::MAIN_SEPARATOR) {
+        let path = PathBuf::from(program);
+        return path.is_file().then_some(path);
+    }
+    std::env::split_paths(search_path?)
+        .map(|dir| dir.join(program))
+        .find(|candidate| candidate.is_file())
+}
+
+/// Path of a file receiving one repository's stdout, e.g. `out/{{.Name}}.txt`
+///
+/// `{{.Name}}` (spaces inside the braces are allowed) expands to the
+/// repository name. Parsing rejects other fields and templates without a
+/// name, which would send every repository to the same file.
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct OutputTemplate {
+    parts: Vec<TemplatePart>,
+}
+
+#[derive(Debug, Clone, PartialEq, Eq)]
+enum TemplatePart {
+    Text(String),
+    Name,
+}
+
+impl OutputTemplate {
+    /// The output path for a repository
+    pub fn render(&self, repo: &Repository) -> PathBuf {
+        let path: String = self
+            .parts
+            .iter()
+            .map(|part| match part {
+                TemplatePart::Text(text) => text.as_str(),
+                TemplatePart::Name => repo.name.as_str(),
+            })
+            .collect();
+        PathBuf::from(path)
+    }
+
+    /// Write `stdout` to the repository's path, creating parent directories
+    fn write(&self, repo: &Repository, stdout: &str) -> Result<()> {
+        let path = self.render(repo);
+        if let Some(parent) = path.parent()
+            && !parent.as_os_str().is_empty()
+        {
+            std::fs::create_dir_all(parent).with_context(|| {
+                format!("Failed to create output directory {}", parent.display())
+            })?;
+        }
+        std::fs::write(&path, stdout)
+            .with_context(|| format!("Failed to write output to {}", path.display()))
+    }
+}
+
+impl FromStr for OutputTemplate {
+    type Err = anyhow::Error;
+
+    fn from_str(template: &str) -> Result<Self> {
+        let mut parts = Vec::new();
+        let mut rest = template;
+        while let Some(start) = rest.find("{{") {
+            if start > 0 {
+                parts.push(TemplatePart::Text(rest[..start].to_string()));
+            }
+            let Some(end) = rest[start..].find("}}") else {
+                anyhow::bail!("Unclosed '{{{{' in output template '{}'", template);
+            };
+            match rest[start + 2..start + end].trim() {
+                ".Name" => parts.push(TemplatePart::Name),
+                field => anyhow::bail!(
+                    "Unknown field '{}' in output template '{}' (supported: .Name)",
+                    field,
+                    template
+                ),
+            }
+            rest = &rest[start + end + 2..];
+        }
+        if !rest.is_empty() {
+            parts.push(TemplatePart::Text(rest.to_string()));
+        }
+
+        if !parts.contains(&TemplatePart::Name) {
+            anyhow::bail!(
+                "Output template '{}' must contain {{{{.Name}}}} so each repository gets its own file",
+                template
+            );
+        }
+        Ok(Self { parts })
+    }
+}
+
+/// Whether `exit_code` counts as success
+///
+/// Only `0` succeeds when `allowed` is empty; otherwise exactly the listed
+/// codes do.
+pub fn exit_code_allowed(exit_code: i32, allowed: &[i32]) -> bool {
+    if allowed.is_empty() {
+        exit_code == 0
+    } else {
+        allowed.contains(&exit_code)
+    }
 }
 
 impl CommandRunner {
@@ -26,6 +463,238 @@ impl CommandRunner {
         Self::default()
     }
 
+    /// Kill commands that are still running after `timeout`
+    pub fn with_timeout(mut self, timeout: Option<Duration>) -> Self {
+        self.timeout = timeout;
+        self
+    }
+
+    /// Treat these exit codes as success instead of only `0`
+    pub fn with_allowed_exit_codes(mut self, codes: &[i32]) -> Self {
+        self.allowed_exit_codes = codes.to_vec();
+        self
+    }
+
+    /// Buffer progress lines instead of printing them as they happen
+    pub fn with_buffered_output(mut self) -> Self {
+        self.output = Some(Mutex::new(OutputBuffer::new()));
+        self
+    }
+
+    /// Feed `input` to the stdin of every command instead of inheriting it
+    pub fn with_stdin(mut self, input: Option<Arc<[u8]>>) -> Self {
+        self.stdin = input;
+        self
+    }
+
+    /// Write each captured stdout to the path `template` renders for the repository
+    pub fn with_output_template(mut self, template: Option<OutputTemplate>) -> Self {
+        self.output_template = template;
+        self
+    }
+
+    /// Run every command inside `container` instead of on the host
+    pub fn with_container(mut self, container: Option<Container>) -> Self {
+        self.container = container;
+        self
+    }
+
+    /// Pass each command line to `shell` instead of one of the [`DEFAULT_SHELLS`]
+    ///
+    /// Commands in a container still run with the image's `sh`.
+    pub fn with_shell(mut self, shell: Shell) -> Self {
+        self.shell = shell;
+        self
+    }
+
+    /// Start `argv` directly instead of passing the command to the shell
+    ///
+    /// The command string is still what gets logged and recorded.
+    pub fn with_argv(mut self, argv: Option<Vec<String>>) -> Self {
+        self.argv = argv;
+        self
+    }
+
+    /// Write each repository's logs under a directory named after its first tag
+    pub fn with_logs_by_tag(mut self, by_tag: bool) -> Self {
+        self.logs_by_tag = by_tag;
+        self
+    }
+
+    /// Run commands that fail again as `retry` allows
+    pub fn with_retry(mut self, retry: RetryPolicy) -> Self {
+        self.retry = retry;
+        self
+    }
+
+    /// Stop commands still running at `deadline`, whatever their timeout
+    pub fn with_deadline(mut self, deadline: Option<Instant>) -> Self {
+        self.deadline = deadline;
+        self
+    }
+
+    /// Add `env` to the environment of every command, also inside a container
+    pub fn with_env(mut self, env: Vec<(String, String)>) -> Self {
+        self.env = env;
+        self
+    }
+
+    /// Decide commands' results, and whether to retry them, by `output_match`
+    /// instead of their exit code
+    pub fn with_output_match(mut self, output_match: OutputMatch) -> Self {
+        self.output_match = output_match;
+        self
+    }
+
+    /// Why a command that ran to completion counts as failed, if it does
+    ///
+    /// Judged by the output patterns when any is set, and by the allowed exit
+    /// codes otherwise.
+    pub fn failure(&self, stdout: &str, stderr: &str, exit_code: i32) -> Option<String> {
+        if !self.output_match.is_empty() {
+            self.output_match.failure(stdout, stderr)
+        } else if exit_code_allowed(exit_code, &self.allowed_exit_codes) {
+            None
+        } else {
+            Some(format!("Command failed with exit code: {exit_code}"))
+        }
+    }
+
+    /// The lines buffered since `with_buffered_output`, if buffering
+    pub fn into_output(self) -> Option<OutputBuffer> {
+        self.output
+            .map(|output| output.into_inner().unwrap_or_else(|e| e.into_inner()))
+    }
+
+    fn info(&self, repo: &Repository, msg: &str) {
+        match &self.output {
+            Some(output) => output
+                .lock()
+                .unwrap_or_else(|e| e.into_inner())
+                .out_line(&self.logger.line(repo, msg)),
+            None => self.logger.info(repo, msg),
+        }
+    }
+
+    fn error(&self, repo: &Repository, msg: &str) {
+        match &self.output {
+            Some(output) => output
+                .lock()
+                .unwrap_or_else(|e| e.into_inner())
+                .err_line(&self.logger.line(repo, &msg.red().to_string())),
+            None => self.logger.error(repo, msg),
+        }
+    }
+
+    /// Start `command` with the shell in the repository, guarded by the timeout if one is set
+    ///
+    /// With a container the shell runs inside it, started by the container runtime.
+    /// With an argv (see [`with_argv`](Self::with_argv)) its program is started
+    /// directly and no shell is involved.
+    fn spawn_shell(
+        &self,
+        repo: &Repository,
+        command: &str,
+        repo_dir: &str,
+        capture: bool,
+    ) -> Result<(std::process::Child, Option<Watchdog>)> {
+        let mut shell = match &self.container {
+            Some(container) => {
+                // Bind mounts need an absolute source path
+                let mount = std::path::absolute(repo_dir)?;
+                let mut runtime = Command::new(&container.runtime);
+                runtime.args(container.args(command, &mount, self.stdin.is_some(), &self.env));
+                runtime
+            }
+            None => match self.argv.as_deref() {
+                Some([program, args @ ..]) => {
+                    let mut direct = Command::new(program);
+                    direct.args(args);
+                    direct
+                }
+                _ => {
+                    let mut shell = Command::new(&self.shell.program);
+                    shell.args(self.shell.args(command));
+                    shell
+                }
+            },
+        };
+        shell.current_dir(repo_dir);
+        shell.envs(self.env.iter().map(|(name, value)| (name, value)));
+        if capture {
+            shell.stdout(Stdio::piped()).stderr(Stdio::piped());
+        }
+        if self.stdin.is_some() {
+            shell.stdin(Stdio::piped());
+        }
+
+        // Run in a process group of its own so a timeout also stops any
+        // processes the command started
+        let limit = self.time_limit();
+        #[cfg(unix)]
+        if limit.is_some() {
+            use std::os::unix::process::CommandExt;
+            shell.process_group(0);
+        }
+
+        let mut child = shell.traced(&repo.name).spawn()?;
+        if let (Some(input), Some(pipe)) = (&self.stdin, child.stdin.take()) {
+            feed_stdin(pipe, input.clone());
+        }
+        let watchdog = limit.map(|limit| Watchdog::start(child.id(), limit));
+        Ok((child, watchdog))
+    }
+
+    /// How long a command starting now may run: its timeout, cut short by the deadline
+    fn time_limit(&self) -> Option<Duration> {
+        let remaining = self
+            .deadline
+            .map(|deadline| deadline.saturating_duration_since(Instant::now()));
+        match (self.timeout, remaining) {
+            (Some(timeout), Some(remaining)) => Some(timeout.min(remaining)),
+            (timeout, remaining) => timeout.or(remaining),
+        }
+    }
+
+    fn deadline_passed(&self) -> bool {
+        self.deadline
+            .is_some_and(|deadline| Instant::now() >= deadline)
+    }
+
+    /// Log a failed attempt and wait out the retry delay
+    async fn before_retry(&self, repo: &Repository, attempt: u32, exit_code: i32) {
+        let reason = if self.output_match.is_empty() {
+            format!("with exit code {exit_code}")
+        } else {
+            "by its output".to_string()
+        };
+        let mut message = format!(
+            "Attempt {} of {} failed {}; retrying",
+            attempt,
+            self.retry.retries + 1,
+            reason
+        );
+        if !self.retry.delay.is_zero() {
+            message.push_str(&format!(" in {}", format_duration(self.retry.delay)));
+        }
+        self.info(repo, &message);
+        tokio::time::sleep(self.retry.delay).await;
+    }
+
+    /// Error for a command the watchdog stopped: at the deadline, or else its timeout
+    fn timeout_error(&self, repo: &Repository) -> anyhow::Error {
+        if self.deadline_passed() {
+            self.error(repo, &DeadlineExceeded.to_string());
+            return DeadlineExceeded.into();
+        }
+        let message = format!(
+            "Timed out after {}",
+            format_duration(self.timeout.unwrap_or_default())
+        );
+        self.error(repo, &message);
+        anyhow::anyhow!(message)
+    }
+
     /// Run command and capture output for the new logging system
     pub async fn run_command_with_capture(
         &self,
@@ -65,32 +734,14 @@ impl CommandRunner {
             .await
     }
 
-    /// Internal implementation that allows skipping log file creation
-    async fn run_command_with_capture_internal(
+    /// Run `command` once, capturing its output
+    async fn capture_attempt(
         &self,
         repo: &Repository,
         command: &str,
-        log_dir: Option<&str>,
-        skip_log_file: bool,
-        recipe_context: Option<RecipeContext>,
-    ) -> Result<(String, String, i32)> {
-        let repo_dir = repo.get_target_dir();
-
-        // Check if directory exists
-        if !Path::new(&repo_dir).exists() {
-            anyhow::bail!("Repository directory does not exist: {}", repo_dir);
-        }
-
-        self.logger.info(repo, &format!("Running '{command}'"));
-
-        // Execute command
-        let mut cmd = Command::new("sh")
-            .arg("-c")
-            .arg(command)
-            .current_dir(&repo_dir)
-            .stdout(Stdio::piped())
-            .stderr(Stdio::piped())
-            .spawn()?;
+        repo_dir: &str,
+    ) -> Result<Attempt> {
+        let (mut cmd, watchdog) = self.spawn_shell(repo, command, repo_dir, true)?;
 
         let stdout = cmd.stdout.take().unwrap();
         let stderr = cmd.stderr.take().unwrap();
@@ -126,30 +777,90 @@ impl CommandRunner {
 
         // Wait for output processing to complete and capture content
         let (stdout_result, stderr_result) = tokio::join!(stdout_handle, stderr_handle);
-        let stdout_content = stdout_result.unwrap_or_default();
-        let stderr_content = stderr_result.unwrap_or_default();
+        let stdout = stdout_result.unwrap_or_default();
+        let stderr = stderr_result.unwrap_or_default();
 
         // Wait for command to complete
         let status = cmd.wait()?;
         let exit_code = status.code().unwrap_or(-1);
+        let timed_out = watchdog.is_some_and(Watchdog::finish);
+        Ok(Attempt {
+            stdout,
+            stderr,
+            exit_code,
+            timed_out,
+        })
+    }
 
-        // Save output to files if log directory is provided and not skipping log files
-        if let Some(log_dir) = log_dir
-            && !skip_log_file
-        {
-            // Create repo-specific subdirectory
-            let repo_log_dir = Path::new(log_dir).join(&repo.name);
+    /// Internal implementation that allows skipping log file creation
+    async fn run_command_with_capture_internal(
+        &self,
+        repo: &Repository,
+        command: &str,
+        log_dir: Option<&str>,
+        skip_log_file: bool,
+        recipe_context: Option<RecipeContext>,
+    ) -> Result<(String, String, i32)> {
+        let repo_dir = repo.get_target_dir();
+
+        // Check if directory exists
+        if !Path::new(&repo_dir).exists() {
+            anyhow::bail!("Repository directory does not exist: {}", repo_dir);
+        }
+
+        self.info(repo, &format!("Running '{command}'"));
+
+        // Output is saved if log directory is provided and not skipping log files
+        let repo_log_dir = log_dir
+            .filter(|_| !skip_log_file)
+            .map(|log_dir| repo_log_dir(Path::new(log_dir), repo, self.logs_by_tag));
+
+        // Execute command, again while it fails and retries are left
+        let started = Instant::now();
+        let mut attempts = 1;
+        let Attempt {
+            stdout: stdout_content,
+            stderr: stderr_content,
+            exit_code,
+            timed_out,
+        } = loop {
+            let attempt = self.capture_attempt(repo, command, &repo_dir).await?;
+            let failed = self
+                .failure(&attempt.stdout, &attempt.stderr, attempt.exit_code)
+                .is_some();
+            if attempt.timed_out || !self.retry.should_retry(attempts, attempt.exit_code, failed) {
+                break attempt;
+            }
+            // Earlier attempts keep their output next to the final logs
+            if let Some(repo_log_dir) = &repo_log_dir {
+                std::fs::create_dir_all(repo_log_dir)?;
+                std::fs::write(
+                    repo_log_dir.join(format!("stdout.attempt-{}.log", attempts)),
+                    &attempt.stdout,
+                )?;
+                std::fs::write(
+                    repo_log_dir.join(format!("stderr.attempt-{}.log", attempts)),
+                    &attempt.stderr,
+                )?;
+            }
+            self.before_retry(repo, attempts, attempt.exit_code).await;
+            attempts += 1;
+        };
+        let duration_ms = started.elapsed().as_millis() as u64;
+
+        if let Some(repo_log_dir) = repo_log_dir {
             std::fs::create_dir_all(&repo_log_dir)?;
 
             // Always write metadata file with command and exit code in JSON format
             let exit_code_description = get_exit_code_description(exit_code);
-            let metadata_content = if let Some(ref recipe_ctx) = recipe_context {
+            let mut metadata_content = if let Some(ref recipe_ctx) = recipe_context {
                 serde_json::json!({
                     "recipe": recipe_ctx.name,
                     "exit_code": exit_code,
                     "exit_code_description": exit_code_description,
                     "repository": repo.name,
                     "timestamp": chrono::Local::now().format("%Y-%m-%d %H:%M:%S").to_string(),
+                    "duration_ms": duration_ms,
                     "recipe_steps": recipe_ctx.steps
                 })
             } else {
@@ -158,9 +869,20 @@ impl CommandRunner {
                     "exit_code": exit_code,
                     "exit_code_description": exit_code_description,
                     "repository": repo.name,
-                    "timestamp": chrono::Local::now().format("%Y-%m-%d %H:%M:%S").to_string()
+                    "timestamp": chrono::Local::now().format("%Y-%m-%d %H:%M:%S").to_string(),
+                    "duration_ms": duration_ms
                 })
             };
+            if attempts > 1 {
+                metadata_content["attempts"] = serde_json::json!(attempts);
+            }
+            if timed_out && self.deadline_passed() {
+                metadata_content["deadline_exceeded"] = serde_json::json!(true);
+            } else if timed_out {
+                metadata_content["timed_out"] = serde_json::json!(true);
+                metadata_content["timeout"] =
+                    serde_json::json!(format_duration(self.timeout.unwrap_or_default()));
+            }
             let metadata_file = repo_log_dir.join("metadata.json");
             std::fs::write(
                 &metadata_file,
@@ -176,10 +898,19 @@ impl CommandRunner {
             std::fs::write(&stderr_file, &stderr_content)?;
         }
 
+        // Separate from the logs: stdout only, at a path of the user's choosing
+        if let Some(template) = &self.output_template {
+            template.write(repo, &stdout_content)?;
+        }
+
+        if timed_out {
+            return Err(self.timeout_error(repo));
+        }
+
         // Log completion with exit code and description
         let exit_code_description = get_exit_code_description(exit_code);
         if let Some(ref recipe_ctx) = recipe_context {
-            self.logger.info(
+            self.info(
                 repo,
                 &format!(
                     "Recipe '{}' ended with exit code {} ({})",
@@ -187,7 +918,7 @@ impl CommandRunner {
                 ),
             );
         } else {
-            self.logger.info(
+            self.info(
                 repo,
                 &format!(
                     "Command '{}' ended with exit code {} ({})",
@@ -214,19 +945,39 @@ impl CommandRunner {
             anyhow::bail!("Repository directory does not exist: {}", repo_dir);
         }
 
-        self.logger.info(repo, &format!("Running '{command}'"));
+        // Output patterns need the output, so it is captured instead of shown
+        if !self.output_match.is_empty() {
+            let (stdout, stderr, exit_code) = self
+                .run_command_with_capture_no_logs(repo, command, None)
+                .await?;
+            return match self.failure(&stdout, &stderr, exit_code) {
+                Some(failure) => Err(anyhow::anyhow!(failure)),
+                None => Ok(()),
+            };
+        }
+
+        self.info(repo, &format!("Running '{command}'"));
 
-        // Execute command
-        let status = Command::new("sh")
-            .arg("-c")
-            .arg(command)
-            .current_dir(&repo_dir)
-            .status()?;
+        // Execute command, again while it fails and retries are left
+        let mut attempts = 1;
+        let exit_code = loop {
+            let (mut child, watchdog) = self.spawn_shell(repo, command, &repo_dir, false)?;
+            let status = child.wait()?;
+            if watchdog.is_some_and(Watchdog::finish) {
+                return Err(self.timeout_error(repo));
+            }
 
-        let exit_code = status.code().unwrap_or(-1);
+            let exit_code = status.code().unwrap_or(-1);
+            let failed = self.failure("", "", exit_code).is_some();
+            if !self.retry.should_retry(attempts, exit_code, failed) {
+                break exit_code;
+            }
+            self.before_retry(repo, attempts, exit_code).await;
+            attempts += 1;
+        };
         let exit_code_description = get_exit_code_description(exit_code);
 
-        self.logger.info(
+        self.info(
             repo,
             &format!(
                 "Command '{}' ended with exit code {} ({})",
@@ -234,14 +985,66 @@ impl CommandRunner {
             ),
         );
 
-        if !status.success() {
-            anyhow::bail!("Command failed with exit code: {}", exit_code);
+        match self.failure("", "", exit_code) {
+            Some(failure) => Err(anyhow::anyhow!(failure)),
+            None => Ok(()),
         }
+    }
+}
+
+/// Write `input` to a child's stdin on a thread of its own, then close it
+///
+/// Writing in the background keeps a command that prints before reading all
+/// of its input from blocking on a full pipe. A command that exits without
+/// reading everything is not an error.
+fn feed_stdin(mut pipe: std::process::ChildStdin, input: Arc<[u8]>) {
+    std::thread::spawn(move || {
+        let _ = pipe.write_all(&input);
+    });
+}
+
+/// Kills a process group that is still running when its time limit expires
+struct Watchdog {
+    done: mpsc::Sender<()>,
+    fired: Arc<AtomicBool>,
+}
+
+impl Watchdog {
+    fn start(pid: u32, limit: Duration) -> Self {
+        let (done, finished) = mpsc::channel();
+        let fired = Arc::new(AtomicBool::new(false));
+        let flag = fired.clone();
+        std::thread::spawn(move || {
+            if finished.recv_timeout(limit) == Err(mpsc::RecvTimeoutError::Timeout) {
+                flag.store(true, Ordering::SeqCst);
+                kill_process_group(pid);
+            }
+        });
+        Self { done, fired }
+    }
 
-        Ok(())
+    /// Stop watching, returning whether the limit was hit
+    fn finish(self) -> bool {
+        let _ = self.done.send(());
+        self.fired.load(Ordering::SeqCst)
     }
 }
 
+fn kill_process_group(pid: u32) {
+    #[cfg(unix)]
+    let _ = Command::new("kill")
+        .args(["-KILL", "--", &format!("-{}", pid)])
+        .stderr(Stdio::null())
+        .status();
+
+    #[cfg(windows)]
+    let _ = Command::new("taskkill")
+        .args(["/PID", &pid.to_string(), "/T", "/F"])
+        .stdout(Stdio::null())
+        .stderr(Stdio::null())
+        .status();
+}
+
 #[cfg(test)]
 mod tests {
     use super::*;
@@ -286,12 +1089,227 @@ mod tests {
         (repo, temp_dir)
     }
 
+    /// A stand-in container runtime or shell that prints its arguments, one per line
+    #[cfg(unix)]
+    fn fake_runtime(dir: &Path, name: &str) -> PathBuf {
+        use std::os::unix::fs::PermissionsExt;
+        let path = dir.join(name);
+        fs::write(
+            &path,
+            "#!/bin/sh\nfor arg in \"$@\"; do echo \"$arg\"; done\n",
+        )
+        .unwrap();
+        fs::set_permissions(&path, fs::Permissions::from_mode(0o755)).unwrap();
+        path
+    }
+
+    #[test]
+    fn test_container_args() {
+        let container = Container {
+            runtime: "docker".to_string(),
+            image: "rust:1.85".to_string(),
+        };
+        assert_eq!(
+            container.args("cargo test", Path::new("/work/api"), false, &[]),
+            vec![
+                "run",
+                "--rm",
+                "-v",
+                "/work/api:/workspace",
+                "-w",
+                "/workspace",
+                "rust:1.85",
+                "sh",
+                "-c",
+                "cargo test",
+            ]
+        );
+        assert_eq!(
+            container.args("cat", Path::new("/work/api"), true, &[])[..3],
+            ["run", "--rm", "-i"]
+        );
+
+        let env = [("ARG_VERSION".to_string(), "1.2.3".to_string())];
+        assert_eq!(
+            container.args("./deploy.script", Path::new("/work/api"), false, &env)[..4],
+            ["run", "--rm", "-e", "ARG_VERSION"]
+        );
+    }
+
+    #[cfg(unix)]
+    #[test]
+    fn test_container_runtime_detection() {
+        let empty = TempDir::new().unwrap();
+        let error = Container::with_search_path("alpine", None, Some(empty.path().as_os_str()))
+            .unwrap_err();
+        assert_eq!(
+            error.to_string(),
+            "No container runtime found for --container: install docker or podman or pass --container-runtime"
+        );
+
+        let bin = TempDir::new().unwrap();
+        fake_runtime(bin.path(), "podman");
+        let search_path = Some(bin.path().as_os_str());
+        let container = Container::with_search_path("alpine", None, search_path).unwrap();
+        assert_eq!(container.runtime, "podman");
+        assert_eq!(
+            Container::with_search_path("alpine", Some("docker"), search_path)
+                .unwrap_err()
+                .to_string(),
+            "Container runtime 'docker' not found"
+        );
+    }
+
+    #[cfg(unix)]
+    #[tokio::test]
+    async fn test_container_runs_command_through_runtime() {
+        let (repo, temp_dir) =
+            create_test_repo_with_git("test-container", "git@github.com:owner/test.git");
+        let runtime = fake_runtime(temp_dir.path(), "docker");
+        let container = Container::new("node:22", Some(runtime.to_str().unwrap())).unwrap();
+        let runner = CommandRunner::new().with_container(Some(container));
+
+        let (stdout, _, exit_code) = runner
+            .run_command_with_capture_no_logs(&repo, "npm test | tee out.txt", None)
+            .await
+            .unwrap();
+        assert_eq!(exit_code, 0);
+        let args: Vec<&str> = stdout.lines().collect();
+        assert_eq!(
+            args,
+            vec![
+                "run",
+                "--rm",
+                "-v",
+                &format!("{}:/workspace", repo.get_target_dir()),
+                "-w",
+                "/workspace",
+                "node:22",
+                "sh",
+                "-c",
+                "npm test | tee out.txt",
+            ]
+        );
+    }
+
+    #[test]
+    fn test_shell_args_follow_the_shell() {
+        assert_eq!(Shell::new("bash").args("echo hi"), vec!["-c", "echo hi"]);
+        assert_eq!(
+            Shell::new("/usr/local/bin/zsh").args("echo hi"),
+            vec!["-c", "echo hi"]
+        );
+        assert_eq!(Shell::new("cmd.exe").args("dir"), vec!["/C", "dir"]);
+        assert_eq!(
+            Shell::new("PowerShell").args("Get-ChildItem"),
+            vec!["-NoProfile", "-Command", "Get-ChildItem"]
+        );
+        assert_eq!(
+            Shell::new("pwsh").args("ls"),
+            vec!["-NoProfile", "-Command", "ls"]
+        );
+        #[cfg(unix)]
+        assert_eq!(Shell::default(), Shell::new("sh"));
+    }
+
+    #[test]
+    fn test_default_shell_is_the_first_installed() {
+        let temp_dir = TempDir::new().unwrap();
+        let search_path = temp_dir.path().as_os_str();
+        let windows = ["pwsh", "cmd"];
+        assert_eq!(
+            Shell::detect(&windows, Some(search_path)),
+            Shell::new("cmd")
+        );
+
+        let pwsh = format!("pwsh{}", std::env::consts::EXE_SUFFIX);
+        fs::write(temp_dir.path().join(pwsh), "").unwrap();
+        assert_eq!(
+            Shell::detect(&windows, Some(search_path)),
+            Shell::new("pwsh")
+        );
+        assert_eq!(Shell::detect(&["sh"], None), Shell::new("sh"));
+    }
+
+    #[cfg(unix)]
+    #[tokio::test]
+    async fn test_command_is_wrapped_with_configured_shell() {
+        let (repo, temp_dir) =
+            create_test_repo_with_git("test-shell", "git@github.com:owner/test.git");
+        let shell = fake_runtime(temp_dir.path(), "bash");
+        let runner = CommandRunner::new().with_shell(Shell::new(shell.to_str().unwrap()));
+
+        let (stdout, _, exit_code) = runner
+            .run_command_with_capture_no_logs(&repo, "echo $0 | tr a-z A-Z", None)
+            .await
+            .unwrap();
+        assert_eq!(exit_code, 0);
+        assert_eq!(
+            stdout.lines().collect::<Vec<_>>(),
+            vec!["-c", "echo $0 | tr a-z A-Z"]
+        );
+    }
+
     #[tokio::test]
     async fn test_runner_creation() {
         let _runner = CommandRunner::new();
         // Verifies that the CommandRunner can be created without panicking.
     }
 
+    #[tokio::test]
+    async fn test_timeout_kills_long_running_command() {
+        let (repo, _temp_dir) =
+            create_test_repo_with_git("test-timeout", "git@github.com:owner/test.git");
+        let runner = CommandRunner::new().with_timeout(Some(Duration::from_secs(1)));
+
+        let started = std::time::Instant::now();
+        // The subshell keeps the pipes open, so only a group kill ends the command
+        let result = runner
+            .run_command_with_capture_no_logs(&repo, "(sleep 10; echo late)", None)
+            .await;
+        assert!(started.elapsed() < Duration::from_secs(5));
+        assert_eq!(result.unwrap_err().to_string(), "Timed out after 1s");
+
+        let result = runner.run_command(&repo, "sleep 10", None).await;
+        assert_eq!(result.unwrap_err().to_string(), "Timed out after 1s");
+    }
+
+    #[tokio::test]
+    async fn test_deadline_stops_command_before_its_timeout() {
+        let (repo, _temp_dir) =
+            create_test_repo_with_git("test-deadline", "git@github.com:owner/test.git");
+        let runner = CommandRunner::new()
+            .with_timeout(Some(Duration::from_secs(30)))
+            .with_deadline(Some(Instant::now() + Duration::from_secs(1)));
+
+        let started = Instant::now();
+        let result = runner
+            .run_command_with_capture_no_logs(&repo, "(sleep 10; echo late)", None)
+            .await;
+        assert!(started.elapsed() < Duration::from_secs(5));
+        let err = result.unwrap_err();
+        assert!(err.is::<DeadlineExceeded>());
+        assert_eq!(err.to_string(), "Stopped at the --deadline");
+
+        // Past the deadline, commands are stopped as soon as they start
+        let result = runner.run_command(&repo, "sleep 10", None).await;
+        assert!(result.unwrap_err().is::<DeadlineExceeded>());
+    }
+
+    #[tokio::test]
+    async fn test_timeout_not_hit_by_fast_command() {
+        let (repo, _temp_dir) =
+            create_test_repo_with_git("test-fast", "git@github.com:owner/test.git");
+        let runner = CommandRunner::new().with_timeout(Some(Duration::from_secs(30)));
+
+        let (stdout, _, exit_code) = runner
+            .run_command_with_capture_no_logs(&repo, "echo quick", None)
+            .await
+            .unwrap();
+        assert_eq!(stdout.trim(), "quick");
+        assert_eq!(exit_code, 0);
+    }
+
     #[tokio::test]
     async fn test_run_command_success() {
         let (repo, _temp_dir) =
@@ -314,6 +1332,295 @@ mod tests {
         assert!(error_msg.contains("Command failed with exit code: 42"));
     }
 
+    #[tokio::test]
+    async fn test_run_command_allowed_exit_codes() {
+        let (repo, _temp_dir) =
+            create_test_repo_with_git("test-allowed", "git@github.com:owner/test.git");
+        let runner = CommandRunner::new().with_allowed_exit_codes(&[0, 1]);
+
+        assert!(runner.run_command(&repo, "exit 1", None).await.is_ok());
+        assert!(runner.run_command(&repo, "true", None).await.is_ok());
+        let error_msg = runner
+            .run_command(&repo, "exit 2", None)
+            .await
+            .unwrap_err()
+            .to_string();
+        assert!(error_msg.contains("Command failed with exit code: 2"));
+    }
+
+    #[test]
+    fn test_output_match_decides_failure() {
+        let output_match = OutputMatch::new(Some("All tests passed"), Some("(?i)error")).unwrap();
+        assert_eq!(output_match.failure("All tests passed\n", ""), None);
+        assert_eq!(
+            output_match.failure("All tests passed\n", "ERROR: lint\n"),
+            Some("Output matched --failure-if '(?i)error'".to_string())
+        );
+        assert_eq!(
+            output_match.failure("3 tests failed\n", ""),
+            Some("Output did not match --success-if 'All tests passed'".to_string())
+        );
+
+        // Without --success-if, output that does not match --failure-if succeeds
+        let output_match = OutputMatch::new(None, Some("^FAIL")).unwrap();
+        assert_eq!(output_match.failure("ok\n", ""), None);
+        assert!(OutputMatch::default().is_empty());
+
+        let err = OutputMatch::new(Some("(unclosed"), None).unwrap_err();
+        assert_eq!(err.to_string(), "Invalid --success-if pattern '(unclosed'");
+    }
+
+    #[tokio::test]
+    async fn test_output_overrides_exit_code() {
+        let (repo, _temp_dir) =
+            create_test_repo_with_git("test-output-match", "git@github.com:owner/test.git");
+        let by_output = CommandRunner::new()
+            .with_output_match(OutputMatch::new(Some("^done"), Some("panicked")).unwrap());
+
+        // Exits non-zero harmlessly
+        let (stdout, stderr, exit_code) = by_output
+            .run_command_with_capture_no_logs(&repo, "echo done; exit 1", None)
+            .await
+            .unwrap();
+        assert_eq!(exit_code, 1);
+        assert_eq!(by_output.failure(&stdout, &stderr, exit_code), None);
+
+        // Exits zero but reports an error
+        let (stdout, stderr, exit_code) = by_output
+            .run_command_with_capture_no_logs(&repo, "echo done; echo panicked >&2", None)
+            .await
+            .unwrap();
+        assert_eq!(exit_code, 0);
+        assert_eq!(
+            by_output.failure(&stdout, &stderr, exit_code),
+            Some("Output matched --failure-if 'panicked'".to_string())
+        );
+
+        // Without patterns the exit code decides
+        let by_exit_code = CommandRunner::new();
+        assert_eq!(by_exit_code.failure("done\n", "", 0), None);
+        assert_eq!(
+            by_exit_code.failure("done\n", "", 1),
+            Some("Command failed with exit code: 1".to_string())
+        );
+    }
+
+    /// Fails with exit code 3 on its first run in a directory, then succeeds
+    const FLAKY: &str = "echo run >> attempts.txt; \
+        if [ -f passed-once ]; then echo ok; else touch passed-once; echo flaky >&2; exit 3; fi";
+
+    fn retries(retries: u32, exit_codes: &[i32]) -> RetryPolicy {
+        RetryPolicy {
+            retries,
+            delay: Duration::from_millis(10),
+            exit_codes: exit_codes.to_vec(),
+        }
+    }
+
+    fn attempts_made(repo: &Repository) -> usize {
+        fs::read_to_string(Path::new(&repo.get_target_dir()).join("attempts.txt"))
+            .unwrap()
+            .lines()
+            .count()
+    }
+
+    #[test]
+    fn test_retry_policy_decisions() {
+        let policy = retries(2, &[]);
+        assert!(policy.should_retry(1, 1, true));
+        assert!(policy.should_retry(2, 1, true));
+        assert!(!policy.should_retry(3, 1, true));
+        // Attempts that succeeded, by exit code or output, are not retried
+        assert!(!policy.should_retry(1, 1, false));
+
+        let policy = retries(2, &[75]);
+        assert!(policy.should_retry(1, 75, true));
+        assert!(!policy.should_retry(1, 1, true));
+        assert!(!RetryPolicy::default().should_retry(1, 1, true));
+    }
+
+    #[tokio::test]
+    async fn test_failing_command_is_retried_until_it_succeeds() {
+        let (repo, temp_dir) =
+            create_test_repo_with_git("test-retry", "git@github.com:owner/test.git");
+        let log_dir = temp_dir.path().join("logs");
+        let runner = CommandRunner::new().with_retry(retries(2, &[]));
+
+        let (stdout, _, exit_code) = runner
+            .run_command_with_capture(&repo, FLAKY, Some(&log_dir.to_string_lossy()))
+            .await
+            .unwrap();
+        assert_eq!(exit_code, 0);
+        assert_eq!(stdout, "ok\n");
+        assert_eq!(attempts_made(&repo), 2);
+
+        // The failed attempt keeps its own logs; the final ones are the usual files
+        let repo_log_dir = log_dir.join(&repo.name);
+        assert_eq!(
+            fs::read_to_string(repo_log_dir.join("stderr.attempt-1.log")).unwrap(),
+            "flaky\n"
+        );
+        assert_eq!(
+            fs::read_to_string(repo_log_dir.join("stdout.log")).unwrap(),
+            "ok\n"
+        );
+        assert!(!repo_log_dir.join("stdout.attempt-2.log").exists());
+        let metadata: serde_json::Value =
+            serde_json::from_str(&fs::read_to_string(repo_log_dir.join("metadata.json")).unwrap())
+                .unwrap();
+        assert_eq!(metadata["attempts"], 2);
+        assert_eq!(metadata["exit_code"], 0);
+    }
+
+    #[tokio::test]
+    async fn test_retries_stop_when_exhausted_or_code_not_listed() {
+        let (repo, _temp_dir) =
+            create_test_repo_with_git("test-retry-limit", "git@github.com:owner/test.git");
+        let always_fails = "echo run >> attempts.txt; exit 1";
+
+        let runner = CommandRunner::new().with_retry(retries(2, &[]));
+        let error = runner
+            .run_command(&repo, always_fails, None)
+            .await
+            .unwrap_err();
+        assert!(error.to_string().contains("exit code: 1"));
+        assert_eq!(attempts_made(&repo), 3);
+
+        // Exit code 3 is not in the list, so the flaky command is not retried
+        fs::remove_file(Path::new(&repo.get_target_dir()).join("attempts.txt")).unwrap();
+        let runner = CommandRunner::new().with_retry(retries(2, &[75]));
+        let (_, _, exit_code) = runner
+            .run_command_with_capture_no_logs(&repo, FLAKY, None)
+            .await
+            .unwrap();
+        assert_eq!(exit_code, 3);
+        assert_eq!(attempts_made(&repo), 1);
+
+        // Commands run without capturing output are retried too
+        let runner = CommandRunner::new().with_retry(retries(1, &[3]));
+        fs::remove_file(Path::new(&repo.get_target_dir()).join("passed-once")).unwrap();
+        assert!(runner.run_command(&repo, FLAKY, None).await.is_ok());
+        assert_eq!(attempts_made(&repo), 3);
+    }
+
+    #[tokio::test]
+    async fn test_output_patterns_decide_retries() {
+        let (repo, _temp_dir) =
+            create_test_repo_with_git("test-retry-output", "git@github.com:owner/test.git");
+        let runner = CommandRunner::new()
+            .with_retry(retries(2, &[]))
+            .with_output_match(OutputMatch::new(None, Some("flaky")).unwrap());
+
+        // Exits zero but reports an error, so it runs again
+        let zero_with_error = "echo run >> attempts.txt; \
+            if [ -f passed-once ]; then echo ok; else touch passed-once; echo flaky >&2; fi";
+        let (stdout, _, _) = runner
+            .run_command_with_capture_no_logs(&repo, zero_with_error, None)
+            .await
+            .unwrap();
+        assert_eq!(stdout, "ok\n");
+        assert_eq!(attempts_made(&repo), 2);
+
+        // A harmless non-zero exit is not retried, also without capturing
+        fs::remove_file(Path::new(&repo.get_target_dir()).join("attempts.txt")).unwrap();
+        assert!(
+            runner
+                .run_command(&repo, "echo run >> attempts.txt; exit 1", None)
+                .await
+                .is_ok()
+        );
+        assert_eq!(attempts_made(&repo), 1);
+
+        let error = runner
+            .run_command(&repo, "echo flaky", None)
+            .await
+            .unwrap_err();
+        assert_eq!(error.to_string(), "Output matched --failure-if 'flaky'");
+    }
+
+    #[tokio::test]
+    async fn test_buffered_output_holds_back_progress_lines() {
+        let (repo, _temp_dir) =
+            create_test_repo_with_git("test-buffered", "git@github.com:owner/test.git");
+        let runner = CommandRunner::new()
+            .with_timeout(Some(Duration::from_millis(200)))
+            .with_buffered_output();
+
+        runner
+            .run_command_with_capture_no_logs(&repo, "echo hi", None)
+            .await
+            .unwrap();
+        assert!(runner.run_command(&repo, "sleep 5", None).await.is_err());
+
+        let mut out = Vec::new();
+        let mut err = Vec::new();
+        runner
+            .into_output()
+            .unwrap()
+            .write_to(&mut out, &mut err)
+            .unwrap();
+        let out = String::from_utf8(out).unwrap();
+        let err = String::from_utf8(err).unwrap();
+        assert!(out.contains("Running 'echo hi'"), "{out}");
+        assert!(out.contains("ended with exit code 0"), "{out}");
+        assert!(out.contains("Running 'sleep 5'"), "{out}");
+        assert!(err.contains("Timed out after"), "{err}");
+        assert!(CommandRunner::new().into_output().is_none());
+    }
+
+    #[tokio::test]
+    async fn test_stdin_is_replayed_to_every_command() {
+        let (repo, _temp_dir) =
+            create_test_repo_with_git("test-stdin", "git@github.com:owner/test.git");
+        let input: Arc<[u8]> = Arc::from(&b"line one\nline two\n"[..]);
+        let runner = CommandRunner::new().with_stdin(Some(input));
+
+        for _ in 0..2 {
+            let (stdout, _, exit_code) = runner
+                .run_command_with_capture_no_logs(&repo, "cat", None)
+                .await
+                .unwrap();
+            assert_eq!(stdout, "line one\nline two\n");
+            assert_eq!(exit_code, 0);
+        }
+
+        runner
+            .run_command(&repo, "cat > got.txt", None)
+            .await
+            .unwrap();
+        let written = fs::read_to_string(Path::new(&repo.get_target_dir()).join("got.txt"));
+        assert_eq!(written.unwrap(), "line one\nline two\n");
+
+        // Commands that ignore their input still succeed
+        assert!(runner.run_command(&repo, "true", None).await.is_ok());
+    }
+
+    #[tokio::test]
+    async fn test_large_stdin_does_not_block_output() {
+        let (repo, _temp_dir) =
+            create_test_repo_with_git("test-stdin-large", "git@github.com:owner/test.git");
+        let input: Arc<[u8]> = vec![b'x'; 1024 * 1024].into();
+        let runner = CommandRunner::new()
+            .with_timeout(Some(Duration::from_secs(30)))
+            .with_stdin(Some(input));
+
+        let (stdout, _, _) = runner
+            .run_command_with_capture_no_logs(&repo, "wc -c", None)
+            .await
+            .unwrap();
+        assert_eq!(stdout.trim(), "1048576");
+    }
+
+    #[test]
+    fn test_exit_code_allowed() {
+        assert!(exit_code_allowed(0, &[]));
+        assert!(!exit_code_allowed(1, &[]));
+        assert!(exit_code_allowed(1, &[0, 1]));
+        assert!(!exit_code_allowed(2, &[0, 1]));
+        // An explicit list replaces the default rather than extending it
+        assert!(!exit_code_allowed(0, &[1]));
+    }
+
     #[tokio::test]
     async fn test_run_command_nonexistent_command() {
         let (repo, _temp_dir) =
@@ -380,6 +1687,125 @@ mod tests {
         assert!(result.is_ok());
     }
 
+    #[test]
+    fn test_command_line_quotes_arguments() {
+        let argv = |args: &[&str]| args.iter().map(|a| a.to_string()).collect::<Vec<_>>();
+        assert_eq!(
+            command_line(&argv(&["git", "log", "-1", "--format=%h %s"])),
+            "git log -1 '--format=%h %s'"
+        );
+        assert_eq!(
+            command_line(&argv(&["echo", "it's", "$HOME", "~", "", "a;b"])),
+            "echo 'it'\\''s' '$HOME' '~' '' 'a;b'"
+        );
+        // A leading assignment stays the program name
+        assert_eq!(command_line(&argv(&["A=1", "env"])), "'A=1' env");
+    }
+
+    #[tokio::test]
+    async fn test_command_line_preserves_arguments_through_shell() {
+        let (repo, _temp_dir) =
+            create_test_repo_with_git("test-repo", "https://github.com/user/test-repo.git");
+        let args = [
+            "a b",
+            "it's",
+            "$HOME",
+            "*",
+            "\"double\" \\ back",
+            "",
+            "x\ny",
+        ];
+        let mut argv = vec!["printf".to_string(), "[%s]".to_string()];
+        argv.extend(args.iter().map(|a| a.to_string()));
+
+        let (stdout, _, exit_code) = CommandRunner::new()
+            .run_command_with_capture_no_logs(&repo, &command_line(&argv), None)
+            .await
+            .unwrap();
+        assert_eq!(exit_code, 0);
+        let expected: String = args.iter().map(|a| format!("[{}]", a)).collect();
+        assert_eq!(stdout, format!("{}\n", expected));
+    }
+
+    #[tokio::test]
+    async fn test_argv_runs_without_shell() {
+        let (repo, _temp_dir) =
+            create_test_repo_with_git("test-repo", "https://github.com/user/test-repo.git");
+        let argv: Vec<String> = ["printf", "[%s]", "$HOME", "a;b", "a b", "$(echo x)"]
+            .iter()
+            .map(|a| a.to_string())
+            .collect();
+
+        // The command string is only for logging; argv is what runs, also
+        // when the shell would not understand its POSIX quoting
+        for shell in [Shell::default(), Shell::new("cmd")] {
+            let (stdout, _, exit_code) = CommandRunner::new()
+                .with_shell(shell)
+                .with_argv(Some(argv.clone()))
+                .run_command_with_capture_no_logs(&repo, "exit 3", None)
+                .await
+                .unwrap();
+            assert_eq!(exit_code, 0);
+            assert_eq!(stdout, "[$HOME][a;b][a b][$(echo x)]");
+        }
+    }
+
+    #[test]
+    fn test_repo_log_dir_groups_by_first_tag() {
+        let log_dir = Path::new("/logs/run");
+        let mut repo = Repository::new("api".to_string(), "git@github.com:o/api.git".to_string());
+
+        assert_eq!(repo_log_dir(log_dir, &repo, false), log_dir.join("api"));
+        assert_eq!(
+            repo_log_dir(log_dir, &repo, true),
+            log_dir.join("untagged").join("api")
+        );
+
+        repo.tags = vec!["backend".to_string(), "rust".to_string()];
+        assert_eq!(repo_log_dir(log_dir, &repo, false), log_dir.join("api"));
+        assert_eq!(
+            repo_log_dir(log_dir, &repo, true),
+            log_dir.join("backend").join("api")
+        );
+
+        repo.tags = vec!["team/payments".to_string()];
+        assert_eq!(
+            repo_log_dir(log_dir, &repo, true),
+            log_dir.join("team_payments").join("api")
+        );
+    }
+
+    #[tokio::test]
+    async fn test_run_command_with_logs_by_tag() {
+        let (mut tagged, temp_dir) =
+            create_test_repo_with_git("tagged", "git@github.com:owner/tagged.git");
+        tagged.tags = vec!["backend".to_string()];
+        let (untagged, _untagged_dir) =
+            create_test_repo_with_git("untagged-repo", "git@github.com:owner/untagged.git");
+        let log_dir = temp_dir.path().join("logs");
+        let log_dir_str = log_dir.to_string_lossy().to_string();
+        let runner = CommandRunner::new().with_logs_by_tag(true);
+
+        for repo in [&tagged, &untagged] {
+            runner
+                .run_command_with_capture(repo, "echo grouped", Some(&log_dir_str))
+                .await
+                .unwrap();
+        }
+
+        for dir in [
+            log_dir.join("backend").join("tagged"),
+            log_dir.join("untagged").join("untagged-repo"),
+        ] {
+            assert!(dir.join("metadata.json").exists(), "{}", dir.display());
+            assert_eq!(
+                fs::read_to_string(dir.join("stdout.log")).unwrap(),
+                "grouped\n"
+            );
+        }
+        assert!(!log_dir.join("tagged").exists());
+    }
+
     #[tokio::test]
     async fn test_run_command_with_log_directory() {
         let (repo, temp_dir) =
@@ -412,6 +1838,7 @@ mod tests {
         assert_eq!(metadata["command"], "echo 'Logged output'");
         assert_eq!(metadata["exit_code"], 0);
         assert_eq!(metadata["exit_code_description"], "success");
+        assert!(metadata["duration_ms"].is_u64());
     }
 
     #[tokio::test]
@@ -670,8 +2097,7 @@ mod tests {
             url: "https://github.com/test/nonexistent".to_string(),
             tags: vec!["test".to_string()],
             path: Some("/nonexistent/path".to_string()),
-            branch: None,
-            config_dir: None,
+            ..Default::default()
         };
         let runner = CommandRunner::new();
 
@@ -687,4 +2113,35 @@ mod tests {
                 .contains("Repository directory does not exist")
         );
     }
+
+    #[test]
+    fn test_output_template_parse_and_render() {
+        let repo = Repository::new("api".to_string(), "git@github.com:o/api.git".to_string());
+        let template: OutputTemplate = "out/{{.Name}}/{{ .Name }}.txt".parse().unwrap();
+        assert_eq!(template.render(&repo), PathBuf::from("out/api/api.txt"));
+
+        let err = "out/{{.Tag}}.txt".parse::<OutputTemplate>().unwrap_err();
+        assert!(err.to_string().contains("Unknown field '.Tag'"));
+        let err = "out/{{.Name.txt".parse::<OutputTemplate>().unwrap_err();
+        assert!(err.to_string().contains("Unclosed '{{'"));
+        let err = "out/all.txt".parse::<OutputTemplate>().unwrap_err();
+        assert!(err.to_string().contains("must contain {{.Name}}"));
+    }
+
+    #[tokio::test]
+    async fn test_output_template_receives_stdout_only() {
+        let (repo, temp_dir) =
+            create_test_repo_with_git("templated", "git@github.com:owner/test.git");
+        let template = format!("{}/out/nested/{{{{.Name}}}}.txt", temp_dir.path().display());
+        let runner = CommandRunner::new().with_output_template(Some(template.parse().unwrap()));
+
+        let (stdout, _, _) = runner
+            .run_command_with_capture_no_logs(&repo, "echo artifact; echo noise >&2", None)
+            .await
+            .unwrap();
+
+        let written = fs::read_to_string(temp_dir.path().join("out/nested/templated.txt")).unwrap();
+        assert_eq!(written, stdout);
+        assert_eq!(written, "artifact\n");
+    }
 }
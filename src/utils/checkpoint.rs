@@ -0,0 +1,169 @@
+//! Checkpoint files for resuming interrupted batches
+//!
+//! A checkpoint records the repositories a command has already completed
+//! successfully. It is keyed by the command (including its options) and a hash
+//! of the configuration, so that a resumed invocation only reuses progress from
+//! an identical batch.
+
+use anyhow::{Context, Result};
+use serde::{Deserialize, Serialize};
+use std::collections::BTreeSet;
+use std::path::{Path, PathBuf};
+
+#[derive(Debug, Default, Serialize, Deserialize)]
+struct CheckpointState {
+    command: String,
+    completed: BTreeSet<String>,
+}
+
+/// Persistent set of repositories completed by a command
+#[derive(Debug)]
+pub struct Checkpoint {
+    path: PathBuf,
+    state: CheckpointState,
+}
+
+impl Checkpoint {
+    /// Derive the checkpoint key for a command and configuration
+    pub fn key(command: &str, config_content: &str) -> String {
+        format!(
+            "{:016x}",
+            fnv1a(format!("{}\0{}", command, config_content).as_bytes())
+        )
+    }
+
+    /// Open the checkpoint for `key` in `dir`, starting empty if none exists yet
+    pub fn open(dir: &Path, key: &str, command: &str) -> Result<Self> {
+        let path = dir.join(format!("{}.json", key));
+
+        let state = if path.exists() {
+            let content = std::fs::read_to_string(&path)
+                .with_context(|| format!("Failed to read checkpoint: {}", path.display()))?;
+            serde_json::from_str(&content)
+                .with_context(|| format!("Failed to parse checkpoint: {}", path.display()))?
+        } else {
+            CheckpointState {
+                command: command.to_string(),
+                completed: BTreeSet::new(),
+            }
+        };
+
+        Ok(Self { path, state })
+    }
+
+    /// Location of the checkpoint file
+    pub fn path(&self) -> &Path {
+        &self.path
+    }
+
+    /// Check whether a repository already completed successfully
+    pub fn is_completed(&self, name: &str) -> bool {
+        self.state.completed.contains(name)
+    }
+
+    /// Names of all completed repositories
+    pub fn completed(&self) -> &BTreeSet<String> {
+        &self.state.completed
+    }
+
+    /// Record a repository as completed and persist the checkpoint immediately
+    pub fn mark_completed(&mut self, name: &str) -> Result<()> {
+        if self.state.completed.insert(name.to_string()) {
+            self.save()?;
+        }
+        Ok(())
+    }
+
+    /// Remove the checkpoint file once the batch has finished successfully
+    pub fn clear(&mut self) -> Result<()> {
+        self.state.completed.clear();
+        if self.path.exists() {
+            std::fs::remove_file(&self.path)
+                .with_context(|| format!("Failed to remove checkpoint: {}", self.path.display()))?;
+        }
+        Ok(())
+    }
+
+    fn save(&self) -> Result<()> {
+        if let Some(parent) = self.path.parent() {
+            std::fs::create_dir_all(parent).with_context(|| {
+                format!(
+                    "Failed to create checkpoint directory: {}",
+                    parent.display()
+                )
+            })?;
+        }
+
+        // Write to a temporary file first so an interruption never leaves a
+        // truncated checkpoint behind
+        let tmp_path = self.path.with_extension("json.tmp");
+        std::fs::write(&tmp_path, serde_json::to_string_pretty(&self.state)?)
+            .with_context(|| format!("Failed to write checkpoint: {}", tmp_path.display()))?;
+        std::fs::rename(&tmp_path, &self.path)
+            .with_context(|| format!("Failed to write checkpoint: {}", self.path.display()))?;
+        Ok(())
+    }
+}
+
+/// 64-bit FNV-1a, used because it is stable across builds and platforms
+fn fnv1a(bytes: &[u8]) -> u64 {
+    bytes.iter().fold(0xcbf2_9ce4_8422_2325, |hash, byte| {
+        (hash ^ u64::from(*byte)).wrapping_mul(0x0100_0000_01b3)
+    })
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use tempfile::TempDir;
+
+    #[test]
+    fn test_key_depends_on_command_and_config() {
+        let key = Checkpoint::key("clone", "repositories: []");
+        assert_eq!(key, Checkpoint::key("clone", "repositories: []"));
+        assert_eq!(key.len(), 16);
+        assert_ne!(key, Checkpoint::key("run", "repositories: []"));
+        assert_ne!(key, Checkpoint::key("clone", "repositories: [a]"));
+    }
+
+    #[test]
+    fn test_mark_completed_persists_across_reopen() {
+        let temp_dir = TempDir::new().unwrap();
+
+        let mut checkpoint = Checkpoint::open(temp_dir.path(), "abc", "clone").unwrap();
+        assert!(!checkpoint.path().exists());
+        checkpoint.mark_completed("repo-a").unwrap();
+        checkpoint.mark_completed("repo-b").unwrap();
+        assert!(checkpoint.path().exists());
+
+        // Simulate an interruption: a fresh process reopens the same checkpoint
+        let reopened = Checkpoint::open(temp_dir.path(), "abc", "clone").unwrap();
+        assert!(reopened.is_completed("repo-a"));
+        assert!(reopened.is_completed("repo-b"));
+        assert!(!reopened.is_completed("repo-c"));
+        assert_eq!(reopened.completed().len(), 2);
+    }
+
+    #[test]
+    fn test_clear_removes_file() {
+        let temp_dir = TempDir::new().unwrap();
+
+        let mut checkpoint = Checkpoint::open(temp_dir.path(), "abc", "run").unwrap();
+        checkpoint.mark_completed("repo-a").unwrap();
+        checkpoint.clear().unwrap();
+
+        assert!(!checkpoint.path().exists());
+        assert!(!checkpoint.is_completed("repo-a"));
+        // Clearing twice is harmless
+        checkpoint.clear().unwrap();
+    }
+
+    #[test]
+    fn test_open_rejects_corrupt_checkpoint() {
+        let temp_dir = TempDir::new().unwrap();
+        std::fs::write(temp_dir.path().join("abc.json"), "not json").unwrap();
+
+        let err = Checkpoint::open(temp_dir.path(), "abc", "run").unwrap_err();
+        assert!(err.to_string().contains("Failed to parse checkpoint"));
+    }
+}
@@ -0,0 +1,178 @@
+//! Parsing of human-friendly durations and points in time
+
+use anyhow::{Result, bail};
+use chrono::{DateTime, NaiveDate, Utc};
+use std::time::Duration;
+
+/// Parse a duration such as `90s`, `15m`, `12h`, `30d` or `2w`
+///
+/// A bare number is interpreted as seconds.
+pub fn parse_duration(value: &str) -> Result<Duration> {
+    let value = value.trim();
+    let split = value
+        .find(|c: char| !c.is_ascii_digit())
+        .unwrap_or(value.len());
+    let (amount, unit) = value.split_at(split);
+
+    let amount: u64 = match amount.parse() {
+        Ok(amount) => amount,
+        Err(_) => bail!(
+            "Invalid duration '{}': expected a number followed by s, m, h, d or w",
+            value
+        ),
+    };
+
+    let seconds = match unit {
+        "" | "s" => 1,
+        "m" => 60,
+        "h" => 60 * 60,
+        "d" => 24 * 60 * 60,
+        "w" => 7 * 24 * 60 * 60,
+        _ => bail!(
+            "Invalid duration unit '{}' in '{}': use s, m, h, d or w",
+            unit,
+            value
+        ),
+    };
+
+    match amount.checked_mul(seconds) {
+        Some(seconds) => Ok(Duration::from_secs(seconds)),
+        None => bail!("Invalid duration '{}': too long", value),
+    }
+}
+
+/// Format a duration in the largest unit [`parse_duration`] accepts that
+/// represents it exactly, e.g. `90s`, `15m` or `2h`
+pub fn format_duration(duration: Duration) -> String {
+    let seconds = duration.as_secs();
+    let units = [
+        ("w", 7 * 24 * 60 * 60),
+        ("d", 24 * 60 * 60),
+        ("h", 60 * 60),
+        ("m", 60),
+    ];
+    for (unit, size) in units {
+        if seconds >= size && seconds.is_multiple_of(size) {
+            return format!("{}{}", seconds / size, unit);
+        }
+    }
+    format!("{}s", seconds)
+}
+
+/// Format a measured wall-clock time for display, e.g. `850ms`, `12.4s` or `3m 05s`
+pub fn format_elapsed(duration: Duration) -> String {
+    let millis = duration.as_millis();
+    if millis < 1000 {
+        format!("{}ms", millis)
+    } else if millis < 60_000 {
+        format!("{:.1}s", duration.as_secs_f64())
+    } else {
+        let seconds = duration.as_secs();
+        format!("{}m {:02}s", seconds / 60, seconds % 60)
+    }
+}
+
+/// Parse a point in time given either as a duration ago or as an absolute date
+///
+/// Accepts durations understood by [`parse_duration`] (`30d` means thirty days
+/// before `now`), calendar dates (`2024-05-01`, midnight UTC) and RFC 3339
+/// timestamps.
+pub fn parse_since(value: &str, now: DateTime<Utc>) -> Result<DateTime<Utc>> {
+    let value = value.trim();
+
+    if let Ok(timestamp) = DateTime::parse_from_rfc3339(value) {
+        return Ok(timestamp.with_timezone(&Utc));
+    }
+
+    if let Ok(date) = NaiveDate::parse_from_str(value, "%Y-%m-%d") {
+        return Ok(date.and_hms_opt(0, 0, 0).unwrap().and_utc());
+    }
+
+    match parse_duration(value) {
+        Ok(duration) => chrono::Duration::from_std(duration)
+            .ok()
+            .and_then(|duration| now.checked_sub_signed(duration))
+            .ok_or_else(|| anyhow::anyhow!("Invalid value '{}': too far in the past", value)),
+        Err(_) => bail!(
+            "Invalid value '{}': expected a duration (e.g. 30d, 12h) or a date (YYYY-MM-DD)",
+            value
+        ),
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_format_elapsed() {
+        assert_eq!(format_elapsed(Duration::from_millis(850)), "850ms");
+        assert_eq!(format_elapsed(Duration::from_millis(12_430)), "12.4s");
+        assert_eq!(format_elapsed(Duration::from_secs(185)), "3m 05s");
+    }
+
+    #[test]
+    fn test_parse_duration_units() {
+        assert_eq!(parse_duration("45").unwrap(), Duration::from_secs(45));
+        assert_eq!(parse_duration("90s").unwrap(), Duration::from_secs(90));
+        assert_eq!(parse_duration("15m").unwrap(), Duration::from_secs(900));
+        assert_eq!(parse_duration("2h").unwrap(), Duration::from_secs(7200));
+        assert_eq!(parse_duration("3d").unwrap(), Duration::from_secs(259_200));
+        assert_eq!(parse_duration("1w").unwrap(), Duration::from_secs(604_800));
+    }
+
+    #[test]
+    fn test_parse_duration_rejects_invalid() {
+        assert!(parse_duration("").is_err());
+        assert!(parse_duration("d").is_err());
+        assert!(parse_duration("10y").is_err());
+        assert!(parse_duration("-5m").is_err());
+        assert!(parse_duration("99999999999999999w").is_err());
+    }
+
+    #[test]
+    fn test_format_duration_round_trips() {
+        assert_eq!(format_duration(Duration::from_secs(0)), "0s");
+        assert_eq!(format_duration(Duration::from_secs(90)), "90s");
+        assert_eq!(format_duration(Duration::from_secs(900)), "15m");
+        assert_eq!(format_duration(Duration::from_secs(7200)), "2h");
+        assert_eq!(format_duration(Duration::from_secs(1_209_600)), "2w");
+        for value in ["45s", "10m", "3d"] {
+            assert_eq!(format_duration(parse_duration(value).unwrap()), value);
+        }
+    }
+
+    #[test]
+    fn test_parse_since_duration_is_relative_to_now() {
+        let now = DateTime::parse_from_rfc3339("2024-06-30T12:00:00Z")
+            .unwrap()
+            .with_timezone(&Utc);
+        let since = parse_since("30d", now).unwrap();
+        assert_eq!(since.to_rfc3339(), "2024-05-31T12:00:00+00:00");
+    }
+
+    #[test]
+    fn test_parse_since_absolute_dates() {
+        let now = Utc::now();
+        assert_eq!(
+            parse_since("2024-05-01", now).unwrap().to_rfc3339(),
+            "2024-05-01T00:00:00+00:00"
+        );
+        assert_eq!(
+            parse_since("2024-05-01T10:00:00+02:00", now)
+                .unwrap()
+                .to_rfc3339(),
+            "2024-05-01T08:00:00+00:00"
+        );
+    }
+
+    #[test]
+    fn test_parse_since_rejects_garbage() {
+        let err = parse_since("last tuesday", Utc::now()).unwrap_err();
+        assert!(err.to_string().contains("Invalid value 'last tuesday'"));
+        for value in ["100000000d", "9999999999999d"] {
+            let err = parse_since(value, Utc::now()).unwrap_err();
+            assert!(err.to_string().contains("too far in the past"));
+        }
+    }
+}
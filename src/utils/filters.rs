@@ -1,6 +1,13 @@
 //! Repository filtering utilities
 
-use crate::config::Repository;
+use crate::config::{Repository, TagPredicate};
+use crate::git;
+use crate::health::{HealthFilter, HealthReport};
+use anyhow::{Result, bail};
+use chrono::{DateTime, Utc};
+use std::fmt;
+use std::path::Path;
+use std::str::FromStr;
 
 /// Filter repositories by specific names
 pub fn filter_by_names(repositories: &[Repository], names: &[String]) -> Vec<Repository> {
@@ -54,6 +61,9 @@ pub fn filter_by_all_tags(repositories: &[Repository], tags: &[String]) -> Vec<R
 }
 
 /// Filter repositories by context (combining tag inclusion, exclusion, and names filters)
+///
+/// A repository is included when it has any of `include_tags` and none of
+/// `exclude_tags`, as [`TagPredicate`] decides.
 pub fn filter_repositories(
     repositories: &[Repository],
     include_tags: &[String],
@@ -68,23 +78,247 @@ pub fn filter_repositories(
         repositories.to_vec()
     };
 
-    // Apply both inclusion and exclusion filters in a single pass
+    let predicate = TagPredicate::new(include_tags, &[], exclude_tags);
     base_repos
         .into_iter()
-        .filter(|repo| {
-            // Check inclusion filter: if include_tags is empty, include all; otherwise check if repo has all included tags (AND logic)
-            let included =
-                include_tags.is_empty() || include_tags.iter().all(|tag| repo.has_tag(tag));
+        .filter(|repo| predicate.matches(repo))
+        .collect()
+}
+
+/// One of `count` deterministic partitions of a repository list (`--shard i/n`)
+///
+/// Shards are numbered from 1. Repositories are dealt out in list order, so
+/// the repository at position `k` (from 0) belongs to shard `k % count + 1`,
+/// and the shards of one list never overlap and together cover it.
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub struct Shard {
+    pub index: usize,
+    pub count: usize,
+}
+
+impl FromStr for Shard {
+    type Err = anyhow::Error;
+
+    /// Parse `i/n` with `1 <= i <= n`
+    fn from_str(value: &str) -> Result<Self> {
+        let parsed = value
+            .split_once('/')
+            .and_then(|(index, count)| Some((index.parse().ok()?, count.parse().ok()?)));
+        match parsed {
+            Some((index, count)) if index >= 1 && index <= count => Ok(Self { index, count }),
+            _ => bail!(
+                "Invalid shard '{}': expected i/n with 1 <= i <= n, e.g. 1/4",
+                value
+            ),
+        }
+    }
+}
 
-            // Check exclusion filter: if exclude_tags is empty, exclude none; otherwise check if repo has any excluded tag
-            let excluded =
-                !exclude_tags.is_empty() && exclude_tags.iter().any(|tag| repo.has_tag(tag));
+impl fmt::Display for Shard {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        write!(f, "{}/{}", self.index, self.count)
+    }
+}
+
+/// The part of a filtered repository list to operate on
+///
+/// The shard is taken first, then `offset` repositories are skipped and at
+/// most `limit` of the rest are kept. The default keeps every repository.
+#[derive(Debug, Clone, Copy, Default, PartialEq, Eq)]
+pub struct RepoSlice {
+    /// `--shard`
+    pub shard: Option<Shard>,
+    /// `--offset`
+    pub offset: usize,
+    /// `--limit`
+    pub limit: Option<usize>,
+}
+
+impl RepoSlice {
+    /// Whether this slice keeps every repository
+    pub fn is_full(&self) -> bool {
+        self.shard.is_none() && self.offset == 0 && self.limit.is_none()
+    }
+}
 
-            included && !excluded
+/// Keep the part of `repositories` that `slice` selects, in list order
+pub fn slice_repositories(repositories: &[Repository], slice: RepoSlice) -> Vec<Repository> {
+    repositories
+        .iter()
+        .enumerate()
+        .filter(|(position, _)| {
+            slice
+                .shard
+                .is_none_or(|shard| position % shard.count + 1 == shard.index)
         })
+        .map(|(_, repo)| repo.clone())
+        .skip(slice.offset)
+        .take(slice.limit.unwrap_or(usize::MAX))
         .collect()
 }
 
+/// A repository left out by [`filter_archived`], [`filter_by_presence`],
+/// [`filter_active_since`], [`filter_unreadable`] or [`filter_by_health`] and why
+#[derive(Debug, Clone, PartialEq)]
+pub struct SkippedRepository {
+    pub name: String,
+    pub reason: String,
+}
+
+/// Leave out repositories marked `archived: true`
+pub fn filter_archived(repositories: &[Repository]) -> (Vec<Repository>, Vec<SkippedRepository>) {
+    let (archived, active): (Vec<Repository>, Vec<Repository>) =
+        repositories.iter().cloned().partition(|repo| repo.archived);
+    let skipped = archived
+        .into_iter()
+        .map(|repo| SkippedRepository {
+            name: repo.name,
+            reason: "archived".to_string(),
+        })
+        .collect();
+    (active, skipped)
+}
+
+/// Which repositories [`filter_by_presence`] keeps
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum Presence {
+    /// Repositories whose clone directory exists (`--only-cloned`)
+    Cloned,
+    /// Repositories that have not been cloned yet (`--only-missing`)
+    Missing,
+}
+
+/// Keep only repositories whose resolved clone path is on disk, or only those
+/// whose path is not, depending on `presence`
+pub fn filter_by_presence(
+    repositories: &[Repository],
+    presence: Presence,
+) -> (Vec<Repository>, Vec<SkippedRepository>) {
+    let mut selected = Vec::new();
+    let mut skipped = Vec::new();
+
+    for repo in repositories {
+        match (presence, repo.exists()) {
+            (Presence::Cloned, true) | (Presence::Missing, false) => selected.push(repo.clone()),
+            (Presence::Cloned, false) => skipped.push(SkippedRepository {
+                name: repo.name.clone(),
+                reason: "not cloned".to_string(),
+            }),
+            (Presence::Missing, true) => skipped.push(SkippedRepository {
+                name: repo.name.clone(),
+                reason: format!("already cloned at {}", repo.get_target_dir()),
+            }),
+        }
+    }
+
+    (selected, skipped)
+}
+
+/// Keep only repositories whose latest commit is at or after `since`
+///
+/// Repositories that are not cloned yet, or whose history cannot be read, are
+/// excluded as well and reported alongside the inactive ones.
+pub fn filter_active_since(
+    repositories: &[Repository],
+    since: DateTime<Utc>,
+) -> (Vec<Repository>, Vec<SkippedRepository>) {
+    let mut active = Vec::new();
+    let mut skipped = Vec::new();
+
+    for repo in repositories {
+        let skip = |reason: String| SkippedRepository {
+            name: repo.name.clone(),
+            reason,
+        };
+
+        if !repo.exists() {
+            skipped.push(skip("not cloned".to_string()));
+            continue;
+        }
+
+        match git::last_commit_date(&repo.get_target_dir()) {
+            Ok(date) if date >= since => active.push(repo.clone()),
+            Ok(date) => skipped.push(skip(format!("last commit {}", date.to_rfc3339()))),
+            Err(e) => skipped.push(skip(e.to_string())),
+        }
+    }
+
+    (active, skipped)
+}
+
+/// Set aside repositories whose clone cannot be read
+///
+/// A clone directory that cannot be listed, or whose `.git` git cannot open,
+/// would fail every operation on it; see [`git::CloneState::Unreadable`].
+/// Repositories that are not cloned are kept.
+pub fn filter_unreadable(repositories: &[Repository]) -> (Vec<Repository>, Vec<SkippedRepository>) {
+    let mut readable = Vec::new();
+    let mut unreadable = Vec::new();
+
+    for repo in repositories {
+        match git::inspect_clone(Path::new(&repo.get_target_dir())) {
+            git::CloneState::Unreadable(reason) => unreadable.push(SkippedRepository {
+                name: repo.name.clone(),
+                reason,
+            }),
+            _ => readable.push(repo.clone()),
+        }
+    }
+
+    (readable, unreadable)
+}
+
+/// Set aside cloned repositories whose `HEAD` is detached
+///
+/// Repositories that are not cloned, or whose `HEAD` cannot be read, are
+/// kept so the command reports them itself.
+pub fn filter_detached(repositories: &[Repository]) -> (Vec<Repository>, Vec<SkippedRepository>) {
+    let mut attached = Vec::new();
+    let mut detached = Vec::new();
+
+    for repo in repositories {
+        if repo.exists() && matches!(git::is_detached_head(&repo.get_target_dir()), Ok(true)) {
+            detached.push(SkippedRepository {
+                name: repo.name.clone(),
+                reason: "detached HEAD".to_string(),
+            });
+        } else {
+            attached.push(repo.clone());
+        }
+    }
+
+    (attached, detached)
+}
+
+/// Keep only repositories whose health report matches `filter`
+///
+/// Repositories without a report (not cloned) are excluded and reported
+/// alongside the ones that did not match.
+pub fn filter_by_health(
+    repositories: &[Repository],
+    reports: &[HealthReport],
+    filter: HealthFilter,
+) -> (Vec<Repository>, Vec<SkippedRepository>) {
+    let mut selected = Vec::new();
+    let mut skipped = Vec::new();
+
+    for repo in repositories {
+        match reports.iter().find(|report| report.repository == repo.name) {
+            Some(report) if filter.matches(report) => selected.push(repo.clone()),
+            Some(report) => skipped.push(SkippedRepository {
+                name: repo.name.clone(),
+                reason: format!("{}, score {:.0}%", report.status(), report.score() * 100.0),
+            }),
+            None => skipped.push(SkippedRepository {
+                name: repo.name.clone(),
+                reason: "not cloned".to_string(),
+            }),
+        }
+    }
+
+    (selected, skipped)
+}
+
 #[cfg(test)]
 mod tests {
     use super::*;
@@ -212,12 +446,12 @@ mod tests {
         // Test include and exclude together
         let filtered = filter_repositories(
             &repos,
-            &["web".to_string(), "frontend".to_string()], // include web AND frontend (only repo1 has both)
-            &["backend".to_string()],                     // but exclude backend
+            &["web".to_string(), "backend".to_string()], // include web OR backend (both repos)
+            &["backend".to_string()],                    // but exclude backend
             None,
         );
         assert_eq!(filtered.len(), 1);
-        assert_eq!(filtered[0].name, "repo1"); // repo1 has web AND frontend, not backend
+        assert_eq!(filtered[0].name, "repo1"); // repo1 has web, not backend
     }
 
     #[test]
@@ -239,30 +473,355 @@ mod tests {
     }
 
     #[test]
-    fn test_filter_repositories_and_logic_with_multiple_tags() {
+    fn test_filter_repositories_or_logic_with_multiple_tags() {
         let repos = create_test_repositories();
 
-        // Multiple tags should use AND logic - all tags must be present
+        // Multiple tags use OR logic - any one of them is enough
         let filtered = filter_repositories(
             &repos,
-            &["frontend".to_string(), "web".to_string()], // both tags required
+            &["frontend".to_string(), "backend".to_string()],
             &[],
             None,
         );
-        assert_eq!(filtered.len(), 1);
-        assert_eq!(filtered[0].name, "repo1"); // Only repo1 has both tags
+        assert_eq!(filtered.len(), 2);
 
-        // If one tag doesn't exist, no repos should match
+        // A tag no repository has does not prevent the others from matching
         let filtered = filter_repositories(
             &repos,
             &["frontend".to_string(), "nonexistent".to_string()],
             &[],
             None,
         );
-        assert_eq!(filtered.len(), 0);
+        assert_eq!(filtered.len(), 1);
+        assert_eq!(filtered[0].name, "repo1");
 
         // Single nonexistent tag should return no repos
         let filtered = filter_repositories(&repos, &["nonexistent".to_string()], &[], None);
         assert_eq!(filtered.len(), 0);
     }
+
+    fn git_repo_with_commit_at(path: &std::path::Path, date: &str) {
+        std::fs::create_dir_all(path).unwrap();
+        let git = |args: &[&str]| {
+            let output = std::process::Command::new("git")
+                .args(args)
+                .current_dir(path)
+                .env("GIT_AUTHOR_DATE", date)
+                .env("GIT_COMMITTER_DATE", date)
+                .output()
+                .unwrap();
+            assert!(output.status.success(), "git {:?} failed", args);
+        };
+        git(&["init", "-q"]);
+        git(&["config", "user.name", "Test User"]);
+        git(&["config", "user.email", "test@example.com"]);
+        std::fs::write(path.join("README.md"), "# test").unwrap();
+        git(&["add", "."]);
+        git(&["commit", "-q", "-m", "Initial commit"]);
+    }
+
+    #[test]
+    fn test_filter_active_since() {
+        let temp_dir = tempfile::TempDir::new().unwrap();
+        let recent_dir = temp_dir.path().join("recent");
+        let stale_dir = temp_dir.path().join("stale");
+        git_repo_with_commit_at(&recent_dir, &Utc::now().to_rfc3339());
+        git_repo_with_commit_at(&stale_dir, "2020-01-01T00:00:00+00:00");
+
+        let repo_at = |name: &str, path: &std::path::Path| {
+            let mut repo = Repository::new(
+                name.to_string(),
+                format!("git@github.com:owner/{}.git", name),
+            );
+            repo.path = Some(path.to_string_lossy().to_string());
+            repo
+        };
+        let repos = vec![
+            repo_at("recent", &recent_dir),
+            repo_at("stale", &stale_dir),
+            repo_at("missing", &temp_dir.path().join("missing")),
+        ];
+
+        let since = Utc::now() - chrono::Duration::days(30);
+        let (active, skipped) = filter_active_since(&repos, since);
+
+        assert_eq!(active.len(), 1);
+        assert_eq!(active[0].name, "recent");
+        assert_eq!(skipped.len(), 2);
+        assert_eq!(skipped[0].name, "stale");
+        assert!(skipped[0].reason.starts_with("last commit 2020-01-01"));
+        assert_eq!(
+            skipped[1],
+            SkippedRepository {
+                name: "missing".to_string(),
+                reason: "not cloned".to_string(),
+            }
+        );
+    }
+
+    #[test]
+    fn test_filter_detached() {
+        let temp_dir = tempfile::TempDir::new().unwrap();
+        let on_branch = temp_dir.path().join("on-branch");
+        let detached = temp_dir.path().join("detached");
+        git_repo_with_commit_at(&on_branch, &Utc::now().to_rfc3339());
+        git_repo_with_commit_at(&detached, &Utc::now().to_rfc3339());
+        let status = std::process::Command::new("git")
+            .args(["checkout", "-q", "--detach"])
+            .current_dir(&detached)
+            .status()
+            .unwrap();
+        assert!(status.success());
+
+        let repos: Vec<Repository> = [
+            ("on-branch", on_branch),
+            ("detached", detached),
+            ("missing", temp_dir.path().join("missing")),
+        ]
+        .into_iter()
+        .map(|(name, path)| {
+            let mut repo = Repository::new(
+                name.to_string(),
+                format!("git@github.com:owner/{}.git", name),
+            );
+            repo.path = Some(path.to_string_lossy().to_string());
+            repo
+        })
+        .collect();
+
+        let (attached, skipped) = filter_detached(&repos);
+        let names: Vec<&str> = attached.iter().map(|repo| repo.name.as_str()).collect();
+        assert_eq!(names, vec!["on-branch", "missing"]);
+        assert_eq!(
+            skipped,
+            vec![SkippedRepository {
+                name: "detached".to_string(),
+                reason: "detached HEAD".to_string(),
+            }]
+        );
+    }
+
+    fn numbered(count: usize) -> Vec<Repository> {
+        (0..count)
+            .map(|i| {
+                Repository::new(
+                    format!("repo{}", i),
+                    format!("git@github.com:o/repo{}.git", i),
+                )
+            })
+            .collect()
+    }
+
+    fn slice_names(repositories: &[Repository], slice: RepoSlice) -> Vec<String> {
+        slice_repositories(repositories, slice)
+            .into_iter()
+            .map(|repo| repo.name)
+            .collect()
+    }
+
+    #[test]
+    fn test_slice_limit_and_offset() {
+        let repos = numbered(5);
+        assert_eq!(slice_names(&repos, RepoSlice::default()).len(), 5);
+        assert_eq!(
+            slice_names(
+                &repos,
+                RepoSlice {
+                    limit: Some(2),
+                    ..RepoSlice::default()
+                }
+            ),
+            vec!["repo0", "repo1"]
+        );
+        assert_eq!(
+            slice_names(
+                &repos,
+                RepoSlice {
+                    offset: 3,
+                    ..RepoSlice::default()
+                }
+            ),
+            vec!["repo3", "repo4"]
+        );
+        assert_eq!(
+            slice_names(
+                &repos,
+                RepoSlice {
+                    offset: 1,
+                    limit: Some(2),
+                    ..RepoSlice::default()
+                }
+            ),
+            vec!["repo1", "repo2"]
+        );
+        let past_end = RepoSlice {
+            offset: 9,
+            ..RepoSlice::default()
+        };
+        assert!(slice_names(&repos, past_end).is_empty());
+    }
+
+    #[test]
+    fn test_shards_partition_the_list() {
+        let repos = numbered(7);
+        let mut seen = Vec::new();
+        for index in 1..=3 {
+            let shard = RepoSlice {
+                shard: Some(Shard { index, count: 3 }),
+                ..RepoSlice::default()
+            };
+            let names = slice_names(&repos, shard);
+            // Dealt out round-robin, so sizes differ by at most one
+            assert!(names.len() == 2 || names.len() == 3);
+            assert_eq!(names, slice_names(&repos, shard));
+            seen.extend(names);
+        }
+        seen.sort();
+        let mut all: Vec<String> = repos.into_iter().map(|repo| repo.name).collect();
+        all.sort();
+        assert_eq!(seen, all);
+
+        let second = RepoSlice {
+            shard: Some("2/3".parse().unwrap()),
+            limit: Some(1),
+            ..RepoSlice::default()
+        };
+        assert_eq!(slice_names(&numbered(7), second), vec!["repo1"]);
+    }
+
+    #[test]
+    fn test_parse_shard() {
+        assert_eq!(
+            "2/4".parse::<Shard>().unwrap(),
+            Shard { index: 2, count: 4 }
+        );
+        assert_eq!(Shard { index: 1, count: 3 }.to_string(), "1/3");
+        for invalid in ["0/3", "4/3", "1/0", "1", "a/b", "1/3/5"] {
+            assert!(invalid.parse::<Shard>().is_err(), "{}", invalid);
+        }
+    }
+
+    #[test]
+    fn test_filter_unreadable() {
+        let temp_dir = tempfile::TempDir::new().unwrap();
+        let healthy = temp_dir.path().join("healthy");
+        let corrupt = temp_dir.path().join("corrupt");
+        git_repo_with_commit_at(&healthy, &Utc::now().to_rfc3339());
+        std::fs::create_dir_all(corrupt.join(".git")).unwrap();
+        std::fs::write(corrupt.join(".git/HEAD"), "not a ref\n").unwrap();
+
+        let repos: Vec<Repository> = [
+            ("healthy", healthy),
+            ("corrupt", corrupt),
+            ("missing", temp_dir.path().join("missing")),
+        ]
+        .into_iter()
+        .map(|(name, path)| {
+            let mut repo = Repository::new(
+                name.to_string(),
+                format!("git@github.com:owner/{}.git", name),
+            );
+            repo.path = Some(path.to_string_lossy().to_string());
+            repo
+        })
+        .collect();
+
+        let (readable, skipped) = filter_unreadable(&repos);
+        let names: Vec<&str> = readable.iter().map(|repo| repo.name.as_str()).collect();
+        assert_eq!(names, vec!["healthy", "missing"]);
+        assert_eq!(skipped.len(), 1);
+        assert_eq!(skipped[0].name, "corrupt");
+        assert!(skipped[0].reason.starts_with("corrupt .git: "));
+    }
+
+    #[test]
+    fn test_filter_archived() {
+        let mut repos = create_test_repositories();
+        repos[1].archived = true;
+
+        let (active, skipped) = filter_archived(&repos);
+        assert_eq!(active.len(), repos.len() - 1);
+        assert!(active.iter().all(|repo| !repo.archived));
+        assert_eq!(
+            skipped,
+            vec![SkippedRepository {
+                name: repos[1].name.clone(),
+                reason: "archived".to_string(),
+            }]
+        );
+    }
+
+    #[test]
+    fn test_filter_by_presence() {
+        let temp_dir = tempfile::TempDir::new().unwrap();
+        let repos: Vec<Repository> = ["cloned", "missing", "also-cloned"]
+            .iter()
+            .map(|name| {
+                let mut repo =
+                    Repository::new(name.to_string(), format!("git@github.com:o/{}.git", name));
+                repo.path = Some(temp_dir.path().join(name).to_string_lossy().to_string());
+                repo
+            })
+            .collect();
+        std::fs::create_dir_all(temp_dir.path().join("cloned")).unwrap();
+        std::fs::create_dir_all(temp_dir.path().join("also-cloned")).unwrap();
+
+        let (selected, skipped) = filter_by_presence(&repos, Presence::Cloned);
+        let names: Vec<&str> = selected.iter().map(|r| r.name.as_str()).collect();
+        assert_eq!(names, vec!["cloned", "also-cloned"]);
+        assert_eq!(
+            skipped,
+            vec![SkippedRepository {
+                name: "missing".to_string(),
+                reason: "not cloned".to_string(),
+            }]
+        );
+
+        let (selected, skipped) = filter_by_presence(&repos, Presence::Missing);
+        let names: Vec<&str> = selected.iter().map(|r| r.name.as_str()).collect();
+        assert_eq!(names, vec!["missing"]);
+        assert_eq!(skipped.len(), 2);
+        assert!(skipped[0].reason.starts_with("already cloned at "));
+    }
+
+    #[test]
+    fn test_filter_by_health_uses_stubbed_reports() {
+        use crate::health::{Category, CheckResult};
+
+        let report = |name: &str, score: f64| HealthReport {
+            repository: name.to_string(),
+            results: vec![CheckResult {
+                checker: "stub".to_string(),
+                category: Category::Documentation,
+                score,
+                findings: vec![],
+                critical: false,
+            }],
+            ignored: Vec::new(),
+        };
+        let repos: Vec<Repository> = ["broken", "shaky", "fine", "absent"]
+            .iter()
+            .map(|name| Repository::new(name.to_string(), format!("git@github.com:o/{}.git", name)))
+            .collect();
+        let reports = vec![
+            report("broken", 0.2),
+            report("shaky", 0.6),
+            report("fine", 1.0),
+        ];
+
+        let (selected, skipped) = filter_by_health(&repos, &reports, HealthFilter::Critical);
+        assert_eq!(selected.len(), 1);
+        assert_eq!(selected[0].name, "broken");
+        assert_eq!(skipped.len(), 3);
+        assert_eq!(skipped[0].reason, "warning, score 60%");
+        assert_eq!(skipped[2].reason, "not cloned");
+
+        let (selected, _) = filter_by_health(&repos, &reports, HealthFilter::Warning);
+        let names: Vec<&str> = selected.iter().map(|r| r.name.as_str()).collect();
+        assert_eq!(names, vec!["shaky"]);
+
+        let (selected, _) = filter_by_health(&repos, &reports, HealthFilter::BelowScore(0.7));
+        let names: Vec<&str> = selected.iter().map(|r| r.name.as_str()).collect();
+        assert_eq!(names, vec!["broken", "shaky"]);
+    }
 }
@@ -1,20 +1,37 @@
 //! Utility modules for common functionality
 
+pub mod checkpoint;
+pub mod duration;
 pub mod exit_codes;
 pub mod filesystem;
 pub mod filters;
+pub mod notify;
+pub mod output_buffer;
+pub mod picker;
 pub mod repository_discovery;
 pub mod sanitizers;
+pub mod table;
 pub mod validators;
 
 // Re-export commonly used functions
+pub use checkpoint::Checkpoint;
+pub use duration::{format_duration, format_elapsed, parse_duration, parse_since};
 pub use exit_codes::get_exit_code_description;
 pub use filesystem::ensure_directory_exists;
-pub use filters::{filter_by_names, filter_by_tag, filter_repositories};
+pub use filters::{
+    Presence, RepoSlice, Shard, filter_active_since, filter_archived, filter_by_health,
+    filter_by_names, filter_by_presence, filter_by_tag, filter_detached, filter_repositories,
+    filter_unreadable, slice_repositories,
+};
+pub use notify::{Notification, NotifyOn, send_notification};
+pub use output_buffer::OutputBuffer;
+pub use picker::pick_repositories;
 pub use repository_discovery::{
     create_repository_from_path, detect_tags_from_path, find_git_repositories, get_remote_url,
+    tags_from_ancestors,
 };
 pub use sanitizers::{sanitize_for_filename, sanitize_script_name};
+pub use table::{Align, Cell, Table};
 pub use validators::{
     ValidationError, validate_config, validate_recipe, validate_repositories, validate_repository,
     validate_tag_exists, validate_tag_filter, validation_errors_to_anyhow,
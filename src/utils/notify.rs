@@ -0,0 +1,215 @@
+//! Completion notifications (`--notify-url`)
+//!
+//! When a command finishes, a JSON [`Notification`] summarising the run is
+//! POSTed to a webhook. Delivery is best effort: the request has a short
+//! timeout and callers only log a failed delivery, so an unreachable webhook
+//! never changes the command's exit code.
+
+use crate::commands::report::RunReport;
+use anyhow::{Context, Result};
+use serde::{Deserialize, Serialize};
+use std::str::FromStr;
+use std::time::Duration;
+
+/// How long the webhook may take to accept a notification
+pub const NOTIFY_TIMEOUT: Duration = Duration::from_secs(10);
+
+/// When to send a notification (`--notify-on`)
+#[derive(Debug, Clone, Copy, Default, PartialEq, Eq)]
+pub enum NotifyOn {
+    /// Only when the command or a repository failed
+    Failure,
+    /// After every run
+    #[default]
+    Always,
+}
+
+impl NotifyOn {
+    /// Whether a run that ended with `success` is notified
+    pub fn applies(self, success: bool) -> bool {
+        match self {
+            Self::Failure => !success,
+            Self::Always => true,
+        }
+    }
+}
+
+impl FromStr for NotifyOn {
+    type Err = anyhow::Error;
+
+    fn from_str(value: &str) -> Result<Self> {
+        match value {
+            "failure" => Ok(Self::Failure),
+            "always" => Ok(Self::Always),
+            _ => anyhow::bail!(
+                "Unknown notify condition '{}': expected failure or always",
+                value
+            ),
+        }
+    }
+}
+
+/// A repository that failed, as listed in a notification
+#[derive(Debug, Clone, PartialEq, Serialize, Deserialize)]
+pub struct NotifiedFailure {
+    pub name: String,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub error: Option<String>,
+}
+
+/// JSON body POSTed to the webhook
+#[derive(Debug, Clone, PartialEq, Serialize, Deserialize)]
+pub struct Notification {
+    pub command: String,
+    pub success: bool,
+    /// Top-level error message when the command failed
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub error: Option<String>,
+    pub repositories: usize,
+    pub succeeded: usize,
+    pub failed: usize,
+    pub duration_ms: u64,
+    pub failures: Vec<NotifiedFailure>,
+}
+
+impl From<&RunReport> for Notification {
+    fn from(report: &RunReport) -> Self {
+        let failures: Vec<NotifiedFailure> = report
+            .repositories
+            .iter()
+            .filter(|outcome| outcome.failed())
+            .map(|outcome| NotifiedFailure {
+                name: outcome.name.clone(),
+                error: outcome.error.clone(),
+            })
+            .collect();
+
+        Self {
+            command: report.command.clone(),
+            success: report.success,
+            error: report.error.clone(),
+            repositories: report.repositories.len(),
+            succeeded: report
+                .repositories
+                .iter()
+                .filter(|outcome| outcome.success)
+                .count(),
+            failed: failures.len(),
+            duration_ms: report.duration_ms,
+            failures,
+        }
+    }
+}
+
+/// POST `notification` as JSON to `url`
+///
+/// # Errors
+/// Returns an error if the request fails, times out, or the webhook answers
+/// with a non-success status
+pub async fn send_notification(url: &str, notification: &Notification) -> Result<()> {
+    let client = reqwest::Client::builder()
+        .timeout(NOTIFY_TIMEOUT)
+        .build()
+        .context("Failed to create HTTP client")?;
+    client
+        .post(url)
+        .json(notification)
+        .send()
+        .await
+        .with_context(|| format!("Failed to send notification to {}", url))?
+        .error_for_status()
+        .with_context(|| format!("Notification rejected by {}", url))?;
+    Ok(())
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::commands::report::RepoOutcome;
+    use repos_mock_server::{MockServer, Response};
+
+    fn report() -> RunReport {
+        let outcome = |name: &str, error: Option<&str>| RepoOutcome {
+            name: name.to_string(),
+            success: error.is_none(),
+            error: error.map(String::from),
+            skipped: false,
+            duration_ms: 5,
+        };
+        RunReport::new(
+            "run",
+            serde_json::json!({}),
+            chrono::Utc::now(),
+            vec![
+                outcome("api", None),
+                outcome("web", Some("exit code 2")),
+                outcome("worker", None),
+            ],
+            &Ok(()),
+        )
+    }
+
+    #[test]
+    fn test_notification_counts_failures() {
+        let notification = Notification::from(&report());
+        assert_eq!(notification.command, "run");
+        assert!(!notification.success);
+        assert_eq!(
+            (
+                notification.repositories,
+                notification.succeeded,
+                notification.failed
+            ),
+            (3, 2, 1)
+        );
+        assert_eq!(
+            notification.failures,
+            vec![NotifiedFailure {
+                name: "web".to_string(),
+                error: Some("exit code 2".to_string()),
+            }]
+        );
+    }
+
+    #[test]
+    fn test_notify_on() {
+        assert_eq!("failure".parse::<NotifyOn>().unwrap(), NotifyOn::Failure);
+        assert!("never".parse::<NotifyOn>().is_err());
+        assert!(!NotifyOn::Failure.applies(true));
+        assert!(NotifyOn::Failure.applies(false));
+        assert!(NotifyOn::Always.applies(true));
+    }
+
+    #[tokio::test]
+    async fn test_send_notification_posts_json_summary() {
+        let server = MockServer::start(vec![Response::new("204 No Content", "")]);
+        let url = format!("{}/hooks/repos", server.url);
+        send_notification(&url, &Notification::from(&report()))
+            .await
+            .unwrap();
+
+        let request = server.requests().remove(0);
+        assert!(request.line.starts_with("POST /hooks/repos "));
+        assert!(request.head.contains("content-type: application/json"));
+        let payload: serde_json::Value = serde_json::from_str(&request.body).unwrap();
+        assert_eq!(payload["command"], "run");
+        assert_eq!(payload["success"], false);
+        assert_eq!(payload["repositories"], 3);
+        assert_eq!(payload["succeeded"], 2);
+        assert_eq!(payload["failed"], 1);
+        assert_eq!(payload["failures"][0]["name"], "web");
+        assert_eq!(payload["failures"][0]["error"], "exit code 2");
+        assert!(payload.get("error").is_none());
+    }
+
+    #[tokio::test]
+    async fn test_rejected_notification_is_an_error() {
+        let server = MockServer::start(vec![Response::new("500 Internal Server Error", "")]);
+        let url = format!("{}/hooks/repos", server.url);
+        let error = send_notification(&url, &Notification::from(&report()))
+            .await
+            .unwrap_err();
+        assert!(error.to_string().starts_with("Notification rejected by"));
+        server.requests();
+    }
+}
@@ -0,0 +1,165 @@
+//! Bounded buffering of per-repository terminal output
+//!
+//! Output is kept in memory up to a limit and moved to an anonymous temporary
+//! file beyond it, so buffering a repository that prints a lot does not grow
+//! memory without bound.
+
+use std::fs::File;
+use std::io::{self, Seek, SeekFrom, Write};
+
+/// Bytes kept in memory per stream before spilling to a temporary file
+pub const DEFAULT_MEMORY_LIMIT: usize = 256 * 1024;
+
+/// Stdout and stderr lines of one repository, held back until flushed
+#[derive(Debug)]
+pub struct OutputBuffer {
+    stdout: SpillBuffer,
+    stderr: SpillBuffer,
+}
+
+impl Default for OutputBuffer {
+    fn default() -> Self {
+        Self::with_limit(DEFAULT_MEMORY_LIMIT)
+    }
+}
+
+impl OutputBuffer {
+    pub fn new() -> Self {
+        Self::default()
+    }
+
+    /// Buffer with `limit` bytes of memory per stream
+    pub fn with_limit(limit: usize) -> Self {
+        Self {
+            stdout: SpillBuffer::new(limit),
+            stderr: SpillBuffer::new(limit),
+        }
+    }
+
+    /// Append a line destined for stdout
+    pub fn out_line(&mut self, line: &str) {
+        self.stdout.push_line(line);
+    }
+
+    /// Append a line destined for stderr
+    pub fn err_line(&mut self, line: &str) {
+        self.stderr.push_line(line);
+    }
+
+    /// Whether any stream has moved to a temporary file
+    pub fn is_spilled(&self) -> bool {
+        self.stdout.file.is_some() || self.stderr.file.is_some()
+    }
+
+    /// Write the buffered output to the given writers
+    pub fn write_to(self, out: &mut impl Write, err: &mut impl Write) -> io::Result<()> {
+        self.stdout.write_to(out)?;
+        self.stderr.write_to(err)
+    }
+
+    /// Print the buffered output to the process's stdout and stderr
+    pub fn flush(self) -> io::Result<()> {
+        self.write_to(&mut io::stdout().lock(), &mut io::stderr().lock())
+    }
+}
+
+#[derive(Debug)]
+struct SpillBuffer {
+    limit: usize,
+    memory: String,
+    file: Option<File>,
+}
+
+impl SpillBuffer {
+    fn new(limit: usize) -> Self {
+        Self {
+            limit,
+            memory: String::new(),
+            file: None,
+        }
+    }
+
+    fn push_line(&mut self, line: &str) {
+        if self.file.is_none() && self.memory.len() + line.len() + 1 > self.limit {
+            self.spill();
+        }
+        match &mut self.file {
+            Some(file) => {
+                // Losing a line of progress output is preferable to failing the run
+                let _ = writeln!(file, "{}", line);
+            }
+            None => {
+                self.memory.push_str(line);
+                self.memory.push('\n');
+            }
+        }
+    }
+
+    /// Move the in-memory contents to a temporary file
+    fn spill(&mut self) {
+        let Ok(mut file) = tempfile::tempfile() else {
+            // Without a temporary file the output stays in memory
+            self.limit = usize::MAX;
+            return;
+        };
+        if file.write_all(self.memory.as_bytes()).is_ok() {
+            self.memory = String::new();
+            self.file = Some(file);
+        } else {
+            self.limit = usize::MAX;
+        }
+    }
+
+    fn write_to(self, out: &mut impl Write) -> io::Result<()> {
+        match self.file {
+            Some(mut file) => {
+                file.seek(SeekFrom::Start(0))?;
+                io::copy(&mut file, out)?;
+            }
+            None => out.write_all(self.memory.as_bytes())?,
+        }
+        out.flush()
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn contents(buffer: OutputBuffer) -> (String, String) {
+        let mut out = Vec::new();
+        let mut err = Vec::new();
+        buffer.write_to(&mut out, &mut err).unwrap();
+        (
+            String::from_utf8(out).unwrap(),
+            String::from_utf8(err).unwrap(),
+        )
+    }
+
+    #[test]
+    fn test_small_output_stays_in_memory() {
+        let mut buffer = OutputBuffer::new();
+        buffer.out_line("api | Running 'make'");
+        buffer.err_line("api | Timed out after 1s");
+        buffer.out_line("api | done");
+
+        assert!(!buffer.is_spilled());
+        let (out, err) = contents(buffer);
+        assert_eq!(out, "api | Running 'make'\napi | done\n");
+        assert_eq!(err, "api | Timed out after 1s\n");
+    }
+
+    #[test]
+    fn test_large_output_spills_to_file() {
+        let mut buffer = OutputBuffer::with_limit(16);
+        buffer.out_line("first line");
+        assert!(!buffer.is_spilled());
+        buffer.out_line("second line");
+        assert!(buffer.is_spilled());
+        buffer.out_line("third line");
+
+        let (out, err) = contents(buffer);
+        assert_eq!(out, "first line\nsecond line\nthird line\n");
+        assert!(err.is_empty());
+    }
+}
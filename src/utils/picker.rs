@@ -0,0 +1,335 @@
+//! Interactive repository selection (`--interactive`)
+//!
+//! On a terminal the repositories are shown as a checkbox list that can be
+//! narrowed with a filter and toggled by number until the selection is
+//! confirmed. When stdin is not a terminal the list is printed once and a
+//! single line of numbers is read instead, so the picker can be scripted.
+//! Either way the prompts go to stderr, keeping stdout for the command.
+
+use crate::config::Repository;
+use crate::utils::table::{Align, Table};
+use anyhow::{Context, Result, bail};
+use std::io::{BufRead, Write};
+
+/// A line entered at the checkbox prompt
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub enum PickerInput {
+    /// Toggle these repositories (0-based positions in the full list)
+    Toggle(Vec<usize>),
+    /// Show only repositories whose name or tags contain this text; empty clears it
+    Filter(String),
+    /// Select every shown repository
+    All,
+    /// Clear every shown repository
+    None,
+    /// Confirm the selection
+    Done,
+    /// Abandon the selection
+    Quit,
+}
+
+impl PickerInput {
+    /// Parse a prompt line for a list of `count` repositories
+    ///
+    /// An empty line confirms, `/text` filters, `a` and `n` select all or none
+    /// of the shown repositories, `q` cancels, and anything else is read as
+    /// numbers and ranges with [`parse_selection`].
+    pub fn parse(line: &str, count: usize) -> Result<Self> {
+        let line = line.trim();
+        Ok(match line {
+            "" => Self::Done,
+            "a" => Self::All,
+            "n" => Self::None,
+            "q" => Self::Quit,
+            _ => match line.strip_prefix('/') {
+                Some(filter) => Self::Filter(filter.trim().to_string()),
+                None => Self::Toggle(parse_selection(line, count)?),
+            },
+        })
+    }
+}
+
+/// Parse 1-based numbers and ranges such as `1,3-5 8` into 0-based positions
+///
+/// Positions are returned sorted and without duplicates.
+///
+/// # Errors
+/// Returns an error for anything that is not a number or range within `1..=count`
+pub fn parse_selection(input: &str, count: usize) -> Result<Vec<usize>> {
+    let number = |value: &str| -> Result<usize> {
+        match value.trim().parse::<usize>() {
+            Ok(n) if (1..=count).contains(&n) => Ok(n - 1),
+            _ => bail!(
+                "Invalid selection '{}': expected numbers from 1 to {}",
+                value.trim(),
+                count
+            ),
+        }
+    };
+
+    let mut positions = Vec::new();
+    for part in input
+        .split(|c: char| c == ',' || c.is_whitespace())
+        .filter(|part| !part.is_empty())
+    {
+        match part.split_once('-') {
+            Some((start, end)) => {
+                let (start, end) = (number(start)?, number(end)?);
+                if start > end {
+                    bail!("Invalid range '{}': start is after end", part);
+                }
+                positions.extend(start..=end);
+            }
+            None => positions.push(number(part)?),
+        }
+    }
+    positions.sort_unstable();
+    positions.dedup();
+    Ok(positions)
+}
+
+/// State of the checkbox list
+struct Picker<'a> {
+    repositories: &'a [Repository],
+    selected: Vec<bool>,
+    filter: String,
+}
+
+impl<'a> Picker<'a> {
+    fn new(repositories: &'a [Repository]) -> Self {
+        Self {
+            repositories,
+            selected: vec![false; repositories.len()],
+            filter: String::new(),
+        }
+    }
+
+    /// Positions of the repositories matching the filter
+    fn shown(&self) -> Vec<usize> {
+        let filter = self.filter.to_lowercase();
+        (0..self.repositories.len())
+            .filter(|&i| {
+                let repo = &self.repositories[i];
+                filter.is_empty()
+                    || repo.name.to_lowercase().contains(&filter)
+                    || repo
+                        .tags
+                        .iter()
+                        .any(|tag| tag.to_lowercase().contains(&filter))
+            })
+            .collect()
+    }
+
+    fn set_shown(&mut self, value: bool) {
+        for i in self.shown() {
+            self.selected[i] = value;
+        }
+    }
+
+    fn chosen(&self) -> Vec<Repository> {
+        self.repositories
+            .iter()
+            .zip(&self.selected)
+            .filter(|(_, selected)| **selected)
+            .map(|(repo, _)| repo.clone())
+            .collect()
+    }
+
+    fn render(&self, checkboxes: bool) -> String {
+        let mut table = Table::new(["", "#", "REPOSITORY", "TAGS"])
+            .align(1, Align::Right)
+            .indent(2);
+        for i in self.shown() {
+            let mark = match (checkboxes, self.selected[i]) {
+                (false, _) => "",
+                (true, true) => "[x]",
+                (true, false) => "[ ]",
+            };
+            let repo = &self.repositories[i];
+            table.add_row([
+                mark.to_string(),
+                (i + 1).to_string(),
+                repo.name.clone(),
+                repo.tags.join(", "),
+            ]);
+        }
+        table.render(false)
+    }
+}
+
+/// Let the user choose some of `repositories`, reading answers from `input`
+///
+/// With `checkboxes` the list is redrawn after every line until it is
+/// confirmed; otherwise one line of numbers (or `all`) is read.
+///
+/// # Errors
+/// Returns an error when the selection is cancelled, empty, or input ends first
+pub fn pick_repositories(
+    repositories: &[Repository],
+    mut input: impl BufRead,
+    mut output: impl Write,
+    checkboxes: bool,
+) -> Result<Vec<Repository>> {
+    if repositories.is_empty() {
+        return Ok(Vec::new());
+    }
+    let mut picker = Picker::new(repositories);
+    let mut read_line = |output: &mut dyn Write, prompt: &str| -> Result<String> {
+        write!(output, "{}", prompt)?;
+        output.flush()?;
+        let mut line = String::new();
+        if input
+            .read_line(&mut line)
+            .context("Failed to read selection")?
+            == 0
+        {
+            bail!("Selection cancelled: input ended");
+        }
+        Ok(line)
+    };
+
+    if !checkboxes {
+        write!(output, "{}", picker.render(false))?;
+        let line = read_line(&mut output, "Repositories to use (e.g. 1,3-5 or all): ")?;
+        if line.trim() == "all" {
+            picker.set_shown(true);
+        } else {
+            for i in parse_selection(&line, repositories.len())? {
+                picker.selected[i] = true;
+            }
+        }
+    } else {
+        loop {
+            if !picker.filter.is_empty() {
+                writeln!(output, "Filter: {}", picker.filter)?;
+            }
+            write!(output, "{}", picker.render(true))?;
+            let line = read_line(
+                &mut output,
+                "Toggle 1,3-5 | /text filter | a all | n none | Enter run | q cancel: ",
+            )?;
+            match PickerInput::parse(&line, repositories.len()) {
+                Ok(PickerInput::Toggle(positions)) => {
+                    for i in positions {
+                        picker.selected[i] = !picker.selected[i];
+                    }
+                }
+                Ok(PickerInput::Filter(filter)) => picker.filter = filter,
+                Ok(PickerInput::All) => picker.set_shown(true),
+                Ok(PickerInput::None) => picker.set_shown(false),
+                Ok(PickerInput::Done) => break,
+                Ok(PickerInput::Quit) => bail!("Selection cancelled"),
+                Err(e) => writeln!(output, "{}", e)?,
+            }
+        }
+    }
+
+    let chosen = picker.chosen();
+    if chosen.is_empty() {
+        bail!("No repositories selected");
+    }
+    Ok(chosen)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use std::io::Cursor;
+
+    fn repositories() -> Vec<Repository> {
+        [
+            ("api", vec!["backend"]),
+            ("web", vec!["frontend"]),
+            ("worker", vec!["backend", "legacy"]),
+            ("docs", vec![]),
+        ]
+        .into_iter()
+        .map(|(name, tags)| {
+            let mut repo =
+                Repository::new(name.to_string(), format!("git@github.com:o/{name}.git"));
+            repo.tags = tags.into_iter().map(String::from).collect();
+            repo
+        })
+        .collect()
+    }
+
+    fn pick(script: &str, checkboxes: bool) -> (Result<Vec<String>>, String) {
+        let mut output = Vec::new();
+        let result = pick_repositories(
+            &repositories(),
+            Cursor::new(script.to_string()),
+            &mut output,
+            checkboxes,
+        )
+        .map(|repos| repos.into_iter().map(|repo| repo.name).collect());
+        (result, String::from_utf8(output).unwrap())
+    }
+
+    #[test]
+    fn test_parse_selection() {
+        assert_eq!(parse_selection("1,3-4", 5).unwrap(), vec![0, 2, 3]);
+        assert_eq!(parse_selection(" 2 2, 1 ", 5).unwrap(), vec![0, 1]);
+        assert!(parse_selection("", 5).unwrap().is_empty());
+        for invalid in ["0", "6", "x", "4-2", "1-", "-3"] {
+            assert!(parse_selection(invalid, 5).is_err(), "{}", invalid);
+        }
+    }
+
+    #[test]
+    fn test_parse_prompt_input() {
+        assert_eq!(PickerInput::parse("\n", 3).unwrap(), PickerInput::Done);
+        assert_eq!(
+            PickerInput::parse("/back\n", 3).unwrap(),
+            PickerInput::Filter("back".to_string())
+        );
+        assert_eq!(
+            PickerInput::parse("/", 3).unwrap(),
+            PickerInput::Filter(String::new())
+        );
+        assert_eq!(PickerInput::parse("a", 3).unwrap(), PickerInput::All);
+        assert_eq!(PickerInput::parse("q", 3).unwrap(), PickerInput::Quit);
+        assert_eq!(
+            PickerInput::parse("1 3", 3).unwrap(),
+            PickerInput::Toggle(vec![0, 2])
+        );
+    }
+
+    #[test]
+    fn test_checkbox_script_filters_and_toggles() {
+        // Select the backend repositories, then drop worker again
+        let (result, output) = pick("/backend\na\n3\n/\n\n", true);
+        assert_eq!(result.unwrap(), vec!["api"]);
+        assert!(output.contains("Filter: backend"));
+        assert!(output.contains("[x]"));
+
+        let (result, output) = pick("9\n2\n\n", true);
+        assert_eq!(result.unwrap(), vec!["web"]);
+        assert!(output.contains("Invalid selection '9'"));
+    }
+
+    #[test]
+    fn test_checkbox_script_cancel_and_empty() {
+        let (result, _) = pick("1\nq\n", true);
+        assert_eq!(result.unwrap_err().to_string(), "Selection cancelled");
+
+        let (result, _) = pick("\n", true);
+        assert_eq!(result.unwrap_err().to_string(), "No repositories selected");
+
+        let (result, _) = pick("1\n", true);
+        assert!(result.unwrap_err().to_string().contains("input ended"));
+    }
+
+    #[test]
+    fn test_numbered_prompt_reads_one_line() {
+        let (result, output) = pick("2-3\n", false);
+        assert_eq!(result.unwrap(), vec!["web", "worker"]);
+        assert!(output.contains("worker"));
+        assert!(!output.contains("[ ]"));
+
+        let (result, _) = pick("all\n", false);
+        assert_eq!(result.unwrap().len(), 4);
+
+        let (result, _) = pick("5\n", false);
+        assert!(result.is_err());
+    }
+}
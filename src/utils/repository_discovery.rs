@@ -50,6 +50,32 @@ pub fn get_remote_url(repo_path: &Path) -> Result<Option<String>> {
     Ok(None)
 }
 
+/// Tags from the directories between `root` and a repository at `repo_dir`
+///
+/// Up to `depth` ancestor directory names are taken, starting at the one
+/// nearest `root`; the repository's own directory is never a tag. A repository
+/// at `platform/auth/service` gets `platform` and `auth` with a depth of 2,
+/// and only `platform` with a depth of 1. Paths outside `root` get no tags.
+pub fn tags_from_ancestors(root: &Path, repo_dir: &Path, depth: usize) -> Vec<String> {
+    let Ok(relative) = repo_dir.strip_prefix(root) else {
+        return Vec::new();
+    };
+    let Some(parent) = relative.parent() else {
+        return Vec::new();
+    };
+
+    let mut tags: Vec<String> = Vec::new();
+    for component in parent.components().take(depth) {
+        if let std::path::Component::Normal(name) = component {
+            let tag = name.to_string_lossy().to_string();
+            if !tags.contains(&tag) {
+                tags.push(tag);
+            }
+        }
+    }
+    tags
+}
+
 /// Detect tags from repository path based on files and directory names
 pub fn detect_tags_from_path(path: &Path) -> Vec<String> {
     let mut tags = Vec::new();
@@ -112,8 +138,8 @@ pub fn create_repository_from_path(path: &Path) -> Result<Option<Repository>> {
                 url,
                 tags,
                 path: Some(path.to_string_lossy().to_string()),
-                branch: None,
                 config_dir: None, // Will be set when config is loaded
+                ..Default::default()
             };
 
             return Ok(Some(repository));
@@ -170,6 +196,39 @@ mod tests {
         Ok(())
     }
 
+    #[test]
+    fn test_tags_from_ancestors_by_depth() {
+        let temp_dir = TempDir::new().unwrap();
+        let root = temp_dir.path();
+        let service = root.join("platform/auth/service");
+        let top_level = root.join("tools");
+        fs::create_dir_all(&service).unwrap();
+        fs::create_dir_all(&top_level).unwrap();
+
+        assert!(tags_from_ancestors(root, &service, 0).is_empty());
+        assert_eq!(tags_from_ancestors(root, &service, 1), vec!["platform"]);
+        assert_eq!(
+            tags_from_ancestors(root, &service, 2),
+            vec!["platform", "auth"]
+        );
+        // Depth beyond the available ancestors stops at the repository itself
+        assert_eq!(
+            tags_from_ancestors(root, &service, 5),
+            vec!["platform", "auth"]
+        );
+        assert!(tags_from_ancestors(root, &top_level, 3).is_empty());
+        assert!(tags_from_ancestors(&service, &top_level, 3).is_empty());
+    }
+
+    #[test]
+    fn test_tags_from_ancestors_deduplicates_names() {
+        let root = Path::new("/work");
+        assert_eq!(
+            tags_from_ancestors(root, Path::new("/work/team/team/api"), 2),
+            vec!["team"]
+        );
+    }
+
     #[test]
     fn test_detect_tags_from_path_go() {
         let temp_dir = TempDir::new().unwrap();
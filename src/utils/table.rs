@@ -0,0 +1,271 @@
+//! Aligned tabular output
+//!
+//! Column widths are computed from the visible text of every cell, so colors
+//! never throw off the alignment. Rendering without color produces plain text
+//! suitable for pipes and log files.
+
+pub use colored::Color;
+
+/// Horizontal alignment of a column
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Default)]
+pub enum Align {
+    #[default]
+    Left,
+    Right,
+}
+
+/// A single table cell with an optional foreground color
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct Cell {
+    text: String,
+    color: Option<Color>,
+}
+
+impl Cell {
+    pub fn new(text: impl Into<String>) -> Self {
+        Self {
+            text: text.into(),
+            color: None,
+        }
+    }
+
+    pub fn colored(text: impl Into<String>, color: Color) -> Self {
+        Self {
+            text: text.into(),
+            color: Some(color),
+        }
+    }
+}
+
+impl From<&str> for Cell {
+    fn from(text: &str) -> Self {
+        Self::new(text)
+    }
+}
+
+impl From<String> for Cell {
+    fn from(text: String) -> Self {
+        Self::new(text)
+    }
+}
+
+/// Table with a header row and aligned columns
+#[derive(Debug, Clone, Default)]
+pub struct Table {
+    headers: Vec<String>,
+    align: Vec<Align>,
+    rows: Vec<Vec<Cell>>,
+    indent: usize,
+}
+
+impl Table {
+    pub fn new<I, S>(headers: I) -> Self
+    where
+        I: IntoIterator<Item = S>,
+        S: Into<String>,
+    {
+        let headers: Vec<String> = headers.into_iter().map(Into::into).collect();
+        Self {
+            align: vec![Align::Left; headers.len()],
+            headers,
+            ..Self::default()
+        }
+    }
+
+    /// Set the alignment of a column (e.g. right-align numbers)
+    pub fn align(mut self, column: usize, align: Align) -> Self {
+        if column >= self.align.len() {
+            self.align.resize(column + 1, Align::Left);
+        }
+        self.align[column] = align;
+        self
+    }
+
+    /// Indent every line by `indent` spaces
+    pub fn indent(mut self, indent: usize) -> Self {
+        self.indent = indent;
+        self
+    }
+
+    pub fn add_row<I, C>(&mut self, row: I)
+    where
+        I: IntoIterator<Item = C>,
+        C: Into<Cell>,
+    {
+        self.rows.push(row.into_iter().map(Into::into).collect());
+    }
+
+    pub fn is_empty(&self) -> bool {
+        self.rows.is_empty()
+    }
+
+    /// Render the table, with ANSI colors when `color` is true
+    ///
+    /// In plain mode any escape codes already present in cell text are
+    /// removed as well.
+    pub fn render(&self, color: bool) -> String {
+        let columns = self
+            .rows
+            .iter()
+            .map(Vec::len)
+            .chain(std::iter::once(self.headers.len()))
+            .max()
+            .unwrap_or(0);
+
+        let mut widths = vec![0; columns];
+        for (i, header) in self.headers.iter().enumerate() {
+            widths[i] = visible_width(header);
+        }
+        for row in &self.rows {
+            for (i, cell) in row.iter().enumerate() {
+                widths[i] = widths[i].max(visible_width(&cell.text));
+            }
+        }
+
+        let mut out = String::new();
+        if !self.headers.is_empty() {
+            let header: Vec<Cell> = self.headers.iter().map(|h| Cell::new(h.as_str())).collect();
+            self.render_line(&mut out, &header, &widths, color, true);
+        }
+        for row in &self.rows {
+            self.render_line(&mut out, row, &widths, color, false);
+        }
+        out
+    }
+
+    /// Render the table as [`print`](Self::print) shows it, e.g. to print it
+    /// later as part of a larger block
+    pub fn render_for_terminal(&self) -> String {
+        let color = colored::control::ShouldColorize::from_env().should_colorize();
+        self.render(color)
+    }
+
+    /// Print the table to stdout, coloring it when the terminal supports it
+    pub fn print(&self) {
+        print!("{}", self.render_for_terminal());
+    }
+
+    fn render_line(
+        &self,
+        out: &mut String,
+        cells: &[Cell],
+        widths: &[usize],
+        color: bool,
+        header: bool,
+    ) {
+        let mut line = " ".repeat(self.indent);
+        for (i, width) in widths.iter().enumerate() {
+            let cell = cells.get(i);
+            let text = cell.map(|c| strip_ansi(&c.text)).unwrap_or_default();
+            let padding = " ".repeat(width - visible_width(&text));
+            let align = self.align.get(i).copied().unwrap_or_default();
+            let last = i + 1 == widths.len();
+
+            if i > 0 {
+                line.push_str("  ");
+            }
+
+            let styled = match (color, header, cell.and_then(|c| c.color)) {
+                (true, true, _) => format!("\x1b[1m{}\x1b[0m", text),
+                (true, false, Some(c)) => format!("\x1b[{}m{}\x1b[0m", c.to_fg_str(), text),
+                _ => text,
+            };
+            match align {
+                Align::Left => {
+                    line.push_str(&styled);
+                    if !last {
+                        line.push_str(&padding);
+                    }
+                }
+                Align::Right => {
+                    line.push_str(&padding);
+                    line.push_str(&styled);
+                }
+            }
+        }
+        out.push_str(line.trim_end());
+        out.push('\n');
+    }
+}
+
+/// Number of characters displayed for `text`, ignoring ANSI escape codes
+fn visible_width(text: &str) -> usize {
+    strip_ansi(text).chars().count()
+}
+
+/// Remove ANSI SGR escape sequences (`ESC [ ... m`)
+pub fn strip_ansi(text: &str) -> String {
+    let mut out = String::with_capacity(text.len());
+    let mut chars = text.chars().peekable();
+    while let Some(c) = chars.next() {
+        if c == '\x1b' && chars.peek() == Some(&'[') {
+            chars.next();
+            for c in chars.by_ref() {
+                if c.is_ascii_alphabetic() {
+                    break;
+                }
+            }
+        } else {
+            out.push(c);
+        }
+    }
+    out
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn sample() -> Table {
+        let mut table = Table::new(["NAME", "STATUS", "SCORE"]).align(2, Align::Right);
+        table.add_row([
+            Cell::new("api"),
+            Cell::colored("ok", Color::Green),
+            Cell::new("100%"),
+        ]);
+        table.add_row([
+            Cell::new("payments-service"),
+            Cell::colored("failing", Color::Red),
+            Cell::new("7%"),
+        ]);
+        table
+    }
+
+    #[test]
+    fn test_render_aligns_columns() {
+        assert_eq!(
+            sample().render(false),
+            "NAME              STATUS   SCORE\n\
+             api               ok        100%\n\
+             payments-service  failing     7%\n"
+        );
+    }
+
+    #[test]
+    fn test_colored_render_keeps_alignment() {
+        let colored = sample().render(true);
+        assert!(colored.contains("\x1b[31mfailing\x1b[0m"));
+        assert!(colored.contains("\x1b[1mNAME\x1b[0m"));
+        assert_eq!(strip_ansi(&colored), sample().render(false));
+    }
+
+    #[test]
+    fn test_plain_mode_strips_color_codes() {
+        let mut table = Table::new(["NAME"]);
+        table.add_row(["\x1b[1;36mapi\x1b[0m"]);
+        table.add_row(["web"]);
+
+        let plain = table.render(false);
+        assert!(!plain.contains('\x1b'));
+        assert_eq!(plain, "NAME\napi\nweb\n");
+    }
+
+    #[test]
+    fn test_short_rows_and_indent() {
+        let mut table = Table::new(Vec::<String>::new()).indent(2);
+        table.add_row(["a", "bb", "c"]);
+        table.add_row(["long"]);
+        assert_eq!(table.render(false), "  a     bb  c\n  long\n");
+        assert!(!table.is_empty());
+    }
+}
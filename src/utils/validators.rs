@@ -18,6 +18,10 @@ pub enum ValidationError {
     EmptyRepositoryUrl(String),
     /// Repository URL format is invalid
     InvalidRepositoryUrl(String, String),
+    /// Repository timeout is not a valid duration
+    InvalidRepositoryTimeout(String, String),
+    /// Repository `clone_args` entry would not be passed to git as an option
+    InvalidCloneArg(String, String),
     /// Duplicate repository names found
     DuplicateRepositoryName(String),
     /// Recipe has no steps defined
@@ -47,6 +51,20 @@ impl std::fmt::Display for ValidationError {
             ValidationError::InvalidRepositoryUrl(name, url) => {
                 write!(f, "Repository '{}' has invalid URL: '{}'", name, url)
             }
+            ValidationError::InvalidRepositoryTimeout(name, timeout) => {
+                write!(
+                    f,
+                    "Repository '{}' has invalid timeout: '{}' (expected e.g. 90s, 10m, 1h)",
+                    name, timeout
+                )
+            }
+            ValidationError::InvalidCloneArg(name, arg) => {
+                write!(
+                    f,
+                    "Repository '{}' has invalid clone argument: '{}' (expected an option such as --filter=blob:none)",
+                    name, arg
+                )
+            }
             ValidationError::DuplicateRepositoryName(name) => {
                 write!(f, "Duplicate repository name: '{}'", name)
             }
@@ -145,6 +163,24 @@ pub fn validate_repository(repository: &Repository) -> Result<(), Vec<Validation
         ));
     }
 
+    if let Some(timeout) = &repository.timeout
+        && crate::utils::parse_duration(timeout).is_err()
+    {
+        errors.push(ValidationError::InvalidRepositoryTimeout(
+            repository.name.clone(),
+            timeout.clone(),
+        ));
+    }
+
+    for arg in &repository.clone_args {
+        if crate::git::check_clone_arg(arg).is_err() {
+            errors.push(ValidationError::InvalidCloneArg(
+                repository.name.clone(),
+                arg.clone(),
+            ));
+        }
+    }
+
     if errors.is_empty() {
         Ok(())
     } else {
@@ -256,14 +292,15 @@ mod tests {
         Recipe {
             name: name.to_string(),
             steps: steps.iter().map(|s| s.to_string()).collect(),
+            args: Vec::new(),
         }
     }
 
     #[test]
     fn test_validate_config_empty_repositories() {
         let config = Config {
-            repositories: vec![],
-            recipes: vec![],
+            version: None,
+            ..Default::default()
         };
 
         // Empty repositories should be allowed (config can be initialized empty)
@@ -278,6 +315,8 @@ mod tests {
                 "git@github.com:owner/repo1.git",
             )],
             recipes: vec![create_valid_recipe("recipe1", vec!["echo hello"])],
+            version: None,
+            ..Default::default()
         };
 
         assert!(validate_config(&config).is_ok());
@@ -352,6 +391,47 @@ mod tests {
         );
     }
 
+    #[test]
+    fn test_validate_repository_timeout() {
+        let mut repo = Repository::new(
+            "repo1".to_string(),
+            "git@github.com:owner/repo1.git".to_string(),
+        );
+        repo.timeout = Some("10m".to_string());
+        assert!(validate_repository(&repo).is_ok());
+
+        repo.timeout = Some("ten minutes".to_string());
+        let errors = validate_repository(&repo).unwrap_err();
+        assert_eq!(
+            errors,
+            vec![ValidationError::InvalidRepositoryTimeout(
+                "repo1".to_string(),
+                "ten minutes".to_string()
+            )]
+        );
+    }
+
+    #[test]
+    fn test_validate_repository_clone_args() {
+        let mut repo = Repository::new(
+            "repo1".to_string(),
+            "git@github.com:owner/repo1.git".to_string(),
+        );
+        repo.clone_args = vec!["--filter=blob:none".to_string(), "-q".to_string()];
+        assert!(validate_repository(&repo).is_ok());
+
+        repo.clone_args = vec!["--depth".to_string(), "1".to_string(), "--".to_string()];
+        let errors = validate_repository(&repo).unwrap_err();
+        assert_eq!(
+            errors,
+            vec![
+                ValidationError::InvalidCloneArg("repo1".to_string(), "--depth".to_string()),
+                ValidationError::InvalidCloneArg("repo1".to_string(), "1".to_string()),
+                ValidationError::InvalidCloneArg("repo1".to_string(), "--".to_string()),
+            ]
+        );
+    }
+
     #[test]
     fn test_validate_recipes_valid() {
         let recipes = vec![
@@ -381,6 +461,7 @@ mod tests {
         let recipe = Recipe {
             name: "".to_string(),
             steps: vec!["echo hello".to_string()],
+            args: Vec::new(),
         };
 
         let result = validate_recipe(&recipe);
@@ -398,6 +479,7 @@ mod tests {
         let recipe = Recipe {
             name: "recipe1".to_string(),
             steps: vec![],
+            args: Vec::new(),
         };
 
         let result = validate_recipe(&recipe);
@@ -0,0 +1,152 @@
+//! End-to-end tests for the embedding API
+
+use repos::git::{CloneOptions, CloneOutcome, PullOptions};
+use repos::{Config, RepoFilter, Repos, Repository};
+use std::fs;
+use std::path::Path;
+use std::process::Command;
+use tempfile::TempDir;
+
+fn git(dir: &Path, args: &[&str]) {
+    let status = Command::new("git")
+        .args(args)
+        .current_dir(dir)
+        .output()
+        .expect("Failed to run git")
+        .status;
+    assert!(status.success(), "git {:?} failed", args);
+}
+
+/// Create a repository with one commit to clone from
+fn create_origin(path: &Path) {
+    fs::create_dir_all(path).unwrap();
+    git(path, &["init", "-q"]);
+    git(path, &["config", "user.name", "Test User"]);
+    git(path, &["config", "user.email", "test@example.com"]);
+    fs::write(path.join("README.md"), "# Origin\n").unwrap();
+    git(path, &["add", "."]);
+    git(path, &["commit", "-q", "-m", "Initial commit"]);
+}
+
+/// Write a config with two cloned repositories under `root`
+fn write_workspace(root: &Path) -> String {
+    for name in ["api", "web"] {
+        create_origin(&root.join(name));
+    }
+    let config_path = root.join("repos.yaml");
+    fs::write(
+        &config_path,
+        "repositories:\n\
+         - name: api\n  url: git@github.com:owner/api.git\n  path: api\n  tags: [backend]\n\
+         - name: web\n  url: git@github.com:owner/web.git\n  path: web\n  tags: [frontend]\n",
+    )
+    .unwrap();
+    config_path.to_string_lossy().to_string()
+}
+
+#[test]
+fn test_load_and_select_by_tag() {
+    let temp_dir = TempDir::new().unwrap();
+    let config_path = write_workspace(temp_dir.path());
+
+    let repos = Repos::load(&config_path).unwrap();
+    assert_eq!(repos.repositories().len(), 2);
+
+    let backend = repos.select(&RepoFilter::tagged(["backend"]));
+    assert_eq!(backend.len(), 1);
+    assert_eq!(backend[0].name, "api");
+    // Relative paths resolve against the config file's directory
+    assert_eq!(
+        Path::new(&backend[0].get_target_dir()),
+        temp_dir.path().join("api")
+    );
+
+    assert!(Repos::load(temp_dir.path().join("missing.yaml")).is_err());
+}
+
+#[tokio::test]
+async fn test_run_captures_output_per_repository() {
+    let temp_dir = TempDir::new().unwrap();
+    let config_path = write_workspace(temp_dir.path());
+    let repos = Repos::load(&config_path).unwrap();
+
+    let selected = repos.select(&RepoFilter::default());
+    let outputs = repos.run(&selected, "cat README.md; exit 3").await;
+
+    assert_eq!(outputs.len(), 2);
+    for output in &outputs {
+        let run = output.result.as_ref().unwrap();
+        assert_eq!(run.stdout.trim(), "# Origin");
+        assert_eq!(run.exit_code, 3);
+        assert!(!run.success());
+    }
+    assert_eq!(outputs[0].repository, "api");
+    assert_eq!(outputs[1].repository, "web");
+}
+
+#[tokio::test]
+async fn test_run_reports_uncloned_repository_as_error() {
+    let temp_dir = TempDir::new().unwrap();
+    let mut repo = Repository::new(
+        "ghost".to_string(),
+        "git@github.com:owner/ghost.git".to_string(),
+    );
+    repo.path = Some(temp_dir.path().join("ghost").to_string_lossy().to_string());
+    let repos = Repos::from_config(Config {
+        repositories: vec![repo],
+        ..Config::default()
+    });
+
+    let outputs = repos.run(repos.repositories(), "true").await;
+    assert_eq!(outputs.len(), 1);
+    assert!(outputs[0].result.is_err());
+}
+
+#[tokio::test]
+async fn test_run_applies_the_run_policy() {
+    let temp_dir = TempDir::new().unwrap();
+    let config_path = write_workspace(temp_dir.path());
+    let mut config = Config::load(&config_path).unwrap();
+    config.run_policy.deny = vec![r"^touch\b".to_string()];
+    let repos = Repos::from_config(config);
+
+    let outputs = repos.run(repos.repositories(), "touch ran").await;
+    assert_eq!(outputs.len(), 2);
+    for output in &outputs {
+        let err = output.result.as_ref().unwrap_err();
+        assert!(err.to_string().contains("denied by run_policy"), "{err}");
+    }
+    assert!(!temp_dir.path().join("api/ran").exists());
+}
+
+#[test]
+fn test_clone_pull_and_health_against_local_origin() {
+    let temp_dir = TempDir::new().unwrap();
+    let origin = temp_dir.path().join("origin");
+    create_origin(&origin);
+
+    let mut repo = Repository::new("app".to_string(), origin.to_string_lossy().to_string());
+    repo.path = Some(temp_dir.path().join("app").to_string_lossy().to_string());
+    let repos = Repos::from_config(Config {
+        repositories: vec![repo],
+        ..Config::default()
+    });
+
+    let cloned = repos.clone_repositories(repos.repositories(), CloneOptions::default());
+    assert_eq!(cloned.len(), 1);
+    assert_eq!(cloned[0].result.as_ref().unwrap(), &CloneOutcome::Cloned);
+    assert!(temp_dir.path().join("app/README.md").exists());
+
+    fs::write(origin.join("CHANGELOG.md"), "# Changes\n").unwrap();
+    git(&origin, &["add", "."]);
+    git(&origin, &["commit", "-q", "-m", "Add changelog"]);
+
+    let pulled = repos.pull_repositories(repos.repositories(), PullOptions::default());
+    assert!(pulled[0].result.is_ok(), "{:?}", pulled[0].result);
+    assert!(temp_dir.path().join("app/CHANGELOG.md").exists());
+
+    let reports = repos.health(repos.repositories());
+    assert_eq!(reports.len(), 1);
+    assert_eq!(reports[0].repository, "app");
+    assert!(!reports[0].results.is_empty());
+}
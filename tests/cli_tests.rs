@@ -1,13 +1,12 @@
 //! CLI argument parsing integration tests
 
 use std::env;
-use std::path::PathBuf;
+use std::path::{Path, PathBuf};
 use std::process::Command;
 use tempfile::TempDir;
 
 /// Helper struct for creating temporary test workspaces
 struct Workspace {
-    #[allow(dead_code)]
     root: TempDir,
     config_path: PathBuf,
 }
@@ -201,3 +200,2701 @@ repositories:
     assert_eq!(output.status, 0);
     assert!(output.stdout.contains("No repositories") || output.stdout.is_empty());
 }
+
+#[test]
+fn test_report_file_written_for_run() {
+    let ws = Workspace::new();
+    let repo_dir = ws.root.path().join("test-repo");
+    std::fs::create_dir_all(&repo_dir).expect("Failed to create repo dir");
+    ws.write_config(&format!(
+        r#"
+repositories:
+  - name: test-repo
+    url: https://github.com/test/repo
+    tags: [backend]
+    path: {}
+"#,
+        repo_dir.display()
+    ));
+    let report_path = ws.root.path().join("reports/run.json");
+
+    let output = run_cli(&[
+        "run",
+        "echo hello",
+        "--no-save",
+        "--config",
+        ws.config_str(),
+        "--report-file",
+        report_path.to_str().unwrap(),
+    ]);
+    assert_eq!(output.status, 0, "stderr: {}", output.stderr);
+
+    let content = std::fs::read_to_string(&report_path).expect("Report file should exist");
+    let report: serde_json::Value = serde_json::from_str(&content).expect("Report should be JSON");
+
+    assert_eq!(report["command"], "run");
+    assert_eq!(report["options"]["command"], "echo hello");
+    assert_eq!(report["success"], true);
+    assert!(report["started_at"].is_string());
+    assert!(report["finished_at"].is_string());
+    assert!(report["duration_ms"].is_u64());
+
+    let repositories = report["repositories"].as_array().unwrap();
+    assert_eq!(repositories.len(), 1);
+    assert_eq!(repositories[0]["name"], "test-repo");
+    assert_eq!(repositories[0]["success"], true);
+}
+
+#[test]
+fn test_report_file_written_on_failure() {
+    let ws = Workspace::new();
+    let report_path = ws.root.path().join("report.json");
+
+    let output = run_cli(&[
+        "ls",
+        "--config",
+        "does-not-exist.yaml",
+        "--report-file",
+        report_path.to_str().unwrap(),
+    ]);
+    assert_ne!(output.status, 0);
+
+    let content = std::fs::read_to_string(&report_path).expect("Report file should exist");
+    let report: serde_json::Value = serde_json::from_str(&content).expect("Report should be JSON");
+
+    assert_eq!(report["command"], "ls");
+    assert_eq!(report["success"], false);
+    assert!(report["error"].is_string());
+}
+
+#[test]
+fn test_on_result_receives_each_outcome_as_json() {
+    let (ws, _, web_dir) = two_repo_workspace();
+    std::fs::write(web_dir.join("broken"), "").unwrap();
+    let captured = ws.root.path().join("results.jsonl");
+
+    let output = run_cli(&[
+        "run",
+        "test ! -e broken",
+        "--no-save",
+        "--config",
+        ws.config_str(),
+        "--on-result",
+        &format!("cat >> '{}'", captured.display()),
+    ]);
+    assert_ne!(output.status, 0);
+
+    let results: Vec<serde_json::Value> = std::fs::read_to_string(&captured)
+        .unwrap()
+        .lines()
+        .map(|line| serde_json::from_str(line).unwrap())
+        .collect();
+    assert_eq!(results.len(), 2);
+    assert_eq!(results[0]["name"], "api");
+    assert_eq!(results[0]["success"], true);
+    assert!(results[0]["duration_ms"].is_u64());
+    assert_eq!(results[1]["name"], "web");
+    assert_eq!(results[1]["success"], false);
+    assert!(results[1]["error"].is_string());
+}
+
+#[test]
+fn test_command_retries_rerun_flaky_commands() {
+    let (ws, api_dir, web_dir) = two_repo_workspace();
+    // Fails once in every repository, then passes
+    let flaky = "echo run >> attempts.txt; test -e passed-once || { touch passed-once; exit 3; }";
+
+    let output = run_cli(&[
+        "run",
+        flaky,
+        "--no-save",
+        "--command-retries",
+        "2",
+        "--command-retry-on",
+        "3",
+        "--config",
+        ws.config_str(),
+    ]);
+    assert_eq!(output.status, 0, "stderr: {}", output.stderr);
+    assert!(
+        output
+            .stdout
+            .contains("Attempt 1 of 3 failed with exit code 3; retrying"),
+        "stdout: {}",
+        output.stdout
+    );
+    for dir in [&api_dir, &web_dir] {
+        let attempts = std::fs::read_to_string(dir.join("attempts.txt")).unwrap();
+        assert_eq!(attempts.lines().count(), 2);
+    }
+
+    let output = run_cli(&[
+        "run",
+        "true",
+        "--command-retry-on",
+        "3",
+        "--config",
+        ws.config_str(),
+    ]);
+    assert_ne!(output.status, 0);
+}
+
+#[test]
+fn test_unreachable_notify_url_keeps_exit_code() {
+    let (ws, _, _) = two_repo_workspace();
+    // Nothing listens on a port that was just released
+    let port = std::net::TcpListener::bind("127.0.0.1:0")
+        .unwrap()
+        .local_addr()
+        .unwrap()
+        .port();
+
+    let output = run_cli(&[
+        "run",
+        "true",
+        "--no-save",
+        "--config",
+        ws.config_str(),
+        "--notify-url",
+        &format!("http://127.0.0.1:{}/hook", port),
+    ]);
+    assert_eq!(output.status, 0, "stderr: {}", output.stderr);
+    assert!(
+        output.stderr.contains("Failed to send notification"),
+        "stderr: {}",
+        output.stderr
+    );
+
+    let output = run_cli(&["ls", "--notify-on", "failure", "--config", ws.config_str()]);
+    assert_ne!(output.status, 0);
+    assert!(output.stderr.contains("--notify-url"), "{}", output.stderr);
+}
+
+#[test]
+fn test_run_named_uses_repository_commands() {
+    let ws = Workspace::new();
+    let custom_dir = ws.root.path().join("custom");
+    let plain_dir = ws.root.path().join("plain");
+    std::fs::create_dir_all(&custom_dir).expect("Failed to create repo dir");
+    std::fs::create_dir_all(&plain_dir).expect("Failed to create repo dir");
+    ws.write_config(&format!(
+        r#"
+repositories:
+  - name: custom
+    url: https://github.com/test/custom
+    tags: [backend]
+    path: {}
+    commands:
+      build: echo custom > built.txt
+  - name: plain
+    url: https://github.com/test/plain
+    tags: [backend]
+    path: {}
+"#,
+        custom_dir.display(),
+        plain_dir.display()
+    ));
+
+    let output = run_cli(&[
+        "run",
+        "--named",
+        "build",
+        "echo default > built.txt",
+        "--no-save",
+        "--config",
+        ws.config_str(),
+    ]);
+    assert_eq!(output.status, 0, "stderr: {}", output.stderr);
+
+    let built = |dir: &std::path::Path| std::fs::read_to_string(dir.join("built.txt")).unwrap();
+    assert_eq!(built(&custom_dir).trim(), "custom");
+    assert_eq!(built(&plain_dir).trim(), "default");
+}
+
+#[test]
+fn test_run_timeout_per_repository_override() {
+    let ws = Workspace::new();
+    let patient_dir = ws.root.path().join("patient");
+    let hasty_dir = ws.root.path().join("hasty");
+    std::fs::create_dir_all(&patient_dir).unwrap();
+    std::fs::create_dir_all(&hasty_dir).unwrap();
+    ws.write_config(&format!(
+        r#"
+repositories:
+  - name: patient
+    url: https://github.com/test/patient
+    tags: []
+    path: {}
+    timeout: 30s
+  - name: hasty
+    url: https://github.com/test/hasty
+    tags: []
+    path: {}
+"#,
+        patient_dir.display(),
+        hasty_dir.display()
+    ));
+    let report_path = ws.root.path().join("report.json");
+
+    let output = run_cli(&[
+        "run",
+        "-p",
+        "--timeout",
+        "1s",
+        "--no-save",
+        "--report-file",
+        report_path.to_str().unwrap(),
+        "--config",
+        ws.config_str(),
+        "sleep 3 && touch done",
+    ]);
+    assert!(
+        output.stderr.contains("Timed out after 1s"),
+        "stderr: {}",
+        output.stderr
+    );
+
+    // The repository with the longer override finishes; the other one is killed
+    assert!(patient_dir.join("done").exists());
+    assert!(!hasty_dir.join("done").exists());
+
+    let report: serde_json::Value =
+        serde_json::from_str(&std::fs::read_to_string(&report_path).unwrap()).unwrap();
+    let outcome = |name: &str| {
+        report["repositories"]
+            .as_array()
+            .unwrap()
+            .iter()
+            .find(|r| r["name"] == name)
+            .unwrap()
+            .clone()
+    };
+    assert_eq!(outcome("patient")["success"], true);
+    assert_eq!(outcome("hasty")["error"], "Timed out after 1s");
+}
+
+#[test]
+fn test_run_allow_exit_codes() {
+    let ws = Workspace::new();
+    let mut paths = Vec::new();
+    for (name, code) in [("clean", 0), ("differs", 1), ("broken", 2)] {
+        let dir = ws.root.path().join(name);
+        std::fs::create_dir_all(&dir).unwrap();
+        std::fs::write(dir.join("code"), code.to_string()).unwrap();
+        paths.push(dir);
+    }
+    ws.write_config(&format!(
+        r#"
+repositories:
+  - name: clean
+    url: https://github.com/test/clean
+    tags: []
+    path: {}
+  - name: differs
+    url: https://github.com/test/differs
+    tags: []
+    path: {}
+  - name: broken
+    url: https://github.com/test/broken
+    tags: []
+    path: {}
+"#,
+        paths[0].display(),
+        paths[1].display(),
+        paths[2].display()
+    ));
+    let report_path = ws.root.path().join("report.json");
+    let success = |args: &[&str]| {
+        let mut full = vec![
+            "run",
+            "-p",
+            "--no-save",
+            "--report-file",
+            report_path.to_str().unwrap(),
+            "--config",
+            ws.config_str(),
+        ];
+        full.extend_from_slice(args);
+        full.push("exit $(cat code)");
+        run_cli(&full);
+
+        let report: serde_json::Value =
+            serde_json::from_str(&std::fs::read_to_string(&report_path).unwrap()).unwrap();
+        ["clean", "differs", "broken"].map(|name| {
+            report["repositories"]
+                .as_array()
+                .unwrap()
+                .iter()
+                .find(|r| r["name"] == name)
+                .unwrap()["success"]
+                .as_bool()
+                .unwrap()
+        })
+    };
+
+    assert_eq!(success(&[]), [true, false, false]);
+    assert_eq!(success(&["--strict"]), [true, false, false]);
+    assert_eq!(success(&["--allow-exit-codes", "0,1"]), [true, true, false]);
+
+    let output = run_cli(&[
+        "run",
+        "--allow-exit-codes",
+        "0,300",
+        "--config",
+        ws.config_str(),
+        "true",
+    ]);
+    assert_ne!(output.status, 0);
+    assert!(
+        output
+            .stderr
+            .contains("exit codes must be between 0 and 255"),
+        "stderr: {}",
+        output.stderr
+    );
+}
+
+#[test]
+fn test_run_output_patterns_decide_the_exit_code() {
+    let (ws, _api_dir, _web_dir) = two_repo_workspace();
+    let run = |args: &[&str]| {
+        let mut full = vec!["run", "--no-save", "--config", ws.config_str()];
+        full.extend_from_slice(args);
+        run_cli(&full)
+    };
+
+    let output = run(&["--success-if", "^up to date", "echo up to date; exit 3"]);
+    assert_eq!(output.status, 0, "stderr: {}", output.stderr);
+
+    let output = run(&["--failure-if", "(?i)error", "echo Error: bad input"]);
+    assert_ne!(output.status, 0);
+
+    let output = run(&["--failure-if", "[", "true"]);
+    assert_ne!(output.status, 0);
+    assert!(
+        output.stderr.contains("Invalid --failure-if pattern '['"),
+        "stderr: {}",
+        output.stderr
+    );
+
+    let output = run(&["--success-if", "ok", "--allow-exit-codes", "0,1", "true"]);
+    assert_ne!(output.status, 0);
+}
+
+#[test]
+fn test_run_ordered_output_follows_config_order() {
+    let ws = Workspace::new();
+    // The first repository finishes last and the last one first
+    let names = ["first", "second", "third"];
+    let mut config = String::from("repositories:\n");
+    for (i, name) in names.iter().enumerate() {
+        let dir = ws.root.path().join(name);
+        std::fs::create_dir_all(&dir).unwrap();
+        std::fs::write(dir.join("delay"), format!("0.{}", 6 - 3 * i)).unwrap();
+        config.push_str(&format!(
+            "  - name: {name}\n    url: https://github.com/test/{name}\n    tags: []\n    path: {}\n",
+            dir.display()
+        ));
+    }
+    ws.write_config(&config);
+
+    let output = run_cli(&[
+        "run",
+        "-p",
+        "--ordered-output",
+        "--no-save",
+        "--config",
+        ws.config_str(),
+        "sleep $(cat delay)",
+    ]);
+    assert_eq!(output.status, 0, "stderr: {}", output.stderr);
+
+    // Every line of a repository is printed before any line of the next one
+    let repo_lines: Vec<&str> = output
+        .stdout
+        .lines()
+        .filter_map(|line| line.split_once(" | ").map(|(name, _)| name))
+        .collect();
+    assert_eq!(
+        repo_lines,
+        vec!["first", "first", "second", "second", "third", "third"],
+        "stdout: {}",
+        output.stdout
+    );
+}
+
+#[test]
+fn test_active_since_skips_stale_and_missing_repos() {
+    let ws = Workspace::new();
+    let recent_dir = ws.root.path().join("recent");
+    let stale_dir = ws.root.path().join("stale");
+    for (dir, date) in [
+        (&recent_dir, chrono::Utc::now().to_rfc3339()),
+        (&stale_dir, "2020-01-01T00:00:00+00:00".to_string()),
+    ] {
+        std::fs::create_dir_all(dir).unwrap();
+        for args in [
+            vec!["init", "-q"],
+            vec![
+                "-c",
+                "user.name=Test",
+                "-c",
+                "user.email=test@example.com",
+                "commit",
+                "-q",
+                "--allow-empty",
+                "-m",
+                "init",
+            ],
+        ] {
+            let status = std::process::Command::new("git")
+                .args(&args)
+                .env("GIT_AUTHOR_DATE", &date)
+                .env("GIT_COMMITTER_DATE", &date)
+                .current_dir(dir)
+                .status()
+                .unwrap();
+            assert!(status.success());
+        }
+    }
+    ws.write_config(&format!(
+        r#"
+repositories:
+  - name: recent
+    url: https://github.com/test/recent
+    tags: [backend]
+    path: {}
+  - name: stale
+    url: https://github.com/test/stale
+    tags: [backend]
+    path: {}
+  - name: missing
+    url: https://github.com/test/missing
+    tags: [backend]
+    path: {}
+"#,
+        recent_dir.display(),
+        stale_dir.display(),
+        ws.root.path().join("missing").display()
+    ));
+
+    let output = run_cli(&[
+        "run",
+        "touch ran.txt",
+        "--no-save",
+        "--active-since",
+        "30d",
+        "--config",
+        ws.config_str(),
+    ]);
+    assert_eq!(output.status, 0, "stderr: {}", output.stderr);
+
+    assert!(recent_dir.join("ran.txt").exists());
+    assert!(!stale_dir.join("ran.txt").exists());
+    assert!(
+        output
+            .stderr
+            .contains("Skipped by --active-since (last commit 2020-01-01")
+    );
+    assert!(
+        output
+            .stderr
+            .contains("Skipped by --active-since (not cloned)")
+    );
+}
+
+#[test]
+fn test_only_cloned_and_only_missing_filter_by_presence() {
+    let ws = Workspace::new();
+    let names = ["api", "web", "docs", "tools"];
+    let mut config = String::from("repositories:\n");
+    for name in names {
+        config.push_str(&format!(
+            "  - name: {name}\n    url: https://github.com/test/{name}\n    tags: [{}]\n    path: {}\n",
+            if name == "tools" { "internal" } else { "backend" },
+            ws.root.path().join(name).display()
+        ));
+    }
+    ws.write_config(&config);
+    // api and tools are on disk; web and docs are not
+    std::fs::create_dir_all(ws.root.path().join("api")).unwrap();
+    std::fs::create_dir_all(ws.root.path().join("tools")).unwrap();
+
+    let listed = |flag: &str| {
+        let output = run_cli(&[
+            "ls",
+            flag,
+            "--tag",
+            "backend",
+            "--json",
+            "--config",
+            ws.config_str(),
+        ]);
+        assert_eq!(output.status, 0, "stderr: {}", output.stderr);
+        let listed: serde_json::Value = serde_json::from_str(&output.stdout).unwrap();
+        let names: Vec<String> = listed
+            .as_array()
+            .unwrap()
+            .iter()
+            .map(|repo| repo["name"].as_str().unwrap().to_string())
+            .collect();
+        (names, output.stderr)
+    };
+
+    let (names, stderr) = listed("--only-cloned");
+    assert_eq!(names, vec!["api"]);
+    assert!(stderr.contains("Skipped by --only-cloned (not cloned)"));
+
+    let (names, stderr) = listed("--only-missing");
+    assert_eq!(names, vec!["web", "docs"]);
+    assert!(stderr.contains("Skipped by --only-missing (already cloned at "));
+
+    let output = run_cli(&[
+        "ls",
+        "--only-cloned",
+        "--only-missing",
+        "--config",
+        ws.config_str(),
+    ]);
+    assert_ne!(output.status, 0);
+    assert!(output.stderr.contains("cannot be used with"));
+}
+
+#[test]
+fn test_repo_flag_targets_names_and_aliases_ignoring_tags() {
+    let ws = Workspace::new();
+    ws.write_config(
+        r#"
+repositories:
+  - name: api-gateway
+    url: https://github.com/test/api-gateway
+    tags: [backend]
+    alias: gw
+  - name: web-frontend
+    url: https://github.com/test/web-frontend
+    tags: [frontend]
+    aliases: [web, ui]
+  - name: billing-worker
+    url: https://github.com/test/billing-worker
+    tags: [backend]
+    aliases: [ui]
+"#,
+    );
+
+    let listed = |args: &[&str]| {
+        let mut full = vec!["ls", "--json", "--config", ws.config_str()];
+        full.extend_from_slice(args);
+        let output = run_cli(&full);
+        let names: Vec<String> = serde_json::from_str::<serde_json::Value>(&output.stdout)
+            .map(|listed| {
+                listed
+                    .as_array()
+                    .unwrap()
+                    .iter()
+                    .map(|repo| repo["name"].as_str().unwrap().to_string())
+                    .collect()
+            })
+            .unwrap_or_default();
+        (output, names)
+    };
+
+    // --tag frontend would exclude both; --repo bypasses it
+    let (output, names) = listed(&[
+        "--repo",
+        "gw",
+        "--repo",
+        "billing-worker",
+        "--tag",
+        "frontend",
+    ]);
+    assert_eq!(output.status, 0, "stderr: {}", output.stderr);
+    assert_eq!(names, vec!["api-gateway", "billing-worker"]);
+
+    let (output, _) = listed(&["--repo", "ui"]);
+    assert_ne!(output.status, 0);
+    assert!(
+        output
+            .stderr
+            .contains("Ambiguous repository or alias 'ui' (matches web-frontend, billing-worker)"),
+        "stderr: {}",
+        output.stderr
+    );
+
+    let (output, _) = listed(&["--repo", "nope"]);
+    assert_ne!(output.status, 0);
+    assert!(output.stderr.contains("Unknown repository or alias 'nope'"));
+}
+
+#[test]
+fn test_rerun_failed_targets_failed_repositories_ignoring_tags() {
+    let ws = Workspace::new();
+    ws.write_config(
+        r#"
+repositories:
+  - name: api
+    url: https://github.com/test/api
+    tags: [backend]
+  - name: billing
+    url: https://github.com/test/billing
+    tags: [backend]
+  - name: worker
+    url: https://github.com/test/worker
+    tags: [backend]
+  - name: legacy
+    url: https://github.com/test/legacy
+    tags: [archive]
+"#,
+    );
+    let report =
+        Path::new(env!("CARGO_MANIFEST_DIR")).join("tests/fixtures/reports/failed-run.json");
+
+    // The fixture's failures are billing and legacy; --tag backend would drop legacy
+    let output = run_cli(&[
+        "ls",
+        "--json",
+        "--tag",
+        "backend",
+        "--rerun-failed",
+        report.to_str().unwrap(),
+        "--config",
+        ws.config_str(),
+    ]);
+    assert_eq!(output.status, 0, "stderr: {}", output.stderr);
+    let listed: serde_json::Value = serde_json::from_str(&output.stdout).unwrap();
+    let names: Vec<&str> = listed
+        .as_array()
+        .unwrap()
+        .iter()
+        .map(|repo| repo["name"].as_str().unwrap())
+        .collect();
+    assert_eq!(names, vec!["billing", "legacy"]);
+
+    let output = run_cli(&[
+        "ls",
+        "--repo",
+        "api",
+        "--rerun-failed",
+        report.to_str().unwrap(),
+        "--config",
+        ws.config_str(),
+    ]);
+    assert_ne!(output.status, 0);
+
+    let output = run_cli(&[
+        "ls",
+        "--rerun-failed",
+        ws.root.path().join("missing.json").to_str().unwrap(),
+        "--config",
+        ws.config_str(),
+    ]);
+    assert_ne!(output.status, 0);
+    assert!(output.stderr.contains("Failed to read report file"));
+}
+
+#[test]
+fn test_repo_file_intersects_with_tag_filters() {
+    let ws = Workspace::new();
+    ws.write_config(
+        r#"
+repositories:
+  - name: api
+    url: https://github.com/test/api
+    tags: [backend]
+  - name: billing
+    url: https://github.com/test/billing
+    tags: [backend]
+  - name: web
+    url: https://github.com/test/web
+    tags: [frontend]
+"#,
+    );
+    let list = ws.root.path().join("targets.txt");
+    std::fs::write(
+        &list,
+        "# from the dependency scanner
+web
+billing
+ghost
+",
+    )
+    .unwrap();
+    let list = list.to_str().unwrap();
+    let listed_names = |stdout: &str| -> Vec<String> {
+        let listed: serde_json::Value = serde_json::from_str(stdout).unwrap();
+        listed
+            .as_array()
+            .unwrap()
+            .iter()
+            .map(|repo| repo["name"].as_str().unwrap().to_string())
+            .collect()
+    };
+
+    let output = run_cli(&[
+        "ls",
+        "--json",
+        "--repo-file",
+        list,
+        "--config",
+        ws.config_str(),
+    ]);
+    assert_ne!(output.status, 0);
+    assert!(output.stderr.contains("ghost"), "stderr: {}", output.stderr);
+
+    let output = run_cli(&[
+        "ls",
+        "--json",
+        "--repo-file",
+        list,
+        "--ignore-missing",
+        "--config",
+        ws.config_str(),
+    ]);
+    assert_eq!(output.status, 0, "stderr: {}", output.stderr);
+    assert_eq!(listed_names(&output.stdout), vec!["billing", "web"]);
+
+    let output = run_cli(&[
+        "ls",
+        "--json",
+        "--tag",
+        "backend",
+        "--repo-file",
+        list,
+        "--ignore-missing",
+        "--config",
+        ws.config_str(),
+    ]);
+    assert_eq!(output.status, 0, "stderr: {}", output.stderr);
+    assert_eq!(listed_names(&output.stdout), vec!["billing"]);
+}
+
+#[test]
+fn test_host_filter_selects_repositories_by_remote_host() {
+    let ws = Workspace::new();
+    ws.write_config(
+        r#"
+repositories:
+  - name: api
+    url: git@github.com:test/api.git
+    tags: [backend]
+  - name: infra
+    url: https://gitlab.com/test/infra.git
+    tags: [ops]
+  - name: billing
+    url: git@bitbucket.org:test/billing.git
+    tags: [backend]
+  - name: web
+    url: https://github.com/test/web
+    tags: [frontend]
+"#,
+    );
+    let listed = |args: &[&str]| -> Vec<String> {
+        let mut full = vec!["ls", "--json", "--config", ws.config_str()];
+        full.extend_from_slice(args);
+        let output = run_cli(&full);
+        assert_eq!(output.status, 0, "stderr: {}", output.stderr);
+        serde_json::from_str::<serde_json::Value>(&output.stdout)
+            .unwrap()
+            .as_array()
+            .unwrap()
+            .iter()
+            .map(|repo| repo["name"].as_str().unwrap().to_string())
+            .collect()
+    };
+
+    assert_eq!(listed(&["--host", "github.com"]), vec!["api", "web"]);
+    assert_eq!(
+        listed(&["--host", "gitlab.com,bitbucket.org"]),
+        vec!["infra", "billing"]
+    );
+    assert_eq!(
+        listed(&["--host", "github.com,bitbucket.org", "--tag", "backend"]),
+        vec!["api", "billing"]
+    );
+}
+
+#[test]
+fn test_count_applies_every_filter() {
+    let ws = Workspace::new();
+    ws.write_config(
+        r#"
+repositories:
+  - name: api
+    url: git@github.com:test/api.git
+    tags: [backend, rust]
+  - name: infra
+    url: https://gitlab.com/test/infra.git
+    tags: [ops]
+  - name: billing
+    url: git@bitbucket.org:test/billing.git
+    tags: [backend]
+  - name: web
+    url: https://github.com/test/web
+    tags: [frontend]
+"#,
+    );
+    let count = |args: &[&str]| -> String {
+        let mut full = vec!["count", "--config", ws.config_str()];
+        full.extend_from_slice(args);
+        let output = run_cli(&full);
+        assert_eq!(output.status, 0, "stderr: {}", output.stderr);
+        output.stdout.trim_end().to_string()
+    };
+
+    assert_eq!(count(&[]), "4");
+    assert_eq!(count(&["--tag", "backend"]), "2");
+    assert_eq!(count(&["--tag", "backend", "--exclude-tag", "rust"]), "1");
+    assert_eq!(count(&["--host", "github.com"]), "2");
+    assert_eq!(count(&["--tags-all", "backend,rust"]), "1");
+    assert_eq!(count(&["--limit", "3", "--offset", "2"]), "2");
+    assert_eq!(count(&["api", "web"]), "2");
+    // Matching nothing is not an error
+    assert_eq!(count(&["--tag", "mobile"]), "0");
+
+    assert_eq!(
+        count(&["--list", "--host", "github.com,gitlab.com", "-e", "ops"]),
+        "2\napi\nweb"
+    );
+}
+
+#[test]
+fn test_selection_files_narrow_the_working_set() {
+    let ws = Workspace::new();
+    let fixtures = Path::new(env!("CARGO_MANIFEST_DIR")).join("tests/fixtures/selection");
+    for file in ["repos.yaml", ".reposinclude", ".reposexclude"] {
+        std::fs::copy(fixtures.join(file), ws.root.path().join(file)).unwrap();
+    }
+
+    let listed = |args: &[&str]| {
+        let mut full = vec!["ls", "--json", "--config", ws.config_str()];
+        full.extend_from_slice(args);
+        let output = run_cli(&full);
+        assert_eq!(output.status, 0, "stderr: {}", output.stderr);
+        serde_json::from_str::<serde_json::Value>(&output.stdout)
+            .unwrap()
+            .as_array()
+            .unwrap()
+            .iter()
+            .map(|repo| repo["name"].as_str().unwrap().to_string())
+            .collect::<Vec<_>>()
+    };
+
+    assert_eq!(listed(&[]), vec!["api", "web", "sandbox-shared"]);
+    // CLI filters apply on top of the files
+    assert_eq!(
+        listed(&["--tag", "frontend"]),
+        vec!["web", "sandbox-shared"]
+    );
+    // --repo names repositories explicitly, even excluded ones
+    assert_eq!(listed(&["--repo", "api-legacy"]), vec!["api-legacy"]);
+}
+
+#[test]
+fn test_tags_any_all_and_none_combine() {
+    let ws = Workspace::new();
+    ws.write_config(
+        r#"
+repositories:
+  - name: api
+    url: https://github.com/test/api
+    tags: [backend, rust, team-a]
+  - name: worker
+    url: https://github.com/test/worker
+    tags: [backend, go, team-a]
+  - name: web
+    url: https://github.com/test/web
+    tags: [frontend, team-b]
+  - name: legacy
+    url: https://github.com/test/legacy
+    tags: [backend, rust, deprecated]
+"#,
+    );
+
+    let listed = |args: &[&str]| {
+        let mut full = vec!["ls", "--json", "--config", ws.config_str()];
+        full.extend_from_slice(args);
+        let output = run_cli(&full);
+        assert_eq!(output.status, 0, "stderr: {}", output.stderr);
+        serde_json::from_str::<serde_json::Value>(&output.stdout)
+            .unwrap()
+            .as_array()
+            .unwrap()
+            .iter()
+            .map(|repo| repo["name"].as_str().unwrap().to_string())
+            .collect::<Vec<_>>()
+    };
+
+    assert_eq!(
+        listed(&["--tags-any", "go,frontend"]),
+        vec!["worker", "web"]
+    );
+    // --tag is the same OR group as --tags-any
+    assert_eq!(
+        listed(&["--tag", "go", "--tags-any", "frontend"]),
+        vec!["worker", "web"]
+    );
+    assert_eq!(
+        listed(&["--tags-all", "backend,rust"]),
+        vec!["api", "legacy"]
+    );
+    // legacy has rust and backend but is excluded; worker is backend but not rust
+    assert_eq!(
+        listed(&[
+            "--tags-any",
+            "rust",
+            "--tags-all",
+            "backend",
+            "--tags-none",
+            "deprecated"
+        ]),
+        vec!["api"]
+    );
+    assert_eq!(listed(&["--tags-all", "team-a", "-e", "go"]), vec!["api"]);
+    // --repo ignores tag filters
+    assert_eq!(
+        listed(&["--tags-all", "frontend", "--repo", "legacy"]),
+        vec!["legacy"]
+    );
+
+    let output = run_cli(&[
+        "config",
+        "migrate",
+        "--config",
+        ws.config_str(),
+        "--tags-all",
+        "backend",
+    ]);
+    assert_ne!(output.status, 0);
+    assert!(output.stderr.contains("--tags-all is not supported"));
+}
+
+#[test]
+fn test_run_and_clone_skip_archived_repos_unless_included() {
+    let ws = Workspace::new();
+    let active_dir = ws.root.path().join("active");
+    let legacy_dir = ws.root.path().join("legacy");
+    std::fs::create_dir_all(&active_dir).unwrap();
+    std::fs::create_dir_all(&legacy_dir).unwrap();
+    ws.write_config(&format!(
+        r#"
+repositories:
+  - name: active
+    url: https://github.com/test/active
+    tags: [backend]
+    path: {}
+  - name: legacy
+    url: https://github.com/test/legacy
+    tags: [reference]
+    path: {}
+    archived: true
+"#,
+        active_dir.display(),
+        legacy_dir.display()
+    ));
+
+    let output = run_cli(&[
+        "run",
+        "--no-save",
+        "--config",
+        ws.config_str(),
+        "touch ran.txt",
+    ]);
+    assert_eq!(output.status, 0, "stderr: {}", output.stderr);
+    assert!(active_dir.join("ran.txt").exists());
+    assert!(!legacy_dir.join("ran.txt").exists());
+    assert!(
+        output
+            .stderr
+            .contains("Skipping 1 archived repositories (legacy)"),
+        "stderr: {}",
+        output.stderr
+    );
+    assert!(
+        output
+            .stdout
+            .contains("1 succeeded, 0 failed, 1 archived skipped"),
+        "stdout: {}",
+        output.stdout
+    );
+
+    // The existing active directory is left alone, the archived one not cloned
+    let output = run_cli(&["clone", "--config", ws.config_str()]);
+    assert_eq!(output.status, 0, "stderr: {}", output.stderr);
+    assert!(
+        output
+            .stdout
+            .contains("Done cloning repositories, 1 archived skipped"),
+        "stdout: {}",
+        output.stdout
+    );
+
+    // An archived repository outside the selection is not mentioned
+    let output = run_cli(&[
+        "run",
+        "--no-save",
+        "-t",
+        "backend",
+        "--config",
+        ws.config_str(),
+        "true",
+    ]);
+    assert!(
+        !output.stderr.contains("archived"),
+        "stderr: {}",
+        output.stderr
+    );
+
+    let output = run_cli(&[
+        "run",
+        "--no-save",
+        "--include-archived",
+        "--config",
+        ws.config_str(),
+        "touch ran.txt",
+    ]);
+    assert_eq!(output.status, 0, "stderr: {}", output.stderr);
+    assert!(legacy_dir.join("ran.txt").exists());
+    assert!(!output.stderr.contains("archived"));
+}
+
+#[test]
+fn test_git_config_applies_repo_and_global_entries() {
+    let ws = Workspace::new();
+    let api_dir = ws.root.path().join("api");
+    let web_dir = ws.root.path().join("web");
+    for dir in [&api_dir, &web_dir] {
+        std::fs::create_dir_all(dir).unwrap();
+        let status = std::process::Command::new("git")
+            .args(["init", "-q"])
+            .current_dir(dir)
+            .status()
+            .unwrap();
+        assert!(status.success());
+    }
+    ws.write_config(&format!(
+        r#"
+repositories:
+  - name: api
+    url: https://github.com/test/api
+    tags: []
+    path: {}
+    git_config:
+      core.hooksPath: .githooks
+      user.email: api@example.com
+  - name: web
+    url: https://github.com/test/web
+    tags: []
+    path: {}
+"#,
+        api_dir.display(),
+        web_dir.display()
+    ));
+
+    let output = run_cli(&[
+        "git-config",
+        "--git-config",
+        "user.email=ci@example.com",
+        "--config",
+        ws.config_str(),
+    ]);
+    assert_eq!(output.status, 0, "stderr: {}", output.stderr);
+
+    let get = |dir: &PathBuf, key: &str| {
+        let output = std::process::Command::new("git")
+            .args(["config", "--local", "--get", key])
+            .current_dir(dir)
+            .output()
+            .unwrap();
+        String::from_utf8_lossy(&output.stdout).trim().to_string()
+    };
+    assert_eq!(get(&api_dir, "core.hooksPath"), ".githooks");
+    // The repository's own entry wins over the --git-config default
+    assert_eq!(get(&api_dir, "user.email"), "api@example.com");
+    assert_eq!(get(&web_dir, "user.email"), "ci@example.com");
+}
+
+#[test]
+fn test_verbose_logs_clone_and_run_commands() {
+    let ws = Workspace::new();
+    let clone_dir = ws.root.path().join("api");
+    // Nothing listens on port 1, so the clone fails fast after being logged
+    ws.write_config(&format!(
+        "repositories:\n  - name: api\n    url: https://127.0.0.1:1/acme/api.git\n    path: {}\n",
+        clone_dir.display()
+    ));
+
+    let output = run_cli(&["clone", "-v", "--config", ws.config_str()]);
+    assert!(
+        output.stderr.contains(&format!(
+            "api | $ GIT_ASKPASS=false GIT_TERMINAL_PROMPT=0 git clone -- https://127.0.0.1:1/acme/api.git {}",
+            clone_dir.display()
+        )),
+        "stderr: {}",
+        output.stderr
+    );
+
+    std::fs::create_dir_all(&clone_dir).unwrap();
+    let output = run_cli(&[
+        "run",
+        "--verbose",
+        "--no-save",
+        "--config",
+        ws.config_str(),
+        "echo hi",
+    ]);
+    assert_eq!(output.status, 0, "stderr: {}", output.stderr);
+    assert!(
+        output.stderr.contains(&format!(
+            "api | $ cd {} && sh -c 'echo hi'",
+            clone_dir.display()
+        )),
+        "stderr: {}",
+        output.stderr
+    );
+
+    // Without the flag nothing is logged
+    let output = run_cli(&["run", "--no-save", "--config", ws.config_str(), "true"]);
+    assert!(!output.stderr.contains("$ "), "stderr: {}", output.stderr);
+}
+
+#[test]
+fn test_pull_ssh_key_is_passed_to_git() {
+    let ws = Workspace::new();
+    let api_dir = ws.root.path().join("api");
+    let web_dir = ws.root.path().join("web");
+    for dir in [&api_dir, &web_dir] {
+        std::fs::create_dir_all(dir).unwrap();
+        std::process::Command::new("git")
+            .arg("init")
+            .current_dir(dir)
+            .output()
+            .unwrap();
+    }
+    ws.write_config(&format!(
+        "repositories:\n  - name: api\n    url: git@github.com:acme/api.git\n    path: {}\n  \
+         - name: web\n    url: git@github.com:acme/web.git\n    path: {}\n    ssh_key: /keys/web\n",
+        api_dir.display(),
+        web_dir.display()
+    ));
+
+    // Neither clone has an upstream, so both pulls fail after being logged
+    let output = run_cli(&[
+        "pull",
+        "-v",
+        "--ssh-key",
+        "/keys/ci",
+        "--config",
+        ws.config_str(),
+    ]);
+    let logged = |name: &str| {
+        output
+            .stderr
+            .lines()
+            .find(|line| line.starts_with(&format!("{name} | $ ")) && line.contains("git -C"))
+            .unwrap_or_else(|| panic!("no {name} command in stderr: {}", output.stderr))
+            .to_string()
+    };
+    assert!(logged("api").contains("GIT_SSH_COMMAND="));
+    assert!(logged("api").contains("/keys/ci"));
+    // A repository's own key wins over --ssh-key
+    assert!(logged("web").contains("/keys/web"));
+    assert!(!logged("web").contains("/keys/ci"));
+}
+
+#[test]
+fn test_proxy_flags_are_passed_to_git() {
+    let ws = Workspace::new();
+    let clone_dir = ws.root.path().join("api");
+    ws.write_config(&format!(
+        "repositories:\n  - name: api\n    url: https://127.0.0.1:1/acme/api.git\n    path: {}\n",
+        clone_dir.display()
+    ));
+
+    // Nothing listens on the proxy either, so the clone fails fast after being logged
+    let output = run_cli(&[
+        "clone",
+        "-v",
+        "--proxy",
+        "http://127.0.0.1:9",
+        "--no-proxy",
+        "git.internal",
+        "--config",
+        ws.config_str(),
+    ]);
+    for env in [
+        "HTTPS_PROXY=http://127.0.0.1:9",
+        "http_proxy=http://127.0.0.1:9",
+        "https_proxy=http://127.0.0.1:9",
+        "no_proxy=git.internal",
+    ] {
+        assert!(output.stderr.contains(env), "stderr: {}", output.stderr);
+    }
+
+    let output = run_cli(&[
+        "clone",
+        "--proxy",
+        "http://[::1",
+        "--config",
+        ws.config_str(),
+    ]);
+    assert_ne!(output.status, 0);
+    assert!(
+        output.stderr.contains("Invalid proxy URL"),
+        "stderr: {}",
+        output.stderr
+    );
+}
+
+#[test]
+fn test_clone_reports_existing_repos_as_skipped_or_updated() {
+    let (ws, api_dir, web_dir) = two_repo_workspace();
+    let origin = ws.root.path().join("origin");
+    std::fs::create_dir_all(&origin).unwrap();
+    let git = |dir: &Path, args: &[&str]| {
+        let status = std::process::Command::new("git")
+            .args(["-c", "user.name=Test", "-c", "user.email=test@example.com"])
+            .args(args)
+            .current_dir(dir)
+            .status()
+            .unwrap();
+        assert!(status.success(), "git {:?}", args);
+    };
+    git(&origin, &["init", "-q"]);
+    std::fs::write(origin.join("VERSION"), "1\n").unwrap();
+    git(&origin, &["add", "."]);
+    git(&origin, &["commit", "-q", "-m", "v1"]);
+    // Existing clones pull from their own origin remote, not the configured URL
+    for dir in [&api_dir, &web_dir] {
+        std::fs::remove_dir(dir).unwrap();
+        git(
+            ws.root.path(),
+            &[
+                "clone",
+                "-q",
+                origin.to_str().unwrap(),
+                dir.to_str().unwrap(),
+            ],
+        );
+    }
+    std::fs::write(origin.join("VERSION"), "2\n").unwrap();
+    git(&origin, &["commit", "-q", "-am", "v2"]);
+
+    let output = run_cli(&["clone", "--config", ws.config_str()]);
+    assert_eq!(output.status, 0, "stderr: {}", output.stderr);
+    assert!(output.stdout.contains("Skipped 2 existing: api, web"));
+    assert_eq!(
+        std::fs::read_to_string(api_dir.join("VERSION")).unwrap(),
+        "1\n"
+    );
+
+    let output = run_cli(&["clone", "--update-existing", "--config", ws.config_str()]);
+    assert_eq!(output.status, 0, "stderr: {}", output.stderr);
+    assert!(output.stdout.contains("Updated 2 existing: api, web"));
+    assert!(!output.stdout.contains("Skipped"));
+    for dir in [&api_dir, &web_dir] {
+        assert_eq!(std::fs::read_to_string(dir.join("VERSION")).unwrap(), "2\n");
+    }
+}
+
+#[test]
+fn test_clone_mirror_then_pull_updates_bare_clone() {
+    let ws = Workspace::new();
+    let origin = ws.root.path().join("origin");
+    std::fs::create_dir_all(&origin).unwrap();
+    let git = |dir: &Path, args: &[&str]| {
+        let status = std::process::Command::new("git")
+            .args(["-c", "user.name=Test", "-c", "user.email=test@example.com"])
+            .args(args)
+            .current_dir(dir)
+            .status()
+            .unwrap();
+        assert!(status.success(), "git {:?}", args);
+    };
+    git(&origin, &["init", "-q"]);
+    git(&origin, &["commit", "-q", "--allow-empty", "-m", "v1"]);
+    ws.write_config(&format!(
+        "repositories:\n  - name: api\n    url: {}\n    path: api.git\n",
+        origin.display()
+    ));
+
+    let output = run_cli(&["clone", "--mirror", "--config", ws.config_str()]);
+    assert_eq!(output.status, 0, "stderr: {}", output.stderr);
+    let mirror = ws.root.path().join("api.git");
+    assert!(mirror.join("HEAD").is_file());
+    assert!(!mirror.join(".git").exists());
+
+    // The bare clone is pulled with `git remote update`, without `mirror` in the config
+    git(&origin, &["branch", "release"]);
+    let output = run_cli(&["pull", "--config", ws.config_str()]);
+    assert_eq!(output.status, 0, "stderr: {}", output.stderr);
+    assert!(output.stdout.contains("Successfully updated mirror"));
+    let refs = std::process::Command::new("git")
+        .args(["for-each-ref", "--format=%(refname)"])
+        .current_dir(&mirror)
+        .output()
+        .unwrap();
+    assert!(String::from_utf8_lossy(&refs.stdout).contains("refs/heads/release"));
+}
+
+#[test]
+fn test_git_config_honours_limit() {
+    let (ws, api_dir, web_dir) = two_repo_workspace();
+    for dir in [&api_dir, &web_dir] {
+        let status = std::process::Command::new("git")
+            .args(["init", "-q"])
+            .current_dir(dir)
+            .status()
+            .unwrap();
+        assert!(status.success());
+    }
+
+    let output = run_cli(&[
+        "git-config",
+        "--git-config",
+        "user.email=ci@example.com",
+        "--limit",
+        "1",
+        "--config",
+        ws.config_str(),
+    ]);
+    assert_eq!(output.status, 0, "stderr: {}", output.stderr);
+    let email = |dir: &Path| {
+        let output = std::process::Command::new("git")
+            .args(["config", "--local", "user.email"])
+            .current_dir(dir)
+            .output()
+            .unwrap();
+        String::from_utf8_lossy(&output.stdout).trim().to_string()
+    };
+    assert_eq!(email(&api_dir), "ci@example.com");
+    assert_eq!(email(&web_dir), "");
+}
+
+#[test]
+fn test_git_config_rejects_malformed_entry() {
+    let ws = Workspace::new();
+    ws.write_config("repositories: []\n");
+
+    let output = run_cli(&[
+        "git-config",
+        "--git-config",
+        "user.email",
+        "--config",
+        ws.config_str(),
+    ]);
+    assert_ne!(output.status, 0);
+    assert!(output.stderr.contains("expected key=value"));
+}
+
+#[test]
+fn test_profile_supplies_defaults_and_cli_flags_override() {
+    let ws = Workspace::new();
+    let api_dir = ws.root.path().join("api");
+    let web_dir = ws.root.path().join("web");
+    std::fs::create_dir_all(&api_dir).unwrap();
+    std::fs::create_dir_all(&web_dir).unwrap();
+    ws.write_config(&format!(
+        r#"
+repositories:
+  - name: api
+    url: https://github.com/test/api
+    tags: [backend]
+    path: {}
+  - name: web
+    url: https://github.com/test/web
+    tags: [frontend]
+    path: {}
+profiles:
+  backend:
+    tag: [backend]
+    parallel: true
+"#,
+        api_dir.display(),
+        web_dir.display()
+    ));
+    let report_path = ws.root.path().join("report.json");
+
+    let output = run_cli(&[
+        "run",
+        "touch profile.txt",
+        "--no-save",
+        "--profile",
+        "backend",
+        "--config",
+        ws.config_str(),
+        "--report-file",
+        report_path.to_str().unwrap(),
+    ]);
+    assert_eq!(output.status, 0, "stderr: {}", output.stderr);
+    assert!(api_dir.join("profile.txt").exists());
+    assert!(!web_dir.join("profile.txt").exists());
+
+    let report: serde_json::Value =
+        serde_json::from_str(&std::fs::read_to_string(&report_path).unwrap()).unwrap();
+    assert_eq!(report["options"]["tag"], serde_json::json!(["backend"]));
+    assert_eq!(report["options"]["parallel"], true);
+
+    // An explicit --tag takes precedence over the profile's tags
+    let output = run_cli(&[
+        "run",
+        "touch override.txt",
+        "--no-save",
+        "--profile",
+        "backend",
+        "-t",
+        "frontend",
+        "--config",
+        ws.config_str(),
+    ]);
+    assert_eq!(output.status, 0, "stderr: {}", output.stderr);
+    assert!(web_dir.join("override.txt").exists());
+    assert!(!api_dir.join("override.txt").exists());
+}
+
+#[test]
+fn test_unknown_profile_is_rejected() {
+    let ws = Workspace::new();
+    ws.write_config("repositories: []\nprofiles:\n  ci:\n    parallel: true\n");
+
+    let output = run_cli(&["ls", "--profile", "nightly", "--config", ws.config_str()]);
+    assert_ne!(output.status, 0);
+    assert!(
+        output
+            .stderr
+            .contains("Unknown profile 'nightly' (available: ci)")
+    );
+}
+
+/// Two plain directories configured as repositories, for commands that only need a cwd
+fn two_repo_workspace() -> (Workspace, PathBuf, PathBuf) {
+    let ws = Workspace::new();
+    let api_dir = ws.root.path().join("api");
+    let web_dir = ws.root.path().join("web");
+    std::fs::create_dir_all(&api_dir).unwrap();
+    std::fs::create_dir_all(&web_dir).unwrap();
+    ws.write_config(&format!(
+        r#"
+repositories:
+  - name: api
+    url: https://github.com/test/api
+    tags: []
+    path: {}
+  - name: web
+    url: https://github.com/test/web
+    tags: []
+    path: {}
+"#,
+        api_dir.display(),
+        web_dir.display()
+    ));
+    (ws, api_dir, web_dir)
+}
+
+#[test]
+fn test_run_stdin_file_feeds_every_parallel_command() {
+    let (ws, api_dir, web_dir) = two_repo_workspace();
+    let input_path = ws.root.path().join("input.txt");
+    std::fs::write(&input_path, "alpha\nbeta\n").unwrap();
+
+    let output = run_cli(&[
+        "run",
+        "cat > got.txt",
+        "--no-save",
+        "--parallel",
+        "--stdin-file",
+        input_path.to_str().unwrap(),
+        "--config",
+        ws.config_str(),
+    ]);
+    assert_eq!(output.status, 0, "stderr: {}", output.stderr);
+    for dir in [&api_dir, &web_dir] {
+        assert_eq!(
+            std::fs::read_to_string(dir.join("got.txt")).unwrap(),
+            "alpha\nbeta\n"
+        );
+    }
+}
+
+#[test]
+fn test_run_stdin_replays_piped_input() {
+    use std::io::Write;
+    use std::process::Stdio;
+
+    let (ws, api_dir, web_dir) = two_repo_workspace();
+    let mut child = Command::new("cargo")
+        .args(["run", "--quiet", "--", "run", "cat > got.txt", "--no-save"])
+        .args(["--stdin", "--config", ws.config_str()])
+        .current_dir(env::current_dir().unwrap())
+        .stdin(Stdio::piped())
+        .stdout(Stdio::piped())
+        .stderr(Stdio::piped())
+        .spawn()
+        .unwrap();
+    child
+        .stdin
+        .take()
+        .unwrap()
+        .write_all(b"from the pipe\n")
+        .unwrap();
+    let output = child.wait_with_output().unwrap();
+    assert!(
+        output.status.success(),
+        "stderr: {}",
+        String::from_utf8_lossy(&output.stderr)
+    );
+    for dir in [&api_dir, &web_dir] {
+        assert_eq!(
+            std::fs::read_to_string(dir.join("got.txt")).unwrap(),
+            "from the pipe\n"
+        );
+    }
+}
+
+#[test]
+fn test_run_passes_arguments_after_double_dash_verbatim() {
+    let (ws, api_dir, web_dir) = two_repo_workspace();
+
+    let output = run_cli(&[
+        "run",
+        "--no-save",
+        "--config",
+        ws.config_str(),
+        "--",
+        "printf",
+        "[%s]",
+        "two words",
+        "it's",
+        "$HOME",
+        "a;b",
+        "",
+    ]);
+    assert_eq!(output.status, 0, "stderr: {}", output.stderr);
+    for dir in [&api_dir, &web_dir] {
+        assert!(dir.exists());
+    }
+    assert_eq!(
+        output
+            .stdout
+            .matches("[two words][it's][$HOME][a;b][]")
+            .count(),
+        2,
+        "stdout: {}",
+        output.stdout
+    );
+
+    // No shell is involved, so shell syntax is a program name
+    let output = run_cli(&[
+        "run",
+        "--no-save",
+        "--config",
+        ws.config_str(),
+        "--",
+        "true && true",
+    ]);
+    assert_ne!(output.status, 0);
+
+    // A positional command and `--` arguments are two commands
+    let output = run_cli(&["run", "echo", "--config", ws.config_str(), "--", "ls"]);
+    assert_ne!(output.status, 0);
+    let output = run_cli(&[
+        "run",
+        "-r",
+        "build",
+        "--config",
+        ws.config_str(),
+        "--",
+        "ls",
+    ]);
+    assert_ne!(output.status, 0);
+}
+
+#[cfg(unix)]
+#[test]
+fn test_run_uses_configured_shell() {
+    use std::os::unix::fs::PermissionsExt;
+
+    let ws = Workspace::new();
+    let api_dir = ws.root.path().join("api");
+    std::fs::create_dir_all(&api_dir).unwrap();
+    // Shells that print how they were called instead of running the command
+    let mut shells = Vec::new();
+    for name in ["config-sh", "flag-sh"] {
+        let path = ws.root.path().join(name);
+        std::fs::write(&path, format!("#!/bin/sh\necho {} \"$@\"\n", name)).unwrap();
+        std::fs::set_permissions(&path, std::fs::Permissions::from_mode(0o755)).unwrap();
+        shells.push(path);
+    }
+    ws.write_config(&format!(
+        r#"
+defaults:
+  shell: {}
+repositories:
+  - name: api
+    url: https://github.com/test/api
+    path: {}
+"#,
+        shells[0].display(),
+        api_dir.display()
+    ));
+
+    let output = run_cli(&["run", "--no-save", "--config", ws.config_str(), "echo hi"]);
+    assert_eq!(output.status, 0, "stderr: {}", output.stderr);
+    assert!(
+        output.stdout.contains("config-sh -c echo hi"),
+        "stdout: {}",
+        output.stdout
+    );
+
+    // --shell wins over the config
+    let output = run_cli(&[
+        "run",
+        "--no-save",
+        "--config",
+        ws.config_str(),
+        "--shell",
+        shells[1].to_str().unwrap(),
+        "echo hi",
+    ]);
+    assert_eq!(output.status, 0, "stderr: {}", output.stderr);
+    assert!(
+        output.stdout.contains("flag-sh -c echo hi"),
+        "stdout: {}",
+        output.stdout
+    );
+}
+
+#[test]
+fn test_exec_runs_program_without_shell() {
+    let (ws, api_dir, web_dir) = two_repo_workspace();
+
+    let output = run_cli(&[
+        "exec",
+        "--no-save",
+        "--config",
+        ws.config_str(),
+        "printf",
+        "[%s]",
+        "two words",
+        "$HOME",
+        "a;b",
+        "--config",
+    ]);
+    assert_eq!(output.status, 0, "stderr: {}", output.stderr);
+    for dir in [&api_dir, &web_dir] {
+        assert!(dir.exists());
+    }
+    assert_eq!(
+        output
+            .stdout
+            .matches("[two words][$HOME][a;b][--config]")
+            .count(),
+        2,
+        "stdout: {}",
+        output.stdout
+    );
+
+    // Shell syntax is a program name, which does not exist
+    let output = run_cli(&[
+        "exec",
+        "--no-save",
+        "--config",
+        ws.config_str(),
+        "--",
+        "true && true",
+    ]);
+    assert_ne!(output.status, 0);
+}
+
+#[test]
+fn test_run_max_failures_skips_remaining_repositories() {
+    let ws = Workspace::new();
+    let mut config = String::from("repositories:\n");
+    for name in ["a", "b", "c", "d", "e"] {
+        let dir = ws.root.path().join(name);
+        std::fs::create_dir_all(&dir).unwrap();
+        config.push_str(&format!(
+            "  - name: {name}\n    url: https://github.com/test/{name}\n    path: {}\n",
+            dir.display()
+        ));
+    }
+    ws.write_config(&config);
+    let output_dir = ws.root.path().join("output");
+    let report_path = ws.root.path().join("report.json");
+
+    let output = run_cli(&[
+        "run",
+        "touch ran && false",
+        "--max-failures",
+        "2",
+        "--output-dir",
+        output_dir.to_str().unwrap(),
+        "--report-file",
+        report_path.to_str().unwrap(),
+        "--config",
+        ws.config_str(),
+    ]);
+    assert_ne!(output.status, 0);
+    assert!(
+        output
+            .stderr
+            .contains("Stopped after 2 failures (--max-failures); skipped 3 repositories"),
+        "stderr: {}",
+        output.stderr
+    );
+    let ran: Vec<&str> = ["a", "b", "c", "d", "e"]
+        .into_iter()
+        .filter(|name| ws.root.path().join(name).join("ran").exists())
+        .collect();
+    assert_eq!(ran, vec!["a", "b"]);
+
+    let report: serde_json::Value =
+        serde_json::from_str(&std::fs::read_to_string(&report_path).unwrap()).unwrap();
+    assert_eq!(report["repositories"].as_array().unwrap().len(), 2);
+
+    // Commands that do not process repositories in batches reject it
+    let output = run_cli(&["ls", "--max-failures", "1", "--config", ws.config_str()]);
+    assert_ne!(output.status, 0);
+    assert!(output.stderr.contains("--max-failures is not supported"));
+}
+
+#[test]
+fn test_pr_max_pr_failures_decides_the_exit_code() {
+    let ws = Workspace::new();
+    ws.write_config(&format!(
+        r#"
+repositories:
+  - name: gone
+    url: https://github.com/test/gone
+    path: {}
+"#,
+        ws.root.path().join("gone").display()
+    ));
+    let pr = |extra: &[&str]| {
+        let mut args = vec![
+            "pr",
+            "--config",
+            ws.config_str(),
+            "--token",
+            "fake-token",
+            "--create-only",
+        ];
+        args.extend_from_slice(extra);
+        run_cli(&args)
+    };
+
+    let output = pr(&["--max-pr-failures", "1"]);
+    assert_eq!(output.status, 0, "stderr: {}", output.stderr);
+    assert!(
+        output
+            .stdout
+            .contains("1 failed within --max-pr-failures 1")
+    );
+
+    let output = pr(&["--max-pr-failures", "0"]);
+    assert_ne!(output.status, 0);
+    assert!(
+        output
+            .stderr
+            .contains("1 pull request operations failed, more than --max-pr-failures 0")
+    );
+
+    let output = pr(&["--max-pr-failures", "1", "--exit-policy", "never"]);
+    assert_ne!(output.status, 0);
+    assert!(
+        output
+            .stderr
+            .contains("--max-pr-failures cannot be combined with --exit-policy")
+    );
+}
+
+#[test]
+fn test_pr_owner_options_reject_repositories() {
+    let ws = Workspace::new();
+    ws.write_config("repositories: []\n");
+
+    let output = run_cli(&[
+        "pr",
+        "--config",
+        ws.config_str(),
+        "--base-owner",
+        "upstream-org/api",
+    ]);
+    assert_ne!(output.status, 0);
+    assert!(output.stderr.contains(
+        "Invalid --base-owner 'upstream-org/api': expected an owner such as acme, not a repository"
+    ));
+}
+
+#[test]
+fn test_deadline_stops_run_and_reports_incomplete_repositories() {
+    let ws = Workspace::new();
+    let mut config = String::from("repositories:\n");
+    for name in ["a", "b", "c"] {
+        let dir = ws.root.path().join(name);
+        std::fs::create_dir_all(&dir).unwrap();
+        config.push_str(&format!(
+            "  - name: {name}\n    url: https://github.com/test/{name}\n    path: {}\n",
+            dir.display()
+        ));
+    }
+    ws.write_config(&config);
+    let report_path = ws.root.path().join("report.json");
+
+    let started = std::time::Instant::now();
+    let output = run_cli(&[
+        "run",
+        "sleep 30",
+        "--no-save",
+        "--deadline",
+        "1s",
+        "--report-file",
+        report_path.to_str().unwrap(),
+        "--config",
+        ws.config_str(),
+    ]);
+    assert!(started.elapsed() < std::time::Duration::from_secs(20));
+    assert_ne!(output.status, 0);
+    assert!(
+        output
+            .stderr
+            .contains("Deadline of 1s reached (--deadline); 3 repositories incomplete: a, b, c"),
+        "stderr: {}",
+        output.stderr
+    );
+
+    let report: serde_json::Value =
+        serde_json::from_str(&std::fs::read_to_string(&report_path).unwrap()).unwrap();
+    let repositories = report["repositories"].as_array().unwrap();
+    assert_eq!(repositories.len(), 3);
+    assert!(repositories.iter().all(|repo| repo["success"] == false));
+
+    let output = run_cli(&["ls", "--deadline", "1s", "--config", ws.config_str()]);
+    assert_ne!(output.status, 0);
+    assert!(output.stderr.contains("--deadline is not supported"));
+
+    let output = run_cli(&[
+        "run",
+        "true",
+        "--deadline",
+        "10000000000000000000s",
+        "--config",
+        ws.config_str(),
+    ]);
+    assert_ne!(output.status, 0);
+    assert!(output.stderr.contains("too far in the future"));
+}
+
+#[test]
+fn test_clone_root_from_defaults_and_dir_flag() {
+    let ws = Workspace::new();
+    ws.write_config(
+        r#"
+defaults:
+  root: work
+repositories:
+  - name: api
+    url: https://github.com/test/api
+    tags: []
+  - name: web
+    url: https://github.com/test/web
+    tags: []
+    path: acme/web
+"#,
+    );
+    let other = ws.root.path().join("other");
+    for root in [ws.root.path().join("work"), other.clone()] {
+        std::fs::create_dir_all(root.join("api")).unwrap();
+        std::fs::create_dir_all(root.join("acme/web")).unwrap();
+    }
+
+    // defaults.root resolves next to the config file
+    let output = run_cli(&["run", "touch ran", "--no-save", "--config", ws.config_str()]);
+    assert_eq!(output.status, 0, "stderr: {}", output.stderr);
+    assert!(ws.root.path().join("work/api/ran").exists());
+    assert!(ws.root.path().join("work/acme/web/ran").exists());
+
+    // --dir overrides it
+    let output = run_cli(&[
+        "run",
+        "touch moved",
+        "--no-save",
+        "--dir",
+        other.to_str().unwrap(),
+        "--config",
+        ws.config_str(),
+    ]);
+    assert_eq!(output.status, 0, "stderr: {}", output.stderr);
+    assert!(other.join("api/moved").exists());
+    assert!(other.join("acme/web/moved").exists());
+    assert!(!ws.root.path().join("work/api/moved").exists());
+}
+
+#[test]
+fn test_merge_repo_tags_selects_by_tags_file() {
+    let (ws, api_dir, web_dir) = two_repo_workspace();
+    std::fs::write(api_dir.join(".repos-tags"), "team-payments, java\n").unwrap();
+
+    // Without the flag the file is ignored
+    let output = run_cli(&[
+        "run",
+        "touch ran",
+        "--no-save",
+        "-t",
+        "team-payments",
+        "--config",
+        ws.config_str(),
+    ]);
+    assert_eq!(output.status, 0, "stderr: {}", output.stderr);
+    assert!(!api_dir.join("ran").exists());
+
+    let output = run_cli(&[
+        "run",
+        "touch ran",
+        "--no-save",
+        "-t",
+        "team-payments",
+        "--merge-repo-tags",
+        "--config",
+        ws.config_str(),
+    ]);
+    assert_eq!(output.status, 0, "stderr: {}", output.stderr);
+    assert!(api_dir.join("ran").exists());
+    assert!(!web_dir.join("ran").exists());
+}
+
+#[test]
+fn test_run_stdin_file_must_exist() {
+    let (ws, _, _) = two_repo_workspace();
+    let output = run_cli(&[
+        "run",
+        "cat",
+        "--no-save",
+        "--stdin-file",
+        "/nonexistent/input.txt",
+        "--config",
+        ws.config_str(),
+    ]);
+    assert_ne!(output.status, 0);
+    assert!(output.stderr.contains("Failed to read --stdin-file"));
+}
+
+#[test]
+fn test_run_output_template_writes_stdout_per_repository() {
+    let (ws, _, _) = two_repo_workspace();
+    let template = format!("{}/out/{{{{.Name}}}}.txt", ws.root.path().display());
+
+    for mode in [&["--no-save"][..], &["--no-save", "--parallel"], &[][..]] {
+        let mut args = vec![
+            "run",
+            "basename \"$PWD\"; echo diagnostics >&2",
+            "--output-template",
+            &template,
+            "--output-dir",
+        ];
+        let output_dir = ws.root.path().join("logs");
+        let output_dir = output_dir.to_str().unwrap();
+        args.push(output_dir);
+        args.extend_from_slice(mode);
+        args.extend(["--config", ws.config_str()]);
+        let output = run_cli(&args);
+        assert_eq!(output.status, 0, "{:?}: {}", mode, output.stderr);
+
+        for name in ["api", "web"] {
+            let path = ws.root.path().join("out").join(format!("{name}.txt"));
+            assert_eq!(
+                std::fs::read_to_string(&path).unwrap(),
+                format!("{name}\n"),
+                "{:?}",
+                mode
+            );
+            std::fs::remove_file(path).unwrap();
+        }
+    }
+}
+
+#[test]
+fn test_run_output_template_requires_name() {
+    let (ws, api_dir, _) = two_repo_workspace();
+    let output = run_cli(&[
+        "run",
+        "touch ran.txt",
+        "--no-save",
+        "--output-template",
+        "out/all.txt",
+        "--config",
+        ws.config_str(),
+    ]);
+    assert_ne!(output.status, 0);
+    assert!(output.stderr.contains("must contain {{.Name}}"));
+    assert!(!api_dir.join("ran.txt").exists());
+}
+
+#[test]
+fn test_active_since_rejects_invalid_value() {
+    let ws = Workspace::new();
+    ws.write_config("repositories: []\n");
+
+    let output = run_cli(&["ls", "--active-since", "soon", "--config", ws.config_str()]);
+    assert_ne!(output.status, 0);
+    assert!(output.stderr.contains("Invalid value 'soon'"));
+
+    let output = run_cli(&[
+        "ls",
+        "--active-since",
+        "100000000d",
+        "--config",
+        ws.config_str(),
+    ]);
+    assert_ne!(output.status, 0);
+    assert!(output.stderr.contains("too far in the past"));
+}
+
+#[test]
+fn test_jobs_must_be_positive() {
+    let ws = Workspace::new();
+    ws.write_config("repositories: []\n");
+
+    let output = run_cli(&[
+        "clone",
+        "-p",
+        "--clone-jobs",
+        "0",
+        "--config",
+        ws.config_str(),
+    ]);
+    assert_ne!(output.status, 0);
+    assert!(output.stderr.contains("--clone-jobs"));
+}
+
+#[test]
+fn test_fetch_topics_requires_github_token() {
+    let ws = Workspace::new();
+    ws.write_config("repositories: []\n");
+
+    let output = Command::new("cargo")
+        .args(["run", "--quiet", "--"])
+        .args(["ls", "--fetch-topics", "--config", ws.config_str()])
+        .env_remove("GITHUB_TOKEN")
+        .output()
+        .expect("Failed to execute cargo run");
+
+    assert!(!output.status.success());
+    assert!(
+        String::from_utf8_lossy(&output.stderr).contains("--fetch-topics requires a GitHub token")
+    );
+}
+
+#[test]
+fn test_orgs_without_token_keeps_listed_repositories() {
+    let ws = Workspace::new();
+    ws.write_config(
+        r#"
+orgs:
+  - org: repos-cli-test-org-without-cache
+    topic: service
+repositories:
+  - name: listed
+    url: https://github.com/test/listed
+    tags: []
+"#,
+    );
+
+    let output = Command::new("cargo")
+        .args(["run", "--quiet", "--"])
+        .args(["ls", "--config", ws.config_str()])
+        .env_remove("GITHUB_TOKEN")
+        .output()
+        .expect("Failed to execute cargo run");
+
+    assert!(output.status.success());
+    assert!(String::from_utf8_lossy(&output.stdout).contains("listed"));
+    let stderr = String::from_utf8_lossy(&output.stderr);
+    assert!(stderr.contains("repos-cli-test-org-without-cache"));
+    assert!(stderr.contains("requires a GitHub token"));
+}
+
+#[test]
+fn test_run_where_health_selects_critical_repos() {
+    let ws = Workspace::new();
+    let healthy_dir = ws.root.path().join("healthy");
+    let neglected_dir = ws.root.path().join("neglected");
+    std::fs::create_dir_all(&healthy_dir).unwrap();
+    std::fs::create_dir_all(&neglected_dir).unwrap();
+    std::fs::write(
+        healthy_dir.join("README.md"),
+        include_str!("fixtures/health/readme/complete.md"),
+    )
+    .unwrap();
+    std::fs::write(healthy_dir.join("LICENSE"), "MIT").unwrap();
+    // Checks that do not apply earn full credit, so give it a failing Dockerfile
+    // and a default branch outside the allowed names too
+    std::fs::write(neglected_dir.join("Dockerfile"), "FROM node\n").unwrap();
+    let status = std::process::Command::new("git")
+        .args(["init", "--quiet", "--initial-branch", "master"])
+        .current_dir(&neglected_dir)
+        .status()
+        .unwrap();
+    assert!(status.success());
+    ws.write_config(&format!(
+        r#"
+repositories:
+  - name: healthy
+    url: https://github.com/test/healthy
+    tags: []
+    path: {}
+    health_ignore: [gitgnore]
+  - name: neglected
+    url: https://github.com/test/neglected
+    tags: []
+    path: {}
+  - name: unselected
+    url: https://github.com/test/unselected
+    tags: [other]
+"#,
+        healthy_dir.display(),
+        neglected_dir.display()
+    ));
+
+    let output = run_cli(&[
+        "run",
+        "--config",
+        ws.config_str(),
+        "--no-save",
+        "--where-health",
+        "critical",
+        "--exclude-tag",
+        "other",
+        "touch ran.txt",
+    ]);
+
+    assert_eq!(output.status, 0, "stderr: {}", output.stderr);
+    assert!(neglected_dir.join("ran.txt").exists());
+    assert!(!healthy_dir.join("ran.txt").exists());
+    assert!(output.stderr.contains("Skipped by --where-health (healthy"));
+    // Repositories outside the selection are not checked
+    assert!(!output.stderr.contains("unselected"));
+    // A misspelled check skips nothing, so it is pointed out
+    assert!(
+        output
+            .stderr
+            .contains("Warning: ignores unknown health check 'gitgnore'")
+    );
+}
+
+#[test]
+fn test_run_where_health_rejects_unknown_filter() {
+    let ws = Workspace::new();
+    ws.write_config("repositories: []\n");
+
+    let output = run_cli(&[
+        "run",
+        "--config",
+        ws.config_str(),
+        "--where-health",
+        "unhealthy",
+        "true",
+    ]);
+
+    assert_ne!(output.status, 0);
+    assert!(output.stderr.contains("Invalid health filter 'unhealthy'"));
+}
+
+#[test]
+fn test_config_migrate_upgrades_unversioned_config() {
+    let ws = Workspace::new();
+    ws.write_config(
+        "repositories:\n  - name: api\n    url: git@github.com:acme/api.git\n    tags: []\n    provider: gh\n",
+    );
+
+    let output = run_cli(&["config", "migrate", "--config", ws.config_str()]);
+    assert_eq!(output.status, 0, "stderr: {}", output.stderr);
+    assert!(output.stdout.contains("from config version 1 to 2"));
+
+    let migrated = std::fs::read_to_string(ws.config_str()).unwrap();
+    assert!(migrated.contains("version: 2"));
+    assert!(migrated.contains("provider: github"));
+
+    let output = run_cli(&["ls", "--config", ws.config_str()]);
+    assert_eq!(output.status, 0, "stderr: {}", output.stderr);
+}
+
+#[test]
+fn test_newer_config_version_is_rejected() {
+    let ws = Workspace::new();
+    ws.write_config("version: 99\nrepositories: []\n");
+
+    let output = run_cli(&["ls", "--config", ws.config_str()]);
+    assert_ne!(output.status, 0);
+    assert!(output.stderr.contains("Config declares version 99"));
+}
+
+#[test]
+fn test_run_resume_skips_completed_repos() {
+    let ws = Workspace::new();
+    let first_dir = ws.root.path().join("first");
+    let second_dir = ws.root.path().join("second");
+    std::fs::create_dir_all(&first_dir).unwrap();
+    std::fs::create_dir_all(&second_dir).unwrap();
+    ws.write_config(&format!(
+        r#"
+repositories:
+  - name: first
+    url: https://github.com/test/first
+    tags: [backend]
+    path: {}
+  - name: second
+    url: https://github.com/test/second
+    tags: [backend]
+    path: {}
+"#,
+        first_dir.display(),
+        second_dir.display()
+    ));
+    let output_dir = ws.root.path().join("output");
+    let checkpoints_dir = output_dir.join("checkpoints");
+    let args = [
+        "run",
+        "echo run >> runs.txt && test ! -f blocked",
+        "--no-save",
+        "--resume",
+        "--output-dir",
+        output_dir.to_str().unwrap(),
+        "--config",
+        ws.config_str(),
+    ];
+
+    // First attempt is "interrupted" by a failure in the second repository
+    std::fs::write(second_dir.join("blocked"), "").unwrap();
+    let output = run_cli(&args);
+    assert_ne!(output.status, 0);
+    assert_eq!(std::fs::read_dir(&checkpoints_dir).unwrap().count(), 1);
+
+    // Resuming skips the repository that already succeeded
+    std::fs::remove_file(second_dir.join("blocked")).unwrap();
+    let output = run_cli(&args);
+    assert_eq!(output.status, 0, "stderr: {}", output.stderr);
+    assert!(
+        output
+            .stderr
+            .contains("first | Already completed, skipping (--resume)")
+    );
+
+    let runs = |dir: &std::path::Path| {
+        std::fs::read_to_string(dir.join("runs.txt"))
+            .unwrap()
+            .lines()
+            .count()
+    };
+    assert_eq!(runs(&first_dir), 1);
+    assert_eq!(runs(&second_dir), 2);
+
+    // Successful completion clears the checkpoint
+    assert_eq!(std::fs::read_dir(&checkpoints_dir).unwrap().count(), 0);
+}
+
+#[test]
+fn test_run_warns_about_or_skips_detached_head() {
+    let (ws, api_dir, web_dir) = two_repo_workspace();
+    for dir in [&api_dir, &web_dir] {
+        for args in [
+            vec!["init", "-q"],
+            vec![
+                "-c",
+                "user.name=Test",
+                "-c",
+                "user.email=test@example.com",
+                "commit",
+                "-q",
+                "--allow-empty",
+                "-m",
+                "init",
+            ],
+        ] {
+            let status = std::process::Command::new("git")
+                .args(&args)
+                .current_dir(dir)
+                .status()
+                .unwrap();
+            assert!(status.success());
+        }
+    }
+    let status = std::process::Command::new("git")
+        .args(["checkout", "-q", "--detach"])
+        .current_dir(&web_dir)
+        .status()
+        .unwrap();
+    assert!(status.success());
+
+    let run = |flag: &str| {
+        run_cli(&[
+            "run",
+            flag,
+            "--no-save",
+            "--config",
+            ws.config_str(),
+            "touch ran",
+        ])
+    };
+
+    let output = run("--warn-detached");
+    assert_eq!(output.status, 0, "stderr: {}", output.stderr);
+    assert!(output.stderr.contains("web | Warning: HEAD is detached"));
+    assert!(!output.stderr.contains("api | Warning"));
+    assert!(web_dir.join("ran").exists());
+
+    std::fs::remove_file(web_dir.join("ran")).unwrap();
+    let output = run("--skip-detached");
+    assert_eq!(output.status, 0, "stderr: {}", output.stderr);
+    assert!(
+        output
+            .stderr
+            .contains("Skipped by --skip-detached (detached HEAD)")
+    );
+    assert!(api_dir.join("ran").exists());
+    assert!(!web_dir.join("ran").exists());
+}
+
+#[test]
+fn test_run_summary_format_json_lists_each_repository() {
+    let (ws, _api_dir, web_dir) = two_repo_workspace();
+    std::fs::write(web_dir.join("fail"), "").unwrap();
+
+    let output = run_cli(&[
+        "run",
+        "--no-save",
+        "--summary-format",
+        "json",
+        "--config",
+        ws.config_str(),
+        "test ! -e fail",
+    ]);
+    // web's command fails, which fails the run
+    assert_ne!(output.status, 0);
+    assert!(!output.stdout.contains("Summary:"));
+
+    // The summary document is the last thing printed
+    let start = output.stdout.find("\n{\n").expect("JSON summary") + 1;
+    let summary: serde_json::Value = serde_json::from_str(&output.stdout[start..]).unwrap();
+    assert_eq!(summary["repositories"], 2);
+    assert_eq!(summary["failed"], 1);
+    assert_eq!(summary["results"][0]["name"], "api");
+    assert_eq!(summary["results"][0]["success"], true);
+    assert_eq!(summary["results"][1]["name"], "web");
+    assert_eq!(summary["results"][1]["success"], false);
+    assert!(summary["results"][1]["duration_ms"].is_u64());
+
+    let output = run_cli(&[
+        "run",
+        "--summary-format",
+        "csv",
+        "--config",
+        ws.config_str(),
+        "true",
+    ]);
+    assert_ne!(output.status, 0);
+    assert!(output.stderr.contains("Unknown summary format 'csv'"));
+}
+
+#[test]
+fn test_exit_policy_decides_exit_code_from_repository_failures() {
+    let (ws, _api_dir, web_dir) = two_repo_workspace();
+    std::fs::write(web_dir.join("fail"), "").unwrap();
+    let run = |policy: &str, command: &str| {
+        run_cli(&[
+            "run",
+            "-p",
+            "--no-save",
+            "--exit-policy",
+            policy,
+            "--config",
+            ws.config_str(),
+            command,
+        ])
+    };
+
+    // web fails, api succeeds
+    let output = run("any-failure", "test ! -e fail");
+    assert_ne!(output.status, 0);
+    assert!(
+        output.stderr.contains("1 of 2 repositories failed"),
+        "stderr: {}",
+        output.stderr
+    );
+    assert_eq!(run("all-failure", "test ! -e fail").status, 0);
+    assert_eq!(run("never", "test ! -e fail").status, 0);
+
+    // Both fail
+    assert_ne!(run("all-failure", "false").status, 0);
+    assert_eq!(run("never", "false").status, 0);
+
+    let output = run("sometimes", "true");
+    assert_ne!(output.status, 0);
+    assert!(output.stderr.contains("Unknown exit policy 'sometimes'"));
+}
+
+#[test]
+fn test_run_skips_unreadable_repository_and_continues() {
+    let (ws, api_dir, web_dir) = two_repo_workspace();
+    std::fs::create_dir_all(web_dir.join(".git")).unwrap();
+    std::fs::write(web_dir.join(".git/HEAD"), "garbage\n").unwrap();
+
+    let output = run_cli(&[
+        "run",
+        "--no-save",
+        "--config",
+        ws.config_path.to_str().unwrap(),
+        "touch ran",
+    ]);
+    assert_eq!(output.status, 0, "stderr: {}", output.stderr);
+    assert!(
+        output
+            .stderr
+            .contains("web | Skipped (unreadable): corrupt .git: ")
+    );
+    assert!(api_dir.join("ran").exists());
+    assert!(!web_dir.join("ran").exists());
+}
+
+#[test]
+fn test_limit_offset_and_shard_slice_filtered_repositories() {
+    let ws = Workspace::new();
+    let mut config = String::from("repositories:\n");
+    for name in ["a", "b", "c", "d", "e", "f"] {
+        config.push_str(&format!(
+            "  - name: {name}\n    url: https://github.com/test/{name}\n    tags: [{}]\n",
+            if name == "f" { "internal" } else { "backend" }
+        ));
+    }
+    ws.write_config(&config);
+
+    let listed = |flags: &[&str]| {
+        let mut args = vec![
+            "ls",
+            "--tag",
+            "backend",
+            "--json",
+            "--config",
+            ws.config_str(),
+        ];
+        args.extend_from_slice(flags);
+        let output = run_cli(&args);
+        assert_eq!(output.status, 0, "stderr: {}", output.stderr);
+        let listed: serde_json::Value = serde_json::from_str(&output.stdout).unwrap();
+        listed
+            .as_array()
+            .unwrap()
+            .iter()
+            .map(|repo| repo["name"].as_str().unwrap().to_string())
+            .collect::<Vec<_>>()
+    };
+
+    // Slicing applies after the tag filter has removed "f"
+    assert_eq!(listed(&["--limit", "2"]), vec!["a", "b"]);
+    assert_eq!(listed(&["--offset", "3"]), vec!["d", "e"]);
+    assert_eq!(listed(&["--offset", "1", "--limit", "2"]), vec!["b", "c"]);
+    assert_eq!(listed(&["--shard", "1/2"]), vec!["a", "c", "e"]);
+    assert_eq!(listed(&["--shard", "2/2"]), vec!["b", "d"]);
+
+    let output = run_cli(&["ls", "--shard", "3/2", "--config", ws.config_str()]);
+    assert_ne!(output.status, 0);
+    assert!(output.stderr.contains("Invalid shard '3/2'"));
+}
+
+#[test]
+fn test_config_is_discovered_from_a_subdirectory() {
+    let (ws, api_dir, _) = two_repo_workspace();
+    let xdg = TempDir::new().unwrap();
+
+    // `cargo run` would look for the manifest in the subdirectory
+    let output = Command::new(env!("CARGO_BIN_EXE_repos"))
+        .arg("ls")
+        .current_dir(&api_dir)
+        .env("XDG_CONFIG_HOME", xdg.path())
+        .env("REPOS_DEBUG", "1")
+        .output()
+        .unwrap();
+    let stdout = String::from_utf8_lossy(&output.stdout);
+    let stderr = String::from_utf8_lossy(&output.stderr);
+    assert!(output.status.success(), "stderr: {}", stderr);
+    assert!(stdout.contains("web"), "stdout: {}", stdout);
+    assert!(
+        stderr.contains(&format!("Using config {}", ws.config_str())),
+        "stderr: {}",
+        stderr
+    );
+}
+
+#[test]
+fn test_mistyped_tag_suggests_closest_known_tags() {
+    let ws = Workspace::new();
+    ws.write_config(
+        r#"
+repositories:
+  - name: api
+    url: https://github.com/test/api
+    tags: [backend]
+  - name: web
+    url: https://github.com/test/web
+    tags: [frontend]
+"#,
+    );
+
+    let output = run_cli(&["ls", "--tag", "backnd", "--config", ws.config_str()]);
+    assert_eq!(output.status, 0, "stderr: {}", output.stderr);
+    assert!(output.stdout.contains("did you mean 'backend'?"));
+
+    let output = run_cli(&["ls", "--tag", "payments", "--config", ws.config_str()]);
+    assert!(output.stdout.contains("No repositories found with tags"));
+    assert!(!output.stdout.contains("did you mean"));
+}
+
+#[test]
+fn test_pull_skips_repositories_locked_by_another_process() {
+    let (ws, _api_dir, _web_dir) = two_repo_workspace();
+    let lock_file = std::fs::File::create(ws.root.path().join(".api.repos.lock")).unwrap();
+    lock_file.lock().unwrap();
+
+    let report_path = ws.root.path().join("report.json");
+    let output = run_cli(&[
+        "pull",
+        "api",
+        "--config",
+        ws.config_str(),
+        "--report-file",
+        report_path.to_str().unwrap(),
+    ]);
+    assert_eq!(output.status, 0, "stderr: {}", output.stderr);
+    assert!(
+        output
+            .stdout
+            .contains("api | Locked by another repos process, skipping")
+    );
+    assert!(!output.stdout.contains("Pulling latest changes"));
+    let report: serde_json::Value =
+        serde_json::from_str(&std::fs::read_to_string(&report_path).unwrap()).unwrap();
+    assert_eq!(report["success"], true);
+    assert_eq!(report["repositories"][0]["success"], false);
+    assert_eq!(report["repositories"][0]["skipped"], true);
+
+    // The lock file of a repository pulled without contention is removed again
+    run_cli(&["pull", "web", "--config", ws.config_str()]);
+    assert!(!ws.root.path().join(".web.repos.lock").exists());
+
+    let output = run_cli(&[
+        "pull",
+        "api",
+        "--on-locked",
+        "block",
+        "--config",
+        ws.config_str(),
+    ]);
+    assert_ne!(output.status, 0);
+    assert!(output.stderr.contains("Unknown lock mode 'block'"));
+}
+
+#[test]
+fn test_interactive_auth_restores_credential_prompts() {
+    let ws = Workspace::new();
+    let clone_dir = ws.root.path().join("api");
+    ws.write_config(&format!(
+        "repositories:\n  - name: api\n    url: https://127.0.0.1:1/acme/api.git\n    path: {}\n",
+        clone_dir.display()
+    ));
+    let clone_line = format!(
+        "api | $ git clone -- https://127.0.0.1:1/acme/api.git {}",
+        clone_dir.display()
+    );
+
+    let output = run_cli(&["clone", "-v", "--config", ws.config_str()]);
+    assert!(output.stderr.contains("GIT_TERMINAL_PROMPT=0"));
+    assert!(!output.stderr.contains(&clone_line));
+
+    let output = run_cli(&[
+        "clone",
+        "-v",
+        "--interactive-auth",
+        "--config",
+        ws.config_str(),
+    ]);
+    assert!(
+        output.stderr.contains(&clone_line),
+        "stderr: {}",
+        output.stderr
+    );
+}
+
+#[test]
+fn test_info_shows_live_facts_for_cloned_repositories() {
+    let (ws, api_dir, web_dir) = two_repo_workspace();
+    std::fs::remove_dir(&web_dir).unwrap();
+    let git = |args: &[&str]| {
+        Command::new("git")
+            .args(args)
+            .current_dir(&api_dir)
+            .output()
+            .unwrap()
+    };
+    git(&["init", "-b", "main"]);
+    git(&[
+        "-c",
+        "user.name=Test User",
+        "-c",
+        "user.email=test@example.com",
+        "commit",
+        "--allow-empty",
+        "-m",
+        "Initial commit",
+    ]);
+    git(&["remote", "add", "origin", "https://github.com/test/api"]);
+
+    let output = run_cli(&["info", "--output", "json", "--config", ws.config_str()]);
+    assert_eq!(output.status, 0, "stderr: {}", output.stderr);
+    let infos: serde_json::Value = serde_json::from_str(&output.stdout).unwrap();
+    assert_eq!(infos[0]["name"], "api");
+    assert_eq!(infos[0]["cloned"], true);
+    assert_eq!(infos[0]["current_branch"], "main");
+    assert_eq!(infos[0]["last_commit"]["subject"], "Initial commit");
+    assert_eq!(infos[0]["remote_url"], "https://github.com/test/api");
+    assert_eq!(infos[1]["name"], "web");
+    assert_eq!(infos[1]["cloned"], false);
+    assert!(infos[1].get("last_commit").is_none());
+
+    let output = run_cli(&["info", "web", "--config", ws.config_str()]);
+    assert_eq!(output.status, 0, "stderr: {}", output.stderr);
+    assert!(output.stdout.contains("URL: https://github.com/test/web"));
+    assert!(output.stdout.contains("Cloned: no"));
+    assert!(!output.stdout.contains("github.com/test/api"));
+
+    let output = run_cli(&["info", "--output", "yaml", "--config", ws.config_str()]);
+    assert_ne!(output.status, 0);
+    assert!(output.stderr.contains("Unknown output format 'yaml'"));
+}
+
+#[test]
+fn test_clone_error_on_existing_reports_present_directories() {
+    let (ws, api_dir, _web_dir) = two_repo_workspace();
+
+    let output = run_cli(&["clone", "--error-on-existing", "--config", ws.config_str()]);
+    assert_ne!(output.status, 0);
+    assert!(
+        output.stderr.contains(&format!(
+            "Target directory already exists: {}",
+            api_dir.display()
+        )),
+        "stderr: {}",
+        output.stderr
+    );
+
+    // Without the flag the same directories are skipped
+    let output = run_cli(&["clone", "--config", ws.config_str()]);
+    assert_eq!(output.status, 0, "stderr: {}", output.stderr);
+
+    let output = run_cli(&[
+        "clone",
+        "--error-on-existing",
+        "--update-existing",
+        "--config",
+        ws.config_str(),
+    ]);
+    assert_ne!(output.status, 0);
+    assert!(output.stderr.contains("cannot be used with"));
+}
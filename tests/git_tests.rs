@@ -1,10 +1,16 @@
 //! Comprehensive integration tests for the git module.
 
 use repos::{
-    config::Repository,
+    config::{PullStrategy, Repository},
     git::{
-        Logger, add_all_changes, clone_repository, commit_changes, create_and_checkout_branch,
-        get_default_branch, has_changes, push_branch, remove_repository,
+        CloneOptions, CloneOutcome, CloneState, Logger, MIRROR_UPDATE_ARGS, MergeConflict,
+        PullOptions, add_all_changes, apply_git_config, changed_files, check_clone_arg,
+        clone_command_args, clone_repository, clone_repository_with, commit_changes,
+        create_and_checkout_branch, delete_local_branch, delete_remote_branch, get_current_branch,
+        get_default_branch, has_changes, inspect_clone, is_bare_clone, is_detached_head, is_mirror,
+        last_commit_date, merge_target, merged_local_branches, merged_remote_branches,
+        pull_repository, pull_repository_with, push_branch, remove_repository, repository_facts,
+        unmerged_paths,
     },
 };
 use std::fs;
@@ -52,8 +58,7 @@ fn create_test_repository(name: &str, url: &str, path: Option<String>) -> Reposi
         url: url.to_string(),
         tags: vec!["test".to_string()],
         path,
-        branch: None,
-        config_dir: None,
+        ..Default::default()
     }
 }
 
@@ -86,10 +91,8 @@ fn test_clone_repository_directory_exists() {
     let repo = Repository {
         name: "existing-repo".to_string(),
         url: "https://github.com/user/existing-repo.git".to_string(),
-        tags: vec![],
         path: Some(temp_dir.path().to_string_lossy().to_string()),
-        branch: None,
-        config_dir: None,
+        ..Default::default()
     };
 
     // Should succeed but skip cloning because the directory exists.
@@ -97,6 +100,434 @@ fn test_clone_repository_directory_exists() {
     assert!(result.is_ok());
 }
 
+/// What an interrupted `git clone` of `url` leaves: a `.git` with an origin but no commits
+fn create_partial_clone(path: &Path, url: &str) {
+    fs::create_dir_all(path).unwrap();
+    Command::new("git")
+        .arg("init")
+        .current_dir(path)
+        .output()
+        .unwrap();
+    Command::new("git")
+        .args(["remote", "add", "origin", url])
+        .current_dir(path)
+        .output()
+        .unwrap();
+}
+
+#[test]
+fn test_inspect_clone_states() {
+    let temp_dir = TempDir::new().unwrap();
+
+    assert_eq!(
+        inspect_clone(&temp_dir.path().join("missing")),
+        CloneState::Missing
+    );
+
+    let empty = temp_dir.path().join("empty");
+    fs::create_dir_all(&empty).unwrap();
+    assert!(matches!(inspect_clone(&empty), CloneState::Incomplete(_)));
+
+    let partial = temp_dir.path().join("partial");
+    create_partial_clone(&partial, "https://github.com/owner/partial.git");
+    assert_eq!(
+        inspect_clone(&partial),
+        CloneState::Incomplete("HEAD does not point to a commit".to_string())
+    );
+
+    let complete = temp_dir.path().join("complete");
+    fs::create_dir_all(&complete).unwrap();
+    create_git_repo(&complete, None).unwrap();
+    assert_eq!(inspect_clone(&complete), CloneState::Complete);
+
+    let foreign = temp_dir.path().join("foreign");
+    fs::create_dir_all(&foreign).unwrap();
+    fs::write(foreign.join("notes.txt"), "mine").unwrap();
+    assert_eq!(inspect_clone(&foreign), CloneState::NotARepository);
+    assert!(!is_bare_clone(&foreign));
+    assert!(!is_bare_clone(&complete));
+}
+
+#[test]
+fn test_inspect_clone_reports_unreadable_directories() {
+    let temp_dir = TempDir::new().unwrap();
+
+    let corrupt = temp_dir.path().join("corrupt");
+    fs::create_dir_all(corrupt.join(".git")).unwrap();
+    fs::write(corrupt.join(".git/HEAD"), "garbage\n").unwrap();
+    assert!(matches!(
+        inspect_clone(&corrupt),
+        CloneState::Unreadable(reason) if reason.starts_with("corrupt .git: ")
+    ));
+
+    // A broken gitfile, as left by a moved worktree or submodule
+    let gitfile = temp_dir.path().join("gitfile");
+    fs::create_dir_all(&gitfile).unwrap();
+    fs::write(gitfile.join(".git"), "garbage\n").unwrap();
+    assert!(matches!(inspect_clone(&gitfile), CloneState::Unreadable(_)));
+
+    // --repair never removes an unreadable directory
+    let repo = create_test_repository(
+        "corrupt",
+        "https://github.com/owner/corrupt.git",
+        Some(corrupt.to_string_lossy().to_string()),
+    );
+    let outcome = clone_repository_with(
+        &repo,
+        &CloneOptions {
+            repair: true,
+            ..CloneOptions::default()
+        },
+    )
+    .unwrap();
+    assert_eq!(outcome, CloneOutcome::Skipped);
+    assert!(corrupt.join(".git/HEAD").exists());
+}
+
+#[cfg(unix)]
+#[test]
+fn test_inspect_clone_reports_restricted_directory() {
+    use std::os::unix::fs::PermissionsExt;
+
+    let temp_dir = TempDir::new().unwrap();
+    let restricted = temp_dir.path().join("restricted");
+    fs::create_dir_all(&restricted).unwrap();
+    create_git_repo(&restricted, None).unwrap();
+    fs::set_permissions(&restricted, fs::Permissions::from_mode(0o000)).unwrap();
+
+    // Permissions do not apply to root, so there is nothing to observe
+    let readable = fs::read_dir(&restricted).is_ok();
+    let state = inspect_clone(&restricted);
+    fs::set_permissions(&restricted, fs::Permissions::from_mode(0o755)).unwrap();
+    if readable {
+        return;
+    }
+
+    assert!(matches!(
+        state,
+        CloneState::Unreadable(reason) if reason.starts_with("cannot read directory: ")
+    ));
+}
+
+#[test]
+fn test_clone_repository_repairs_partial_clone() {
+    let temp_dir = TempDir::new().unwrap();
+    let origin = temp_dir.path().join("origin");
+    fs::create_dir_all(&origin).unwrap();
+    create_git_repo(&origin, None).unwrap();
+
+    let target = temp_dir.path().join("checkout");
+    create_partial_clone(&target, &origin.to_string_lossy());
+    let repo = create_test_repository(
+        "checkout",
+        &origin.to_string_lossy(),
+        Some(target.to_string_lossy().to_string()),
+    );
+
+    // Without --repair the broken directory is left alone
+    clone_repository(&repo).unwrap();
+    assert!(matches!(inspect_clone(&target), CloneState::Incomplete(_)));
+
+    clone_repository_with(
+        &repo,
+        &CloneOptions {
+            repair: true,
+            ..CloneOptions::default()
+        },
+    )
+    .unwrap();
+    assert_eq!(inspect_clone(&target), CloneState::Complete);
+    assert!(target.join("README.md").exists());
+}
+
+#[test]
+fn test_clone_repair_never_removes_foreign_directory() {
+    let temp_dir = TempDir::new().unwrap();
+    let target = temp_dir.path().join("foreign");
+    fs::create_dir_all(&target).unwrap();
+    fs::write(target.join("notes.txt"), "mine").unwrap();
+    let repo = create_test_repository(
+        "foreign",
+        "https://invalid-domain-12345-unique-xyz.com/repo.git",
+        Some(target.to_string_lossy().to_string()),
+    );
+
+    clone_repository_with(
+        &repo,
+        &CloneOptions {
+            repair: true,
+            ..CloneOptions::default()
+        },
+    )
+    .unwrap();
+    assert_eq!(
+        fs::read_to_string(target.join("notes.txt")).unwrap(),
+        "mine"
+    );
+}
+
+#[test]
+fn test_clone_repair_refuses_repositories_it_did_not_clone() {
+    let temp_dir = TempDir::new().unwrap();
+    let url = "https://invalid-domain-12345-unique-xyz.com/repo.git";
+    let repair = CloneOptions {
+        repair: true,
+        ..CloneOptions::default()
+    };
+    let attempt = |name: &str| {
+        let target = temp_dir.path().join(name);
+        let repo = create_test_repository(name, url, Some(target.to_string_lossy().to_string()));
+        (clone_repository_with(&repo, &repair), target)
+    };
+
+    // A fresh `git init` has no origin
+    let fresh = temp_dir.path().join("fresh");
+    fs::create_dir_all(&fresh).unwrap();
+    Command::new("git")
+        .arg("init")
+        .current_dir(&fresh)
+        .output()
+        .unwrap();
+    let (result, target) = attempt("fresh");
+    assert!(result.unwrap_err().to_string().contains("no origin remote"));
+    assert!(target.join(".git").exists());
+
+    // Another repository's clone
+    create_partial_clone(
+        &temp_dir.path().join("other"),
+        "https://github.com/owner/other.git",
+    );
+    let (result, target) = attempt("other");
+    assert!(result.unwrap_err().to_string().contains("its origin is"));
+    assert!(target.join(".git").exists());
+
+    // Work that has not been committed yet
+    let started = temp_dir.path().join("started");
+    create_partial_clone(&started, url);
+    fs::write(started.join("main.rs"), "fn main() {}").unwrap();
+    let (result, target) = attempt("started");
+    assert!(
+        result
+            .unwrap_err()
+            .to_string()
+            .contains("work tree holds files")
+    );
+    assert!(target.join("main.rs").exists());
+}
+
+/// Read a key from a repository's local git config
+fn local_git_config(path: &Path, key: &str) -> Option<String> {
+    let output = Command::new("git")
+        .args(["config", "--local", "--get", key])
+        .current_dir(path)
+        .output()
+        .unwrap();
+    output
+        .status
+        .success()
+        .then(|| String::from_utf8_lossy(&output.stdout).trim().to_string())
+}
+
+#[test]
+fn test_clone_repository_applies_git_config() {
+    let temp_dir = TempDir::new().unwrap();
+    let origin = temp_dir.path().join("origin");
+    fs::create_dir_all(&origin).unwrap();
+    create_git_repo(&origin, None).unwrap();
+
+    let target = temp_dir.path().join("configured");
+    let mut repo = create_test_repository(
+        "configured",
+        &origin.to_string_lossy(),
+        Some(target.to_string_lossy().to_string()),
+    );
+    repo.git_config
+        .insert("user.email".to_string(), "bot@example.com".to_string());
+    repo.git_config
+        .insert("core.hooksPath".to_string(), ".githooks".to_string());
+
+    clone_repository(&repo).unwrap();
+    assert_eq!(
+        local_git_config(&target, "user.email").as_deref(),
+        Some("bot@example.com")
+    );
+    assert_eq!(
+        local_git_config(&target, "core.hooksPath").as_deref(),
+        Some(".githooks")
+    );
+
+    // Re-applying to an existing clone updates changed values
+    repo.git_config
+        .insert("user.email".to_string(), "release@example.com".to_string());
+    apply_git_config(&repo).unwrap();
+    assert_eq!(
+        local_git_config(&target, "user.email").as_deref(),
+        Some("release@example.com")
+    );
+}
+
+#[test]
+fn test_apply_git_config_reports_failures() {
+    let temp_dir = TempDir::new().unwrap();
+    let mut repo = create_test_repository(
+        "absent",
+        "git@github.com:owner/absent.git",
+        Some(temp_dir.path().join("absent").to_string_lossy().to_string()),
+    );
+    // Nothing to apply is fine even without a clone
+    apply_git_config(&repo).unwrap();
+
+    repo.git_config
+        .insert("user.email".to_string(), "bot@example.com".to_string());
+    let err = apply_git_config(&repo).unwrap_err();
+    assert!(
+        err.to_string()
+            .contains("Failed to set git config 'user.email'")
+    );
+}
+
+#[test]
+fn test_clone_command_args_include_clone_args() {
+    let mut repo = create_test_repository(
+        "partial",
+        "git@github.com:owner/partial.git",
+        Some("/tmp/partial".to_string()),
+    );
+    repo.branch = Some("develop".to_string());
+    repo.clone_args = vec!["--filter=blob:none".to_string(), "--depth=1".to_string()];
+
+    assert_eq!(
+        clone_command_args(&repo).unwrap(),
+        vec![
+            "clone",
+            "-b",
+            "develop",
+            "--filter=blob:none",
+            "--depth=1",
+            "--",
+            "git@github.com:owner/partial.git",
+            "/tmp/partial",
+        ]
+    );
+
+    // A bare value would become the URL or target directory
+    repo.clone_args = vec!["--depth".to_string(), "1".to_string()];
+    let err = clone_command_args(&repo).unwrap_err();
+    assert!(format!("{:#}", err).contains("Invalid clone argument '--depth'"));
+
+    // Without `=`, a value-taking option would take `--` as its value
+    for arg in [
+        "--depth",
+        "--reference",
+        "--separate-git-dir",
+        "--sep",
+        "-b",
+        "-qo",
+        "-c",
+    ] {
+        assert!(check_clone_arg(arg).is_err(), "{arg} should be rejected");
+    }
+    for arg in ["--depth=1", "-bmain", "-qbmain", "--single-branch", "-q"] {
+        assert!(check_clone_arg(arg).is_ok(), "{arg} should be accepted");
+    }
+}
+
+#[test]
+fn test_clone_command_args_for_mirror() {
+    let mut repo = create_test_repository(
+        "mirror",
+        "git@github.com:owner/mirror.git",
+        Some("/tmp/mirror".to_string()),
+    );
+    repo.branch = Some("develop".to_string());
+    repo.mirror = true;
+
+    // A mirror copies every ref, so the branch is not passed
+    assert_eq!(
+        clone_command_args(&repo).unwrap(),
+        vec![
+            "clone",
+            "--mirror",
+            "--",
+            "git@github.com:owner/mirror.git",
+            "/tmp/mirror",
+        ]
+    );
+}
+
+#[test]
+fn test_mirror_clone_is_bare_and_updated_with_remote_update() {
+    let temp_dir = TempDir::new().unwrap();
+    let origin = temp_dir.path().join("origin");
+    fs::create_dir_all(&origin).unwrap();
+    create_git_repo(&origin, None).unwrap();
+
+    let target = temp_dir.path().join("mirror.git");
+    let mut repo = create_test_repository(
+        "mirror",
+        &origin.to_string_lossy(),
+        Some(target.to_string_lossy().to_string()),
+    );
+    repo.mirror = true;
+
+    assert_eq!(
+        clone_repository_with(&repo, &CloneOptions::default()).unwrap(),
+        CloneOutcome::Cloned
+    );
+    assert!(!target.join(".git").exists());
+    assert!(is_bare_clone(&target));
+    assert_eq!(inspect_clone(&target), CloneState::Complete);
+
+    // The bare directory selects the mirror path even without `mirror` set
+    repo.mirror = false;
+    assert!(is_mirror(&repo));
+    assert_eq!(MIRROR_UPDATE_ARGS, ["remote", "update", "--prune"]);
+
+    commit_readme(&origin, "# Changed upstream");
+    git(&origin, &["branch", "feature"]);
+    pull_repository(&repo).unwrap();
+    let refs = Command::new("git")
+        .args(["for-each-ref", "--format=%(refname)"])
+        .current_dir(&target)
+        .output()
+        .unwrap();
+    let refs = String::from_utf8_lossy(&refs.stdout);
+    assert!(refs.contains("refs/heads/feature"));
+
+    // Branches deleted upstream are pruned
+    git(&origin, &["branch", "-D", "feature"]);
+    pull_repository(&repo).unwrap();
+    let refs = Command::new("git")
+        .args(["for-each-ref", "--format=%(refname)"])
+        .current_dir(&target)
+        .output()
+        .unwrap();
+    assert!(!String::from_utf8_lossy(&refs.stdout).contains("refs/heads/feature"));
+
+    remove_repository(&repo).unwrap();
+    assert!(!target.exists());
+}
+
+#[test]
+fn test_clone_repository_passes_clone_args() {
+    let temp_dir = TempDir::new().unwrap();
+    let origin = temp_dir.path().join("origin");
+    fs::create_dir_all(&origin).unwrap();
+    create_git_repo(&origin, None).unwrap();
+
+    let target = temp_dir.path().join("bare-checkout");
+    let mut repo = create_test_repository(
+        "bare-checkout",
+        &origin.to_string_lossy(),
+        Some(target.to_string_lossy().to_string()),
+    );
+    repo.clone_args = vec!["--no-checkout".to_string()];
+
+    clone_repository(&repo).unwrap();
+    assert!(target.join(".git").is_dir());
+    assert!(!target.join("README.md").exists());
+}
+
 #[test]
 fn test_clone_repository_network_failure() {
     use uuid::Uuid;
@@ -105,10 +536,8 @@ fn test_clone_repository_network_failure() {
     let repo = Repository {
         name: unique_name,
         url: "https://invalid-domain-12345-unique-xyz.com/repo.git".to_string(),
-        tags: vec![],
         path: Some(temp_dir.path().to_string_lossy().to_string()),
-        branch: None,
-        config_dir: None,
+        ..Default::default()
     };
 
     // Ensure the target directory doesn't exist by checking and removing if it does
@@ -136,10 +565,8 @@ fn test_remove_repository() {
     let repo = Repository {
         name: "to-remove".to_string(),
         url: "https://github.com/user/to-remove.git".to_string(),
-        tags: vec![],
         path: Some(temp_dir.path().to_string_lossy().to_string()),
-        branch: None,
-        config_dir: None,
+        ..Default::default()
     };
 
     // Test successful removal
@@ -424,3 +851,535 @@ fn test_push_branch() {
             .contains("Failed to push")
     );
 }
+
+#[test]
+fn test_last_commit_date() {
+    let temp_dir = TempDir::new().unwrap();
+    let repo_path = temp_dir.path();
+    create_git_repo(repo_path, None).unwrap();
+
+    // Backdate a second commit so the result is deterministic
+    fs::write(repo_path.join("CHANGELOG.md"), "# Changes").unwrap();
+    add_all_changes(repo_path.to_str().unwrap()).unwrap();
+    Command::new("git")
+        .args(["commit", "-m", "Backdated commit"])
+        .env("GIT_COMMITTER_DATE", "2021-03-04T05:06:07+02:00")
+        .current_dir(repo_path)
+        .output()
+        .unwrap();
+
+    let date = last_commit_date(repo_path.to_str().unwrap()).unwrap();
+    assert_eq!(date.to_rfc3339(), "2021-03-04T05:06:07+02:00");
+}
+
+#[test]
+fn test_last_commit_date_without_commits() {
+    let temp_dir = TempDir::new().unwrap();
+    Command::new("git")
+        .arg("init")
+        .current_dir(temp_dir.path())
+        .output()
+        .unwrap();
+
+    assert!(last_commit_date(temp_dir.path().to_str().unwrap()).is_err());
+
+    let not_a_repo = TempDir::new().unwrap();
+    assert!(last_commit_date(not_a_repo.path().to_str().unwrap()).is_err());
+}
+
+#[test]
+fn test_changed_files_lists_untracked_files_individually() {
+    let temp_dir = TempDir::new().unwrap();
+    create_git_repo(temp_dir.path(), None).unwrap();
+    let path = temp_dir.path().to_str().unwrap();
+    assert!(changed_files(path).unwrap().is_empty());
+
+    fs::write(temp_dir.path().join("README.md"), "# Edited").unwrap();
+    fs::create_dir_all(temp_dir.path().join("docs/api")).unwrap();
+    fs::write(temp_dir.path().join("docs/api/index.md"), "api").unwrap();
+
+    let mut files = changed_files(path).unwrap();
+    files.sort();
+    assert_eq!(files, vec!["README.md", "docs/api/index.md"]);
+}
+
+// =================================
+// ===== Pull Tests
+// =================================
+
+/// Commit `content` as README.md in `path`
+fn commit_readme(path: &Path, content: &str) {
+    fs::write(path.join("README.md"), content).unwrap();
+    add_all_changes(path.to_str().unwrap()).unwrap();
+    commit_changes(path.to_str().unwrap(), "Update README").unwrap();
+}
+
+/// An origin repository and a clone whose README edits conflict with it
+fn create_conflicting_clone(root: &Path) -> (Repository, std::path::PathBuf) {
+    let origin = root.join("origin");
+    let clone = root.join("clone");
+    fs::create_dir_all(&origin).unwrap();
+    create_git_repo(&origin, None).unwrap();
+
+    Command::new("git")
+        .args(["clone", "-q"])
+        .arg(&origin)
+        .arg(&clone)
+        .output()
+        .unwrap();
+    for (key, value) in [
+        ("user.name", "Test User"),
+        ("user.email", "test@example.com"),
+        ("pull.rebase", "false"),
+    ] {
+        Command::new("git")
+            .args(["config", key, value])
+            .current_dir(&clone)
+            .output()
+            .unwrap();
+    }
+
+    commit_readme(&origin, "# Changed upstream");
+    commit_readme(&clone, "# Changed locally");
+
+    let repo = create_test_repository(
+        "clone",
+        origin.to_str().unwrap(),
+        Some(clone.to_string_lossy().to_string()),
+    );
+    (repo, clone)
+}
+
+#[test]
+fn test_pull_repository_fast_forward() {
+    let temp_dir = TempDir::new().unwrap();
+    let origin = temp_dir.path().join("origin");
+    let clone = temp_dir.path().join("clone");
+    fs::create_dir_all(&origin).unwrap();
+    create_git_repo(&origin, None).unwrap();
+    Command::new("git")
+        .args(["clone", "-q"])
+        .arg(&origin)
+        .arg(&clone)
+        .output()
+        .unwrap();
+    commit_readme(&origin, "# Changed upstream");
+
+    let repo = create_test_repository(
+        "clone",
+        origin.to_str().unwrap(),
+        Some(clone.to_string_lossy().to_string()),
+    );
+    pull_repository(&repo).unwrap();
+    assert_eq!(
+        fs::read_to_string(clone.join("README.md")).unwrap(),
+        "# Changed upstream"
+    );
+}
+
+#[test]
+fn test_pull_repository_reports_merge_conflict() {
+    let temp_dir = TempDir::new().unwrap();
+    let (repo, clone) = create_conflicting_clone(temp_dir.path());
+
+    let err = pull_repository(&repo).unwrap_err();
+    let conflict = err
+        .downcast_ref::<MergeConflict>()
+        .expect("a merge conflict error");
+    assert_eq!(conflict.files, vec!["README.md"]);
+    assert!(!conflict.aborted);
+    // The conflicted merge is left for the user to resolve
+    assert_eq!(unmerged_paths(&clone).unwrap(), vec!["README.md"]);
+}
+
+#[test]
+fn test_pull_repository_aborts_on_conflict() {
+    let temp_dir = TempDir::new().unwrap();
+    let (repo, clone) = create_conflicting_clone(temp_dir.path());
+
+    let options = PullOptions {
+        abort_on_conflict: true,
+        ..PullOptions::default()
+    };
+    let err = pull_repository_with(&repo, &options).unwrap_err();
+    let conflict = err.downcast_ref::<MergeConflict>().unwrap();
+    assert_eq!(conflict.files, vec!["README.md"]);
+    assert!(conflict.aborted);
+
+    assert!(unmerged_paths(&clone).unwrap().is_empty());
+    assert!(!clone.join(".git/MERGE_HEAD").exists());
+    assert_eq!(
+        fs::read_to_string(clone.join("README.md")).unwrap(),
+        "# Changed locally"
+    );
+}
+
+#[test]
+fn test_pull_repository_other_failures_are_not_conflicts() {
+    let temp_dir = TempDir::new().unwrap();
+    create_git_repo(temp_dir.path(), Some("/nonexistent/origin.git")).unwrap();
+    let repo = create_test_repository(
+        "no-remote",
+        "/nonexistent/origin.git",
+        Some(temp_dir.path().to_string_lossy().to_string()),
+    );
+
+    let err = pull_repository(&repo).unwrap_err();
+    assert!(err.downcast_ref::<MergeConflict>().is_none());
+    assert!(err.to_string().contains("Failed to pull repository"));
+}
+
+#[test]
+fn test_pull_strategy_of_a_diverged_clone() {
+    let temp_dir = TempDir::new().unwrap();
+    let (mut repo, clone) = create_conflicting_clone(temp_dir.path());
+    // Replace the conflicting local commit with one touching another file
+    Command::new("git")
+        .args(["reset", "-q", "--hard", "HEAD~1"])
+        .current_dir(&clone)
+        .output()
+        .unwrap();
+    fs::write(clone.join("NOTES.md"), "local").unwrap();
+    add_all_changes(clone.to_str().unwrap()).unwrap();
+    commit_changes(clone.to_str().unwrap(), "Add notes").unwrap();
+
+    repo.pull_strategy = Some(PullStrategy::FfOnly);
+    let options = PullOptions::default().for_repository(&repo);
+    let err = pull_repository_with(&repo, &options).unwrap_err();
+    assert!(err.to_string().contains("Failed to pull repository"));
+
+    // The repository's strategy wins over the global one
+    repo.pull_strategy = Some(PullStrategy::Rebase);
+    let options = PullOptions {
+        strategy: Some(PullStrategy::Merge),
+        ..PullOptions::default()
+    }
+    .for_repository(&repo);
+    pull_repository_with(&repo, &options).unwrap();
+
+    let parents = Command::new("git")
+        .args(["rev-list", "--parents", "-n", "1", "HEAD"])
+        .current_dir(&clone)
+        .output()
+        .unwrap();
+    // Rebased: the local commit has a single parent, no merge commit
+    assert_eq!(
+        String::from_utf8_lossy(&parents.stdout)
+            .split_whitespace()
+            .count(),
+        2
+    );
+    assert_eq!(
+        fs::read_to_string(clone.join("README.md")).unwrap(),
+        "# Changed upstream"
+    );
+}
+
+#[test]
+fn test_clone_existing_is_skipped_or_updated() {
+    let temp_dir = TempDir::new().unwrap();
+    let origin = temp_dir.path().join("origin");
+    let clone = temp_dir.path().join("clone");
+    fs::create_dir_all(&origin).unwrap();
+    create_git_repo(&origin, None).unwrap();
+    let repo = create_test_repository(
+        "clone",
+        origin.to_str().unwrap(),
+        Some(clone.to_string_lossy().to_string()),
+    );
+    assert_eq!(
+        clone_repository_with(&repo, &CloneOptions::default()).unwrap(),
+        CloneOutcome::Cloned
+    );
+    commit_readme(&origin, "# Changed upstream");
+
+    // By default the existing clone is left as it is
+    assert_eq!(
+        clone_repository_with(&repo, &CloneOptions::default()).unwrap(),
+        CloneOutcome::Skipped
+    );
+    assert_eq!(
+        fs::read_to_string(clone.join("README.md")).unwrap(),
+        "# Test Repository"
+    );
+
+    let update = CloneOptions {
+        update_existing: true,
+        ..CloneOptions::default()
+    };
+    assert_eq!(
+        clone_repository_with(&repo, &update).unwrap(),
+        CloneOutcome::Updated
+    );
+    assert_eq!(
+        fs::read_to_string(clone.join("README.md")).unwrap(),
+        "# Changed upstream"
+    );
+}
+
+#[test]
+fn test_clone_error_on_existing_fails_instead_of_skipping() {
+    let temp_dir = TempDir::new().unwrap();
+    let origin = temp_dir.path().join("origin");
+    let clone = temp_dir.path().join("clone");
+    fs::create_dir_all(&origin).unwrap();
+    create_git_repo(&origin, None).unwrap();
+    let repo = create_test_repository(
+        "clone",
+        origin.to_str().unwrap(),
+        Some(clone.to_string_lossy().to_string()),
+    );
+    let strict = CloneOptions {
+        error_on_existing: true,
+        ..CloneOptions::default()
+    };
+
+    // A fresh directory is cloned as usual
+    assert_eq!(
+        clone_repository_with(&repo, &strict).unwrap(),
+        CloneOutcome::Cloned
+    );
+
+    let err = clone_repository_with(&repo, &strict).unwrap_err();
+    assert_eq!(
+        err.to_string(),
+        format!("Target directory already exists: {}", clone.display())
+    );
+
+    // So is a directory that is not a repository
+    let plain = temp_dir.path().join("plain");
+    fs::create_dir_all(&plain).unwrap();
+    fs::write(plain.join("notes.txt"), "keep me").unwrap();
+    let repo = create_test_repository(
+        "plain",
+        origin.to_str().unwrap(),
+        Some(plain.to_string_lossy().to_string()),
+    );
+    assert!(clone_repository_with(&repo, &strict).is_err());
+    assert!(plain.join("notes.txt").exists());
+
+    // --repair still re-clones an incomplete directory
+    let partial = temp_dir.path().join("partial");
+    create_partial_clone(&partial, origin.to_str().unwrap());
+    let repo = create_test_repository(
+        "partial",
+        origin.to_str().unwrap(),
+        Some(partial.to_string_lossy().to_string()),
+    );
+    assert!(clone_repository_with(&repo, &strict).is_err());
+    let repair = CloneOptions {
+        repair: true,
+        ..strict
+    };
+    assert_eq!(
+        clone_repository_with(&repo, &repair).unwrap(),
+        CloneOutcome::Cloned
+    );
+}
+
+#[test]
+fn test_clone_update_existing_refuses_to_merge_diverged_clone() {
+    let temp_dir = TempDir::new().unwrap();
+    let (repo, clone) = create_conflicting_clone(temp_dir.path());
+
+    let update = CloneOptions {
+        update_existing: true,
+        ..CloneOptions::default()
+    };
+    let err = clone_repository_with(&repo, &update).unwrap_err();
+    assert!(err.to_string().contains("Failed to pull repository"));
+    // Fast-forward only: no merge was started
+    assert!(!clone.join(".git/MERGE_HEAD").exists());
+    assert_eq!(
+        fs::read_to_string(clone.join("README.md")).unwrap(),
+        "# Changed locally"
+    );
+}
+
+#[test]
+fn test_is_detached_head() {
+    let temp_dir = TempDir::new().unwrap();
+    let repo_path = temp_dir.path();
+    create_git_repo(repo_path, None).unwrap();
+    let path = repo_path.to_str().unwrap();
+    assert!(!is_detached_head(path).unwrap());
+
+    let status = Command::new("git")
+        .args(["checkout", "-q", "--detach"])
+        .current_dir(repo_path)
+        .status()
+        .unwrap();
+    assert!(status.success());
+    assert!(is_detached_head(path).unwrap());
+
+    // A directory that is not a repository is an error, not "attached"
+    let plain = TempDir::new().unwrap();
+    assert!(is_detached_head(plain.path().to_str().unwrap()).is_err());
+}
+
+#[test]
+fn test_repository_facts_of_clone() {
+    let temp_dir = TempDir::new().unwrap();
+    let repo_path = temp_dir.path();
+    create_git_repo(repo_path, Some("https://github.com/acme/api.git")).unwrap();
+    let path = repo_path.to_str().unwrap();
+
+    let facts = repository_facts(path).unwrap();
+    assert_eq!(
+        facts.current_branch,
+        Some(get_current_branch(path).unwrap())
+    );
+    assert_eq!(
+        facts.remote_url.as_deref(),
+        Some("https://github.com/acme/api.git")
+    );
+    let commit = facts.last_commit.unwrap();
+    assert_eq!(commit.sha.len(), 40);
+    assert_eq!(commit.subject, "Initial commit");
+    assert_eq!(commit.author, "Test User");
+    assert!(chrono::DateTime::parse_from_rfc3339(&commit.date).is_ok());
+
+    // A detached HEAD still reports the commit
+    Command::new("git")
+        .args(["checkout", "-q", "--detach"])
+        .current_dir(repo_path)
+        .status()
+        .unwrap();
+    let facts = repository_facts(path).unwrap();
+    assert_eq!(facts.current_branch, None);
+    assert!(facts.last_commit.is_some());
+}
+
+#[test]
+fn test_repository_facts_without_commits_or_clone() {
+    let temp_dir = TempDir::new().unwrap();
+    Command::new("git")
+        .args(["init", "-b", "main"])
+        .current_dir(temp_dir.path())
+        .output()
+        .unwrap();
+    let facts = repository_facts(temp_dir.path().to_str().unwrap()).unwrap();
+    assert_eq!(facts.current_branch.as_deref(), Some("main"));
+    assert_eq!(facts.last_commit, None);
+    assert_eq!(facts.remote_url, None);
+
+    let not_a_repo = TempDir::new().unwrap();
+    assert!(repository_facts(not_a_repo.path().to_str().unwrap()).is_err());
+
+    // A directory inside a repository does not report the enclosing one
+    let nested = temp_dir.path().join("vendor/api");
+    std::fs::create_dir_all(&nested).unwrap();
+    assert!(repository_facts(nested.to_str().unwrap()).is_err());
+}
+
+// =================================
+// ===== Prune Branches Tests
+// =================================
+
+/// Run git with `args` in `path`, asserting it succeeds
+fn git(path: &Path, args: &[&str]) {
+    let status = Command::new("git")
+        .args(args)
+        .current_dir(path)
+        .status()
+        .unwrap();
+    assert!(status.success(), "git {:?} failed", args);
+}
+
+/// Create `branch` off `HEAD` with one commit of its own, then switch back
+fn commit_on_branch(path: &Path, branch: &str) {
+    let default = get_current_branch(path.to_str().unwrap()).unwrap();
+    git(path, &["checkout", "-q", "-b", branch]);
+    commit_readme(path, &format!("# Work on {}", branch));
+    git(path, &["checkout", "-q", &default]);
+}
+
+#[test]
+fn test_merged_local_branches_skips_unmerged_current_and_protected() {
+    let temp_dir = TempDir::new().unwrap();
+    let repo_path = temp_dir.path();
+    create_git_repo(repo_path, None).unwrap();
+    let path = repo_path.to_str().unwrap();
+    let default = get_current_branch(path).unwrap();
+
+    git(repo_path, &["branch", "merged-at-head"]);
+    git(repo_path, &["branch", "develop"]);
+    commit_on_branch(repo_path, "merged-later");
+    git(repo_path, &["merge", "-q", "--no-edit", "merged-later"]);
+    commit_on_branch(repo_path, "unmerged");
+
+    // Without a remote the local default branch is the target
+    let target = merge_target(path, &default);
+    assert_eq!(target, default);
+    let mut merged = merged_local_branches(path, &target, &default).unwrap();
+    merged.sort();
+    assert_eq!(merged, vec!["merged-at-head", "merged-later"]);
+
+    // The current branch is never offered, even when merged
+    git(repo_path, &["checkout", "-q", "merged-at-head"]);
+    assert_eq!(
+        merged_local_branches(path, &target, &default).unwrap(),
+        vec!["merged-later"]
+    );
+    git(repo_path, &["checkout", "-q", &default]);
+
+    delete_local_branch(path, "merged-later").unwrap();
+    let err = delete_local_branch(path, "unmerged").unwrap_err();
+    assert!(
+        err.to_string()
+            .contains("Failed to delete branch 'unmerged'")
+    );
+
+    let output = Command::new("git")
+        .args(["branch", "--format=%(refname:short)"])
+        .current_dir(repo_path)
+        .output()
+        .unwrap();
+    let mut remaining: Vec<String> = String::from_utf8_lossy(&output.stdout)
+        .lines()
+        .map(str::to_string)
+        .collect();
+    remaining.sort();
+    let mut expected = vec!["develop", "merged-at-head", "unmerged", default.as_str()];
+    expected.sort();
+    assert_eq!(remaining, expected);
+}
+
+#[test]
+fn test_merged_remote_branches_and_remote_deletion() {
+    let temp_dir = TempDir::new().unwrap();
+    let origin = temp_dir.path().join("origin");
+    let clone = temp_dir.path().join("clone");
+    fs::create_dir_all(&origin).unwrap();
+    create_git_repo(&origin, None).unwrap();
+    git(&origin, &["branch", "shipped"]);
+    commit_on_branch(&origin, "in-review");
+    Command::new("git")
+        .args(["clone", "-q"])
+        .arg(&origin)
+        .arg(&clone)
+        .output()
+        .unwrap();
+
+    let path = clone.to_str().unwrap();
+    let default = get_default_branch(path).unwrap();
+    let target = merge_target(path, &default);
+    assert_eq!(target, format!("origin/{}", default));
+    assert_eq!(
+        merged_remote_branches(path, &target, &default).unwrap(),
+        vec!["shipped"]
+    );
+
+    delete_remote_branch(path, "shipped", None).unwrap();
+    let output = Command::new("git")
+        .args(["branch", "--list", "shipped"])
+        .current_dir(&origin)
+        .output()
+        .unwrap();
+    assert!(output.stdout.is_empty());
+    assert!(
+        merged_remote_branches(path, &target, &default)
+            .unwrap()
+            .is_empty()
+    );
+}
@@ -1,4 +1,5 @@
 use repos::config::repository::Repository;
+use repos::git::CommitIdentity;
 use repos::github::api::create_pr_from_workspace;
 use repos::github::types::PrOptions;
 use repos_github::GitHubClient;
@@ -80,9 +81,7 @@ async fn test_create_pr_from_workspace_with_changes_success_flow() {
         name: "test-repo".to_string(),
         url: "https://github.com/owner/repo.git".to_string(),
         path: Some(repo_path.to_string_lossy().to_string()),
-        tags: Vec::new(),
-        branch: None,
-        config_dir: None,
+        ..Default::default()
     };
 
     let options = PrOptions::new(
@@ -90,6 +89,7 @@ async fn test_create_pr_from_workspace_with_changes_success_flow() {
         "Test body".to_string(),
         "fake-token".to_string(),
     )
+    .with_commit_all()
     .create_only();
 
     // This should succeed and create a branch without network calls
@@ -136,9 +136,7 @@ async fn test_create_pr_workspace_no_changes_early_return() {
         name: "test-repo".to_string(),
         url: "https://github.com/owner/repo.git".to_string(),
         path: Some(repo_path.to_string_lossy().to_string()),
-        tags: Vec::new(),
-        branch: None,
-        config_dir: None,
+        ..Default::default()
     };
 
     let options = PrOptions::new(
@@ -182,9 +180,7 @@ async fn test_create_pr_workspace_commit_message_fallback() {
         name: "test-repo".to_string(),
         url: "https://github.com/owner/repo.git".to_string(),
         path: Some(repo_path.to_string_lossy().to_string()),
-        tags: Vec::new(),
-        branch: None,
-        config_dir: None,
+        ..Default::default()
     };
 
     // Options without commit_msg to test fallback to title
@@ -193,6 +189,7 @@ async fn test_create_pr_workspace_commit_message_fallback() {
         "Test body".to_string(),
         "fake-token".to_string(),
     )
+    .with_commit_all()
     .create_only();
 
     // This should use title as commit message (fallback path)
@@ -251,9 +248,7 @@ async fn test_create_pr_workspace_branch_name_generation() {
         name: "test-repo".to_string(),
         url: "https://github.com/owner/repo.git".to_string(),
         path: Some(repo_path.to_string_lossy().to_string()),
-        tags: Vec::new(),
-        branch: None,
-        config_dir: None,
+        ..Default::default()
     };
 
     // Options without branch_name to test auto-generation
@@ -262,6 +257,7 @@ async fn test_create_pr_workspace_branch_name_generation() {
         "Test body".to_string(),
         "fake-token".to_string(),
     )
+    .with_commit_all()
     .create_only();
 
     let result = create_pr_from_workspace(&repo, &options).await;
@@ -291,9 +287,7 @@ async fn test_create_pr_workspace_git_operations_error_paths() {
         name: "test-repo".to_string(),
         url: "https://github.com/owner/repo.git".to_string(),
         path: Some(repo_path.to_string_lossy().to_string()),
-        tags: Vec::new(),
-        branch: None,
-        config_dir: None,
+        ..Default::default()
     };
 
     let options = PrOptions::new(
@@ -301,6 +295,7 @@ async fn test_create_pr_workspace_git_operations_error_paths() {
         "Test body".to_string(),
         "fake-token".to_string(),
     )
+    .with_commit_all()
     .create_only();
 
     // This should fail on git::has_changes due to no git repo
@@ -338,9 +333,7 @@ async fn test_create_pr_workspace_custom_branch_and_commit() {
         name: "test-repo".to_string(),
         url: "https://github.com/owner/repo.git".to_string(),
         path: Some(repo_path.to_string_lossy().to_string()),
-        tags: Vec::new(),
-        branch: None,
-        config_dir: None,
+        ..Default::default()
     };
 
     // Options with custom branch name and commit message
@@ -351,6 +344,7 @@ async fn test_create_pr_workspace_custom_branch_and_commit() {
     )
     .with_branch_name("custom-branch".to_string())
     .with_commit_message("Custom commit message".to_string())
+    .with_commit_all()
     .create_only();
 
     let result = create_pr_from_workspace(&repo, &options).await;
@@ -377,6 +371,251 @@ async fn test_create_pr_workspace_custom_branch_and_commit() {
     assert_eq!(commit_msg, "Custom commit message");
 }
 
+/// A committed repository with an uncommitted `docs/guide.md` and `src/main.rs`
+fn create_repo_with_changes(repo_path: &std::path::Path) -> Repository {
+    create_git_repo(repo_path).unwrap();
+    fs::write(repo_path.join("README.md"), "readme").unwrap();
+    for args in [vec!["add", "."], vec!["commit", "-m", "Initial commit"]] {
+        std::process::Command::new("git")
+            .args(args)
+            .current_dir(repo_path)
+            .output()
+            .unwrap();
+    }
+    fs::create_dir_all(repo_path.join("docs")).unwrap();
+    fs::write(repo_path.join("docs/guide.md"), "guide").unwrap();
+    fs::create_dir_all(repo_path.join("src")).unwrap();
+    fs::write(repo_path.join("src/main.rs"), "fn main() {}").unwrap();
+
+    let mut repo = Repository::new(
+        "filtered-repo".to_string(),
+        "https://github.com/owner/filtered-repo.git".to_string(),
+    );
+    repo.path = Some(repo_path.to_string_lossy().to_string());
+    repo
+}
+
+fn branches(repo_path: &std::path::Path) -> String {
+    let output = std::process::Command::new("git")
+        .args(["branch", "--list"])
+        .current_dir(repo_path)
+        .output()
+        .unwrap();
+    String::from_utf8(output.stdout).unwrap()
+}
+
+#[tokio::test]
+async fn test_create_pr_workspace_changed_files_match() {
+    let temp_dir = TempDir::new().unwrap();
+    let repo = create_repo_with_changes(temp_dir.path());
+
+    let options = PrOptions::new(
+        "Docs PR".to_string(),
+        "Docs changes".to_string(),
+        "fake-token".to_string(),
+    )
+    .with_branch_name("docs-update".to_string())
+    .with_changed_files(vec![
+        glob::Pattern::new("*.toml").unwrap(),
+        glob::Pattern::new("docs/*.md").unwrap(),
+    ])
+    .with_commit_all()
+    .create_only();
+
+    create_pr_from_workspace(&repo, &options).await.unwrap();
+    assert!(branches(temp_dir.path()).contains("docs-update"));
+}
+
+#[tokio::test]
+async fn test_create_pr_workspace_changed_files_no_match_skips() {
+    let temp_dir = TempDir::new().unwrap();
+    let repo = create_repo_with_changes(temp_dir.path());
+
+    let options = PrOptions::new(
+        "Config PR".to_string(),
+        "Config changes".to_string(),
+        "fake-token".to_string(),
+    )
+    .with_branch_name("config-update".to_string())
+    .with_changed_files(vec![glob::Pattern::new("**/*.yaml").unwrap()])
+    .with_commit_all()
+    .create_only();
+
+    create_pr_from_workspace(&repo, &options).await.unwrap();
+    assert!(!branches(temp_dir.path()).contains("config-update"));
+    // Nothing was committed: the changes are still in the working tree
+    assert!(repos::git::has_changes(&temp_dir.path().to_string_lossy()).unwrap());
+}
+
+/// Stage `docs/guide.md` of [`create_repo_with_changes`], leaving `src/main.rs` unstaged
+fn stage_guide(repo_path: &std::path::Path) {
+    std::process::Command::new("git")
+        .args(["add", "docs/guide.md"])
+        .current_dir(repo_path)
+        .output()
+        .unwrap();
+}
+
+/// Files changed by the commit at the tip of `branch`
+fn committed_files(repo_path: &std::path::Path, branch: &str) -> Vec<String> {
+    let output = std::process::Command::new("git")
+        .args(["show", "--name-only", "--pretty=format:", branch])
+        .current_dir(repo_path)
+        .output()
+        .unwrap();
+    String::from_utf8(output.stdout)
+        .unwrap()
+        .lines()
+        .filter(|line| !line.is_empty())
+        .map(str::to_string)
+        .collect()
+}
+
+#[tokio::test]
+async fn test_create_pr_workspace_commits_staged_changes_only_by_default() {
+    let temp_dir = TempDir::new().unwrap();
+    let repo = create_repo_with_changes(temp_dir.path());
+    stage_guide(temp_dir.path());
+
+    let options = PrOptions::new(
+        "Docs PR".to_string(),
+        "Docs changes".to_string(),
+        "fake-token".to_string(),
+    )
+    .with_branch_name("staged-only".to_string())
+    .create_only();
+
+    create_pr_from_workspace(&repo, &options).await.unwrap();
+    assert_eq!(
+        committed_files(temp_dir.path(), "staged-only"),
+        vec!["docs/guide.md"]
+    );
+    // The unstaged file is left in the working tree
+    assert_eq!(
+        repos::git::changed_files(&temp_dir.path().to_string_lossy()).unwrap(),
+        vec!["src/main.rs"]
+    );
+}
+
+#[tokio::test]
+async fn test_create_pr_workspace_commit_all_stages_everything() {
+    let temp_dir = TempDir::new().unwrap();
+    let repo = create_repo_with_changes(temp_dir.path());
+    stage_guide(temp_dir.path());
+
+    let options = PrOptions::new(
+        "All PR".to_string(),
+        "All changes".to_string(),
+        "fake-token".to_string(),
+    )
+    .with_branch_name("everything".to_string())
+    .with_commit_all()
+    .create_only();
+
+    create_pr_from_workspace(&repo, &options).await.unwrap();
+    assert_eq!(
+        committed_files(temp_dir.path(), "everything"),
+        vec!["docs/guide.md", "src/main.rs"]
+    );
+    assert!(!repos::git::has_changes(&temp_dir.path().to_string_lossy()).unwrap());
+}
+
+#[tokio::test]
+async fn test_create_pr_workspace_without_staged_changes_skips() {
+    let temp_dir = TempDir::new().unwrap();
+    let repo = create_repo_with_changes(temp_dir.path());
+
+    let options = PrOptions::new(
+        "Docs PR".to_string(),
+        "Docs changes".to_string(),
+        "fake-token".to_string(),
+    )
+    .with_branch_name("nothing-staged".to_string())
+    .create_only();
+
+    create_pr_from_workspace(&repo, &options).await.unwrap();
+    assert!(!branches(temp_dir.path()).contains("nothing-staged"));
+
+    // --changed-files only looks at the staged changes too
+    stage_guide(temp_dir.path());
+    let options = options.with_changed_files(vec![glob::Pattern::new("src/*.rs").unwrap()]);
+    create_pr_from_workspace(&repo, &options).await.unwrap();
+    assert!(!branches(temp_dir.path()).contains("nothing-staged"));
+}
+
+#[tokio::test]
+async fn test_create_pr_workspace_renders_title_template_for_commit() {
+    let temp_dir = TempDir::new().unwrap();
+    let repo = create_repo_with_changes(temp_dir.path());
+
+    let options = PrOptions::new(
+        "chore({{.Name}}): sync {{.Branch}} into {{.Base}}".to_string(),
+        "Sync".to_string(),
+        "fake-token".to_string(),
+    )
+    .with_branch_name("sync".to_string())
+    .with_base_branch("develop".to_string())
+    .with_commit_all()
+    .create_only();
+
+    create_pr_from_workspace(&repo, &options).await.unwrap();
+    let output = std::process::Command::new("git")
+        .args(["log", "-1", "--pretty=format:%s", "sync"])
+        .current_dir(temp_dir.path())
+        .output()
+        .unwrap();
+    assert_eq!(
+        String::from_utf8(output.stdout).unwrap(),
+        "chore(filtered-repo): sync sync into develop"
+    );
+}
+
+#[tokio::test]
+async fn test_create_pr_workspace_commits_as_configured_author() {
+    let temp_dir = TempDir::new().unwrap();
+    let repo = create_repo_with_changes(temp_dir.path());
+    let identity = |branch: &str| {
+        let output = std::process::Command::new("git")
+            .args(["log", "-1", "--pretty=format:%an <%ae>|%cn <%ce>", branch])
+            .current_dir(temp_dir.path())
+            .output()
+            .unwrap();
+        String::from_utf8(output.stdout).unwrap()
+    };
+
+    let options = PrOptions::new(
+        "Bot PR".to_string(),
+        "Bot changes".to_string(),
+        "fake-token".to_string(),
+    )
+    .with_branch_name("bot".to_string())
+    .with_author(CommitIdentity {
+        name: Some("Release Bot".to_string()),
+        email: Some("bot@example.com".to_string()),
+    })
+    .with_commit_all()
+    .create_only();
+    create_pr_from_workspace(&repo, &options).await.unwrap();
+    assert_eq!(
+        identity("bot"),
+        "Release Bot <bot@example.com>|Release Bot <bot@example.com>"
+    );
+
+    // The part left unset comes from the repository's git config
+    fs::write(temp_dir.path().join("CHANGELOG.md"), "changes").unwrap();
+    let options = options
+        .with_branch_name("bot-name-only".to_string())
+        .with_author(CommitIdentity {
+            name: Some("Release Bot".to_string()),
+            email: None,
+        });
+    create_pr_from_workspace(&repo, &options).await.unwrap();
+    assert_eq!(
+        identity("bot-name-only"),
+        "Release Bot <test@example.com>|Release Bot <test@example.com>"
+    );
+}
+
 // ===== GitHub End-to-End Integration Tests =====
 
 #[tokio::test]
@@ -412,9 +651,7 @@ async fn test_github_integration_auth_client_api() {
         name: "integration-repo".to_string(),
         url: "https://github.com/owner/integration-repo.git".to_string(),
         path: Some(repo_path.to_string_lossy().to_string()),
-        tags: Vec::new(),
-        branch: None,
-        config_dir: None,
+        ..Default::default()
     };
 
     let options = PrOptions::new(
@@ -422,6 +659,7 @@ async fn test_github_integration_auth_client_api() {
         "This PR tests the integration flow".to_string(),
         token,
     )
+    .with_commit_all()
     .create_only();
 
     // Test the complete flow
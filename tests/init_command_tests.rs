@@ -1,4 +1,4 @@
-use repos::commands::{Command, CommandContext, init::InitCommand};
+use repos::commands::{Command, CommandContext, OutcomeRecorder, init::InitCommand};
 use repos::config::Config;
 use serial_test::serial;
 use std::fs;
@@ -41,6 +41,8 @@ async fn test_init_command_basic_creation() {
         output: output_path.to_string_lossy().to_string(),
         overwrite: false,
         supplement: false,
+        tag_depth: 0,
+        config_format: None,
     };
 
     let context = CommandContext {
@@ -49,6 +51,8 @@ async fn test_init_command_basic_creation() {
         exclude_tag: vec![],
         repos: None,
         parallel: false,
+        outcomes: OutcomeRecorder::new(),
+        jobs: None,
     };
 
     let original_dir = std::env::current_dir().unwrap();
@@ -84,6 +88,8 @@ async fn test_init_command_overwrite_existing_file() {
         output: output_path.to_string_lossy().to_string(),
         overwrite: true, // Should overwrite
         supplement: false,
+        tag_depth: 0,
+        config_format: None,
     };
 
     let context = CommandContext {
@@ -92,6 +98,8 @@ async fn test_init_command_overwrite_existing_file() {
         exclude_tag: vec![],
         repos: None,
         parallel: false,
+        outcomes: OutcomeRecorder::new(),
+        jobs: None,
     };
 
     let original_dir = std::env::current_dir().unwrap();
@@ -122,6 +130,8 @@ async fn test_init_command_no_overwrite_existing_file() {
         output: output_path.to_string_lossy().to_string(),
         overwrite: false, // Should not overwrite
         supplement: false,
+        tag_depth: 0,
+        config_format: None,
     };
 
     let context = CommandContext {
@@ -130,6 +140,8 @@ async fn test_init_command_no_overwrite_existing_file() {
         exclude_tag: vec![],
         repos: None,
         parallel: false,
+        outcomes: OutcomeRecorder::new(),
+        jobs: None,
     };
 
     let original_dir = std::env::current_dir().unwrap();
@@ -166,6 +178,8 @@ async fn test_init_command_with_git_repository() {
         output: output_path.to_string_lossy().to_string(),
         overwrite: false,
         supplement: false,
+        tag_depth: 0,
+        config_format: None,
     };
 
     let context = CommandContext {
@@ -174,6 +188,8 @@ async fn test_init_command_with_git_repository() {
         exclude_tag: vec![],
         repos: None,
         parallel: false,
+        outcomes: OutcomeRecorder::new(),
+        jobs: None,
     };
 
     let original_dir = std::env::current_dir().unwrap();
@@ -201,7 +217,8 @@ async fn test_init_command_supplement_with_duplicate_repository() {
             "test-repo".to_string(),
             "git@github.com:owner/test-repo.git".to_string(),
         )],
-        recipes: vec![],
+        version: None,
+        ..Default::default()
     };
     existing_config
         .save(&output_path.to_string_lossy())
@@ -217,6 +234,8 @@ async fn test_init_command_supplement_with_duplicate_repository() {
         output: output_path.to_string_lossy().to_string(),
         overwrite: false,
         supplement: true, // Should supplement but skip duplicates
+        tag_depth: 0,
+        config_format: None,
     };
 
     let context = CommandContext {
@@ -225,6 +244,8 @@ async fn test_init_command_supplement_with_duplicate_repository() {
         exclude_tag: vec![],
         repos: None,
         parallel: false,
+        outcomes: OutcomeRecorder::new(),
+        jobs: None,
     };
 
     let original_dir = std::env::current_dir().unwrap();
@@ -251,7 +272,8 @@ async fn test_init_command_supplement_with_new_repository() {
             "existing-repo".to_string(),
             "git@github.com:owner/existing-repo.git".to_string(),
         )],
-        recipes: vec![],
+        version: None,
+        ..Default::default()
     };
     existing_config
         .save(&output_path.to_string_lossy())
@@ -267,6 +289,8 @@ async fn test_init_command_supplement_with_new_repository() {
         output: output_path.to_string_lossy().to_string(),
         overwrite: false,
         supplement: true, // Should supplement with new repo
+        tag_depth: 0,
+        config_format: None,
     };
 
     let context = CommandContext {
@@ -275,6 +299,8 @@ async fn test_init_command_supplement_with_new_repository() {
         exclude_tag: vec![],
         repos: None,
         parallel: false,
+        outcomes: OutcomeRecorder::new(),
+        jobs: None,
     };
 
     let original_dir = std::env::current_dir().unwrap();
@@ -314,6 +340,8 @@ async fn test_init_command_git_directory_edge_cases() {
         output: output_path.to_string_lossy().to_string(),
         overwrite: false,
         supplement: false,
+        tag_depth: 0,
+        config_format: None,
     };
 
     let context = CommandContext {
@@ -322,6 +350,8 @@ async fn test_init_command_git_directory_edge_cases() {
         exclude_tag: vec![],
         repos: None,
         parallel: false,
+        outcomes: OutcomeRecorder::new(),
+        jobs: None,
     };
 
     let original_dir = std::env::current_dir().unwrap();
@@ -346,6 +376,8 @@ async fn test_init_command_empty_directory() {
         output: output_path.to_string_lossy().to_string(),
         overwrite: false,
         supplement: false,
+        tag_depth: 0,
+        config_format: None,
     };
 
     let context = CommandContext {
@@ -354,6 +386,8 @@ async fn test_init_command_empty_directory() {
         exclude_tag: vec![],
         repos: None,
         parallel: false,
+        outcomes: OutcomeRecorder::new(),
+        jobs: None,
     };
 
     let original_dir = std::env::current_dir().unwrap();
@@ -392,6 +426,8 @@ async fn test_init_command_multiple_git_repositories() {
         output: output_path.to_string_lossy().to_string(),
         overwrite: false,
         supplement: false,
+        tag_depth: 0,
+        config_format: None,
     };
 
     let context = CommandContext {
@@ -400,6 +436,8 @@ async fn test_init_command_multiple_git_repositories() {
         exclude_tag: vec![],
         repos: None,
         parallel: false,
+        outcomes: OutcomeRecorder::new(),
+        jobs: None,
     };
 
     let original_dir = std::env::current_dir().unwrap();
@@ -447,6 +485,8 @@ async fn test_init_command_integration_flow() {
         output: output_path.to_string_lossy().to_string(),
         overwrite: false,
         supplement: false,
+        tag_depth: 0,
+        config_format: None,
     };
 
     let context = CommandContext {
@@ -455,6 +495,8 @@ async fn test_init_command_integration_flow() {
         exclude_tag: vec![],
         repos: None,
         parallel: false,
+        outcomes: OutcomeRecorder::new(),
+        jobs: None,
     };
 
     let original_dir = std::env::current_dir().unwrap();
@@ -503,6 +545,8 @@ async fn test_init_command_discovers_repos_two_levels_deep() {
         output: output_path.to_string_lossy().to_string(),
         overwrite: false,
         supplement: false,
+        tag_depth: 0,
+        config_format: None,
     };
 
     let context = CommandContext {
@@ -511,6 +555,8 @@ async fn test_init_command_discovers_repos_two_levels_deep() {
         exclude_tag: vec![],
         repos: None,
         parallel: false,
+        outcomes: OutcomeRecorder::new(),
+        jobs: None,
     };
 
     let original_dir = std::env::current_dir().unwrap();
@@ -596,6 +642,8 @@ async fn test_init_command_depth_boundary() {
         output: output_path.to_string_lossy().to_string(),
         overwrite: false,
         supplement: false,
+        tag_depth: 0,
+        config_format: None,
     };
 
     let context = CommandContext {
@@ -604,6 +652,8 @@ async fn test_init_command_depth_boundary() {
         exclude_tag: vec![],
         repos: None,
         parallel: false,
+        outcomes: OutcomeRecorder::new(),
+        jobs: None,
     };
 
     let original_dir = std::env::current_dir().unwrap();
@@ -632,3 +682,97 @@ async fn test_init_command_depth_boundary() {
     assert!(repo_names.contains(&"repo3"));
     assert!(!repo_names.contains(&"repo4")); // Should not be discovered
 }
+
+/// Run init with `tag_depth` over `root` and return each repository's tags by name
+async fn init_tags(root: &std::path::Path, tag_depth: usize) -> Vec<(String, Vec<String>)> {
+    let output_path = root.join(format!("tags-{tag_depth}.yaml"));
+    let command = InitCommand {
+        output: output_path.to_string_lossy().to_string(),
+        overwrite: true,
+        supplement: false,
+        tag_depth,
+        config_format: None,
+    };
+    let context = CommandContext {
+        config: Config::new(),
+        tag: vec![],
+        exclude_tag: vec![],
+        repos: None,
+        parallel: false,
+        outcomes: OutcomeRecorder::new(),
+        jobs: None,
+    };
+
+    let original_dir = std::env::current_dir().unwrap();
+    std::env::set_current_dir(root).unwrap();
+    let result = command.execute(&context).await;
+    std::env::set_current_dir(original_dir).unwrap();
+    result.unwrap();
+
+    let config = Config::load(&output_path.to_string_lossy()).unwrap();
+    let mut tags: Vec<_> = config
+        .repositories
+        .into_iter()
+        .map(|repo| (repo.name, repo.tags))
+        .collect();
+    tags.sort();
+    tags
+}
+
+#[tokio::test]
+#[serial]
+async fn test_init_command_tag_depth_over_nested_tree() {
+    let temp_dir = TempDir::new().unwrap();
+    for (path, name) in [
+        ("tools", "tools"),
+        ("platform/gateway", "gateway"),
+        ("platform/auth/service", "service"),
+    ] {
+        let repo_dir = temp_dir.path().join(path);
+        fs::create_dir_all(&repo_dir).unwrap();
+        create_git_repo(&repo_dir).unwrap();
+        std::process::Command::new("git")
+            .args([
+                "remote",
+                "add",
+                "origin",
+                &format!("git@github.com:test/{name}.git"),
+            ])
+            .current_dir(&repo_dir)
+            .output()
+            .unwrap();
+    }
+
+    let tags = |pairs: &[(&str, &[&str])]| -> Vec<(String, Vec<String>)> {
+        pairs
+            .iter()
+            .map(|(name, tags)| {
+                (
+                    name.to_string(),
+                    tags.iter().map(|tag| tag.to_string()).collect(),
+                )
+            })
+            .collect()
+    };
+
+    assert_eq!(
+        init_tags(temp_dir.path(), 0).await,
+        tags(&[("gateway", &[]), ("service", &[]), ("tools", &[])])
+    );
+    assert_eq!(
+        init_tags(temp_dir.path(), 1).await,
+        tags(&[
+            ("gateway", &["platform"]),
+            ("service", &["platform"]),
+            ("tools", &[]),
+        ])
+    );
+    assert_eq!(
+        init_tags(temp_dir.path(), 2).await,
+        tags(&[
+            ("gateway", &["platform"]),
+            ("service", &["platform", "auth"]),
+            ("tools", &[]),
+        ])
+    );
+}
@@ -1,3 +1,4 @@
+pub mod api_tests;
 pub mod cli_tests;
 pub mod git_tests;
 pub mod github_tests;
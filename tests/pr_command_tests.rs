@@ -2,7 +2,7 @@
 //! Tests cover command execution, repository filtering, parallel execution, and error handling
 
 use repos::commands::pr::PrCommand;
-use repos::commands::{Command, CommandContext};
+use repos::commands::{Command, CommandContext, OutcomeRecorder};
 use repos::config::{Config, Repository};
 
 /// Helper function to create a test config with repositories
@@ -30,7 +30,8 @@ fn create_test_config() -> Config {
 
     Config {
         repositories: vec![repo1, repo2, repo3],
-        recipes: vec![],
+        version: None,
+        ..Default::default()
     }
 }
 
@@ -48,6 +49,8 @@ fn create_test_context(
         exclude_tag,
         parallel,
         repos,
+        outcomes: OutcomeRecorder::new(),
+        jobs: None,
     }
 }
 
@@ -59,12 +62,9 @@ async fn test_pr_command_basic_execution() {
     let pr_command = PrCommand {
         title: "Test PR".to_string(),
         body: "Test body".to_string(),
-        branch_name: None,
-        base_branch: None,
-        commit_msg: None,
-        draft: false,
         token: "fake-token".to_string(),
         create_only: true, // Avoid actual GitHub API calls
+        ..Default::default()
     };
 
     // Should not panic and complete execution
@@ -81,12 +81,9 @@ async fn test_pr_command_with_tag_filter() {
     let pr_command = PrCommand {
         title: "Backend PR".to_string(),
         body: "Backend changes".to_string(),
-        branch_name: None,
-        base_branch: None,
-        commit_msg: None,
-        draft: false,
         token: "fake-token".to_string(),
         create_only: true,
+        ..Default::default()
     };
 
     let result = pr_command.execute(&context).await;
@@ -107,12 +104,9 @@ async fn test_pr_command_with_specific_repos() {
     let pr_command = PrCommand {
         title: "Specific repos PR".to_string(),
         body: "Changes for specific repos".to_string(),
-        branch_name: None,
-        base_branch: None,
-        commit_msg: None,
-        draft: false,
         token: "fake-token".to_string(),
         create_only: true,
+        ..Default::default()
     };
 
     let result = pr_command.execute(&context).await;
@@ -133,12 +127,9 @@ async fn test_pr_command_with_tag_and_repos_filter() {
     let pr_command = PrCommand {
         title: "Filtered PR".to_string(),
         body: "Changes for filtered repos".to_string(),
-        branch_name: None,
-        base_branch: None,
-        commit_msg: None,
-        draft: false,
         token: "fake-token".to_string(),
         create_only: true,
+        ..Default::default()
     };
 
     let result = pr_command.execute(&context).await;
@@ -153,12 +144,9 @@ async fn test_pr_command_no_matching_repositories() {
     let pr_command = PrCommand {
         title: "No repos PR".to_string(),
         body: "Should find no repos".to_string(),
-        branch_name: None,
-        base_branch: None,
-        commit_msg: None,
-        draft: false,
         token: "fake-token".to_string(),
         create_only: true,
+        ..Default::default()
     };
 
     // Should succeed (print message about no repos found)
@@ -169,20 +157,17 @@ async fn test_pr_command_no_matching_repositories() {
 #[tokio::test]
 async fn test_pr_command_empty_repositories() {
     let config = Config {
-        repositories: vec![],
-        recipes: vec![],
+        version: None,
+        ..Default::default()
     };
     let context = create_test_context(config, vec![], vec![], None, false);
 
     let pr_command = PrCommand {
         title: "Empty config PR".to_string(),
         body: "No repositories in config".to_string(),
-        branch_name: None,
-        base_branch: None,
-        commit_msg: None,
-        draft: false,
         token: "fake-token".to_string(),
         create_only: true,
+        ..Default::default()
     };
 
     // Should succeed (print message about no repos found)
@@ -198,12 +183,9 @@ async fn test_pr_command_parallel_execution() {
     let pr_command = PrCommand {
         title: "Parallel PR".to_string(),
         body: "Parallel execution test".to_string(),
-        branch_name: None,
-        base_branch: None,
-        commit_msg: None,
-        draft: false,
         token: "fake-token".to_string(),
         create_only: true,
+        ..Default::default()
     };
 
     let result = pr_command.execute(&context).await;
@@ -219,11 +201,9 @@ async fn test_pr_command_with_custom_branch_name() {
         title: "Custom branch PR".to_string(),
         body: "PR with custom branch".to_string(),
         branch_name: Some("feature/custom-branch".to_string()),
-        base_branch: None,
-        commit_msg: None,
-        draft: false,
         token: "fake-token".to_string(),
         create_only: true,
+        ..Default::default()
     };
 
     let result = pr_command.execute(&context).await;
@@ -238,12 +218,10 @@ async fn test_pr_command_with_custom_base_branch() {
     let pr_command = PrCommand {
         title: "Custom base PR".to_string(),
         body: "PR with custom base branch".to_string(),
-        branch_name: None,
         base_branch: Some("develop".to_string()),
-        commit_msg: None,
-        draft: false,
         token: "fake-token".to_string(),
         create_only: true,
+        ..Default::default()
     };
 
     let result = pr_command.execute(&context).await;
@@ -258,12 +236,10 @@ async fn test_pr_command_with_custom_commit_message() {
     let pr_command = PrCommand {
         title: "Custom commit PR".to_string(),
         body: "PR with custom commit message".to_string(),
-        branch_name: None,
-        base_branch: None,
         commit_msg: Some("feat: add new feature".to_string()),
-        draft: false,
         token: "fake-token".to_string(),
         create_only: true,
+        ..Default::default()
     };
 
     let result = pr_command.execute(&context).await;
@@ -278,12 +254,10 @@ async fn test_pr_command_draft_mode() {
     let pr_command = PrCommand {
         title: "Draft PR".to_string(),
         body: "Draft pull request".to_string(),
-        branch_name: None,
-        base_branch: None,
-        commit_msg: None,
         draft: true,
         token: "fake-token".to_string(),
         create_only: true,
+        ..Default::default()
     };
 
     let result = pr_command.execute(&context).await;
@@ -298,12 +272,9 @@ async fn test_pr_command_create_only_mode() {
     let pr_command = PrCommand {
         title: "Create only PR".to_string(),
         body: "Create only mode test".to_string(),
-        branch_name: None,
-        base_branch: None,
-        commit_msg: None,
-        draft: false,
         token: "fake-token".to_string(),
         create_only: true,
+        ..Default::default()
     };
 
     let result = pr_command.execute(&context).await;
@@ -318,12 +289,9 @@ async fn test_pr_command_without_create_only() {
     let pr_command = PrCommand {
         title: "Full PR".to_string(),
         body: "Full PR creation test".to_string(),
-        branch_name: None,
-        base_branch: None,
-        commit_msg: None,
-        draft: false,
         token: "fake-token".to_string(),
         create_only: false, // This will try to push and create actual PR
+        ..Default::default()
     };
 
     // This should fail since we're using a fake token
@@ -339,12 +307,9 @@ async fn test_pr_command_empty_token() {
     let pr_command = PrCommand {
         title: "Empty token PR".to_string(),
         body: "PR with empty token".to_string(),
-        branch_name: None,
-        base_branch: None,
-        commit_msg: None,
-        draft: false,
         token: "".to_string(), // Empty token
         create_only: true,
+        ..Default::default()
     };
 
     let result = pr_command.execute(&context).await;
@@ -359,12 +324,9 @@ async fn test_pr_command_special_characters_in_title() {
     let pr_command = PrCommand {
         title: "PR with special chars: 你好 🚀 @#$%".to_string(),
         body: "Body with\nmultiple\nlines".to_string(),
-        branch_name: None,
-        base_branch: None,
-        commit_msg: None,
-        draft: false,
         token: "fake-token".to_string(),
         create_only: true,
+        ..Default::default()
     };
 
     let result = pr_command.execute(&context).await;
@@ -380,12 +342,9 @@ async fn test_pr_command_very_long_title() {
     let pr_command = PrCommand {
         title: long_title,
         body: "PR with very long title".to_string(),
-        branch_name: None,
-        base_branch: None,
-        commit_msg: None,
-        draft: false,
         token: "fake-token".to_string(),
         create_only: true,
+        ..Default::default()
     };
 
     let result = pr_command.execute(&context).await;
@@ -401,12 +360,9 @@ async fn test_pr_command_very_long_body() {
     let pr_command = PrCommand {
         title: "PR with long body".to_string(),
         body: long_body,
-        branch_name: None,
-        base_branch: None,
-        commit_msg: None,
-        draft: false,
         token: "fake-token".to_string(),
         create_only: true,
+        ..Default::default()
     };
 
     let result = pr_command.execute(&context).await;
@@ -433,6 +389,7 @@ async fn test_pr_command_all_options_combined() {
         draft: true,
         token: "fake-token".to_string(),
         create_only: true,
+        ..Default::default()
     };
 
     let result = pr_command.execute(&context).await;
@@ -453,12 +410,9 @@ async fn test_pr_command_invalid_repository_names() {
     let pr_command = PrCommand {
         title: "Invalid repos PR".to_string(),
         body: "PR for nonexistent repos".to_string(),
-        branch_name: None,
-        base_branch: None,
-        commit_msg: None,
-        draft: false,
         token: "fake-token".to_string(),
         create_only: true,
+        ..Default::default()
     };
 
     // Should succeed (print message about no repos found)
@@ -484,12 +438,9 @@ async fn test_pr_command_mixed_valid_invalid_repos() {
     let pr_command = PrCommand {
         title: "Mixed repos PR".to_string(),
         body: "PR for mix of valid and invalid repos".to_string(),
-        branch_name: None,
-        base_branch: None,
-        commit_msg: None,
-        draft: false,
         token: "fake-token".to_string(),
         create_only: true,
+        ..Default::default()
     };
 
     let result = pr_command.execute(&context).await;
@@ -504,12 +455,9 @@ async fn test_pr_command_case_sensitive_tag_filter() {
     let pr_command = PrCommand {
         title: "Case sensitive PR".to_string(),
         body: "Testing case sensitivity".to_string(),
-        branch_name: None,
-        base_branch: None,
-        commit_msg: None,
-        draft: false,
         token: "fake-token".to_string(),
         create_only: true,
+        ..Default::default()
     };
 
     // Should find no repos because tags are case sensitive
@@ -531,12 +479,9 @@ async fn test_pr_command_case_sensitive_repo_names() {
     let pr_command = PrCommand {
         title: "Case sensitive repos PR".to_string(),
         body: "Testing repo name case sensitivity".to_string(),
-        branch_name: None,
-        base_branch: None,
-        commit_msg: None,
-        draft: false,
         token: "fake-token".to_string(),
         create_only: true,
+        ..Default::default()
     };
 
     // Should find no repos because repo names are case sensitive
@@ -558,12 +503,9 @@ async fn test_pr_command_with_exclude_tag() {
     let pr_command = PrCommand {
         title: "Backend only PR".to_string(),
         body: "Excludes frontend repos".to_string(),
-        branch_name: None,
-        base_branch: None,
-        commit_msg: None,
-        draft: false,
         token: "fake-token".to_string(),
         create_only: true,
+        ..Default::default()
     };
 
     // Should only work with backend repos (repo2, repo3)
@@ -585,12 +527,9 @@ async fn test_pr_command_with_multiple_exclude_tags() {
     let pr_command = PrCommand {
         title: "Rust only PR".to_string(),
         body: "Excludes frontend and database repos".to_string(),
-        branch_name: None,
-        base_branch: None,
-        commit_msg: None,
-        draft: false,
         token: "fake-token".to_string(),
         create_only: true,
+        ..Default::default()
     };
 
     // Should only work with repo2 (rust backend, no database tag)
@@ -612,12 +551,9 @@ async fn test_pr_command_with_inclusion_and_exclusion() {
     let pr_command = PrCommand {
         title: "Backend no DB PR".to_string(),
         body: "Backend repos but not database ones".to_string(),
-        branch_name: None,
-        base_branch: None,
-        commit_msg: None,
-        draft: false,
         token: "fake-token".to_string(),
         create_only: true,
+        ..Default::default()
     };
 
     // Should only work with repo2 (backend but not database)
@@ -639,12 +575,9 @@ async fn test_pr_command_exclude_all_repos() {
     let pr_command = PrCommand {
         title: "No repos PR".to_string(),
         body: "Should exclude all repos".to_string(),
-        branch_name: None,
-        base_branch: None,
-        commit_msg: None,
-        draft: false,
         token: "fake-token".to_string(),
         create_only: true,
+        ..Default::default()
     };
 
     // Should find no repos
@@ -666,15 +599,102 @@ async fn test_pr_command_multiple_inclusion_tags() {
     let pr_command = PrCommand {
         title: "Multi-tag PR".to_string(),
         body: "Includes repos with frontend OR rust tags".to_string(),
-        branch_name: None,
-        base_branch: None,
-        commit_msg: None,
-        draft: false,
         token: "fake-token".to_string(),
         create_only: true,
+        ..Default::default()
     };
 
     // Should work with repo1 (frontend) and repo2 (rust)
     let result = pr_command.execute(&context).await;
     assert!(result.is_ok() || result.is_err());
 }
+
+/// One clean clone, which has nothing to open a PR for, and `missing`
+/// repositories whose directories do not exist, which fail
+fn partially_failing_config(root: &std::path::Path, missing: usize) -> Config {
+    let clean = root.join("clean");
+    std::fs::create_dir(&clean).unwrap();
+    let status = std::process::Command::new("git")
+        .args(["init", "--quiet"])
+        .current_dir(&clean)
+        .status()
+        .unwrap();
+    assert!(status.success());
+
+    let mut config = create_test_config();
+    let mut repo = Repository::new(
+        "clean".to_string(),
+        "git@github.com:owner/clean.git".to_string(),
+    );
+    repo.path = Some(clean.to_string_lossy().into_owned());
+    config.repositories = vec![repo];
+    for i in 0..missing {
+        let mut repo = Repository::new(
+            format!("missing{i}"),
+            format!("git@github.com:owner/missing{i}.git"),
+        );
+        repo.path = Some(
+            root.join(format!("missing{i}"))
+                .to_string_lossy()
+                .into_owned(),
+        );
+        config.repositories.push(repo);
+    }
+    config
+}
+
+fn tolerant_pr_command(max_failures: usize) -> PrCommand {
+    PrCommand {
+        title: "Bulk PR".to_string(),
+        body: "Best effort".to_string(),
+        token: "fake-token".to_string(),
+        create_only: true,
+        max_failures: Some(max_failures),
+        ..Default::default()
+    }
+}
+
+#[tokio::test]
+async fn test_pr_command_failures_within_max_pr_failures() {
+    let temp_dir = tempfile::TempDir::new().unwrap();
+    let config = partially_failing_config(temp_dir.path(), 2);
+    let context = create_test_context(config, vec![], vec![], None, false);
+
+    let result = tolerant_pr_command(2).execute(&context).await;
+    assert!(result.is_ok(), "{:?}", result);
+
+    // Failures are still recorded for the report and exit policy
+    let failed: Vec<_> = context
+        .outcomes
+        .outcomes()
+        .into_iter()
+        .filter(|outcome| !outcome.success)
+        .map(|outcome| outcome.name)
+        .collect();
+    assert_eq!(failed, vec!["missing0", "missing1"]);
+
+    // Tolerated even when nothing succeeded
+    let temp_dir = tempfile::TempDir::new().unwrap();
+    let mut config = partially_failing_config(temp_dir.path(), 1);
+    config.repositories.remove(0);
+    let context = create_test_context(config, vec![], vec![], None, true);
+    assert!(tolerant_pr_command(1).execute(&context).await.is_ok());
+}
+
+#[tokio::test]
+async fn test_pr_command_failures_over_max_pr_failures() {
+    let temp_dir = tempfile::TempDir::new().unwrap();
+    let config = partially_failing_config(temp_dir.path(), 3);
+    let context = create_test_context(config, vec![], vec![], None, true);
+
+    let err = tolerant_pr_command(2)
+        .execute(&context)
+        .await
+        .unwrap_err()
+        .to_string();
+    assert!(
+        err.starts_with("3 pull request operations failed, more than --max-pr-failures 2"),
+        "{}",
+        err
+    );
+}
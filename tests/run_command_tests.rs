@@ -1,13 +1,16 @@
 use repos::{
     commands::{
-        Command, CommandContext,
+        Command, CommandContext, OutcomeHooks, OutcomeRecorder,
+        report::DEADLINE_ERROR,
         run::{RunCommand, RunType},
     },
-    config::{Config, Recipe, Repository},
+    config::{Config, Recipe, RecipeArg, RecipeArgs, Repository},
+    runner::OutputMatch,
 };
 use std::fs;
 use std::path::PathBuf;
 use std::process::Command as ProcessCommand;
+use std::time::{Duration, Instant};
 use tempfile::TempDir;
 
 // =================================
@@ -65,24 +68,28 @@ fn setup_recipe_test(
         url: format!("https://github.com/user/{}.git", repo_name),
         tags: vec!["test".to_string()],
         path: Some(repo_dir.to_string_lossy().to_string()),
-        branch: None,
-        config_dir: None,
+        ..Default::default()
     };
 
     let recipe = Recipe {
         name: recipe_name.to_string(),
         steps: steps.into_iter().map(|s| s.to_string()).collect(),
+        args: Vec::new(),
     };
 
     let context = CommandContext {
         config: Config {
             repositories: vec![repo.clone()],
             recipes: vec![recipe.clone()],
+            version: None,
+            ..Default::default()
         },
         tag: vec![],
         exclude_tag: vec![],
         repos: None,
         parallel: false,
+        outcomes: OutcomeRecorder::new(),
+        jobs: None,
     };
 
     (temp_dir, repo, recipe, context)
@@ -100,19 +107,21 @@ fn setup_basic_test(repo_name: &str) -> (TempDir, Repository, CommandContext) {
         url: format!("https://github.com/user/{}.git", repo_name),
         tags: vec!["test".to_string()],
         path: Some(repo_dir.to_string_lossy().to_string()),
-        branch: None,
-        config_dir: None,
+        ..Default::default()
     };
 
     let context = CommandContext {
         config: Config {
             repositories: vec![repo.clone()],
-            recipes: vec![],
+            version: None,
+            ..Default::default()
         },
         tag: vec![],
         exclude_tag: vec![],
         repos: None,
         parallel: false,
+        outcomes: OutcomeRecorder::new(),
+        jobs: None,
     };
 
     (temp_dir, repo, context)
@@ -133,8 +142,7 @@ fn setup_parallel_test(
         url: format!("https://github.com/user/{}.git", repo1_name),
         tags: vec!["test".to_string()],
         path: Some(repo1_dir.to_string_lossy().to_string()),
-        branch: None,
-        config_dir: None,
+        ..Default::default()
     };
 
     let repo2_dir = temp_dir.path().join(repo2_name);
@@ -145,20 +153,22 @@ fn setup_parallel_test(
         url: format!("https://github.com/user/{}.git", repo2_name),
         tags: vec!["test".to_string()],
         path: Some(repo2_dir.to_string_lossy().to_string()),
-        branch: None,
-        config_dir: None,
+        ..Default::default()
     };
 
     let repos = vec![repo1, repo2];
     let context = CommandContext {
         config: Config {
             repositories: repos.clone(),
-            recipes: vec![],
+            version: None,
+            ..Default::default()
         },
         tag: vec![],
         exclude_tag: vec![],
         repos: None,
         parallel: true,
+        outcomes: OutcomeRecorder::new(),
+        jobs: None,
     };
 
     (temp_dir, repos, context)
@@ -179,8 +189,7 @@ fn create_tagged_repo_setup(
         url: format!("https://github.com/user/{}.git", repo_name),
         tags: tags.into_iter().map(|s| s.to_string()).collect(),
         path: Some(repo_dir.to_string_lossy().to_string()),
-        branch: None,
-        config_dir: None,
+        ..Default::default()
     };
 
     (repo_dir, repo)
@@ -223,11 +232,15 @@ impl CommandContextBuilder {
             config: Config {
                 repositories: self.repositories,
                 recipes: self.recipes,
+                version: None,
+                ..Default::default()
             },
             tag: self.tag,
             exclude_tag: self.exclude_tag,
             repos: self.repos,
             parallel: self.parallel,
+            outcomes: OutcomeRecorder::new(),
+            jobs: None,
         }
     }
 }
@@ -242,13 +255,15 @@ async fn test_run_command_creation() {
     let command = RunCommand {
         run_type: RunType::Command("echo hello".to_string()),
         no_save: true,
-        output_dir: None,
+        ..Default::default()
     };
 
     // Test that the run_type contains the right command
     match &command.run_type {
         RunType::Command(cmd) => assert_eq!(cmd, "echo hello"),
-        RunType::Recipe(_) => panic!("Expected Command variant"),
+        RunType::Recipe(_) | RunType::Named { .. } | RunType::Exec(_) => {
+            panic!("Expected Command variant")
+        }
     }
     assert!(command.no_save);
     assert!(command.output_dir.is_none());
@@ -259,13 +274,14 @@ async fn test_run_command_creation() {
 async fn test_run_command_recipe_creation() {
     let command = RunCommand {
         run_type: RunType::Recipe("test-recipe".to_string()),
-        no_save: false,
-        output_dir: None,
+        ..Default::default()
     };
 
     match &command.run_type {
         RunType::Recipe(recipe) => assert_eq!(recipe, "test-recipe"),
-        RunType::Command(_) => panic!("Expected Recipe variant"),
+        RunType::Command(_) | RunType::Named { .. } | RunType::Exec(_) => {
+            panic!("Expected Recipe variant")
+        }
     }
     assert!(!command.no_save);
 }
@@ -275,13 +291,15 @@ async fn test_run_command_with_custom_output_dir() {
     let output_dir = PathBuf::from("/tmp/custom");
     let command = RunCommand {
         run_type: RunType::Command("ls".to_string()),
-        no_save: false,
         output_dir: Some(output_dir.clone()),
+        ..Default::default()
     };
 
     match &command.run_type {
         RunType::Command(cmd) => assert_eq!(cmd, "ls"),
-        RunType::Recipe(_) => panic!("Expected Command variant"),
+        RunType::Recipe(_) | RunType::Named { .. } | RunType::Exec(_) => {
+            panic!("Expected Command variant")
+        }
     }
     assert!(!command.no_save);
     assert_eq!(command.output_dir, Some(output_dir));
@@ -292,18 +310,20 @@ async fn test_run_command_empty_repositories() {
     let command = RunCommand {
         run_type: RunType::Command("echo test".to_string()),
         no_save: true,
-        output_dir: None,
+        ..Default::default()
     };
 
     let context = CommandContext {
         config: Config {
-            repositories: vec![],
-            recipes: vec![],
+            version: None,
+            ..Default::default()
         },
         tag: vec![],
         exclude_tag: vec![],
         parallel: false,
         repos: None,
+        outcomes: OutcomeRecorder::new(),
+        jobs: None,
     };
 
     let result = command.execute(&context).await;
@@ -317,7 +337,7 @@ async fn test_run_command_basic_execution() {
     let command = RunCommand {
         run_type: RunType::Command("echo hello".to_string()),
         no_save: true,
-        output_dir: None,
+        ..Default::default()
     };
 
     let result = command.execute(&context).await;
@@ -331,7 +351,7 @@ async fn test_run_command_parallel_execution() {
     let command = RunCommand {
         run_type: RunType::Command("echo hello".to_string()),
         no_save: true,
-        output_dir: None,
+        ..Default::default()
     };
 
     let result = command.execute(&context).await;
@@ -349,7 +369,7 @@ async fn test_run_command_with_tag_filter() {
     let command = RunCommand {
         run_type: RunType::Command("echo hello".to_string()),
         no_save: true,
-        output_dir: None,
+        ..Default::default()
     };
 
     let context = CommandContextBuilder::new()
@@ -368,7 +388,7 @@ async fn test_run_command_error_handling() {
     let command = RunCommand {
         run_type: RunType::Command("false".to_string()), // Command that will fail
         no_save: true,
-        output_dir: None,
+        ..Default::default()
     };
 
     let result = command.execute(&context).await;
@@ -381,18 +401,20 @@ async fn test_run_command_with_special_characters() {
     let command = RunCommand {
         run_type: RunType::Command("echo \"test with spaces and symbols: @#$%\"".to_string()),
         no_save: true,
-        output_dir: None,
+        ..Default::default()
     };
 
     let context = CommandContext {
         config: Config {
-            repositories: vec![],
-            recipes: vec![],
+            version: None,
+            ..Default::default()
         },
         tag: vec![],
         exclude_tag: vec![],
         parallel: false,
         repos: None,
+        outcomes: OutcomeRecorder::new(),
+        jobs: None,
     };
 
     let result = command.execute(&context).await;
@@ -406,18 +428,20 @@ async fn test_run_command_error_no_command_nor_recipe() {
     let command = RunCommand {
         run_type: RunType::Command("".to_string()), // Empty command
         no_save: true,
-        output_dir: None,
+        ..Default::default()
     };
 
     let context = CommandContext {
         config: Config {
-            repositories: vec![],
-            recipes: vec![],
+            version: None,
+            ..Default::default()
         },
         tag: vec![],
         exclude_tag: vec![],
         parallel: false,
         repos: None,
+        outcomes: OutcomeRecorder::new(),
+        jobs: None,
     };
 
     let result = command.execute(&context).await;
@@ -434,8 +458,8 @@ async fn test_run_command_existing_output_dir() {
 
     let command = RunCommand {
         run_type: RunType::Command("echo existing_out_dir".to_string()),
-        no_save: false,
         output_dir: Some(output_dir.clone()),
+        ..Default::default()
     };
 
     let result = command.execute(&context).await;
@@ -451,7 +475,7 @@ async fn test_run_recipe_without_shebang_implicit_shell() {
     let command = RunCommand {
         run_type: RunType::Recipe("no-shebang".to_string()),
         no_save: true,
-        output_dir: None,
+        ..Default::default()
     };
 
     let result = command.execute(&context).await;
@@ -469,6 +493,7 @@ async fn test_run_recipe_parallel_failure_branch() {
             "echo FIRST".to_string(),
             "this-command-should-not-exist-12345".to_string(),
         ],
+        args: Vec::new(),
     };
 
     // Update context to include the recipe
@@ -476,17 +501,21 @@ async fn test_run_recipe_parallel_failure_branch() {
         config: Config {
             repositories: context.config.repositories,
             recipes: vec![recipe],
+            version: None,
+            ..Default::default()
         },
         tag: context.tag,
         exclude_tag: context.exclude_tag,
         parallel: true, // Enable parallel execution
         repos: context.repos,
+        outcomes: OutcomeRecorder::new(),
+        jobs: None,
     };
 
     let command = RunCommand {
         run_type: RunType::Recipe("parallel-failure".to_string()),
         no_save: true,
-        output_dir: None,
+        ..Default::default()
     };
 
     let result = command.execute(&context).await;
@@ -503,7 +532,7 @@ async fn test_run_command_skip_save_branch() {
     let command = RunCommand {
         run_type: RunType::Command("echo SKIP_SAVE_MODE".to_string()),
         no_save: true, // Skip save mode
-        output_dir: None,
+        ..Default::default()
     };
 
     let result = command.execute(&context).await;
@@ -518,8 +547,8 @@ async fn test_run_long_command_name_sanitization() {
     let long_cmd = "echo THIS_IS_A_REALLY_LONG_COMMAND_NAME_WITH_SPECIAL_CHARS_%_#_@_!_____END";
     let command = RunCommand {
         run_type: RunType::Command(long_cmd.to_string()),
-        no_save: false,
         output_dir: Some(temp_dir.path().join("long_cmd_output")),
+        ..Default::default()
     };
 
     let result = command.execute(&context).await;
@@ -539,7 +568,7 @@ async fn test_run_recipe_script_creation_error_handling() {
     let command = RunCommand {
         run_type: RunType::Recipe("script-creation".to_string()),
         no_save: true,
-        output_dir: None,
+        ..Default::default()
     };
 
     let result = command.execute(&context).await;
@@ -557,7 +586,7 @@ async fn test_run_recipe_with_readonly_directory() {
     let command = RunCommand {
         run_type: RunType::Recipe("readonly-test".to_string()),
         no_save: true,
-        output_dir: None,
+        ..Default::default()
     };
 
     let result = command.execute(&context).await;
@@ -572,7 +601,9 @@ async fn test_run_command_new_command() {
 
     match &command.run_type {
         RunType::Command(cmd) => assert_eq!(cmd, "echo test"),
-        RunType::Recipe(_) => panic!("Expected Command variant"),
+        RunType::Recipe(_) | RunType::Named { .. } | RunType::Exec(_) => {
+            panic!("Expected Command variant")
+        }
     }
     assert!(command.no_save);
     assert!(command.output_dir.is_none());
@@ -585,7 +616,9 @@ async fn test_run_command_new_recipe() {
 
     match &command.run_type {
         RunType::Recipe(recipe) => assert_eq!(recipe, "my-recipe"),
-        RunType::Command(_) => panic!("Expected Recipe variant"),
+        RunType::Command(_) | RunType::Named { .. } | RunType::Exec(_) => {
+            panic!("Expected Recipe variant")
+        }
     }
     assert!(!command.no_save);
     assert_eq!(command.output_dir, output_dir);
@@ -597,7 +630,9 @@ async fn test_run_command_new_for_test() {
 
     match &command.run_type {
         RunType::Command(cmd) => assert_eq!(cmd, "test command"),
-        RunType::Recipe(_) => panic!("Expected Command variant"),
+        RunType::Recipe(_) | RunType::Named { .. } | RunType::Exec(_) => {
+            panic!("Expected Command variant")
+        }
     }
     assert!(!command.no_save);
     assert_eq!(command.output_dir, Some(PathBuf::from("/tmp/test")));
@@ -614,7 +649,7 @@ async fn test_run_command_recipe_execution() {
     let command = RunCommand {
         run_type: RunType::Recipe("test-recipe".to_string()),
         no_save: true,
-        output_dir: None,
+        ..Default::default()
     };
 
     let result = command.execute(&context).await;
@@ -626,18 +661,20 @@ async fn test_run_command_recipe_not_found() {
     let command = RunCommand {
         run_type: RunType::Recipe("nonexistent-recipe".to_string()),
         no_save: true,
-        output_dir: None,
+        ..Default::default()
     };
 
     let context = CommandContext {
         config: Config {
-            repositories: vec![],
-            recipes: vec![],
+            version: None,
+            ..Default::default()
         },
         tag: vec![],
         exclude_tag: vec![],
         repos: None,
         parallel: false,
+        outcomes: OutcomeRecorder::new(),
+        jobs: None,
     };
 
     let result = command.execute(&context).await;
@@ -658,6 +695,7 @@ async fn test_run_command_recipe_parallel_execution() {
     let recipe = Recipe {
         name: "parallel-recipe".to_string(),
         steps: vec!["echo 'Parallel recipe execution'".to_string()],
+        args: Vec::new(),
     };
     context.config.recipes.push(recipe);
     context.parallel = true;
@@ -665,7 +703,7 @@ async fn test_run_command_recipe_parallel_execution() {
     let command = RunCommand {
         run_type: RunType::Recipe("parallel-recipe".to_string()),
         no_save: true,
-        output_dir: None,
+        ..Default::default()
     };
 
     let result = command.execute(&context).await;
@@ -690,7 +728,7 @@ async fn test_run_command_with_exclude_tag() {
     let command = RunCommand {
         run_type: RunType::Command("echo exclude_test".to_string()),
         no_save: true,
-        output_dir: None,
+        ..Default::default()
     };
 
     let result = command.execute(&context).await;
@@ -715,7 +753,7 @@ async fn test_run_command_with_specific_repos() {
     let command = RunCommand {
         run_type: RunType::Command("echo specific_repo_test".to_string()),
         no_save: true,
-        output_dir: None,
+        ..Default::default()
     };
 
     let result = command.execute(&context).await;
@@ -735,6 +773,7 @@ async fn test_run_command_with_output_directory_creation() {
         run_type: RunType::Command("echo 'Testing output directory'".to_string()),
         no_save: false, // Enable saving to test directory creation
         output_dir: Some(output_dir.clone()),
+        ..Default::default()
     };
 
     let result = command.execute(&context).await;
@@ -766,8 +805,7 @@ async fn test_run_command_mixed_success_failure_sequential() {
         url: "https://github.com/user/good-repo.git".to_string(),
         tags: vec!["test".to_string()],
         path: Some(repo_dir1.to_string_lossy().to_string()),
-        branch: None,
-        config_dir: None,
+        ..Default::default()
     };
 
     let bad_repo = Repository {
@@ -775,25 +813,27 @@ async fn test_run_command_mixed_success_failure_sequential() {
         url: "https://github.com/user/bad-repo.git".to_string(),
         tags: vec!["test".to_string()],
         path: Some(bad_repo_path.to_string_lossy().to_string()),
-        branch: None,
-        config_dir: None,
+        ..Default::default()
     };
 
     let command = RunCommand {
         run_type: RunType::Command("echo hello".to_string()),
         no_save: true,
-        output_dir: None,
+        ..Default::default()
     };
 
     let context = CommandContext {
         config: Config {
             repositories: vec![good_repo, bad_repo],
-            recipes: vec![],
+            version: None,
+            ..Default::default()
         },
         tag: vec![],
         exclude_tag: vec![],
         repos: None,
         parallel: false,
+        outcomes: OutcomeRecorder::new(),
+        jobs: None,
     };
 
     let result = command.execute(&context).await;
@@ -808,18 +848,20 @@ async fn test_run_command_empty_command_string() {
     let command = RunCommand {
         run_type: RunType::Command("".to_string()),
         no_save: true,
-        output_dir: None,
+        ..Default::default()
     };
 
     let context = CommandContext {
         config: Config {
-            repositories: vec![],
-            recipes: vec![],
+            version: None,
+            ..Default::default()
         },
         tag: vec![],
         exclude_tag: vec![],
         parallel: false,
         repos: None,
+        outcomes: OutcomeRecorder::new(),
+        jobs: None,
     };
 
     let result = command.execute(&context).await;
@@ -839,6 +881,7 @@ async fn test_run_command_with_save_enabled() {
         run_type: RunType::Command("echo 'save test'".to_string()),
         no_save: false, // Enable saving
         output_dir: Some(output_dir.clone()),
+        ..Default::default()
     };
 
     let result = command.execute(&context).await;
@@ -857,6 +900,7 @@ async fn test_run_command_with_save_default_output_dir() {
         run_type: RunType::Command("echo 'default output test'".to_string()),
         no_save: false,   // Enable saving
         output_dir: None, // Use default "output" directory
+        ..Default::default()
     };
 
     let result = command.execute(&context).await;
@@ -875,6 +919,7 @@ async fn test_run_command_parallel_with_save() {
         run_type: RunType::Command("echo 'parallel save test'".to_string()),
         no_save: false, // Enable saving
         output_dir: Some(output_dir.clone()),
+        ..Default::default()
     };
 
     let result = command.execute(&context).await;
@@ -893,7 +938,7 @@ async fn test_run_command_parallel_with_no_save() {
     let command = RunCommand {
         run_type: RunType::Command("echo 'parallel no save test'".to_string()),
         no_save: true, // Disable saving
-        output_dir: None,
+        ..Default::default()
     };
 
     let result = command.execute(&context).await;
@@ -915,6 +960,7 @@ async fn test_run_command_recipe_with_save_enabled() {
         run_type: RunType::Recipe("save-recipe".to_string()),
         no_save: false, // Enable saving
         output_dir: Some(output_dir.clone()),
+        ..Default::default()
     };
 
     let result = command.execute(&context).await;
@@ -936,6 +982,7 @@ async fn test_run_command_recipe_parallel_with_save() {
     let recipe = Recipe {
         name: "parallel-save-recipe".to_string(),
         steps: vec!["echo 'Parallel recipe with save'".to_string()],
+        args: Vec::new(),
     };
     context.config.recipes.push(recipe);
     context.parallel = true; // Enable parallel execution
@@ -944,6 +991,7 @@ async fn test_run_command_recipe_parallel_with_save() {
         run_type: RunType::Recipe("parallel-save-recipe".to_string()),
         no_save: false, // Enable saving
         output_dir: Some(output_dir.clone()),
+        ..Default::default()
     };
 
     let result = command.execute(&context).await;
@@ -962,6 +1010,7 @@ async fn test_run_command_recipe_parallel_with_no_save() {
     let recipe = Recipe {
         name: "parallel-no-save-recipe".to_string(),
         steps: vec!["echo 'Parallel recipe without save'".to_string()],
+        args: Vec::new(),
     };
     context.config.recipes.push(recipe);
     context.parallel = true; // Enable parallel execution
@@ -969,7 +1018,7 @@ async fn test_run_command_recipe_parallel_with_no_save() {
     let command = RunCommand {
         run_type: RunType::Recipe("parallel-no-save-recipe".to_string()),
         no_save: true, // Disable saving
-        output_dir: None,
+        ..Default::default()
     };
 
     let result = command.execute(&context).await;
@@ -985,7 +1034,7 @@ async fn test_run_command_recipe_sequential_with_no_save() {
     let command = RunCommand {
         run_type: RunType::Recipe("sequential-no-save-recipe".to_string()),
         no_save: true, // Disable saving
-        output_dir: None,
+        ..Default::default()
     };
 
     let result = command.execute(&context).await;
@@ -1003,7 +1052,7 @@ async fn test_script_materialization_with_shebang() {
     let command = RunCommand {
         run_type: RunType::Recipe("shebang-recipe".to_string()),
         no_save: true,
-        output_dir: None,
+        ..Default::default()
     };
 
     let result = command.execute(&context).await;
@@ -1019,7 +1068,7 @@ async fn test_script_materialization_without_shebang() {
     let command = RunCommand {
         run_type: RunType::Recipe("no-shebang-recipe".to_string()),
         no_save: true,
-        output_dir: None,
+        ..Default::default()
     };
 
     let result = command.execute(&context).await;
@@ -1036,6 +1085,7 @@ async fn test_sanitize_command_for_filename() {
         run_type: RunType::Command("echo 'test with / \\ : * ? \" < > | characters'".to_string()),
         no_save: false, // Enable saving to test sanitization
         output_dir: Some(temp_dir.path().join("sanitize_test")),
+        ..Default::default()
     };
 
     let result = command.execute(&context).await;
@@ -1054,7 +1104,7 @@ async fn test_sanitize_script_name() {
     let command = RunCommand {
         run_type: RunType::Recipe("Recipe-With.Special@Characters#And$Symbols%".to_string()),
         no_save: true,
-        output_dir: None,
+        ..Default::default()
     };
 
     let result = command.execute(&context).await;
@@ -1074,6 +1124,7 @@ async fn test_long_command_name_truncation() {
         run_type: RunType::Command(long_command),
         no_save: false, // Enable saving to test truncation
         output_dir: Some(temp_dir.path().join("long_command_test")),
+        ..Default::default()
     };
 
     let result = command.execute(&context).await;
@@ -1091,7 +1142,7 @@ async fn test_recipe_sequential_execution_with_script_error() {
     let command = RunCommand {
         run_type: RunType::Recipe("script-error-recipe".to_string()),
         no_save: true,
-        output_dir: None,
+        ..Default::default()
     };
 
     let result = command.execute(&context).await;
@@ -1100,6 +1151,217 @@ async fn test_recipe_sequential_execution_with_script_error() {
     assert!(result.is_ok());
 }
 
+#[tokio::test]
+async fn test_recipe_args_are_rendered_and_exported() {
+    let (_temp_dir, repo, _recipe, mut context) = setup_recipe_test(
+        "test-repo",
+        "release",
+        vec![
+            "echo \"{{.version}} {{ .channel }}\" > rendered.txt",
+            "echo \"$ARG_VERSION $ARG_CHANNEL\" > exported.txt",
+        ],
+    );
+    context.config.recipes[0].args = vec![
+        RecipeArg {
+            name: "version".to_string(),
+            default: None,
+        },
+        RecipeArg {
+            name: "channel".to_string(),
+            default: Some("beta".to_string()),
+        },
+    ];
+
+    let command = RunCommand::new_recipe("release".to_string(), true, None).with_recipe_args(
+        RecipeArgs::from([("version".to_string(), "1.2.3".to_string())]),
+    );
+    command.execute(&context).await.unwrap();
+
+    let repo_dir = PathBuf::from(repo.get_target_dir());
+    assert_eq!(
+        fs::read_to_string(repo_dir.join("rendered.txt")).unwrap(),
+        "1.2.3 beta\n"
+    );
+    assert_eq!(
+        fs::read_to_string(repo_dir.join("exported.txt")).unwrap(),
+        "1.2.3 beta\n"
+    );
+}
+
+#[tokio::test]
+async fn test_recipe_missing_required_arg_fails_before_running() {
+    let (_temp_dir, repo, _recipe, mut context) = setup_recipe_test(
+        "test-repo",
+        "release",
+        vec!["touch ran.txt", "echo {{.version}}"],
+    );
+    context.config.recipes[0].args = vec![RecipeArg {
+        name: "version".to_string(),
+        default: None,
+    }];
+
+    let command = RunCommand::new_recipe("release".to_string(), true, None);
+    let err = command.execute(&context).await.unwrap_err();
+    assert_eq!(
+        err.to_string(),
+        "Recipe 'release' requires --arg version=<value>"
+    );
+    assert!(
+        !PathBuf::from(repo.get_target_dir())
+            .join("ran.txt")
+            .exists()
+    );
+}
+
+#[tokio::test]
+async fn test_outcome_hooks_run_by_result() {
+    let (_temp_dir, repos, context) = setup_parallel_test("passing", "failing");
+    let passing_dir = PathBuf::from(repos[0].get_target_dir());
+    let failing_dir = PathBuf::from(repos[1].get_target_dir());
+    fs::write(passing_dir.join("ready"), "").unwrap();
+
+    let command = RunCommand::new_command("test -f ready".to_string(), true, None)
+        .with_outcome_hooks(OutcomeHooks {
+            on_success: Some("echo \"$REPOS_REPO_NAME $REPOS_RESULT\" > hook.txt".to_string()),
+            on_failure: Some(
+                "echo \"$REPOS_REPO_NAME $REPOS_RESULT $REPOS_ERROR\" > hook.txt".to_string(),
+            ),
+            ..OutcomeHooks::default()
+        });
+    command.execute(&context).await.unwrap();
+
+    assert_eq!(
+        fs::read_to_string(passing_dir.join("hook.txt")).unwrap(),
+        "passing success\n"
+    );
+    assert_eq!(
+        fs::read_to_string(failing_dir.join("hook.txt")).unwrap(),
+        "failing failure Command failed with exit code: 1\n"
+    );
+
+    // Hooks never change the recorded outcome
+    let outcomes = context.outcomes.outcomes();
+    let failed: Vec<_> = outcomes
+        .iter()
+        .filter(|outcome| !outcome.success)
+        .map(|outcome| outcome.name.as_str())
+        .collect();
+    assert_eq!(failed, vec!["failing"]);
+}
+
+#[tokio::test]
+async fn test_repository_on_failure_overrides_flag() {
+    let (_temp_dir, repo, mut context) = setup_basic_test("test-repo");
+    context.config.repositories[0].on_failure = Some("touch own-hook".to_string());
+
+    let command = RunCommand::new_command("exit 3".to_string(), true, None).with_outcome_hooks(
+        OutcomeHooks {
+            on_success: Some("touch success-hook".to_string()),
+            on_failure: Some("touch flag-hook".to_string()),
+            ..OutcomeHooks::default()
+        },
+    );
+    assert!(command.execute(&context).await.is_err());
+
+    let repo_dir = PathBuf::from(repo.get_target_dir());
+    assert!(repo_dir.join("own-hook").exists());
+    assert!(!repo_dir.join("flag-hook").exists());
+    assert!(!repo_dir.join("success-hook").exists());
+}
+
+/// Name and error of every recorded outcome
+fn outcome_errors(context: &CommandContext) -> Vec<(String, Option<String>)> {
+    context
+        .outcomes
+        .outcomes()
+        .into_iter()
+        .map(|outcome| (outcome.name, outcome.error))
+        .collect()
+}
+
+#[tokio::test]
+async fn test_output_patterns_override_exit_codes() {
+    let (_temp_dir, _repos, mut context) = setup_parallel_test("harmless", "noisy");
+    context.parallel = true;
+
+    // `harmless` exits 1 after finishing; `noisy` exits 0 after an error
+    let command = RunCommand::new_command(
+        "if [ \"$(basename \"$PWD\")\" = harmless ]; then echo BUILD OK; exit 1; \
+         else echo BUILD OK; echo 'ERROR: lint' >&2; fi"
+            .to_string(),
+        true,
+        None,
+    )
+    .with_output_match(OutputMatch::new(Some("BUILD OK"), Some("^ERROR")).unwrap());
+    command.execute(&context).await.unwrap();
+
+    let mut errors = outcome_errors(&context);
+    errors.sort();
+    assert_eq!(
+        errors,
+        vec![
+            ("harmless".to_string(), None),
+            (
+                "noisy".to_string(),
+                Some("Output matched --failure-if '^ERROR'".to_string())
+            ),
+        ]
+    );
+}
+
+#[tokio::test]
+async fn test_success_pattern_is_matched_in_sequential_run_without_logs() {
+    let (_temp_dir, _repo, context) = setup_basic_test("quiet");
+
+    // Output is captured to be matched even though nothing is saved
+    let command = RunCommand::new_command("echo nothing to do".to_string(), true, None)
+        .with_output_match(OutputMatch::new(Some("deployed"), None).unwrap());
+    command.execute(&context).await.unwrap();
+
+    assert_eq!(
+        outcome_errors(&context),
+        vec![(
+            "quiet".to_string(),
+            Some("Output did not match --success-if 'deployed'".to_string())
+        )]
+    );
+}
+
+#[tokio::test]
+async fn test_deadline_stops_batch_and_marks_remaining_incomplete() {
+    let (_temp_dir, _repos, mut context) = setup_parallel_test("slow", "queued");
+    // One at a time, so the second repository is still waiting at the deadline
+    context.jobs = Some(1);
+    context.outcomes =
+        OutcomeRecorder::new().with_deadline(Some(Instant::now() + Duration::from_secs(1)));
+
+    let started = Instant::now();
+    let command = RunCommand::new_command("sleep 10".to_string(), true, None);
+    command.execute(&context).await.unwrap();
+    assert!(started.elapsed() < Duration::from_secs(5));
+
+    assert_eq!(context.outcomes.incomplete(), vec!["slow", "queued"]);
+    let outcomes = context.outcomes.outcomes();
+    assert_eq!(outcomes.len(), 2);
+    assert!(
+        outcomes
+            .iter()
+            .all(|outcome| !outcome.success && outcome.error.as_deref() == Some(DEADLINE_ERROR))
+    );
+}
+
+#[tokio::test]
+async fn test_deadline_in_sequential_run_marks_remaining_incomplete() {
+    let (_temp_dir, _repos, mut context) = setup_parallel_test("first", "second");
+    context.parallel = false;
+    context.outcomes =
+        OutcomeRecorder::new().with_deadline(Some(Instant::now() + Duration::from_secs(1)));
+
+    let command = RunCommand::new_command("sleep 10".to_string(), true, None);
+    command.execute(&context).await.unwrap();
+    assert_eq!(context.outcomes.incomplete(), vec!["first", "second"]);
+}
+
 // ===== Complex Path and Script Tests =====
 
 #[tokio::test]
@@ -1114,7 +1376,7 @@ async fn test_recipe_script_path_resolution() {
     let command = RunCommand {
         run_type: RunType::Recipe("path-resolution-recipe".to_string()),
         no_save: true,
-        output_dir: None,
+        ..Default::default()
     };
 
     let result = command.execute(&context).await;
@@ -1131,7 +1393,7 @@ async fn test_recipe_with_empty_steps() {
     let command = RunCommand {
         run_type: RunType::Recipe("empty-recipe".to_string()),
         no_save: true,
-        output_dir: None,
+        ..Default::default()
     };
 
     let result = command.execute(&context).await;
@@ -1155,7 +1417,7 @@ async fn test_script_creation_with_various_contents() {
     let command = RunCommand {
         run_type: RunType::Recipe("complex-script".to_string()),
         no_save: true,
-        output_dir: None,
+        ..Default::default()
     };
 
     let result = command.execute(&context).await;
@@ -1176,6 +1438,7 @@ async fn test_recipe_sequential_execution_with_default_output() {
         run_type: RunType::Recipe("default-output-recipe".to_string()),
         no_save: false,   // Enable saving with default output directory
         output_dir: None, // Use default
+        ..Default::default()
     };
 
     let result = command.execute(&context).await;
@@ -1198,7 +1461,7 @@ async fn test_multi_step_recipe_sequential() {
     let command = RunCommand {
         run_type: RunType::Recipe("multi-step-recipe".to_string()),
         no_save: true,
-        output_dir: None,
+        ..Default::default()
     };
 
     let result = command.execute(&context).await;
@@ -1215,13 +1478,14 @@ async fn test_recipe_multi_repo_complex_names() {
     let recipe = Recipe {
         name: "Complex-Recipe_Name.With@Special#Characters".to_string(),
         steps: vec!["echo 'Complex recipe with multiple repos'".to_string()],
+        args: Vec::new(),
     };
     context.config.recipes.push(recipe);
 
     let command = RunCommand {
         run_type: RunType::Recipe("Complex-Recipe_Name.With@Special#Characters".to_string()),
         no_save: true,
-        output_dir: None,
+        ..Default::default()
     };
 
     let result = command.execute(&context).await;
@@ -1242,6 +1506,7 @@ async fn test_run_command_creates_logs_with_content() {
         run_type: RunType::Command(format!("echo '{}'", test_output)),
         no_save: false, // Enable saving to create log files
         output_dir: Some(output_dir.clone()),
+        ..Default::default()
     };
 
     let result = command.execute(&context).await;
@@ -1339,6 +1604,7 @@ async fn test_run_recipe_creates_logs_with_content() {
         run_type: RunType::Recipe("log-test-recipe".to_string()),
         no_save: false, // Enable saving to create log files
         output_dir: Some(output_dir.clone()),
+        ..Default::default()
     };
 
     let result = command.execute(&context).await;
@@ -4,7 +4,7 @@
 //! across integration and E2E tests.
 
 use repos::{
-    commands::CommandContext,
+    commands::{CommandContext, OutcomeRecorder},
     config::{Config, Recipe, Repository},
 };
 use std::{fs, path::PathBuf, process::Command};
@@ -117,8 +117,7 @@ pub fn create_test_repo(name: &str, temp_dir: &TempDir) -> Repository {
         url: format!("https://github.com/user/{}.git", name),
         tags: vec!["test".to_string()],
         path: Some(repo_dir.to_string_lossy().to_string()),
-        branch: None,
-        config_dir: None,
+        ..Default::default()
     }
 }
 
@@ -127,6 +126,7 @@ pub fn create_test_recipe(name: &str, steps: Vec<&str>) -> Recipe {
     Recipe {
         name: name.to_string(),
         steps: steps.into_iter().map(|s| s.to_string()).collect(),
+        args: Vec::new(),
     }
 }
 
@@ -136,11 +136,15 @@ pub fn create_test_context(repositories: Vec<Repository>, recipes: Vec<Recipe>)
         config: Config {
             repositories,
             recipes,
+            version: None,
+            ..Default::default()
         },
         tag: vec![],
         exclude_tag: vec![],
         repos: None,
         parallel: false,
+        outcomes: OutcomeRecorder::new(),
+        jobs: None,
     }
 }
 